@@ -0,0 +1,235 @@
+// hkv-bench puts a HydraKV server under a configurable set/get/del load over HTTP or gRPC and reports the
+// throughput and latency percentiles per operation.
+//
+//	go run ./cmd/hkv-bench -proto grpc -addr 127.0.0.1:9292 -c 64 -d 30s -keys 100000 -value 256 -read 0.9
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// operations of the load - the index into the stats of a worker
+const (
+	opGet = iota
+	opSet
+	opDel
+	opCount
+)
+
+var opNames = [opCount]string{"get", "set", "del"}
+
+type config struct {
+	proto       string
+	addr        string
+	db          string
+	apikey      string
+	create      bool
+	concurrency int
+	duration    time.Duration
+	timeout     time.Duration
+	keys        int
+	valueSize   int
+	readRatio   float64
+	delRatio    float64
+}
+
+// opStats are the results of one operation - the latencies of the successful requests and the errors
+type opStats struct {
+	latencies []time.Duration
+	misses    int
+	errors    int
+}
+
+func main() {
+	var cfg config
+	flag.StringVar(&cfg.proto, "proto", "http", "Protocol of the target: http or grpc")
+	flag.StringVar(&cfg.addr, "addr", "", "Address of the target (default 127.0.0.1:9191 for http, 127.0.0.1:9292 for grpc)")
+	flag.StringVar(&cfg.db, "db", "benchdb", "DB to put the load on")
+	flag.StringVar(&cfg.apikey, "apikey", "", "API key of the DB - a key returned by -create is used otherwise")
+	flag.BoolVar(&cfg.create, "create", true, "Create the DB before the run")
+	flag.IntVar(&cfg.concurrency, "c", 32, "Number of concurrent workers")
+	flag.DurationVar(&cfg.duration, "d", 10*time.Second, "Duration of the run")
+	flag.DurationVar(&cfg.timeout, "timeout", 5*time.Second, "Timeout of a single request")
+	flag.IntVar(&cfg.keys, "keys", 10000, "Size of the key space")
+	flag.IntVar(&cfg.valueSize, "value", 64, "Size of the values in bytes")
+	flag.Float64Var(&cfg.readRatio, "read", 0.8, "Share of reads (0-1) - the rest are writes")
+	flag.Float64Var(&cfg.delRatio, "del", 0.1, "Share of deletes among the writes (0-1)")
+	flag.Parse()
+
+	if err := cfg.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "hkv-bench:", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	t, err := cfg.target()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer t.close()
+
+	if cfg.create {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+		apikey, err := t.createDB(ctx, cfg.db)
+		cancel()
+		if err != nil {
+			log.Fatalf("create db %s: %v", cfg.db, err)
+		}
+		// a new DB returns its key - retarget with it, unless one was given
+		if apikey != "" && cfg.apikey == "" {
+			cfg.apikey = apikey
+			_ = t.close()
+			if t, err = cfg.target(); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	fmt.Printf("hkv-bench: %s %s db=%s c=%d d=%s keys=%d value=%dB read=%.2f del=%.2f\n",
+		cfg.proto, cfg.addr, cfg.db, cfg.concurrency, cfg.duration, cfg.keys, cfg.valueSize, cfg.readRatio, cfg.delRatio)
+
+	stats, elapsed := run(t, cfg)
+	report(os.Stdout, stats, elapsed)
+}
+
+// validate checks the flags and fills in the default address
+func (cfg *config) validate() error {
+	switch cfg.proto {
+	case "http":
+		if cfg.addr == "" {
+			cfg.addr = "127.0.0.1:9191"
+		}
+	case "grpc":
+		if cfg.addr == "" {
+			cfg.addr = "127.0.0.1:9292"
+		}
+	default:
+		return fmt.Errorf("invalid -proto %q: http or grpc", cfg.proto)
+	}
+	switch {
+	case cfg.concurrency < 1:
+		return fmt.Errorf("-c must be at least 1")
+	case cfg.duration <= 0 || cfg.timeout <= 0:
+		return fmt.Errorf("-d and -timeout must be positive")
+	case cfg.keys < 1 || cfg.valueSize < 1:
+		return fmt.Errorf("-keys and -value must be at least 1")
+	case cfg.readRatio < 0 || cfg.readRatio > 1 || cfg.delRatio < 0 || cfg.delRatio > 1:
+		return fmt.Errorf("-read and -del must be between 0 and 1")
+	}
+	return nil
+}
+
+// target creates the client of the configured protocol
+func (cfg *config) target() (target, error) {
+	if cfg.proto == "grpc" {
+		return newGRPCTarget(cfg.addr, cfg.db, cfg.apikey)
+	}
+	return newHTTPTarget(cfg.addr, cfg.db, cfg.apikey, cfg.concurrency), nil
+}
+
+// run puts the load on t until the duration is over and returns the merged stats of all workers
+func run(t target, cfg config) ([opCount]opStats, time.Duration) {
+	value := strings.Repeat("x", cfg.valueSize)
+	deadline := time.Now().Add(cfg.duration)
+
+	results := make([][opCount]opStats, cfg.concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := range cfg.concurrency {
+		wg.Go(func() {
+			stats := &results[w]
+			for time.Now().Before(deadline) {
+				key := "bench-" + strconv.Itoa(rand.IntN(cfg.keys))
+				op := opSet
+				switch {
+				case rand.Float64() < cfg.readRatio:
+					op = opGet
+				case rand.Float64() < cfg.delRatio:
+					op = opDel
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+				begin := time.Now()
+				var err error
+				found := true
+				switch op {
+				case opGet:
+					found, err = t.get(ctx, key)
+				case opSet:
+					err = t.set(ctx, key, value)
+				case opDel:
+					found, err = t.del(ctx, key)
+				}
+				took := time.Since(begin)
+				cancel()
+
+				if err != nil {
+					stats[op].errors++
+					continue
+				}
+				if !found {
+					stats[op].misses++
+				}
+				stats[op].latencies = append(stats[op].latencies, took)
+			}
+		})
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var merged [opCount]opStats
+	for _, r := range results {
+		for op := range opCount {
+			merged[op].latencies = append(merged[op].latencies, r[op].latencies...)
+			merged[op].misses += r[op].misses
+			merged[op].errors += r[op].errors
+		}
+	}
+	return merged, elapsed
+}
+
+// percentile returns the p-th percentile (0-100) of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p / 100)
+	return sorted[i]
+}
+
+// report prints the throughput and the latency percentiles per operation and in total
+func report(w *os.File, stats [opCount]opStats, elapsed time.Duration) {
+	fmt.Fprintf(w, "\n%-6s %10s %10s %8s %8s %10s %10s %10s %10s %10s\n",
+		"op", "requests", "req/s", "errors", "misses", "p50", "p90", "p99", "p99.9", "max")
+
+	var all []time.Duration
+	totalErrors, totalMisses := 0, 0
+	line := func(name string, latencies []time.Duration, errors, misses int) {
+		slices.Sort(latencies)
+		requests := len(latencies) + errors
+		fmt.Fprintf(w, "%-6s %10d %10.0f %8d %8d %10s %10s %10s %10s %10s\n",
+			name, requests, float64(requests)/elapsed.Seconds(), errors, misses,
+			percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99),
+			percentile(latencies, 99.9), percentile(latencies, 100))
+	}
+	for op := range opCount {
+		s := stats[op]
+		line(opNames[op], s.latencies, s.errors, s.misses)
+		all = append(all, s.latencies...)
+		totalErrors += s.errors
+		totalMisses += s.misses
+	}
+	line("total", all, totalErrors, totalMisses)
+	fmt.Fprintf(w, "\nelapsed %s - latencies of successful requests, a miss is a get or del of a missing key\n",
+		elapsed.Round(time.Millisecond))
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"hydrakv/server/hydrakv/proto/kvpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// errUnexpected is returned for answers which are neither a success nor a miss
+var errUnexpected = errors.New("unexpected answer")
+
+// target is a server under load. get and del return false for a missing key - a miss is no error.
+type target interface {
+	createDB(ctx context.Context, db string) (apikey string, err error)
+	set(ctx context.Context, key, value string) error
+	get(ctx context.Context, key string) (bool, error)
+	del(ctx context.Context, key string) (bool, error)
+	close() error
+}
+
+/********/
+/* HTTP */
+/********/
+
+type httpTarget struct {
+	client *http.Client
+	base   string
+	db     string
+	apikey string
+}
+
+// newHTTPTarget creates a target for the REST API - one idle connection per worker
+func newHTTPTarget(addr, db, apikey string, workers int) *httpTarget {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = workers
+	transport.MaxIdleConnsPerHost = workers
+	return &httpTarget{client: &http.Client{Transport: transport}, base: "http://" + addr, db: db, apikey: apikey}
+}
+
+// do sends a JSON request and returns the status code - the body is drained to reuse the connection
+func (t *httpTarget) do(ctx context.Context, method, path string, payload any, out any) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, t.base+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.apikey != "" {
+		req.Header.Set("X-API-Key", t.apikey)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		err = json.NewDecoder(resp.Body).Decode(out)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, err
+}
+
+func (t *httpTarget) createDB(ctx context.Context, db string) (string, error) {
+	var created struct {
+		ApiKey string `json:"api_key"`
+	}
+	code, err := t.do(ctx, http.MethodPost, "/create", map[string]string{"name": db}, &created)
+	if err != nil {
+		return "", err
+	}
+	if code != http.StatusCreated && code != http.StatusConflict {
+		return "", fmt.Errorf("create db: status %d", code)
+	}
+	return created.ApiKey, nil
+}
+
+func (t *httpTarget) set(ctx context.Context, key, value string) error {
+	code, err := t.do(ctx, http.MethodPut, "/db/"+t.db, map[string]string{"key": key, "value": value}, nil)
+	if err == nil && code != http.StatusOK {
+		return fmt.Errorf("%w: status %d", errUnexpected, code)
+	}
+	return err
+}
+
+func (t *httpTarget) get(ctx context.Context, key string) (bool, error) {
+	code, err := t.do(ctx, http.MethodPost, "/db/"+t.db+"/keys", map[string]string{"key": key}, nil)
+	switch {
+	case err != nil:
+		return false, err
+	case code == http.StatusOK:
+		return true, nil
+	case code == http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("%w: status %d", errUnexpected, code)
+}
+
+func (t *httpTarget) del(ctx context.Context, key string) (bool, error) {
+	var deleted struct {
+		OK bool `json:"ok"`
+	}
+	code, err := t.do(ctx, http.MethodDelete, "/db/"+t.db+"/keys", map[string]string{"key": key}, &deleted)
+	if err == nil && code != http.StatusOK {
+		return false, fmt.Errorf("%w: status %d", errUnexpected, code)
+	}
+	return deleted.OK, err
+}
+
+func (t *httpTarget) close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
+
+/********/
+/* gRPC */
+/********/
+
+type grpcTarget struct {
+	conn   *grpc.ClientConn
+	client kvpb.KVServiceClient
+	db     string
+	apikey string
+}
+
+// newGRPCTarget creates a target for the gRPC API - all workers share one connection like the gRPC clients do
+func newGRPCTarget(addr, db, apikey string) (*grpcTarget, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	return &grpcTarget{conn: conn, client: kvpb.NewKVServiceClient(conn), db: db, apikey: apikey}, nil
+}
+
+func (t *grpcTarget) createDB(ctx context.Context, db string) (string, error) {
+	resp, err := t.client.CreateDB(ctx, &kvpb.CreateDBRequest{Name: db})
+	if err != nil {
+		return "", err
+	}
+	return resp.Apikey, nil
+}
+
+func (t *grpcTarget) set(ctx context.Context, key, value string) error {
+	resp, err := t.client.Set(ctx, &kvpb.SetRequest{Db: t.db, Apikey: t.apikey, Key: key, Value: value})
+	if err == nil && !resp.Ok {
+		return errUnexpected
+	}
+	return err
+}
+
+func (t *grpcTarget) get(ctx context.Context, key string) (bool, error) {
+	resp, err := t.client.Get(ctx, &kvpb.GetRequest{Db: t.db, Apikey: t.apikey, Key: key})
+	if err != nil {
+		return false, err
+	}
+	return resp.Found, nil
+}
+
+func (t *grpcTarget) del(ctx context.Context, key string) (bool, error) {
+	resp, err := t.client.Delete(ctx, &kvpb.DeleteRequest{Db: t.db, Apikey: t.apikey, Key: key})
+	if err != nil {
+		return false, err
+	}
+	return resp.Ok, nil
+}
+
+func (t *grpcTarget) close() error {
+	return t.conn.Close()
+}
@@ -0,0 +1,61 @@
+package envhandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// ReadConfigFile reads the settings of a JSON (.json) or YAML (.yaml, .yml) file. Its keys are the HKV_* names,
+// its values scalars - they are returned as strings, like the environment. Unknown keys are reported as error,
+// so a typo does not silently keep the default.
+func ReadConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		// numbers are kept as written - a float64 would print large ints in exponent notation
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("%s: unsupported config file format - use .json, .yaml or .yml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	unknown := make([]string, 0)
+	for key, val := range raw {
+		// every setting is a flag of the same name - the config file can not point to another one
+		if flag.Lookup(key) == nil || key == CONFIG_FILE {
+			unknown = append(unknown, key)
+			continue
+		}
+		switch val.(type) {
+		case string, json.Number, bool, int, float64:
+			values[key] = fmt.Sprint(val)
+		default:
+			return nil, fmt.Errorf("%s: %s must be a string, number or bool", path, key)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return nil, fmt.Errorf("%s: unknown settings %s", path, strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
@@ -2,11 +2,15 @@ package envhandler
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"reflect"
 	"runtime"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 )
 
 const (
@@ -28,31 +32,161 @@ const (
 	GRPC_BIND_ADDRESS           = "HKV_GRPC_BIND_ADDRESS"
 	GRPC_REQ_LIMIT              = "HKV_GRPC_REQUEST_LIMIT"
 	GRPC_MAX_DURATION           = "HKV_GRPC_MAX_DURATION"
-	GRPC_MAX_CONCURRENT_STREAMS = "GRPC_MAX_CONCURRENT_STREAMS"
+	GRPC_MAX_CONCURRENT_STREAMS = "HKV_GRPC_MAX_CONCURRENT_STREAMS"
 	CPU_MULTIPLIER              = "HKV_CPU_MULTIPLIER"
+	COMPACT_RATIO               = "HKV_COMPACT_RATIO"
+	COMPACT_GROWTH              = "HKV_COMPACT_GROWTH"
+	MAX_AOF_BYTES               = "HKV_MAX_AOF_BYTES"
+	FSYNC                       = "HKV_FSYNC"
+	IDEMPOTENCY_TTL             = "HKV_IDEMPOTENCY_TTL"
+	SHOW_LOGO                   = "HKV_SHOW_LOGO"
+	DBNAME_REGEX                = "HKV_DBNAME_REGEX"
+	DBNAME_MAXLEN               = "HKV_DBNAME_MAXLEN"
+	DBNAME_CASE_SENSITIVE       = "HKV_DBNAME_CASE_SENSITIVE"
+	CORS_ORIGINS                = "HKV_CORS_ORIGINS"
+	GZIP_MIN_BYTES              = "HKV_GZIP_MIN_BYTES"
+	MAX_BODY_BYTES              = "HKV_MAX_BODY_BYTES"
+	AUDIT_LOG                   = "HKV_AUDIT_LOG"
+	AUTH_LOCKOUT                = "HKV_AUTH_LOCKOUT"
+	AUTH_LOCKOUT_WINDOW         = "HKV_AUTH_LOCKOUT_WINDOW"
+	AUTH_LOCKOUT_COOLDOWN       = "HKV_AUTH_LOCKOUT_COOLDOWN"
+	ALLOW_CIDRS                 = "HKV_ALLOW_CIDRS"
+	DENY_CIDRS                  = "HKV_DENY_CIDRS"
+	TRUST_PROXY                 = "HKV_TRUST_PROXY"
+	ADMIN_KEY                   = "HKV_ADMIN_KEY"
+	OVERSIZE_POLICY             = "HKV_OVERSIZE_POLICY"
+	APPROX_CARDINALITY          = "HKV_APPROX_CARDINALITY"
+	REQUEST_WAIT_MS             = "HKV_REQUEST_WAIT_MS"
+	SHUTDOWN_TIMEOUT            = "HKV_SHUTDOWN_TIMEOUT"
+	ENV_FILE                    = "HKV_ENV_FILE"
+	MAX_CONNS_PER_IP            = "HKV_MAX_CONNS_PER_IP"
+	AOF_HIGH_WATER              = "HKV_AOF_HIGH_WATER"
+	AOF_LOW_WATER               = "HKV_AOF_LOW_WATER"
+	TTL_SHARDS                  = "HKV_TTL_SHARDS"
+	RESP_PORT                   = "HKV_RESP_PORT"
+	UNIX_SOCKET                 = "HKV_UNIX_SOCKET"
+	GRPC_UNIX_SOCKET            = "HKV_GRPC_UNIX_SOCKET"
+	HTTP2_H2C                   = "HKV_HTTP2_H2C"
+	BATCH_MAX_OPS               = "HKV_BATCH_MAX_OPS"
+	CONFIG_FILE                 = "HKV_CONFIG_FILE"
+	REPLAY_BUFFER               = "HKV_REPLAY_BUFFER"
+	CHAIN_SAMPLE_INTERVAL       = "HKV_CHAIN_SAMPLE_INTERVAL"
+	CHAIN_SAMPLE_SIZE           = "HKV_CHAIN_SAMPLE_SIZE"
+	CHANGELOG_MAX_BYTES         = "HKV_CHANGELOG_MAX_BYTES"
+	CHANGELOG_RETENTION         = "HKV_CHANGELOG_RETENTION"
+	MAX_TTL_SECONDS             = "HKV_MAX_TTL_SECONDS"
+	TTL_POLICY                  = "HKV_TTL_POLICY"
+	ALLOW_NO_EXPIRY             = "HKV_ALLOW_NO_EXPIRY"
+	KEY_MISS_STATUS             = "HKV_KEY_MISS_STATUS"
+	LAZY_BASKETS                = "HKV_LAZY_BASKETS"
+	FILE_MODE                   = "HKV_FILE_MODE"
+	DIR_MODE                    = "HKV_DIR_MODE"
+	SUB_BUFFER                  = "HKV_SUB_BUFFER"
+	SUB_OVERFLOW                = "HKV_SUB_OVERFLOW"
+	INDEX_PAGE                  = "HKV_INDEX_PAGE"
+	SNAPSHOT_FOLDER             = "HKV_SNAPSHOT_FOLDER"
+)
+
+// grpcMaxConcurrentStreamsLegacy is the former name of GRPC_MAX_CONCURRENT_STREAMS without the HKV_ prefix - it is
+// still read, with a warning
+const grpcMaxConcurrentStreamsLegacy = "GRPC_MAX_CONCURRENT_STREAMS"
+
+// fsync policies of the AOF
+const (
+	FSYNC_INTERVAL = "interval"
+	FSYNC_ALWAYS   = "always"
+)
+
+// policies for values above ENTRY_SIZE
+const (
+	OVERSIZE_REJECT   = "reject"
+	OVERSIZE_TRUNCATE = "truncate"
+)
+
+// policies for TTLs above MAX_TTL_SECONDS
+const (
+	TTL_CLAMP  = "clamp"
+	TTL_REJECT = "reject"
+)
+
+// policies for subscribers whose buffer is full
+const (
+	SUB_DROP_OLDEST = "drop-oldest"
+	SUB_DISCONNECT  = "disconnect"
 )
 
 type EnvHandler struct {
-	BIND_ADDRESS                *string `env:"BIND_ADDRESS"`
-	PORT                        *int    `env:"PORT"`
-	DB_FOLDER                   *string `env:"DB_FOLDER"`
-	MAX_ENTRIES                 *int    `env:"MAX_ENTRIES"`
-	WRITE_TIMEOUT               *int    `env:"WRITE_TIMEOUT"`
-	APIKEY_ENABLED              *bool   `env:"APIKEY_ENABLED"`
-	READ_TIMEOUT                *int    `env:"READ_TIMEOUT"`
-	IDLE_TIMEOUT                *int    `env:"IDLE_TIMEOUT"`
-	METRICS                     *bool   `env:"METRICS"`
-	ENTRY_SIZE                  *int    `env:"ENTRY_SIZE"`
-	MAX_HEADER_BATES            *int    `env:"MAX_HEADER_BYTES"`
-	XXHASH_SEED                 *uint64 `env:"XXHASH_SEED"`
-	REQ_LIMIT                   *int    `env:"REQUEST_LIMIT"`
-	GRPC_ENABLED                *bool   `env:"GRPC_ENABLED"`
-	GRPC_PORT                   *int    `env:"GRPC_PORT"`
-	GRPC_BIND_ADDRESS           *string `env:"GRPC_BIND_ADDRESS"`
-	GRPC_REQ_LIMIT              *int    `env:"GRPC_REQUEST_LIMIT"`
-	GRPC_MAX_DURATION           *int    `env:"GRPC_MAX_DURATION"`
-	GRPC_MAX_CONCURRENT_STREAMS *int    `env:"GRPC_MAX_CONCURRENT_STREAMS"`
-	CPU_MULTIPLIER              *int    `env:"CPU_MULTIPLIER"`
+	BIND_ADDRESS                *string  `env:"BIND_ADDRESS"`
+	PORT                        *int     `env:"PORT"`
+	DB_FOLDER                   *string  `env:"DB_FOLDER"`
+	MAX_ENTRIES                 *int     `env:"MAX_ENTRIES"`
+	WRITE_TIMEOUT               *int     `env:"WRITE_TIMEOUT"`
+	APIKEY_ENABLED              *bool    `env:"APIKEY_ENABLED"`
+	READ_TIMEOUT                *int     `env:"READ_TIMEOUT"`
+	IDLE_TIMEOUT                *int     `env:"IDLE_TIMEOUT"`
+	METRICS                     *bool    `env:"METRICS"`
+	ENTRY_SIZE                  *int     `env:"ENTRY_SIZE"`
+	MAX_HEADER_BATES            *int     `env:"MAX_HEADER_BYTES"`
+	XXHASH_SEED                 *uint64  `env:"XXHASH_SEED"`
+	REQ_LIMIT                   *int     `env:"REQUEST_LIMIT"`
+	GRPC_ENABLED                *bool    `env:"GRPC_ENABLED"`
+	GRPC_PORT                   *int     `env:"GRPC_PORT"`
+	GRPC_BIND_ADDRESS           *string  `env:"GRPC_BIND_ADDRESS"`
+	GRPC_REQ_LIMIT              *int     `env:"GRPC_REQUEST_LIMIT"`
+	GRPC_MAX_DURATION           *int     `env:"GRPC_MAX_DURATION"`
+	GRPC_MAX_CONCURRENT_STREAMS *int     `env:"GRPC_MAX_CONCURRENT_STREAMS"`
+	CPU_MULTIPLIER              *int     `env:"CPU_MULTIPLIER"`
+	COMPACT_RATIO               *float64 `env:"COMPACT_RATIO"`
+	COMPACT_GROWTH              *float64 `env:"COMPACT_GROWTH"`
+	MAX_AOF_BYTES               *int     `env:"MAX_AOF_BYTES"`
+	FSYNC                       *string  `env:"FSYNC"`
+	IDEMPOTENCY_TTL             *int     `env:"IDEMPOTENCY_TTL"`
+	SHOW_LOGO                   *bool    `env:"SHOW_LOGO"`
+	DBNAME_REGEX                *string  `env:"DBNAME_REGEX"`
+	DBNAME_MAXLEN               *int     `env:"DBNAME_MAXLEN"`
+	DBNAME_CASE_SENSITIVE       *bool    `env:"DBNAME_CASE_SENSITIVE"`
+	CORS_ORIGINS                *string  `env:"CORS_ORIGINS"`
+	GZIP_MIN_BYTES              *int     `env:"GZIP_MIN_BYTES"`
+	MAX_BODY_BYTES              *int     `env:"MAX_BODY_BYTES"`
+	AUDIT_LOG                   *string  `env:"AUDIT_LOG"`
+	AUTH_LOCKOUT                *int     `env:"AUTH_LOCKOUT"`
+	AUTH_LOCKOUT_WINDOW         *int     `env:"AUTH_LOCKOUT_WINDOW"`
+	AUTH_LOCKOUT_COOLDOWN       *int     `env:"AUTH_LOCKOUT_COOLDOWN"`
+	ALLOW_CIDRS                 *string  `env:"ALLOW_CIDRS"`
+	DENY_CIDRS                  *string  `env:"DENY_CIDRS"`
+	TRUST_PROXY                 *bool    `env:"TRUST_PROXY"`
+	ADMIN_KEY                   *string  `env:"ADMIN_KEY" secret:"true"`
+	OVERSIZE_POLICY             *string  `env:"OVERSIZE_POLICY"`
+	APPROX_CARDINALITY          *bool    `env:"APPROX_CARDINALITY"`
+	REQUEST_WAIT_MS             *int     `env:"REQUEST_WAIT_MS"`
+	SHUTDOWN_TIMEOUT            *int     `env:"SHUTDOWN_TIMEOUT"`
+	ENV_FILE                    *string  `env:"ENV_FILE"`
+	MAX_CONNS_PER_IP            *int     `env:"MAX_CONNS_PER_IP"`
+	AOF_HIGH_WATER              *float64 `env:"AOF_HIGH_WATER"`
+	AOF_LOW_WATER               *float64 `env:"AOF_LOW_WATER"`
+	TTL_SHARDS                  *int     `env:"TTL_SHARDS"`
+	RESP_PORT                   *int     `env:"RESP_PORT"`
+	UNIX_SOCKET                 *string  `env:"UNIX_SOCKET"`
+	GRPC_UNIX_SOCKET            *string  `env:"GRPC_UNIX_SOCKET"`
+	HTTP2_H2C                   *bool    `env:"HTTP2_H2C"`
+	BATCH_MAX_OPS               *int     `env:"BATCH_MAX_OPS"`
+	CONFIG_FILE                 *string  `env:"CONFIG_FILE"`
+	REPLAY_BUFFER               *int     `env:"REPLAY_BUFFER"`
+	CHAIN_SAMPLE_INTERVAL       *int     `env:"CHAIN_SAMPLE_INTERVAL"`
+	CHAIN_SAMPLE_SIZE           *int     `env:"CHAIN_SAMPLE_SIZE"`
+	CHANGELOG_MAX_BYTES         *int     `env:"CHANGELOG_MAX_BYTES"`
+	CHANGELOG_RETENTION         *int     `env:"CHANGELOG_RETENTION"`
+	MAX_TTL_SECONDS             *int     `env:"MAX_TTL_SECONDS"`
+	TTL_POLICY                  *string  `env:"TTL_POLICY"`
+	ALLOW_NO_EXPIRY             *bool    `env:"ALLOW_NO_EXPIRY"`
+	KEY_MISS_STATUS             *int     `env:"KEY_MISS_STATUS"`
+	LAZY_BASKETS                *bool    `env:"LAZY_BASKETS"`
+	FILE_MODE                   *string  `env:"FILE_MODE"`
+	DIR_MODE                    *string  `env:"DIR_MODE"`
+	SUB_BUFFER                  *int     `env:"SUB_BUFFER"`
+	SUB_OVERFLOW                *string  `env:"SUB_OVERFLOW"`
+	INDEX_PAGE                  *bool    `env:"INDEX_PAGE"`
+	SNAPSHOT_FOLDER             *string  `env:"SNAPSHOT_FOLDER"`
 }
 
 // ENV is the global EnvHandler - its a singleton
@@ -81,11 +215,276 @@ func init() {
 		GRPC_MAX_DURATION:           flag.Int(GRPC_MAX_DURATION, 10, "The maximum duration in seconds for a gRPC call"),
 		GRPC_MAX_CONCURRENT_STREAMS: flag.Int(GRPC_MAX_CONCURRENT_STREAMS, runtime.NumCPU()*4, "The maximum number of concurrent streams for a gRPC call"),
 		CPU_MULTIPLIER:              flag.Int(CPU_MULTIPLIER, 16, "The multiplier to use for CPU usage"),
+		COMPACT_RATIO:               flag.Float64(COMPACT_RATIO, 0.5, "The ratio of deleted to live entries which triggers an AOF compaction"),
+		COMPACT_GROWTH:              flag.Float64(COMPACT_GROWTH, 4, "The factor the AOF may grow beyond its size after the last compaction before it is compacted (0 = disabled)"),
+		MAX_AOF_BYTES:               flag.Int(MAX_AOF_BYTES, 0, "The maximum size of an AOF file in bytes, writes are rejected above (0 = unlimited)"),
+		FSYNC:                       flag.String(FSYNC, FSYNC_INTERVAL, "The fsync policy of the AOF: interval (every 100ms) or always (before a write returns)"),
+		IDEMPOTENCY_TTL:             flag.Int(IDEMPOTENCY_TTL, 60, "The time in seconds the result of a write with an idempotency key is remembered"),
+		SHOW_LOGO:                   flag.Bool(SHOW_LOGO, true, "Show the logo at startup"),
+		DBNAME_REGEX:                flag.String(DBNAME_REGEX, "^[a-zA-Z0-9_-]+$", "The regex DB names have to match"),
+		DBNAME_MAXLEN:               flag.Int(DBNAME_MAXLEN, 100, "The maximum length of DB names"),
+		DBNAME_CASE_SENSITIVE:       flag.Bool(DBNAME_CASE_SENSITIVE, false, "Keep the case of DB names instead of uppercasing them"),
+		CORS_ORIGINS:                flag.String(CORS_ORIGINS, "", "Comma-separated list of origins allowed by CORS or * (empty = CORS disabled)"),
+		GZIP_MIN_BYTES:              flag.Int(GZIP_MIN_BYTES, 1024, "The minimum size of a HTTP response in bytes to be gzip compressed (0 = disabled)"),
+		MAX_BODY_BYTES:              flag.Int(MAX_BODY_BYTES, 64*1024, "The maximum size of a HTTP request body in bytes"),
+		AUDIT_LOG:                   flag.String(AUDIT_LOG, "", "The audit log of authentication decisions: a file path, stdout or stderr (empty = disabled)"),
+		AUTH_LOCKOUT:                flag.Int(AUTH_LOCKOUT, 0, "The number of failed auth attempts per IP and DB within the window before further attempts are rejected (0 = disabled)"),
+		AUTH_LOCKOUT_WINDOW:         flag.Int(AUTH_LOCKOUT_WINDOW, 60, "The window in seconds in which failed auth attempts are counted"),
+		AUTH_LOCKOUT_COOLDOWN:       flag.Int(AUTH_LOCKOUT_COOLDOWN, 300, "The time in seconds an IP is locked out of a DB after too many failed auth attempts"),
+		ALLOW_CIDRS:                 flag.String(ALLOW_CIDRS, "", "Comma-separated list of CIDRs or IPs allowed to connect (empty = all)"),
+		DENY_CIDRS:                  flag.String(DENY_CIDRS, "", "Comma-separated list of CIDRs or IPs rejected with 403"),
+		TRUST_PROXY:                 flag.Bool(TRUST_PROXY, false, "Use the last hop of X-Forwarded-For or X-Real-IP as the client IP"),
+		ADMIN_KEY:                   flag.String(ADMIN_KEY, "", "The key of the admin endpoints, sent as X-Admin-Key (empty = admin endpoints disabled)"),
+		OVERSIZE_POLICY:             flag.String(OVERSIZE_POLICY, OVERSIZE_REJECT, "The policy for values above ENTRY_SIZE: reject or truncate"),
+		APPROX_CARDINALITY:          flag.Bool(APPROX_CARDINALITY, false, "Maintain a HyperLogLog estimate of the distinct keys of each DB"),
+		REQUEST_WAIT_MS:             flag.Int(REQUEST_WAIT_MS, 0, "Milliseconds a request waits for a free slot when the request limit is reached - 0 rejects right away"),
+		SHUTDOWN_TIMEOUT:            flag.Int(SHUTDOWN_TIMEOUT, 30, "Deadline in seconds for the whole shutdown - draining the requests and closing the DBs"),
+		ENV_FILE:                    flag.String(ENV_FILE, "", "File of HKV_*=value lines for the hot-reloadable settings - read at start and on SIGHUP"),
+		MAX_CONNS_PER_IP:            flag.Int(MAX_CONNS_PER_IP, 0, "The maximum number of open HTTP connections per client IP - 0 is unlimited"),
+		AOF_HIGH_WATER:              flag.Float64(AOF_HIGH_WATER, 0.8, "The fill of the AOF queue (0-1) above which writes to the DB are rejected until it drained - 0 disables the throttle"),
+		AOF_LOW_WATER:               flag.Float64(AOF_LOW_WATER, 0.5, "The fill of the AOF queue (0-1) below which a throttled DB accepts writes again"),
+		TTL_SHARDS:                  flag.Int(TTL_SHARDS, 0, "Number of TTL shards - a power of two, 0 derives it from NumCPU * CPU_MULTIPLIER"),
+		RESP_PORT:                   flag.Int(RESP_PORT, 0, "Port of the RESP (Redis protocol) listener - 0 disables it"),
+		UNIX_SOCKET:                 flag.String(UNIX_SOCKET, "", "Path of a Unix socket the HTTP server listens on in addition to TCP - empty disables it"),
+		GRPC_UNIX_SOCKET:            flag.String(GRPC_UNIX_SOCKET, "", "Path of a Unix socket the gRPC server listens on in addition to TCP - empty disables it"),
+		HTTP2_H2C:                   flag.Bool(HTTP2_H2C, false, "Serve HTTP/2 without TLS (h2c, prior knowledge) next to HTTP/1.1"),
+		BATCH_MAX_OPS:               flag.Int(BATCH_MAX_OPS, 100, "The maximum number of operations of a HTTP batch request and of keys of a mget-ttl request"),
+		CONFIG_FILE:                 flag.String(CONFIG_FILE, "", "JSON or YAML file of HKV_* settings - the environment and flags override it"),
+		REPLAY_BUFFER:               flag.Int(REPLAY_BUFFER, 64*1024, "The size in bytes of the read buffer of the AOF replay on startup"),
+		CHAIN_SAMPLE_INTERVAL:       flag.Int(CHAIN_SAMPLE_INTERVAL, 60, "The interval in seconds in which a sample of baskets is walked for the kv_chain_length metric - 0 disables it"),
+		CHAIN_SAMPLE_SIZE:           flag.Int(CHAIN_SAMPLE_SIZE, 1024, "The number of random baskets walked per chain length sample"),
+		CHANGELOG_MAX_BYTES:         flag.Int(CHANGELOG_MAX_BYTES, 64*1024*1024, "The maximum size in bytes of the changelog of a DB including its rotated segment"),
+		CHANGELOG_RETENTION:         flag.Int(CHANGELOG_RETENTION, 0, "The time in seconds changes are kept in the changelog at least - 0 keeps them until HKV_CHANGELOG_MAX_BYTES is reached"),
+		MAX_TTL_SECONDS:             flag.Int(MAX_TTL_SECONDS, 0, "The maximum TTL of an entry in seconds, 0 disables the cap"),
+		TTL_POLICY:                  flag.String(TTL_POLICY, TTL_CLAMP, "The policy for TTLs above MAX_TTL_SECONDS: clamp or reject"),
+		ALLOW_NO_EXPIRY:             flag.Bool(ALLOW_NO_EXPIRY, true, "Allow entries without expiry (ttl 0)"),
+		KEY_MISS_STATUS:             flag.Int(KEY_MISS_STATUS, 404, "The HTTP status of a get of a missing key: 404 or 200"),
+		LAZY_BASKETS:                flag.Bool(LAZY_BASKETS, false, "Start a DB with as few baskets as the basket locks allow instead of 2048"),
+		FILE_MODE:                   flag.String(FILE_MODE, "0644", "The octal permissions of created data files like AOFs"),
+		DIR_MODE:                    flag.String(DIR_MODE, "0755", "The octal permissions of created data folders"),
+		SUB_BUFFER:                  flag.Int(SUB_BUFFER, 1024, "The number of notifications buffered per subscriber"),
+		SUB_OVERFLOW:                flag.String(SUB_OVERFLOW, SUB_DROP_OLDEST, "The policy for a subscriber whose buffer is full: drop-oldest or disconnect"),
+		INDEX_PAGE:                  flag.Bool(INDEX_PAGE, false, "List all DBs at / instead of a neutral landing page - with API keys enabled only for requests with the admin key"),
+		SNAPSHOT_FOLDER:             flag.String(SNAPSHOT_FOLDER, "", "The folder to spool snapshots to - defaults to the DB folder"),
+	}
+}
+
+// envName maps the env tag of an EnvHandler field to its HKV_* name - empty for fields which are no setting
+func envName(tag string) string {
+	switch tag {
+	case "BIND_ADDRESS":
+		return BIND_ADDRESS
+	case "PORT":
+		return PORT
+	case "DB_FOLDER":
+		return DB_FOLDER
+	case "MAX_ENTRIES":
+		return MAX_ENTRIES
+	case "WRITE_TIMEOUT":
+		return WRITE_TIMEOUT
+	case "APIKEY_ENABLED":
+		return APIKEY_ENABLED
+	case "READ_TIMEOUT":
+		return READ_TIMEOUT
+	case "IDLE_TIMEOUT":
+		return IDLE_TIMEOUT
+	case "METRICS":
+		return METRICS
+	case "ENTRY_SIZE":
+		return ENTRY_SIZE
+	case "MAX_HEADER_BYTES":
+		return MAX_HEADER_BYTES
+	case "XXHASH_SEED":
+		return XXHASH_SEED
+	case "REQUEST_LIMIT":
+		return REQ_LIMIT
+	case "GRPC_ENABLED":
+		return GRPC_ENABLED
+	case "GRPC_PORT":
+		return GRPC_PORT
+	case "GRPC_BIND_ADDRESS":
+		return GRPC_BIND_ADDRESS
+	case "GRPC_REQUEST_LIMIT":
+		return GRPC_REQ_LIMIT
+	case "GRPC_MAX_DURATION":
+		return GRPC_MAX_DURATION
+	case "GRPC_MAX_CONCURRENT_STREAMS":
+		return GRPC_MAX_CONCURRENT_STREAMS
+	case "CPU_MULTIPLIER":
+		return CPU_MULTIPLIER
+	case "COMPACT_RATIO":
+		return COMPACT_RATIO
+	case "COMPACT_GROWTH":
+		return COMPACT_GROWTH
+	case "MAX_AOF_BYTES":
+		return MAX_AOF_BYTES
+	case "FSYNC":
+		return FSYNC
+	case "IDEMPOTENCY_TTL":
+		return IDEMPOTENCY_TTL
+	case "SHOW_LOGO":
+		return SHOW_LOGO
+	case "DBNAME_REGEX":
+		return DBNAME_REGEX
+	case "DBNAME_MAXLEN":
+		return DBNAME_MAXLEN
+	case "DBNAME_CASE_SENSITIVE":
+		return DBNAME_CASE_SENSITIVE
+	case "CORS_ORIGINS":
+		return CORS_ORIGINS
+	case "GZIP_MIN_BYTES":
+		return GZIP_MIN_BYTES
+	case "MAX_BODY_BYTES":
+		return MAX_BODY_BYTES
+	case "AUDIT_LOG":
+		return AUDIT_LOG
+	case "AUTH_LOCKOUT":
+		return AUTH_LOCKOUT
+	case "AUTH_LOCKOUT_WINDOW":
+		return AUTH_LOCKOUT_WINDOW
+	case "AUTH_LOCKOUT_COOLDOWN":
+		return AUTH_LOCKOUT_COOLDOWN
+	case "ALLOW_CIDRS":
+		return ALLOW_CIDRS
+	case "DENY_CIDRS":
+		return DENY_CIDRS
+	case "TRUST_PROXY":
+		return TRUST_PROXY
+	case "ADMIN_KEY":
+		return ADMIN_KEY
+	case "OVERSIZE_POLICY":
+		return OVERSIZE_POLICY
+	case "APPROX_CARDINALITY":
+		return APPROX_CARDINALITY
+	case "REQUEST_WAIT_MS":
+		return REQUEST_WAIT_MS
+	case "SHUTDOWN_TIMEOUT":
+		return SHUTDOWN_TIMEOUT
+	case "ENV_FILE":
+		return ENV_FILE
+	case "MAX_CONNS_PER_IP":
+		return MAX_CONNS_PER_IP
+	case "AOF_HIGH_WATER":
+		return AOF_HIGH_WATER
+	case "AOF_LOW_WATER":
+		return AOF_LOW_WATER
+	case "TTL_SHARDS":
+		return TTL_SHARDS
+	case "RESP_PORT":
+		return RESP_PORT
+	case "UNIX_SOCKET":
+		return UNIX_SOCKET
+	case "GRPC_UNIX_SOCKET":
+		return GRPC_UNIX_SOCKET
+	case "HTTP2_H2C":
+		return HTTP2_H2C
+	case "BATCH_MAX_OPS":
+		return BATCH_MAX_OPS
+	case "CONFIG_FILE":
+		return CONFIG_FILE
+	case "REPLAY_BUFFER":
+		return REPLAY_BUFFER
+	case "CHAIN_SAMPLE_INTERVAL":
+		return CHAIN_SAMPLE_INTERVAL
+	case "CHAIN_SAMPLE_SIZE":
+		return CHAIN_SAMPLE_SIZE
+	case "CHANGELOG_MAX_BYTES":
+		return CHANGELOG_MAX_BYTES
+	case "CHANGELOG_RETENTION":
+		return CHANGELOG_RETENTION
+	case "MAX_TTL_SECONDS":
+		return MAX_TTL_SECONDS
+	case "TTL_POLICY":
+		return TTL_POLICY
+	case "ALLOW_NO_EXPIRY":
+		return ALLOW_NO_EXPIRY
+	case "KEY_MISS_STATUS":
+		return KEY_MISS_STATUS
+	case "LAZY_BASKETS":
+		return LAZY_BASKETS
+	case "FILE_MODE":
+		return FILE_MODE
+	case "DIR_MODE":
+		return DIR_MODE
+	case "SUB_BUFFER":
+		return SUB_BUFFER
+	case "SUB_OVERFLOW":
+		return SUB_OVERFLOW
+	case "INDEX_PAGE":
+		return INDEX_PAGE
+	case "SNAPSHOT_FOLDER":
+		return SNAPSHOT_FOLDER
+	}
+	return ""
+}
+
+// parseMode parses octal permissions like 0640 - need are the bits the owner must have, so the server can still use
+// what it creates
+func parseMode(s string, need os.FileMode) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("must be octal permissions up to 0777")
+	}
+	if os.FileMode(mode)&need != need {
+		return 0, fmt.Errorf("must include %04o for the owner", need)
+	}
+	return os.FileMode(mode), nil
+}
+
+// FileMode returns the permissions of created data files - HKV_FILE_MODE
+func (e *EnvHandler) FileMode() os.FileMode {
+	mode, err := parseMode(*e.FILE_MODE, 0600)
+	if err != nil {
+		return 0644
+	}
+	return mode
+}
+
+// DirMode returns the permissions of created data folders - HKV_DIR_MODE
+func (e *EnvHandler) DirMode() os.FileMode {
+	mode, err := parseMode(*e.DIR_MODE, 0700)
+	if err != nil {
+		return 0755
 	}
+	return mode
 }
 
-// LoadENVs loads all ENV variables into the EnvHandler
+// SnapshotFolder returns the folder the snapshots are spooled to - HKV_DB_FOLDER unless HKV_SNAPSHOT_FOLDER is set
+func (e *EnvHandler) SnapshotFolder() string {
+	if *e.SNAPSHOT_FOLDER != "" {
+		return *e.SNAPSHOT_FOLDER
+	}
+	return *e.DB_FOLDER
+}
+
+// LoadENVs loads all ENV variables into the EnvHandler. A setting is taken from the flags if given on the command
+// line, else from the environment, else from HKV_CONFIG_FILE - the flag defaults stay for the rest.
 func (e *EnvHandler) LoadENVs() {
+	// the flags given on the command line - their defaults do not count
+	flagged := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		flagged[f.Name] = true
+	})
+
+	// the config file itself is only set by flag or environment
+	configFile := *e.CONFIG_FILE
+	if val, ok := os.LookupEnv(CONFIG_FILE); ok && !flagged[CONFIG_FILE] {
+		configFile = val
+	}
+	fileValues := make(map[string]string)
+	if configFile != "" {
+		var err error
+		if fileValues, err = ReadConfigFile(configFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// a running server reads the HotReloadable settings while they are loaded, e.g. a restart within the tests
+	clearSources()
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	clear(overridden)
+
 	v := reflect.ValueOf(e).Elem()
 	t := reflect.TypeOf(e).Elem()
 
@@ -100,56 +499,29 @@ func (e *EnvHandler) LoadENVs() {
 		}
 
 		// Map internal tag names to the actual HKV_* environment variable names
-		actualEnvKey := ""
-		switch envKey {
-		case "BIND_ADDRESS":
-			actualEnvKey = BIND_ADDRESS
-		case "PORT":
-			actualEnvKey = PORT
-		case "DB_FOLDER":
-			actualEnvKey = DB_FOLDER
-		case "MAX_ENTRIES":
-			actualEnvKey = MAX_ENTRIES
-		case "WRITE_TIMEOUT":
-			actualEnvKey = WRITE_TIMEOUT
-		case "APIKEY_ENABLED":
-			actualEnvKey = APIKEY_ENABLED
-		case "READ_TIMEOUT":
-			actualEnvKey = READ_TIMEOUT
-		case "IDLE_TIMEOUT":
-			actualEnvKey = IDLE_TIMEOUT
-		case "METRICS":
-			actualEnvKey = METRICS
-		case "ENTRY_SIZE":
-			actualEnvKey = ENTRY_SIZE
-		case "MAX_HEADER_BYTES":
-			actualEnvKey = MAX_HEADER_BYTES
-		case "XXHASH_SEED":
-			actualEnvKey = XXHASH_SEED
-		case "REQUEST_LIMIT":
-			actualEnvKey = REQ_LIMIT
-		case "GRPC_ENABLED":
-			actualEnvKey = GRPC_ENABLED
-		case GRPC_PORT:
-			actualEnvKey = GRPC_PORT
-		case GRPC_BIND_ADDRESS:
-			actualEnvKey = GRPC_BIND_ADDRESS
-		case GRPC_REQ_LIMIT:
-			actualEnvKey = GRPC_REQ_LIMIT
-		case GRPC_MAX_DURATION:
-			actualEnvKey = GRPC_MAX_DURATION
-		case GRPC_MAX_CONCURRENT_STREAMS:
-			actualEnvKey = GRPC_MAX_CONCURRENT_STREAMS
-		case CPU_MULTIPLIER:
-			actualEnvKey = CPU_MULTIPLIER
-		default:
+		actualEnvKey := envName(envKey)
+		if actualEnvKey == "" {
 			continue
 		}
 
+		if flagged[actualEnvKey] {
+			setSource(actualEnvKey, SourceFlag)
+			continue
+		}
+		source := SourceEnv
 		envVal, ok := os.LookupEnv(actualEnvKey)
+		if !ok && actualEnvKey == GRPC_MAX_CONCURRENT_STREAMS {
+			if envVal, ok = os.LookupEnv(grpcMaxConcurrentStreamsLegacy); ok {
+				log.Printf("WARNING: %s is deprecated, use %s\n", grpcMaxConcurrentStreamsLegacy, GRPC_MAX_CONCURRENT_STREAMS)
+			}
+		}
 		if !ok {
-			continue
+			if envVal, ok = fileValues[actualEnvKey]; !ok {
+				continue
+			}
+			source = SourceFile
 		}
+		setSource(actualEnvKey, source)
 
 		elem := field.Elem() // Field Value
 
@@ -177,13 +549,261 @@ func (e *EnvHandler) LoadENVs() {
 				log.Fatalf("Invalid uint for %s", actualEnvKey)
 			}
 
+		case reflect.Float64:
+			if f, err := strconv.ParseFloat(envVal, 64); err == nil {
+				elem.SetFloat(f)
+			} else {
+				log.Fatalf("Invalid float for %s", actualEnvKey)
+			}
+
 		default:
 			log.Fatalf("Unsupported type for %s", actualEnvKey)
 		}
 	}
 
+	if *e.FSYNC != FSYNC_INTERVAL && *e.FSYNC != FSYNC_ALWAYS {
+		log.Fatalf("Invalid fsync policy %s for %s", *e.FSYNC, FSYNC)
+	}
+
+	if *e.OVERSIZE_POLICY != OVERSIZE_REJECT && *e.OVERSIZE_POLICY != OVERSIZE_TRUNCATE {
+		log.Fatalf("Invalid oversize policy %s for %s", *e.OVERSIZE_POLICY, OVERSIZE_POLICY)
+	}
+
+	if *e.TTL_POLICY != TTL_CLAMP && *e.TTL_POLICY != TTL_REJECT {
+		log.Fatalf("Invalid ttl policy %s for %s", *e.TTL_POLICY, TTL_POLICY)
+	}
+
+	// the shard of an entry is its hash masked with TTL_SHARDS-1
+	if n := *e.TTL_SHARDS; n < 0 || n&(n-1) != 0 {
+		log.Fatalf("Invalid %s %d: must be a power of two or 0", TTL_SHARDS, n)
+	}
+
+	if *e.BATCH_MAX_OPS < 1 {
+		log.Fatalf("Invalid %s %d: must be at least 1", BATCH_MAX_OPS, *e.BATCH_MAX_OPS)
+	}
+
+	if *e.REPLAY_BUFFER < 1 {
+		log.Fatalf("Invalid %s %d: must be at least 1", REPLAY_BUFFER, *e.REPLAY_BUFFER)
+	}
+
+	if *e.CHAIN_SAMPLE_INTERVAL < 0 {
+		log.Fatalf("Invalid %s %d: must not be negative", CHAIN_SAMPLE_INTERVAL, *e.CHAIN_SAMPLE_INTERVAL)
+	}
+
+	if *e.CHAIN_SAMPLE_SIZE < 1 {
+		log.Fatalf("Invalid %s %d: must be at least 1", CHAIN_SAMPLE_SIZE, *e.CHAIN_SAMPLE_SIZE)
+	}
+
+	if *e.CHANGELOG_MAX_BYTES < 1 {
+		log.Fatalf("Invalid %s %d: must be at least 1", CHANGELOG_MAX_BYTES, *e.CHANGELOG_MAX_BYTES)
+	}
+
+	if *e.CHANGELOG_RETENTION < 0 {
+		log.Fatalf("Invalid %s %d: must not be negative", CHANGELOG_RETENTION, *e.CHANGELOG_RETENTION)
+	}
+
+	if *e.MAX_TTL_SECONDS < 0 {
+		log.Fatalf("Invalid %s %d: must not be negative", MAX_TTL_SECONDS, *e.MAX_TTL_SECONDS)
+	}
+
+	if *e.KEY_MISS_STATUS != 404 && *e.KEY_MISS_STATUS != 200 {
+		log.Fatalf("Invalid %s %d: must be 404 or 200", KEY_MISS_STATUS, *e.KEY_MISS_STATUS)
+	}
+
+	if _, err := parseMode(*e.FILE_MODE, 0600); err != nil {
+		log.Fatalf("Invalid %s %q: %v", FILE_MODE, *e.FILE_MODE, err)
+	}
+
+	if _, err := parseMode(*e.DIR_MODE, 0700); err != nil {
+		log.Fatalf("Invalid %s %q: %v", DIR_MODE, *e.DIR_MODE, err)
+	}
+
+	if *e.SUB_BUFFER < 1 {
+		log.Fatalf("Invalid %s %d: must be at least 1", SUB_BUFFER, *e.SUB_BUFFER)
+	}
+
+	if *e.SUB_OVERFLOW != SUB_DROP_OLDEST && *e.SUB_OVERFLOW != SUB_DISCONNECT {
+		log.Fatalf("Invalid subscriber overflow policy %s for %s", *e.SUB_OVERFLOW, SUB_OVERFLOW)
+	}
+
 	// warn the user when there is APIKey false
 	if !*e.APIKEY_ENABLED {
 		log.Println("WARNING: APIKEY_ENABLED is false, all requests will be accepted without authentication!")
 	}
 }
+
+// HotReloadable are the settings ReloadENVs applies to the running server - all others need a restart
+var HotReloadable = []string{
+	REQ_LIMIT, GRPC_REQ_LIMIT, REQUEST_WAIT_MS,
+	AUTH_LOCKOUT, AUTH_LOCKOUT_WINDOW, AUTH_LOCKOUT_COOLDOWN,
+	MAX_ENTRIES, IDEMPOTENCY_TTL, FSYNC, OVERSIZE_POLICY,
+	MAX_TTL_SECONDS, TTL_POLICY,
+}
+
+// ReloadENVs applies the HotReloadable settings of values - the content of HKV_ENV_FILE - and returns the names
+// of the changed ones. The settings are swapped under the write lock of reloadMu, the running server reads them
+// through their accessors, e.g. ReqLimit. Invalid values are logged and the current value is kept - a typo must not
+// stop the server. Settings overridden at runtime by Override are kept.
+func (e *EnvHandler) ReloadENVs(values map[string]string) []string {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	fileValues := make(map[string]string, len(values))
+	for key, val := range values {
+		if overridden[key] {
+			log.Printf("Reload: %s was overridden at runtime - keeping it\n", key)
+			continue
+		}
+		fileValues[key] = val
+	}
+	return e.applyReloadable(fileValues, SourceEnvFile)
+}
+
+// Override applies the HotReloadable settings of values like ReloadENVs, but a later reload of HKV_ENV_FILE keeps
+// them - for settings changed at runtime by an admin. The overrides last until the restart.
+func (e *EnvHandler) Override(values map[string]string) []string {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	changed := e.applyReloadable(values, SourceAdmin)
+	for key := range values {
+		if slices.Contains(HotReloadable, key) {
+			overridden[key] = true
+		}
+	}
+	return changed
+}
+
+// applyReloadable sets the HotReloadable settings of values and records source for the changed ones
+func (e *EnvHandler) applyReloadable(values map[string]string, source string) []string {
+	ints := map[string]**int{
+		REQ_LIMIT:             &e.REQ_LIMIT,
+		GRPC_REQ_LIMIT:        &e.GRPC_REQ_LIMIT,
+		REQUEST_WAIT_MS:       &e.REQUEST_WAIT_MS,
+		AUTH_LOCKOUT:          &e.AUTH_LOCKOUT,
+		AUTH_LOCKOUT_WINDOW:   &e.AUTH_LOCKOUT_WINDOW,
+		AUTH_LOCKOUT_COOLDOWN: &e.AUTH_LOCKOUT_COOLDOWN,
+		MAX_ENTRIES:           &e.MAX_ENTRIES,
+		IDEMPOTENCY_TTL:       &e.IDEMPOTENCY_TTL,
+		MAX_TTL_SECONDS:       &e.MAX_TTL_SECONDS,
+	}
+	strs := map[string]**string{
+		FSYNC:           &e.FSYNC,
+		OVERSIZE_POLICY: &e.OVERSIZE_POLICY,
+		TTL_POLICY:      &e.TTL_POLICY,
+	}
+
+	for key := range values {
+		if !slices.Contains(HotReloadable, key) {
+			log.Printf("Reload: %s is not hot-reloadable - restart to apply it\n", key)
+		}
+	}
+
+	changed := make([]string, 0)
+	for _, key := range HotReloadable {
+		envVal, ok := values[key]
+		if !ok {
+			continue
+		}
+
+		if p, ok := ints[key]; ok {
+			i, err := strconv.Atoi(envVal)
+			if err != nil || i < 0 || (i == 0 && (key == REQ_LIMIT || key == GRPC_REQ_LIMIT)) {
+				log.Printf("Reload: invalid int %q for %s - keeping %d\n", envVal, key, **p)
+				continue
+			}
+			if i != **p {
+				*p = &i
+				changed = append(changed, key)
+				setSource(key, source)
+			}
+			continue
+		}
+
+		p := strs[key]
+		if (key == FSYNC && envVal != FSYNC_INTERVAL && envVal != FSYNC_ALWAYS) ||
+			(key == OVERSIZE_POLICY && envVal != OVERSIZE_REJECT && envVal != OVERSIZE_TRUNCATE) ||
+			(key == TTL_POLICY && envVal != TTL_CLAMP && envVal != TTL_REJECT) {
+			log.Printf("Reload: invalid policy %q for %s - keeping %s\n", envVal, key, **p)
+			continue
+		}
+		if envVal != **p {
+			*p = &envVal
+			changed = append(changed, key)
+			setSource(key, source)
+		}
+	}
+	return changed
+}
+
+// reloadMu serializes the reloads and overrides - both swap the pointers of the HotReloadable settings, which are
+// read under its read lock by their accessors
+var reloadMu sync.RWMutex
+
+// load reads a HotReloadable setting - the accessors below are the only readers while the server runs
+func load[T any](p **T) T {
+	reloadMu.RLock()
+	defer reloadMu.RUnlock()
+	return **p
+}
+
+// ReqLimit returns HKV_REQUEST_LIMIT
+func (e *EnvHandler) ReqLimit() int { return load(&e.REQ_LIMIT) }
+
+// GRPCReqLimit returns HKV_GRPC_REQUEST_LIMIT
+func (e *EnvHandler) GRPCReqLimit() int { return load(&e.GRPC_REQ_LIMIT) }
+
+// RequestWaitMs returns HKV_REQUEST_WAIT_MS
+func (e *EnvHandler) RequestWaitMs() int { return load(&e.REQUEST_WAIT_MS) }
+
+// AuthLockout returns HKV_AUTH_LOCKOUT
+func (e *EnvHandler) AuthLockout() int { return load(&e.AUTH_LOCKOUT) }
+
+// AuthLockoutWindow returns HKV_AUTH_LOCKOUT_WINDOW
+func (e *EnvHandler) AuthLockoutWindow() int { return load(&e.AUTH_LOCKOUT_WINDOW) }
+
+// AuthLockoutCooldown returns HKV_AUTH_LOCKOUT_COOLDOWN
+func (e *EnvHandler) AuthLockoutCooldown() int { return load(&e.AUTH_LOCKOUT_COOLDOWN) }
+
+// MaxEntries returns HKV_MAX_ENTRIES
+func (e *EnvHandler) MaxEntries() int { return load(&e.MAX_ENTRIES) }
+
+// IdempotencyTTL returns HKV_IDEMPOTENCY_TTL
+func (e *EnvHandler) IdempotencyTTL() int { return load(&e.IDEMPOTENCY_TTL) }
+
+// MaxTTLSeconds returns HKV_MAX_TTL_SECONDS
+func (e *EnvHandler) MaxTTLSeconds() int { return load(&e.MAX_TTL_SECONDS) }
+
+// Fsync returns HKV_FSYNC
+func (e *EnvHandler) Fsync() string { return load(&e.FSYNC) }
+
+// OversizePolicy returns HKV_OVERSIZE_POLICY
+func (e *EnvHandler) OversizePolicy() string { return load(&e.OVERSIZE_POLICY) }
+
+// TTLPolicy returns HKV_TTL_POLICY
+func (e *EnvHandler) TTLPolicy() string { return load(&e.TTL_POLICY) }
+
+// overridden are the settings set by Override, guarded by reloadMu
+var overridden = make(map[string]bool)
+
+// ReadEnvFile reads a file of KEY=value lines. Empty lines and lines starting with # are skipped.
+func ReadEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: missing '='", path, n+1)
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return values, nil
+}
@@ -0,0 +1,76 @@
+package envhandler
+
+import (
+	"reflect"
+	"sync"
+)
+
+// sources of a setting reported by Settings
+const (
+	SourceDefault = "default"
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	// SourceEnvFile is a hot-reloadable setting changed by HKV_ENV_FILE
+	SourceEnvFile = "env_file"
+	// SourceAdmin is a hot-reloadable setting overridden at runtime, e.g. by /admin/ratelimit
+	SourceAdmin = "admin"
+)
+
+// redacted replaces the value of a secret setting
+const redacted = "[redacted]"
+
+// sources records where the settings which are no default came from - a reload changes it while the server runs
+var sources = struct {
+	sync.RWMutex
+	m map[string]string
+}{m: make(map[string]string)}
+
+func setSource(name, source string) {
+	sources.Lock()
+	defer sources.Unlock()
+	sources.m[name] = source
+}
+
+func clearSources() {
+	sources.Lock()
+	defer sources.Unlock()
+	clear(sources.m)
+}
+
+// Setting is the effective value of a setting and where it came from
+type Setting struct {
+	Name   string `json:"name"`
+	Value  any    `json:"value"`
+	Source string `json:"source"`
+}
+
+// Settings returns the effective settings in the order of the EnvHandler fields. The values of fields tagged
+// secret are redacted - an empty value is kept, so it shows whether the secret is set.
+func (e *EnvHandler) Settings() []Setting {
+	reloadMu.RLock()
+	defer reloadMu.RUnlock()
+	sources.RLock()
+	defer sources.RUnlock()
+
+	v := reflect.ValueOf(e).Elem()
+	t := v.Type()
+	settings := make([]Setting, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		name := envName(t.Field(i).Tag.Get("env"))
+		if name == "" || v.Field(i).IsNil() {
+			continue
+		}
+
+		value := v.Field(i).Elem().Interface()
+		if t.Field(i).Tag.Get("secret") == "true" && !v.Field(i).Elem().IsZero() {
+			value = redacted
+		}
+		source, ok := sources.m[name]
+		if !ok {
+			source = SourceDefault
+		}
+		settings = append(settings, Setting{Name: name, Value: value, Source: source})
+	}
+	return settings
+}
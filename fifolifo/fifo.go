@@ -1,12 +1,26 @@
 package fifolifo
 
 import (
+	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"sync/atomic"
 )
 
+var (
+	// ErrEmpty is returned when popping from an empty queue
+	ErrEmpty = errors.New("queue is empty")
+	// ErrFull is returned when pushing to a queue holding maxEntries entries
+	ErrFull = errors.New("queue is full")
+	// ErrEmptyEntry is returned when pushing an empty entry
+	ErrEmptyEntry = errors.New("entry cannot be empty")
+	// ErrNotFound is returned for a queue which does not exist
+	ErrNotFound = errors.New("queue does not exist")
+)
+
 // A FIFO queue
 type FifoLifo struct {
 	elements    *Element
@@ -15,6 +29,8 @@ type FifoLifo struct {
 	maxEntries  int
 	length      atomic.Int32
 	lastElement *Element
+	// waiters are the blocked pops waiting for an entry, the oldest first
+	waiters []chan string
 }
 
 // An Element of the queue
@@ -40,8 +56,11 @@ func NewFiFoLiFo(name string, maxEntries int) (*FifoLifo, error) {
 
 // FPush an entry to the queue
 func (f *FifoLifo) Push(entry string) (bool, error) {
-	if entry == "" || f.length.Load() >= int32(f.maxEntries) {
-		return false, fmt.Errorf("entry cannot be empty or queue is full, maxEntries: %d, length: %d", f.maxEntries, f.length.Load())
+	if entry == "" {
+		return false, ErrEmptyEntry
+	}
+	if f.length.Load() >= int32(f.maxEntries) {
+		return false, fmt.Errorf("%w, maxEntries: %d", ErrFull, f.maxEntries)
 	}
 
 	// get Pseudo UUID
@@ -53,16 +72,26 @@ func (f *FifoLifo) Push(entry string) (bool, error) {
 	f.mut.Lock()
 	defer f.mut.Unlock()
 
+	f.push(b, entry)
+	return true, nil
+}
+
+// push appends an entry - must be called with the lock held. If pops are blocked the oldest one gets the entry
+// directly instead.
+func (f *FifoLifo) push(id [16]byte, entry string) {
+	if f.handOver(entry) {
+		return
+	}
 	if f.lastElement == nil {
 		f.elements = &Element{
-			id:    b,
+			id:    id,
 			entry: entry,
 			next:  nil,
 		}
 		f.lastElement = f.elements
 	} else {
 		elem := &Element{
-			id:    b,
+			id:    id,
 			entry: entry,
 			next:  nil,
 		}
@@ -71,16 +100,22 @@ func (f *FifoLifo) Push(entry string) (bool, error) {
 		f.lastElement = elem
 	}
 	f.length.Add(1)
-	return true, nil
 }
 
 // FPop an entry from the FIFO queue
 func (f *FifoLifo) FPop() (string, error) {
-	if f.length.Load() == 0 {
-		return "", fmt.Errorf("queue is empty")
-	}
 	f.mut.Lock()
 	defer f.mut.Unlock()
+
+	// checked under the lock, since a concurrent pop or move may have taken the last entry
+	if f.length.Load() == 0 {
+		return "", ErrEmpty
+	}
+	return f.fpop(), nil
+}
+
+// fpop removes the oldest entry - must be called with the lock held on a non-empty queue
+func (f *FifoLifo) fpop() string {
 	data := f.elements.entry
 	f.elements = f.elements.next
 
@@ -91,16 +126,71 @@ func (f *FifoLifo) FPop() (string, error) {
 		f.elements.before = nil
 	}
 	f.length.Add(-1)
-	return data, nil
+	return data
+}
+
+// handOver passes the entry to the oldest blocked pop - must be called with the lock held. Returns false if no pop
+// is waiting.
+func (f *FifoLifo) handOver(entry string) bool {
+	if len(f.waiters) == 0 {
+		return false
+	}
+	w := f.waiters[0]
+	f.waiters = f.waiters[1:]
+	w <- entry
+	return true
+}
+
+// BFPop pops an entry from the FIFO queue and waits for a push if the queue is empty. Blocked pops are served in
+// the order they started waiting. Returns the error of the context if it is done before an entry arrives.
+func (f *FifoLifo) BFPop(ctx context.Context) (string, error) {
+	f.mut.Lock()
+	if f.length.Load() > 0 {
+		data := f.fpop()
+		f.mut.Unlock()
+		return data, nil
+	}
+	w := make(chan string, 1)
+	f.waiters = append(f.waiters, w)
+	f.mut.Unlock()
+
+	select {
+	case data := <-w:
+		return data, nil
+	case <-ctx.Done():
+	}
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if i := slices.Index(f.waiters, w); i >= 0 {
+		f.waiters = slices.Delete(f.waiters, i, i+1)
+		return "", ctx.Err()
+	}
+
+	// the entry was handed over while the context ended - it goes back to the head, so it is not lost
+	data := <-w
+	if !f.handOver(data) {
+		id, _ := f.PseudoUUID()
+		f.elements = &Element{id: id, entry: data, next: f.elements}
+		if f.elements.next == nil {
+			f.lastElement = f.elements
+		} else {
+			f.elements.next.before = f.elements
+		}
+		f.length.Add(1)
+	}
+	return "", ctx.Err()
 }
 
 // LPop an entry from the LIFO queue
 func (f *FifoLifo) LPop() (string, error) {
-	if f.length.Load() == 0 {
-		return "", fmt.Errorf("queue is empty")
-	}
 	f.mut.Lock()
 	defer f.mut.Unlock()
+
+	// checked under the lock, since a concurrent pop or move may have taken the last entry
+	if f.length.Load() == 0 {
+		return "", ErrEmpty
+	}
 	data := f.lastElement.entry
 	f.lastElement = f.lastElement.before
 
@@ -114,6 +204,39 @@ func (f *FifoLifo) LPop() (string, error) {
 	return data, nil
 }
 
+// Move pops the oldest entry of src and pushes it to dst as one step, so the entry is always in one of the queues.
+// Both queues are locked in the order of their names to avoid deadlocks with a concurrent move in the other direction.
+func Move(src, dst *FifoLifo) (string, error) {
+	// get Pseudo UUID
+	b, err := dst.PseudoUUID()
+	if err != nil {
+		return "", err
+	}
+
+	first, second := src, dst
+	if dst.name < src.name {
+		first, second = dst, src
+	}
+	first.mut.Lock()
+	defer first.mut.Unlock()
+	if second != first {
+		second.mut.Lock()
+		defer second.mut.Unlock()
+	}
+
+	if src.length.Load() == 0 {
+		return "", ErrEmpty
+	}
+	// a move within one queue rotates it and never overflows
+	if dst != src && dst.length.Load() >= int32(dst.maxEntries) {
+		return "", fmt.Errorf("%w, maxEntries: %d", ErrFull, dst.maxEntries)
+	}
+
+	data := src.fpop()
+	dst.push(b, data)
+	return data, nil
+}
+
 // Len returns the length of the queue
 func (f *FifoLifo) Len() int {
 	return int(f.length.Load())
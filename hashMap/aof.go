@@ -2,14 +2,24 @@ package hashMap
 
 import (
 	"bufio"
+	"crypto/rand"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"hydrakv/envhandler"
+	"hydrakv/utils"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 type Data struct {
@@ -17,43 +27,207 @@ type Data struct {
 	Key    string
 	Value  string
 	Ttl    int64
+	// ack is closed once the frame is fsynced - only set under the always fsync policy
+	ack chan struct{}
+	// expired marks the del of an expired key - it is written as del, only the changelog tells it apart
+	expired bool
+}
+
+// packValue packs several strings into the value of a frame, e.g. the field and the value of a hash. All parts but
+// the last are prefixed with their length as uvarint.
+func packValue(parts ...string) string {
+	var buf []byte
+	for i, part := range parts {
+		if i < len(parts)-1 {
+			buf = binary.AppendUvarint(buf, uint64(len(part)))
+		}
+		buf = append(buf, part...)
+	}
+	return string(buf)
+}
+
+// unpackValue unpacks n strings packed by packValue - false if the value is malformed
+func unpackValue(value string, n int) ([]string, bool) {
+	parts := make([]string, 0, n)
+	for i := 0; i < n-1; i++ {
+		size, read := binary.Uvarint([]byte(value[:min(len(value), binary.MaxVarintLen64)]))
+		if read <= 0 || uint64(len(value)-read) < size {
+			return nil, false
+		}
+		parts = append(parts, value[read:read+int(size)])
+		value = value[read+int(size):]
+	}
+	return append(parts, value), true
 }
 
 type AOFEntry struct {
-	Key   string
-	Value string
-	Ttl   int64
+	Action string
+	Key    string
+	Value  string
+	Ttl    int64
 }
 
+// Metrics for Prometheus in AOF
+var (
+	// Gauge for the size of the AOF file on disk
+	kvAofSize = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kv_aof_size_bytes",
+			Help: "Current size of the AOF file in bytes",
+		},
+		[]string{"db"},
+	)
+
+	// Gauge for the bytes written to the AOF since the last compaction
+	kvAofBytesSinceCompaction = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kv_aof_bytes_since_compaction",
+			Help: "Bytes written to the AOF file since the last compaction",
+		},
+		[]string{"db"},
+	)
+)
+
 type AOF struct {
+	name        string
 	com         chan Data
 	quit        chan bool
-	compressing chan struct{}
+	compressing chan chan struct{}
+	pausing     chan chan struct{}
+	syncing     chan chan error
 	FileName    string
 	file        *bufio.Writer
 	iofile      *os.File
 	readBuf     []byte
-	aeCB        func() []*AOFEntry
+	sizeBuf     [4]byte
+	aeCB        func(emit func(*AOFEntry) error) error
+	written     atomic.Int64
+	baseSize    atomic.Int64
+	heartbeat   atomic.Int64
+	errMu       sync.Mutex
+	err         error
+	// version is the format version of the file - set by readHeader, new and compacted files get aofVersion
+	version uint16
+	// seed is the hash seed of the DB - drawn for a new DB, read from the header of a version 2 file
+	seed uint64
+	// throttled is set while the queue of the loop is backed up - see Throttled
+	throttled atomic.Bool
+	// changes is the changelog of the DB - nil if it is disabled
+	changes atomic.Pointer[Changelog]
+	// subs receive the changes written by the loop - they follow the name of the DB like the changelog
+	subs atomic.Pointer[subscribers]
+	// started is set once the loop runs - from then on only the loop uses iofile, a compaction replaces it
+	started atomic.Bool
+	// spill holds the frames written while the channel is full in their order, the loop writes them after the channel.
+	// spilled is set while it holds frames, spillReady wakes up the loop.
+	spillMu    sync.Mutex
+	spill      []Data
+	spilled    atomic.Bool
+	spillReady chan struct{}
+}
+
+const (
+	// frameOverhead is the size of the length prefixes and the TTL of a frame
+	frameOverhead = 4 + 4 + 4 + 8
+	// minGrowthCompactSize is the AOF size below which growth never triggers a compaction
+	minGrowthCompactSize = 1024 * 1024
+	// maxGroupCommit is the maximum number of frames written with a single fsync
+	maxGroupCommit = 4096
+	// aofStallTimeout is the time after which a loop without heartbeat is considered dead
+	aofStallTimeout = 30 * time.Second
+	// readBufMin is the first size of the buffer of the replay for the fields of a frame
+	readBufMin = 4096
+	// readBufMax is the largest buffer of the replay kept for the next frame - a larger value gets its own
+	readBufMax = 64 * 1024
+)
+
+// The AOF starts with a header of aofMagic and the format version as uint16. Files without it are version 0 -
+// a version 0 file can't start with the magic, since its first uint32 would be an action length of over 1GB.
+const (
+	aofMagic      = "HKVAOF"
+	aofHeaderSize = len(aofMagic) + 2
+	// aofSeedSize is the size of the hash seed behind the version of a version 2 header
+	aofSeedSize = 8
+	// aofVersion0 is the headerless format of the first releases
+	aofVersion0 = 0
+	// aofVersion1 has the same frames as version 0 behind the header
+	aofVersion1 = 1
+	// aofVersion2 has the hash seed of the DB as uint64 behind the version, the frames are those of version 1
+	aofVersion2 = 2
+	// aofVersion is the version of new and compacted files
+	aofVersion = aofVersion2
+)
+
+// appendHeader appends the header of the current format with the hash seed to buf
+func appendHeader(buf []byte, seed uint64) []byte {
+	buf = append(buf, aofMagic...)
+	buf = binary.BigEndian.AppendUint16(buf, aofVersion)
+	return binary.BigEndian.AppendUint64(buf, seed)
+}
+
+// newSeed draws the hash seed of a new DB. The keys are placed in the baskets by their seeded hash - a random seed
+// per DB keeps keys crafted to collide in one DB from colliding in another one.
+func newSeed() uint64 {
+	var b [aofSeedSize]byte
+	_, _ = rand.Read(b[:])
+	return binary.BigEndian.Uint64(b[:])
+}
+
+// hashSeed returns the seed the keys of the DB are hashed with - HKV_XXHASH_SEED is mixed in, so the seed in the
+// file alone does not reveal it
+func (a *AOF) hashSeed() uint64 {
+	return a.seed ^ *envhandler.ENV.XXHASH_SEED
+}
+
+// readHeader detects the format version of the file behind r and skips its header. Headerless files are version 0.
+func (a *AOF) readHeader(r *bufio.Reader) error {
+	head, err := r.Peek(aofHeaderSize)
+	if err != nil || string(head[:len(aofMagic)]) != aofMagic {
+		// too short for a header or a frame of version 0 - readFrame reports a truncated file
+		a.version = aofVersion0
+		return nil
+	}
+
+	version := binary.BigEndian.Uint16(head[len(aofMagic):])
+	if version > aofVersion {
+		return fmt.Errorf("AOF %s has version %d, this build reads up to version %d", a.FileName, version, aofVersion)
+	}
+	a.version = version
+	if _, err := r.Discard(aofHeaderSize); err != nil {
+		return err
+	}
+	if version < aofVersion2 {
+		// older files keep the drawn seed - the next compaction writes it to the header
+		return nil
+	}
+
+	seed, err := r.Peek(aofSeedSize)
+	if err != nil {
+		return fmt.Errorf("AOF %s has a truncated header: %w", a.FileName, io.ErrUnexpectedEOF)
+	}
+	a.seed = binary.BigEndian.Uint64(seed)
+	_, err = r.Discard(aofSeedSize)
+	return err
 }
 
 // NewAOF creates a new AOF
-func NewAOF(file string, cbFunc func() []*AOFEntry) (*AOF, error) {
+func NewAOF(name string, cbFunc func(emit func(*AOFEntry) error) error) (*AOF, error) {
 	// first check if the Aof dir exists - if not create it
-	if _, err := os.Stat(*envhandler.ENV.DB_FOLDER); err != nil {
-		// dir does not exist - create it
-		err := os.Mkdir(*envhandler.ENV.DB_FOLDER, 0755)
-		if err != nil {
-			return nil, err
-		}
+	if err := utils.U.EnsureDir(*envhandler.ENV.DB_FOLDER, envhandler.DB_FOLDER); err != nil {
+		return nil, err
 	}
 
 	// the file is .Aof/file.bin
-	file = *envhandler.ENV.DB_FOLDER + "/" + file + ".bin"
+	file := *envhandler.ENV.DB_FOLDER + "/" + name + ".bin"
 
 	// creat ethe AOF structure
 	aof := &AOF{
-		com: make(chan Data, 100000), quit: make(chan bool), FileName: file, compressing: make(chan struct{}), aeCB: cbFunc,
+		name: utils.U.DbKey(name),
+		com:  make(chan Data, 100000), quit: make(chan bool), FileName: file, compressing: make(chan chan struct{}), aeCB: cbFunc,
+		pausing: make(chan chan struct{}), syncing: make(chan chan error), seed: newSeed(),
+		spillReady: make(chan struct{}, 1),
 	}
+	aof.subs.Store(&subscribers{name: aof.name})
 
 	// Create the structure
 	return aof, nil
@@ -62,18 +236,103 @@ func NewAOF(file string, cbFunc func() []*AOFEntry) (*AOF, error) {
 // Start starts the AOF loop
 func (a *AOF) Start() error {
 	// open the file in binary mode
-	f, err := os.OpenFile(a.FileName, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	f, err := os.OpenFile(a.FileName, os.O_RDWR|os.O_CREATE|os.O_APPEND, envhandler.ENV.FileMode())
 	if err != nil {
 		return err
 	}
 	a.iofile = f
 	a.file = bufio.NewWriterSize(f, 1024*64)
 
+	// a new file gets the header of the current format - old files keep theirs until the next compaction
+	if stat, err := f.Stat(); err == nil && stat.Size() == 0 {
+		if _, err := f.Write(appendHeader(nil, a.seed)); err != nil {
+			return err
+		}
+		a.version = aofVersion
+
+		// the file and its directory entry are made durable, else a crash could lose the whole new DB
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		if err := syncDir(filepath.Dir(a.FileName)); err != nil {
+			return err
+		}
+	}
+
+	// the size at start is the base for the growth of the file
+	if stat, err := f.Stat(); err == nil {
+		a.baseSize.Store(stat.Size())
+	}
+	a.updateMetrics()
+
+	// the changelog is enabled by its file - a broken one does not keep the DB from loading
+	if err := a.openExistingChangelog(); err != nil {
+		log.Printf("Error opening changelog of %s: %v", a.name, err)
+	}
+
 	// start the loop
+	a.beat()
+	a.started.Store(true)
 	go a.Loop()
 	return nil
 }
 
+// write enqueues a frame for the AOF loop without blocking. The writers call it under the locks of their operation,
+// so the AOF has the order of the memory and a compaction finds the frames of the entries it walks queued - a full
+// channel must not block them while the compaction waits for the global lock, so the frames spill into a list the
+// loop writes after the channel. Under the always fsync policy the returned channel is closed once the frame is on
+// disk - the caller waits for it after releasing its locks.
+func (a *AOF) write(d Data) chan struct{} {
+	if envhandler.ENV.Fsync() == envhandler.FSYNC_ALWAYS {
+		d.ack = make(chan struct{})
+	}
+	// once a frame spilled the following ones spill as well - through the channel they would overtake it
+	if !a.spilled.Load() {
+		select {
+		case a.com <- d:
+			return d.ack
+		default:
+		}
+	}
+	a.spillMu.Lock()
+	a.spill = append(a.spill, d)
+	a.spilled.Store(true)
+	a.spillMu.Unlock()
+	select {
+	case a.spillReady <- struct{}{}:
+	default:
+	}
+	return d.ack
+}
+
+// takeSpill returns the spilled frames and empties the list
+func (a *AOF) takeSpill() []Data {
+	a.spillMu.Lock()
+	defer a.spillMu.Unlock()
+	spill := a.spill
+	a.spill = nil
+	a.spilled.Store(false)
+	return spill
+}
+
+// pendingFrame is the AOF frame of an operation. It is enqueued under the locks of the operation, waiting for its
+// fsync under the always policy is deferred until they are released.
+type pendingFrame struct {
+	ack chan struct{}
+}
+
+// add enqueues the frame - it never blocks, see write
+func (p *pendingFrame) add(a *AOF, d Data) {
+	p.ack = a.write(d)
+}
+
+// wait blocks until the frame is on disk under the always fsync policy - without a frame or policy it returns at once
+func (p *pendingFrame) wait() {
+	if p.ack != nil {
+		<-p.ack
+	}
+}
+
 // writeFrame, writes a GOB frame to the file
 func (a *AOF) writeFrame(data Data) error {
 	// Write Action
@@ -114,83 +373,260 @@ func (a *AOF) writeFrame(data Data) error {
 		return err
 	}
 
+	a.written.Add(int64(frameOverhead + len(data.Action) + len(data.Key) + len(data.Value)))
 	return nil
 }
 
-func (a *AOF) readFrame(r io.Reader, data *Data) error {
-	if a.readBuf == nil {
-		a.readBuf = make([]byte, 4096)
+// Compact compacts the AOF file and blocks until the compaction is done
+func (a *AOF) Compact() {
+	done := make(chan struct{})
+	select {
+	case a.compressing <- done:
+		<-done
+	case <-a.quit:
+		// the AOF is already closed
 	}
+}
 
-	var sizeBuf [4]byte
+// Sync writes the frames sent so far to the file, fsyncs it and blocks until they are durable - a barrier, e.g.
+// before a filesystem snapshot, which the periodic flush does not give at a precise moment. An AOF which is not
+// running or already closed has nothing pending and returns nil.
+func (a *AOF) Sync() error {
+	if !a.started.Load() {
+		return nil
+	}
+	done := make(chan error, 1)
+	select {
+	case a.syncing <- done:
+		return <-done
+	case <-a.quit:
+		return nil
+	}
+}
 
-	// Read Action
-	if _, err := io.ReadFull(r, sizeBuf[:]); err != nil {
-		return err
+// pause stops the loop and returns the func which resumes it - the loop flushes the frames written so far, new frames
+// wait in the channel meanwhile. An AOF which is not running returns at once.
+func (a *AOF) pause() (resume func()) {
+	if !a.started.Load() {
+		return func() {}
 	}
-	size := binary.BigEndian.Uint32(sizeBuf[:])
-	if int(size) > len(a.readBuf) {
-		a.readBuf = make([]byte, size)
+	resumed := make(chan struct{})
+	select {
+	case a.pausing <- resumed:
+		return func() { close(resumed) }
+	case <-a.quit:
+		return func() {}
 	}
-	if size > 0 {
-		if _, err := io.ReadFull(r, a.readBuf[:size]); err != nil {
-			return err
-		}
-		data.Action = string(a.readBuf[:size])
-	} else {
-		data.Action = ""
+}
+
+// Size returns the size of the AOF including the frames which are not flushed yet
+func (a *AOF) Size() int64 {
+	return a.baseSize.Load() + a.written.Load()
+}
+
+// FileSize returns the size of the AOF file on disk - buffered frames are not included
+func (a *AOF) FileSize() int64 {
+	stat, err := os.Stat(a.FileName)
+	if err != nil {
+		return 0
 	}
+	return stat.Size()
+}
 
-	// Read Key
-	if _, err := io.ReadFull(r, sizeBuf[:]); err != nil {
-		return err
+// Throttled returns true while the queue of the loop is backed up: it turns on once the queue is filled to
+// HKV_AOF_HIGH_WATER of its capacity and off once it drained to HKV_AOF_LOW_WATER. A high water <= 0 disables it.
+func (a *AOF) Throttled() bool {
+	high := *envhandler.ENV.AOF_HIGH_WATER
+	if high <= 0 {
+		a.throttled.Store(false)
+		return false
 	}
-	size = binary.BigEndian.Uint32(sizeBuf[:])
-	if int(size) > len(a.readBuf) {
-		a.readBuf = make([]byte, size)
+
+	fill := float64(len(a.com)) / float64(cap(a.com))
+	if a.spilled.Load() {
+		fill = 1
 	}
-	if size > 0 {
-		if _, err := io.ReadFull(r, a.readBuf[:size]); err != nil {
-			return err
+	switch {
+	case fill >= high:
+		if !a.throttled.Swap(true) {
+			log.Printf("AOF queue of %s is %.0f%% full - throttling writes", a.name, fill*100)
+		}
+	case fill <= *envhandler.ENV.AOF_LOW_WATER:
+		if a.throttled.Swap(false) {
+			log.Printf("AOF queue of %s drained - accepting writes", a.name)
 		}
-		data.Key = string(a.readBuf[:size])
-	} else {
-		data.Key = ""
 	}
+	return a.throttled.Load()
+}
 
-	// Read Value
-	if _, err := io.ReadFull(r, sizeBuf[:]); err != nil {
-		return err
+// BytesSinceCompaction returns the bytes written to the AOF since the last compaction (or start)
+func (a *AOF) BytesSinceCompaction() int64 {
+	return a.written.Load()
+}
+
+// updateMetrics updates the AOF size gauges
+func (a *AOF) updateMetrics() {
+	kvAofSize.WithLabelValues(a.name).Set(float64(a.FileSize()))
+	kvAofBytesSinceCompaction.WithLabelValues(a.name).Set(float64(a.written.Load()))
+}
+
+// Grown returns true if the AOF has grown to factor times its size after the last compaction (or start).
+// Small files never count as grown, a factor <= 1 disables the check.
+func (a *AOF) Grown(factor float64) bool {
+	if factor <= 1 {
+		return false
 	}
-	size = binary.BigEndian.Uint32(sizeBuf[:])
-	if int(size) > len(a.readBuf) {
-		a.readBuf = make([]byte, size)
+	base := a.baseSize.Load()
+	size := base + a.written.Load()
+	return size >= minGrowthCompactSize && float64(size) >= float64(base)*factor
+}
+
+// readFrame reads the next frame with the parser of the version found by readHeader
+func (a *AOF) readFrame(r io.Reader, data *Data) error {
+	switch a.version {
+	case aofVersion0, aofVersion1, aofVersion2:
+		return a.readFrameV0(r, data)
+	default:
+		return fmt.Errorf("unsupported AOF version %d", a.version)
 	}
-	if size > 0 {
-		if _, err := io.ReadFull(r, a.readBuf[:size]); err != nil {
-			return err
+}
+
+// readFrameV0 reads a frame of length-prefixed action, key and value followed by the TTL
+func (a *AOF) readFrameV0(r io.Reader, data *Data) error {
+	// a buffer grown above readBufMax for a huge value is dropped after its frame - it would stay for the whole replay
+	defer func() {
+		if len(a.readBuf) > readBufMax {
+			a.readBuf = nil
 		}
-		data.Value = string(a.readBuf[:size])
-	} else {
-		data.Value = ""
+	}()
+
+	// Read Action
+	var err error
+	if data.Action, err = a.readField(r); err != nil {
+		return err
+	}
+	// Read Key
+	if data.Key, err = a.readField(r); err != nil {
+		return midFrame(err)
+	}
+	// Read Value
+	if data.Value, err = a.readField(r); err != nil {
+		return midFrame(err)
 	}
 
 	// Read TTL
 	if err := binary.Read(r, binary.BigEndian, &data.Ttl); err != nil {
-		return err
+		return midFrame(err)
 	}
 
 	return nil
 }
 
+// readField reads a length-prefixed field of a frame into readBuf. Only an EOF before the length is returned as
+// io.EOF - one within the field as io.ErrUnexpectedEOF.
+func (a *AOF) readField(r io.Reader) (string, error) {
+	if _, err := io.ReadFull(r, a.sizeBuf[:]); err != nil {
+		return "", err
+	}
+	size := binary.BigEndian.Uint32(a.sizeBuf[:])
+	if size == 0 {
+		return "", nil
+	}
+	if int(size) > len(a.readBuf) {
+		a.readBuf = make([]byte, max(size, readBufMin))
+	}
+	if _, err := io.ReadFull(r, a.readBuf[:size]); err != nil {
+		return "", midFrame(err)
+	}
+	return string(a.readBuf[:size]), nil
+}
+
+// midFrame turns an io.EOF within a frame into io.ErrUnexpectedEOF - only an EOF before a frame is the clean end
+// of the file
+func midFrame(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
 // Close closes the AOF and waits for the loop to finish
 func (a *AOF) Close() error {
+	// the loop was never started - nothing to flush, a failed Start may have left the file open
+	if !a.started.Load() {
+		a.subs.Load().closeAll()
+		if a.iofile != nil {
+			return a.iofile.Close()
+		}
+		return nil
+	}
 	close(a.com)
 	<-a.quit
 	log.Printf("AOF file %s closed", a.FileName)
+	a.subs.Load().closeAll()
+	if c := a.changes.Load(); c != nil {
+		if err := c.close(); err != nil {
+			log.Printf("Error closing changelog of %s: %v", a.name, err)
+		}
+	}
+
+	// the DB is gone - so are its metrics
+	kvAofSize.DeleteLabelValues(a.name)
+	kvAofBytesSinceCompaction.DeleteLabelValues(a.name)
+	kvSubDropped.DeletePartialMatch(prometheus.Labels{"db": a.name})
 	return a.iofile.Close()
 }
 
+// flush writes the buffer to the file and fsyncs it
+func (a *AOF) flush() error {
+	if err := a.file.Flush(); err != nil {
+		log.Println("Error flushing AOF:", err)
+		return err
+	}
+	if err := a.iofile.Sync(); err != nil {
+		log.Println("Error syncing AOF:", err)
+		return err
+	}
+	// the changelog is as durable as the AOF
+	if c := a.changes.Load(); c != nil {
+		if err := c.flush(); err != nil {
+			log.Printf("Error flushing changelog of %s: %v", a.name, err)
+		}
+	}
+	return nil
+}
+
+// beat updates the heartbeat of the loop
+func (a *AOF) beat() {
+	a.heartbeat.Store(time.Now().UnixNano())
+}
+
+// setErr records the last error of the loop - a successful flush clears it
+func (a *AOF) setErr(err error) {
+	a.errMu.Lock()
+	defer a.errMu.Unlock()
+	a.err = err
+}
+
+// Healthy returns an error if the loop is stalled or writing the file failed. An AOF which is not started is healthy.
+func (a *AOF) Healthy() error {
+	if !a.started.Load() {
+		return nil
+	}
+
+	a.errMu.Lock()
+	err := a.err
+	a.errMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("aof write failed: %w", err)
+	}
+
+	if since := time.Since(time.Unix(0, a.heartbeat.Load())); since > aofStallTimeout {
+		return fmt.Errorf("aof loop stalled for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
 // Loop reads the data comming from the channel and writes it to the file
 func (a *AOF) Loop() {
 	ticker := time.NewTicker(100 * time.Millisecond)
@@ -200,126 +636,309 @@ func (a *AOF) Loop() {
 	for {
 		select {
 		case d, ok := <-a.com:
-			if !ok {
-				a.file.Flush()
-				a.iofile.Sync()
-				close(a.quit)
+			if !a.receive(d, ok) {
 				return
 			}
-			err := a.writeFrame(d)
-			if err != nil {
-				log.Println("Error writing to AOF:", err)
+		case <-a.spillReady:
+			if !a.drainQueued() {
+				return
 			}
 		case <-ticker.C:
+			a.beat()
+			if c := a.changes.Load(); c != nil {
+				if err := c.retain(time.Now()); err != nil {
+					log.Printf("Error rotating changelog of %s: %v", a.name, err)
+				}
+			}
 			// flush only when the buffer is filled
 			if a.file.Buffered() > 0 {
-				a.file.Flush()
-				a.iofile.Sync()
+				a.setErr(a.flush())
+				a.updateMetrics()
+			}
+		case done := <-a.compressing:
+			// Data to create a new AOF bin File - this is a callback to HashMap which drains the queued frames
+			// and streams the entries, it blocks writes to the Aof file until the compression is done
+			a.createCompressedAOF(a.aeCB)
+			a.beat()
+			if done != nil {
+				close(done)
+			}
+		case done := <-a.syncing:
+			// the frames sent before the sync may still wait in the channel
+			if !a.drainQueued() {
+				done <- nil
+				return
 			}
-		case <-a.compressing:
-			// Data to create a new AOF bin File - this is a callback to HashMap to get the entries
-			// it blocks writes to the Aof file until the compression is done
-			a.createCompressedAOF(a.aeCB())
+			err := a.flush()
+			a.setErr(err)
+			a.updateMetrics()
+			a.beat()
+			done <- err
+		case resumed := <-a.pausing:
+			// the open file is written on while its name changes, e.g. by SwapDBs
+			a.setErr(a.flush())
+			<-resumed
+			a.beat()
 		}
 	}
 }
 
-// createCompressedAOF creates a new AOF file with compressed entries and replaces
-// the old file in an atomic, crash-safe way.
-func (a *AOF) createCompressedAOF(entries []*AOFEntry) {
-
-	tmpName := strings.Split(a.FileName, ".")[0] + ".tmp.bin"
+// receive writes a frame taken from the channel. Returns false if the channel got closed - the AOF is flushed
+// and quit is closed then.
+func (a *AOF) receive(d Data, ok bool) bool {
+	if !ok {
+		a.closeLoop()
+		return false
+	}
+	if d.ack == nil {
+		a.writeData(d)
+		return true
+	}
+	return a.groupCommit(d)
+}
 
-	// 1. Create temp file
-	tmpFile, err := os.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		log.Println("cannot create compressed AOF file! " + err.Error())
-		return
+// writeData writes the frame of d and records it in the changelog
+func (a *AOF) writeData(d Data) {
+	if err := a.writeFrame(d); err != nil {
+		log.Println("Error writing to AOF:", err)
+		a.setErr(err)
 	}
-	tmpBuf := bufio.NewWriterSize(tmpFile, 1024*1024*16)
+	a.recordChange(d)
+}
+
+// closeLoop writes the frames spilled before the channel got closed, flushes the file and closes quit
+func (a *AOF) closeLoop() {
+	a.drain()
+	a.file.Flush()
+	a.iofile.Sync()
+	close(a.quit)
+}
 
-	// 2. Write all entries to tmp file
-	for _, e := range entries {
+// drainQueued writes the frames waiting in the channel and the spill without blocking. Returns false if the channel
+// got closed.
+func (a *AOF) drainQueued() bool {
+	if !a.drain() {
+		a.closeLoop()
+		return false
+	}
+	return true
+}
 
-		// write action "set"
-		if err := binary.Write(tmpBuf, binary.BigEndian, uint32(len("set"))); err != nil {
-			log.Println("error writing action to tmp AOF! " + err.Error())
-			tmpFile.Close()
-			return
-		}
-		ptr := unsafe.StringData("set")
-		if _, err := tmpBuf.Write(unsafe.Slice(ptr, len("set"))); err != nil {
-			log.Println("error writing action string to tmp AOF! " + err.Error())
-			tmpFile.Close()
-			return
+// drain writes the frames queued so far - the channel first, its frames are older than the spilled ones - and
+// acknowledges them with a single fsync. Returns false once the channel is closed, closing the loop is up to the
+// caller. It runs in the loop only, e.g. by a compaction under the global lock: the walked entries include the
+// changes of the queued frames, appended to the compacted file they would be replayed twice.
+func (a *AOF) drain() bool {
+	var acks []chan struct{}
+	write := func(d Data) {
+		a.writeData(d)
+		if d.ack != nil {
+			acks = append(acks, d.ack)
 		}
-
-		// write key
-		if err := binary.Write(tmpBuf, binary.BigEndian, uint32(len(e.Key))); err != nil {
-			log.Println("error writing key length to tmp AOF! " + err.Error())
-			tmpFile.Close()
+	}
+	defer func() {
+		if len(acks) == 0 {
 			return
 		}
-		ptr = unsafe.StringData(e.Key)
-		if _, err := tmpBuf.Write(unsafe.Slice(ptr, len(e.Key))); err != nil {
-			log.Println("error writing key to tmp AOF! " + err.Error())
-			tmpFile.Close()
-			return
+		a.setErr(a.flush())
+		for _, ack := range acks {
+			close(ack)
 		}
+	}()
 
-		// write value
-		if err := binary.Write(tmpBuf, binary.BigEndian, uint32(len(e.Value))); err != nil {
-			log.Println("error writing value length to tmp AOF! " + err.Error())
-			tmpFile.Close()
-			return
+	for {
+		select {
+		case d, ok := <-a.com:
+			if !ok {
+				// there are no writers anymore - the spilled frames are the last ones
+				for _, d := range a.takeSpill() {
+					write(d)
+				}
+				return false
+			}
+			write(d)
+		default:
+			spill := a.takeSpill()
+			if len(spill) == 0 {
+				return true
+			}
+			for _, d := range spill {
+				write(d)
+			}
 		}
-		ptr = unsafe.StringData(e.Value)
-		if _, err := tmpBuf.Write(unsafe.Slice(ptr, len(e.Value))); err != nil {
-			log.Println("error writing value to tmp AOF! " + err.Error())
-			tmpFile.Close()
-			return
+	}
+}
+
+// groupCommit writes the frame and all frames already waiting in the channel with a single fsync and
+// acknowledges them afterward. The frames keep their order. Returns false if the channel got closed.
+func (a *AOF) groupCommit(first Data) bool {
+	acks := make([]chan struct{}, 0, 16)
+	commit := func(d Data) {
+		a.writeData(d)
+		if d.ack != nil {
+			acks = append(acks, d.ack)
 		}
+	}
+	commit(first)
 
-		// write ttl
-		if err := binary.Write(tmpBuf, binary.BigEndian, e.Ttl); err != nil {
-			log.Println("error writing ttl to tmp AOF! " + err.Error())
-			tmpFile.Close()
-			return
+	// drain the channel without blocking
+	open := true
+drain:
+	for n := 1; n < maxGroupCommit; n++ {
+		select {
+		case d, ok := <-a.com:
+			if !ok {
+				open = false
+				break drain
+			}
+			commit(d)
+		default:
+			break drain
 		}
 	}
 
+	a.setErr(a.flush())
+	for _, ack := range acks {
+		close(ack)
+	}
+
+	if !open {
+		a.closeLoop()
+	}
+	return open
+}
+
+// syncDir fsyncs the directory of the AOF after it was created or replaced - a variable to count the calls in tests
+var syncDir = fsyncDir
+
+// fsyncDir fsyncs the directory dir. POSIX makes a created or renamed file durable with its data only - the directory
+// entry pointing to it is part of the directory and needs an fsync of its own. Without it a crash can leave the
+// directory with the old entry or none, e.g. on ext4 with data=writeback or on XFS.
+func fsyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		_ = d.Close()
+		return err
+	}
+	return d.Close()
+}
+
+// rename replaces the AOF by the compacted file - a variable for fault injection in tests
+var rename = os.Rename
+
+// appendEntry appends the frame of an entry to buf - an entry without action is a set
+func appendEntry(buf []byte, e *AOFEntry) []byte {
+	action := e.Action
+	if action == "" {
+		action = "set"
+	}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(action)))
+	buf = append(buf, action...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(e.Key)))
+	buf = append(buf, e.Key...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(e.Value)))
+	buf = append(buf, e.Value...)
+	return binary.BigEndian.AppendUint64(buf, uint64(e.Ttl))
+}
+
+// createCompressedAOF creates a new AOF file with the entries streamed by walk and replaces the old file in an
+// atomic, crash-safe way. The entries go to the tmp file one by one, so the compaction never holds all of them in
+// memory. If any step fails before the new file is in place, the tmp file is removed and the old file stays open -
+// the writes go on there.
+func (a *AOF) createCompressedAOF(walk func(emit func(*AOFEntry) error) error) {
+
+	tmpName := strings.TrimSuffix(a.FileName, ".bin") + ".tmp.bin"
+
+	// 1. Create temp file
+	tmpFile, err := os.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, envhandler.ENV.FileMode())
+	if err != nil {
+		log.Println("cannot create compressed AOF file! " + err.Error())
+		return
+	}
+	tmpBuf := bufio.NewWriterSize(tmpFile, 1024*1024*16)
+
+	// abort drops the tmp file - the old file was not touched
+	abort := func(msg string, err error) {
+		log.Println(msg + " " + err.Error())
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpName)
+	}
+
+	// the compacted file is written in the current format - this migrates old files
+	if _, err := tmpBuf.Write(appendHeader(nil, a.seed)); err != nil {
+		abort("error writing header to tmp AOF!", err)
+		return
+	}
+
+	// 2. Write all entries to tmp file - one frame buffer is reused for all of them
+	var frame []byte
+	err = walk(func(e *AOFEntry) error {
+		frame = appendEntry(frame[:0], e)
+		_, err := tmpBuf.Write(frame)
+		return err
+	})
+	if err != nil {
+		abort("error writing entries to tmp AOF!", err)
+		return
+	}
+
 	// 3. Flush + fsync tmp file
 	if err := tmpBuf.Flush(); err != nil {
-		log.Println("error flushing tmp AOF buffer! " + err.Error())
-		tmpFile.Close()
+		abort("error flushing tmp AOF buffer!", err)
 		return
 	}
 	if err := tmpFile.Sync(); err != nil {
-		log.Println("error syncing tmp AOF file! " + err.Error())
-		tmpFile.Close()
+		abort("error syncing tmp AOF file!", err)
+		return
+	}
+	// remember the compacted size as new base for the growth
+	var compactedSize int64
+	if stat, err := tmpFile.Stat(); err == nil {
+		compactedSize = stat.Size()
+	}
+	if err := tmpFile.Close(); err != nil {
+		abort("error closing tmp AOF file!", err)
 		return
 	}
-	tmpFile.Close() // safe to close
 
-	// 4. Finish writing to the old file: flush + fsync + close
-	a.file.Flush()
-	a.iofile.Sync()
-	a.iofile.Close()
+	// 4. Finish writing to the old file: flush + fsync. It stays open until the new file is in place.
+	if err := a.flush(); err != nil {
+		a.setErr(err)
+		abort("cannot flush the AOF before the compaction!", err)
+		return
+	}
 
 	// 5. Atomically replace old file with tmp file
 	// rename() is atomic on POSIX systems.
-	if err := os.Rename(tmpName, a.FileName); err != nil {
-		log.Println("cannot atomically rename tmp AOF! " + err.Error())
+	if err := rename(tmpName, a.FileName); err != nil {
+		abort("cannot atomically rename tmp AOF!", err)
 		return
 	}
 
-	// 6. Re-open the new AOF file
-	a.iofile, err = os.OpenFile(a.FileName, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	// the rename is only durable once the directory is fsynced - a crash before could bring back the old file
+	if err := syncDir(filepath.Dir(a.FileName)); err != nil {
+		log.Println("cannot sync the AOF directory after the rename! " + err.Error())
+		a.setErr(fmt.Errorf("sync AOF directory: %w", err))
+	}
+
+	// 6. Re-open the new AOF file - only then the old one is closed
+	iofile, err := os.OpenFile(a.FileName, os.O_RDWR|os.O_CREATE|os.O_APPEND, envhandler.ENV.FileMode())
 	if err != nil {
+		// the old handle points to the replaced file now, writes to it are lost on restart
 		log.Println("cannot reopen new AOF file! " + err.Error())
+		a.setErr(fmt.Errorf("reopen compacted AOF: %w", err))
 		return
 	}
+	_ = a.iofile.Close()
+	a.iofile = iofile
 	a.file = bufio.NewWriterSize(a.iofile, 1024*64)
+	a.version = aofVersion
+	a.baseSize.Store(compactedSize)
+	a.written.Store(0)
+	a.updateMetrics()
 
 	log.Println("Compressed AOF file created")
 }
@@ -1,10 +1,73 @@
 package hashMap
 
+import (
+	"hydrakv/envhandler"
+	"sync/atomic"
+)
+
 type Basket struct {
 	Items *Entry
+	// count is the number of entries in Items, kept by push, unlink and the resize - atomic, as with more basket
+	// locks than baskets the keys of one basket are guarded by different locks
+	count atomic.Int64
 }
 
 // NewBasket returns a new Basket
 func NewBasket() *Basket {
 	return &Basket{}
 }
+
+// lazyBasketSize is the least number of baskets of a HashMap under HKV_LAZY_BASKETS
+const lazyBasketSize = 16
+
+// newTable returns a table of size empty baskets
+func newTable(size int) []*Basket {
+	table := make([]*Basket, size)
+	for i := range table {
+		table[i] = NewBasket()
+	}
+	return table
+}
+
+// initialBasketSize returns the number of baskets of a new or flushed HashMap: DefaultBasketSize, or under
+// HKV_LAZY_BASKETS as few as the basket locks allow - with more locks than baskets the keys of one basket would be
+// written under different locks. The resize grows the table like for any other HashMap.
+func (hm *HashMap) initialBasketSize() int {
+	if !*envhandler.ENV.LAZY_BASKETS {
+		return DefaultBasketSize
+	}
+	return max(lazyBasketSize, hm.basketLockNum)
+}
+
+// find returns the entry of the key and its predecessor in the basket - nil if the key is missing
+func (b *Basket) find(key string) (*Entry, *Entry) {
+	var prev *Entry
+	for item := b.Items; item != nil; item = item.Next {
+		if item.Key == key {
+			return item, prev
+		}
+		prev = item
+	}
+	return nil, nil
+}
+
+// push links e in front of the basket - e.Next must be the old head
+func (b *Basket) push(e *Entry) {
+	b.Items = e
+	b.count.Add(1)
+}
+
+// unlink removes item, found after prev, from the basket
+func (b *Basket) unlink(item, prev *Entry) {
+	if prev != nil {
+		prev.Next = item.Next
+	} else {
+		b.Items = item.Next
+	}
+	b.count.Add(-1)
+}
+
+// Len returns the number of entries in the basket
+func (b *Basket) Len() int64 {
+	return b.count.Load()
+}
@@ -0,0 +1,143 @@
+package hashMap
+
+import (
+	"hydrakv/envhandler"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// kvChainLength is the distribution of the sampled basket chain lengths - long chains mean a degraded hash, e.g.
+// keys forced into the same basket
+var kvChainLength = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "kv_chain_length",
+		Help:    "Length of the entry chains of sampled baskets",
+		Buckets: []float64{0, 1, 2, 3, 4, 6, 8, 12, 16, 32, 64},
+	},
+	[]string{"db"},
+)
+
+// ChainSampler observes the chain lengths of HKV_CHAIN_SAMPLE_SIZE baskets every HKV_CHAIN_SAMPLE_INTERVAL
+// seconds. It returns at once if the metrics or the sampling are disabled, otherwise once the HashMap is closed.
+func (hm *HashMap) ChainSampler() {
+	interval := *envhandler.ENV.CHAIN_SAMPLE_INTERVAL
+	if !*envhandler.ENV.METRICS || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+	// the name is read under the global lock - SwapDBs changes it
+	defer func() {
+		hm.mutex.RLock()
+		kvChainLength.DeleteLabelValues(hm.Name)
+		hm.mutex.RUnlock()
+	}()
+
+	for {
+		select {
+		case <-ticker.C:
+			name, lengths := hm.sampleChains(*envhandler.ENV.CHAIN_SAMPLE_SIZE)
+			observer := kvChainLength.WithLabelValues(name)
+			for _, length := range lengths {
+				observer.Observe(float64(length))
+			}
+		case <-hm.done:
+			return
+		}
+	}
+}
+
+// sampleChains returns the name of the DB and the chain lengths of n consecutive baskets from a random start - all
+// baskets if n exceeds them. The keys are spread by their hash, so a window is as good a sample as random baskets and
+// walks the locks in order.
+func (hm *HashMap) sampleChains(n int) (string, []int) {
+	hm.mutex.RLock()
+	defer hm.mutex.RUnlock()
+
+	size := len(hm.table)
+	n = min(n, size)
+	start := rand.IntN(size)
+	lengths := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		index := (start + i) & (size - 1)
+		unlock := hm.rlockBasket(index)
+		length := 0
+		for item := hm.table[index].Items; item != nil; item = item.Next {
+			length++
+		}
+		unlock()
+		lengths = append(lengths, length)
+	}
+	return hm.Name, lengths
+}
+
+// hotBasketKeys is the number of keys returned per basket by HotBaskets
+const hotBasketKeys = 5
+
+// BasketStat is the entry count of a basket with some of its keys
+type BasketStat struct {
+	Index   int
+	Entries int64
+	Keys    []string
+}
+
+// HotBaskets returns the n baskets holding the most entries, the fullest first, with up to hotBasketKeys of their
+// keys - a basket far above the load factor points to a bad hash or keys forced into it. Only the counts of the
+// baskets are read, the chains of the returned ones are walked for the keys. Empty baskets are left out.
+func (hm *HashMap) HotBaskets(n int) []BasketStat {
+	if n <= 0 {
+		return []BasketStat{}
+	}
+
+	hm.mutex.RLock()
+	defer hm.mutex.RUnlock()
+
+	hot := make([]BasketStat, 0, n+1)
+	for index, basket := range hm.table {
+		entries := basket.Len()
+		if entries == 0 || (len(hot) == n && entries <= hot[n-1].Entries) {
+			continue
+		}
+		// insert sorted, the smallest falls off
+		at := sort.Search(len(hot), func(i int) bool { return hot[i].Entries < entries })
+		hot = append(hot, BasketStat{})
+		copy(hot[at+1:], hot[at:])
+		hot[at] = BasketStat{Index: index, Entries: entries}
+		if len(hot) > n {
+			hot = hot[:n]
+		}
+	}
+
+	for i := range hot {
+		unlock := hm.rlockBasket(hot[i].Index)
+		hot[i].Keys = make([]string, 0, min(hot[i].Entries, hotBasketKeys))
+		for item := hm.table[hot[i].Index].Items; item != nil && len(hot[i].Keys) < hotBasketKeys; item = item.Next {
+			hot[i].Keys = append(hot[i].Keys, item.Key)
+		}
+		unlock()
+	}
+	return hot
+}
+
+// rlockBasket read locks all basket locks covering the basket at index and returns the func which unlocks them.
+// With more locks than baskets the keys of one basket spread over several locks.
+func (hm *HashMap) rlockBasket(index int) func() {
+	size := len(hm.table)
+	if hm.basketLockNum <= size {
+		hm.RLockBasketLock(uint64(index))
+		return func() { hm.RUnlockBasketLock(uint64(index)) }
+	}
+	for i := index; i < hm.basketLockNum; i += size {
+		hm.RLockBasketLock(uint64(i))
+	}
+	return func() {
+		for i := index; i < hm.basketLockNum; i += size {
+			hm.RUnlockBasketLock(uint64(i))
+		}
+	}
+}
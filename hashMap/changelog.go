@@ -0,0 +1,439 @@
+package hashMap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hydrakv/envhandler"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// changelogExt replaces the .bin of the AOF for the changelog - its existence enables the changelog of the DB
+	changelogExt = ".changelog"
+	// changelogRotatedExt is appended to the segment holding the older changes
+	changelogRotatedExt = ".1"
+	// changelogBufferSize is the size of the write buffer, flushed together with the AOF
+	changelogBufferSize = 64 * 1024
+)
+
+// ErrChangelogDisabled is returned for the changes of a DB without changelog
+var ErrChangelogDisabled = errors.New("changelog is disabled")
+
+// Change is a mutation of a DB as recorded by its changelog. For hset Field is the field, for zadd the member and
+// Value its score. A swap has the name of the other DB as Value. A key deleted by its expiry has the action expired.
+type Change struct {
+	Seq         int64  `json:"seq"`
+	Time        string `json:"time"`
+	Action      string `json:"action"`
+	Key         string `json:"key,omitempty"`
+	Field       string `json:"field,omitempty"`
+	Value       string `json:"value,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Ttl         int64  `json:"ttl,omitempty"`
+}
+
+// changeOf returns the change of an AOF frame - packed values are split into their parts
+func changeOf(seq int64, d Data, now time.Time) Change {
+	ch := Change{Seq: seq, Time: now.UTC().Format(time.RFC3339Nano), Action: d.Action, Key: d.Key, Value: d.Value,
+		Ttl: d.Ttl}
+	switch d.Action {
+	case "del":
+		if d.expired {
+			ch.Action = "expired"
+		}
+	case "setct":
+		if parts, ok := unpackValue(d.Value, 2); ok {
+			ch.Action, ch.ContentType, ch.Value = "set", parts[0], parts[1]
+		}
+	case "hset":
+		if parts, ok := unpackValue(d.Value, 2); ok {
+			ch.Field, ch.Value = parts[0], parts[1]
+		}
+	case "zadd":
+		if parts, ok := unpackValue(d.Value, 2); ok {
+			ch.Field, ch.Value = parts[1], parts[0]
+		}
+	}
+	return ch
+}
+
+// Changelog is the durable change feed of a DB: the AOF loop appends every mutation as JSON line with a sequence
+// number. Unlike the AOF it is never compacted - once the file reaches half of HKV_CHANGELOG_MAX_BYTES or its first
+// change is older than HKV_CHANGELOG_RETENTION it is rotated, replacing the segment rotated before.
+type Changelog struct {
+	mu       sync.Mutex
+	fileName string
+	file     *os.File
+	out      *bufio.Writer
+	size     int64
+	// seq is the sequence number of the last change
+	seq int64
+	// first and firstTime describe the first change of the file - first is 0 while it is empty
+	first     int64
+	firstTime time.Time
+	// rotated is true while the rotated segment exists - oldest is its first change
+	rotated   bool
+	rotatedAt time.Time
+	oldest    int64
+}
+
+// openChangelog opens the changelog with the given file name and continues its sequence numbers. A last line cut
+// by a crash is dropped.
+func openChangelog(fileName string) (*Changelog, error) {
+	c := &Changelog{fileName: fileName}
+
+	if info, err := os.Stat(fileName + changelogRotatedExt); err == nil {
+		f, err := os.Open(fileName + changelogRotatedExt)
+		if err != nil {
+			return nil, err
+		}
+		first, _, last, _, err := scanChangelog(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("changelog %s: %w", fileName+changelogRotatedExt, err)
+		}
+		if first != 0 {
+			c.rotated, c.rotatedAt, c.oldest, c.seq = true, info.ModTime(), first, last
+		}
+	}
+
+	f, err := os.OpenFile(fileName, os.O_RDWR|os.O_CREATE|os.O_APPEND, envhandler.ENV.FileMode())
+	if err != nil {
+		return nil, err
+	}
+	first, firstTime, last, end, err := scanChangelog(f)
+	if err == nil {
+		err = f.Truncate(end)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("changelog %s: %w", fileName, err)
+	}
+	if first != 0 {
+		c.first, c.firstTime, c.seq = first, firstTime, last
+	}
+	c.file, c.out, c.size = f, bufio.NewWriterSize(f, changelogBufferSize), end
+	return c, nil
+}
+
+// scanChangelog reads the changes of r and returns the first with its time, the last sequence number and the end of
+// the last complete line
+func scanChangelog(r io.Reader) (first int64, firstTime time.Time, last int64, end int64, err error) {
+	reader := bufio.NewReaderSize(r, changelogBufferSize)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			// EOF - a line without newline was not completely written
+			return first, firstTime, last, end, nil
+		}
+		seq, ok := lineSeq(line)
+		if !ok {
+			return 0, time.Time{}, 0, 0, fmt.Errorf("corrupt change at offset %d", end)
+		}
+		if first == 0 {
+			var ch Change
+			if err := json.Unmarshal(line, &ch); err != nil {
+				return 0, time.Time{}, 0, 0, fmt.Errorf("corrupt change at offset %d: %w", end, err)
+			}
+			first = seq
+			firstTime, _ = time.Parse(time.RFC3339Nano, ch.Time)
+		}
+		last = seq
+		end += int64(len(line))
+	}
+}
+
+// lineSeq returns the sequence number of a change without decoding it - it is the first field of the line
+func lineSeq(line []byte) (int64, bool) {
+	rest, ok := bytes.CutPrefix(line, []byte(`{"seq":`))
+	if !ok {
+		return 0, false
+	}
+	n := bytes.IndexByte(rest, ',')
+	if n < 0 {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(string(rest[:n]), 10, 64)
+	return seq, err == nil
+}
+
+// append records the frame d as next change and rotates the file once it is full. Returns the sequence number of the
+// change.
+func (c *Changelog) append(d Data, now time.Time) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return 0, ErrChangelogDisabled
+	}
+
+	c.seq++
+	line, err := json.Marshal(changeOf(c.seq, d, now))
+	if err != nil {
+		return c.seq, err
+	}
+	n, err := c.out.Write(append(line, '\n'))
+	c.size += int64(n)
+	if err != nil {
+		return c.seq, err
+	}
+	if c.first == 0 {
+		c.first, c.firstTime = c.seq, now
+	}
+
+	if c.size >= int64(*envhandler.ENV.CHANGELOG_MAX_BYTES)/2 {
+		return c.seq, c.rotate(now)
+	}
+	return c.seq, nil
+}
+
+// flush writes the buffer to the file and fsyncs it
+func (c *Changelog) flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sync()
+}
+
+// sync flushes and fsyncs the file - must be called with the lock held
+func (c *Changelog) sync() error {
+	if c.file == nil {
+		return nil
+	}
+	if err := c.out.Flush(); err != nil {
+		return err
+	}
+	return c.file.Sync()
+}
+
+// retain applies HKV_CHANGELOG_RETENTION: the rotated segment is dropped once it was rotated longer ago, the file is
+// rotated once its first change is older. So changes are kept at least the retention and at most twice as long.
+func (c *Changelog) retain(now time.Time) error {
+	retention := time.Duration(*envhandler.ENV.CHANGELOG_RETENTION) * time.Second
+	if retention <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	if c.rotated && now.Sub(c.rotatedAt) > retention {
+		if err := os.Remove(c.fileName + changelogRotatedExt); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		c.rotated = false
+	}
+	if c.first != 0 && now.Sub(c.firstTime) > retention {
+		return c.rotate(now)
+	}
+	return nil
+}
+
+// rotate moves the file to the rotated segment and starts a new one - must be called with the lock held
+func (c *Changelog) rotate(now time.Time) error {
+	if err := c.sync(); err != nil {
+		return err
+	}
+	if err := os.Rename(c.fileName, c.fileName+changelogRotatedExt); err != nil {
+		return err
+	}
+	// a lost rename after a crash only keeps the changes in the file - no reason to stop
+	if err := syncDir(filepath.Dir(c.fileName)); err != nil {
+		log.Printf("Error syncing the directory of changelog %s: %v", c.fileName, err)
+	}
+
+	f, err := os.OpenFile(c.fileName, os.O_RDWR|os.O_CREATE|os.O_APPEND, envhandler.ENV.FileMode())
+	if err != nil {
+		// the open file is the rotated segment now - the changelog stops instead of writing on there
+		_ = c.file.Close()
+		c.file = nil
+		return err
+	}
+	_ = c.file.Close()
+	c.rotated, c.rotatedAt, c.oldest = true, now, c.first
+	c.file, c.out, c.size = f, bufio.NewWriterSize(f, changelogBufferSize), 0
+	c.first, c.firstTime = 0, time.Time{}
+	return nil
+}
+
+// close flushes and closes the file
+func (c *Changelog) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	err := c.sync()
+	if closeErr := c.file.Close(); err == nil {
+		err = closeErr
+	}
+	c.file = nil
+	return err
+}
+
+// remove closes the changelog and deletes its files
+func (c *Changelog) remove() error {
+	err := c.close()
+	for _, name := range []string{c.fileName, c.fileName + changelogRotatedExt} {
+		if removeErr := os.Remove(name); removeErr != nil && !os.IsNotExist(removeErr) && err == nil {
+			err = removeErr
+		}
+	}
+	return err
+}
+
+// Changes returns up to limit changes after the sequence number since and the first sequence number which is still
+// retained - a consumer whose since is lower than that minus one has missed changes.
+func (c *Changelog) Changes(since int64, limit int) ([]Change, int64, error) {
+	changes, oldest, _, err := c.ChangesMatching(since, limit, nil)
+	return changes, oldest, err
+}
+
+// ChangesMatching returns up to limit changes after since for which match returns true - all for a nil match - like
+// Changes. next is the sequence number of the last change read, the since of the following call, so changes which
+// did not match are not read again.
+func (c *Changelog) ChangesMatching(since int64, limit int, match func(*Change) bool) (changes []Change, oldest int64,
+	next int64, err error) {
+	c.mu.Lock()
+	if c.file == nil {
+		c.mu.Unlock()
+		return nil, 0, since, ErrChangelogDisabled
+	}
+	if err := c.out.Flush(); err != nil {
+		c.mu.Unlock()
+		return nil, 0, since, err
+	}
+	oldest = c.seq + 1
+	switch {
+	case c.rotated:
+		oldest = c.oldest
+	case c.first != 0:
+		oldest = c.first
+	}
+
+	// the segments are opened under the lock - a rotation afterward renames the files, the open ones stay readable
+	var files []*os.File
+	var sizes []int64
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	if c.rotated && (c.first == 0 || since+1 < c.first) {
+		if f, err := os.Open(c.fileName + changelogRotatedExt); err == nil {
+			if info, err := f.Stat(); err == nil {
+				files, sizes = append(files, f), append(sizes, info.Size())
+			} else {
+				_ = f.Close()
+			}
+		}
+	}
+	f, err := os.Open(c.fileName)
+	if err != nil {
+		c.mu.Unlock()
+		return nil, 0, since, err
+	}
+	files, sizes = append(files, f), append(sizes, c.size)
+	c.mu.Unlock()
+
+	changes, next = make([]Change, 0, min(limit, 64)), since
+	for i, f := range files {
+		reader := bufio.NewReaderSize(io.LimitReader(f, sizes[i]), changelogBufferSize)
+		for len(changes) < limit {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				break
+			}
+			if seq, ok := lineSeq(line); !ok || seq <= since {
+				continue
+			}
+			var ch Change
+			if err := json.Unmarshal(line, &ch); err != nil {
+				return nil, 0, since, err
+			}
+			next = ch.Seq
+			if match == nil || match(&ch) {
+				changes = append(changes, ch)
+			}
+		}
+	}
+	return changes, oldest, next, nil
+}
+
+// changelogName returns the file name of the changelog of the AOF
+func (a *AOF) changelogName() string {
+	return strings.TrimSuffix(a.FileName, ".bin") + changelogExt
+}
+
+// openExistingChangelog opens the changelog if the DB has one - called by Start
+func (a *AOF) openExistingChangelog() error {
+	if a.changes.Load() != nil {
+		return nil
+	}
+	if _, err := os.Stat(a.changelogName()); err != nil {
+		return nil
+	}
+	c, err := openChangelog(a.changelogName())
+	if err != nil {
+		return err
+	}
+	a.changes.Store(c)
+	return nil
+}
+
+// recordChange appends the frame d to the changelog if enabled - a failure is logged, the AOF stays the truth - and
+// publishes it to the subscribers of the DB
+func (a *AOF) recordChange(d Data) {
+	now := time.Now()
+	var seq int64
+	if c := a.changes.Load(); c != nil {
+		var err error
+		if seq, err = c.append(d, now); err != nil {
+			log.Printf("Error writing to changelog of %s: %v", a.name, err)
+		}
+	}
+	if subs := a.subs.Load(); subs.active() {
+		subs.publish(changeOf(seq, d, now))
+	}
+}
+
+// Changelog returns the changelog of the DB - nil if it is disabled
+func (hm *HashMap) Changelog() *Changelog {
+	return hm.Aof.changes.Load()
+}
+
+// EnableChangelog starts the changelog of the DB - changes before are not recorded
+func (hm *HashMap) EnableChangelog() error {
+	resume := hm.Aof.pause()
+	defer resume()
+	if hm.Aof.changes.Load() != nil {
+		return nil
+	}
+	c, err := openChangelog(hm.Aof.changelogName())
+	if err != nil {
+		return err
+	}
+	hm.Aof.changes.Store(c)
+	return nil
+}
+
+// DisableChangelog stops the changelog of the DB and deletes its files - of a closed DB as well
+func (hm *HashMap) DisableChangelog() error {
+	resume := hm.Aof.pause()
+	defer resume()
+	c := hm.Aof.changes.Swap(nil)
+	if c == nil {
+		c = &Changelog{fileName: hm.Aof.changelogName()}
+	}
+	return c.remove()
+}
@@ -1,14 +1,127 @@
 package hashMap
 
+import (
+	"strconv"
+	"time"
+)
+
+// EntryType tags the representation of the value held by an Entry
+type EntryType uint8
+
+const (
+	// TypeString is a plain string value
+	TypeString EntryType = iota
+	// TypeCounter is a counter - the value is held as raw int64 in Counter
+	TypeCounter
+	// TypeHash is a hash of fields and their values held in Fields
+	TypeHash
+	// TypeSet is a set of unique members held in Members
+	TypeSet
+	// TypeZSet is a sorted set - the scores are held in Scores, the members ordered by score in Ranked
+	TypeZSet
+	// TypeList is a list of values held in List
+	TypeList
+)
+
+// String returns the name of the type as reported by HashMap.Type
+func (t EntryType) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeCounter:
+		return "counter"
+	case TypeHash:
+		return "hash"
+	case TypeSet:
+		return "set"
+	case TypeZSet:
+		return "zset"
+	case TypeList:
+		return "list"
+	}
+	return "unknown"
+}
+
 type Entry struct {
-	Hash  uint64
-	Key   string
-	Value string
-	Next  *Entry
-	Ttl   int64
+	Hash     uint64
+	Key      string
+	Value    string
+	Next     *Entry
+	Ttl      int64
+	ExpireAt int64
+	Type     EntryType
+	Counter  int64
+	// Fields holds the fields of a hash
+	Fields map[string]string
+	// Members holds the members of a set
+	Members map[string]struct{}
+	// Scores and Ranked hold the members of a sorted set
+	Scores map[string]float64
+	Ranked []ZMember
+	// List holds the values of a list
+	List []string
+	// size is the number of bytes held by a collection - limited by HKV_ENTRY_SIZE
+	size int
+	// ModifiedAt is the time of the last write in Unix nanos - it is not persisted, a replay sets the replay time
+	ModifiedAt int64
+	// ContentType is the encoding hint of a string value given by its client, e.g. application/json - opaque to the
+	// server. A write without one removes it.
+	ContentType string
 }
 
 // NewEntry creates a new Entry
 func NewEntry(ttl int64, key string, value string, hash uint64, last *Entry) *Entry {
-	return &Entry{Ttl: ttl, Key: key, Value: value, Hash: hash, Next: last}
+	return &Entry{Ttl: ttl, Key: key, Value: value, Hash: hash, Next: last, ModifiedAt: time.Now().UnixNano()}
+}
+
+// NewCounterEntry creates a new Entry holding a counter
+func NewCounterEntry(ttl int64, key string, counter int64, hash uint64, last *Entry) *Entry {
+	return &Entry{Ttl: ttl, Key: key, Hash: hash, Next: last, Type: TypeCounter, Counter: counter, ModifiedAt: time.Now().UnixNano()}
+}
+
+// StringValue returns the value of the entry as string - counters are formatted on demand
+func (e *Entry) StringValue() string {
+	if e.Type == TypeCounter {
+		return strconv.FormatInt(e.Counter, 10)
+	}
+	return e.Value
+}
+
+// isCollection returns true if the entry holds a collection instead of a string or counter
+func (e *Entry) isCollection() bool {
+	return e.Type != TypeString && e.Type != TypeCounter
+}
+
+// setString sets a string value and drops a possible counter or collection representation
+func (e *Entry) setString(value string) {
+	e.Value = value
+	e.Type = TypeString
+	e.ContentType = ""
+	e.Counter = 0
+	e.Fields = nil
+	e.Members = nil
+	e.Scores = nil
+	e.Ranked = nil
+	e.List = nil
+	e.size = 0
+	e.touch()
+}
+
+// touch marks the entry as written now
+func (e *Entry) touch() {
+	e.ModifiedAt = time.Now().UnixNano()
+}
+
+// remainingTtl returns the remaining TTL in seconds - 0 if the entry has no expiry
+func (e *Entry) remainingTtl() int64 {
+	if e.ExpireAt == 0 {
+		return 0
+	}
+	// an entry which is about to expire still has a TTL
+	return max(e.ExpireAt-time.Now().Unix(), 1)
+}
+
+// aofTtl returns the TTL the entry was written with - the compaction keeps it like the frames of the AOF
+func (e *Entry) aofTtl() int64 {
+	return e.Ttl
 }
@@ -0,0 +1,177 @@
+package hashMap
+
+import (
+	"hydrakv/envhandler"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// withEntry calls fn with the basket and the entry of the key under the basket lock - the entry is nil if the key is
+// missing. With write the basket is write locked, otherwise read locked.
+func (hm *HashMap) withEntry(key string, write bool, fn func(basket *Basket, item, prev *Entry, hash uint64)) {
+	// we need global read lock
+	hm.mutex.RLock()
+	defer hm.mutex.RUnlock()
+
+	// get the right index
+	index, hash := hm.getIndex(key)
+	basket := hm.table[index]
+
+	// basketlocal lock
+	if write {
+		hm.WLockBasketLock(hash)
+		defer hm.WUnlockBasketLock(hash)
+	} else {
+		hm.RLockBasketLock(hash)
+		defer hm.RUnlockBasketLock(hash)
+	}
+
+	item, prev := basket.find(key)
+	fn(basket, item, prev, hash)
+}
+
+// addEntry links a new entry into the basket - must be called under the basket write lock
+func (hm *HashMap) addEntry(basket *Basket, e *Entry) {
+	basket.push(e)
+	hm.cardinality.add(e.Hash)
+	hm.TTlManager.addEntry(e)
+	hm.Entries.Add(1)
+	hm.signalResize()
+	kvStorageSize.Set(float64(hm.Entries.Load()))
+}
+
+// unlinkEntry removes an entry from the basket, e.g. an emptied collection - must be called under the basket write lock
+func (hm *HashMap) unlinkEntry(basket *Basket, item, prev *Entry) {
+	hm.TTlManager.delEntry(item)
+	basket.unlink(item, prev)
+	hm.Entries.Add(^uint64(0))
+	hm.deletedEntries.Add(1)
+	kvStorageSize.Set(float64(hm.Entries.Load()))
+}
+
+// HSet sets a field of the hash stored at key - a missing key is created as hash. Returns false if the key holds
+// another type or the hash would exceed HKV_ENTRY_SIZE bytes.
+func (hm *HashMap) HSet(key, field, value string) bool {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("hset"))
+	defer timer.ObserveDuration()
+
+	if !validKey(key, "hset") {
+		return false
+	}
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	ok := false
+	hm.withEntry(key, true, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item != nil && item.Type != TypeHash {
+			kvOperations.WithLabelValues("hset", "wrong_type").Inc()
+			return
+		}
+
+		// the fields and values of a hash are limited by HKV_ENTRY_SIZE
+		size := len(field) + len(value)
+		if item != nil {
+			size += item.size
+			if old, exists := item.Fields[field]; exists {
+				size -= len(field) + len(old)
+			}
+		}
+		if size > *envhandler.ENV.ENTRY_SIZE && !hm.reset {
+			kvOperations.WithLabelValues("hset", "too_large").Inc()
+			return
+		}
+
+		// only changes are written to the AOF
+		if !hm.reset {
+			frame.add(hm.Aof, Data{Action: "hset", Key: key, Value: packValue(field, value)})
+		}
+
+		if item == nil {
+			item = &Entry{Key: key, Hash: hash, Next: basket.Items, Type: TypeHash, Fields: make(map[string]string)}
+			hm.addEntry(basket, item)
+		}
+		item.Fields[field] = value
+		item.size = size
+		item.touch()
+		ok = true
+		kvOperations.WithLabelValues("hset", "ok").Inc()
+	})
+	return ok
+}
+
+// HGet returns the value of a field of the hash stored at key
+func (hm *HashMap) HGet(key, field string) (bool, string) {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("hget"))
+	defer timer.ObserveDuration()
+
+	var found bool
+	var value string
+	hm.withEntry(key, false, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item == nil || item.Type != TypeHash {
+			kvOperations.WithLabelValues("hget", "not_found").Inc()
+			return
+		}
+		value, found = item.Fields[field]
+		kvOperations.WithLabelValues("hget", "found").Inc()
+	})
+	return found, value
+}
+
+// HGetAll returns a copy of the fields of the hash stored at key - nil if the key holds no hash
+func (hm *HashMap) HGetAll(key string) map[string]string {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("hgetall"))
+	defer timer.ObserveDuration()
+
+	var fields map[string]string
+	hm.withEntry(key, false, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item == nil || item.Type != TypeHash {
+			kvOperations.WithLabelValues("hgetall", "not_found").Inc()
+			return
+		}
+		fields = make(map[string]string, len(item.Fields))
+		for k, v := range item.Fields {
+			fields[k] = v
+		}
+		kvOperations.WithLabelValues("hgetall", "found").Inc()
+	})
+	return fields
+}
+
+// HDel removes a field of the hash stored at key - the key is removed with the last field. Returns true if the field
+// existed.
+func (hm *HashMap) HDel(key, field string) bool {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("hdel"))
+	defer timer.ObserveDuration()
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	ok := false
+	hm.withEntry(key, true, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item == nil || item.Type != TypeHash {
+			kvOperations.WithLabelValues("hdel", "not_found").Inc()
+			return
+		}
+		value, exists := item.Fields[field]
+		if !exists {
+			kvOperations.WithLabelValues("hdel", "not_found").Inc()
+			return
+		}
+
+		if !hm.reset {
+			frame.add(hm.Aof, Data{Action: "hdel", Key: key, Value: field})
+		}
+		delete(item.Fields, field)
+		item.size -= len(field) + len(value)
+		item.touch()
+		if len(item.Fields) == 0 {
+			hm.unlinkEntry(basket, item, prev)
+		}
+		ok = true
+		kvOperations.WithLabelValues("hdel", "ok").Inc()
+	})
+	return ok
+}
@@ -2,10 +2,12 @@ package hashMap
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"hydrakv/envhandler"
 	"hydrakv/fifolifo"
+	"hydrakv/utils"
 	"hydrakv/xxhash64"
 	"io"
 	"log"
@@ -13,10 +15,10 @@ import (
 	"os"
 	"runtime"
 	"strconv"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -27,10 +29,12 @@ const (
 )
 
 type HashMap struct {
-	table          []*Basket
-	keyCount       int64
-	mutex          sync.RWMutex
-	xxhash         *xxhash64.XXHash64
+	table    []*Basket
+	keyCount int64
+	mutex    sync.RWMutex
+	xxhash   *xxhash64.XXHash64
+	// seed is the hash seed of the keys - see AOF.hashSeed
+	seed           uint64
 	Entries        atomic.Uint64
 	Name           string
 	Aof            *AOF
@@ -40,10 +44,21 @@ type HashMap struct {
 	resizeCheck    chan struct{}
 	deletedEntries atomic.Int64
 	done           chan struct{}
-	TTlManager     *TTLManager
-	basketNum      int
-	basketLockNum  int
-	fifolifos      sync.Map
+	// checker runs the ResizeChecker and the ChainSampler - Close waits for them, so no resize or compaction outlives the HashMap
+	checker       sync.WaitGroup
+	TTlManager    *TTLManager
+	basketNum     atomic.Int64
+	basketLockNum int
+	fifolifos     sync.Map
+	ready         atomic.Bool
+	loadMu        sync.Mutex
+	closed        bool
+	storageFull   atomic.Bool
+	storageMu     sync.Mutex
+	// cardinality estimates the distinct keys if HKV_APPROX_CARDINALITY is set - nil otherwise
+	cardinality *hll
+	// progress is the progress of the AOF replay
+	progress replayProgress
 }
 
 // Metrics for Prometheus in Hashmap
@@ -76,27 +91,44 @@ var (
 	)
 )
 
-// NewHashMap returns a new HashMap struct
+// NewHashMap returns a new HashMap struct with its AOF replayed and ready to serve
 func NewHashMap(name string) (*HashMap, error) {
+	hm, err := OpenHashMap(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := hm.Load(); err != nil {
+		return nil, err
+	}
+	return hm, nil
+}
+
+// OpenHashMap returns a new HashMap struct without loading it - Load has to be called before it is ready
+func OpenHashMap(name string) (*HashMap, error) {
 
 	// Create a new HashMap
 	hm := &HashMap{
-		table: make([]*Basket, DefaultBasketSize), mutex: sync.RWMutex{}, xxhash: xxhash64.XXH,
-		Name: strings.ToUpper(name), reset: true, cpuCount: runtime.NumCPU(),
-		resizeCheck: make(chan struct{}, 1001), done: make(chan struct{}),
+		mutex: sync.RWMutex{}, xxhash: xxhash64.XXH,
+		Name: utils.U.DbKey(name), reset: true, cpuCount: runtime.NumCPU(),
+		resizeCheck: make(chan struct{}, 1), done: make(chan struct{}),
 		fifolifos: sync.Map{},
 	}
+	if *envhandler.ENV.APPROX_CARDINALITY {
+		hm.cardinality = &hll{}
+	}
 
 	// Create TTL Manager for this HashMap
-	hm.TTlManager = NewTTLManager(name, hm.Del)
+	hm.TTlManager = NewTTLManager(hm.Name, hm.delExpired)
 
 	// create AOF to save data to disk
-	aof, err := NewAOF(name, hm.GetAllEntriesAndCompress)
+	aof, err := NewAOF(name, hm.compactEntries)
 	if err != nil {
 		return nil, err
 	}
 
 	hm.Aof = aof
+	hm.seed = aof.hashSeed()
 
 	// init the Locks
 	lpot := hm.TTlManager.LowerPowerOfTwo(uint64(hm.cpuCount * (*envhandler.ENV.CPU_MULTIPLIER)))
@@ -109,18 +141,31 @@ func NewHashMap(name string) (*HashMap, error) {
 	hm.basketLocks = make([]sync.RWMutex, lpot)
 
 	// init the Baskets
-	for i := 0; i < DefaultBasketSize; i++ {
-		hm.table[i] = NewBasket()
-	}
-	hm.basketNum = DefaultBasketSize
+	hm.table = newTable(hm.initialBasketSize())
+	hm.basketNum.Store(int64(len(hm.table)))
 
-	// start the resize checker
-	go hm.ResizeChecker()
+	// start the resize checker and the chain length sampler
+	hm.checker.Go(hm.ResizeChecker)
+	hm.checker.Go(hm.ChainSampler)
+
+	return hm, nil
+}
+
+// Load replays the AOF file, starts the AOF loop and the TTLManager and marks the HashMap as ready
+func (hm *HashMap) Load() error {
+	// Close waits until the load is done
+	hm.loadMu.Lock()
+	defer hm.loadMu.Unlock()
+
+	// the HashMap was closed before it got loaded
+	if hm.closed {
+		return fmt.Errorf("DB %s is closed", hm.Name)
+	}
 
 	// try to replay the AOF file
-	err = hm.ReplayAOF()
+	err := hm.ReplayAOF()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// set reset to false
@@ -128,124 +173,945 @@ func NewHashMap(name string) (*HashMap, error) {
 
 	// start the AOF loop
 	if err := hm.Aof.Start(); err != nil {
-		return nil, err
+		return err
 	}
 
 	// Start the ttlmanager
 	hm.TTlManager.Start()
 
-	return hm, nil
+	hm.ready.Store(true)
+	return nil
+}
+
+// Ready returns true once the AOF is replayed and the HashMap is ready to serve
+func (hm *HashMap) Ready() bool {
+	return hm.ready.Load()
+}
+
+// Healthy returns an error if the AOF of a loaded DB is failing - a DB which is still loading is healthy
+func (hm *HashMap) Healthy() error {
+	if !hm.Ready() {
+		return nil
+	}
+	return hm.Aof.Healthy()
 }
 
 // ReplayAOF replays the AOF file to restore the HashMap state
 func (hm *HashMap) ReplayAOF() error {
+	if _, err := hm.replayAOF(); err != nil {
+		return err
+	}
+	progress := hm.ReplayProgress()
+	log.Printf("Replayed AOF for %s: %d frames, %d bytes in %s", hm.Name, progress.Frames, progress.Bytes,
+		progress.Elapsed.Round(time.Millisecond))
+	return nil
+}
+
+// replayStats describes a replayed AOF file
+type replayStats struct {
+	frames int64
+	// truncated is true if the file ends within a frame
+	truncated bool
+	// unknown counts the frames of unknown actions, firstUnknown is the number of the first one
+	unknown      int64
+	firstUnknown int64
+}
+
+// maxPendingSets bounds the sets a replay coalesces before it applies them, so a DB of distinct keys is not held twice
+const maxPendingSets = 1 << 16
+
+// replayAOF replays the AOF file and returns what it found in it. A missing file is an empty DB.
+func (hm *HashMap) replayAOF() (replayStats, error) {
+	var stats replayStats
+
 	// if the bin file not exists we can return
 	if _, err := os.Stat(hm.Aof.FileName); os.IsNotExist(err) {
-		return nil
+		return stats, nil
 	}
 
 	// open the file
 	f, err := os.Open(hm.Aof.FileName)
 	if err != nil {
-		return err
+		return stats, err
 	}
 	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return stats, err
+	}
 
-	// Create buffered reader
-	reader := bufio.NewReaderSize(f, 1024*64)
+	// Create buffered reader - the progress counts the bytes below it
+	hm.progress.begin(info.Size())
+	defer func() { hm.progress.finish(stats.frames) }()
+	reader := bufio.NewReaderSize(&progressReader{r: f, read: &hm.progress.read}, *envhandler.ENV.REPLAY_BUFFER)
+	if err := hm.Aof.readHeader(reader); err != nil {
+		return stats, err
+	}
+	// the header may carry the seed of the DB - the table is still empty, so no key was placed with the drawn one
+	hm.mutex.Lock()
+	hm.seed = hm.Aof.hashSeed()
+	hm.mutex.Unlock()
+
+	// consecutive sets are coalesced by key, the last writer wins - an AOF with many overwrites builds every key once
+	// instead of once per frame. Any other action may depend on the keys, so the pending sets are applied before it.
+	pending := make(map[string]Data)
+	applyPending := func() {
+		for _, d := range pending {
+			hm.replaySet(d)
+		}
+		clear(pending)
+	}
+	defer applyPending()
 
-	// ... existing code ...
 	for {
 		var d Data
 		err := hm.Aof.readFrame(reader, &d)
 		if err != nil {
-			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
 				log.Printf("AOF truncated for %s, stopping replay", hm.Name)
+				stats.truncated = true
 				break
 			}
-			return err
+			return stats, err
+		}
+		stats.frames++
+		if stats.frames%progressFrames == 0 {
+			hm.progress.update(hm.Name, stats.frames)
+		}
+
+		if (d.Action != "set" && d.Action != "setct") || len(pending) >= maxPendingSets {
+			applyPending()
 		}
 
 		switch d.Action {
-		case "set":
-			hm.Set(d.Ttl, d.Key, d.Value)
-			// ... existing code ...
+		case "set", "setct":
+			pending[d.Key] = d
 		case "del":
 			hm.Del(d.Key)
+		case "flush":
+			hm.flush()
+		case "expire":
+			hm.Touch(d.Key, d.Ttl)
 		case "incr":
 			hm.Incr(d.Ttl, d.Key, d.Value)
+		case "cincr":
+			if amount, ok := hm.checkIsNumber(d.Value); ok {
+				hm.CounterIncr(d.Ttl, d.Key, amount)
+			}
+		case "hset":
+			if parts, ok := unpackValue(d.Value, 2); ok {
+				hm.HSet(d.Key, parts[0], parts[1])
+			}
+		case "hdel":
+			hm.HDel(d.Key, d.Value)
+		case "sadd":
+			hm.SAdd(d.Key, d.Value)
+		case "srem":
+			hm.SRem(d.Key, d.Value)
+		case "lpush":
+			hm.LPush(d.Key, d.Value)
+		case "rpush":
+			hm.RPush(d.Key, d.Value)
+		case "lpop":
+			hm.LPop(d.Key)
+		case "rpop":
+			hm.RPop(d.Key)
+		case "zadd":
+			if parts, ok := unpackValue(d.Value, 2); ok {
+				if score, err := strconv.ParseFloat(parts[0], 64); err == nil {
+					hm.ZAdd(d.Key, parts[1], score)
+				}
+			}
+		default:
+			if stats.unknown == 0 {
+				stats.firstUnknown = stats.frames
+			}
+			stats.unknown++
+		}
+	}
+	return stats, nil
+}
+
+// replaySet applies a set or setct frame of the AOF - a malformed setct is dropped
+func (hm *HashMap) replaySet(d Data) {
+	if d.Action == "set" {
+		hm.Set(d.Ttl, d.Key, d.Value)
+		return
+	}
+	if parts, ok := unpackValue(d.Value, 2); ok {
+		hm.SetWithContentType(d.Ttl, d.Key, parts[1], parts[0], false)
+	}
+}
+
+// setFrame returns the AOF frame of a set - a setct frame with the content type packed before the value if given
+func setFrame(key, value, contentType string, ttl int64) Data {
+	if contentType == "" {
+		return Data{Action: "set", Key: key, Value: value, Ttl: ttl}
+	}
+	return Data{Action: "setct", Key: key, Value: packValue(contentType, value), Ttl: ttl}
+}
+
+// validKey reports whether key can be stored - empty keys are rejected by all writes, so no entry ever has one and
+// reads of an empty key find nothing
+func validKey(key, operation string) bool {
+	if key != "" {
+		return true
+	}
+	kvOperations.WithLabelValues(operation, "empty_key").Inc()
+	return false
+}
+
+// getIndex gets the Index of a Key
+func (hm *HashMap) getIndex(key string) (int, uint64) {
+	h := hm.xxhash.HashStringSeed(key, hm.seed)
+	index := h & uint64(hm.basketNum.Load()-1)
+	return int(index), h
+}
+
+// Set inserts or updates a key-value pair in the HashMap. Returns true if the operation is successful.
+func (hm *HashMap) Set(ttl int64, key string, value string) bool {
+	return hm.set(ttl, key, value, false, "")
+}
+
+// SetKeepTTL inserts or updates a key-value pair in the HashMap and keeps the expiry of an existing key.
+// New keys are created without a TTL. Returns true if the operation is successful.
+func (hm *HashMap) SetKeepTTL(key string, value string) bool {
+	return hm.set(0, key, value, true, "")
+}
+
+// SetWithContentType sets a value like Set - or like SetKeepTTL with keepTTL - and stores contentType with it,
+// an encoding hint for clients which the server does not interpret. An empty contentType is a plain set.
+func (hm *HashMap) SetWithContentType(ttl int64, key, value, contentType string, keepTTL bool) bool {
+	return hm.set(ttl, key, value, keepTTL, contentType)
+}
+
+// set inserts or updates a key-value pair - if keepTTL is true an existing expiry is preserved and ttl is ignored.
+// A contentType is written as setct frame, so values without one cost nothing extra in the AOF.
+func (hm *HashMap) set(ttl int64, key string, value string, keepTTL bool, contentType string) bool {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("set"))
+	defer timer.ObserveDuration()
+
+	if !validKey(key, "set") {
+		return false
+	}
+
+	// values above HKV_ENTRY_SIZE are rejected or truncated - the AOF gets the value as stored,
+	// so a replay keeps values written before the limit was lowered
+	if !hm.reset {
+		var ok bool
+		if value, _, ok = FitValue(value); !ok {
+			kvOperations.WithLabelValues("set", "too_large").Inc()
+			return false
+		}
+	}
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	// we need global read lock
+	hm.mutex.RLock()
+	defer hm.mutex.RUnlock()
+
+	// we need the index of the key
+	index, hash := hm.getIndex(key)
+
+	// we need a Basketlocal write lock
+	hm.WLockBasketLock(hash)
+	defer hm.WUnlockBasketLock(hash)
+
+	// Get the basket which should hold / newly hold our entry
+	basket := hm.table[index]
+
+	// Does it exist? If yes - update value
+	for item := basket.Items; item != nil; item = item.Next {
+		if item.Key == key {
+			item.setString(value)
+			item.ContentType = contentType
+
+			// keep the expiry - the AOF gets the remaining TTL
+			if keepTTL {
+				if !hm.reset {
+					frame.add(hm.Aof, setFrame(key, value, contentType, item.remainingTtl()))
+				}
+				return true
+			}
+			if !hm.reset {
+				frame.add(hm.Aof, setFrame(key, value, contentType, ttl))
+			}
+
+			// if there was a TTL add delete the entry from the TTLManager
+			if item.Ttl != 0 {
+				hm.TTlManager.delEntry(item)
+			}
+			item.Ttl = ttl
+			hm.TTlManager.addEntry(item)
+			return true
+		}
+	}
+
+	// a new key has no expiry to keep
+	if !hm.reset {
+		frame.add(hm.Aof, setFrame(key, value, contentType, ttl))
+	}
+
+	// If not - add it
+	e := NewEntry(ttl, key, value, hash, hm.table[index].Items)
+	e.ContentType = contentType
+	hm.cardinality.add(hash)
+	hm.table[index].push(e)
+	hm.TTlManager.addEntry(e)
+	hm.Entries.Add(1)
+	hm.signalResize()
+	kvStorageSize.Set(float64(hm.Entries.Load()))
+	kvOperations.WithLabelValues("set", "ok").Inc()
+	return true
+}
+
+// SetNX sets a key-value pair only if the key does not exist. The check and the insert happen under one basket
+// lock, so of concurrent callers exactly one wins - with a ttl this is a lock with expiry. Returns false if the key
+// exists, including keys holding a collection.
+func (hm *HashMap) SetNX(ttl int64, key string, value string) bool {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("setnx"))
+	defer timer.ObserveDuration()
+
+	if !validKey(key, "setnx") {
+		return false
+	}
+
+	if !hm.reset {
+		var ok bool
+		if value, _, ok = FitValue(value); !ok {
+			kvOperations.WithLabelValues("setnx", "too_large").Inc()
+			return false
+		}
+	}
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	// we need global read lock
+	hm.mutex.RLock()
+	defer hm.mutex.RUnlock()
+
+	// get the right index
+	index, hash := hm.getIndex(key)
+	basket := hm.table[index]
+
+	// basketlocal write lock
+	hm.WLockBasketLock(hash)
+	defer hm.WUnlockBasketLock(hash)
+
+	for item := basket.Items; item != nil; item = item.Next {
+		if item.Key == key {
+			kvOperations.WithLabelValues("setnx", "exists").Inc()
+			return false
+		}
+	}
+
+	// only a successful insert is written to the AOF - as set, since replaying the set has the same result
+	if !hm.reset {
+		frame.add(hm.Aof, Data{Action: "set", Key: key, Value: value, Ttl: ttl})
+	}
+	e := NewEntry(ttl, key, value, hash, basket.Items)
+	hm.cardinality.add(hash)
+	basket.push(e)
+	hm.TTlManager.addEntry(e)
+	hm.Entries.Add(1)
+	hm.signalResize()
+	kvStorageSize.Set(float64(hm.Entries.Load()))
+	kvOperations.WithLabelValues("setnx", "ok").Inc()
+	return true
+}
+
+// GetOrSet returns the value of key or, if the key is missing, stores defaultValue with ttl and returns it - in one
+// step under the basket lock, so two clients missing the same key never both write. created is true if the value
+// was stored. A collection at key, an empty key or a too large value return "" and false.
+func (hm *HashMap) GetOrSet(key, defaultValue string, ttl int64) (value string, created bool) {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("getorset"))
+	defer timer.ObserveDuration()
+
+	if !validKey(key, "getorset") {
+		return "", false
+	}
+
+	if !hm.reset {
+		var ok bool
+		if defaultValue, _, ok = FitValue(defaultValue); !ok {
+			kvOperations.WithLabelValues("getorset", "too_large").Inc()
+			return "", false
+		}
+	}
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	// we need global read lock
+	hm.mutex.RLock()
+	defer hm.mutex.RUnlock()
+
+	// get the right index
+	index, hash := hm.getIndex(key)
+	basket := hm.table[index]
+
+	// basketlocal write lock
+	hm.WLockBasketLock(hash)
+	defer hm.WUnlockBasketLock(hash)
+
+	for item := basket.Items; item != nil; item = item.Next {
+		if item.Key != key {
+			continue
+		}
+		// collections have no string value
+		if item.isCollection() {
+			kvOperations.WithLabelValues("getorset", "wrong_type").Inc()
+			return "", false
+		}
+		kvOperations.WithLabelValues("getorset", "found").Inc()
+		return item.StringValue(), false
+	}
+
+	// only the insert is written to the AOF - as set, since replaying the set has the same result
+	if !hm.reset {
+		frame.add(hm.Aof, Data{Action: "set", Key: key, Value: defaultValue, Ttl: ttl})
+	}
+	e := NewEntry(ttl, key, defaultValue, hash, basket.Items)
+	hm.cardinality.add(hash)
+	basket.push(e)
+	hm.TTlManager.addEntry(e)
+	hm.Entries.Add(1)
+	hm.signalResize()
+	kvStorageSize.Set(float64(hm.Entries.Load()))
+	kvOperations.WithLabelValues("getorset", "created").Inc()
+	return defaultValue, true
+}
+
+// Get retrieves the value associated with the given key from the HashMap. Returns an empty string if the key is not found.
+func (hm *HashMap) Get(key string) (bool, string) {
+	ok, value, _ := hm.GetModified(key)
+	return ok, value
+}
+
+// GetModified returns the value of key like Get and the time of its last write in Unix nanos
+func (hm *HashMap) GetModified(key string) (bool, string, int64) {
+	ok, value, info := hm.GetInfo(key)
+	return ok, value, info.ModifiedAt
+}
+
+// ValueInfo is the metadata of a value returned by GetInfo
+type ValueInfo struct {
+	// ModifiedAt is the time of the last write in Unix nanos
+	ModifiedAt int64
+	// ContentType is the encoding hint given with the value - empty if there is none
+	ContentType string
+}
+
+// GetInfo returns the value of key like Get and its ValueInfo
+func (hm *HashMap) GetInfo(key string) (bool, string, ValueInfo) {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("get"))
+	defer timer.ObserveDuration()
+
+	// we need global read lock
+	hm.mutex.RLock()
+	defer hm.mutex.RUnlock()
+
+	// get the right index
+	index, hash := hm.getIndex(key)
+	basket := hm.table[index]
+
+	// we need a Basketlocal write lock
+	hm.RLockBasketLock(hash)
+	defer hm.RUnlockBasketLock(hash)
+
+	// Try to get the value in existing entries
+	for item := basket.Items; item != nil; item = item.Next {
+		if item.Key == key {
+			// collections have no string value
+			if item.isCollection() {
+				break
+			}
+			kvOperations.WithLabelValues("get", "found").Inc()
+			return true, item.StringValue(), ValueInfo{ModifiedAt: item.ModifiedAt, ContentType: item.ContentType}
+		}
+	}
+
+	// it doesent exist!
+	kvOperations.WithLabelValues("get", "not_found").Inc()
+	return false, "", ValueInfo{}
+}
+
+// PersistTTL passed as ttl to GetEX removes the expiry of a key
+const PersistTTL int64 = -1
+
+// GetEX returns the value of key and changes its expiry under the same lock: a ttl > 0 sets a new expiry, 0 keeps
+// it and PersistTTL (any ttl < 0) removes it. Returns false if the key is missing or holds a collection.
+func (hm *HashMap) GetEX(key string, ttl int64) (bool, string) {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("getex"))
+	defer timer.ObserveDuration()
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	// we need global read lock
+	hm.mutex.RLock()
+	defer hm.mutex.RUnlock()
+
+	// get the right index
+	index, hash := hm.getIndex(key)
+	basket := hm.table[index]
+
+	// basketlocal write lock - the expiry may change
+	hm.WLockBasketLock(hash)
+	defer hm.WUnlockBasketLock(hash)
+
+	for item := basket.Items; item != nil; item = item.Next {
+		if item.Key != key {
+			continue
+		}
+		// collections have no string value
+		if item.isCollection() {
+			break
+		}
+		hm.expire(item, ttl, &frame)
+		kvOperations.WithLabelValues("getex", "found").Inc()
+		return true, item.StringValue()
+	}
+
+	kvOperations.WithLabelValues("getex", "not_found").Inc()
+	return false, ""
+}
+
+// Touch changes the expiry of key like GetEX without reading the value: a ttl > 0 sets a new expiry, 0 keeps it and
+// PersistTTL removes it. Returns false if the key is missing or holds a collection.
+func (hm *HashMap) Touch(key string, ttl int64) bool {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("touch"))
+	defer timer.ObserveDuration()
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	// we need global read lock
+	hm.mutex.RLock()
+	defer hm.mutex.RUnlock()
+
+	// get the right index
+	index, hash := hm.getIndex(key)
+	basket := hm.table[index]
+
+	// basketlocal write lock - the expiry changes
+	hm.WLockBasketLock(hash)
+	defer hm.WUnlockBasketLock(hash)
+
+	for item := basket.Items; item != nil; item = item.Next {
+		if item.Key != key {
+			continue
+		}
+		// the expiry of collections is not persisted
+		if item.isCollection() {
+			break
+		}
+		hm.expire(item, ttl, &frame)
+		kvOperations.WithLabelValues("touch", "found").Inc()
+		return true
+	}
+
+	kvOperations.WithLabelValues("touch", "not_found").Inc()
+	return false
+}
+
+// expire sets (ttl > 0), keeps (0) or removes (< 0) the expiry of item and enqueues its AOF frame with frame - must
+// be called with the basket write lock held
+func (hm *HashMap) expire(item *Entry, ttl int64, frame *pendingFrame) {
+	if ttl == 0 {
+		return
+	}
+	hm.TTlManager.delEntry(item)
+	item.Ttl = max(ttl, 0)
+	hm.TTlManager.addEntry(item)
+	if !hm.reset {
+		frame.add(hm.Aof, Data{Action: "expire", Key: item.Key, Ttl: ttl})
+	}
+}
+
+// Type returns the type of the value stored at key, e.g. "string" or "counter", and false if the key is missing.
+func (hm *HashMap) Type(key string) (string, bool) {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("type"))
+	defer timer.ObserveDuration()
+
+	// we need global read lock
+	hm.mutex.RLock()
+	defer hm.mutex.RUnlock()
+
+	// get the right index
+	index, hash := hm.getIndex(key)
+	basket := hm.table[index]
+
+	// basketlocal read lock
+	hm.RLockBasketLock(hash)
+	defer hm.RUnlockBasketLock(hash)
+
+	for item := basket.Items; item != nil; item = item.Next {
+		if item.Key == key {
+			kvOperations.WithLabelValues("type", "found").Inc()
+			return item.Type.String(), true
+		}
+	}
+
+	kvOperations.WithLabelValues("type", "not_found").Inc()
+	return "", false
+}
+
+// KeyMeta describes a key without its value
+type KeyMeta struct {
+	Type string
+	// Ttl is the remaining TTL in seconds - 0 if the key has no expiry
+	Ttl int64
+	// ModifiedAt is the time of the last write in Unix nanos
+	ModifiedAt int64
+	// Size is the length of a string or counter and the bytes held by a collection
+	Size int
+	// ContentType is the encoding hint of a string - empty if there is none
+	ContentType string
+}
+
+// Meta returns the metadata of key and false if the key is missing
+func (hm *HashMap) Meta(key string) (KeyMeta, bool) {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("meta"))
+	defer timer.ObserveDuration()
+
+	var meta KeyMeta
+	found := false
+	hm.withEntry(key, false, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item == nil {
+			return
+		}
+		found = true
+		meta = KeyMeta{Type: item.Type.String(), Ttl: item.remainingTtl(), ModifiedAt: item.ModifiedAt, Size: item.size,
+			ContentType: item.ContentType}
+		if !item.isCollection() {
+			meta.Size = len(item.StringValue())
+		}
+	})
+
+	if !found {
+		kvOperations.WithLabelValues("meta", "not_found").Inc()
+		return meta, false
+	}
+	kvOperations.WithLabelValues("meta", "found").Inc()
+	return meta, true
+}
+
+// NoTTL is the TTL of a KeyTTL without expiry
+const NoTTL int64 = -1
+
+// KeyTTL is a value of MGetWithTTL - TTL is the remaining TTL in seconds or NoTTL
+type KeyTTL struct {
+	Found bool
+	Value string
+	TTL   int64
+}
+
+// MGetWithTTL returns the values of keys with their remaining TTL in the order of keys, e.g. for clients deciding on a
+// sliding expiry. Missing keys and collections are not found.
+func (hm *HashMap) MGetWithTTL(keys []string) []KeyTTL {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("mget_ttl"))
+	defer timer.ObserveDuration()
+
+	values := make([]KeyTTL, len(keys))
+	for i, key := range keys {
+		hm.withEntry(key, false, func(basket *Basket, item, prev *Entry, hash uint64) {
+			if item == nil || item.isCollection() {
+				return
+			}
+			ttl := item.remainingTtl()
+			if ttl == 0 {
+				ttl = NoTTL
+			}
+			values[i] = KeyTTL{Found: true, Value: item.StringValue(), TTL: ttl}
+		})
+
+		if values[i].Found {
+			kvOperations.WithLabelValues("mget_ttl", "found").Inc()
+		} else {
+			kvOperations.WithLabelValues("mget_ttl", "not_found").Inc()
+		}
+	}
+	return values
+}
+
+// ExpiringWithin returns up to MaxExpiring keys which expire in the next seconds, the soonest first
+func (hm *HashMap) ExpiringWithin(seconds int64) []string {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("expiring"))
+	defer timer.ObserveDuration()
+
+	return hm.TTlManager.ExpiringWithin(seconds)
+}
+
+// Incr increments the value associated with the given key by the given amount. Returns the new value.
+func (hm *HashMap) Incr(ttl int64, key, amount string) bool {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("incr"))
+	defer timer.ObserveDuration()
+
+	if !validKey(key, "incr") {
+		return false
+	}
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	// we need global read lock
+	hm.mutex.RLock()
+	defer hm.mutex.RUnlock()
+
+	// get the right index
+	index, hash := hm.getIndex(key)
+	basket := hm.table[index]
+
+	// basketlocal write lock
+	hm.WLockBasketLock(hash)
+	defer hm.WUnlockBasketLock(hash)
+
+	if !hm.reset {
+		frame.add(hm.Aof, Data{Action: "incr", Key: key, Value: amount})
+	}
+
+	// we need the amount as int64
+	for item := basket.Items; item != nil; item = item.Next {
+		if item.Key == key {
+			add, ok := hm.checkIsNumber(amount)
+			if !ok {
+				return false
+			}
+
+			// counters are incremented directly
+			if item.Type == TypeCounter {
+				item.Counter += add
+			} else {
+				// make a number from item.Value and amount
+				val, ok := hm.checkIsNumber(item.Value)
+				if !ok {
+					return false
+				}
+				item.Value = strconv.FormatInt(val+add, 10)
+			}
+			item.touch()
+
+			// if there was a TTL add delete the entry from the TTLManager
+			if item.Ttl != 0 {
+				hm.TTlManager.delEntry(item)
+			}
+			item.Ttl = ttl
+			hm.TTlManager.addEntry(item)
+			kvOperations.WithLabelValues("incr", "ok").Inc()
+			return true
+		}
+	}
+
+	// if it not exists - set the value to the amount value
+	e := NewEntry(ttl, key, amount, hash, basket.Items)
+	hm.cardinality.add(hash)
+	basket.push(e)
+	hm.TTlManager.addEntry(e)
+	hm.Entries.Add(1)
+	hm.signalResize()
+	kvStorageSize.Set(float64(hm.Entries.Load()))
+	kvOperations.WithLabelValues("incr", "ok").Inc()
+	return true
+}
+
+// SetIfGreater sets the value only if it and the stored value are numbers and it is greater than the stored value.
+// Missing keys are set. Returns true if the value was changed - the ttl is handled like in Set.
+func (hm *HashMap) SetIfGreater(ttl int64, key, value string) bool {
+	return hm.setIf(ttl, key, value, "setifgreater", func(cur, cand int64) bool { return cand > cur })
+}
+
+// SetIfLess sets the value only if it and the stored value are numbers and it is less than the stored value.
+// Missing keys are set. Returns true if the value was changed - the ttl is handled like in Set.
+func (hm *HashMap) SetIfLess(ttl int64, key, value string) bool {
+	return hm.setIf(ttl, key, value, "setifless", func(cur, cand int64) bool { return cand < cur })
+}
+
+// setIf sets the value if the condition of the stored and the new number holds - the check and the update happen
+// under the basket lock, so concurrent calls can't lose an update
+func (hm *HashMap) setIf(ttl int64, key, value, operation string, cond func(cur, cand int64) bool) bool {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues(operation))
+	defer timer.ObserveDuration()
+
+	if !validKey(key, operation) {
+		return false
+	}
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	cand, ok := hm.checkIsNumber(value)
+	if !ok {
+		kvOperations.WithLabelValues(operation, "nan").Inc()
+		return false
+	}
+	// the value is stored in its canonical form, e.g. without leading zeros or a plus sign
+	value = strconv.FormatInt(cand, 10)
+
+	// we need global read lock
+	hm.mutex.RLock()
+	defer hm.mutex.RUnlock()
+
+	// get the right index
+	index, hash := hm.getIndex(key)
+	basket := hm.table[index]
+
+	// basketlocal write lock
+	hm.WLockBasketLock(hash)
+	defer hm.WUnlockBasketLock(hash)
+
+	for item := basket.Items; item != nil; item = item.Next {
+		if item.Key == key {
+			cur := item.Counter
+			if item.Type != TypeCounter {
+				if cur, ok = hm.checkIsNumber(item.Value); !ok {
+					kvOperations.WithLabelValues(operation, "nan").Inc()
+					return false
+				}
+			}
+			if !cond(cur, cand) {
+				kvOperations.WithLabelValues(operation, "unchanged").Inc()
+				return false
+			}
+
+			// only changes are written to the AOF - as set, since replaying the set has the same result
+			if !hm.reset {
+				frame.add(hm.Aof, Data{Action: "set", Key: key, Value: value, Ttl: ttl})
+			}
+			item.setString(value)
+			if item.Ttl != 0 {
+				hm.TTlManager.delEntry(item)
+			}
+			item.Ttl = ttl
+			hm.TTlManager.addEntry(item)
+			kvOperations.WithLabelValues(operation, "ok").Inc()
+			return true
 		}
 	}
-	log.Printf("Replayed AOF for %s", hm.Name)
-	return nil
-}
 
-// getIndex gets the Index of a Key
-func (hm *HashMap) getIndex(key string) (int, uint64) {
-	h := hm.xxhash.HashString(key)
-	index := h & uint64(hm.basketNum-1)
-	return int(index), h
+	// a missing key is set
+	if !hm.reset {
+		frame.add(hm.Aof, Data{Action: "set", Key: key, Value: value, Ttl: ttl})
+	}
+	e := NewEntry(ttl, key, value, hash, basket.Items)
+	hm.cardinality.add(hash)
+	basket.push(e)
+	hm.TTlManager.addEntry(e)
+	hm.Entries.Add(1)
+	hm.signalResize()
+	kvStorageSize.Set(float64(hm.Entries.Load()))
+	kvOperations.WithLabelValues(operation, "ok").Inc()
+	return true
 }
 
-// Set inserts or updates a key-value pair in the HashMap. Returns true if the operation is successful.
-func (hm *HashMap) Set(ttl int64, key string, value string) bool {
-	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("set"))
+// CounterIncr increments the counter stored at key by amount. The counter holds its value as raw int64, so no
+// parsing or formatting is needed on the hot path. Missing keys are created as counter, numeric string values are
+// converted into a counter. A ttl > 0 (re)sets the expiry, a ttl of 0 keeps it. Returns the new value.
+func (hm *HashMap) CounterIncr(ttl int64, key string, amount int64) (int64, bool) {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("cincr"))
 	defer timer.ObserveDuration()
 
-	// Write the AOF - this happens in a separate goroutine
-	if !hm.reset {
-		hm.Aof.com <- Data{Action: "set", Key: key, Value: value, Ttl: ttl}
+	if !validKey(key, "cincr") {
+		return 0, false
 	}
 
-	// check resize
-	select {
-	case hm.resizeCheck <- struct{}{}:
-	default:
-	}
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
 
 	// we need global read lock
 	hm.mutex.RLock()
 	defer hm.mutex.RUnlock()
 
-	// we need the index of the key
+	// get the right index
 	index, hash := hm.getIndex(key)
+	basket := hm.table[index]
 
-	// we need a Basketlocal write lock
+	// basketlocal write lock
 	hm.WLockBasketLock(hash)
 	defer hm.WUnlockBasketLock(hash)
 
-	// Get the basket which should hold / newly hold our entry
-	basket := hm.table[index]
+	if !hm.reset {
+		frame.add(hm.Aof, Data{Action: "cincr", Key: key, Value: strconv.FormatInt(amount, 10), Ttl: ttl})
+	}
 
-	// Does it exist? If yes - update value
 	for item := basket.Items; item != nil; item = item.Next {
 		if item.Key == key {
-			item.Value = value
-			// if there was a TTL add delete the entry from the TTLManager
-			if item.Ttl != 0 {
-				hm.TTlManager.delEntry(item, item.Ttl)
+			// convert a numeric string once into the counter representation
+			if item.Type != TypeCounter {
+				val, ok := hm.checkIsNumber(item.Value)
+				if !ok {
+					kvOperations.WithLabelValues("cincr", "not_a_number").Inc()
+					return 0, false
+				}
+				item.Value = ""
+				item.Type = TypeCounter
+				item.ContentType = ""
+				item.Counter = val
 			}
-			item.Ttl = ttl
-			hm.TTlManager.addEntry(item)
-			return true
+
+			// we dont want to overflow
+			sum := item.Counter + amount
+			if (amount > 0 && sum < item.Counter) || (amount < 0 && sum > item.Counter) {
+				kvOperations.WithLabelValues("cincr", "overflow").Inc()
+				return item.Counter, false
+			}
+			item.Counter = sum
+			item.touch()
+
+			// a new TTL replaces the old one
+			if ttl > 0 {
+				if item.Ttl != 0 {
+					hm.TTlManager.delEntry(item)
+				}
+				item.Ttl = ttl
+				hm.TTlManager.addEntry(item)
+			}
+			kvOperations.WithLabelValues("cincr", "ok").Inc()
+			return item.Counter, true
 		}
 	}
 
-	// If not - add it
-	e := NewEntry(ttl, key, value, hash, hm.table[index].Items)
-	hm.table[index].Items = e
+	// if it not exists - create the counter with the amount value
+	e := NewCounterEntry(ttl, key, amount, hash, basket.Items)
+	hm.cardinality.add(hash)
+	basket.push(e)
 	hm.TTlManager.addEntry(e)
 	hm.Entries.Add(1)
+	hm.signalResize()
 	kvStorageSize.Set(float64(hm.Entries.Load()))
-	kvOperations.WithLabelValues("set", "ok").Inc()
-	return true
+	kvOperations.WithLabelValues("cincr", "ok").Inc()
+	return amount, true
 }
 
-// Get retrieves the value associated with the given key from the HashMap. Returns an empty string if the key is not found.
-func (hm *HashMap) Get(key string) (bool, string) {
-	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("get"))
+// IncrAndCheck increments the counter stored at key by one if it is below limit - a rate limiter. A missing key is
+// created with ttl, which opens the window, later calls keep the expiry. Returns the count and true if the call is
+// allowed. Calls above the limit don't increment the counter. Keys which hold no number are never allowed.
+func (hm *HashMap) IncrAndCheck(key string, limit int64, ttl int64) (int64, bool) {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("incrcheck"))
 	defer timer.ObserveDuration()
 
+	if !validKey(key, "incrcheck") {
+		return 0, false
+	}
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
 	// we need global read lock
 	hm.mutex.RLock()
 	defer hm.mutex.RUnlock()
@@ -254,31 +1120,89 @@ func (hm *HashMap) Get(key string) (bool, string) {
 	index, hash := hm.getIndex(key)
 	basket := hm.table[index]
 
-	// we need a Basketlocal write lock
-	hm.RLockBasketLock(hash)
-	defer hm.RUnlockBasketLock(hash)
+	// basketlocal write lock
+	hm.WLockBasketLock(hash)
+	defer hm.WUnlockBasketLock(hash)
 
-	// Try to get the value in existing entries
 	for item := basket.Items; item != nil; item = item.Next {
 		if item.Key == key {
-			kvOperations.WithLabelValues("get", "found").Inc()
-			return true, item.Value
+			// convert a numeric string once into the counter representation
+			if item.Type != TypeCounter {
+				val, ok := hm.checkIsNumber(item.Value)
+				if !ok {
+					kvOperations.WithLabelValues("incrcheck", "not_a_number").Inc()
+					return 0, false
+				}
+				item.Value = ""
+				item.Type = TypeCounter
+				item.ContentType = ""
+				item.Counter = val
+			}
+			if item.Counter >= limit {
+				kvOperations.WithLabelValues("incrcheck", "rejected").Inc()
+				return item.Counter, false
+			}
+
+			// replayed as counter increment which keeps the expiry
+			if !hm.reset {
+				frame.add(hm.Aof, Data{Action: "cincr", Key: key, Value: "1"})
+			}
+			item.Counter++
+			item.touch()
+			kvOperations.WithLabelValues("incrcheck", "ok").Inc()
+			return item.Counter, true
 		}
 	}
 
-	// it doesent exist!
-	kvOperations.WithLabelValues("get", "not_found").Inc()
-	return false, ""
+	if limit < 1 {
+		kvOperations.WithLabelValues("incrcheck", "rejected").Inc()
+		return 0, false
+	}
+
+	// the first call creates the counter and starts the window
+	if !hm.reset {
+		frame.add(hm.Aof, Data{Action: "cincr", Key: key, Value: "1", Ttl: ttl})
+	}
+	e := NewCounterEntry(ttl, key, 1, hash, basket.Items)
+	hm.cardinality.add(hash)
+	basket.push(e)
+	hm.TTlManager.addEntry(e)
+	hm.Entries.Add(1)
+	hm.signalResize()
+	kvStorageSize.Set(float64(hm.Entries.Load()))
+	kvOperations.WithLabelValues("incrcheck", "ok").Inc()
+	return 1, true
 }
 
-// Incr increments the value associated with the given key by the given amount. Returns the new value.
-func (hm *HashMap) Incr(ttl int64, key, amount string) bool {
-	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("incr"))
+// CounterDecr decrements the counter stored at key by amount. See CounterIncr.
+func (hm *HashMap) CounterDecr(ttl int64, key string, amount int64) (int64, bool) {
+	if amount == math.MinInt64 {
+		return 0, false
+	}
+	return hm.CounterIncr(ttl, key, -amount)
+}
+
+// SetBit sets or clears the bit at offset in the value stored at key. The value grows as needed (zero padded)
+// up to the configured entry size. Returns the original bit value and true if the operation was successful.
+func (hm *HashMap) SetBit(key string, offset int64, bit int) (int, bool) {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("setbit"))
 	defer timer.ObserveDuration()
-	// Writes the AOF - this happens in a separate goroutine
-	if !hm.reset {
-		hm.Aof.com <- Data{Action: "incr", Key: key, Value: amount}
+
+	if !validKey(key, "setbit") {
+		return 0, false
+	}
+
+	// the resulting value is only known under the basket lock - its fsync is waited for once the locks are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	// validate the input - bits are 0 or 1 and the resulting value must fit into an entry
+	if offset < 0 || (bit != 0 && bit != 1) || offset/8 >= int64(*envhandler.ENV.ENTRY_SIZE) {
+		kvOperations.WithLabelValues("setbit", "invalid").Inc()
+		return 0, false
 	}
+	byteIndex := int(offset / 8)
+	mask := byte(1 << (7 - uint(offset%8)))
 
 	// we need global read lock
 	hm.mutex.RLock()
@@ -292,52 +1216,109 @@ func (hm *HashMap) Incr(ttl int64, key, amount string) bool {
 	hm.WLockBasketLock(hash)
 	defer hm.WUnlockBasketLock(hash)
 
-	// we need the amount as int64
+	// find the entry - if it not exists we create a new one
+	var entry *Entry
 	for item := basket.Items; item != nil; item = item.Next {
 		if item.Key == key {
-			// make a number from item.Value and amount
-			val, ok := hm.checkIsNumber(item.Value)
-			if !ok {
-				return false
-			}
+			entry = item
+			break
+		}
+	}
 
-			add, ok := hm.checkIsNumber(amount)
-			if !ok {
-				return false
-			}
-			item.Value = strconv.FormatInt(val+add, 10)
+	// collections have no bits
+	if entry != nil && entry.isCollection() {
+		kvOperations.WithLabelValues("setbit", "wrong_type").Inc()
+		return 0, false
+	}
 
-			// if there was a TTL add delete the entry from the TTLManager
-			if item.Ttl != 0 {
-				hm.TTlManager.delEntry(item, item.Ttl)
-			}
-			item.Ttl = ttl
-			hm.TTlManager.addEntry(item)
-			kvOperations.WithLabelValues("incr", "ok").Inc()
-			return true
+	// copy the value into a buffer which is big enough to hold the offset
+	var buf []byte
+	if entry != nil && len(entry.StringValue()) > byteIndex {
+		buf = []byte(entry.StringValue())
+	} else {
+		buf = make([]byte, byteIndex+1)
+		if entry != nil {
+			copy(buf, entry.StringValue())
 		}
 	}
 
-	// if it not exists - set the value to the amount value
-	e := NewEntry(ttl, key, amount, hash, basket.Items)
-	basket.Items = e
-	hm.TTlManager.addEntry(e)
+	// remember the old bit and set the new one
+	old := 0
+	if buf[byteIndex]&mask != 0 {
+		old = 1
+	}
+	if bit == 1 {
+		buf[byteIndex] |= mask
+	} else {
+		buf[byteIndex] &^= mask
+	}
+	value := string(buf)
+
+	// Write the resulting value to the AOF - with the remaining TTL, a replay must not restart the expiry
+	if !hm.reset {
+		ttl := int64(0)
+		if entry != nil {
+			ttl = entry.remainingTtl()
+		}
+		frame.add(hm.Aof, Data{Action: "set", Key: key, Value: value, Ttl: ttl})
+	}
+
+	if entry != nil {
+		entry.setString(value)
+		kvOperations.WithLabelValues("setbit", "ok").Inc()
+		return old, true
+	}
+
+	// if it not exists - create it without a TTL
+	e := NewEntry(0, key, value, hash, basket.Items)
+	hm.cardinality.add(hash)
+	basket.push(e)
 	hm.Entries.Add(1)
+	hm.signalResize()
 	kvStorageSize.Set(float64(hm.Entries.Load()))
-	kvOperations.WithLabelValues("incr", "ok").Inc()
-	return true
+	kvOperations.WithLabelValues("setbit", "ok").Inc()
+	return old, true
+}
+
+// GetBit returns the bit at offset in the value stored at key. Missing keys and offsets beyond the value return 0.
+func (hm *HashMap) GetBit(key string, offset int64) int {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("getbit"))
+	defer timer.ObserveDuration()
+
+	if offset < 0 {
+		return 0
+	}
+
+	ok, value := hm.Get(key)
+	if !ok || offset/8 >= int64(len(value)) {
+		return 0
+	}
+
+	if value[offset/8]&byte(1<<(7-uint(offset%8))) != 0 {
+		return 1
+	}
+	return 0
 }
 
 // Del deletes the entry associated with the provided key from the HashMap.
 // Returns true if the key was found and successfully removed; otherwise, returns false.
 func (hm *HashMap) Del(key string) bool {
+	return hm.del(key, false)
+}
+
+// delExpired deletes an expired key for the TTLManager like Del - the changelog records it as expired
+func (hm *HashMap) delExpired(key string) bool {
+	return hm.del(key, true)
+}
+
+// del deletes key - expired tells an expiry from an explicit delete
+func (hm *HashMap) del(key string, expired bool) bool {
 	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("del"))
 	defer timer.ObserveDuration()
 
-	// Write the AOF - this happens in a separate goroutine
-	if !hm.reset {
-		hm.Aof.com <- Data{Action: "del", Key: key}
-	}
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
 
 	// we need global read lock
 	hm.mutex.RLock()
@@ -351,6 +1332,10 @@ func (hm *HashMap) Del(key string) bool {
 	hm.WLockBasketLock(hash)
 	defer hm.WUnlockBasketLock(hash)
 
+	if !hm.reset {
+		frame.add(hm.Aof, Data{Action: "del", Key: key, expired: expired})
+	}
+
 	// Basket is empty
 	if basket.Items == nil {
 		return false
@@ -362,12 +1347,8 @@ func (hm *HashMap) Del(key string) bool {
 	for item := basket.Items; item != nil; item = item.Next {
 		if item.Key == key {
 			// remove the entry from the TTLManager
-			hm.TTlManager.delEntry(item, item.Ttl)
-			if prev != nil {
-				prev.Next = item.Next
-			} else {
-				basket.Items = item.Next
-			}
+			hm.TTlManager.delEntry(item)
+			basket.unlink(item, prev)
 			hm.Entries.Add(^uint64(0))
 			hm.deletedEntries.Add(1)
 			kvStorageSize.Set(float64(hm.Entries.Load()))
@@ -383,40 +1364,122 @@ func (hm *HashMap) Del(key string) bool {
 // checkNewBasket checks if the load factor exceeds 0.75 and resizes the HashMap by doubling its capacity if necessary.
 func (hm *HashMap) checkNewBasket() {
 	newSize := len(hm.table) * 2
-	newTable := make([]*Basket, newSize)
-
-	for i := 0; i < newSize; i++ {
-		newTable[i] = NewBasket()
-	}
+	table := newTable(newSize)
 
 	for _, oldBucket := range hm.table {
 		for item := oldBucket.Items; item != nil; {
 			next := item.Next
 			newIndex := int(item.Hash & uint64(newSize-1))
-			item.Next = newTable[newIndex].Items
-			newTable[newIndex].Items = item
+			item.Next = table[newIndex].Items
+			table[newIndex].push(item)
 			item = next
 		}
 	}
-	hm.table = newTable
-	hm.basketNum = newSize
+	hm.table = table
+	hm.basketNum.Store(int64(newSize))
 }
 
-// GetAllEntriesAndCompress returns a slice of all entries in the HashMap
-// This is needed for compression of the AOF
+// GetAllEntriesAndCompress returns a slice of all entries in the HashMap - the compaction and snapshots stream them
+// with walkEntries instead
 func (hm *HashMap) GetAllEntriesAndCompress() []*AOFEntry {
+	var entries []*AOFEntry
+	_ = hm.walkEntries(func(e *AOFEntry) error {
+		entry := *e
+		entries = append(entries, &entry)
+		return nil
+	}, (*Entry).aofTtl)
+	return entries
+}
+
+// walkEntries calls emit with the AOF entries which restore the live entries, e.g. one hset per field of a hash, and
+// the TTL returned by ttl. The global lock is held for the whole walk, so the entries are a consistent cut of the
+// AOF. The entry passed to emit is reused - emit must copy it to keep it. Stops at the first error of emit and
+// returns it.
+func (hm *HashMap) walkEntries(emit func(*AOFEntry) error, ttl func(*Entry) int64) error {
 	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("compress"))
 	defer timer.ObserveDuration()
 	hm.mutex.Lock()
 	defer hm.mutex.Unlock()
-	var entries []*AOFEntry
+	return hm.walk(emit, ttl)
+}
+
+// compactEntries is the walk of the compaction, it runs in the AOF loop. The writers enqueue their frames under the
+// global read lock, so once the write lock is held the queue holds the frames of all walked changes - they are
+// drained to the old file first, written after the compacted entries they would be replayed twice.
+func (hm *HashMap) compactEntries(emit func(*AOFEntry) error) error {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("compress"))
+	defer timer.ObserveDuration()
+	hm.mutex.Lock()
+	defer hm.mutex.Unlock()
+	hm.Aof.drain()
+	return hm.walk(emit, (*Entry).aofTtl)
+}
+
+// walk emits the entries for walkEntries and compactEntries - must be called with the global lock held
+func (hm *HashMap) walk(emit func(*AOFEntry) error, ttl func(*Entry) int64) error {
+	var e AOFEntry
+	write := func(action, key, value string, ttl int64) error {
+		e = AOFEntry{Action: action, Key: key, Value: value, Ttl: ttl}
+		return emit(&e)
+	}
 	for _, bucket := range hm.table {
 		for item := bucket.Items; item != nil; item = item.Next {
-			d := &AOFEntry{Key: item.Key, Value: item.Value, Ttl: item.Ttl}
-			entries = append(entries, d)
+			var err error
+			switch item.Type {
+			// hashes are restored field by field
+			case TypeHash:
+				for field, value := range item.Fields {
+					if err = write("hset", item.Key, packValue(field, value), 0); err != nil {
+						break
+					}
+				}
+			// sets are restored member by member
+			case TypeSet:
+				for member := range item.Members {
+					if err = write("sadd", item.Key, member, 0); err != nil {
+						break
+					}
+				}
+			// lists are restored value by value
+			case TypeList:
+				for _, value := range item.List {
+					if err = write("rpush", item.Key, value, 0); err != nil {
+						break
+					}
+				}
+			// sorted sets are restored member by member
+			case TypeZSet:
+				for _, z := range item.Ranked {
+					value := packValue(strconv.FormatFloat(z.Score, 'g', -1, 64), z.Member)
+					if err = write("zadd", item.Key, value, 0); err != nil {
+						break
+					}
+				}
+			// counters are restored as counters
+			case TypeCounter:
+				err = write("cincr", item.Key, item.StringValue(), ttl(item))
+			default:
+				if item.ContentType != "" {
+					err = write("setct", item.Key, packValue(item.ContentType, item.Value), ttl(item))
+				} else {
+					err = write("set", item.Key, item.Value, ttl(item))
+				}
+			}
+			if err != nil {
+				return err
+			}
 		}
 	}
-	return entries
+	return nil
+}
+
+// GetApproxCardinality returns the estimated number of distinct keys written since the DB was loaded, including
+// keys deleted since. Without HKV_APPROX_CARDINALITY it returns the exact number of entries.
+func (hm *HashMap) GetApproxCardinality() int64 {
+	if hm.cardinality == nil {
+		return hm.GetEntries()
+	}
+	return hm.cardinality.estimate()
 }
 
 // GetBasketNum returns the number of baskets in the HashMap
@@ -431,23 +1494,150 @@ func (hm *HashMap) GetEntries() int64 {
 	return int64(hm.Entries.Load())
 }
 
+// Flush deletes all keys and FiFo/LiFos of the HashMap and returns the number of deleted keys. The flush is written
+// to the AOF, which gets compacted afterward. A closed HashMap is not flushed, e.g. one dropped meanwhile.
+func (hm *HashMap) Flush() int64 {
+	// Close waits until the flush is done
+	hm.loadMu.Lock()
+	defer hm.loadMu.Unlock()
+	if hm.closed {
+		return 0
+	}
+	return hm.flush()
+}
+
+// flush is Flush without the check for a closed HashMap - the replay holds the load lock already
+func (hm *HashMap) flush() int64 {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("flush"))
+	defer timer.ObserveDuration()
+
+	// the frame is enqueued under the global lock - under the always fsync policy the flush returns after the AOF is
+	// synced
+	var frame pendingFrame
+	var deleted int64
+	func() {
+		// the global write lock keeps out all other operations
+		hm.mutex.Lock()
+		defer hm.mutex.Unlock()
+
+		if !hm.reset {
+			frame.add(hm.Aof, Data{Action: "flush"})
+		}
+		deleted = int64(hm.Entries.Load())
+
+		hm.table = newTable(hm.initialBasketSize())
+		hm.basketNum.Store(int64(len(hm.table)))
+		hm.TTlManager.clear()
+		hm.cardinality.reset()
+		hm.fifolifos.Clear()
+		hm.Entries.Store(0)
+		hm.deletedEntries.Store(0)
+		kvStorageSize.Set(0)
+	}()
+	frame.wait()
+
+	// the compaction takes the global lock as well, so it runs after it got released
+	if !hm.reset {
+		hm.Aof.Compact()
+	}
+	kvOperations.WithLabelValues("flush", "ok").Inc()
+	return deleted
+}
+
+// Sync blocks until all writes done so far are fsynced to the AOF
+func (hm *HashMap) Sync() error {
+	return hm.Aof.Sync()
+}
+
 // Close Closes the AOF and Hashmap
 func (hm *HashMap) Close() error {
-	hm.TTlManager.Stop()
-	err := hm.Aof.Close()
+	return hm.close(true)
+}
+
+// Drop closes the HashMap like Close for a DB which gets deleted - its AOF is not compacted anymore
+func (hm *HashMap) Drop() error {
+	return hm.close(false)
+}
+
+// close stops the HashMap and closes its AOF - with compact a due compaction is done first
+func (hm *HashMap) close(compact bool) error {
+	// wait for a running load
+	hm.loadMu.Lock()
+	defer hm.loadMu.Unlock()
+	if hm.closed {
+		return nil
+	}
+	hm.closed = true
+
+	// the shutdown goes from the producers of AOF frames to the AOF: the checker finishes a running compaction,
+	// the TTLManager its expiry - then a due compaction is done, so the next start replays a compact AOF
 	close(hm.done)
-	return err
+	hm.checker.Wait()
+	hm.TTlManager.Stop()
+	if compact && hm.Ready() && hm.needsCompaction() {
+		hm.Aof.Compact()
+	}
+	return hm.Aof.Close()
 }
 
-// CheckResize locks the HashMap and checks if the load factor exceeds 0.75; triggers resizing if necessary.
+// CheckResize locks the HashMap and doubles the baskets until the load factor is at most 0.75
 func (hm *HashMap) CheckResize() {
 	hm.mutex.Lock()
 	defer hm.mutex.Unlock()
-	if float64(hm.Entries.Load())/float64(len(hm.table)) > 0.75 {
+	for hm.overloaded() {
 		hm.checkNewBasket()
 	}
 }
 
+// overloaded returns true if the load factor exceeds 0.75 - it only reads atomics and needs no lock
+func (hm *HashMap) overloaded() bool {
+	return float64(hm.Entries.Load()) > 0.75*float64(hm.basketNum.Load())
+}
+
+// signalResize wakes up the ResizeChecker after an insert. A dropped signal is fine, since a pending one already
+// makes the checker read the current load factor.
+func (hm *HashMap) signalResize() {
+	select {
+	case hm.resizeCheck <- struct{}{}:
+	default:
+	}
+}
+
+// FitValue applies HKV_OVERSIZE_POLICY to a value above HKV_ENTRY_SIZE bytes. It returns the value to store,
+// whether it was truncated and false if it has to be rejected.
+func FitValue(value string) (string, bool, bool) {
+	limit := *envhandler.ENV.ENTRY_SIZE
+	if len(value) <= limit {
+		return value, false, true
+	}
+	if envhandler.ENV.OversizePolicy() != envhandler.OVERSIZE_TRUNCATE {
+		return value, false, false
+	}
+	// don't cut a UTF-8 character in half
+	for limit > 0 && !utf8.RuneStart(value[limit]) {
+		limit--
+	}
+	return value[:limit], true, true
+}
+
+// FitTTL applies HKV_MAX_TTL_SECONDS and HKV_ALLOW_NO_EXPIRY to a ttl in seconds, 0 meaning no expiry. It returns the
+// ttl to store and false if it has to be rejected. Under HKV_TTL_POLICY=clamp a ttl above the cap gets the cap, and so
+// does a ttl of 0 if no expiry is disallowed - without a cap there is nothing to clamp to, so it is rejected.
+func FitTTL(ttl int64) (int64, bool) {
+	limit := int64(envhandler.ENV.MaxTTLSeconds())
+	clamp := envhandler.ENV.TTLPolicy() == envhandler.TTL_CLAMP
+	if ttl == 0 {
+		if *envhandler.ENV.ALLOW_NO_EXPIRY {
+			return 0, true
+		}
+		return limit, clamp && limit > 0
+	}
+	if limit == 0 || ttl <= limit {
+		return ttl, true
+	}
+	return limit, clamp
+}
+
 // checkIsNumber checks if the given string is a number
 func (hm *HashMap) checkIsNumber(s string) (int64, bool) {
 	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
@@ -476,10 +1666,13 @@ func (hm *HashMap) RUnlockBasketLock(index uint64) {
 	hm.basketLocks[index&uint64(hm.basketLockNum-1)].RUnlock()
 }
 
-// ResizeChecker processes resize check signals and triggers resize if a threshold of 1000 signals is met.
+// compactInterval is the interval in which the ResizeChecker checks for a due compaction - a variable for tests
+var compactInterval = 60 * time.Second
+
+// ResizeChecker resizes the table as soon as an insert pushes the load factor above 0.75 and periodically
+// triggers the AOF compaction. It returns once the HashMap is closed.
 func (hm *HashMap) ResizeChecker() {
-	inputs := 0
-	resizeTicker := time.NewTicker(60 * time.Second)
+	resizeTicker := time.NewTicker(compactInterval)
 
 	// on return clean up
 	defer func() {
@@ -490,19 +1683,15 @@ func (hm *HashMap) ResizeChecker() {
 	for {
 		select {
 		case <-hm.resizeCheck:
-			inputs++
-			if inputs%1000 == 0 {
+			// the global write lock is only taken if the table is really too small
+			if hm.overloaded() {
 				hm.CheckResize()
-				inputs = 0
 			}
 		case <-resizeTicker.C:
-			// this will compress the AOF file
-			entries := hm.Entries.Load()
-			deleted := hm.deletedEntries.Load()
-
-			if (entries > 2 || deleted > 2) && deleted >= int64(entries)/2 {
-				// this will compress the AOF file
-				hm.Aof.compressing <- struct{}{}
+			// the AOF loop only runs once the DB is loaded - a request before would block the checker
+			if hm.Ready() && hm.needsCompaction() {
+				// this will compress the AOF file and waits for it, so Close does not cut it off
+				hm.Aof.Compact()
 				hm.deletedEntries.Store(0)
 			}
 		case <-hm.done:
@@ -511,6 +1700,83 @@ func (hm *HashMap) ResizeChecker() {
 	}
 }
 
+// needsCompaction returns true if enough entries got deleted (HKV_COMPACT_RATIO) or the AOF has grown too much
+// since the last compaction (HKV_COMPACT_GROWTH) - the latter catches overwrite heavy workloads
+func (hm *HashMap) needsCompaction() bool {
+	entries := hm.Entries.Load()
+	deleted := hm.deletedEntries.Load()
+
+	if (entries > 2 || deleted > 2) && float64(deleted) >= float64(entries)*(*envhandler.ENV.COMPACT_RATIO) {
+		return true
+	}
+
+	// a full storage may be freed by a compaction after deletes
+	if hm.storageFull.Load() {
+		return true
+	}
+	return hm.Aof.Grown(*envhandler.ENV.COMPACT_GROWTH)
+}
+
+// CheckStorage returns true if writes are allowed. If the AOF exceeds HKV_MAX_AOF_BYTES a compaction is tried
+// first - if the live data itself is too big, the storage is marked as full and writes are rejected until the
+// AOF is below the limit again. Must not be called while holding HashMap locks.
+func (hm *HashMap) CheckStorage() bool {
+	limit := int64(*envhandler.ENV.MAX_AOF_BYTES)
+	if limit <= 0 || hm.Aof.Size() <= limit {
+		hm.storageFull.Store(false)
+		return true
+	}
+
+	// already known to be full - a compaction wont help until the next periodic one
+	if hm.storageFull.Load() {
+		return false
+	}
+
+	// only one writer tries the compaction, the others wait for its result
+	hm.storageMu.Lock()
+	defer hm.storageMu.Unlock()
+	if hm.storageFull.Load() {
+		return false
+	}
+	if hm.Aof.Size() <= limit {
+		return true
+	}
+
+	hm.Aof.Compact()
+	hm.deletedEntries.Store(0)
+	if hm.Aof.Size() <= limit {
+		return true
+	}
+
+	log.Printf("AOF of DB %s exceeds %d bytes after compaction - rejecting writes", hm.Name, limit)
+	hm.storageFull.Store(true)
+	return false
+}
+
+// StorageFull returns true if writes are rejected because the AOF exceeds HKV_MAX_AOF_BYTES
+func (hm *HashMap) StorageFull() bool {
+	return hm.storageFull.Load()
+}
+
+// TTLEntries returns the number of entries with a TTL and of their expiry buckets - the bookkeeping of the TTLManager
+func (hm *HashMap) TTLEntries() (entries, buckets int64) {
+	return hm.TTlManager.Entries()
+}
+
+// Throttled returns true if writes are rejected because the AOF can't keep up - see AOF.Throttled
+func (hm *HashMap) Throttled() bool {
+	return hm.Aof.Throttled()
+}
+
+// CompactRatio returns the current ratio of deleted to live entries since the last compaction
+func (hm *HashMap) CompactRatio() float64 {
+	entries := hm.Entries.Load()
+	if entries == 0 {
+		return 0
+	}
+	return float64(hm.deletedEntries.Load()) / float64(entries)
+}
+
 // AddFifoLifo adds a new FifoLifo instance to the server's map of FifoLifos, keyed by the specified name.'
 func (hm *HashMap) AddFifoLifo(name string, maxEntries int) error {
 	if _, ok := hm.fifolifos.Load(name); ok {
@@ -527,9 +1793,13 @@ func (hm *HashMap) AddFifoLifo(name string, maxEntries int) error {
 	return err
 }
 
-// DelFiFoLiFo deletes a FifoLifo instance from the server's map of FifoLifos, keyed by the specified name.'
-func (hm *HashMap) DelFiFoLiFo(name string) {
-	hm.fifolifos.Delete(name)
+// DelFiFoLiFo deletes a FifoLifo instance from the server's map of FifoLifos, keyed by the specified name.
+// Returns fifolifo.ErrNotFound if it does not exist.
+func (hm *HashMap) DelFiFoLiFo(name string) error {
+	if _, ok := hm.fifolifos.LoadAndDelete(name); !ok {
+		return fmt.Errorf("FifoLifo with name %s: %w", name, fifolifo.ErrNotFound)
+	}
+	return nil
 }
 
 // PushEntryFiFoLiFo adds an Entry to the Fifo Lifo
@@ -538,7 +1808,7 @@ func (hm *HashMap) PushEntryFiFoLiFo(fifolifoName, data string) (bool, error) {
 	// We are checking for empty data in the Api - so dont worry here :) ++ look in models!
 	val, ok := hm.fifolifos.Load(fifolifoName)
 	if !ok {
-		return false, fmt.Errorf("FifoLifo with name %s does not exist", fifolifoName)
+		return false, fmt.Errorf("FifoLifo with name %s: %w", fifolifoName, fifolifo.ErrNotFound)
 	}
 	return (val.(*fifolifo.FifoLifo)).Push(data)
 }
@@ -548,7 +1818,7 @@ func (hm *HashMap) PopEntryFiFo(fifolifoName string) (string, error) {
 
 	val, ok := hm.fifolifos.Load(fifolifoName)
 	if !ok {
-		return "", fmt.Errorf("FifoLifo with name %s does not exist", fifolifoName)
+		return "", fmt.Errorf("FifoLifo with name %s: %w", fifolifoName, fifolifo.ErrNotFound)
 	}
 	return (val.(*fifolifo.FifoLifo)).FPop()
 }
@@ -558,7 +1828,39 @@ func (hm *HashMap) PopEntryLiFo(fifolifoName string) (string, error) {
 
 	val, ok := hm.fifolifos.Load(fifolifoName)
 	if !ok {
-		return "", fmt.Errorf("FifoLifo with name %s does not exist", fifolifoName)
+		return "", fmt.Errorf("FifoLifo with name %s: %w", fifolifoName, fifolifo.ErrNotFound)
 	}
 	return (val.(*fifolifo.FifoLifo)).LPop()
 }
+
+// BPopEntryFiFo removes an Entry from the Fifo Lifo and waits up to timeout for a push if it is empty. Returns
+// fifolifo.ErrEmpty if nothing was pushed in time.
+func (hm *HashMap) BPopEntryFiFo(ctx context.Context, fifolifoName string, timeout time.Duration) (string, error) {
+
+	val, ok := hm.fifolifos.Load(fifolifoName)
+	if !ok {
+		return "", fmt.Errorf("FifoLifo with name %s: %w", fifolifoName, fifolifo.ErrNotFound)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	data, err := (val.(*fifolifo.FifoLifo)).BFPop(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "", fifolifo.ErrEmpty
+	}
+	return data, err
+}
+
+// MoveFiFoLiFo pops the oldest Entry of the src Fifo Lifo and pushes it to the dst Fifo Lifo atomically
+func (hm *HashMap) MoveFiFoLiFo(src, dst string) (string, error) {
+
+	srcVal, ok := hm.fifolifos.Load(src)
+	if !ok {
+		return "", fmt.Errorf("FifoLifo with name %s: %w", src, fifolifo.ErrNotFound)
+	}
+	dstVal, ok := hm.fifolifos.Load(dst)
+	if !ok {
+		return "", fmt.Errorf("FifoLifo with name %s: %w", dst, fifolifo.ErrNotFound)
+	}
+	return fifolifo.Move(srcVal.(*fifolifo.FifoLifo), dstVal.(*fifolifo.FifoLifo))
+}
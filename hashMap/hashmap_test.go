@@ -1,12 +1,28 @@
 package hashMap
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"hydrakv/envhandler"
+	"io"
+	"maps"
+	"math"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // helper to create a unique AOF Name per test and ensure cleanup
@@ -115,6 +131,41 @@ func TestHashMap_ResizeOnLoadFactor(t *testing.T) {
 	}
 }
 
+func TestHashMap_ResizeBurst(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	// a burst of concurrent inserts - most resize signals are dropped
+	const workers, keys = 8, 5000
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < keys; i++ {
+				hm.Set(0, fmt.Sprintf("w%d-k%d", w, i), "v")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the table has to follow the load without a manual CheckResize
+	deadline := time.Now().Add(2 * time.Second)
+	for float64(hm.GetEntries()) > 0.75*float64(hm.GetBasketNum()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("table not resized: %d entries in %d baskets", hm.GetEntries(), hm.GetBasketNum())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func TestAOF_ConsistencyReplay(t *testing.T) {
 	name := uniqueAOFName(t)
 
@@ -164,6 +215,51 @@ func TestAOF_ConsistencyReplay(t *testing.T) {
 	}
 }
 
+func TestAOF_ReplayOverwrites(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	// overwrites around other actions - the coalesced sets must keep their order to them
+	for i := 0; i < 1000; i++ {
+		hm.Set(0, "a", strconv.Itoa(i))
+		hm.Set(0, "b", "b-"+strconv.Itoa(i))
+	}
+	hm.Incr(0, "a", "1")
+	hm.Set(0, "c", "gone")
+	hm.Del("c")
+	hm.Set(0, "b", "last")
+	hm.Set(3600, "d", "ttl")
+	hm.Set(0, "d", "no-ttl")
+	if err := hm.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	hm, err = NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap reopen error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+	for key, want := range map[string]string{"a": "1000", "b": "last", "d": "no-ttl"} {
+		if ok, v := hm.Get(key); !ok || v != want {
+			t.Fatalf("%s after replay: got %q, %v want %q", key, v, ok, want)
+		}
+	}
+	if ok, _ := hm.Get("c"); ok {
+		t.Fatalf("c deleted before the replay ended, but found")
+	}
+	if meta, _ := hm.Meta("d"); meta.Ttl != 0 {
+		t.Fatalf("d was overwritten without TTL, got ttl %d", meta.Ttl)
+	}
+	if hm.GetEntries() != 3 {
+		t.Fatalf("entries after replay: got %d want 3", hm.GetEntries())
+	}
+}
+
 func TestHashMap_Incr(t *testing.T) {
 	name := uniqueAOFName(t)
 	hm, err := NewHashMap(name)
@@ -219,6 +315,221 @@ func TestHashMap_Incr(t *testing.T) {
 	}
 }
 
+func TestHashMap_Counter(t *testing.T) {
+	name := uniqueAOFName(t)
+
+	// Phase 1: write counters
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+
+		// 1. CounterIncr on non-existing key creates the counter
+		if v, ok := hm.CounterIncr(0, "hits", 5); !ok || v != 5 {
+			t.Fatalf("Expected 5, got %d (ok=%v)", v, ok)
+		}
+		if v, ok := hm.CounterIncr(0, "hits", 3); !ok || v != 8 {
+			t.Fatalf("Expected 8, got %d (ok=%v)", v, ok)
+		}
+		if v, ok := hm.CounterDecr(0, "hits", 2); !ok || v != 6 {
+			t.Fatalf("Expected 6, got %d (ok=%v)", v, ok)
+		}
+
+		// 2. Get formats the counter on demand
+		if ok, v := hm.Get("hits"); !ok || v != "6" {
+			t.Fatalf("Expected 6, got %s (ok=%v)", v, ok)
+		}
+
+		// 3. the general Incr works on counters as well
+		if ok := hm.Incr(0, "hits", "4"); !ok {
+			t.Fatal("Incr on counter failed")
+		}
+
+		// 4. numeric strings are converted - others fail
+		hm.Set(0, "numeric", "41")
+		if v, ok := hm.CounterIncr(0, "numeric", 1); !ok || v != 42 {
+			t.Fatalf("Expected 42, got %d (ok=%v)", v, ok)
+		}
+		hm.Set(0, "alpha", "not-a-number")
+		if _, ok := hm.CounterIncr(0, "alpha", 1); ok {
+			t.Fatal("CounterIncr on non-numeric value should have failed")
+		}
+
+		// 5. overflow is rejected
+		hm.CounterIncr(0, "big", math.MaxInt64)
+		if _, ok := hm.CounterIncr(0, "big", 1); ok {
+			t.Fatal("CounterIncr should have failed on overflow")
+		}
+
+		// 6. Set replaces the counter with a string again
+		hm.CounterIncr(0, "replaced", 1)
+		hm.Set(0, "replaced", "text")
+		if ok, v := hm.Get("replaced"); !ok || v != "text" {
+			t.Fatalf("Expected text, got %s", v)
+		}
+
+		if err := hm.Close(); err != nil {
+			t.Fatalf("Close error: %v", err)
+		}
+	}
+
+	// Phase 2: reopen and validate replay
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap reopen error: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = hm.Close()
+			removeAOF(t, name)
+		})
+
+		for key, want := range map[string]string{"hits": "10", "numeric": "42", "alpha": "not-a-number", "replaced": "text"} {
+			if ok, v := hm.Get(key); !ok || v != want {
+				t.Fatalf("wrong value after replay for %s: got %s want %s", key, v, want)
+			}
+		}
+	}
+}
+
+func TestHashMap_EmptyKeyAndValue(t *testing.T) {
+	name := uniqueAOFName(t)
+	t.Cleanup(func() { removeAOF(t, name) })
+
+	// an AOF written before empty keys were rejected
+	data := appendHeader(nil, 0)
+	data = appendFrame(data, Data{Action: "set", Key: "", Value: "v"})
+	data = appendFrame(data, Data{Action: "set", Key: "empty", Value: ""})
+	if err := os.WriteFile(filepath.Join(*envhandler.ENV.DB_FOLDER, name+".bin"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Phase 1: the replay skips the empty key and keeps the empty value
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		if got := hm.GetEntries(); got != 1 {
+			t.Fatalf("expected 1 entry after the replay, got %d", got)
+		}
+		if ok, v := hm.Get("empty"); !ok || v != "" {
+			t.Fatalf("expected the empty value, got %v %q", ok, v)
+		}
+
+		// every write of an empty key is rejected
+		if hm.Set(0, "", "v") || hm.SetNX(0, "", "v") || hm.SetKeepTTL("", "v") || hm.Incr(0, "", "1") ||
+			hm.SetIfGreater(0, "", "1") || hm.HSet("", "f", "v") || hm.SAdd("", "m") || hm.RPush("", "v") ||
+			hm.ZAdd("", "m", 1) {
+			t.Fatal("expected the writes of an empty key to be rejected")
+		}
+		if _, ok := hm.CounterIncr(0, "", 1); ok {
+			t.Fatal("expected CounterIncr of an empty key to be rejected")
+		}
+		if _, ok := hm.SetBit("", 0, 1); ok {
+			t.Fatal("expected SetBit of an empty key to be rejected")
+		}
+		if ok, _ := hm.Get(""); ok || hm.Del("") {
+			t.Fatal("expected an empty key not to be found")
+		}
+
+		// an empty value is stored like any other
+		if !hm.Set(0, "empty", "") || !hm.Set(0, "other", "") {
+			t.Fatal("expected an empty value to be stored")
+		}
+		if !hm.Del("other") {
+			t.Fatal("expected the key with an empty value to be deleted")
+		}
+		_ = hm.Close()
+	}
+
+	// Phase 2: the empty value survives a replay
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		t.Cleanup(func() { _ = hm.Close() })
+		if got := hm.GetEntries(); got != 1 {
+			t.Fatalf("expected 1 entry after the replay, got %d", got)
+		}
+		if ok, v := hm.Get("empty"); !ok || v != "" {
+			t.Fatalf("expected the empty value after the replay, got %v %q", ok, v)
+		}
+	}
+}
+
+func TestHashMap_SetBitGetBit(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	// 1. SetBit on non-existing key creates a zero padded value
+	if old, ok := hm.SetBit("bits", 17, 1); !ok || old != 0 {
+		t.Fatalf("SetBit on new key: old=%d ok=%v", old, ok)
+	}
+	if ok, v := hm.Get("bits"); !ok || len(v) != 3 || v[2] != 0x40 {
+		t.Fatalf("Expected 3 byte value with 0x40 at the end, got %q (ok=%v)", v, ok)
+	}
+
+	// 2. GetBit returns the set bit and 0 for others / offsets beyond the value
+	if bit := hm.GetBit("bits", 17); bit != 1 {
+		t.Fatalf("Expected bit 17 to be 1, got %d", bit)
+	}
+	if bit := hm.GetBit("bits", 16); bit != 0 {
+		t.Fatalf("Expected bit 16 to be 0, got %d", bit)
+	}
+	if bit := hm.GetBit("bits", 1000); bit != 0 {
+		t.Fatalf("Expected bit beyond the value to be 0, got %d", bit)
+	}
+	if bit := hm.GetBit("missing", 0); bit != 0 {
+		t.Fatalf("Expected bit of missing key to be 0, got %d", bit)
+	}
+
+	// 3. Clearing returns the old bit
+	if old, ok := hm.SetBit("bits", 17, 0); !ok || old != 1 {
+		t.Fatalf("SetBit clear: old=%d ok=%v", old, ok)
+	}
+
+	// 4. SetBit on an existing string keeps the other bits ('a' = 0x61 -> 'b' = 0x62)
+	hm.Set(0, "str", "a")
+	hm.SetBit("str", 6, 1)
+	hm.SetBit("str", 7, 0)
+	if ok, v := hm.Get("str"); !ok || v != "b" {
+		t.Fatalf("Expected b, got %q", v)
+	}
+
+	// 5. invalid bits and offsets beyond the entry size are rejected
+	if _, ok := hm.SetBit("bits", 0, 2); ok {
+		t.Fatal("SetBit with bit=2 should have failed")
+	}
+	if _, ok := hm.SetBit("bits", int64(*envhandler.ENV.ENTRY_SIZE)*8, 1); ok {
+		t.Fatal("SetBit beyond the entry size should have failed")
+	}
+
+	// 6. the AOF gets the remaining TTL - a replay does not restart the expiry
+	hm.Set(100, "expiring", "a")
+	hm.withEntry("expiring", true, func(_ *Basket, item, _ *Entry, _ uint64) {
+		item.ExpireAt = time.Now().Unix() + 5
+	})
+	hm.SetBit("expiring", 6, 1)
+	_ = hm.Close()
+	hm, err = NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	if got := hm.MGetWithTTL([]string{"expiring"})[0]; !got.Found || got.Value != "c" || got.TTL > 5 {
+		t.Fatalf("expected c with at most 5s left after the replay, got %+v", got)
+	}
+}
+
 func TestHashMap_TTL(t *testing.T) {
 	name := uniqueAOFName(t)
 	hm, err := NewHashMap(name)
@@ -230,6 +541,11 @@ func TestHashMap_TTL(t *testing.T) {
 		removeAOF(t, name)
 	})
 
+	// the TTL metrics are labeled like the other metrics of the DB
+	if hm.TTlManager.Name != hm.Name || hm.Name != hm.Aof.name {
+		t.Fatalf("TTLManager name %q, DB %q, AOF %q", hm.TTlManager.Name, hm.Name, hm.Aof.name)
+	}
+
 	// 1. Set with short TTL (1 second)
 	key := "ttl-key"
 	value := "ttl-value"
@@ -280,52 +596,2842 @@ func TestHashMap_TTL(t *testing.T) {
 	}
 }
 
-// Benchmarks: measure latency of Set and Get operations
-func BenchmarkHashMap_Set(b *testing.B) {
-	name := fmt.Sprintf("bench_set_%d", time.Now().UnixNano())
+func TestHashMap_KeepTTL(t *testing.T) {
+	name := uniqueAOFName(t)
 	hm, err := NewHashMap(name)
 	if err != nil {
-		b.Fatalf("NewHashMap error: %v", err)
+		t.Fatalf("NewHashMap error: %v", err)
 	}
-	b.Cleanup(func() {
+	t.Cleanup(func() {
 		_ = hm.Close()
-		removeAOF(&testing.T{}, name)
+		removeAOF(t, name)
 	})
 
-	b.ReportAllocs()
-	for i := 0; i < b.N; i++ {
-		k := "k-" + strconv.Itoa(i)
-		v := "v-" + strconv.Itoa(i)
-		if !hm.Set(0, k, v) {
-			b.Fatalf("Set failed at %d", i)
+	// 1. SetKeepTTL keeps the expiry of an existing key
+	hm.Set(1, "session", "v1")
+	if ok := hm.SetKeepTTL("session", "v2"); !ok {
+		t.Fatal("SetKeepTTL failed")
+	}
+	if ok, v := hm.Get("session"); !ok || v != "v2" {
+		t.Fatalf("Expected v2, got %s (ok=%v)", v, ok)
+	}
+
+	// 2. a plain Set with ttl 0 clears the expiry
+	hm.Set(1, "persist", "v1")
+	hm.Set(0, "persist", "v2")
+
+	// 3. SetKeepTTL on a new key creates it without expiry
+	hm.SetKeepTTL("fresh", "v1")
+
+	time.Sleep(2500 * time.Millisecond)
+
+	if ok, _ := hm.Get("session"); ok {
+		t.Fatal("session should be expired - its TTL was kept")
+	}
+	if ok, v := hm.Get("persist"); !ok || v != "v2" {
+		t.Fatalf("persist should still exist, got %s (ok=%v)", v, ok)
+	}
+	if ok, _ := hm.Get("fresh"); !ok {
+		t.Fatal("fresh should still exist")
+	}
+}
+
+func TestAOF_GrowthTriggersCompaction(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	// 1. Overwrite the same key until the AOF holds more than the minimum size
+	value := strings.Repeat("x", 1024)
+	for i := 0; i < 1100; i++ {
+		hm.Set(0, "hot", value)
+	}
+
+	// wait until the AOF loop has written the frames
+	deadline := time.Now().Add(5 * time.Second)
+	for hm.Aof.written.Load() < minGrowthCompactSize {
+		if time.Now().After(deadline) {
+			t.Fatalf("AOF frames not written, written=%d", hm.Aof.written.Load())
 		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 2. No deletes - but the AOF has grown
+	if !hm.needsCompaction() {
+		t.Fatal("expected the grown AOF to need a compaction")
+	}
+
+	// 3. After the compaction only one entry is left, so the AOF is no longer grown
+	hm.Aof.Compact()
+	if hm.needsCompaction() {
+		t.Fatal("expected no compaction to be needed after compacting")
+	}
+	if ok, v := hm.Get("hot"); !ok || v != value {
+		t.Fatal("value lost after compaction")
 	}
 }
 
-func BenchmarkHashMap_Get(b *testing.B) {
-	name := fmt.Sprintf("bench_get_%d", time.Now().UnixNano())
+func TestHashMap_MaxAOFBytes(t *testing.T) {
+	limit := *envhandler.ENV.MAX_AOF_BYTES
+	*envhandler.ENV.MAX_AOF_BYTES = 64 * 1024
+	t.Cleanup(func() { *envhandler.ENV.MAX_AOF_BYTES = limit })
+
+	name := uniqueAOFName(t)
 	hm, err := NewHashMap(name)
 	if err != nil {
-		b.Fatalf("NewHashMap error: %v", err)
+		t.Fatalf("NewHashMap error: %v", err)
 	}
-	b.Cleanup(func() {
+	t.Cleanup(func() {
 		_ = hm.Close()
-		removeAOF(&testing.T{}, name)
+		removeAOF(t, name)
 	})
 
-	// prefill
-	const N = 10000
-	for i := 0; i < N; i++ {
-		_ = hm.Set(0, "k-"+strconv.Itoa(i), "v-"+strconv.Itoa(i))
+	waitForSize := func(min int64) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for hm.Aof.Size() <= min {
+			if time.Now().After(deadline) {
+				t.Fatalf("AOF frames not written, size=%d", hm.Aof.Size())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
 	}
 
-	b.ReportAllocs()
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		key := "k-" + strconv.Itoa(i%N)
-		ok, _ := hm.Get(key)
-		if !ok {
-			b.Fatalf("Get failed for key %s", key)
+	// 1. Overwriting one key grows the AOF, but a compaction brings it under the limit
+	value := strings.Repeat("x", 1024)
+	for i := 0; i < 100; i++ {
+		hm.Set(0, "hot", value)
+	}
+	waitForSize(64 * 1024)
+	if !hm.CheckStorage() || hm.StorageFull() {
+		t.Fatal("expected the compaction to free the storage")
+	}
+
+	// 2. Live data above the limit marks the storage as full
+	for i := 0; i < 100; i++ {
+		hm.Set(0, fmt.Sprintf("k%d", i), value)
+	}
+	waitForSize(64 * 1024)
+	if hm.CheckStorage() || !hm.StorageFull() {
+		t.Fatal("expected the storage to be full")
+	}
+
+	// 3. Deletes and a compaction make the DB writable again
+	for i := 0; i < 90; i++ {
+		hm.Del(fmt.Sprintf("k%d", i))
+	}
+	hm.Aof.Compact()
+	if !hm.CheckStorage() || hm.StorageFull() {
+		t.Fatalf("expected the storage to be writable again, size=%d", hm.Aof.Size())
+	}
+}
+
+func TestAOF_FsyncAlwaysGroupCommit(t *testing.T) {
+	policy := *envhandler.ENV.FSYNC
+	*envhandler.ENV.FSYNC = envhandler.FSYNC_ALWAYS
+	t.Cleanup(func() { *envhandler.ENV.FSYNC = policy })
+
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+
+	// 1. Concurrent writers - each overwrites its own key in order
+	const writers, rounds = 16, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				hm.Set(0, "w-"+strconv.Itoa(w), strconv.Itoa(r))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// 2. Every acknowledged write is already on disk
+	if size, want := hm.Aof.FileSize(), hm.Aof.Size(); size != want {
+		t.Fatalf("AOF not synced: file size %d want %d", size, want)
+	}
+	if err := hm.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	// 3. The replay keeps the order of the frames
+	hm, err = NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap reopen error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+	for w := 0; w < writers; w++ {
+		if ok, v := hm.Get("w-" + strconv.Itoa(w)); !ok || v != strconv.Itoa(rounds-1) {
+			t.Fatalf("wrong value after replay for w-%d: %s", w, v)
 		}
 	}
 }
+
+func TestAOF_Healthy(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	if err := hm.Healthy(); err != nil {
+		t.Fatalf("expected a healthy DB, got %v", err)
+	}
+
+	// a loop without heartbeat is reported as stalled - the running loop may beat in between, so try again
+	for i := 0; ; i++ {
+		hm.Aof.heartbeat.Store(time.Now().Add(-2 * aofStallTimeout).UnixNano())
+		err := hm.Healthy()
+		if err != nil && strings.Contains(err.Error(), "stalled") {
+			break
+		}
+		if i == 10 {
+			t.Fatalf("expected a stalled AOF loop, got %v", err)
+		}
+	}
+}
+
+// Benchmarks: measure latency of Set and Get operations
+func BenchmarkHashMap_Set(b *testing.B) {
+	name := fmt.Sprintf("bench_set_%d", time.Now().UnixNano())
+	hm, err := NewHashMap(name)
+	if err != nil {
+		b.Fatalf("NewHashMap error: %v", err)
+	}
+	b.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(&testing.T{}, name)
+	})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		k := "k-" + strconv.Itoa(i)
+		v := "v-" + strconv.Itoa(i)
+		if !hm.Set(0, k, v) {
+			b.Fatalf("Set failed at %d", i)
+		}
+	}
+}
+
+// BenchmarkHashMap_EmptyDBs reports the heap held per empty DB with and without HKV_LAZY_BASKETS - most of it is
+// the queue of the AOF loop, so the number of DBs is kept small
+func BenchmarkHashMap_EmptyDBs(b *testing.B) {
+	const dbs = 100
+	for _, lazy := range []bool{false, true} {
+		b.Run(fmt.Sprintf("lazy=%v", lazy), func(b *testing.B) {
+			old := *envhandler.ENV.LAZY_BASKETS
+			*envhandler.ENV.LAZY_BASKETS = lazy
+			defer func() { *envhandler.ENV.LAZY_BASKETS = old }()
+
+			var perDB float64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				hms := make([]*HashMap, 0, dbs)
+				for d := 0; d < dbs; d++ {
+					hm, err := OpenHashMap(fmt.Sprintf("bench_empty_%d_%d", i, d))
+					if err != nil {
+						b.Fatalf("OpenHashMap error: %v", err)
+					}
+					hms = append(hms, hm)
+				}
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				perDB = float64(after.HeapAlloc-before.HeapAlloc) / dbs
+				for _, hm := range hms {
+					_ = hm.Close()
+				}
+			}
+			b.ReportMetric(perDB, "heap-B/db")
+		})
+	}
+}
+
+func BenchmarkHashMap_SetFsyncAlways(b *testing.B) {
+	policy := *envhandler.ENV.FSYNC
+	*envhandler.ENV.FSYNC = envhandler.FSYNC_ALWAYS
+	b.Cleanup(func() { *envhandler.ENV.FSYNC = policy })
+
+	name := fmt.Sprintf("bench_set_always_%d", time.Now().UnixNano())
+	hm, err := NewHashMap(name)
+	if err != nil {
+		b.Fatalf("NewHashMap error: %v", err)
+	}
+	b.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(&testing.T{}, name)
+	})
+
+	// concurrent writers share the fsyncs
+	var n atomic.Int64
+	b.SetParallelism(16)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := n.Add(1)
+			hm.Set(0, "k-"+strconv.FormatInt(i, 10), "v")
+		}
+	})
+}
+
+func BenchmarkHashMap_Get(b *testing.B) {
+	name := fmt.Sprintf("bench_get_%d", time.Now().UnixNano())
+	hm, err := NewHashMap(name)
+	if err != nil {
+		b.Fatalf("NewHashMap error: %v", err)
+	}
+	b.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(&testing.T{}, name)
+	})
+
+	// prefill
+	const N = 10000
+	for i := 0; i < N; i++ {
+		_ = hm.Set(0, "k-"+strconv.Itoa(i), "v-"+strconv.Itoa(i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := "k-" + strconv.Itoa(i%N)
+		ok, _ := hm.Get(key)
+		if !ok {
+			b.Fatalf("Get failed for key %s", key)
+		}
+	}
+}
+
+func TestHashMap_OversizePolicy(t *testing.T) {
+	entrySize, policy := *envhandler.ENV.ENTRY_SIZE, *envhandler.ENV.OVERSIZE_POLICY
+	t.Cleanup(func() {
+		*envhandler.ENV.ENTRY_SIZE = entrySize
+		*envhandler.ENV.OVERSIZE_POLICY = policy
+	})
+	*envhandler.ENV.ENTRY_SIZE = 8
+
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() { removeAOF(t, name) })
+
+	// 1. reject keeps the old value
+	*envhandler.ENV.OVERSIZE_POLICY = envhandler.OVERSIZE_REJECT
+	hm.Set(0, "k", "small")
+	if ok := hm.Set(0, "k", "0123456789"); ok {
+		t.Fatal("oversized value should be rejected")
+	}
+	if _, v := hm.Get("k"); v != "small" {
+		t.Fatalf("Expected small, got %s", v)
+	}
+
+	// 2. truncate stores the first ENTRY_SIZE bytes - without cutting a UTF-8 character
+	*envhandler.ENV.OVERSIZE_POLICY = envhandler.OVERSIZE_TRUNCATE
+	if ok := hm.Set(0, "k", "0123456789"); !ok {
+		t.Fatal("oversized value should be truncated")
+	}
+	hm.SetKeepTTL("u", "0123456äx")
+	if _, v := hm.Get("k"); v != "01234567" {
+		t.Fatalf("Expected 01234567, got %s", v)
+	}
+	if _, v := hm.Get("u"); v != "0123456" {
+		t.Fatalf("Expected 0123456, got %q", v)
+	}
+
+	// 3. the AOF holds the truncated values
+	if err := hm.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	hm, err = NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	defer hm.Close()
+	if _, v := hm.Get("k"); v != "01234567" {
+		t.Fatalf("Expected 01234567 after replay, got %s", v)
+	}
+}
+
+func TestHashMap_SetIfGreaterLess(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	// 1. missing keys are set, then only greater / less values
+	if !hm.SetIfGreater(0, "max", "10") || hm.SetIfGreater(0, "max", "5") || hm.SetIfGreater(0, "max", "10") {
+		t.Fatal("unexpected SetIfGreater result")
+	}
+	if !hm.SetIfLess(0, "min", "10") || !hm.SetIfLess(0, "min", "-3") || hm.SetIfLess(0, "min", "7") {
+		t.Fatal("unexpected SetIfLess result")
+	}
+	if _, v := hm.Get("max"); v != "10" {
+		t.Fatalf("Expected 10, got %s", v)
+	}
+	if _, v := hm.Get("min"); v != "-3" {
+		t.Fatalf("Expected -3, got %s", v)
+	}
+
+	// 2. non-numeric values fail
+	hm.Set(0, "text", "abc")
+	if hm.SetIfGreater(0, "text", "1") || hm.SetIfGreater(0, "max", "x") {
+		t.Fatal("non-numeric values should fail")
+	}
+
+	// 3. counters are compared by their value
+	hm.CounterIncr(0, "counter", 5)
+	if hm.SetIfLess(0, "counter", "6") || !hm.SetIfGreater(0, "counter", "6") {
+		t.Fatal("unexpected result for a counter")
+	}
+
+	// 4. concurrent updates don't lose the maximum
+	var wg sync.WaitGroup
+	for i := 1; i <= 500; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hm.SetIfGreater(0, "hwm", strconv.Itoa(i))
+		}(i)
+	}
+	wg.Wait()
+	if _, v := hm.Get("hwm"); v != "500" {
+		t.Fatalf("Expected 500, got %s", v)
+	}
+}
+
+func TestHashMap_SetNX(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	// many goroutines race for the lock - exactly one acquires it
+	const racers = 64
+	var wins atomic.Int32
+	var winner atomic.Value
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < racers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			owner := "owner-" + strconv.Itoa(i)
+			if hm.SetNX(60, "leader", owner) {
+				wins.Add(1)
+				winner.Store(owner)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if wins.Load() != 1 {
+		t.Fatalf("expected exactly one winner, got %d", wins.Load())
+	}
+	if ok, v := hm.Get("leader"); !ok || v != winner.Load() {
+		t.Fatalf("lock holds %q, winner was %v", v, winner.Load())
+	}
+	hm.withEntry("leader", false, func(_ *Basket, item, _ *Entry, _ uint64) {
+		if item == nil || item.remainingTtl() <= 0 {
+			t.Fatal("expected the lock to expire")
+		}
+	})
+
+	// a collection blocks SetNX as well
+	hm.SAdd("members", "a")
+	if hm.SetNX(0, "members", "x") {
+		t.Fatal("SetNX overwrote a set")
+	}
+
+	// a released lock can be acquired again
+	hm.Del("leader")
+	if !hm.SetNX(0, "leader", "next") {
+		t.Fatal("expected SetNX to succeed after the delete")
+	}
+}
+
+func TestHashMap_GetEX(t *testing.T) {
+	name := uniqueAOFName(t)
+	expireAt := func(hm *HashMap, key string) int64 {
+		var at int64
+		hm.withEntry(key, false, func(_ *Basket, item, _ *Entry, _ uint64) {
+			if item != nil {
+				at = item.ExpireAt
+			}
+		})
+		return at
+	}
+
+	// Phase 1: refresh, keep and persist the expiry
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		hm.Set(10, "session", "s1")
+		hm.Set(10, "sticky", "s2")
+
+		if ok, v := hm.GetEX("session", 3600); !ok || v != "s1" {
+			t.Fatalf("GetEX: got %v %q", ok, v)
+		}
+		refreshed := expireAt(hm, "session")
+		if refreshed < time.Now().Unix()+3500 {
+			t.Fatalf("expected the expiry to be refreshed, got %d", refreshed)
+		}
+		if ok, _ := hm.GetEX("session", 0); !ok || expireAt(hm, "session") != refreshed {
+			t.Fatal("expected ttl 0 to keep the expiry")
+		}
+		if ok, _ := hm.GetEX("sticky", PersistTTL); !ok || expireAt(hm, "sticky") != 0 {
+			t.Fatal("expected PersistTTL to remove the expiry")
+		}
+		if ok, _ := hm.GetEX("missing", 10); ok {
+			t.Fatal("expected a missing key not to be found")
+		}
+		_ = hm.Close()
+	}
+
+	// Phase 2: the expiry changes survive a replay
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = hm.Close()
+			removeAOF(t, name)
+		})
+		if at := expireAt(hm, "session"); at < time.Now().Unix()+3500 {
+			t.Fatalf("refreshed expiry lost on replay, got %d", at)
+		}
+		if at := expireAt(hm, "sticky"); at != 0 {
+			t.Fatalf("persisted key expires after replay at %d", at)
+		}
+	}
+}
+
+func TestHashMap_Touch(t *testing.T) {
+	name := uniqueAOFName(t)
+	expireAt := func(hm *HashMap, key string) int64 {
+		var at int64
+		hm.withEntry(key, false, func(_ *Basket, item, _ *Entry, _ uint64) {
+			if item != nil {
+				at = item.ExpireAt
+			}
+		})
+		return at
+	}
+
+	// Phase 1: touch strings and counters, not collections or missing keys
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		hm.Set(10, "session", "s1")
+		hm.CounterIncr(0, "hits", 1)
+		hm.HSet("user", "name", "ada")
+
+		if !hm.Touch("session", 3600) || expireAt(hm, "session") < time.Now().Unix()+3500 {
+			t.Fatal("expected the expiry of the session to be refreshed")
+		}
+		if !hm.Touch("hits", 3600) || expireAt(hm, "hits") == 0 {
+			t.Fatal("expected the counter to get an expiry")
+		}
+		if hm.Touch("user", 3600) {
+			t.Fatal("expected a collection not to be touched")
+		}
+		if hm.Touch("missing", 3600) {
+			t.Fatal("expected a missing key not to be touched")
+		}
+		if ok, v := hm.Get("session"); !ok || v != "s1" {
+			t.Fatalf("expected the value to be kept, got %v %q", ok, v)
+		}
+		_ = hm.Close()
+	}
+
+	// Phase 2: the expiry survives a replay
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = hm.Close()
+			removeAOF(t, name)
+		})
+		if at := expireAt(hm, "session"); at < time.Now().Unix()+3500 {
+			t.Fatalf("touched expiry lost on replay, got %d", at)
+		}
+	}
+}
+
+func TestHashMap_KeyScan(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+	for i := range 1000 {
+		hm.Set(0, "k"+strconv.Itoa(i), "v")
+	}
+	for i := range 100 {
+		hm.Set(0, "d"+strconv.Itoa(i), "v")
+	}
+	hm.HSet("h", "f", "v")
+
+	scan := hm.ScanKeys()
+	if scan.Remaining() != 1101 {
+		t.Fatalf("expected 1101 hashes, got %d", scan.Remaining())
+	}
+
+	// the writes resize the table during the scan - the keys present throughout come exactly once
+	seen := make(map[string]int)
+	for page := 0; ; page++ {
+		for i := range 1000 {
+			hm.Set(0, "n"+strconv.Itoa(page*1000+i), "v")
+		}
+		hm.Del("d" + strconv.Itoa(page))
+		keys, done := scan.Next(100)
+		for _, key := range keys {
+			seen[key]++
+		}
+		if done {
+			break
+		}
+	}
+	if scan.Remaining() != 0 {
+		t.Fatalf("expected no remaining hashes, got %d", scan.Remaining())
+	}
+	if hm.GetBasketNum() == DefaultBasketSize {
+		t.Fatalf("expected the table to grow during the scan")
+	}
+	for key, n := range seen {
+		if n != 1 {
+			t.Fatalf("key %s returned %d times", key, n)
+		}
+	}
+	for i := range 1000 {
+		if seen["k"+strconv.Itoa(i)] != 1 {
+			t.Fatalf("key k%d missing", i)
+		}
+	}
+	if seen["h"] != 1 || seen["d0"] != 0 {
+		t.Fatalf("expected the hash h and not the deleted d0, got %v and %v", seen["h"], seen["d0"])
+	}
+	if keys, done := scan.Next(10); len(keys) != 0 || !done {
+		t.Fatalf("expected a finished scan, got %v", keys)
+	}
+}
+
+func TestHashMap_HotBaskets(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	// strings and collections are counted, deletes and resizes keep the counts
+	for i := range 2000 {
+		hm.Set(0, "k-"+strconv.Itoa(i), "v")
+	}
+	hm.HSet("user", "name", "ada")
+	for i := range 500 {
+		hm.Del("k-" + strconv.Itoa(i))
+	}
+	hm.HDel("user", "name")
+	hm.CheckResize()
+
+	hm.mutex.RLock()
+	var total int64
+	for _, basket := range hm.table {
+		length := int64(0)
+		for item := basket.Items; item != nil; item = item.Next {
+			length++
+		}
+		if basket.Len() != length {
+			hm.mutex.RUnlock()
+			t.Fatalf("basket count %d, chain length %d", basket.Len(), length)
+		}
+		total += length
+	}
+	hm.mutex.RUnlock()
+	if total != hm.GetEntries() {
+		t.Fatalf("basket counts sum to %d, want %d entries", total, hm.GetEntries())
+	}
+
+	hot := hm.HotBaskets(3)
+	if len(hot) != 3 {
+		t.Fatalf("expected 3 baskets, got %d", len(hot))
+	}
+	for i, b := range hot {
+		if i > 0 && b.Entries > hot[i-1].Entries {
+			t.Fatalf("baskets not sorted: %+v", hot)
+		}
+		if len(b.Keys) != int(min(b.Entries, hotBasketKeys)) {
+			t.Fatalf("expected %d keys, got %v", min(b.Entries, hotBasketKeys), b.Keys)
+		}
+		for _, key := range b.Keys {
+			if index, _ := hm.getIndex(key); index != b.Index {
+				t.Fatalf("key %s is in basket %d, not %d", key, index, b.Index)
+			}
+		}
+	}
+	if len(hm.HotBaskets(0)) != 0 {
+		t.Fatal("expected no baskets for n = 0")
+	}
+}
+
+func TestHashMap_DataFolder(t *testing.T) {
+	old := *envhandler.ENV.DB_FOLDER
+	t.Cleanup(func() { *envhandler.ENV.DB_FOLDER = old })
+
+	// missing parents are created
+	*envhandler.ENV.DB_FOLDER = filepath.Join(t.TempDir(), "nested", "data")
+	hm, err := NewHashMap("folderdb")
+	if err != nil {
+		t.Fatalf("NewHashMap in a nested folder: %v", err)
+	}
+	_ = hm.Close()
+
+	// a file in place of the folder is named in the error
+	file := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	*envhandler.ENV.DB_FOLDER = file
+	_, err = NewHashMap("folderdb")
+	if err == nil || !strings.Contains(err.Error(), "HKV_DB_FOLDER") || !strings.Contains(err.Error(), "not a directory") {
+		t.Fatalf("expected an error about the file, got %v", err)
+	}
+}
+
+func TestHashMap_LazyBaskets(t *testing.T) {
+	old := *envhandler.ENV.LAZY_BASKETS
+	*envhandler.ENV.LAZY_BASKETS = true
+	t.Cleanup(func() { *envhandler.ENV.LAZY_BASKETS = old })
+
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	// a lazy DB starts small, but never with fewer baskets than locks
+	start := hm.GetBasketNum()
+	if start >= DefaultBasketSize || start < lazyBasketSize || start < hm.basketLockNum {
+		t.Fatalf("unexpected initial baskets %d with %d locks", start, hm.basketLockNum)
+	}
+
+	// it grows like any other DB
+	for i := range 4 * start {
+		hm.Set(0, "k-"+strconv.Itoa(i), "v")
+	}
+	hm.CheckResize()
+	if got := hm.GetBasketNum(); got <= start {
+		t.Fatalf("expected the baskets to grow from %d, got %d", start, got)
+	}
+	for i := range 4 * start {
+		if ok, _ := hm.Get("k-" + strconv.Itoa(i)); !ok {
+			t.Fatalf("key k-%d lost by the resize", i)
+		}
+	}
+
+	// a flush starts small again
+	hm.Flush()
+	if got := hm.GetBasketNum(); got != start {
+		t.Fatalf("expected %d baskets after the flush, got %d", start, got)
+	}
+}
+
+func TestHashMap_Sync(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	// the writes are queued for the loop - after the sync they are on disk, without waiting for the ticker
+	for i := range 1000 {
+		hm.Set(0, "k-"+strconv.Itoa(i), "v")
+	}
+	if err := hm.Sync(); err != nil {
+		t.Fatalf("Sync error: %v", err)
+	}
+	if size, want := hm.Aof.FileSize(), hm.Aof.Size(); size != want {
+		t.Fatalf("AOF not synced: file size %d want %d", size, want)
+	}
+
+	// a closed AOF has nothing pending
+	_ = hm.Close()
+	if err := hm.Sync(); err != nil {
+		t.Fatalf("Sync after Close error: %v", err)
+	}
+}
+
+func TestHashMap_GetOrSet(t *testing.T) {
+	name := uniqueAOFName(t)
+
+	// Phase 1: the first call stores the default, the later ones return it
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		if v, created := hm.GetOrSet("cache", "v1", 3600); !created || v != "v1" {
+			t.Fatalf("expected v1 to be created, got %q %v", v, created)
+		}
+		for range 2 {
+			if v, created := hm.GetOrSet("cache", "v2", 3600); created || v != "v1" {
+				t.Fatalf("expected the stored v1, got %q %v", v, created)
+			}
+		}
+		hm.HSet("user", "name", "ada")
+		if v, created := hm.GetOrSet("user", "v1", 0); created || v != "" {
+			t.Fatalf("expected a collection to be kept, got %q %v", v, created)
+		}
+		if _, created := hm.GetOrSet("", "v1", 0); created {
+			t.Fatal("expected an empty key to be rejected")
+		}
+		_ = hm.Close()
+	}
+
+	// Phase 2: only the created value was written to the AOF
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = hm.Close()
+			removeAOF(t, name)
+		})
+		if frames := hm.ReplayProgress().Frames; frames != 2 {
+			t.Fatalf("expected the set and the hset frame, got %d frames", frames)
+		}
+		if ok, v := hm.Get("cache"); !ok || v != "v1" {
+			t.Fatalf("expected v1 after the replay, got %v %q", ok, v)
+		}
+	}
+}
+
+func TestHashMap_IncrAndCheck(t *testing.T) {
+	name := uniqueAOFName(t)
+	expireAt := func(hm *HashMap, key string) int64 {
+		var at int64
+		hm.withEntry(key, false, func(_ *Basket, item, _ *Entry, _ uint64) {
+			if item != nil {
+				at = item.ExpireAt
+			}
+		})
+		return at
+	}
+
+	// Phase 1: concurrent calls are capped at the limit
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		const limit, calls = 10, 50
+		var allowed atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < calls; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, ok := hm.IncrAndCheck("window", limit, 60); ok {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if allowed.Load() != limit {
+			t.Fatalf("expected %d allowed calls, got %d", limit, allowed.Load())
+		}
+		if count, ok := hm.IncrAndCheck("window", limit, 60); ok || count != limit {
+			t.Fatalf("expected a rejected call at %d, got %d %v", limit, count, ok)
+		}
+		// the window is opened by the first call only
+		if at := expireAt(hm, "window"); at == 0 || at > time.Now().Unix()+60 {
+			t.Fatalf("unexpected window expiry %d", at)
+		}
+
+		hm.Set(0, "text", "abc")
+		if _, ok := hm.IncrAndCheck("text", limit, 60); ok {
+			t.Fatal("expected a string key to be rejected")
+		}
+		_ = hm.Close()
+	}
+
+	// Phase 2: the count and the window survive a replay
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = hm.Close()
+			removeAOF(t, name)
+		})
+		if ok, v := hm.Get("window"); !ok || v != "10" {
+			t.Fatalf("expected 10 after replay, got %q", v)
+		}
+		if expireAt(hm, "window") == 0 {
+			t.Fatal("window lost its expiry on replay")
+		}
+	}
+}
+
+func TestHashMap_Flush(t *testing.T) {
+	name := uniqueAOFName(t)
+
+	// Phase 1: flush a DB with strings, expiring keys and collections
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		for i := 0; i < 100; i++ {
+			hm.Set(int64(i%2)*60, "k"+strconv.Itoa(i), "v")
+		}
+		hm.SAdd("set", "a")
+		if err := hm.AddFifoLifo("queue", 10); err != nil {
+			t.Fatalf("AddFifoLifo error: %v", err)
+		}
+
+		if n := hm.Flush(); n != 101 {
+			t.Fatalf("expected 101 flushed keys, got %d", n)
+		}
+		if hm.GetEntries() != 0 || hm.SCard("set") != 0 {
+			t.Fatalf("expected an empty DB, got %d entries", hm.GetEntries())
+		}
+		if err := hm.AddFifoLifo("queue", 10); err != nil {
+			t.Fatalf("expected the queue to be flushed: %v", err)
+		}
+
+		// the DB is usable afterward
+		hm.Set(0, "after", "v")
+		_ = hm.Close()
+	}
+
+	// Phase 2: the replay only restores what was written after the flush
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = hm.Close()
+			removeAOF(t, name)
+		})
+		if hm.GetEntries() != 1 {
+			t.Fatalf("expected 1 entry after replay, got %d", hm.GetEntries())
+		}
+		if ok, _ := hm.Get("k0"); ok {
+			t.Fatal("flushed key restored on replay")
+		}
+
+		// a DB closed meanwhile, e.g. dropped during a FlushAll, is not flushed
+		_ = hm.Close()
+		if n := hm.Flush(); n != 0 {
+			t.Fatalf("expected no flushed keys of a closed DB, got %d", n)
+		}
+	}
+}
+
+func TestHashMap_Snapshot(t *testing.T) {
+	name, copyName := uniqueAOFName(t), uniqueAOFName(t)+"_copy"
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+		removeAOF(t, copyName)
+	})
+	hm.Set(60, "s", "v")
+	hm.CounterIncr(0, "c", 7)
+	hm.HSet("h", "f", "x")
+	hm.RPush("l", "a")
+	hm.RPush("l", "b")
+	hm.withEntry("s", true, func(_ *Basket, item, _ *Entry, _ uint64) {
+		item.ExpireAt = time.Now().Unix() + 5
+	})
+
+	// the snapshot is spooled to its own folder, created if missing
+	oldFolder := *envhandler.ENV.SNAPSHOT_FOLDER
+	*envhandler.ENV.SNAPSHOT_FOLDER = filepath.Join(t.TempDir(), "snapshots")
+	t.Cleanup(func() { *envhandler.ENV.SNAPSHOT_FOLDER = oldFolder })
+
+	// a written snapshot is replayed as AOF of a new DB
+	snapshot, err := hm.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	spooled, _ := filepath.Glob(filepath.Join(*envhandler.ENV.SNAPSHOT_FOLDER, ".snapshot-*"))
+	if len(spooled) != 1 {
+		t.Fatalf("expected the spool file in %s, got %v", *envhandler.ENV.SNAPSHOT_FOLDER, spooled)
+	}
+	var buf bytes.Buffer
+	if n, err := snapshot.WriteTo(&buf); err != nil || n != snapshot.Size() || int64(buf.Len()) != n {
+		t.Fatalf("WriteTo: wrote %d of %d bytes (err=%v)", n, snapshot.Size(), err)
+	}
+	if err := os.WriteFile(filepath.Join(*envhandler.ENV.DB_FOLDER, copyName+".bin"), buf.Bytes(), 0644); err != nil {
+		t.Fatalf("write copy: %v", err)
+	}
+	cp, err := NewHashMap(copyName)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	defer cp.Close()
+
+	// the snapshot gets the remaining TTL
+	if got := cp.MGetWithTTL([]string{"s"})[0]; !got.Found || got.Value != "v" || got.TTL > 5 {
+		t.Fatalf("expected v with at most 5s left, got %+v", got)
+	}
+	if typ, _ := cp.Type("c"); typ != "counter" {
+		t.Fatalf("counter restored as %s", typ)
+	}
+	if ok, v := cp.HGet("h", "f"); !ok || v != "x" {
+		t.Fatalf("hash: got %q", v)
+	}
+	if values := cp.LRange("l", 0, -1); !slices.Equal(values, []string{"a", "b"}) {
+		t.Fatalf("list: got %v", values)
+	}
+
+	// Close removes the spool file
+	if err := snapshot.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	spooled, _ = filepath.Glob(filepath.Join(*envhandler.ENV.SNAPSHOT_FOLDER, ".snapshot-*"))
+	if len(spooled) != 0 {
+		t.Fatalf("expected the spool file to be removed, got %v", spooled)
+	}
+}
+
+func TestHashMap_Type(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	hm.Set(0, "s", "v")
+	hm.CounterIncr(0, "c", 1)
+	if typ, ok := hm.Type("s"); !ok || typ != "string" {
+		t.Fatalf("Expected string, got %s (ok=%v)", typ, ok)
+	}
+	if typ, ok := hm.Type("c"); !ok || typ != "counter" {
+		t.Fatalf("Expected counter, got %s (ok=%v)", typ, ok)
+	}
+	if _, ok := hm.Type("missing"); ok {
+		t.Fatal("missing key should not have a type")
+	}
+
+	// a set turns a counter into a string
+	hm.Set(0, "c", "text")
+	if typ, _ := hm.Type("c"); typ != "string" {
+		t.Fatalf("Expected string after set, got %s", typ)
+	}
+}
+
+func TestHashMap_Hash(t *testing.T) {
+	name := uniqueAOFName(t)
+
+	// Phase 1: write fields
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		if !hm.HSet("user", "name", "hydra") || !hm.HSet("user", "age", "3") || !hm.HSet("user", "tmp", "x") {
+			t.Fatal("HSet failed")
+		}
+		if ok, v := hm.HGet("user", "name"); !ok || v != "hydra" {
+			t.Fatalf("HGet: got %s (ok=%v)", v, ok)
+		}
+		if typ, _ := hm.Type("user"); typ != "hash" {
+			t.Fatalf("Expected hash, got %s", typ)
+		}
+		if !hm.HDel("user", "tmp") || hm.HDel("user", "tmp") {
+			t.Fatal("HDel should remove the field once")
+		}
+
+		// hashes and strings dont mix
+		hm.Set(0, "s", "v")
+		if hm.HSet("s", "f", "v") {
+			t.Fatal("HSet on a string should fail")
+		}
+		if ok, _ := hm.Get("user"); ok {
+			t.Fatal("Get on a hash should fail")
+		}
+
+		// the last field removes the key
+		hm.HSet("gone", "f", "v")
+		hm.HDel("gone", "f")
+		if _, ok := hm.Type("gone"); ok {
+			t.Fatal("emptied hash should be removed")
+		}
+		if err := hm.Close(); err != nil {
+			t.Fatalf("Close error: %v", err)
+		}
+	}
+
+	// Phase 2: reopen, validate the replay and compact
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap reopen error: %v", err)
+	}
+	want := map[string]string{"name": "hydra", "age": "3"}
+	if got := hm.HGetAll("user"); !maps.Equal(got, want) {
+		t.Fatalf("HGetAll after replay: got %v want %v", got, want)
+	}
+	hm.Aof.Compact()
+	if err := hm.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	// Phase 3: reopen the compacted AOF
+	hm, err = NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap reopen error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+	if got := hm.HGetAll("user"); !maps.Equal(got, want) {
+		t.Fatalf("HGetAll after compaction: got %v want %v", got, want)
+	}
+	if hm.GetEntries() != 2 {
+		t.Fatalf("entries after compaction: got %d want 2", hm.GetEntries())
+	}
+}
+
+func TestHashMap_Set(t *testing.T) {
+	name := uniqueAOFName(t)
+	old := *envhandler.ENV.ENTRY_SIZE
+	*envhandler.ENV.ENTRY_SIZE = 10
+	t.Cleanup(func() { *envhandler.ENV.ENTRY_SIZE = old })
+
+	// Phase 1: add members
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		if !hm.SAdd("tags", "go") || !hm.SAdd("tags", "kv") || hm.SAdd("tags", "go") {
+			t.Fatal("SAdd should add each member once")
+		}
+		if !hm.SIsMember("tags", "go") || hm.SIsMember("tags", "rust") {
+			t.Fatal("SIsMember returned a wrong result")
+		}
+
+		// the member bytes are limited by HKV_ENTRY_SIZE
+		if hm.SAdd("tags", "toolong") {
+			t.Fatal("SAdd above the entry size should fail")
+		}
+		hm.SAdd("tags", "tmp")
+		if !hm.SRem("tags", "tmp") || hm.SRem("tags", "tmp") {
+			t.Fatal("SRem should remove the member once")
+		}
+
+		// sets and strings dont mix
+		hm.Set(0, "s", "v")
+		if hm.SAdd("s", "m") {
+			t.Fatal("SAdd on a string should fail")
+		}
+		if err := hm.Close(); err != nil {
+			t.Fatalf("Close error: %v", err)
+		}
+	}
+
+	// Phase 2: reopen, validate the replay and compact
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap reopen error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+	if got := hm.SMembers("tags"); !slices.Equal(got, []string{"go", "kv"}) {
+		t.Fatalf("SMembers after replay: got %v", got)
+	}
+	hm.Aof.Compact()
+	if hm.SCard("tags") != 2 {
+		t.Fatalf("SCard after compaction: got %d want 2", hm.SCard("tags"))
+	}
+
+	// the last member removes the key
+	hm.SRem("tags", "go")
+	hm.SRem("tags", "kv")
+	if typ, ok := hm.Type("tags"); ok {
+		t.Fatalf("emptied set should be removed, got %s", typ)
+	}
+}
+
+func TestHashMap_ZSet(t *testing.T) {
+	name := uniqueAOFName(t)
+
+	members := func(zs []ZMember) []string {
+		names := make([]string, len(zs))
+		for i, z := range zs {
+			names[i] = z.Member
+		}
+		return names
+	}
+
+	// Phase 1: add members - b and c tie and are ordered by name
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		for _, z := range []ZMember{{"c", 2}, {"a", 1}, {"b", 2}, {"d", 3}} {
+			if !hm.ZAdd("board", z.Member, z.Score) {
+				t.Fatalf("ZAdd %s failed", z.Member)
+			}
+		}
+		if got := members(hm.ZRange("board", 0, -1)); !slices.Equal(got, []string{"a", "b", "c", "d"}) {
+			t.Fatalf("ZRange: got %v", got)
+		}
+
+		// an update moves the member
+		hm.ZAdd("board", "a", 2.5)
+		if rank, ok := hm.ZRank("board", "a"); !ok || rank != 2 {
+			t.Fatalf("ZRank after update: got %d (ok=%v) want 2", rank, ok)
+		}
+		if score, ok := hm.ZScore("board", "a"); !ok || score != 2.5 {
+			t.Fatalf("ZScore: got %v (ok=%v)", score, ok)
+		}
+		if hm.ZAdd("board", "nan", math.NaN()) {
+			t.Fatal("ZAdd with NaN should fail")
+		}
+		hm.Set(0, "s", "v")
+		if hm.ZAdd("s", "m", 1) {
+			t.Fatal("ZAdd on a string should fail")
+		}
+		if err := hm.Close(); err != nil {
+			t.Fatalf("Close error: %v", err)
+		}
+	}
+
+	// Phase 2: reopen, validate the replay and the range boundaries
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap reopen error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+	hm.Aof.Compact()
+
+	for _, c := range []struct {
+		start, stop int
+		want        []string
+	}{
+		{0, -1, []string{"b", "c", "a", "d"}},
+		{1, 2, []string{"c", "a"}},
+		{-2, -1, []string{"a", "d"}},
+		{2, 100, []string{"a", "d"}},
+		{-100, 0, []string{"b"}},
+		{3, 1, []string{}},
+		{4, -1, []string{}},
+	} {
+		if got := members(hm.ZRange("board", c.start, c.stop)); !slices.Equal(got, c.want) {
+			t.Fatalf("ZRange(%d, %d): got %v want %v", c.start, c.stop, got, c.want)
+		}
+	}
+	if hm.ZRange("missing", 0, -1) != nil {
+		t.Fatal("ZRange of a missing key should be nil")
+	}
+	if _, ok := hm.ZRank("board", "missing"); ok {
+		t.Fatal("ZRank of a missing member should fail")
+	}
+}
+
+func TestHashMap_List(t *testing.T) {
+	name := uniqueAOFName(t)
+	old := *envhandler.ENV.ENTRY_SIZE
+	*envhandler.ENV.ENTRY_SIZE = 10
+	t.Cleanup(func() { *envhandler.ENV.ENTRY_SIZE = old })
+
+	// Phase 1: push and pop values
+	{
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		hm.RPush("l", "b")
+		hm.RPush("l", "c")
+		hm.LPush("l", "a")
+		hm.RPush("l", "x")
+		if v, ok := hm.RPop("l"); !ok || v != "x" {
+			t.Fatalf("RPop: got %s (ok=%v)", v, ok)
+		}
+		hm.LPush("l", "y")
+		if v, ok := hm.LPop("l"); !ok || v != "y" {
+			t.Fatalf("LPop: got %s (ok=%v)", v, ok)
+		}
+
+		// the value bytes are limited by HKV_ENTRY_SIZE
+		if hm.RPush("l", "toolongvalue") {
+			t.Fatal("RPush above the entry size should fail")
+		}
+		hm.Set(0, "s", "v")
+		if hm.LPush("s", "v") {
+			t.Fatal("LPush on a string should fail")
+		}
+		if err := hm.Close(); err != nil {
+			t.Fatalf("Close error: %v", err)
+		}
+	}
+
+	// Phase 2: reopen, validate the replay and compact
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap reopen error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+	hm.Aof.Compact()
+
+	if got := hm.LRange("l", 0, -1); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("LRange after replay: got %v", got)
+	}
+	if got := hm.LRange("l", -2, 10); !slices.Equal(got, []string{"b", "c"}) {
+		t.Fatalf("LRange(-2, 10): got %v", got)
+	}
+	if hm.LLen("l") != 3 {
+		t.Fatalf("LLen: got %d want 3", hm.LLen("l"))
+	}
+
+	// the last value removes the key
+	for range 3 {
+		hm.LPop("l")
+	}
+	if _, ok := hm.LPop("l"); ok {
+		t.Fatal("LPop of an empty list should fail")
+	}
+	if hm.LRange("l", 0, -1) != nil {
+		t.Fatal("emptied list should be removed")
+	}
+}
+
+func TestHashMap_ApproxCardinality(t *testing.T) {
+	old := *envhandler.ENV.APPROX_CARDINALITY
+	*envhandler.ENV.APPROX_CARDINALITY = true
+	t.Cleanup(func() { *envhandler.ENV.APPROX_CARDINALITY = old })
+
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	if n := hm.GetApproxCardinality(); n != 0 {
+		t.Fatalf("expected an empty estimate, got %d", n)
+	}
+
+	// overwrites and collections count their key once
+	const keys = 20000
+	for i := 0; i < keys; i++ {
+		key := "key-" + strconv.Itoa(i)
+		hm.Set(0, key, "a")
+		hm.Set(0, key, "b")
+	}
+	hm.SAdd("set", "a")
+	hm.SAdd("set", "b")
+
+	n := hm.GetApproxCardinality()
+	if diff := math.Abs(float64(n-keys-1)) / (keys + 1); diff > 0.05 {
+		t.Fatalf("estimate %d is off by %.1f%%", n, diff*100)
+	}
+}
+
+func TestHashMap_AOFVersion(t *testing.T) {
+	name := uniqueAOFName(t)
+	file := filepath.Join(*envhandler.ENV.DB_FOLDER, name+".bin")
+	t.Cleanup(func() { _ = os.Remove(file) })
+
+	// a headerless file of the first releases
+	var v0 []byte
+	for _, d := range []Data{{Action: "set", Key: "a", Value: "1"}, {Action: "set", Key: "b", Value: "2"}, {Action: "del", Key: "a"}} {
+		v0 = binary.BigEndian.AppendUint32(v0, uint32(len(d.Action)))
+		v0 = append(v0, d.Action...)
+		v0 = binary.BigEndian.AppendUint32(v0, uint32(len(d.Key)))
+		v0 = append(v0, d.Key...)
+		v0 = binary.BigEndian.AppendUint32(v0, uint32(len(d.Value)))
+		v0 = append(v0, d.Value...)
+		v0 = binary.BigEndian.AppendUint64(v0, 0)
+	}
+	if err := os.WriteFile(file, v0, 0644); err != nil {
+		t.Fatalf("write v0: %v", err)
+	}
+
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	if hm.Aof.version != aofVersion0 {
+		t.Fatalf("headerless file detected as version %d", hm.Aof.version)
+	}
+	if ok, _ := hm.Get("a"); ok {
+		t.Fatalf("deleted key a replayed")
+	}
+	if ok, v := hm.Get("b"); !ok || v != "2" {
+		t.Fatalf("b: got %q", v)
+	}
+
+	// new frames are appended to the old file, the compaction migrates it to the current version
+	hm.Set(0, "c", "3")
+	hm.Aof.Compact()
+	_ = hm.Close()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.HasPrefix(data, appendHeader(nil, hm.Aof.seed)) {
+		t.Fatalf("compacted file has no header: %q", data[:min(len(data), aofHeaderSize)])
+	}
+
+	hm, err = NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	if hm.Aof.version != aofVersion {
+		t.Fatalf("compacted file detected as version %d", hm.Aof.version)
+	}
+	if ok, v := hm.Get("c"); !ok || v != "3" {
+		t.Fatalf("c: got %q", v)
+	}
+	_ = hm.Close()
+
+	// a version from the future is refused instead of mis-parsed
+	future := append([]byte(aofMagic), 0, aofVersion+1)
+	if err := os.WriteFile(file, future, 0644); err != nil {
+		t.Fatalf("write future: %v", err)
+	}
+	if _, err := NewHashMap(name); err == nil {
+		t.Fatalf("expected an error for an unknown version")
+	}
+}
+
+func TestHashMap_Meta(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	if _, ok := hm.Meta("missing"); ok {
+		t.Fatalf("meta of a missing key")
+	}
+
+	// every write moves the modification time forward
+	modified := func(key string) int64 {
+		t.Helper()
+		meta, ok := hm.Meta(key)
+		if !ok {
+			t.Fatalf("%s is missing", key)
+		}
+		return meta.ModifiedAt
+	}
+	writes := []struct {
+		key   string
+		write func()
+	}{
+		{"s", func() { hm.Set(0, "s", "v") }},
+		{"s", func() { hm.Incr(0, "s", "1") }},
+		{"c", func() { hm.CounterIncr(0, "c", 1) }},
+		{"c", func() { hm.IncrAndCheck("c", 10, 0) }},
+		{"h", func() { hm.HSet("h", "f", "x") }},
+		{"l", func() { hm.RPush("l", "a") }},
+		{"l", func() { hm.RPush("l", "b") }},
+		{"l", func() { hm.LPop("l") }},
+	}
+	last := map[string]int64{}
+	for i, w := range writes {
+		w.write()
+		got := modified(w.key)
+		if got == 0 || got < last[w.key] {
+			t.Fatalf("write %d: modified_at %d not after %d", i, got, last[w.key])
+		}
+		last[w.key] = got
+	}
+
+	// a read keeps it
+	if _, _, at := hm.GetModified("s"); at != last["s"] {
+		t.Fatalf("GetModified: got %d want %d", at, last["s"])
+	}
+	if meta, _ := hm.Meta("l"); meta.Type != "list" || meta.Size != 1 {
+		t.Fatalf("list meta: %+v", meta)
+	}
+}
+
+func TestHashMap_ContentType(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	contentType := func(key string) string {
+		t.Helper()
+		ok, _, info := hm.GetInfo(key)
+		meta, _ := hm.Meta(key)
+		if !ok || info.ContentType != meta.ContentType {
+			t.Fatalf("%s: GetInfo %q, Meta %q", key, info.ContentType, meta.ContentType)
+		}
+		return info.ContentType
+	}
+
+	hm.SetWithContentType(60, "doc", `{"a":1}`, "application/json", false)
+	hm.SetWithContentType(0, "kept", "v", "text/plain", false)
+	hm.SetWithContentType(0, "kept", "v2", "text/csv", true)
+	hm.SetWithContentType(0, "plain", "v", "text/plain", false)
+	hm.Set(0, "plain", "v2")
+	hm.SetWithContentType(0, "num", "1", "text/plain", false)
+	hm.CounterIncr(0, "num", 1)
+
+	// the content type goes with the value, a write without one removes it
+	want := map[string]string{"doc": "application/json", "kept": "text/csv", "plain": "", "num": ""}
+	for key, ct := range want {
+		if got := contentType(key); got != ct {
+			t.Fatalf("%s: got %q want %q", key, got, ct)
+		}
+	}
+
+	// kept by the replay and by a compaction
+	for _, compact := range []bool{false, true} {
+		if compact {
+			hm.Aof.Compact()
+		}
+		if err := hm.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if report := VerifyAOF(name); !report.OK {
+			t.Fatalf("VerifyAOF: %+v", report)
+		}
+		if hm, err = NewHashMap(name); err != nil {
+			t.Fatalf("reopen: %v", err)
+		}
+		for key, ct := range want {
+			if got := contentType(key); got != ct {
+				t.Fatalf("%s after reopen (compacted %v): got %q want %q", key, compact, got, ct)
+			}
+		}
+		if ok, v := hm.Get("doc"); !ok || v != `{"a":1}` {
+			t.Fatalf("doc after reopen: got %q", v)
+		}
+	}
+}
+
+func TestHashMap_MGetWithTTL(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	hm.Set(100, "s", "v")
+	hm.Set(0, "forever", "f")
+	hm.CounterIncr(0, "c", 3)
+	hm.HSet("h", "f", "x")
+
+	got := hm.MGetWithTTL([]string{"s", "missing", "forever", "c", "h", "s"})
+	want := []KeyTTL{{true, "v", 100}, {}, {true, "f", NoTTL}, {true, "3", NoTTL}, {}, {true, "v", 100}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d values", len(got))
+	}
+	for i := range want {
+		// the second of the set may have passed
+		if got[i].TTL == 99 {
+			got[i].TTL = 100
+		}
+		if got[i] != want[i] {
+			t.Fatalf("value %d: got %+v want %+v", i, got[i], want[i])
+		}
+	}
+	if len(hm.MGetWithTTL(nil)) != 0 {
+		t.Fatalf("values without keys")
+	}
+}
+
+func TestAOF_Throttled(t *testing.T) {
+	high, low := *envhandler.ENV.AOF_HIGH_WATER, *envhandler.ENV.AOF_LOW_WATER
+	t.Cleanup(func() {
+		*envhandler.ENV.AOF_HIGH_WATER = high
+		*envhandler.ENV.AOF_LOW_WATER = low
+	})
+	*envhandler.ENV.AOF_HIGH_WATER = 0.5
+	*envhandler.ENV.AOF_LOW_WATER = 0.2
+
+	// the loop is not started, so the queue only drains when we read it
+	a, err := NewAOF(uniqueAOFName(t), nil)
+	if err != nil {
+		t.Fatalf("NewAOF error: %v", err)
+	}
+	fill := func(n int) {
+		for len(a.com) < n {
+			a.com <- Data{Action: "set", Key: "k"}
+		}
+		for len(a.com) > n {
+			<-a.com
+		}
+	}
+
+	steps := []struct {
+		fill      float64
+		throttled bool
+	}{
+		{0.4, false},
+		{0.5, true},
+		// between the marks the state is kept
+		{0.3, true},
+		{0.2, false},
+		{0.3, false},
+	}
+	for _, step := range steps {
+		fill(int(step.fill * float64(cap(a.com))))
+		if got := a.Throttled(); got != step.throttled {
+			t.Fatalf("fill %.1f: throttled %v, want %v", step.fill, got, step.throttled)
+		}
+	}
+
+	// a high water of 0 disables the throttle
+	fill(cap(a.com))
+	*envhandler.ENV.AOF_HIGH_WATER = 0
+	if a.Throttled() {
+		t.Fatalf("throttled although disabled")
+	}
+}
+
+func TestHashMap_ExpiringWithin(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	hm.Set(50, "later", "v")
+	hm.Set(5, "soon", "v")
+	hm.Set(500, "much-later", "v")
+	hm.Set(0, "forever", "v")
+	hm.Set(30, "deleted", "v")
+	hm.Del("deleted")
+
+	if got := hm.ExpiringWithin(60); !slices.Equal(got, []string{"soon", "later"}) {
+		t.Fatalf("ExpiringWithin(60): got %v", got)
+	}
+	if got := hm.ExpiringWithin(10); !slices.Equal(got, []string{"soon"}) {
+		t.Fatalf("ExpiringWithin(10): got %v", got)
+	}
+	if got := hm.ExpiringWithin(0); len(got) != 0 {
+		t.Fatalf("ExpiringWithin(0): got %v", got)
+	}
+
+	// the result is bounded
+	for i := range MaxExpiring + 10 {
+		hm.Set(20, "bulk-"+strconv.Itoa(i), "v")
+	}
+	got := hm.ExpiringWithin(60)
+	if len(got) != MaxExpiring || got[0] != "soon" {
+		t.Fatalf("bounded: got %d keys, first %q", len(got), got[0])
+	}
+}
+
+func TestTTLManager_Shards(t *testing.T) {
+	shards := *envhandler.ENV.TTL_SHARDS
+	t.Cleanup(func() { *envhandler.ENV.TTL_SHARDS = shards })
+
+	*envhandler.ENV.TTL_SHARDS = 0
+	derived := NewTTLManager("shards", func(string) bool { return true })
+	if n := len(derived.List); n < 2 || n&(n-1) != 0 {
+		t.Fatalf("derived shards: got %d, want a power of two", n)
+	}
+
+	*envhandler.ENV.TTL_SHARDS = 128
+	if n := len(NewTTLManager("shards", func(string) bool { return true }).List); n != 128 {
+		t.Fatalf("HKV_TTL_SHARDS: got %d shards, want 128", n)
+	}
+}
+
+func TestTTLManager_ShardsPowerOfTwo(t *testing.T) {
+	shards := *envhandler.ENV.TTL_SHARDS
+	t.Cleanup(func() { *envhandler.ENV.TTL_SHARDS = shards })
+
+	// LoadENVs rejects it, the constructor has to as well
+	*envhandler.ENV.TTL_SHARDS = 3
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("no panic for 3 shards")
+		}
+	}()
+	NewTTLManager("shards", func(string) bool { return true })
+}
+
+func TestTTLManager_ShardsExpire(t *testing.T) {
+	shards := *envhandler.ENV.TTL_SHARDS
+	t.Cleanup(func() { *envhandler.ENV.TTL_SHARDS = shards })
+	*envhandler.ENV.TTL_SHARDS = 8
+
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	const n = 400
+	for i := range n {
+		hm.Set(1, "k"+strconv.Itoa(i), "v")
+	}
+
+	// the entries are spread over all shards
+	for i, em := range hm.TTlManager.List {
+		em.mut.Lock()
+		count := 0
+		for _, bucket := range em.list {
+			count += len(bucket)
+		}
+		em.mut.Unlock()
+		if count == 0 {
+			t.Fatalf("shard %d holds no entries", i)
+		}
+	}
+
+	// and all of them expire
+	deadline := time.Now().Add(5 * time.Second)
+	for hm.Entries.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if left := hm.Entries.Load(); left != 0 {
+		t.Fatalf("%d of %d entries did not expire", left, n)
+	}
+	for i := range n {
+		if ok, _ := hm.Get("k" + strconv.Itoa(i)); ok {
+			t.Fatalf("k%d did not expire", i)
+		}
+	}
+}
+
+func TestTTLManager_Entries(t *testing.T) {
+	ttlm := NewTTLManager("ttlentries", func(string) bool { return true })
+	// the buckets are seconds - start right after one, so all entries get the same
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	entries := make([]*Entry, 4)
+	for i := range entries {
+		entries[i] = NewEntry(60, "k"+strconv.Itoa(i), "v", uint64(i), nil)
+		ttlm.addEntry(entries[i])
+	}
+	ttlm.addEntry(NewEntry(120, "k4", "v", 0, nil))
+	// adding a key again replaces it
+	ttlm.addEntry(NewEntry(60, "k0", "v", 0, nil))
+
+	// the buckets are per shard - the entries of the same second in two shards are two buckets
+	wantBuckets := int64(min(4, ttlm.numShards)) + 1
+	if n, b := ttlm.Entries(); n != 5 || b != wantBuckets {
+		t.Fatalf("expected 5 entries in %d buckets, got %d in %d", wantBuckets, n, b)
+	}
+
+	ttlm.delEntry(entries[1])
+	ttlm.delEntry(entries[1])
+	if n, _ := ttlm.Entries(); n != 4 {
+		t.Fatalf("expected 4 entries after the delete, got %d", n)
+	}
+
+	// the expiry drops the buckets of the second - the 120s entry stays
+	ttlm.delEntries(time.Now().Unix() + 61)
+	if n, b := ttlm.Entries(); n != 1 || b != 1 {
+		t.Fatalf("expected 1 entry in 1 bucket after the expiry, got %d in %d", n, b)
+	}
+	ttlm.clear()
+	if n, b := ttlm.Entries(); n != 0 || b != 0 {
+		t.Fatalf("expected no entries after clear, got %d in %d", n, b)
+	}
+}
+
+func TestTTLManager_DeleteMisses(t *testing.T) {
+	name := "ttlmisses"
+	ttlm := NewTTLManager(name, func(string) bool { return false })
+	for i := range 5 {
+		ttlm.addEntry(NewEntry(1, "k"+strconv.Itoa(i), "v", uint64(i), nil))
+	}
+	ttlm.delEntries(time.Now().Unix() + 2)
+
+	misses := 0.0
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather error: %v", err)
+	}
+	for _, mf := range families {
+		if mf.GetName() != "kv_ttl_delete_misses_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "db" && l.GetValue() == name {
+					misses = m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	if misses != 5 {
+		t.Fatalf("kv_ttl_delete_misses_total: got %v want 5", misses)
+	}
+}
+
+func TestTTLManager_StopWaitsForExpiry(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	var calls atomic.Int32
+	ttlm := NewTTLManager("ttlstop", func(string) bool {
+		if calls.Add(1) == 1 {
+			close(entered)
+			<-release
+		}
+		return true
+	})
+	for i := range 10 {
+		ttlm.addEntry(NewEntry(1, "k"+strconv.Itoa(i), "v", 0, nil))
+	}
+	ttlm.Start()
+
+	select {
+	case <-entered:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("the entries did not expire")
+	}
+
+	// Stop has to wait for the running delete
+	stopped := make(chan struct{})
+	go func() {
+		ttlm.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatalf("Stop returned during a delete")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+
+	// the rest of the expired entries are skipped after Stop
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("delete callback called %d times after Stop, want 1", n)
+	}
+}
+
+// appendFrame appends the AOF frame of d to b
+func appendFrame(b []byte, d Data) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(d.Action)))
+	b = append(b, d.Action...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(d.Key)))
+	b = append(b, d.Key...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(d.Value)))
+	b = append(b, d.Value...)
+	return binary.BigEndian.AppendUint64(b, uint64(d.Ttl))
+}
+
+func TestVerifyAOF(t *testing.T) {
+	frame := func(d Data) []byte { return appendFrame(nil, d) }
+	clean := appendHeader(nil, 0)
+	for _, d := range []Data{{Action: "set", Key: "a", Value: "1"}, {Action: "set", Key: "b", Value: "2"}, {Action: "del", Key: "a"}} {
+		clean = append(clean, frame(d)...)
+	}
+
+	cases := []struct {
+		name      string
+		data      []byte
+		ok        bool
+		frames    int64
+		truncated bool
+	}{
+		{"clean", clean, true, 3, false},
+		// the last frame of a crash is cut within its key
+		{"truncated", append(slices.Clone(clean), frame(Data{Action: "set", Key: "c", Value: "3"})[:9]...), true, 3, true},
+		{"unknown action", append(slices.Clone(clean), frame(Data{Action: "\x00\xffjunk", Key: "c"})...), false, 4, false},
+		{"newer version", binary.BigEndian.AppendUint16([]byte(aofMagic), aofVersion+1), false, 0, false},
+	}
+	for _, c := range cases {
+		name := uniqueAOFName(t)
+		t.Run(c.name, func(t *testing.T) {
+			file := filepath.Join(*envhandler.ENV.DB_FOLDER, name+".bin")
+			t.Cleanup(func() { _ = os.Remove(file) })
+			if err := os.WriteFile(file, c.data, 0644); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			report := VerifyAOF(name)
+			if report.OK != c.ok || report.Frames != c.frames || report.Truncated != c.truncated ||
+				report.Bytes != int64(len(c.data)) {
+				t.Fatalf("got %+v", report)
+			}
+			if c.ok && report.Entries != 1 {
+				t.Fatalf("expected 1 entry, got %+v", report)
+			}
+
+			// the file is only read
+			if data, err := os.ReadFile(file); err != nil || !bytes.Equal(data, c.data) {
+				t.Fatalf("the AOF was changed: %v", err)
+			}
+		})
+	}
+}
+
+func TestAOF_CompactionRenameFails(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	// the disk is full when the compacted file replaces the AOF
+	t.Cleanup(func() { rename = os.Rename })
+	rename = func(string, string) error { return fmt.Errorf("no space left on device") }
+
+	hm.Set(0, "a", "1")
+	hm.Set(0, "a", "2")
+	hm.Set(0, "b", "1")
+	hm.Aof.Compact()
+
+	// the tmp file is gone and the writes go on to the original file
+	tmpName := strings.TrimSuffix(hm.Aof.FileName, ".bin") + ".tmp.bin"
+	if _, err := os.Stat(tmpName); !os.IsNotExist(err) {
+		t.Fatalf("tmp AOF left behind: %v", err)
+	}
+	hm.Set(0, "c", "3")
+	hm.Del("b")
+	if err := hm.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if report := VerifyAOF(name); !report.OK || report.Frames != 5 || report.Entries != 2 {
+		t.Fatalf("original AOF: %+v", report)
+	}
+
+	// a later compaction succeeds
+	rename = os.Rename
+	hm, err = NewHashMap(name)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	hm.Aof.Compact()
+	if err := hm.Aof.Healthy(); err != nil {
+		t.Fatalf("Healthy: %v", err)
+	}
+	if ok, v := hm.Get("a"); !ok || v != "2" {
+		t.Fatalf("a: got %q", v)
+	}
+	if err := hm.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if report := VerifyAOF(name); !report.OK || report.Frames != 2 || report.Entries != 2 {
+		t.Fatalf("compacted AOF: %+v", report)
+	}
+}
+
+func TestSwapDBs(t *testing.T) {
+	nameA, nameB := uniqueAOFName(t)+"_a", uniqueAOFName(t)+"_b"
+	a, err := NewHashMap(nameA)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	b, err := NewHashMap(nameB)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = a.Close()
+		_ = b.Close()
+		removeAOF(t, nameA)
+		removeAOF(t, nameB)
+	})
+	a.Set(0, "k", "a")
+	b.Set(0, "k", "b")
+	b.Set(0, "only-b", "1")
+	dbA, dbB, fileA := a.Name, b.Name, a.Aof.FileName
+	ttlA, ttlB := a.TTlManager.name(), b.TTlManager.name()
+
+	// a failed rename is rolled back, both DBs keep their names and files
+	t.Cleanup(func() { rename = os.Rename })
+	calls := 0
+	rename = func(from, to string) error {
+		if calls++; calls == 2 {
+			return fmt.Errorf("no space left on device")
+		}
+		return os.Rename(from, to)
+	}
+	if err := SwapDBs(a, b); err == nil {
+		t.Fatalf("expected the swap to fail")
+	}
+	if a.Name != dbA || a.Aof.FileName != fileA {
+		t.Fatalf("a after the failed swap: %s %s", a.Name, a.Aof.FileName)
+	}
+	if _, err := os.Stat(fileA + swapTmpExt); !os.IsNotExist(err) {
+		t.Fatalf("swap file left behind: %v", err)
+	}
+
+	rename = os.Rename
+	if err := SwapDBs(a, b); err != nil {
+		t.Fatalf("SwapDBs: %v", err)
+	}
+	if a.Name != dbB || b.Name != dbA || b.Aof.FileName != fileA {
+		t.Fatalf("names after the swap: %s %s", a.Name, b.Name)
+	}
+	// the TTL metrics follow the names
+	if a.TTlManager.name() != ttlB || b.TTlManager.name() != ttlA {
+		t.Fatalf("TTLManager names after the swap: %s %s", a.TTlManager.name(), b.TTlManager.name())
+	}
+	// the writes after the swap go to the file of the new name
+	a.Set(0, "after", "1")
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	for name, want := range map[string]map[string]string{
+		nameA: {"k": "b", "only-b": "1"},
+		nameB: {"k": "a", "after": "1"},
+	} {
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("reopen %s: %v", name, err)
+		}
+		for key, value := range want {
+			if ok, v := hm.Get(key); !ok || v != value {
+				t.Fatalf("%s %s: got %q want %q", name, key, v, value)
+			}
+		}
+		if hm.GetEntries() != int64(len(want)) {
+			t.Fatalf("%s: %d entries", name, hm.GetEntries())
+		}
+		_ = hm.Close()
+	}
+}
+
+func TestHashMap_CloseCompacts(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() { removeAOF(t, name) })
+
+	// deletes make a compaction due which the periodic check did not run yet
+	for i := 0; i < 100; i++ {
+		hm.Set(0, "key-"+strconv.Itoa(i), "v")
+	}
+	for i := 10; i < 100; i++ {
+		hm.Del("key-" + strconv.Itoa(i))
+	}
+	if !hm.needsCompaction() {
+		t.Fatalf("expected a due compaction")
+	}
+	if err := hm.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if report := VerifyAOF(name); !report.OK || report.Frames != 10 || report.Entries != 10 {
+		t.Fatalf("AOF after Close: %+v", report)
+	}
+}
+
+func TestHashMap_CloseBeforeLoad(t *testing.T) {
+	interval := compactInterval
+	t.Cleanup(func() { compactInterval = interval })
+	compactInterval = 5 * time.Millisecond
+
+	name := uniqueAOFName(t)
+	t.Cleanup(func() { removeAOF(t, name) })
+	data := appendHeader(nil, 0)
+	for i := 0; i < 10; i++ {
+		data = appendFrame(data, Data{Action: "set", Key: "key-" + strconv.Itoa(i), Value: "v"})
+		data = appendFrame(data, Data{Action: "del", Key: "key-" + strconv.Itoa(i)})
+	}
+	if err := os.WriteFile(filepath.Join(*envhandler.ENV.DB_FOLDER, name+".bin"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the replayed deletes make a compaction due, but the AOF loop is not running - the checker must not wait for it
+	hm, err := OpenHashMap(name)
+	if err != nil {
+		t.Fatalf("OpenHashMap: %v", err)
+	}
+	if err := hm.ReplayAOF(); err != nil {
+		t.Fatalf("ReplayAOF: %v", err)
+	}
+	time.Sleep(20 * compactInterval)
+
+	closed := make(chan error)
+	go func() { closed <- hm.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Close blocked by the ResizeChecker")
+	}
+}
+
+func TestHashMap_SampleChains(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() { _ = hm.Close(); removeAOF(t, name) })
+
+	for i := 0; i < 5000; i++ {
+		hm.Set(0, "key-"+strconv.Itoa(i), "v")
+	}
+
+	// a sample of all baskets counts every entry once - the ResizeChecker may still grow the table meanwhile
+	before := hm.GetBasketNum()
+	_, lengths := hm.sampleChains(1 << 30)
+	if after := hm.GetBasketNum(); len(lengths) < before || len(lengths) > after {
+		t.Fatalf("expected %d to %d sampled baskets, got %d", before, after, len(lengths))
+	}
+	total := 0
+	for _, length := range lengths {
+		total += length
+	}
+	if total != 5000 {
+		t.Fatalf("expected 5000 entries in the chains, got %d", total)
+	}
+
+	if _, lengths := hm.sampleChains(16); len(lengths) != 16 {
+		t.Fatalf("expected 16 sampled baskets, got %d", len(lengths))
+	}
+}
+
+func TestHashMap_Seed(t *testing.T) {
+	names := []string{uniqueAOFName(t), uniqueAOFName(t)}
+	hms := make([]*HashMap, len(names))
+	for i, name := range names {
+		hm, err := NewHashMap(name)
+		if err != nil {
+			t.Fatalf("NewHashMap error: %v", err)
+		}
+		t.Cleanup(func() { removeAOF(t, name) })
+		hm.Set(0, "key", "v")
+		hms[i] = hm
+	}
+	if hms[0].seed == hms[1].seed {
+		t.Fatalf("both DBs got the seed %d", hms[0].seed)
+	}
+
+	// the seed is kept in the header, the restarted DB places the keys like before
+	seed := hms[0].seed
+	_ = hms[0].Close()
+	_ = hms[1].Close()
+	hm, err := NewHashMap(names[0])
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	if hm.seed != seed {
+		t.Fatalf("expected the seed %d after restart, got %d", seed, hm.seed)
+	}
+	if ok, v := hm.Get("key"); !ok || v != "v" {
+		t.Fatalf("key after restart: %v %q", ok, v)
+	}
+	_ = hm.Close()
+
+	// a header cut within the seed is refused
+	file := filepath.Join(*envhandler.ENV.DB_FOLDER, names[0]+".bin")
+	if err := os.WriteFile(file, appendHeader(nil, seed)[:aofHeaderSize+3], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewHashMap(names[0]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected a truncated header, got %v", err)
+	}
+}
+
+func TestChangelog(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() { removeAOF(t, name) })
+	if err := hm.EnableChangelog(); err != nil {
+		t.Fatalf("EnableChangelog: %v", err)
+	}
+	fileName := hm.Aof.changelogName()
+	t.Cleanup(func() { _ = os.Remove(fileName) })
+
+	hm.Set(0, "a", "1")
+	hm.SetWithContentType(10, "b", "{}", "application/json", false)
+	hm.HSet("h", "f", "v")
+	hm.ZAdd("z", "m", 1.5)
+	hm.Del("a")
+	// Close writes the queued frames - the changelog is enabled again by its file
+	_ = hm.Close()
+	hm, err = NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	if hm.Changelog() == nil {
+		t.Fatalf("changelog not enabled after restart")
+	}
+
+	changes, oldest, err := hm.Changelog().Changes(0, 100)
+	if err != nil || oldest != 1 {
+		t.Fatalf("Changes: %v, oldest %d", err, oldest)
+	}
+	want := []Change{
+		{Seq: 1, Action: "set", Key: "a", Value: "1"},
+		{Seq: 2, Action: "set", Key: "b", Value: "{}", ContentType: "application/json", Ttl: 10},
+		{Seq: 3, Action: "hset", Key: "h", Field: "f", Value: "v"},
+		{Seq: 4, Action: "zadd", Key: "z", Field: "m", Value: "1.5"},
+		{Seq: 5, Action: "del", Key: "a"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for i := range want {
+		want[i].Time = changes[i].Time
+		if changes[i] != want[i] {
+			t.Fatalf("change %d: expected %+v, got %+v", i, want[i], changes[i])
+		}
+	}
+	if changes, _, _ := hm.Changelog().Changes(2, 2); len(changes) != 2 || changes[0].Seq != 3 || changes[1].Seq != 4 {
+		t.Fatalf("expected the changes 3 and 4, got %+v", changes)
+	}
+
+	// the sequence goes on after the restart
+	hm.Set(0, "c", "3")
+	_ = hm.Close()
+	c, err := openChangelog(fileName)
+	if err != nil {
+		t.Fatalf("openChangelog: %v", err)
+	}
+	if changes, _, _ := c.Changes(5, 100); len(changes) != 1 || changes[0].Seq != 6 || changes[0].Key != "c" {
+		t.Fatalf("expected the change 6, got %+v", changes)
+	}
+	_ = c.close()
+
+	// disabling deletes the changelog
+	hm, err = NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	if err := hm.DisableChangelog(); err != nil {
+		t.Fatalf("DisableChangelog: %v", err)
+	}
+	_ = hm.Close()
+	if _, err := os.Stat(fileName); !os.IsNotExist(err) {
+		t.Fatalf("changelog not deleted: %v", err)
+	}
+}
+
+func TestChangelog_Expired(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() { removeAOF(t, name) })
+	if err := hm.EnableChangelog(); err != nil {
+		t.Fatalf("EnableChangelog: %v", err)
+	}
+	fileName := hm.Aof.changelogName()
+	t.Cleanup(func() {
+		_ = hm.Close()
+		_ = os.Remove(fileName)
+	})
+
+	hm.Set(1, "cache:a", "1")
+	hm.Set(0, "cache:b", "2")
+	hm.Set(0, "other", "3")
+	hm.Del("cache:b")
+
+	// the TTLManager deletes the key, the changelog tells it apart from a DEL
+	expired := func(c *Change) bool { return c.Action == "expired" }
+	var changes []Change
+	deadline := time.Now().Add(5 * time.Second)
+	for len(changes) == 0 && time.Now().Before(deadline) {
+		changes, _, _, err = hm.Changelog().ChangesMatching(0, 100, expired)
+		if err != nil {
+			t.Fatalf("ChangesMatching: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if len(changes) != 1 || changes[0].Key != "cache:a" || changes[0].Seq != 5 {
+		t.Fatalf("expected the expired cache:a, got %+v", changes)
+	}
+	all, _, _ := hm.Changelog().Changes(0, 100)
+	if len(all) != 5 || all[3].Action != "del" || all[3].Key != "cache:b" {
+		t.Fatalf("expected the DEL of cache:b, got %+v", all)
+	}
+
+	// next moves past the changes which did not match
+	prefixed := func(c *Change) bool { return strings.HasPrefix(c.Key, "cache:") }
+	changes, _, next, err := hm.Changelog().ChangesMatching(2, 1, prefixed)
+	if err != nil || len(changes) != 1 || changes[0].Seq != 4 || next != 4 {
+		t.Fatalf("expected the change 4 and next 4, got %+v, next %d, %v", changes, next, err)
+	}
+	changes, _, next, err = hm.Changelog().ChangesMatching(0, 100, func(c *Change) bool { return c.Key == "none" })
+	if err != nil || len(changes) != 0 || next != 5 {
+		t.Fatalf("expected no changes and next 5, got %+v, next %d, %v", changes, next, err)
+	}
+}
+
+func TestSubscriber(t *testing.T) {
+	size, policy := *envhandler.ENV.SUB_BUFFER, *envhandler.ENV.SUB_OVERFLOW
+	t.Cleanup(func() {
+		*envhandler.ENV.SUB_BUFFER = size
+		*envhandler.ENV.SUB_OVERFLOW = policy
+	})
+	*envhandler.ENV.SUB_BUFFER = 2
+
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	*envhandler.ENV.SUB_OVERFLOW = envhandler.SUB_DROP_OLDEST
+	dropOldest := hm.Subscribe(nil)
+	*envhandler.ENV.SUB_OVERFLOW = envhandler.SUB_DISCONNECT
+	disconnect := hm.Subscribe(nil)
+	filtered := hm.Subscribe(func(c *Change) bool { return c.Key == "b" })
+
+	// the loop goes on although nobody reads
+	hm.Set(0, "a", "1")
+	hm.Set(0, "b", "2")
+	hm.Set(0, "c", "3")
+	if err := hm.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	ctx := context.Background()
+	next := func(s *Subscriber) string {
+		c, err := s.Next(ctx)
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		return c.Key
+	}
+	if a, b := next(dropOldest), next(dropOldest); a != "b" || b != "c" || dropOldest.Dropped() != 1 {
+		t.Fatalf("drop-oldest: expected b and c with 1 dropped, got %s and %s with %d", a, b, dropOldest.Dropped())
+	}
+	if a, b := next(disconnect), next(disconnect); a != "a" || b != "b" {
+		t.Fatalf("disconnect: expected a and b, got %s and %s", a, b)
+	}
+	if _, err := disconnect.Next(ctx); !errors.Is(err, ErrSubscriberOverflow) {
+		t.Fatalf("disconnect: expected ErrSubscriberOverflow, got %v", err)
+	}
+	if key := next(filtered); key != "b" {
+		t.Fatalf("filtered: expected b, got %s", key)
+	}
+
+	// Next waits for the next change
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := filtered.Next(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		hm.Set(0, "b", "4")
+	}()
+	if c, err := filtered.Next(ctx); err != nil || c.Value != "4" {
+		t.Fatalf("expected the new value of b, got %+v, %v", c, err)
+	}
+
+	// closing the DB ends the subscriptions - after the changes still buffered
+	_ = hm.Close()
+	if key := next(dropOldest); key != "b" {
+		t.Fatalf("expected the buffered b, got %s", key)
+	}
+	if _, err := dropOldest.Next(ctx); !errors.Is(err, ErrSubscriberClosed) {
+		t.Fatalf("expected ErrSubscriberClosed, got %v", err)
+	}
+	if _, err := hm.Subscribe(nil).Next(ctx); !errors.Is(err, ErrSubscriberClosed) {
+		t.Fatalf("expected a closed subscriber, got %v", err)
+	}
+}
+
+func TestChangelog_Swap(t *testing.T) {
+	nameA, nameB := uniqueAOFName(t)+"_a", uniqueAOFName(t)+"_b"
+	a, err := NewHashMap(nameA)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	b, err := NewHashMap(nameB)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	fileName := a.Aof.changelogName()
+	t.Cleanup(func() {
+		_ = a.Close()
+		_ = b.Close()
+		removeAOF(t, nameA)
+		removeAOF(t, nameB)
+		_ = os.Remove(fileName)
+	})
+	if err := a.EnableChangelog(); err != nil {
+		t.Fatalf("EnableChangelog: %v", err)
+	}
+	dbB := b.Name
+
+	// the changelog stays with the name of a and records the swap with b
+	if err := SwapDBs(a, b); err != nil {
+		t.Fatalf("SwapDBs: %v", err)
+	}
+	if a.Changelog() != nil || b.Changelog() == nil {
+		t.Fatalf("the changelog did not stay with the name")
+	}
+	changes, _, err := b.Changelog().Changes(0, 100)
+	if err != nil || len(changes) != 1 || changes[0].Action != "swap" || changes[0].Value != dbB {
+		t.Fatalf("expected the swap with %s, got %v %+v", dbB, err, changes)
+	}
+}
+
+func TestChangelog_Rotation(t *testing.T) {
+	maxBytes, retention := *envhandler.ENV.CHANGELOG_MAX_BYTES, *envhandler.ENV.CHANGELOG_RETENTION
+	t.Cleanup(func() {
+		*envhandler.ENV.CHANGELOG_MAX_BYTES, *envhandler.ENV.CHANGELOG_RETENTION = maxBytes, retention
+	})
+	*envhandler.ENV.CHANGELOG_MAX_BYTES = 1000
+
+	fileName := filepath.Join(t.TempDir(), "db"+changelogExt)
+	c, err := openChangelog(fileName)
+	if err != nil {
+		t.Fatalf("openChangelog: %v", err)
+	}
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		if _, err := c.append(Data{Action: "set", Key: "key-" + strconv.Itoa(i), Value: "v"}, now); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	// the size is bounded, the kept changes are contiguous up to the last one
+	info, err := os.Stat(fileName + changelogRotatedExt)
+	if err != nil {
+		t.Fatalf("no rotated segment: %v", err)
+	}
+	if info.Size()+c.size > 1000 {
+		t.Fatalf("changelog has %d bytes", info.Size()+c.size)
+	}
+	changes, oldest, err := c.Changes(0, 100)
+	if err != nil || oldest <= 1 || len(changes) == 0 || changes[0].Seq != oldest {
+		t.Fatalf("Changes: %v, oldest %d, %+v", err, oldest, changes)
+	}
+	for i, ch := range changes {
+		if ch.Seq != oldest+int64(i) {
+			t.Fatalf("change %d has the seq %d", i, ch.Seq)
+		}
+	}
+	if changes[len(changes)-1].Seq != 20 {
+		t.Fatalf("last change %d", changes[len(changes)-1].Seq)
+	}
+
+	// with a retention the old changes are rotated and dropped
+	*envhandler.ENV.CHANGELOG_MAX_BYTES = maxBytes
+	*envhandler.ENV.CHANGELOG_RETENTION = 1
+	if err := c.retain(now.Add(2 * time.Second)); err != nil {
+		t.Fatalf("retain: %v", err)
+	}
+	if err := c.retain(now.Add(4 * time.Second)); err != nil {
+		t.Fatalf("retain: %v", err)
+	}
+	if changes, oldest, _ := c.Changes(0, 100); len(changes) != 0 || oldest != 21 {
+		t.Fatalf("expected no changes and oldest 21, got %d, %+v", oldest, changes)
+	}
+	if _, err := os.Stat(fileName + changelogRotatedExt); !os.IsNotExist(err) {
+		t.Fatalf("rotated segment not dropped: %v", err)
+	}
+
+	// a line cut by a crash is dropped, the sequence goes on
+	_, _ = c.append(Data{Action: "del", Key: "a"}, now)
+	_ = c.close()
+	f, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"seq":22,"ti`)
+	_ = f.Close()
+	c, err = openChangelog(fileName)
+	if err != nil {
+		t.Fatalf("openChangelog: %v", err)
+	}
+	_, _ = c.append(Data{Action: "del", Key: "b"}, now)
+	if changes, _, _ := c.Changes(20, 100); len(changes) != 2 || changes[1].Seq != 22 || changes[1].Key != "b" {
+		t.Fatalf("expected the changes 21 and 22, got %+v", changes)
+	}
+	_ = c.close()
+}
+
+func TestAOF_SyncDir(t *testing.T) {
+	if err := fsyncDir(t.TempDir()); err != nil {
+		t.Fatalf("fsyncDir: %v", err)
+	}
+	if err := fsyncDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+
+	var dirs []string
+	t.Cleanup(func() { syncDir = fsyncDir })
+	syncDir = func(dir string) error {
+		dirs = append(dirs, dir)
+		return fsyncDir(dir)
+	}
+
+	// the directory is synced once the AOF is created and after every compaction - not when an AOF is reopened
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+	hm.Set(0, "a", "1")
+	hm.Aof.Compact()
+	_ = hm.Close()
+	if hm, err = NewHashMap(name); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+
+	want := filepath.Dir(hm.Aof.FileName)
+	if !slices.Equal(dirs, []string{want, want}) {
+		t.Fatalf("synced %v, want %s twice", dirs, want)
+	}
+}
+
+func TestAOF_ReadFrameBuffer(t *testing.T) {
+	huge := strings.Repeat("h", 10*1024*1024)
+	frames := []Data{
+		{Action: "set", Key: "tiny-1", Value: "1"},
+		{Action: "set", Key: "huge", Value: huge},
+		{Action: "set", Key: "tiny-2", Value: "2", Ttl: 60},
+		{Action: "set", Key: "max", Value: strings.Repeat("m", readBufMax)},
+		{Action: "set", Key: "tiny-3", Value: ""},
+	}
+	data := appendHeader(nil, 0)
+	for _, d := range frames {
+		data = appendFrame(data, d)
+	}
+
+	// the buffer of a huge value is dropped after its frame, one up to readBufMax is kept
+	a := &AOF{}
+	r := bufio.NewReader(bytes.NewReader(data))
+	if err := a.readHeader(r); err != nil {
+		t.Fatalf("readHeader: %v", err)
+	}
+	for _, want := range frames {
+		var d Data
+		if err := a.readFrame(r, &d); err != nil {
+			t.Fatalf("readFrame %s: %v", want.Key, err)
+		}
+		if d != want {
+			t.Fatalf("readFrame %s: got %s with %d bytes", want.Key, d.Key, len(d.Value))
+		}
+		if len(a.readBuf) > readBufMax {
+			t.Fatalf("after %s the buffer holds %d bytes", want.Key, len(a.readBuf))
+		}
+	}
+	if len(a.readBuf) != readBufMax {
+		t.Fatalf("buffer of max not kept, got %d bytes", len(a.readBuf))
+	}
+
+	// a replay buffer smaller than the frames still reads them
+	replayBuffer := *envhandler.ENV.REPLAY_BUFFER
+	t.Cleanup(func() { *envhandler.ENV.REPLAY_BUFFER = replayBuffer })
+	*envhandler.ENV.REPLAY_BUFFER = 16
+
+	name := uniqueAOFName(t)
+	t.Cleanup(func() { removeAOF(t, name) })
+	if err := os.WriteFile(filepath.Join(*envhandler.ENV.DB_FOLDER, name+".bin"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	hm, err := OpenHashMap(name)
+	if err != nil {
+		t.Fatalf("OpenHashMap: %v", err)
+	}
+	defer hm.Close()
+	if err := hm.ReplayAOF(); err != nil {
+		t.Fatalf("ReplayAOF: %v", err)
+	}
+	if ok, v := hm.Get("huge"); !ok || v != huge {
+		t.Fatalf("huge after replay: got %d bytes", len(v))
+	}
+	if hm.GetEntries() != int64(len(frames)) {
+		t.Fatalf("entries after replay: got %d want %d", hm.GetEntries(), len(frames))
+	}
+}
+
+// BenchmarkHashMap_ReplayOverwrites replays an AOF of 1M sets spread over a number of keys
+func BenchmarkHashMap_ReplayOverwrites(b *testing.B) {
+	for _, keys := range []int{1, 1000, 1000000} {
+		b.Run(strconv.Itoa(keys)+"keys", func(b *testing.B) {
+			name := fmt.Sprintf("bench_replay_%d", time.Now().UnixNano())
+			file := filepath.Join(*envhandler.ENV.DB_FOLDER, name+".bin")
+			b.Cleanup(func() { _ = os.Remove(file) })
+			data := appendHeader(nil, 0)
+			for i := range 1000000 {
+				data = appendFrame(data, Data{Action: "set", Key: "k-" + strconv.Itoa(i%keys), Value: "v-" + strconv.Itoa(i)})
+			}
+			if err := os.WriteFile(file, data, 0644); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			for b.Loop() {
+				hm, err := OpenHashMap(name)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := hm.ReplayAOF(); err != nil || hm.GetEntries() != int64(keys) {
+					b.Fatalf("replay: %v, %d entries", err, hm.GetEntries())
+				}
+				_ = hm.Close()
+			}
+		})
+	}
+}
+
+func TestHashMap_ReplayProgress(t *testing.T) {
+	interval := progressLogInterval
+	t.Cleanup(func() { progressLogInterval = interval })
+	progressLogInterval = 0
+
+	name := uniqueAOFName(t)
+	t.Cleanup(func() { removeAOF(t, name) })
+	data := appendHeader(nil, 0)
+	const frames = 3*progressFrames + 5
+	for i := 0; i < frames; i++ {
+		data = appendFrame(data, Data{Action: "set", Key: "key-" + strconv.Itoa(i), Value: "v"})
+	}
+	if err := os.WriteFile(filepath.Join(*envhandler.ENV.DB_FOLDER, name+".bin"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hm, err := OpenHashMap(name)
+	if err != nil {
+		t.Fatalf("OpenHashMap: %v", err)
+	}
+	t.Cleanup(func() { _ = hm.Close() })
+	if progress := hm.ReplayProgress(); progress.Frames != 0 || progress.Elapsed != 0 {
+		t.Fatalf("expected no progress before the replay, got %+v", progress)
+	}
+	if err := hm.ReplayAOF(); err != nil {
+		t.Fatalf("ReplayAOF: %v", err)
+	}
+
+	// the final numbers cover the whole file, not only the last update
+	progress := hm.ReplayProgress()
+	if progress.Frames != frames || progress.Bytes != int64(len(data)) || progress.Size != int64(len(data)) {
+		t.Fatalf("expected %d frames and %d bytes, got %+v", frames, len(data), progress)
+	}
+	if progress.Elapsed <= 0 {
+		t.Fatalf("expected an elapsed time, got %v", progress.Elapsed)
+	}
+	if elapsed := hm.ReplayProgress().Elapsed; elapsed != progress.Elapsed {
+		t.Fatalf("expected the clock to stop with the replay, got %v and %v", progress.Elapsed, elapsed)
+	}
+}
+
+func TestHashMap_CompactStreams(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+
+	// types whose frames come in a fixed order - fields and members of hashes and sets don't. The keys have no TTL,
+	// the snapshot writes the remaining one while the compaction keeps the TTL of the frames.
+	for i := 0; i < 500; i++ {
+		hm.Set(0, "key-"+strconv.Itoa(i), "v")
+	}
+	hm.CounterIncr(0, "hits", 7)
+	hm.SetWithContentType(0, "doc", "{}", "application/json", false)
+	hm.RPush("queue", "a")
+	hm.RPush("queue", "b")
+	hm.Del("key-0")
+
+	// the streamed compaction writes the same file as a snapshot
+	var want bytes.Buffer
+	snapshot, err := hm.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	_, err = snapshot.WriteTo(&want)
+	_ = snapshot.Close()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	hm.Aof.Compact()
+	got, err := os.ReadFile(hm.Aof.FileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Fatalf("compacted AOF differs from the snapshot: %d and %d bytes", len(got), want.Len())
+	}
+
+	// a failing walk keeps the old file and drops the tmp file
+	hm.Aof.createCompressedAOF(func(emit func(*AOFEntry) error) error {
+		if err := emit(&AOFEntry{Action: "set", Key: "partial", Value: "v"}); err != nil {
+			return err
+		}
+		return errors.New("walk failed")
+	})
+	after, err := os.ReadFile(hm.Aof.FileName)
+	if err != nil || !bytes.Equal(after, got) {
+		t.Fatalf("expected the AOF to be kept after a failed compaction (err=%v)", err)
+	}
+	tmpName := strings.TrimSuffix(hm.Aof.FileName, ".bin") + ".tmp.bin"
+	if _, err := os.Stat(tmpName); !os.IsNotExist(err) {
+		t.Fatalf("expected the tmp file to be removed, got %v", err)
+	}
+}
+
+func TestAOF_CompactDuringWrites(t *testing.T) {
+	name := uniqueAOFName(t)
+	hm, err := NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap error: %v", err)
+	}
+
+	// counters are not idempotent - a frame written before and after the compaction would count twice
+	const writers, incrs = 16, 5000
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < incrs; i++ {
+				hm.CounterIncr(0, "hits", 1)
+			}
+		}()
+	}
+	stop := make(chan struct{})
+	compacted := make(chan struct{})
+	go func() {
+		defer close(compacted)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				hm.Aof.Compact()
+			}
+		}
+	}()
+	wg.Wait()
+	close(stop)
+	<-compacted
+
+	if err := hm.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	hm, err = NewHashMap(name)
+	if err != nil {
+		t.Fatalf("NewHashMap reopen error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = hm.Close()
+		removeAOF(t, name)
+	})
+	if ok, v := hm.Get("hits"); !ok || v != strconv.Itoa(writers*incrs) {
+		t.Fatalf("hits after replay: got %q want %d", v, writers*incrs)
+	}
+}
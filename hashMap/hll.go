@@ -0,0 +1,62 @@
+package hashMap
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+// hllPrecision is the number of hash bits which select a register - 2^12 registers give a standard error of ~1.6%
+const hllPrecision = 12
+
+// hll is a HyperLogLog estimating the number of distinct keys. It is fed with the xxhash of the keys, which is
+// computed for the basket index anyway. Registers are updated lock free, since inserts only hold basket locks.
+type hll struct {
+	registers [1 << hllPrecision]atomic.Uint32
+}
+
+// add counts the key of a hash - a nil hll ignores it
+func (h *hll) add(hash uint64) {
+	if h == nil {
+		return
+	}
+	// the top bits select the register, the basket index uses the bottom bits
+	register := &h.registers[hash>>(64-hllPrecision)]
+	rank := uint32(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1))) + 1
+	for {
+		old := register.Load()
+		if rank <= old || register.CompareAndSwap(old, rank) {
+			return
+		}
+	}
+}
+
+// reset clears all registers - a nil hll ignores it
+func (h *hll) reset() {
+	if h == nil {
+		return
+	}
+	for i := range h.registers {
+		h.registers[i].Store(0)
+	}
+}
+
+// estimate returns the estimated number of distinct keys added
+func (h *hll) estimate() int64 {
+	const m = float64(1 << hllPrecision)
+	sum, zeros := 0.0, 0
+	for i := range h.registers {
+		r := h.registers[i].Load()
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	// small cardinalities are estimated better by linear counting
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return int64(e + 0.5)
+}
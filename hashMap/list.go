@@ -0,0 +1,181 @@
+package hashMap
+
+import (
+	"hydrakv/envhandler"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// rangeBounds resolves the inclusive positions start and stop of a collection with n elements - negative positions
+// count from the end. Returns false if the range is empty.
+func rangeBounds(n, start, stop int) (int, int, bool) {
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if stop < 0 {
+		stop = n + stop
+	}
+	stop = min(stop, n-1)
+	return start, stop, start <= stop
+}
+
+// LPush prepends a value to the list stored at key - a missing key is created as list
+func (hm *HashMap) LPush(key, value string) bool {
+	return hm.push(key, value, true)
+}
+
+// RPush appends a value to the list stored at key - a missing key is created as list
+func (hm *HashMap) RPush(key, value string) bool {
+	return hm.push(key, value, false)
+}
+
+// push adds a value at the head or the tail of a list. Returns false if the key holds another type or the list would
+// exceed HKV_ENTRY_SIZE bytes.
+func (hm *HashMap) push(key, value string, head bool) bool {
+	operation := "rpush"
+	if head {
+		operation = "lpush"
+	}
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues(operation))
+	defer timer.ObserveDuration()
+
+	if !validKey(key, operation) {
+		return false
+	}
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	ok := false
+	hm.withEntry(key, true, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item != nil && item.Type != TypeList {
+			kvOperations.WithLabelValues(operation, "wrong_type").Inc()
+			return
+		}
+
+		// the values of a list are limited by HKV_ENTRY_SIZE
+		size := len(value)
+		if item != nil {
+			size += item.size
+		}
+		if size > *envhandler.ENV.ENTRY_SIZE && !hm.reset {
+			kvOperations.WithLabelValues(operation, "too_large").Inc()
+			return
+		}
+
+		if !hm.reset {
+			frame.add(hm.Aof, Data{Action: operation, Key: key, Value: value})
+		}
+
+		if item == nil {
+			item = &Entry{Key: key, Hash: hash, Next: basket.Items, Type: TypeList}
+			hm.addEntry(basket, item)
+		}
+		if head {
+			item.List = append(item.List, "")
+			copy(item.List[1:], item.List)
+			item.List[0] = value
+		} else {
+			item.List = append(item.List, value)
+		}
+		item.size = size
+		item.touch()
+		ok = true
+		kvOperations.WithLabelValues(operation, "ok").Inc()
+	})
+	return ok
+}
+
+// LPop removes and returns the first value of the list stored at key
+func (hm *HashMap) LPop(key string) (string, bool) {
+	return hm.pop(key, true)
+}
+
+// RPop removes and returns the last value of the list stored at key
+func (hm *HashMap) RPop(key string) (string, bool) {
+	return hm.pop(key, false)
+}
+
+// pop removes a value from the head or the tail of a list - the key is removed with the last value
+func (hm *HashMap) pop(key string, head bool) (string, bool) {
+	operation := "rpop"
+	if head {
+		operation = "lpop"
+	}
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues(operation))
+	defer timer.ObserveDuration()
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	var value string
+	ok := false
+	hm.withEntry(key, true, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item == nil || item.Type != TypeList {
+			kvOperations.WithLabelValues(operation, "not_found").Inc()
+			return
+		}
+
+		if !hm.reset {
+			frame.add(hm.Aof, Data{Action: operation, Key: key})
+		}
+
+		last := len(item.List) - 1
+		if head {
+			value = item.List[0]
+			item.List[0] = ""
+			item.List = item.List[1:]
+		} else {
+			value = item.List[last]
+			item.List[last] = ""
+			item.List = item.List[:last]
+		}
+		item.size -= len(value)
+		item.touch()
+		if len(item.List) == 0 {
+			hm.unlinkEntry(basket, item, prev)
+		}
+		ok = true
+		kvOperations.WithLabelValues(operation, "ok").Inc()
+	})
+	return value, ok
+}
+
+// LRange returns the values of the list stored at key from position start to stop (both inclusive). Negative
+// positions count from the end, e.g. -1 is the last value. Returns nil if the key holds no list and an empty slice if
+// the range is empty.
+func (hm *HashMap) LRange(key string, start, stop int) []string {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("lrange"))
+	defer timer.ObserveDuration()
+
+	var values []string
+	hm.withEntry(key, false, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item == nil || item.Type != TypeList {
+			kvOperations.WithLabelValues("lrange", "not_found").Inc()
+			return
+		}
+		values = []string{}
+		if start, stop, ok := rangeBounds(len(item.List), start, stop); ok {
+			values = append(values, item.List[start:stop+1]...)
+		}
+		kvOperations.WithLabelValues("lrange", "found").Inc()
+	})
+	return values
+}
+
+// LLen returns the number of values of the list stored at key - 0 if the key holds no list
+func (hm *HashMap) LLen(key string) int {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("llen"))
+	defer timer.ObserveDuration()
+
+	n := 0
+	hm.withEntry(key, false, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item != nil && item.Type == TypeList {
+			n = len(item.List)
+		}
+	})
+	kvOperations.WithLabelValues("llen", "ok").Inc()
+	return n
+}
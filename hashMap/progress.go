@@ -0,0 +1,98 @@
+package hashMap
+
+import (
+	"io"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// progressFrames is the number of frames between two progress updates of a replay - the clock is read only then,
+// so the progress costs the replay next to nothing
+const progressFrames = 1 << 12
+
+// progressLogInterval is the minimum time between two progress logs of a replay
+var progressLogInterval = 2 * time.Second
+
+// ReplayProgress describes the AOF replay of a HashMap
+type ReplayProgress struct {
+	// Frames is the number of replayed frames
+	Frames int64
+	// Bytes is the number of bytes read from the AOF and Size the size of the AOF at the start of the replay
+	Bytes int64
+	Size  int64
+	// Elapsed is the time since the replay started
+	Elapsed time.Duration
+}
+
+// replayProgress is updated by the replay and read by ReplayProgress from other goroutines
+type replayProgress struct {
+	frames atomic.Int64
+	read   atomic.Int64
+	size   atomic.Int64
+	// start and end are the start and the end of the replay in Unix nanoseconds - 0 before
+	start atomic.Int64
+	end   atomic.Int64
+	// logged is the time of the last progress log, only used by the replay
+	logged time.Time
+}
+
+// begin resets the progress for a replay of an AOF of size bytes
+func (p *replayProgress) begin(size int64) {
+	p.frames.Store(0)
+	p.read.Store(0)
+	p.size.Store(size)
+	p.end.Store(0)
+	p.logged = time.Now()
+	p.start.Store(p.logged.UnixNano())
+}
+
+// update records the replayed frames and logs the progress at most every progressLogInterval
+func (p *replayProgress) update(name string, frames int64) {
+	p.frames.Store(frames)
+	now := time.Now()
+	if now.Sub(p.logged) < progressLogInterval {
+		return
+	}
+	p.logged = now
+	progress := p.snapshot()
+	log.Printf("Replaying AOF for %s: %d frames, %d of %d bytes (%.0f%%) in %s", name, progress.Frames,
+		progress.Bytes, progress.Size, 100*float64(progress.Bytes)/float64(max(progress.Size, 1)),
+		progress.Elapsed.Round(time.Millisecond))
+}
+
+// finish records the frames of the finished replay and stops its clock
+func (p *replayProgress) finish(frames int64) {
+	p.frames.Store(frames)
+	p.end.Store(time.Now().UnixNano())
+}
+
+// snapshot returns the current progress
+func (p *replayProgress) snapshot() ReplayProgress {
+	progress := ReplayProgress{Frames: p.frames.Load(), Bytes: p.read.Load(), Size: p.size.Load()}
+	start, end := p.start.Load(), p.end.Load()
+	switch {
+	case end != 0:
+		progress.Elapsed = time.Duration(end - start)
+	case start != 0:
+		progress.Elapsed = time.Since(time.Unix(0, start))
+	}
+	return progress
+}
+
+// progressReader counts the bytes read from the AOF - it sits below the buffered reader, so it counts once per fill
+type progressReader struct {
+	r    io.Reader
+	read *atomic.Int64
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.read.Add(int64(n))
+	return n, err
+}
+
+// ReplayProgress returns the progress of the AOF replay - the final numbers once the HashMap is ready
+func (hm *HashMap) ReplayProgress() ReplayProgress {
+	return hm.progress.snapshot()
+}
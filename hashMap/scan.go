@@ -0,0 +1,64 @@
+package hashMap
+
+import (
+	"slices"
+)
+
+// KeyScan iterates the keys of a DB in the order of their hashes. The order of the baskets changes with every resize,
+// so a walk over them during writes can miss or repeat keys - the hashes don't change. ScanKeys takes a sorted
+// snapshot of the hashes, every key present for the whole scan is returned exactly once and no key twice. A key
+// written during the scan is only returned if it shares its hash with a key of the snapshot. The price is the
+// snapshot: 8 bytes per key for as long as the scan is kept, e.g. for an export or a migration.
+type KeyScan struct {
+	hm     *HashMap
+	hashes []uint64
+	pos    int
+}
+
+// ScanKeys takes the snapshot of the key hashes of the DB for a KeyScan. The baskets are read one after the other,
+// writes are not held back for the whole snapshot.
+func (hm *HashMap) ScanKeys() *KeyScan {
+	hm.mutex.RLock()
+	hashes := make([]uint64, 0, max(hm.GetEntries(), 0))
+	for index, basket := range hm.table {
+		unlock := hm.rlockBasket(index)
+		for item := basket.Items; item != nil; item = item.Next {
+			hashes = append(hashes, item.Hash)
+		}
+		unlock()
+	}
+	hm.mutex.RUnlock()
+
+	slices.Sort(hashes)
+	return &KeyScan{hm: hm, hashes: slices.Compact(hashes)}
+}
+
+// Next returns the keys of the next count hashes of the snapshot which are still in the DB - fewer keys for deleted
+// ones, more for keys sharing a hash. done is true once all hashes are visited.
+func (ks *KeyScan) Next(count int) (keys []string, done bool) {
+	end := min(ks.pos+max(count, 0), len(ks.hashes))
+	keys = make([]string, 0, end-ks.pos)
+
+	hm := ks.hm
+	hm.mutex.RLock()
+	for _, hash := range ks.hashes[ks.pos:end] {
+		// the basket of the hash in the current table - it may have moved since the snapshot
+		index := int(hash & uint64(len(hm.table)-1))
+		unlock := hm.rlockBasket(index)
+		for item := hm.table[index].Items; item != nil; item = item.Next {
+			if item.Hash == hash {
+				keys = append(keys, item.Key)
+			}
+		}
+		unlock()
+	}
+	hm.mutex.RUnlock()
+
+	ks.pos = end
+	return keys, ks.pos == len(ks.hashes)
+}
+
+// Remaining returns the number of hashes of the snapshot not visited yet
+func (ks *KeyScan) Remaining() int {
+	return len(ks.hashes) - ks.pos
+}
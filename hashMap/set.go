@@ -0,0 +1,152 @@
+package hashMap
+
+import (
+	"hydrakv/envhandler"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SAdd adds a member to the set stored at key - a missing key is created as set. Returns false if the member already
+// exists, the key holds another type or the set would exceed HKV_ENTRY_SIZE bytes.
+func (hm *HashMap) SAdd(key, member string) bool {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("sadd"))
+	defer timer.ObserveDuration()
+
+	if !validKey(key, "sadd") {
+		return false
+	}
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	ok := false
+	hm.withEntry(key, true, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item != nil && item.Type != TypeSet {
+			kvOperations.WithLabelValues("sadd", "wrong_type").Inc()
+			return
+		}
+		if item != nil {
+			if _, exists := item.Members[member]; exists {
+				kvOperations.WithLabelValues("sadd", "exists").Inc()
+				return
+			}
+		}
+
+		// the members of a set are limited by HKV_ENTRY_SIZE
+		size := len(member)
+		if item != nil {
+			size += item.size
+		}
+		if size > *envhandler.ENV.ENTRY_SIZE && !hm.reset {
+			kvOperations.WithLabelValues("sadd", "too_large").Inc()
+			return
+		}
+
+		// only changes are written to the AOF
+		if !hm.reset {
+			frame.add(hm.Aof, Data{Action: "sadd", Key: key, Value: member})
+		}
+
+		if item == nil {
+			item = &Entry{Key: key, Hash: hash, Next: basket.Items, Type: TypeSet, Members: make(map[string]struct{})}
+			hm.addEntry(basket, item)
+		}
+		item.Members[member] = struct{}{}
+		item.size = size
+		item.touch()
+		ok = true
+		kvOperations.WithLabelValues("sadd", "ok").Inc()
+	})
+	return ok
+}
+
+// SRem removes a member of the set stored at key - the key is removed with the last member. Returns true if the
+// member existed.
+func (hm *HashMap) SRem(key, member string) bool {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("srem"))
+	defer timer.ObserveDuration()
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	ok := false
+	hm.withEntry(key, true, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item == nil || item.Type != TypeSet {
+			kvOperations.WithLabelValues("srem", "not_found").Inc()
+			return
+		}
+		if _, exists := item.Members[member]; !exists {
+			kvOperations.WithLabelValues("srem", "not_found").Inc()
+			return
+		}
+
+		if !hm.reset {
+			frame.add(hm.Aof, Data{Action: "srem", Key: key, Value: member})
+		}
+		delete(item.Members, member)
+		item.size -= len(member)
+		item.touch()
+		if len(item.Members) == 0 {
+			hm.unlinkEntry(basket, item, prev)
+		}
+		ok = true
+		kvOperations.WithLabelValues("srem", "ok").Inc()
+	})
+	return ok
+}
+
+// SIsMember returns true if member is a member of the set stored at key
+func (hm *HashMap) SIsMember(key, member string) bool {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("sismember"))
+	defer timer.ObserveDuration()
+
+	found := false
+	hm.withEntry(key, false, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item != nil && item.Type == TypeSet {
+			_, found = item.Members[member]
+		}
+	})
+	kvOperations.WithLabelValues("sismember", "ok").Inc()
+	return found
+}
+
+// SMembers returns the sorted members of the set stored at key - nil if the key holds no set
+func (hm *HashMap) SMembers(key string) []string {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("smembers"))
+	defer timer.ObserveDuration()
+
+	var members []string
+	hm.withEntry(key, false, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item == nil || item.Type != TypeSet {
+			kvOperations.WithLabelValues("smembers", "not_found").Inc()
+			return
+		}
+		members = make([]string, 0, len(item.Members))
+		for member := range item.Members {
+			members = append(members, member)
+		}
+		kvOperations.WithLabelValues("smembers", "found").Inc()
+	})
+
+	// sorted outside of the lock
+	sort.Strings(members)
+	return members
+}
+
+// SCard returns the number of members of the set stored at key - 0 if the key holds no set
+func (hm *HashMap) SCard(key string) int {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("scard"))
+	defer timer.ObserveDuration()
+
+	card := 0
+	hm.withEntry(key, false, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item != nil && item.Type == TypeSet {
+			card = len(item.Members)
+		}
+	})
+	kvOperations.WithLabelValues("scard", "ok").Inc()
+	return card
+}
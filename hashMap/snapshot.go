@@ -0,0 +1,97 @@
+package hashMap
+
+import (
+	"bufio"
+	"hydrakv/envhandler"
+	"hydrakv/utils"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// snapshotPrefix and snapshotSuffix frame the names of the spool files - no *.bin, so a shared folder with the AOFs
+// is safe
+const (
+	snapshotPrefix = ".snapshot-"
+	snapshotSuffix = ".tmp"
+)
+
+// Snapshot is a point-in-time copy of the live entries of a HashMap. It is encoded in the AOF format, so a written
+// snapshot can be replayed as AOF of a new DB. The frames are spooled to a file in HKV_SNAPSHOT_FOLDER, which Close
+// removes.
+type Snapshot struct {
+	file *os.File
+	size int64
+}
+
+// Snapshot streams the live entries with their remaining TTL into a spool file under the global lock, like the
+// compaction does - the snapshot is written by WriteTo without holding it
+func (hm *HashMap) Snapshot() (*Snapshot, error) {
+	dir := envhandler.ENV.SnapshotFolder()
+	if err := utils.U.EnsureDir(dir, envhandler.SNAPSHOT_FOLDER); err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(dir, snapshotPrefix+hm.Name+"-*"+snapshotSuffix)
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{file: file}
+	buf := bufio.NewWriterSize(file, 64*1024)
+	frame := appendHeader(nil, hm.Aof.seed)
+	_, err = buf.Write(frame)
+	if err == nil {
+		err = hm.walkEntries(func(e *AOFEntry) error {
+			frame = appendEntry(frame[:0], e)
+			_, err := buf.Write(frame)
+			return err
+		}, (*Entry).remainingTtl)
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err == nil {
+		s.size, err = file.Seek(0, io.SeekCurrent)
+	}
+	if err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Size returns the number of bytes WriteTo writes
+func (s *Snapshot) Size() int64 {
+	return s.size
+}
+
+// WriteTo writes the snapshot as AOF with header and frames to w
+func (s *Snapshot) WriteTo(w io.Writer) (int64, error) {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.Copy(w, io.LimitReader(s.file, s.size))
+}
+
+// Close removes the spool file of the snapshot
+func (s *Snapshot) Close() error {
+	err := s.file.Close()
+	if rmErr := os.Remove(s.file.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// RemoveStaleSnapshots removes the spool files a crash left in HKV_SNAPSHOT_FOLDER - it runs at startup, before a
+// snapshot is taken
+func RemoveStaleSnapshots() error {
+	stale, err := filepath.Glob(filepath.Join(envhandler.ENV.SnapshotFolder(), snapshotPrefix+"*"+snapshotSuffix))
+	if err != nil {
+		return err
+	}
+	for _, name := range stale {
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package hashMap
+
+import (
+	"context"
+	"errors"
+	"hydrakv/envhandler"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ErrSubscriberClosed is returned by Next once the subscriber or its DB got closed
+	ErrSubscriberClosed = errors.New("subscriber closed")
+	// ErrSubscriberOverflow is returned by Next once a subscriber fell behind with HKV_SUB_OVERFLOW=disconnect
+	ErrSubscriberOverflow = errors.New("subscriber fell behind")
+)
+
+// kvSubDropped counts the changes which did not fit into the buffer of a subscriber
+var kvSubDropped = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kv_sub_dropped_total",
+		Help: "Total number of notifications dropped because the buffer of a subscriber was full",
+	},
+	[]string{"db", "policy"},
+)
+
+// Subscriber receives the changes of a DB from the AOF loop. The changes wait in a ring buffer of HKV_SUB_BUFFER
+// entries - the loop never blocks on a slow subscriber: once the buffer is full HKV_SUB_OVERFLOW either drops the
+// oldest change or closes the subscriber. Seq is the one of the changelog and 0 for a DB without changelog.
+type Subscriber struct {
+	subs  *subscribers
+	match func(*Change) bool
+
+	mu     sync.Mutex
+	buf    []Change
+	head   int
+	n      int
+	policy string
+	err    error
+	// ready is signaled after a change got published or the subscriber got closed
+	ready   chan struct{}
+	dropped atomic.Int64
+}
+
+// Next returns the oldest buffered change - waiting for one until ctx is done. A closed subscriber returns the
+// changes still buffered first, then ErrSubscriberClosed or ErrSubscriberOverflow.
+func (s *Subscriber) Next(ctx context.Context) (Change, error) {
+	for {
+		s.mu.Lock()
+		if s.n > 0 {
+			c := s.buf[s.head]
+			s.buf[s.head] = Change{}
+			s.head = (s.head + 1) % len(s.buf)
+			s.n--
+			s.mu.Unlock()
+			return c, nil
+		}
+		err := s.err
+		s.mu.Unlock()
+		if err != nil {
+			return Change{}, err
+		}
+
+		select {
+		case <-s.ready:
+		case <-ctx.Done():
+			return Change{}, ctx.Err()
+		}
+	}
+}
+
+// Dropped returns the number of changes which did not fit into the buffer
+func (s *Subscriber) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes from the DB
+func (s *Subscriber) Close() {
+	s.subs.remove(s)
+	s.close(ErrSubscriberClosed)
+}
+
+// publish buffers the change without blocking. Returns false if the subscriber got closed by its overflow policy.
+func (s *Subscriber) publish(c *Change) bool {
+	if s.match != nil && !s.match(c) {
+		return true
+	}
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return true
+	}
+	if s.n == len(s.buf) {
+		s.dropped.Add(1)
+		kvSubDropped.WithLabelValues(s.subs.name, s.policy).Inc()
+		if s.policy == envhandler.SUB_DISCONNECT {
+			s.err = ErrSubscriberOverflow
+			s.mu.Unlock()
+			s.signal()
+			return false
+		}
+		s.head = (s.head + 1) % len(s.buf)
+		s.n--
+	}
+	s.buf[(s.head+s.n)%len(s.buf)] = *c
+	s.n++
+	s.mu.Unlock()
+	s.signal()
+	return true
+}
+
+// close ends the subscription with err - Next returns it once the buffer is empty
+func (s *Subscriber) close(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.signal()
+}
+
+// signal wakes up a waiting Next - a signal already pending is enough
+func (s *Subscriber) signal() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// subscribers are the subscribers of a DB - count lets the AOF loop skip the changes of DBs without any
+type subscribers struct {
+	name  string
+	mu    sync.RWMutex
+	list  map[*Subscriber]struct{}
+	count atomic.Int32
+	// closed is set once the DB is closed - new subscribers are closed right away
+	closed bool
+}
+
+// active returns true if the DB has subscribers
+func (ss *subscribers) active() bool {
+	return ss.count.Load() > 0
+}
+
+// add registers a new subscriber with the buffer size and overflow policy of the settings
+func (ss *subscribers) add(match func(*Change) bool) *Subscriber {
+	s := &Subscriber{subs: ss, match: match, buf: make([]Change, *envhandler.ENV.SUB_BUFFER),
+		policy: *envhandler.ENV.SUB_OVERFLOW, ready: make(chan struct{}, 1)}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.closed {
+		s.err = ErrSubscriberClosed
+		return s
+	}
+	if ss.list == nil {
+		ss.list = make(map[*Subscriber]struct{})
+	}
+	ss.list[s] = struct{}{}
+	ss.count.Store(int32(len(ss.list)))
+	return s
+}
+
+// remove unregisters the subscriber
+func (ss *subscribers) remove(s *Subscriber) {
+	ss.mu.Lock()
+	delete(ss.list, s)
+	ss.count.Store(int32(len(ss.list)))
+	ss.mu.Unlock()
+}
+
+// publish passes the change to every subscriber - the ones closed by their overflow policy are removed
+func (ss *subscribers) publish(c Change) {
+	var overflowed []*Subscriber
+	ss.mu.RLock()
+	for s := range ss.list {
+		if !s.publish(&c) {
+			overflowed = append(overflowed, s)
+		}
+	}
+	ss.mu.RUnlock()
+	for _, s := range overflowed {
+		ss.remove(s)
+	}
+}
+
+// closeAll closes every subscriber - the DB is closed
+func (ss *subscribers) closeAll() {
+	ss.mu.Lock()
+	list := ss.list
+	ss.list, ss.closed = nil, true
+	ss.count.Store(0)
+	ss.mu.Unlock()
+	for s := range list {
+		s.close(ErrSubscriberClosed)
+	}
+}
+
+// Subscribe returns a subscriber receiving the changes of the DB for which match returns true - all for a nil match.
+// The subscriber has to be closed once it is no longer needed.
+func (hm *HashMap) Subscribe(match func(*Change) bool) *Subscriber {
+	return hm.Aof.subs.Load().add(match)
+}
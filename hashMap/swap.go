@@ -0,0 +1,70 @@
+package hashMap
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+)
+
+// swapTmpExt is appended to the AOF moved aside while two DBs are swapped - without the .bin suffix a restart does
+// not load it as a DB
+const swapTmpExt = ".swap"
+
+// SwapDBs swaps the names and AOF files of a and b, so each one goes on as the other DB - the changelogs keep their
+// names. The AOF loops of both are paused while the files are renamed; the caller has to keep other users of the
+// names away, e.g. by a lock. A failed rename is rolled back. A crash between the renames leaves the AOF of a as
+// <a>.bin.swap.
+func SwapDBs(a, b *HashMap) error {
+	resumeA := a.Aof.pause()
+	defer resumeA()
+	resumeB := b.Aof.pause()
+	defer resumeB()
+
+	fileA, fileB := a.Aof.FileName, b.Aof.FileName
+	tmpName := fileA + swapTmpExt
+	if err := rename(fileA, tmpName); err != nil {
+		return fmt.Errorf("swap %s and %s: %w", a.Name, b.Name, err)
+	}
+	if err := rename(fileB, fileA); err != nil {
+		_ = rename(tmpName, fileA)
+		return fmt.Errorf("swap %s and %s: %w", a.Name, b.Name, err)
+	}
+	if err := rename(tmpName, fileB); err != nil {
+		_ = rename(fileA, fileB)
+		_ = rename(tmpName, fileA)
+		return fmt.Errorf("swap %s and %s: %w", a.Name, b.Name, err)
+	}
+
+	// like after a compaction - a crash before could bring back the old names
+	if err := syncDir(filepath.Dir(fileA)); err != nil {
+		log.Println("cannot sync the AOF directory after the swap! " + err.Error())
+		a.Aof.setErr(fmt.Errorf("sync AOF directory: %w", err))
+		b.Aof.setErr(fmt.Errorf("sync AOF directory: %w", err))
+	}
+
+	// the ChainSamplers read the names under the global locks, the TTLManagers under their own
+	a.mutex.Lock()
+	b.mutex.Lock()
+	a.Name, b.Name = b.Name, a.Name
+	b.mutex.Unlock()
+	a.mutex.Unlock()
+	nameA, nameB := a.TTlManager.name(), b.TTlManager.name()
+	a.TTlManager.rename(nameB)
+	b.TTlManager.rename(nameA)
+	a.Aof.name, b.Aof.name = b.Aof.name, a.Aof.name
+	a.Aof.FileName, b.Aof.FileName = fileB, fileA
+	a.Aof.updateMetrics()
+	b.Aof.updateMetrics()
+
+	// the changelogs and subscribers stay with the names - each records the swap, since its DB goes on with the other
+	// data
+	changesA, changesB := a.Aof.changes.Load(), b.Aof.changes.Load()
+	a.Aof.changes.Store(changesB)
+	b.Aof.changes.Store(changesA)
+	subsA, subsB := a.Aof.subs.Load(), b.Aof.subs.Load()
+	a.Aof.subs.Store(subsB)
+	b.Aof.subs.Store(subsA)
+	a.Aof.recordChange(Data{Action: "swap", Value: b.Name})
+	b.Aof.recordChange(Data{Action: "swap", Value: a.Name})
+	return nil
+}
@@ -2,24 +2,70 @@ package hashMap
 
 import (
 	"context"
+	"fmt"
 	"hydrakv/envhandler"
 	"log"
 	"math/bits"
 	"runtime"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// kvTTLDeleteMisses counts expired entries whose delete found no key - deleted meanwhile
+var kvTTLDeleteMisses = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kv_ttl_delete_misses_total",
+		Help: "Total number of expired entries which were already gone when the TTLManager deleted them",
+	},
+	[]string{"db"},
+)
+
+var (
+	// kvTTLEntries is the number of entries tracked by the TTLManager of a DB
+	kvTTLEntries = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kv_ttl_entries",
+			Help: "Number of entries with a TTL tracked by the TTLManager",
+		},
+		[]string{"db"},
+	)
+	// kvTTLBuckets is the number of expiry buckets - one map per second and shard holding entries
+	kvTTLBuckets = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kv_ttl_buckets",
+			Help: "Number of expiry buckets of the TTLManager over all shards",
+		},
+		[]string{"db"},
+	)
 )
 
 type TTLManager struct {
 	List        []*TTLEntryManager
 	lastDeleted atomic.Int64
+	// Name is the DbKey of the DB, like the labels of its other metrics - the watchdog reads it with name(), since
+	// SwapDBs changes it with rename
 	Name        string
+	nameMu      sync.RWMutex
 	delCallback func(key string) bool
 	numShards   int64
 	cancel      context.CancelFunc
+	// stopped is set by Stop - a running expiry stops calling delCallback, the HashMap is closing
+	stopped atomic.Bool
+	// running is the watchdog goroutine - Stop waits for it, so no delete reaches a closed AOF
+	running sync.WaitGroup
+	// entries and buckets are counted by the changes of the shards, so the metrics need no walk
+	entries atomic.Int64
+	buckets atomic.Int64
 }
 
+// MaxExpiring bounds the keys returned by ExpiringWithin
+const MaxExpiring = 1000
+
 type TTLEntryManager struct {
 	list map[int64]map[string]*Entry
 	mut  sync.Mutex
@@ -31,8 +77,16 @@ func NewTTLManager(name string, delFunc func(key string) bool) *TTLManager {
 	// Create the TTLManager
 	ttl := &TTLManager{lastDeleted: atomic.Int64{}, Name: name, delCallback: delFunc, List: make([]*TTLEntryManager, 0)}
 
-	// set numshards
-	ttl.numShards = int64(ttl.LowerPowerOfTwo(uint64(runtime.NumCPU() * (*envhandler.ENV.CPU_MULTIPLIER))))
+	// set numshards - HKV_TTL_SHARDS or derived like the basket locks
+	ttl.numShards = int64(*envhandler.ENV.TTL_SHARDS)
+	if ttl.numShards <= 0 {
+		ttl.numShards = int64(ttl.LowerPowerOfTwo(uint64(runtime.NumCPU() * (*envhandler.ENV.CPU_MULTIPLIER))))
+	}
+	// the shard of an entry is Hash & (numShards-1) - any other count routes entries to the wrong shards
+	// and their TTLs never fire
+	if ttl.numShards < 1 || ttl.numShards&(ttl.numShards-1) != 0 {
+		panic(fmt.Sprintf("TTLManager for DB %s: %d shards is no power of two", name, ttl.numShards))
+	}
 
 	// Create the TTLEntryManagers
 	for i := 0; i < int(ttl.numShards); i++ {
@@ -51,8 +105,30 @@ func (ttlm *TTLManager) Stop() {
 	if ttlm.cancel == nil {
 		return
 	}
+	ttlm.stopped.Store(true)
 	ttlm.cancel()
-	log.Println("TTLManager for DB " + ttlm.Name + " stopped..")
+	ttlm.running.Wait()
+
+	// the DB is gone - so are its metrics
+	name := ttlm.name()
+	kvTTLDeleteMisses.DeleteLabelValues(name)
+	kvTTLEntries.DeleteLabelValues(name)
+	kvTTLBuckets.DeleteLabelValues(name)
+	log.Println("TTLManager for DB " + name + " stopped..")
+}
+
+// name returns the name of the DB
+func (ttlm *TTLManager) name() string {
+	ttlm.nameMu.RLock()
+	defer ttlm.nameMu.RUnlock()
+	return ttlm.Name
+}
+
+// rename changes the name of the DB the metrics are reported for
+func (ttlm *TTLManager) rename(name string) {
+	ttlm.nameMu.Lock()
+	ttlm.Name = name
+	ttlm.nameMu.Unlock()
 }
 
 // newTTLEntryManager creates a new TTLEntryManager
@@ -81,30 +157,112 @@ func (ttlm *TTLManager) addEntry(entry *Entry) {
 	if future <= ttlm.lastDeleted.Load() {
 		return
 	}
+	entry.ExpireAt = future
 
 	// if map already exist - add - else create new map and add
 	if values, ok := em.list[future]; ok {
+		if _, ok := values[entry.Key]; !ok {
+			ttlm.entries.Add(1)
+		}
 		values[entry.Key] = entry
 	} else {
 		em.list[future] = map[string]*Entry{entry.Key: entry}
+		ttlm.entries.Add(1)
+		ttlm.buckets.Add(1)
 	}
 }
 
 // delEntry deletes an entry from the TTLEntryManager
-func (ttlm *TTLManager) delEntry(entry *Entry, ttl int64) {
+func (ttlm *TTLManager) delEntry(entry *Entry) {
+	// return if unnecessary
+	if entry.ExpireAt == 0 {
+		return
+	}
+
 	// get the TTLEntryManager
 	em := ttlm.List[entry.Hash&uint64(ttlm.numShards-1)]
 	em.mut.Lock()
 	defer em.mut.Unlock()
 
-	// Delete bucket if empty
-	if bucket, ok := em.list[ttl]; ok {
-		delete(bucket, entry.Key)
+	// the bucket is keyed by the absolute expiry - delete bucket if empty
+	if bucket, ok := em.list[entry.ExpireAt]; ok {
+		if _, ok := bucket[entry.Key]; ok {
+			delete(bucket, entry.Key)
+			ttlm.entries.Add(-1)
+		}
 		if len(bucket) == 0 {
-			delete(em.list, ttl)
+			delete(em.list, entry.ExpireAt)
+			ttlm.buckets.Add(-1)
 		}
 	}
+	entry.ExpireAt = 0
+}
 
+// clear drops all entries of the TTLEntryManagers, e.g. after the HashMap got flushed
+func (ttlm *TTLManager) clear() {
+	for _, em := range ttlm.List {
+		em.mut.Lock()
+		for _, bucket := range em.list {
+			ttlm.entries.Add(-int64(len(bucket)))
+			ttlm.buckets.Add(-1)
+		}
+		em.list = make(map[int64]map[string]*Entry)
+		em.mut.Unlock()
+	}
+}
+
+// Entries returns the number of entries tracked and the number of their expiry buckets over all shards
+func (ttlm *TTLManager) Entries() (entries, buckets int64) {
+	return ttlm.entries.Load(), ttlm.buckets.Load()
+}
+
+// updateMetrics sets the gauges of the DB to the counted entries and buckets
+func (ttlm *TTLManager) updateMetrics() {
+	entries, buckets := ttlm.Entries()
+	name := ttlm.name()
+	kvTTLEntries.WithLabelValues(name).Set(float64(entries))
+	kvTTLBuckets.WithLabelValues(name).Set(float64(buckets))
+}
+
+// ExpiringWithin returns the keys expiring in the next seconds (between now+1 and now+seconds), the soonest
+// first and at most MaxExpiring. It walks the buckets of the shards, so a large range costs no more than a small one.
+func (ttlm *TTLManager) ExpiringWithin(seconds int64) []string {
+	keys := make([]string, 0)
+	if seconds <= 0 {
+		return keys
+	}
+	now := time.Now().Unix()
+	from, to := now+1, now+seconds
+
+	// collect the expiry seconds in range of all shards
+	times := make([]int64, 0)
+	for _, em := range ttlm.List {
+		em.mut.Lock()
+		for at := range em.list {
+			if at >= from && at <= to {
+				times = append(times, at)
+			}
+		}
+		em.mut.Unlock()
+	}
+	slices.Sort(times)
+	times = slices.Compact(times)
+
+	// the buckets may have changed in between - a key deleted meanwhile is simply skipped
+	for _, at := range times {
+		for _, em := range ttlm.List {
+			em.mut.Lock()
+			for key := range em.list[at] {
+				keys = append(keys, key)
+				if len(keys) == MaxExpiring {
+					em.mut.Unlock()
+					return keys
+				}
+			}
+			em.mut.Unlock()
+		}
+	}
+	return keys
 }
 
 // deleteEntries deletes expired entries (if there are some)
@@ -117,6 +275,8 @@ func (ttlm *TTLManager) delEntries(now int64) {
 			entries, ok := ttlEntry.list[i]
 			if ok {
 				delete(ttlEntry.list, i)
+				ttlm.entries.Add(-int64(len(entries)))
+				ttlm.buckets.Add(-1)
 			}
 			ttlEntry.mut.Unlock()
 			if ok {
@@ -127,10 +287,16 @@ func (ttlm *TTLManager) delEntries(now int64) {
 	ttlm.lastDeleted.Store(now)
 }
 
-// delEntriesFromHashMap deletes the entries from the HashMap
+// delEntriesFromHashMap deletes the entries from the HashMap. A missing key is counted as a miss, after Stop the
+// remaining entries are skipped - they are still in the AOF and expire again after the next start.
 func (ttlm *TTLManager) delEntriesFromHashMap(entries map[string]*Entry) {
 	for _, entry := range entries {
-		ttlm.delCallback(entry.Key) // fire and forget
+		if ttlm.stopped.Load() {
+			return
+		}
+		if !ttlm.delCallback(entry.Key) {
+			kvTTLDeleteMisses.WithLabelValues(ttlm.name()).Inc()
+		}
 	}
 }
 
@@ -141,7 +307,7 @@ func (ttlm *TTLManager) Start() {
 	ttlm.cancel = cancel
 
 	// start the go routine
-	go func() {
+	ttlm.running.Go(func() {
 		for {
 			// What we need is a Secondexact deletion of expired entries
 			now := time.Now()
@@ -152,9 +318,10 @@ func (ttlm *TTLManager) Start() {
 				return
 			case <-time.After(time.Until(next)):
 				ttlm.delEntries(next.Unix())
+				ttlm.updateMetrics()
 			}
 		}
-	}()
+	})
 }
 
 // LowerPowerOfTwo returns the lower power of two greater than or equal to shards
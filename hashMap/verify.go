@@ -0,0 +1,50 @@
+package hashMap
+
+import (
+	"fmt"
+	"os"
+)
+
+// AOFReport is the result of VerifyAOF for a DB
+type AOFReport struct {
+	DB      string `json:"db"`
+	Version uint16 `json:"version"`
+	Bytes   int64  `json:"bytes"`
+	Frames  int64  `json:"frames"`
+	Entries int64  `json:"entries"`
+	// Truncated is true if the file ends within a frame - the write of a crash, the replay drops it
+	Truncated bool `json:"truncated"`
+	// OK is false if the AOF can not be replayed or holds unknown frames - the DB is corrupt, Error tells why.
+	// A truncated last frame is no corruption.
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// VerifyAOF replays the AOF of the DB name into a HashMap which is thrown away afterwards, without starting its AOF
+// loop or TTLManager - the file is only read. The DB must not be opened by a running server.
+func VerifyAOF(name string) AOFReport {
+	hm, err := OpenHashMap(name)
+	if err != nil {
+		return AOFReport{DB: name, Error: err.Error()}
+	}
+	defer hm.Close()
+
+	report := AOFReport{DB: hm.Name}
+	if stat, err := os.Stat(hm.Aof.FileName); err == nil {
+		report.Bytes = stat.Size()
+	}
+
+	stats, err := hm.replayAOF()
+	report.Version = hm.Aof.version
+	report.Frames = stats.frames
+	report.Truncated = stats.truncated
+	report.Entries = hm.GetEntries()
+	switch {
+	case err != nil:
+		report.Error = err.Error()
+	case stats.unknown > 0:
+		report.Error = fmt.Sprintf("%d frames of unknown actions, the first is frame %d", stats.unknown, stats.firstUnknown)
+	}
+	report.OK = report.Error == ""
+	return report
+}
@@ -0,0 +1,171 @@
+package hashMap
+
+import (
+	"hydrakv/envhandler"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// zsetScoreSize is the number of bytes a score adds to the size of a sorted set
+const zsetScoreSize = 8
+
+// ZMember is a member of a sorted set and its score
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// less orders the members of a sorted set by score - members with the same score by their name
+func (z ZMember) less(o ZMember) bool {
+	if z.Score != o.Score {
+		return z.Score < o.Score
+	}
+	return z.Member < o.Member
+}
+
+// rank returns the position of the member in the ordered members
+func (e *Entry) rank(z ZMember) int {
+	return sort.Search(len(e.Ranked), func(i int) bool { return !e.Ranked[i].less(z) })
+}
+
+// ZAdd adds a member with its score to the sorted set stored at key or updates its score - a missing key is created
+// as sorted set. Returns false if the score is NaN, the key holds another type or the sorted set would exceed
+// HKV_ENTRY_SIZE bytes.
+func (hm *HashMap) ZAdd(key, member string, score float64) bool {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("zadd"))
+	defer timer.ObserveDuration()
+
+	if !validKey(key, "zadd") {
+		return false
+	}
+
+	// NaN has no order
+	if math.IsNaN(score) {
+		kvOperations.WithLabelValues("zadd", "invalid").Inc()
+		return false
+	}
+
+	// the frame is enqueued under the locks, its fsync is waited for once they are released
+	var frame pendingFrame
+	defer frame.wait()
+
+	ok := false
+	hm.withEntry(key, true, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item != nil && item.Type != TypeZSet {
+			kvOperations.WithLabelValues("zadd", "wrong_type").Inc()
+			return
+		}
+
+		old, exists := 0.0, false
+		if item != nil {
+			old, exists = item.Scores[member]
+		}
+		if exists && old == score {
+			ok = true
+			kvOperations.WithLabelValues("zadd", "unchanged").Inc()
+			return
+		}
+
+		// the members and their scores are limited by HKV_ENTRY_SIZE
+		size := 0
+		if item != nil {
+			size = item.size
+		}
+		if !exists {
+			size += len(member) + zsetScoreSize
+		}
+		if size > *envhandler.ENV.ENTRY_SIZE && !hm.reset {
+			kvOperations.WithLabelValues("zadd", "too_large").Inc()
+			return
+		}
+
+		// only changes are written to the AOF
+		if !hm.reset {
+			frame.add(hm.Aof, Data{Action: "zadd", Key: key, Value: packValue(strconv.FormatFloat(score, 'g', -1, 64), member)})
+		}
+
+		if item == nil {
+			item = &Entry{Key: key, Hash: hash, Next: basket.Items, Type: TypeZSet, Scores: make(map[string]float64)}
+			hm.addEntry(basket, item)
+		}
+
+		// move the member to the position of its new score
+		if exists {
+			i := item.rank(ZMember{Member: member, Score: old})
+			item.Ranked = append(item.Ranked[:i], item.Ranked[i+1:]...)
+		}
+		z := ZMember{Member: member, Score: score}
+		i := item.rank(z)
+		item.Ranked = append(item.Ranked, ZMember{})
+		copy(item.Ranked[i+1:], item.Ranked[i:])
+		item.Ranked[i] = z
+
+		item.Scores[member] = score
+		item.size = size
+		item.touch()
+		ok = true
+		kvOperations.WithLabelValues("zadd", "ok").Inc()
+	})
+	return ok
+}
+
+// ZScore returns the score of a member of the sorted set stored at key
+func (hm *HashMap) ZScore(key, member string) (float64, bool) {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("zscore"))
+	defer timer.ObserveDuration()
+
+	var score float64
+	var found bool
+	hm.withEntry(key, false, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item != nil && item.Type == TypeZSet {
+			score, found = item.Scores[member]
+		}
+	})
+	kvOperations.WithLabelValues("zscore", "ok").Inc()
+	return score, found
+}
+
+// ZRank returns the 0-based position of a member in the sorted set stored at key, ordered by ascending score
+func (hm *HashMap) ZRank(key, member string) (int, bool) {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("zrank"))
+	defer timer.ObserveDuration()
+
+	rank, found := 0, false
+	hm.withEntry(key, false, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item == nil || item.Type != TypeZSet {
+			return
+		}
+		var score float64
+		if score, found = item.Scores[member]; found {
+			rank = item.rank(ZMember{Member: member, Score: score})
+		}
+	})
+	kvOperations.WithLabelValues("zrank", "ok").Inc()
+	return rank, found
+}
+
+// ZRange returns the members of the sorted set stored at key from position start to stop (both inclusive), ordered
+// by ascending score. Negative positions count from the end, e.g. -1 is the last member. Returns nil if the key
+// holds no sorted set and an empty slice if the range is empty.
+func (hm *HashMap) ZRange(key string, start, stop int) []ZMember {
+	timer := prometheus.NewTimer(kvOperationDuration.WithLabelValues("zrange"))
+	defer timer.ObserveDuration()
+
+	var members []ZMember
+	hm.withEntry(key, false, func(basket *Basket, item, prev *Entry, hash uint64) {
+		if item == nil || item.Type != TypeZSet {
+			kvOperations.WithLabelValues("zrange", "not_found").Inc()
+			return
+		}
+
+		members = []ZMember{}
+		if start, stop, ok := rangeBounds(len(item.Ranked), start, stop); ok {
+			members = append(members, item.Ranked[start:stop+1]...)
+		}
+		kvOperations.WithLabelValues("zrange", "found").Inc()
+	})
+	return members
+}
@@ -1,35 +1,31 @@
 package logo
 
 import (
+	_ "embed"
 	"fmt"
-	"os"
+	"strings"
 )
 
+// logoTxt is the ASCII Art embedded into the binary
+//
+//go:embed logo.txt
+var logoTxt string
+
 type Logo struct {
 	logo string
 }
 
 // Create the Logo
 func NewLogo() *Logo {
-	l := Logo{}
-	l.readLogo()
-	return &l
-}
-
-// read logo from file
-func (l *Logo) readLogo() {
-	b, err := os.ReadFile("logo.txt")
-
-	// if we dont find any logo - die...
-	if err != nil {
-		panic(err)
-	}
-	l.logo = string(b)
+	return &Logo{logo: logoTxt}
 }
 
-// Shows a hydrakv logo at startup in ASCII Art
+// Shows a hydrakv logo at startup in ASCII Art - without a logo only the startup line is shown
 func (l *Logo) ShowLogo() {
 	fmt.Println("HydraKV starting Up...")
+	if strings.TrimSpace(l.logo) == "" {
+		return
+	}
 	fmt.Println(l.logo)
 	fmt.Println("")
 }
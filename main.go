@@ -2,65 +2,148 @@ package main
 
 import (
 	"context"
+	"flag"
 	"hydrakv/envhandler"
 	"hydrakv/logo"
 	server2 "hydrakv/server"
+	"hydrakv/utils"
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 )
 
 func main() {
 
-	// Load the Logo
-	l := logo.NewLogo()
-	l.ShowLogo()
+	// Create ENV Handler - flags given on the command line override the environment
+	flag.Parse()
+	envhandler.ENV.LoadENVs()
+
+	// the env file overrides the hot-reloadable settings of the environment
+	if *envhandler.ENV.ENV_FILE != "" {
+		values, err := envhandler.ReadEnvFile(*envhandler.ENV.ENV_FILE)
+		if err != nil {
+			log.Fatal(err)
+		}
+		envhandler.ENV.ReloadENVs(values)
+	}
+
+	// the DB name policy has to be valid before any DB is touched
+	if err := utils.U.SetDbNamePolicy(*envhandler.ENV.DBNAME_REGEX, *envhandler.ENV.DBNAME_MAXLEN); err != nil {
+		log.Fatal(err)
+	}
+
+	// a typo in the IP lists must not open the server
+	if err := server2.CheckIPFilter(); err != nil {
+		log.Fatal(err)
+	}
+
+	// the selftest only verifies the DBs - nothing is served
+	if *selftest {
+		os.Exit(runSelftest(os.Stdout))
+	}
+
+	// Show the Logo - log-scraping deployments can suppress it
+	if *envhandler.ENV.SHOW_LOGO {
+		logo.NewLogo().ShowLogo()
+	}
 
 	// Create stop channel
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
-	// Create ENV Handler
-	envhandler.ENV.LoadENVs()
-
 	// Create Server
 	server := server2.NewServer(*envhandler.ENV.PORT, *envhandler.ENV.BIND_ADDRESS)
 
 	// if *envhandler.ENV.GRPC_ENABLED - we will start a GRPC Server as well
 	grpcServer := server2.NewGRPCServer(server)
+	// a reload - by SIGHUP or /admin/ratelimit - resizes the gRPC limiter as well
+	server.OnReload(grpcServer.Reload)
 
 	// Only start GRPC Server if *envhandler.ENV.GRPC_ENABLED
 	if *envhandler.ENV.GRPC_ENABLED {
 		go grpcServer.Start(*envhandler.ENV.GRPC_BIND_ADDRESS, *envhandler.ENV.GRPC_PORT)
 	}
 
+	// the RESP listener is optional - HKV_RESP_PORT 0 disables it
+	respServer := server2.NewRESPServer(server)
+	if *envhandler.ENV.RESP_PORT > 0 {
+		go respServer.Start(*envhandler.ENV.BIND_ADDRESS, *envhandler.ENV.RESP_PORT)
+	}
+
 	// Start the Server in its own goroutine
 	go server.Start()
 
+	// SIGHUP reloads the hot-reloadable settings from the env file
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloadConfig(server)
+		}
+	}()
+
 	// Wait for Signal to terminate
 	<-stop
 	log.Println("Received Signal - shutting down...")
 
-	// Stop grpc if *envhandler.ENV.GRPC_ENABLED
+	// one deadline for the whole shutdown - orchestrators kill us after their grace period anyway
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(*envhandler.ENV.SHUTDOWN_TIMEOUT)*time.Second)
+	defer cancel()
+
+	// 1. stop accepting requests and drain the in-flight ones - HTTP, gRPC and RESP together
+	phase := time.Now()
+	var wg sync.WaitGroup
 	if *envhandler.ENV.GRPC_ENABLED {
-		grpcServer.Stop()
+		wg.Go(func() {
+			if err := grpcServer.Shutdown(ctx); err != nil {
+				log.Println("GRPCServer Shutdown:", err)
+			}
+		})
 	}
+	if *envhandler.ENV.RESP_PORT > 0 {
+		wg.Go(func() {
+			if err := respServer.Shutdown(ctx); err != nil {
+				log.Println("RESPServer Shutdown:", err)
+			}
+		})
+	}
+	wg.Go(func() {
+		if err := server.Server.Shutdown(ctx); err != nil {
+			log.Println("Server Shutdown:", err)
+			_ = server.Server.Close()
+		}
+	})
+	wg.Wait()
+	log.Printf("Shutdown: requests drained in %s\n", time.Since(phase))
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(*envhandler.ENV.WRITE_TIMEOUT+5)*time.Second)
-	defer cancel()
-
-	// End the Serving
-	if err := server.Server.Shutdown(ctx); err != nil {
-		log.Println("Server Shutdown:", err)
+	// 2. close all DBs - nothing can write anymore
+	phase = time.Now()
+	if err := server.CloseDbsContext(ctx); err != nil {
+		log.Printf("Shutdown: closing the DBs did not finish: %v\n", err)
+	} else {
+		log.Printf("Shutdown: DBs closed in %s\n", time.Since(phase))
 	}
 
-	// Close all DBs gracefully
-	server.CloseDbs()
+	log.Printf("Server stopped in %s\n", time.Since(start))
+}
+
+// reloadConfig applies the hot-reloadable settings of HKV_ENV_FILE to the running servers
+func reloadConfig(server *server2.Server) {
+	if *envhandler.ENV.ENV_FILE == "" {
+		log.Printf("Reload: %s is not set - nothing to reload\n", envhandler.ENV_FILE)
+		return
+	}
+	values, err := envhandler.ReadEnvFile(*envhandler.ENV.ENV_FILE)
+	if err != nil {
+		log.Println("Reload:", err)
+		return
+	}
 
-	log.Println(
-		"Server stopped",
-	)
+	changed := envhandler.ENV.ReloadENVs(values)
+	server.Reload()
+	log.Printf("Reload: applied %d changed settings %v\n", len(changed), changed)
 }
@@ -2,6 +2,8 @@ package restartcheck
 
 import (
 	"hydrakv/envhandler"
+	"hydrakv/hashMap"
+	"hydrakv/utils"
 	"log"
 	"os"
 	"strings"
@@ -19,12 +21,20 @@ func init() {
 
 // Check Checks if in the AOF dir already *.bin data files exist
 func (r *RestartCheck) Check() ([]string, error) {
+	// the dirs are created if missing - a file in their place is a misconfiguration to report right away
+	if err := utils.U.EnsureDir(*envhandler.ENV.DB_FOLDER, envhandler.DB_FOLDER); err != nil {
+		return nil, err
+	}
+	if err := utils.U.EnsureDir(envhandler.ENV.SnapshotFolder(), envhandler.SNAPSHOT_FOLDER); err != nil {
+		return nil, err
+	}
+	if err := hashMap.RemoveStaleSnapshots(); err != nil {
+		return nil, err
+	}
+
 	log.Println("Checking for existing bin files in aof dir...")
 	d, err := os.ReadDir(*envhandler.ENV.DB_FOLDER)
 	if err != nil {
-		// Create the dir if it does not exist
-		err := os.Mkdir(*envhandler.ENV.DB_FOLDER, 0755)
-		log.Println("Creating aof dir... No DB directories and no DB files found.")
 		return nil, err
 	}
 	var files []string
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"hydrakv/hashMap"
+	"hydrakv/restartcheck"
+	"io"
+	"log"
+)
+
+// selftest verifies the AOFs of all DBs instead of serving - a pre-flight check before a rollout
+var selftest = flag.Bool("selftest", false, "Verify that the AOFs of all DBs replay cleanly, print a JSON summary and exit - 1 if a DB is corrupt")
+
+// selftestSummary is printed by the selftest
+type selftestSummary struct {
+	OK  bool                `json:"ok"`
+	DBs []hashMap.AOFReport `json:"dbs"`
+}
+
+// runSelftest replays the AOF of every DB in HKV_DB_FOLDER and writes the summary to w. Returns the exit code.
+func runSelftest(w io.Writer) int {
+	dbs, err := restartcheck.RCheck.Check()
+	if err != nil {
+		log.Println("Selftest:", err)
+		return 1
+	}
+
+	summary := selftestSummary{OK: true, DBs: make([]hashMap.AOFReport, 0, len(dbs))}
+	for _, db := range dbs {
+		report := hashMap.VerifyAOF(db)
+		if !report.OK {
+			log.Printf("Selftest: DB %s is corrupt: %s\n", report.DB, report.Error)
+			summary.OK = false
+		}
+		summary.DBs = append(summary.DBs, report)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(summary); err != nil {
+		log.Println("Selftest:", err)
+		return 1
+	}
+	if !summary.OK {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hydrakv/envhandler"
+	"hydrakv/utils"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// outcomes of an auth decision
+const (
+	authGranted  = "granted"
+	authDenied   = "denied"
+	authReadOnly = "read_only"
+	authLocked   = "locked"
+)
+
+// unknownDB is the metric label of DBs which don't exist, so guessed names don't create new series
+const unknownDB = "_unknown"
+
+// Counter for failed auth decisions
+var kvAuthFailures = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kv_auth_failures_total",
+		Help: "Total number of rejected API keys",
+	},
+	[]string{"db"},
+)
+
+// auditEntry is one line of the audit log
+type auditEntry struct {
+	Time    string `json:"time"`
+	Proto   string `json:"proto"`
+	IP      string `json:"ip"`
+	DB      string `json:"db"`
+	Key     string `json:"key"`
+	Outcome string `json:"outcome"`
+}
+
+// auditLog writes auth decisions as JSON lines to HKV_AUDIT_LOG
+type auditLog struct {
+	mu   sync.Mutex
+	dest string
+	out  io.Writer
+}
+
+// audit is the global audit log - it is opened on first use
+var audit = &auditLog{}
+
+// open (re)opens the destination of the audit log if HKV_AUDIT_LOG changed - a nil writer disables it.
+// Must be called with the lock held.
+func (a *auditLog) open() {
+	dest := *envhandler.ENV.AUDIT_LOG
+	if dest == a.dest {
+		return
+	}
+	if c, ok := a.out.(io.Closer); ok && a.out != os.Stdout && a.out != os.Stderr {
+		_ = c.Close()
+	}
+	a.dest, a.out = dest, nil
+
+	switch dest {
+	case "":
+	case "stdout":
+		a.out = os.Stdout
+	case "stderr":
+		a.out = os.Stderr
+	default:
+		f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			log.Printf("Error opening audit log %s: %v", dest, err)
+			return
+		}
+		a.out = f
+	}
+}
+
+// record writes an auth decision - the key itself is never written, only a prefix of its SHA-256
+func (a *auditLog) record(proto, ip, db, key, outcome string) {
+	entry := auditEntry{
+		Time:    time.Now().UTC().Format(time.RFC3339Nano),
+		Proto:   proto,
+		IP:      ip,
+		DB:      db,
+		Key:     keyFingerprint(key),
+		Outcome: outcome,
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.open()
+	if a.out == nil {
+		return
+	}
+	_, _ = a.out.Write(append(line, '\n'))
+}
+
+// keyFingerprint returns the first 8 hex chars of the SHA-256 of the key
+func keyFingerprint(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// authorize checks the api key of a request, writes the decision to the audit log and counts failures.
+// IPs with too many failed attempts for the DB are rejected without checking the key.
+func authorize(kv kvLogic, proto, ip, db, key string, readOnly bool) string {
+	outcome := authGranted
+	switch {
+	case lockout.locked(ip, db):
+		outcome = authLocked
+	case key == "" || !utils.U.HasAccess(db, key, readOnly):
+		outcome = authDenied
+		if !readOnly && utils.U.IsReadKeyValid(db, key) {
+			outcome = authReadOnly
+		} else {
+			lockout.fail(ip, db)
+		}
+	default:
+		lockout.reset(ip, db)
+	}
+
+	audit.record(proto, ip, db, key, outcome)
+	if outcome != authGranted {
+		label := utils.U.DbKey(db)
+		if !kv.DBExists(db) {
+			label = unknownDB
+		}
+		kvAuthFailures.WithLabelValues(label).Inc()
+	}
+	return outcome
+}
+
+// auth checks the api key of a gRPC call if api keys are enabled
+func (s *KVService) auth(ctx context.Context, db, key string, readOnly bool) error {
+	if !*envhandler.ENV.APIKEY_ENABLED {
+		return nil
+	}
+	switch authorize(s.kv, "grpc", grpcClientIP(ctx), db, key, readOnly) {
+	case authGranted:
+		return nil
+	case authReadOnly:
+		return status.Errorf(codes.PermissionDenied, "read-only apikey")
+	case authLocked:
+		return status.Errorf(codes.ResourceExhausted, "too many failed auth attempts")
+	default:
+		return status.Errorf(codes.Unauthenticated, "invalid apikey")
+	}
+}
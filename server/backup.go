@@ -0,0 +1,206 @@
+package server
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hydrakv/envhandler"
+	"hydrakv/hashMap"
+	"hydrakv/utils"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupVersion is the version of the archive layout written by Backup
+const backupVersion = 1
+
+// the members of a backup archive - the manifest comes first, followed by a snapshot and the api keys per DB
+const (
+	backupManifest  = "manifest.json"
+	backupDBDir     = "dbs/"
+	backupKeysDir   = "keys/"
+	backupDBExt     = ".bin"
+	backupKeysExt   = ".apikey"
+	restoreTmpExt   = ".restore"
+	backupFileMode  = 0600
+	maxApiKeysBytes = 1024
+)
+
+// Backup streams a tar archive of all loaded DBs to w. Every DB is copied under its own lock one after another,
+// so only one snapshot is held in memory at a time. With withKeys the SHA-256 hashes of the api keys are included.
+func (s *Server) Backup(w io.Writer, withKeys bool) error {
+	s.mut.RLock()
+	dbs := make([]*hashMap.HashMap, 0, len(s.dbs))
+	for _, hm := range s.dbs {
+		if hm.Ready() {
+			dbs = append(dbs, hm)
+		}
+	}
+	s.mut.RUnlock()
+	sort.Slice(dbs, func(i, j int) bool { return dbs[i].Name < dbs[j].Name })
+
+	manifest := BackupManifest{Version: backupVersion, CreatedAt: time.Now().UTC(), ApiKeys: withKeys,
+		DBs: make([]BackupDB, 0, len(dbs))}
+	for _, hm := range dbs {
+		manifest.DBs = append(manifest.DBs, BackupDB{Name: hm.Name, Entries: hm.GetEntries()})
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	if err := writeTarFile(tw, backupManifest, data); err != nil {
+		return err
+	}
+	for _, hm := range dbs {
+		if err := writeSnapshot(tw, hm, manifest.CreatedAt); err != nil {
+			return err
+		}
+
+		if !withKeys {
+			continue
+		}
+		if keys, ok := utils.U.ExportApiKeys(hm.Name); ok {
+			if err := writeTarFile(tw, backupKeysDir+hm.Name+backupKeysExt, keys); err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
+// writeSnapshot writes the snapshot of a DB to the archive - its spool file is removed afterward
+func writeSnapshot(tw *tar.Writer, hm *hashMap.HashMap, modTime time.Time) error {
+	snapshot, err := hm.Snapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+
+	header := &tar.Header{Name: backupDBDir + hm.Name + backupDBExt, Mode: backupFileMode, Size: snapshot.Size(),
+		ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = snapshot.WriteTo(tw)
+	return err
+}
+
+// writeTarFile writes a small file to the archive
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{Name: name, Mode: backupFileMode, Size: int64(len(data)), ModTime: time.Now().UTC()}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Restore creates the DBs of an archive written by Backup. DBs which already exist are skipped and keep their data
+// and api keys. The snapshots are replayed like AOFs, so the restored DBs are ready when Restore returns.
+func (s *Server) Restore(r io.Reader) (RestoreResult, error) {
+	result := RestoreResult{Restored: make([]string, 0), Skipped: make([]string, 0)}
+	tr := tar.NewReader(r)
+
+	// the manifest has to come first
+	header, err := tr.Next()
+	if err != nil {
+		return result, fmt.Errorf("reading the manifest: %w", err)
+	}
+	if header.Name != backupManifest {
+		return result, fmt.Errorf("expected %s, got %s", backupManifest, header.Name)
+	}
+	var manifest BackupManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return result, fmt.Errorf("decoding the manifest: %w", err)
+	}
+	if manifest.Version != backupVersion {
+		return result, fmt.Errorf("unsupported backup version %d", manifest.Version)
+	}
+
+	restored := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+
+		dir, file := path.Split(header.Name)
+		switch {
+		case dir == backupDBDir && strings.HasSuffix(file, backupDBExt):
+			name := strings.TrimSuffix(file, backupDBExt)
+			if !utils.U.CheckDbName(name) {
+				return result, fmt.Errorf("invalid db name %q", name)
+			}
+			if s.DBExists(name) {
+				result.Skipped = append(result.Skipped, name)
+				continue
+			}
+			if err := s.restoreDB(name, tr); err != nil {
+				return result, fmt.Errorf("restoring %s: %w", name, err)
+			}
+			restored[utils.U.DbKey(name)] = true
+			result.Restored = append(result.Restored, name)
+		case dir == backupKeysDir && strings.HasSuffix(file, backupKeysExt):
+			name := strings.TrimSuffix(file, backupKeysExt)
+			// keys of skipped DBs are not touched
+			if !restored[utils.U.DbKey(name)] {
+				continue
+			}
+			data, err := io.ReadAll(io.LimitReader(tr, maxApiKeysBytes))
+			if err != nil {
+				return result, err
+			}
+			if err := utils.U.ImportApiKeys(name, data); err != nil {
+				return result, err
+			}
+		}
+	}
+}
+
+// restoreDB writes a snapshot as AOF of a new DB and loads it
+func (s *Server) restoreDB(name string, snapshot io.Reader) error {
+	hm, err := hashMap.OpenHashMap(name)
+	if err != nil {
+		return err
+	}
+
+	// the AOF is written next to the DBs and renamed once complete, so a crash leaves no partial DB behind
+	tmpName := hm.Aof.FileName + restoreTmpExt
+	f, err := os.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, envhandler.ENV.FileMode())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, snapshot)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, hm.Aof.FileName)
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+
+	s.mut.Lock()
+	s.dbs[utils.U.DbKey(name)] = hm
+	s.mut.Unlock()
+
+	if err := hm.Load(); err != nil {
+		s.mut.Lock()
+		delete(s.dbs, utils.U.DbKey(name))
+		s.mut.Unlock()
+		return err
+	}
+	return nil
+}
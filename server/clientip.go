@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"hydrakv/envhandler"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc/peer"
+)
+
+// httpClientIP returns the IP of the client of a HTTP request, used by the IP filter, the audit log and the lockout.
+// Without HKV_TRUST_PROXY this is always the address of the connection. Behind a trusted proxy it is the last
+// hop of X-Forwarded-For - the address the proxy appended, earlier hops can be sent by the client - or X-Real-IP.
+// Header values which are no IPs are ignored.
+func httpClientIP(r *http.Request) string {
+	if *envhandler.ENV.TRUST_PROXY {
+		if fwd := r.Header.Values("X-Forwarded-For"); len(fwd) > 0 {
+			hops := strings.Split(fwd[len(fwd)-1], ",")
+			if ip, ok := parseClientIP(hops[len(hops)-1]); ok {
+				return ip
+			}
+		} else if ip, ok := parseClientIP(r.Header.Get("X-Real-IP")); ok {
+			return ip
+		}
+	}
+	return hostIP(r.RemoteAddr)
+}
+
+// grpcClientIP returns the IP of the peer of a gRPC call
+func grpcClientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	return hostIP(p.Addr.String())
+}
+
+// parseClientIP parses an IP from a proxy header - a port is removed
+func parseClientIP(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", false
+	}
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap().String(), true
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return "", false
+	}
+	return addr.Unmap().String(), true
+}
+
+// hostIP removes the port of an address
+func hostIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"hydrakv/envhandler"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// connLimiter caps the open HTTP connections per client IP - HKV_MAX_CONNS_PER_IP
+type connLimiter struct {
+	limit int
+	mu    sync.Mutex
+	perIP map[string]int
+	// conns maps the counted connections to the IP they are counted for
+	conns map[net.Conn]string
+}
+
+// connCtxKey is the context key of the connection of a request
+type connCtxKey struct{}
+
+// newConnLimiter creates a connection limiter - a limit <= 0 disables it
+func newConnLimiter() *connLimiter {
+	return &connLimiter{
+		limit: *envhandler.ENV.MAX_CONNS_PER_IP,
+		perIP: make(map[string]int),
+		conns: make(map[net.Conn]string),
+	}
+}
+
+// enabled returns true if a limit is set
+func (l *connLimiter) enabled() bool {
+	return l.limit > 0
+}
+
+// add counts conn for ip - false if ip already has limit connections. A counted connection is accepted again.
+func (l *connLimiter) add(conn net.Conn, ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.conns[conn]; ok {
+		return true
+	}
+	if l.perIP[ip] >= l.limit {
+		return false
+	}
+	l.perIP[ip]++
+	l.conns[conn] = ip
+	return true
+}
+
+// remove releases the slot of conn if it was counted
+func (l *connLimiter) remove(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ip, ok := l.conns[conn]
+	if !ok {
+		return
+	}
+	delete(l.conns, conn)
+	if l.perIP[ip] <= 1 {
+		delete(l.perIP, ip)
+		return
+	}
+	l.perIP[ip]--
+}
+
+// connState is the ConnState hook of the http.Server. Without HKV_TRUST_PROXY a connection is counted for its
+// remote address when it is opened and closed right away above the limit.
+func (l *connLimiter) connState(conn net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		// behind a proxy the client is only known from the first request - see wrap. Unix socket clients have no IP.
+		if *envhandler.ENV.TRUST_PROXY || isUnixAddr(conn.LocalAddr()) {
+			return
+		}
+		if ip := hostIP(conn.RemoteAddr().String()); !l.add(conn, ip) {
+			log.Printf("connection limit reached for %s - closing connection", ip)
+			_ = conn.Close()
+		}
+	case http.StateClosed, http.StateHijacked:
+		l.remove(conn)
+	}
+}
+
+// connContext keeps the connection in the context of its requests
+func (l *connLimiter) connContext(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connCtxKey{}, conn)
+}
+
+// wrap counts connections behind a proxy for the client IP of their first request. Above the limit the request is
+// answered with 429 and the connection is closed afterward.
+func (l *connLimiter) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if *envhandler.ENV.TRUST_PROXY && !isUnixRequest(r) {
+			conn, ok := r.Context().Value(connCtxKey{}).(net.Conn)
+			if ok && !l.add(conn, httpClientIP(r)) {
+				log.Printf("connection limit reached for %s - closing connection", httpClientIP(r))
+				w.Header().Set("Connection", "close")
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "too_many_connections"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"hydrakv/envhandler"
+	"net/http"
+	"strings"
+)
+
+type corsHandler struct {
+	origins map[string]bool
+	any     bool
+}
+
+// creates a new CORS handler from HKV_CORS_ORIGINS - an empty list disables CORS
+func newCorsHandler() *corsHandler {
+	c := &corsHandler{origins: make(map[string]bool)}
+	for _, origin := range strings.Split(*envhandler.ENV.CORS_ORIGINS, ",") {
+		origin = strings.TrimSpace(origin)
+		switch origin {
+		case "":
+		case "*":
+			c.any = true
+		default:
+			c.origins[origin] = true
+		}
+	}
+	return c
+}
+
+// enabled returns true if any origin is allowed
+func (c *corsHandler) enabled() bool {
+	return c.any || len(c.origins) > 0
+}
+
+// wrap sets the CORS headers for allowed origins and answers preflight requests
+func (c *corsHandler) wrap(next http.Handler) http.Handler {
+	if !c.enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed := c.any || c.origins[origin]
+		if allowed {
+			if c.any {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			w.Header().Set("Access-Control-Expose-Headers", "Idempotent-Replayed")
+		}
+
+		// preflight requests never reach the routes - they carry no API key
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if !allowed {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, UPDATE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key, Idempotency-Key")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
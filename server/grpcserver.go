@@ -2,11 +2,13 @@ package server
 
 import (
 	"context"
+	"errors"
+	"hydrakv/fifolifo"
+	"hydrakv/hashMap"
 	"hydrakv/utils"
 	"log"
 	"net"
 	"strconv"
-	"strings"
 	"time"
 
 	"hydrakv/envhandler"
@@ -14,6 +16,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -22,9 +25,7 @@ import (
 // =========================
 
 // Global request limit (concurrency)
-func grpcRequestLimitInterceptor(limit int) grpc.UnaryServerInterceptor {
-	sem := make(chan struct{}, limit)
-
+func grpcRequestLimitInterceptor(limiter *requestLimiter) grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
 		req any,
@@ -32,16 +33,19 @@ func grpcRequestLimitInterceptor(limit int) grpc.UnaryServerInterceptor {
 		handler grpc.UnaryHandler,
 	) (any, error) {
 
-		select {
-		case sem <- struct{}{}:
+		if sem, ok := limiter.acquire(ctx); ok {
 			defer func() { <-sem }()
-			return handler(ctx, req)
-		default:
-			return nil, status.Error(
-				codes.ResourceExhausted,
-				"grpc request limit reached",
-			)
+			start := time.Now()
+			resp, err := handler(ctx, req)
+			limiter.observe(time.Since(start))
+			return resp, err
 		}
+		// clients back off by the retry-after trailer in seconds
+		_ = grpc.SetTrailer(ctx, metadata.Pairs("retry-after", strconv.Itoa(limiter.retryAfter())))
+		return nil, status.Error(
+			codes.ResourceExhausted,
+			"grpc request limit reached",
+		)
 	}
 }
 
@@ -74,6 +78,75 @@ func grpcDeadlineInterceptor() grpc.UnaryServerInterceptor {
 	}
 }
 
+// Reject requests for DBs which are still replaying their AOF
+func grpcDBLoadingInterceptor(kv kvLogic) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+
+		if r, ok := req.(interface{ GetDb() string }); ok && kv.DBLoading(r.GetDb()) {
+			return nil, status.Error(
+				codes.Unavailable,
+				"db_loading",
+			)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// grpcWriteMethods are the RPCs rejected while a DB's AOF exceeds HKV_MAX_AOF_BYTES
+var grpcWriteMethods = map[string]bool{
+	"/kv.KVService/Set":          true,
+	"/kv.KVService/SetNX":        true,
+	"/kv.KVService/SetIfGreater": true,
+	"/kv.KVService/SetIfLess":    true,
+	"/kv.KVService/Incr":         true,
+	"/kv.KVService/CounterIncr":  true,
+	"/kv.KVService/CounterDecr":  true,
+	"/kv.KVService/IncrAndCheck": true,
+	"/kv.KVService/SetBit":       true,
+	"/kv.KVService/HSet":         true,
+	"/kv.KVService/SAdd":         true,
+	"/kv.KVService/ZAdd":         true,
+	"/kv.KVService/LPush":        true,
+	"/kv.KVService/RPush":        true,
+}
+
+// Reject writes for DBs whose AOF exceeds HKV_MAX_AOF_BYTES
+func grpcStorageInterceptor(kv kvLogic) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+
+		if !grpcWriteMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		r, ok := req.(interface{ GetDb() string })
+		if ok && kv.DBThrottled(r.GetDb()) {
+			return nil, status.Error(
+				codes.Unavailable,
+				"aof_backlog",
+			)
+		}
+		if ok && !kv.DBWritable(r.GetDb()) {
+			return nil, status.Error(
+				codes.ResourceExhausted,
+				"storage_full",
+			)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
 // =========================
 // KVService
 // =========================
@@ -88,18 +161,28 @@ type KVService struct {
 // =========================
 
 type GRPCServer struct {
-	server *grpc.Server
-	lis    net.Listener
-	ks     *KVService
+	server      *grpc.Server
+	lis         net.Listener
+	ks          *KVService
+	idempotency *idempotencyStore
+	// limiter caps the running RPCs - gRPC never waits for a slot
+	limiter *requestLimiter
 }
 
 // NewGRPCServer creates a new gRPC server instance
 func NewGRPCServer(svc kvLogic) *GRPCServer {
 	return &GRPCServer{
-		ks: &KVService{kv: svc},
+		ks:          &KVService{kv: svc},
+		idempotency: newIdempotencyStore(),
+		limiter:     newLimiter(envhandler.ENV.GRPCReqLimit(), 0),
 	}
 }
 
+// Reload applies the hot-reloadable gRPC request limit of the env
+func (g *GRPCServer) Reload() {
+	g.limiter.resize(envhandler.ENV.GRPCReqLimit())
+}
+
 // Start starts the gRPC server
 func (g *GRPCServer) Start(ip string, port int) {
 	var err error
@@ -110,20 +193,37 @@ func (g *GRPCServer) Start(ip string, port int) {
 	}
 
 	concurrentStreams := *envhandler.ENV.GRPC_MAX_CONCURRENT_STREAMS
-	reqLimit := *envhandler.ENV.GRPC_REQ_LIMIT
 
 	g.server = grpc.NewServer(
 		grpc.MaxRecvMsgSize(1<<20), // 1 MB
 		grpc.MaxSendMsgSize(1<<20), // 1 MB
 		grpc.MaxConcurrentStreams(uint32(concurrentStreams)),
 		grpc.ChainUnaryInterceptor(
-			grpcRequestLimitInterceptor(reqLimit),
+			grpcIPFilterInterceptor(newIPFilter()),
+			grpcRequestLimitInterceptor(g.limiter),
 			grpcDeadlineInterceptor(),
+			grpcDBLoadingInterceptor(g.ks.kv),
+			grpcStorageInterceptor(g.ks.kv),
+			grpcIdempotencyInterceptor(g.idempotency),
 		),
 	)
 
 	kvpb.RegisterKVServiceServer(g.server, g.ks)
 
+	// the Unix socket is served next to TCP - GracefulStop closes and removes it
+	if path := *envhandler.ENV.GRPC_UNIX_SOCKET; path != "" {
+		lis, err := listenUnix(path)
+		if err != nil {
+			log.Fatalf("failed to listen: %v", err)
+		}
+		log.Printf("Starting GRPCServer on unix socket %s\n", path)
+		go func() {
+			if err := g.server.Serve(lis); err != nil {
+				log.Printf("Serve unix socket: %v", err)
+			}
+		}()
+	}
+
 	log.Printf("Starting GRPCServer on %s:%d\n", ip, port)
 	if err := g.server.Serve(g.lis); err != nil {
 		log.Fatalf("failed to serve: %v", err)
@@ -142,6 +242,28 @@ func (g *GRPCServer) Stop() {
 	}
 }
 
+// Shutdown stops accepting RPCs and waits for the running ones until ctx is done - the rest is cancelled then
+func (g *GRPCServer) Shutdown(ctx context.Context) error {
+	if g.server == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		g.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		// blocking pops and long calls would hold the shutdown forever
+		g.server.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
+
 // =========================
 // RPC Implementations
 // =========================
@@ -160,7 +282,7 @@ func (s *KVService) CreateDB(ctx context.Context, req *kvpb.CreateDBRequest,
 	}
 
 	return &kvpb.CreateDBResponse{
-		Name:    strings.ToUpper(req.Name),
+		Name:    utils.U.DbKey(req.Name),
 		Created: created,
 		Exists:  exists,
 		Apikey:  apikey,
@@ -177,12 +299,29 @@ func (s *KVService) Set(
 	}
 
 	// if apikey is enabled, check it
-	if *envhandler.ENV.APIKEY_ENABLED && !utils.U.IsApiKeyValid(req.Db, req.Apikey) {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid apikey")
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
 	}
 
-	ok := s.kv.Set(req.Db, req.Key, req.Value, req.Ttl)
-	return &kvpb.OKResponse{Ok: ok}, nil
+	// values above HKV_ENTRY_SIZE are rejected or truncated by HKV_OVERSIZE_POLICY
+	value, truncated, fits := hashMap.FitValue(req.Value)
+	if !fits {
+		return nil, status.Errorf(codes.InvalidArgument, "value too large")
+	}
+
+	// keepttl preserves the expiry of an existing key
+	if req.Keepttl {
+		ok := s.kv.SetKeepTTL(req.Db, req.Key, value)
+		return &kvpb.OKResponse{Ok: ok, Truncated: ok && truncated}, nil
+	}
+
+	// ttls above HKV_MAX_TTL_SECONDS are clamped or rejected by HKV_TTL_POLICY
+	ttl, fits := hashMap.FitTTL(req.Ttl)
+	if !fits {
+		return nil, status.Errorf(codes.InvalidArgument, "ttl out of range")
+	}
+	ok := s.kv.Set(req.Db, req.Key, value, ttl)
+	return &kvpb.OKResponse{Ok: ok, Truncated: ok && truncated}, nil
 }
 
 func (s *KVService) SetNX(
@@ -194,10 +333,58 @@ func (s *KVService) SetNX(
 		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
 	}
 	// if apikey is enabled, check it
-	if *envhandler.ENV.APIKEY_ENABLED && !utils.U.IsApiKeyValid(req.Db, req.Apikey) {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid apikey")
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+	value, truncated, fits := hashMap.FitValue(req.Value)
+	if !fits {
+		return nil, status.Errorf(codes.InvalidArgument, "value too large")
+	}
+	ttl, fits := hashMap.FitTTL(req.Ttl)
+	if !fits {
+		return nil, status.Errorf(codes.InvalidArgument, "ttl out of range")
+	}
+	ok := s.kv.SetNX(req.Db, req.Key, value, ttl)
+	return &kvpb.OKResponse{Ok: ok, Truncated: ok && truncated}, nil
+}
+
+func (s *KVService) SetIfGreater(
+	ctx context.Context,
+	req *kvpb.SetRequest,
+) (*kvpb.OKResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
 	}
-	ok := s.kv.SetNX(req.Db, req.Key, req.Value, req.Ttl)
+	ttl, fits := hashMap.FitTTL(req.Ttl)
+	if !fits {
+		return nil, status.Errorf(codes.InvalidArgument, "ttl out of range")
+	}
+	ok := s.kv.SetIfGreater(req.Db, req.Key, req.Value, ttl)
+	return &kvpb.OKResponse{Ok: ok}, nil
+}
+
+func (s *KVService) SetIfLess(
+	ctx context.Context,
+	req *kvpb.SetRequest,
+) (*kvpb.OKResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+	ttl, fits := hashMap.FitTTL(req.Ttl)
+	if !fits {
+		return nil, status.Errorf(codes.InvalidArgument, "ttl out of range")
+	}
+	ok := s.kv.SetIfLess(req.Db, req.Key, req.Value, ttl)
 	return &kvpb.OKResponse{Ok: ok}, nil
 }
 
@@ -210,8 +397,8 @@ func (s *KVService) Incr(
 		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
 	}
 	// if apikey is enabled, check it
-	if *envhandler.ENV.APIKEY_ENABLED && !utils.U.IsApiKeyValid(req.Db, req.Apikey) {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid apikey")
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
 	}
 	ok := s.kv.Incr(req.Db, req.Key, req.Amount)
 	return &kvpb.OKResponse{Ok: ok}, nil
@@ -226,8 +413,12 @@ func (s *KVService) Get(
 		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
 	}
 	// if apikey is enabled, check it
-	if *envhandler.ENV.APIKEY_ENABLED && !utils.U.IsApiKeyValid(req.Db, req.Apikey) {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid apikey")
+	if err := s.auth(ctx, req.Db, req.Apikey, true); err != nil {
+		return nil, err
+	}
+	// a missing DB is an error, a missing key is found=false
+	if !s.kv.DBExists(req.Db) {
+		return nil, status.Error(codes.NotFound, "db_not_found")
 	}
 
 	found, val := s.kv.Get(req.Db, req.Key)
@@ -237,6 +428,112 @@ func (s *KVService) Get(
 	}, nil
 }
 
+func (s *KVService) CounterIncr(
+	ctx context.Context,
+	req *kvpb.CounterRequest,
+) (*kvpb.CounterResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+
+	// a ttl of 0 keeps the expiry of the counter
+	ttl, fits := req.Ttl, true
+	if ttl > 0 {
+		if ttl, fits = hashMap.FitTTL(ttl); !fits {
+			return nil, status.Errorf(codes.InvalidArgument, "ttl out of range")
+		}
+	}
+	value, ok := s.kv.CounterIncr(req.Db, req.Key, req.Amount, ttl)
+	return &kvpb.CounterResponse{Ok: ok, Value: value}, nil
+}
+
+func (s *KVService) CounterDecr(
+	ctx context.Context,
+	req *kvpb.CounterRequest,
+) (*kvpb.CounterResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+
+	// a ttl of 0 keeps the expiry of the counter
+	ttl, fits := req.Ttl, true
+	if ttl > 0 {
+		if ttl, fits = hashMap.FitTTL(ttl); !fits {
+			return nil, status.Errorf(codes.InvalidArgument, "ttl out of range")
+		}
+	}
+	value, ok := s.kv.CounterDecr(req.Db, req.Key, req.Amount, ttl)
+	return &kvpb.CounterResponse{Ok: ok, Value: value}, nil
+}
+
+func (s *KVService) IncrAndCheck(
+	ctx context.Context,
+	req *kvpb.IncrAndCheckRequest,
+) (*kvpb.IncrAndCheckResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	if req.Limit < 1 || req.Ttl < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be >= 1 and ttl >= 0")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+
+	ttl, fits := hashMap.FitTTL(req.Ttl)
+	if !fits {
+		return nil, status.Errorf(codes.InvalidArgument, "ttl out of range")
+	}
+	count, allowed := s.kv.IncrAndCheck(req.Db, req.Key, req.Limit, ttl)
+	return &kvpb.IncrAndCheckResponse{Allowed: allowed, Count: count}, nil
+}
+
+func (s *KVService) SetBit(
+	ctx context.Context,
+	req *kvpb.SetBitRequest,
+) (*kvpb.BitResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+
+	old, ok := s.kv.SetBit(req.Db, req.Key, req.Offset, int(req.Bit))
+	return &kvpb.BitResponse{Ok: ok, Bit: int32(old)}, nil
+}
+
+func (s *KVService) GetBit(
+	ctx context.Context,
+	req *kvpb.GetBitRequest,
+) (*kvpb.BitResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, true); err != nil {
+		return nil, err
+	}
+
+	bit := s.kv.GetBit(req.Db, req.Key, req.Offset)
+	return &kvpb.BitResponse{Ok: true, Bit: int32(bit)}, nil
+}
+
 func (s *KVService) Delete(
 	ctx context.Context,
 	req *kvpb.DeleteRequest,
@@ -246,14 +543,365 @@ func (s *KVService) Delete(
 		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
 	}
 	// if apikey is enabled, check it
-	if *envhandler.ENV.APIKEY_ENABLED && !utils.U.IsApiKeyValid(req.Db, req.Apikey) {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid apikey")
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
 	}
 
 	ok := s.kv.Del(req.Db, req.Key)
 	return &kvpb.OKResponse{Ok: ok}, nil
 }
 
+func (s *KVService) HSet(
+	ctx context.Context,
+	req *kvpb.HSetRequest,
+) (*kvpb.OKResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+
+	ok := s.kv.HSet(req.Db, req.Key, req.Field, req.Value)
+	return &kvpb.OKResponse{Ok: ok}, nil
+}
+
+func (s *KVService) HGet(
+	ctx context.Context,
+	req *kvpb.HGetRequest,
+) (*kvpb.GetResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, true); err != nil {
+		return nil, err
+	}
+
+	found, val := s.kv.HGet(req.Db, req.Key, req.Field)
+	return &kvpb.GetResponse{
+		Found: found,
+		Value: val,
+	}, nil
+}
+
+func (s *KVService) HGetAll(
+	ctx context.Context,
+	req *kvpb.HGetAllRequest,
+) (*kvpb.HGetAllResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, true); err != nil {
+		return nil, err
+	}
+
+	fields := s.kv.HGetAll(req.Db, req.Key)
+	return &kvpb.HGetAllResponse{
+		Found:  fields != nil,
+		Fields: fields,
+	}, nil
+}
+
+func (s *KVService) HDel(
+	ctx context.Context,
+	req *kvpb.HDelRequest,
+) (*kvpb.OKResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+
+	ok := s.kv.HDel(req.Db, req.Key, req.Field)
+	return &kvpb.OKResponse{Ok: ok}, nil
+}
+
+func (s *KVService) SAdd(
+	ctx context.Context,
+	req *kvpb.SMemberRequest,
+) (*kvpb.OKResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+
+	ok := s.kv.SAdd(req.Db, req.Key, req.Member)
+	return &kvpb.OKResponse{Ok: ok}, nil
+}
+
+func (s *KVService) SRem(
+	ctx context.Context,
+	req *kvpb.SMemberRequest,
+) (*kvpb.OKResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+
+	ok := s.kv.SRem(req.Db, req.Key, req.Member)
+	return &kvpb.OKResponse{Ok: ok}, nil
+}
+
+func (s *KVService) SIsMember(
+	ctx context.Context,
+	req *kvpb.SMemberRequest,
+) (*kvpb.OKResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, true); err != nil {
+		return nil, err
+	}
+
+	ok := s.kv.SIsMember(req.Db, req.Key, req.Member)
+	return &kvpb.OKResponse{Ok: ok}, nil
+}
+
+func (s *KVService) SMembers(
+	ctx context.Context,
+	req *kvpb.SKeyRequest,
+) (*kvpb.SMembersResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, true); err != nil {
+		return nil, err
+	}
+
+	members := s.kv.SMembers(req.Db, req.Key)
+	return &kvpb.SMembersResponse{
+		Found:   members != nil,
+		Members: members,
+	}, nil
+}
+
+func (s *KVService) SCard(
+	ctx context.Context,
+	req *kvpb.SKeyRequest,
+) (*kvpb.SCardResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, true); err != nil {
+		return nil, err
+	}
+
+	card := s.kv.SCard(req.Db, req.Key)
+	return &kvpb.SCardResponse{Card: int64(card)}, nil
+}
+
+func (s *KVService) ZAdd(
+	ctx context.Context,
+	req *kvpb.ZAddRequest,
+) (*kvpb.OKResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+
+	ok := s.kv.ZAdd(req.Db, req.Key, req.Member, req.Score)
+	return &kvpb.OKResponse{Ok: ok}, nil
+}
+
+func (s *KVService) ZScore(
+	ctx context.Context,
+	req *kvpb.ZMemberRequest,
+) (*kvpb.ZScoreResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, true); err != nil {
+		return nil, err
+	}
+
+	score, found := s.kv.ZScore(req.Db, req.Key, req.Member)
+	return &kvpb.ZScoreResponse{
+		Found: found,
+		Score: score,
+	}, nil
+}
+
+func (s *KVService) ZRank(
+	ctx context.Context,
+	req *kvpb.ZMemberRequest,
+) (*kvpb.ZRankResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, true); err != nil {
+		return nil, err
+	}
+
+	rank, found := s.kv.ZRank(req.Db, req.Key, req.Member)
+	return &kvpb.ZRankResponse{
+		Found: found,
+		Rank:  int64(rank),
+	}, nil
+}
+
+func (s *KVService) ZRange(
+	ctx context.Context,
+	req *kvpb.ZRangeRequest,
+) (*kvpb.ZRangeResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, true); err != nil {
+		return nil, err
+	}
+
+	members := s.kv.ZRange(req.Db, req.Key, int(req.Start), int(req.Stop))
+	resp := &kvpb.ZRangeResponse{Found: members != nil}
+	for _, z := range members {
+		resp.Members = append(resp.Members, &kvpb.ZMember{Member: z.Member, Score: z.Score})
+	}
+	return resp, nil
+}
+
+func (s *KVService) LPush(
+	ctx context.Context,
+	req *kvpb.ListPushRequest,
+) (*kvpb.OKResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+
+	ok := s.kv.LPush(req.Db, req.Key, req.Value)
+	return &kvpb.OKResponse{Ok: ok}, nil
+}
+
+func (s *KVService) RPush(
+	ctx context.Context,
+	req *kvpb.ListPushRequest,
+) (*kvpb.OKResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+
+	ok := s.kv.RPush(req.Db, req.Key, req.Value)
+	return &kvpb.OKResponse{Ok: ok}, nil
+}
+
+func (s *KVService) LPop(
+	ctx context.Context,
+	req *kvpb.ListKeyRequest,
+) (*kvpb.GetResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+
+	val, found := s.kv.LPop(req.Db, req.Key)
+	return &kvpb.GetResponse{
+		Found: found,
+		Value: val,
+	}, nil
+}
+
+func (s *KVService) RPop(
+	ctx context.Context,
+	req *kvpb.ListKeyRequest,
+) (*kvpb.GetResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, false); err != nil {
+		return nil, err
+	}
+
+	val, found := s.kv.RPop(req.Db, req.Key)
+	return &kvpb.GetResponse{
+		Found: found,
+		Value: val,
+	}, nil
+}
+
+func (s *KVService) LRange(
+	ctx context.Context,
+	req *kvpb.ListRangeRequest,
+) (*kvpb.ListRangeResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, true); err != nil {
+		return nil, err
+	}
+
+	values := s.kv.LRange(req.Db, req.Key, int(req.Start), int(req.Stop))
+	return &kvpb.ListRangeResponse{
+		Found:  values != nil,
+		Values: values,
+	}, nil
+}
+
+func (s *KVService) LLen(
+	ctx context.Context,
+	req *kvpb.ListKeyRequest,
+) (*kvpb.LLenResponse, error) {
+
+	if !utils.U.CheckDbName(req.Db) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid db name")
+	}
+	// if apikey is enabled, check it
+	if err := s.auth(ctx, req.Db, req.Apikey, true); err != nil {
+		return nil, err
+	}
+
+	n := s.kv.LLen(req.Db, req.Key)
+	return &kvpb.LLenResponse{Len: int64(n)}, nil
+}
+
 func (s *KVService) Exists(
 	ctx context.Context,
 	req *kvpb.ExistsRequest,
@@ -266,16 +914,44 @@ func (s *KVService) Exists(
 	return &kvpb.ExistsResponse{Exists: ok}, nil
 }
 
+// fifolifoStatus maps an error of a FiFoLiFo operation on the queue name to a gRPC status, so clients can tell an
+// unknown queue (create it) from a full one (retry later) and an empty one (wait)
+func fifolifoStatus(name string, err error) error {
+	switch {
+	case errors.Is(err, fifolifo.ErrNotFound):
+		// the error of the HashMap names the missing queue, which for a move may be either of both
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, fifolifo.ErrFull):
+		return status.Errorf(codes.ResourceExhausted, "queue %s is full", name)
+	case errors.Is(err, fifolifo.ErrEmpty):
+		return status.Errorf(codes.FailedPrecondition, "queue %s is empty", name)
+	case errors.Is(err, fifolifo.ErrEmptyEntry):
+		return status.Errorf(codes.InvalidArgument, "queue %s: %v", name, err)
+	default:
+		return status.Errorf(codes.Internal, "queue %s: %v", name, err)
+	}
+}
+
+// fifolifoDB checks the api key and that the DB of a FiFoLiFo call exists
+func (s *KVService) fifolifoDB(ctx context.Context, db, apikey string) error {
+	if err := s.auth(ctx, db, apikey, false); err != nil {
+		return err
+	}
+	if !s.kv.DBExists(db) {
+		return status.Error(codes.NotFound, "db_not_found")
+	}
+	return nil
+}
+
 func (s *KVService) FiFoLiFoDelete(
 	ctx context.Context,
 	req *kvpb.FiFoLiFoDeleteRequest,
 ) (*kvpb.OKResponse, error) {
-	if *envhandler.ENV.APIKEY_ENABLED && !utils.U.IsApiKeyValid(req.Db, req.Apikey) {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid apikey")
+	if err := s.fifolifoDB(ctx, req.Db, req.Apikey); err != nil {
+		return nil, err
 	}
-	err := s.kv.DelFiFoLiFo(req.Db, req.Name)
-	if err != nil {
-		return &kvpb.OKResponse{Ok: false}, status.Error(codes.NotFound, err.Error())
+	if err := s.kv.DelFiFoLiFo(req.Db, req.Name); err != nil {
+		return &kvpb.OKResponse{Ok: false}, fifolifoStatus(req.Name, err)
 	}
 	return &kvpb.OKResponse{Ok: true}, nil
 }
@@ -284,12 +960,12 @@ func (s *KVService) FiFoLiFoPush(
 	ctx context.Context,
 	req *kvpb.FiFoLiFoPushRequest,
 ) (*kvpb.OKResponse, error) {
-	if *envhandler.ENV.APIKEY_ENABLED && !utils.U.IsApiKeyValid(req.Db, req.Apikey) {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid apikey")
+	if err := s.fifolifoDB(ctx, req.Db, req.Apikey); err != nil {
+		return nil, err
 	}
 	ok, err := s.kv.PushEntryFiFoLiFo(req.Db, req.Name, req.Value)
 	if err != nil {
-		return &kvpb.OKResponse{Ok: false}, status.Error(codes.Internal, err.Error())
+		return &kvpb.OKResponse{Ok: false}, fifolifoStatus(req.Name, err)
 	}
 	return &kvpb.OKResponse{Ok: ok}, nil
 }
@@ -298,12 +974,12 @@ func (s *KVService) FiFoLiFoFPop(
 	ctx context.Context,
 	req *kvpb.FiFoLiFoPopRequest,
 ) (*kvpb.FiFoLiFoPopResponse, error) {
-	if *envhandler.ENV.APIKEY_ENABLED && !utils.U.IsApiKeyValid(req.Db, req.Apikey) {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid apikey")
+	if err := s.fifolifoDB(ctx, req.Db, req.Apikey); err != nil {
+		return nil, err
 	}
 	val, err := s.kv.PopEntryFiFo(req.Db, req.Name)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, fifolifoStatus(req.Name, err)
 	}
 	return &kvpb.FiFoLiFoPopResponse{Value: val}, nil
 }
@@ -313,12 +989,74 @@ func (s *KVService) FiFoLiFoLPop(
 	req *kvpb.FiFoLiFoPopRequest,
 ) (*kvpb.FiFoLiFoPopResponse, error) {
 	// Check if api key is activated
-	if *envhandler.ENV.APIKEY_ENABLED && !utils.U.IsApiKeyValid(req.Db, req.Apikey) {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid apikey")
+	if err := s.fifolifoDB(ctx, req.Db, req.Apikey); err != nil {
+		return nil, err
 	}
 	val, err := s.kv.PopEntryLiFo(req.Db, req.Name)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, fifolifoStatus(req.Name, err)
 	}
 	return &kvpb.FiFoLiFoPopResponse{Value: val}, nil
 }
+
+func (s *KVService) FiFoLiFoBPop(
+	ctx context.Context,
+	req *kvpb.FiFoLiFoBPopRequest,
+) (*kvpb.FiFoLiFoPopResponse, error) {
+	if err := s.fifolifoDB(ctx, req.Db, req.Apikey); err != nil {
+		return nil, err
+	}
+
+	// without a timeout the pop waits until the deadline of the call, which is capped by HKV_GRPC_MAX_DURATION
+	timeout := time.Duration(req.TimeoutMs) * time.Millisecond
+	if deadline, ok := ctx.Deadline(); req.TimeoutMs <= 0 && ok {
+		timeout = time.Until(deadline)
+	}
+	val, err := s.kv.BPopEntryFiFo(ctx, req.Db, req.Name, timeout)
+	switch {
+	case err == nil:
+		return &kvpb.FiFoLiFoPopResponse{Value: val}, nil
+	case ctx.Err() != nil && !errors.Is(err, fifolifo.ErrEmpty):
+		return nil, status.FromContextError(ctx.Err()).Err()
+	default:
+		return nil, fifolifoStatus(req.Name, err)
+	}
+}
+
+func (s *KVService) FiFoLiFoMove(
+	ctx context.Context,
+	req *kvpb.FiFoLiFoMoveRequest,
+) (*kvpb.FiFoLiFoPopResponse, error) {
+	if err := s.fifolifoDB(ctx, req.Db, req.Apikey); err != nil {
+		return nil, err
+	}
+	val, err := s.kv.MoveFiFoLiFo(req.Db, req.Src, req.Dst)
+	if err != nil {
+		// a full queue is the destination, an empty one the source
+		name := req.Src
+		if errors.Is(err, fifolifo.ErrFull) {
+			name = req.Dst
+		}
+		return nil, fifolifoStatus(name, err)
+	}
+	return &kvpb.FiFoLiFoPopResponse{Value: val}, nil
+}
+
+// FlushAll flushes or drops all DBs - it needs HKV_ADMIN_KEY instead of a DB api key
+func (s *KVService) FlushAll(
+	ctx context.Context,
+	req *kvpb.FlushAllRequest,
+) (*kvpb.FlushAllResponse, error) {
+	ok := validAdminKey(req.AdminKey)
+	outcome := authGranted
+	if !ok {
+		outcome = authDenied
+	}
+	audit.record("grpc", grpcClientIP(ctx), "", req.AdminKey, outcome)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid admin key")
+	}
+
+	n := s.kv.FlushAll(req.Drop)
+	return &kvpb.FlushAllResponse{Dbs: int32(n)}, nil
+}
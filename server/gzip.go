@@ -0,0 +1,143 @@
+package server
+
+import (
+	"compress/gzip"
+	"hydrakv/envhandler"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// gzipWriters are reused, since a gzip.Writer allocates a lot
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+type gzipHandler struct {
+	minSize int
+}
+
+// creates a new gzip handler - responses smaller than HKV_GZIP_MIN_BYTES are sent uncompressed
+func newGzipHandler() *gzipHandler {
+	return &gzipHandler{minSize: *envhandler.ENV.GZIP_MIN_BYTES}
+}
+
+// acceptsGzip checks the Accept-Encoding header for gzip
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.TrimSpace(name) != "gzip" {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
+
+// wrap compresses the responses of clients which accept gzip
+func (g *gzipHandler) wrap(next http.Handler) http.Handler {
+	if g.minSize <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead || !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		gw := &gzipResponseWriter{ResponseWriter: w, minSize: g.minSize}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// gzipResponseWriter buffers the response until it is known whether it is big enough to be compressed
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Unwrap gives http.ResponseController access to the underlying writer, e.g. to extend the write deadline
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// start sends the header and the buffered body - compressed if possible
+func (w *gzipResponseWriter) start(compress bool) error {
+	w.decided = true
+	h := w.Header()
+
+	// already encoded responses (e.g. /metrics) and responses without a body stay as they are
+	if h.Get("Content-Encoding") != "" || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		compress = false
+	}
+
+	if compress {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		// the compressed representation needs its own entity tag
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", strings.TrimSuffix(etag, `"`)+`-gzip"`)
+		}
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// close sends small responses uncompressed and finishes the gzip stream
+func (w *gzipResponseWriter) close() {
+	if !w.decided {
+		if w.status == 0 {
+			return
+		}
+		_ = w.start(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
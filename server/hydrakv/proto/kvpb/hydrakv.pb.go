@@ -67,14 +67,16 @@ func (x *CreateDBRequest) GetName() string {
 }
 
 type SetRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
-	Apikey        string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
-	Ttl           int64                  `protobuf:"varint,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
-	Key           string                 `protobuf:"bytes,4,opt,name=key,proto3" json:"key,omitempty"`
-	Value         string                 `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Db             string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Ttl            int64                  `protobuf:"varint,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	Key            string                 `protobuf:"bytes,4,opt,name=key,proto3" json:"key,omitempty"`
+	Value          string                 `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+	Keepttl        bool                   `protobuf:"varint,6,opt,name=keepttl,proto3" json:"keepttl,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,7,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *SetRequest) Reset() {
@@ -142,6 +144,20 @@ func (x *SetRequest) GetValue() string {
 	return ""
 }
 
+func (x *SetRequest) GetKeepttl() bool {
+	if x != nil {
+		return x.Keepttl
+	}
+	return false
+}
+
+func (x *SetRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
 type GetRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
@@ -203,12 +219,13 @@ func (x *GetRequest) GetKey() string {
 }
 
 type DeleteRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
-	Apikey        string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
-	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Db             string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key            string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,4,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *DeleteRequest) Reset() {
@@ -262,14 +279,22 @@ func (x *DeleteRequest) GetKey() string {
 	return ""
 }
 
+func (x *DeleteRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
 type IncrRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
-	Apikey        string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
-	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
-	Amount        string                 `protobuf:"bytes,4,opt,name=amount,proto3" json:"amount,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Db             string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key            string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Amount         string                 `protobuf:"bytes,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,5,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *IncrRequest) Reset() {
@@ -330,27 +355,39 @@ func (x *IncrRequest) GetAmount() string {
 	return ""
 }
 
-type ExistsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *IncrRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
 }
 
-func (x *ExistsRequest) Reset() {
-	*x = ExistsRequest{}
+type CounterRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Db             string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key            string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Amount         int64                  `protobuf:"varint,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	Ttl            int64                  `protobuf:"varint,5,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,6,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CounterRequest) Reset() {
+	*x = CounterRequest{}
 	mi := &file_hydrakv_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ExistsRequest) String() string {
+func (x *CounterRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ExistsRequest) ProtoMessage() {}
+func (*CounterRequest) ProtoMessage() {}
 
-func (x *ExistsRequest) ProtoReflect() protoreflect.Message {
+func (x *CounterRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_hydrakv_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -362,39 +399,79 @@ func (x *ExistsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ExistsRequest.ProtoReflect.Descriptor instead.
-func (*ExistsRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use CounterRequest.ProtoReflect.Descriptor instead.
+func (*CounterRequest) Descriptor() ([]byte, []int) {
 	return file_hydrakv_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *ExistsRequest) GetDb() string {
+func (x *CounterRequest) GetDb() string {
 	if x != nil {
 		return x.Db
 	}
 	return ""
 }
 
-type OKResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *CounterRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
 }
 
-func (x *OKResponse) Reset() {
-	*x = OKResponse{}
+func (x *CounterRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *CounterRequest) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *CounterRequest) GetTtl() int64 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *CounterRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type IncrAndCheckRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Db             string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key            string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Limit          int64                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	Ttl            int64                  `protobuf:"varint,5,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,6,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *IncrAndCheckRequest) Reset() {
+	*x = IncrAndCheckRequest{}
 	mi := &file_hydrakv_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *OKResponse) String() string {
+func (x *IncrAndCheckRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*OKResponse) ProtoMessage() {}
+func (*IncrAndCheckRequest) ProtoMessage() {}
 
-func (x *OKResponse) ProtoReflect() protoreflect.Message {
+func (x *IncrAndCheckRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_hydrakv_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -406,42 +483,75 @@ func (x *OKResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use OKResponse.ProtoReflect.Descriptor instead.
-func (*OKResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use IncrAndCheckRequest.ProtoReflect.Descriptor instead.
+func (*IncrAndCheckRequest) Descriptor() ([]byte, []int) {
 	return file_hydrakv_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *OKResponse) GetOk() bool {
+func (x *IncrAndCheckRequest) GetDb() string {
 	if x != nil {
-		return x.Ok
+		return x.Db
 	}
-	return false
+	return ""
 }
 
-type CreateDBResponse struct {
+func (x *IncrAndCheckRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *IncrAndCheckRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *IncrAndCheckRequest) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *IncrAndCheckRequest) GetTtl() int64 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *IncrAndCheckRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type IncrAndCheckResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Apikey        string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
-	Created       bool                   `protobuf:"varint,3,opt,name=created,proto3" json:"created,omitempty"`
-	Exists        bool                   `protobuf:"varint,4,opt,name=exists,proto3" json:"exists,omitempty"`
+	Allowed       bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CreateDBResponse) Reset() {
-	*x = CreateDBResponse{}
+func (x *IncrAndCheckResponse) Reset() {
+	*x = IncrAndCheckResponse{}
 	mi := &file_hydrakv_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CreateDBResponse) String() string {
+func (x *IncrAndCheckResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CreateDBResponse) ProtoMessage() {}
+func (*IncrAndCheckResponse) ProtoMessage() {}
 
-func (x *CreateDBResponse) ProtoReflect() protoreflect.Message {
+func (x *IncrAndCheckResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_hydrakv_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -453,61 +563,51 @@ func (x *CreateDBResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CreateDBResponse.ProtoReflect.Descriptor instead.
-func (*CreateDBResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use IncrAndCheckResponse.ProtoReflect.Descriptor instead.
+func (*IncrAndCheckResponse) Descriptor() ([]byte, []int) {
 	return file_hydrakv_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *CreateDBResponse) GetName() string {
-	if x != nil {
-		return x.Name
-	}
-	return ""
-}
-
-func (x *CreateDBResponse) GetApikey() string {
-	if x != nil {
-		return x.Apikey
-	}
-	return ""
-}
-
-func (x *CreateDBResponse) GetCreated() bool {
+func (x *IncrAndCheckResponse) GetAllowed() bool {
 	if x != nil {
-		return x.Created
+		return x.Allowed
 	}
 	return false
 }
 
-func (x *CreateDBResponse) GetExists() bool {
+func (x *IncrAndCheckResponse) GetCount() int64 {
 	if x != nil {
-		return x.Exists
+		return x.Count
 	}
-	return false
+	return 0
 }
 
-type GetResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Found         bool                   `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
-	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type SetBitRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Db             string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key            string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Offset         int64                  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Bit            int32                  `protobuf:"varint,5,opt,name=bit,proto3" json:"bit,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,6,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
-func (x *GetResponse) Reset() {
-	*x = GetResponse{}
+func (x *SetBitRequest) Reset() {
+	*x = SetBitRequest{}
 	mi := &file_hydrakv_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetResponse) String() string {
+func (x *SetBitRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetResponse) ProtoMessage() {}
+func (*SetBitRequest) ProtoMessage() {}
 
-func (x *GetResponse) ProtoReflect() protoreflect.Message {
+func (x *SetBitRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_hydrakv_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -519,46 +619,77 @@ func (x *GetResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
-func (*GetResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use SetBitRequest.ProtoReflect.Descriptor instead.
+func (*SetBitRequest) Descriptor() ([]byte, []int) {
 	return file_hydrakv_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *GetResponse) GetFound() bool {
+func (x *SetBitRequest) GetDb() string {
 	if x != nil {
-		return x.Found
+		return x.Db
 	}
-	return false
+	return ""
 }
 
-func (x *GetResponse) GetValue() string {
+func (x *SetBitRequest) GetApikey() string {
 	if x != nil {
-		return x.Value
+		return x.Apikey
 	}
 	return ""
 }
 
-type ExistsResponse struct {
+func (x *SetBitRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SetBitRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SetBitRequest) GetBit() int32 {
+	if x != nil {
+		return x.Bit
+	}
+	return 0
+}
+
+func (x *SetBitRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type GetBitRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Exists        bool                   `protobuf:"varint,1,opt,name=exists,proto3" json:"exists,omitempty"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey        string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Offset        int64                  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ExistsResponse) Reset() {
-	*x = ExistsResponse{}
+func (x *GetBitRequest) Reset() {
+	*x = GetBitRequest{}
 	mi := &file_hydrakv_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ExistsResponse) String() string {
+func (x *GetBitRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ExistsResponse) ProtoMessage() {}
+func (*GetBitRequest) ProtoMessage() {}
 
-func (x *ExistsResponse) ProtoReflect() protoreflect.Message {
+func (x *GetBitRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_hydrakv_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -570,41 +701,65 @@ func (x *ExistsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ExistsResponse.ProtoReflect.Descriptor instead.
-func (*ExistsResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use GetBitRequest.ProtoReflect.Descriptor instead.
+func (*GetBitRequest) Descriptor() ([]byte, []int) {
 	return file_hydrakv_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *ExistsResponse) GetExists() bool {
+func (x *GetBitRequest) GetDb() string {
 	if x != nil {
-		return x.Exists
+		return x.Db
 	}
-	return false
+	return ""
 }
 
-type FiFoLiFoDeleteRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Db            string                 `protobuf:"bytes,2,opt,name=db,proto3" json:"db,omitempty"`
-	Apikey        string                 `protobuf:"bytes,3,opt,name=Apikey,proto3" json:"Apikey,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *GetBitRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
 }
 
-func (x *FiFoLiFoDeleteRequest) Reset() {
-	*x = FiFoLiFoDeleteRequest{}
+func (x *GetBitRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *GetBitRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type HSetRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Db             string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key            string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Field          string                 `protobuf:"bytes,4,opt,name=field,proto3" json:"field,omitempty"`
+	Value          string                 `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,6,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *HSetRequest) Reset() {
+	*x = HSetRequest{}
 	mi := &file_hydrakv_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FiFoLiFoDeleteRequest) String() string {
+func (x *HSetRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FiFoLiFoDeleteRequest) ProtoMessage() {}
+func (*HSetRequest) ProtoMessage() {}
 
-func (x *FiFoLiFoDeleteRequest) ProtoReflect() protoreflect.Message {
+func (x *HSetRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_hydrakv_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -616,57 +771,1774 @@ func (x *FiFoLiFoDeleteRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FiFoLiFoDeleteRequest.ProtoReflect.Descriptor instead.
-func (*FiFoLiFoDeleteRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use HSetRequest.ProtoReflect.Descriptor instead.
+func (*HSetRequest) Descriptor() ([]byte, []int) {
 	return file_hydrakv_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *FiFoLiFoDeleteRequest) GetName() string {
+func (x *HSetRequest) GetDb() string {
 	if x != nil {
-		return x.Name
+		return x.Db
 	}
 	return ""
 }
 
-func (x *FiFoLiFoDeleteRequest) GetDb() string {
+func (x *HSetRequest) GetApikey() string {
 	if x != nil {
-		return x.Db
+		return x.Apikey
 	}
 	return ""
 }
 
-func (x *FiFoLiFoDeleteRequest) GetApikey() string {
+func (x *HSetRequest) GetKey() string {
 	if x != nil {
-		return x.Apikey
+		return x.Key
 	}
 	return ""
 }
 
-type FiFoLiFoPushRequest struct {
+func (x *HSetRequest) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *HSetRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *HSetRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type HGetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey        string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Field         string                 `protobuf:"bytes,4,opt,name=field,proto3" json:"field,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HGetRequest) Reset() {
+	*x = HGetRequest{}
+	mi := &file_hydrakv_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HGetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HGetRequest) ProtoMessage() {}
+
+func (x *HGetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HGetRequest.ProtoReflect.Descriptor instead.
+func (*HGetRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *HGetRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *HGetRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *HGetRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *HGetRequest) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+type HGetAllRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey        string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HGetAllRequest) Reset() {
+	*x = HGetAllRequest{}
+	mi := &file_hydrakv_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HGetAllRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HGetAllRequest) ProtoMessage() {}
+
+func (x *HGetAllRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HGetAllRequest.ProtoReflect.Descriptor instead.
+func (*HGetAllRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *HGetAllRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *HGetAllRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *HGetAllRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type HDelRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Db             string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key            string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Field          string                 `protobuf:"bytes,4,opt,name=field,proto3" json:"field,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,5,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *HDelRequest) Reset() {
+	*x = HDelRequest{}
+	mi := &file_hydrakv_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HDelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HDelRequest) ProtoMessage() {}
+
+func (x *HDelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HDelRequest.ProtoReflect.Descriptor instead.
+func (*HDelRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *HDelRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *HDelRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *HDelRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *HDelRequest) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *HDelRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type SMemberRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Db             string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key            string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Member         string                 `protobuf:"bytes,4,opt,name=member,proto3" json:"member,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,5,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SMemberRequest) Reset() {
+	*x = SMemberRequest{}
+	mi := &file_hydrakv_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SMemberRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SMemberRequest) ProtoMessage() {}
+
+func (x *SMemberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SMemberRequest.ProtoReflect.Descriptor instead.
+func (*SMemberRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SMemberRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *SMemberRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *SMemberRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SMemberRequest) GetMember() string {
+	if x != nil {
+		return x.Member
+	}
+	return ""
+}
+
+func (x *SMemberRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type SKeyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey        string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SKeyRequest) Reset() {
+	*x = SKeyRequest{}
+	mi := &file_hydrakv_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SKeyRequest) ProtoMessage() {}
+
+func (x *SKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SKeyRequest.ProtoReflect.Descriptor instead.
+func (*SKeyRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *SKeyRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *SKeyRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *SKeyRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type ZAddRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Db             string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key            string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Member         string                 `protobuf:"bytes,4,opt,name=member,proto3" json:"member,omitempty"`
+	Score          float64                `protobuf:"fixed64,5,opt,name=score,proto3" json:"score,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,6,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ZAddRequest) Reset() {
+	*x = ZAddRequest{}
+	mi := &file_hydrakv_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ZAddRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ZAddRequest) ProtoMessage() {}
+
+func (x *ZAddRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ZAddRequest.ProtoReflect.Descriptor instead.
+func (*ZAddRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ZAddRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *ZAddRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *ZAddRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ZAddRequest) GetMember() string {
+	if x != nil {
+		return x.Member
+	}
+	return ""
+}
+
+func (x *ZAddRequest) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *ZAddRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type ZMemberRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey        string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Member        string                 `protobuf:"bytes,4,opt,name=member,proto3" json:"member,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ZMemberRequest) Reset() {
+	*x = ZMemberRequest{}
+	mi := &file_hydrakv_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ZMemberRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ZMemberRequest) ProtoMessage() {}
+
+func (x *ZMemberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ZMemberRequest.ProtoReflect.Descriptor instead.
+func (*ZMemberRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ZMemberRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *ZMemberRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *ZMemberRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ZMemberRequest) GetMember() string {
+	if x != nil {
+		return x.Member
+	}
+	return ""
+}
+
+type ZRangeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey        string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Start         int64                  `protobuf:"varint,4,opt,name=start,proto3" json:"start,omitempty"`
+	Stop          int64                  `protobuf:"varint,5,opt,name=stop,proto3" json:"stop,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ZRangeRequest) Reset() {
+	*x = ZRangeRequest{}
+	mi := &file_hydrakv_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ZRangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ZRangeRequest) ProtoMessage() {}
+
+func (x *ZRangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ZRangeRequest.ProtoReflect.Descriptor instead.
+func (*ZRangeRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ZRangeRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *ZRangeRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *ZRangeRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ZRangeRequest) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *ZRangeRequest) GetStop() int64 {
+	if x != nil {
+		return x.Stop
+	}
+	return 0
+}
+
+type ListPushRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Db             string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key            string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Value          string                 `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,5,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ListPushRequest) Reset() {
+	*x = ListPushRequest{}
+	mi := &file_hydrakv_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPushRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPushRequest) ProtoMessage() {}
+
+func (x *ListPushRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPushRequest.ProtoReflect.Descriptor instead.
+func (*ListPushRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListPushRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *ListPushRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *ListPushRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ListPushRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *ListPushRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type ListKeyRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Db             string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key            string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,4,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ListKeyRequest) Reset() {
+	*x = ListKeyRequest{}
+	mi := &file_hydrakv_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListKeyRequest) ProtoMessage() {}
+
+func (x *ListKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListKeyRequest.ProtoReflect.Descriptor instead.
+func (*ListKeyRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ListKeyRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *ListKeyRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *ListKeyRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ListKeyRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type ListRangeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey        string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Start         int64                  `protobuf:"varint,4,opt,name=start,proto3" json:"start,omitempty"`
+	Stop          int64                  `protobuf:"varint,5,opt,name=stop,proto3" json:"stop,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRangeRequest) Reset() {
+	*x = ListRangeRequest{}
+	mi := &file_hydrakv_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRangeRequest) ProtoMessage() {}
+
+func (x *ListRangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRangeRequest.ProtoReflect.Descriptor instead.
+func (*ListRangeRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ListRangeRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *ListRangeRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *ListRangeRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ListRangeRequest) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *ListRangeRequest) GetStop() int64 {
+	if x != nil {
+		return x.Stop
+	}
+	return 0
+}
+
+type ExistsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExistsRequest) Reset() {
+	*x = ExistsRequest{}
+	mi := &file_hydrakv_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExistsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExistsRequest) ProtoMessage() {}
+
+func (x *ExistsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExistsRequest.ProtoReflect.Descriptor instead.
+func (*ExistsRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ExistsRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+type OKResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Truncated     bool                   `protobuf:"varint,2,opt,name=truncated,proto3" json:"truncated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OKResponse) Reset() {
+	*x = OKResponse{}
+	mi := &file_hydrakv_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OKResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OKResponse) ProtoMessage() {}
+
+func (x *OKResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OKResponse.ProtoReflect.Descriptor instead.
+func (*OKResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *OKResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *OKResponse) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+type CreateDBResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Apikey        string                 `protobuf:"bytes,2,opt,name=apikey,proto3" json:"apikey,omitempty"`
+	Created       bool                   `protobuf:"varint,3,opt,name=created,proto3" json:"created,omitempty"`
+	Exists        bool                   `protobuf:"varint,4,opt,name=exists,proto3" json:"exists,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateDBResponse) Reset() {
+	*x = CreateDBResponse{}
+	mi := &file_hydrakv_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateDBResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateDBResponse) ProtoMessage() {}
+
+func (x *CreateDBResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDBResponse.ProtoReflect.Descriptor instead.
+func (*CreateDBResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *CreateDBResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateDBResponse) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *CreateDBResponse) GetCreated() bool {
+	if x != nil {
+		return x.Created
+	}
+	return false
+}
+
+func (x *CreateDBResponse) GetExists() bool {
+	if x != nil {
+		return x.Exists
+	}
+	return false
+}
+
+type GetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Found         bool                   `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResponse) Reset() {
+	*x = GetResponse{}
+	mi := &file_hydrakv_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResponse) ProtoMessage() {}
+
+func (x *GetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
+func (*GetResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *GetResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *GetResponse) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type HGetAllResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Found         bool                   `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	Fields        map[string]string      `protobuf:"bytes,2,rep,name=fields,proto3" json:"fields,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HGetAllResponse) Reset() {
+	*x = HGetAllResponse{}
+	mi := &file_hydrakv_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HGetAllResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HGetAllResponse) ProtoMessage() {}
+
+func (x *HGetAllResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HGetAllResponse.ProtoReflect.Descriptor instead.
+func (*HGetAllResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *HGetAllResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *HGetAllResponse) GetFields() map[string]string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+type SMembersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Found         bool                   `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	Members       []string               `protobuf:"bytes,2,rep,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SMembersResponse) Reset() {
+	*x = SMembersResponse{}
+	mi := &file_hydrakv_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SMembersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SMembersResponse) ProtoMessage() {}
+
+func (x *SMembersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SMembersResponse.ProtoReflect.Descriptor instead.
+func (*SMembersResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *SMembersResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *SMembersResponse) GetMembers() []string {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type SCardResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Card          int64                  `protobuf:"varint,1,opt,name=card,proto3" json:"card,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SCardResponse) Reset() {
+	*x = SCardResponse{}
+	mi := &file_hydrakv_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SCardResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SCardResponse) ProtoMessage() {}
+
+func (x *SCardResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SCardResponse.ProtoReflect.Descriptor instead.
+func (*SCardResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *SCardResponse) GetCard() int64 {
+	if x != nil {
+		return x.Card
+	}
+	return 0
+}
+
+type ZScoreResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Found         bool                   `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	Score         float64                `protobuf:"fixed64,2,opt,name=score,proto3" json:"score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ZScoreResponse) Reset() {
+	*x = ZScoreResponse{}
+	mi := &file_hydrakv_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ZScoreResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ZScoreResponse) ProtoMessage() {}
+
+func (x *ZScoreResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ZScoreResponse.ProtoReflect.Descriptor instead.
+func (*ZScoreResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ZScoreResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *ZScoreResponse) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+type ZRankResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Found         bool                   `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	Rank          int64                  `protobuf:"varint,2,opt,name=rank,proto3" json:"rank,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ZRankResponse) Reset() {
+	*x = ZRankResponse{}
+	mi := &file_hydrakv_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ZRankResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ZRankResponse) ProtoMessage() {}
+
+func (x *ZRankResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ZRankResponse.ProtoReflect.Descriptor instead.
+func (*ZRankResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ZRankResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *ZRankResponse) GetRank() int64 {
+	if x != nil {
+		return x.Rank
+	}
+	return 0
+}
+
+type ZMember struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Member        string                 `protobuf:"bytes,1,opt,name=member,proto3" json:"member,omitempty"`
+	Score         float64                `protobuf:"fixed64,2,opt,name=score,proto3" json:"score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ZMember) Reset() {
+	*x = ZMember{}
+	mi := &file_hydrakv_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ZMember) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ZMember) ProtoMessage() {}
+
+func (x *ZMember) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ZMember.ProtoReflect.Descriptor instead.
+func (*ZMember) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ZMember) GetMember() string {
+	if x != nil {
+		return x.Member
+	}
+	return ""
+}
+
+func (x *ZMember) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+type ZRangeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Found         bool                   `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	Members       []*ZMember             `protobuf:"bytes,2,rep,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ZRangeResponse) Reset() {
+	*x = ZRangeResponse{}
+	mi := &file_hydrakv_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ZRangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ZRangeResponse) ProtoMessage() {}
+
+func (x *ZRangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ZRangeResponse.ProtoReflect.Descriptor instead.
+func (*ZRangeResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ZRangeResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *ZRangeResponse) GetMembers() []*ZMember {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type ListRangeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Found         bool                   `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	Values        []string               `protobuf:"bytes,2,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRangeResponse) Reset() {
+	*x = ListRangeResponse{}
+	mi := &file_hydrakv_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRangeResponse) ProtoMessage() {}
+
+func (x *ListRangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRangeResponse.ProtoReflect.Descriptor instead.
+func (*ListRangeResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ListRangeResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *ListRangeResponse) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type LLenResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Len           int64                  `protobuf:"varint,1,opt,name=len,proto3" json:"len,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LLenResponse) Reset() {
+	*x = LLenResponse{}
+	mi := &file_hydrakv_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LLenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LLenResponse) ProtoMessage() {}
+
+func (x *LLenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LLenResponse.ProtoReflect.Descriptor instead.
+func (*LLenResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *LLenResponse) GetLen() int64 {
+	if x != nil {
+		return x.Len
+	}
+	return 0
+}
+
+type ExistsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Exists        bool                   `protobuf:"varint,1,opt,name=exists,proto3" json:"exists,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExistsResponse) Reset() {
+	*x = ExistsResponse{}
+	mi := &file_hydrakv_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExistsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExistsResponse) ProtoMessage() {}
+
+func (x *ExistsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExistsResponse.ProtoReflect.Descriptor instead.
+func (*ExistsResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *ExistsResponse) GetExists() bool {
+	if x != nil {
+		return x.Exists
+	}
+	return false
+}
+
+type FiFoLiFoDeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Db            string                 `protobuf:"bytes,2,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey        string                 `protobuf:"bytes,3,opt,name=Apikey,proto3" json:"Apikey,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FiFoLiFoDeleteRequest) Reset() {
+	*x = FiFoLiFoDeleteRequest{}
+	mi := &file_hydrakv_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FiFoLiFoDeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FiFoLiFoDeleteRequest) ProtoMessage() {}
+
+func (x *FiFoLiFoDeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FiFoLiFoDeleteRequest.ProtoReflect.Descriptor instead.
+func (*FiFoLiFoDeleteRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *FiFoLiFoDeleteRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FiFoLiFoDeleteRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *FiFoLiFoDeleteRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+type FiFoLiFoPushRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Name           string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value          string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Db             string                 `protobuf:"bytes,3,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,4,opt,name=Apikey,proto3" json:"Apikey,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,5,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *FiFoLiFoPushRequest) Reset() {
+	*x = FiFoLiFoPushRequest{}
+	mi := &file_hydrakv_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FiFoLiFoPushRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FiFoLiFoPushRequest) ProtoMessage() {}
+
+func (x *FiFoLiFoPushRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FiFoLiFoPushRequest.ProtoReflect.Descriptor instead.
+func (*FiFoLiFoPushRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *FiFoLiFoPushRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FiFoLiFoPushRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *FiFoLiFoPushRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *FiFoLiFoPushRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+func (x *FiFoLiFoPushRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type FiFoLiFoPopRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Db            string                 `protobuf:"bytes,2,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey        string                 `protobuf:"bytes,3,opt,name=Apikey,proto3" json:"Apikey,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FiFoLiFoPopRequest) Reset() {
+	*x = FiFoLiFoPopRequest{}
+	mi := &file_hydrakv_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FiFoLiFoPopRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FiFoLiFoPopRequest) ProtoMessage() {}
+
+func (x *FiFoLiFoPopRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FiFoLiFoPopRequest.ProtoReflect.Descriptor instead.
+func (*FiFoLiFoPopRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *FiFoLiFoPopRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FiFoLiFoPopRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *FiFoLiFoPopRequest) GetApikey() string {
+	if x != nil {
+		return x.Apikey
+	}
+	return ""
+}
+
+type FiFoLiFoBPopRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
-	Db            string                 `protobuf:"bytes,3,opt,name=db,proto3" json:"db,omitempty"`
-	Apikey        string                 `protobuf:"bytes,4,opt,name=Apikey,proto3" json:"Apikey,omitempty"`
+	Db            string                 `protobuf:"bytes,2,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey        string                 `protobuf:"bytes,3,opt,name=Apikey,proto3" json:"Apikey,omitempty"`
+	TimeoutMs     int64                  `protobuf:"varint,4,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FiFoLiFoPushRequest) Reset() {
-	*x = FiFoLiFoPushRequest{}
-	mi := &file_hydrakv_proto_msgTypes[11]
+func (x *FiFoLiFoBPopRequest) Reset() {
+	*x = FiFoLiFoBPopRequest{}
+	mi := &file_hydrakv_proto_msgTypes[39]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FiFoLiFoPushRequest) String() string {
+func (x *FiFoLiFoBPopRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FiFoLiFoPushRequest) ProtoMessage() {}
+func (*FiFoLiFoBPopRequest) ProtoMessage() {}
 
-func (x *FiFoLiFoPushRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_hydrakv_proto_msgTypes[11]
+func (x *FiFoLiFoBPopRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[39]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -677,63 +2549,65 @@ func (x *FiFoLiFoPushRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FiFoLiFoPushRequest.ProtoReflect.Descriptor instead.
-func (*FiFoLiFoPushRequest) Descriptor() ([]byte, []int) {
-	return file_hydrakv_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use FiFoLiFoBPopRequest.ProtoReflect.Descriptor instead.
+func (*FiFoLiFoBPopRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{39}
 }
 
-func (x *FiFoLiFoPushRequest) GetName() string {
+func (x *FiFoLiFoBPopRequest) GetName() string {
 	if x != nil {
 		return x.Name
 	}
 	return ""
 }
 
-func (x *FiFoLiFoPushRequest) GetValue() string {
+func (x *FiFoLiFoBPopRequest) GetDb() string {
 	if x != nil {
-		return x.Value
+		return x.Db
 	}
 	return ""
 }
 
-func (x *FiFoLiFoPushRequest) GetDb() string {
+func (x *FiFoLiFoBPopRequest) GetApikey() string {
 	if x != nil {
-		return x.Db
+		return x.Apikey
 	}
 	return ""
 }
 
-func (x *FiFoLiFoPushRequest) GetApikey() string {
+func (x *FiFoLiFoBPopRequest) GetTimeoutMs() int64 {
 	if x != nil {
-		return x.Apikey
+		return x.TimeoutMs
 	}
-	return ""
+	return 0
 }
 
-type FiFoLiFoPopRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Db            string                 `protobuf:"bytes,2,opt,name=db,proto3" json:"db,omitempty"`
-	Apikey        string                 `protobuf:"bytes,3,opt,name=Apikey,proto3" json:"Apikey,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type FiFoLiFoMoveRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Src            string                 `protobuf:"bytes,1,opt,name=src,proto3" json:"src,omitempty"`
+	Dst            string                 `protobuf:"bytes,2,opt,name=dst,proto3" json:"dst,omitempty"`
+	Db             string                 `protobuf:"bytes,3,opt,name=db,proto3" json:"db,omitempty"`
+	Apikey         string                 `protobuf:"bytes,4,opt,name=Apikey,proto3" json:"Apikey,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,5,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
-func (x *FiFoLiFoPopRequest) Reset() {
-	*x = FiFoLiFoPopRequest{}
-	mi := &file_hydrakv_proto_msgTypes[12]
+func (x *FiFoLiFoMoveRequest) Reset() {
+	*x = FiFoLiFoMoveRequest{}
+	mi := &file_hydrakv_proto_msgTypes[40]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FiFoLiFoPopRequest) String() string {
+func (x *FiFoLiFoMoveRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FiFoLiFoPopRequest) ProtoMessage() {}
+func (*FiFoLiFoMoveRequest) ProtoMessage() {}
 
-func (x *FiFoLiFoPopRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_hydrakv_proto_msgTypes[12]
+func (x *FiFoLiFoMoveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[40]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -744,32 +2618,46 @@ func (x *FiFoLiFoPopRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FiFoLiFoPopRequest.ProtoReflect.Descriptor instead.
-func (*FiFoLiFoPopRequest) Descriptor() ([]byte, []int) {
-	return file_hydrakv_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use FiFoLiFoMoveRequest.ProtoReflect.Descriptor instead.
+func (*FiFoLiFoMoveRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{40}
 }
 
-func (x *FiFoLiFoPopRequest) GetName() string {
+func (x *FiFoLiFoMoveRequest) GetSrc() string {
 	if x != nil {
-		return x.Name
+		return x.Src
 	}
 	return ""
 }
 
-func (x *FiFoLiFoPopRequest) GetDb() string {
+func (x *FiFoLiFoMoveRequest) GetDst() string {
+	if x != nil {
+		return x.Dst
+	}
+	return ""
+}
+
+func (x *FiFoLiFoMoveRequest) GetDb() string {
 	if x != nil {
 		return x.Db
 	}
 	return ""
 }
 
-func (x *FiFoLiFoPopRequest) GetApikey() string {
+func (x *FiFoLiFoMoveRequest) GetApikey() string {
 	if x != nil {
 		return x.Apikey
 	}
 	return ""
 }
 
+func (x *FiFoLiFoMoveRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
 type FiFoLiFoPopResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
@@ -781,7 +2669,7 @@ type FiFoLiFoPopResponse struct {
 
 func (x *FiFoLiFoPopResponse) Reset() {
 	*x = FiFoLiFoPopResponse{}
-	mi := &file_hydrakv_proto_msgTypes[13]
+	mi := &file_hydrakv_proto_msgTypes[41]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -793,7 +2681,7 @@ func (x *FiFoLiFoPopResponse) String() string {
 func (*FiFoLiFoPopResponse) ProtoMessage() {}
 
 func (x *FiFoLiFoPopResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_hydrakv_proto_msgTypes[13]
+	mi := &file_hydrakv_proto_msgTypes[41]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -806,7 +2694,7 @@ func (x *FiFoLiFoPopResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FiFoLiFoPopResponse.ProtoReflect.Descriptor instead.
 func (*FiFoLiFoPopResponse) Descriptor() ([]byte, []int) {
-	return file_hydrakv_proto_rawDescGZIP(), []int{13}
+	return file_hydrakv_proto_rawDescGZIP(), []int{41}
 }
 
 func (x *FiFoLiFoPopResponse) GetValue() string {
@@ -830,6 +2718,206 @@ func (x *FiFoLiFoPopResponse) GetApikey() string {
 	return ""
 }
 
+type CounterResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Value         int64                  `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CounterResponse) Reset() {
+	*x = CounterResponse{}
+	mi := &file_hydrakv_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CounterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CounterResponse) ProtoMessage() {}
+
+func (x *CounterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CounterResponse.ProtoReflect.Descriptor instead.
+func (*CounterResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *CounterResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *CounterResponse) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+type BitResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Bit           int32                  `protobuf:"varint,2,opt,name=bit,proto3" json:"bit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BitResponse) Reset() {
+	*x = BitResponse{}
+	mi := &file_hydrakv_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BitResponse) ProtoMessage() {}
+
+func (x *BitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BitResponse.ProtoReflect.Descriptor instead.
+func (*BitResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *BitResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *BitResponse) GetBit() int32 {
+	if x != nil {
+		return x.Bit
+	}
+	return 0
+}
+
+type FlushAllRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AdminKey      string                 `protobuf:"bytes,1,opt,name=admin_key,json=adminKey,proto3" json:"admin_key,omitempty"`
+	Drop          bool                   `protobuf:"varint,2,opt,name=drop,proto3" json:"drop,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FlushAllRequest) Reset() {
+	*x = FlushAllRequest{}
+	mi := &file_hydrakv_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FlushAllRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlushAllRequest) ProtoMessage() {}
+
+func (x *FlushAllRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlushAllRequest.ProtoReflect.Descriptor instead.
+func (*FlushAllRequest) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *FlushAllRequest) GetAdminKey() string {
+	if x != nil {
+		return x.AdminKey
+	}
+	return ""
+}
+
+func (x *FlushAllRequest) GetDrop() bool {
+	if x != nil {
+		return x.Drop
+	}
+	return false
+}
+
+type FlushAllResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dbs           int32                  `protobuf:"varint,1,opt,name=dbs,proto3" json:"dbs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FlushAllResponse) Reset() {
+	*x = FlushAllResponse{}
+	mi := &file_hydrakv_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FlushAllResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlushAllResponse) ProtoMessage() {}
+
+func (x *FlushAllResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hydrakv_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlushAllResponse.ProtoReflect.Descriptor instead.
+func (*FlushAllResponse) Descriptor() ([]byte, []int) {
+	return file_hydrakv_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *FlushAllResponse) GetDbs() int32 {
+	if x != nil {
+		return x.Dbs
+	}
+	return 0
+}
+
 type HealthResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
@@ -839,7 +2927,7 @@ type HealthResponse struct {
 
 func (x *HealthResponse) Reset() {
 	*x = HealthResponse{}
-	mi := &file_hydrakv_proto_msgTypes[14]
+	mi := &file_hydrakv_proto_msgTypes[46]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -851,7 +2939,7 @@ func (x *HealthResponse) String() string {
 func (*HealthResponse) ProtoMessage() {}
 
 func (x *HealthResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_hydrakv_proto_msgTypes[14]
+	mi := &file_hydrakv_proto_msgTypes[46]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -864,7 +2952,7 @@ func (x *HealthResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
 func (*HealthResponse) Descriptor() ([]byte, []int) {
-	return file_hydrakv_proto_rawDescGZIP(), []int{14}
+	return file_hydrakv_proto_rawDescGZIP(), []int{46}
 }
 
 func (x *HealthResponse) GetStatus() string {
@@ -880,33 +2968,134 @@ const file_hydrakv_proto_rawDesc = "" +
 	"\n" +
 	"\rhydrakv.proto\x12\x02kv\x1a\x1bgoogle/protobuf/empty.proto\"%\n" +
 	"\x0fCreateDBRequest\x12\x12\n" +
-	"\x04name\x18\x01 \x01(\tR\x04name\"n\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"\xb1\x01\n" +
 	"\n" +
 	"SetRequest\x12\x0e\n" +
 	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
 	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
 	"\x03ttl\x18\x03 \x01(\x03R\x03ttl\x12\x10\n" +
 	"\x03key\x18\x04 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x05 \x01(\tR\x05value\"F\n" +
+	"\x05value\x18\x05 \x01(\tR\x05value\x12\x18\n" +
+	"\akeepttl\x18\x06 \x01(\bR\akeepttl\x12'\n" +
+	"\x0fidempotency_key\x18\a \x01(\tR\x0eidempotencyKey\"F\n" +
 	"\n" +
 	"GetRequest\x12\x0e\n" +
 	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
 	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
-	"\x03key\x18\x03 \x01(\tR\x03key\"I\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\"r\n" +
 	"\rDeleteRequest\x12\x0e\n" +
 	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
 	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
-	"\x03key\x18\x03 \x01(\tR\x03key\"_\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12'\n" +
+	"\x0fidempotency_key\x18\x04 \x01(\tR\x0eidempotencyKey\"\x88\x01\n" +
 	"\vIncrRequest\x12\x0e\n" +
 	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
 	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
 	"\x03key\x18\x03 \x01(\tR\x03key\x12\x16\n" +
-	"\x06amount\x18\x04 \x01(\tR\x06amount\"\x1f\n" +
+	"\x06amount\x18\x04 \x01(\tR\x06amount\x12'\n" +
+	"\x0fidempotency_key\x18\x05 \x01(\tR\x0eidempotencyKey\"\x9d\x01\n" +
+	"\x0eCounterRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x16\n" +
+	"\x06amount\x18\x04 \x01(\x03R\x06amount\x12\x10\n" +
+	"\x03ttl\x18\x05 \x01(\x03R\x03ttl\x12'\n" +
+	"\x0fidempotency_key\x18\x06 \x01(\tR\x0eidempotencyKey\"\xa0\x01\n" +
+	"\x13IncrAndCheckRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x03R\x05limit\x12\x10\n" +
+	"\x03ttl\x18\x05 \x01(\x03R\x03ttl\x12'\n" +
+	"\x0fidempotency_key\x18\x06 \x01(\tR\x0eidempotencyKey\"F\n" +
+	"\x14IncrAndCheckResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\"\x9c\x01\n" +
+	"\rSetBitRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x03R\x06offset\x12\x10\n" +
+	"\x03bit\x18\x05 \x01(\x05R\x03bit\x12'\n" +
+	"\x0fidempotency_key\x18\x06 \x01(\tR\x0eidempotencyKey\"a\n" +
+	"\rGetBitRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x03R\x06offset\"\x9c\x01\n" +
+	"\vHSetRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x14\n" +
+	"\x05field\x18\x04 \x01(\tR\x05field\x12\x14\n" +
+	"\x05value\x18\x05 \x01(\tR\x05value\x12'\n" +
+	"\x0fidempotency_key\x18\x06 \x01(\tR\x0eidempotencyKey\"]\n" +
+	"\vHGetRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x14\n" +
+	"\x05field\x18\x04 \x01(\tR\x05field\"J\n" +
+	"\x0eHGetAllRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\"\x86\x01\n" +
+	"\vHDelRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x14\n" +
+	"\x05field\x18\x04 \x01(\tR\x05field\x12'\n" +
+	"\x0fidempotency_key\x18\x05 \x01(\tR\x0eidempotencyKey\"\x8b\x01\n" +
+	"\x0eSMemberRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x16\n" +
+	"\x06member\x18\x04 \x01(\tR\x06member\x12'\n" +
+	"\x0fidempotency_key\x18\x05 \x01(\tR\x0eidempotencyKey\"G\n" +
+	"\vSKeyRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\"\x9e\x01\n" +
+	"\vZAddRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x16\n" +
+	"\x06member\x18\x04 \x01(\tR\x06member\x12\x14\n" +
+	"\x05score\x18\x05 \x01(\x01R\x05score\x12'\n" +
+	"\x0fidempotency_key\x18\x06 \x01(\tR\x0eidempotencyKey\"b\n" +
+	"\x0eZMemberRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x16\n" +
+	"\x06member\x18\x04 \x01(\tR\x06member\"s\n" +
+	"\rZRangeRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x14\n" +
+	"\x05start\x18\x04 \x01(\x03R\x05start\x12\x12\n" +
+	"\x04stop\x18\x05 \x01(\x03R\x04stop\"\x8a\x01\n" +
+	"\x0fListPushRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\tR\x05value\x12'\n" +
+	"\x0fidempotency_key\x18\x05 \x01(\tR\x0eidempotencyKey\"s\n" +
+	"\x0eListKeyRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12'\n" +
+	"\x0fidempotency_key\x18\x04 \x01(\tR\x0eidempotencyKey\"v\n" +
+	"\x10ListRangeRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x14\n" +
+	"\x05start\x18\x04 \x01(\x03R\x05start\x12\x12\n" +
+	"\x04stop\x18\x05 \x01(\x03R\x04stop\"\x1f\n" +
 	"\rExistsRequest\x12\x0e\n" +
-	"\x02db\x18\x01 \x01(\tR\x02db\"\x1c\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\":\n" +
 	"\n" +
 	"OKResponse\x12\x0e\n" +
-	"\x02ok\x18\x01 \x01(\bR\x02ok\"p\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x1c\n" +
+	"\ttruncated\x18\x02 \x01(\bR\ttruncated\"p\n" +
 	"\x10CreateDBResponse\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
 	"\x06apikey\x18\x02 \x01(\tR\x06apikey\x12\x18\n" +
@@ -914,40 +3103,121 @@ const file_hydrakv_proto_rawDesc = "" +
 	"\x06exists\x18\x04 \x01(\bR\x06exists\"9\n" +
 	"\vGetResponse\x12\x14\n" +
 	"\x05found\x18\x01 \x01(\bR\x05found\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value\"(\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"\x9b\x01\n" +
+	"\x0fHGetAllResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\x127\n" +
+	"\x06fields\x18\x02 \x03(\v2\x1f.kv.HGetAllResponse.FieldsEntryR\x06fields\x1a9\n" +
+	"\vFieldsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"B\n" +
+	"\x10SMembersResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\x12\x18\n" +
+	"\amembers\x18\x02 \x03(\tR\amembers\"#\n" +
+	"\rSCardResponse\x12\x12\n" +
+	"\x04card\x18\x01 \x01(\x03R\x04card\"<\n" +
+	"\x0eZScoreResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x01R\x05score\"9\n" +
+	"\rZRankResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\x12\x12\n" +
+	"\x04rank\x18\x02 \x01(\x03R\x04rank\"7\n" +
+	"\aZMember\x12\x16\n" +
+	"\x06member\x18\x01 \x01(\tR\x06member\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x01R\x05score\"M\n" +
+	"\x0eZRangeResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\x12%\n" +
+	"\amembers\x18\x02 \x03(\v2\v.kv.ZMemberR\amembers\"A\n" +
+	"\x11ListRangeResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\x12\x16\n" +
+	"\x06values\x18\x02 \x03(\tR\x06values\" \n" +
+	"\fLLenResponse\x12\x10\n" +
+	"\x03len\x18\x01 \x01(\x03R\x03len\"(\n" +
 	"\x0eExistsResponse\x12\x16\n" +
 	"\x06exists\x18\x01 \x01(\bR\x06exists\"S\n" +
 	"\x15FiFoLiFoDeleteRequest\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x0e\n" +
 	"\x02db\x18\x02 \x01(\tR\x02db\x12\x16\n" +
-	"\x06Apikey\x18\x03 \x01(\tR\x06Apikey\"g\n" +
+	"\x06Apikey\x18\x03 \x01(\tR\x06Apikey\"\x90\x01\n" +
 	"\x13FiFoLiFoPushRequest\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value\x12\x0e\n" +
 	"\x02db\x18\x03 \x01(\tR\x02db\x12\x16\n" +
-	"\x06Apikey\x18\x04 \x01(\tR\x06Apikey\"P\n" +
+	"\x06Apikey\x18\x04 \x01(\tR\x06Apikey\x12'\n" +
+	"\x0fidempotency_key\x18\x05 \x01(\tR\x0eidempotencyKey\"P\n" +
 	"\x12FiFoLiFoPopRequest\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x0e\n" +
 	"\x02db\x18\x02 \x01(\tR\x02db\x12\x16\n" +
-	"\x06Apikey\x18\x03 \x01(\tR\x06Apikey\"S\n" +
+	"\x06Apikey\x18\x03 \x01(\tR\x06Apikey\"p\n" +
+	"\x13FiFoLiFoBPopRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x0e\n" +
+	"\x02db\x18\x02 \x01(\tR\x02db\x12\x16\n" +
+	"\x06Apikey\x18\x03 \x01(\tR\x06Apikey\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x04 \x01(\x03R\ttimeoutMs\"\x8a\x01\n" +
+	"\x13FiFoLiFoMoveRequest\x12\x10\n" +
+	"\x03src\x18\x01 \x01(\tR\x03src\x12\x10\n" +
+	"\x03dst\x18\x02 \x01(\tR\x03dst\x12\x0e\n" +
+	"\x02db\x18\x03 \x01(\tR\x02db\x12\x16\n" +
+	"\x06Apikey\x18\x04 \x01(\tR\x06Apikey\x12'\n" +
+	"\x0fidempotency_key\x18\x05 \x01(\tR\x0eidempotencyKey\"S\n" +
 	"\x13FiFoLiFoPopResponse\x12\x14\n" +
 	"\x05value\x18\x01 \x01(\tR\x05value\x12\x0e\n" +
 	"\x02db\x18\x02 \x01(\tR\x02db\x12\x16\n" +
-	"\x06Apikey\x18\x03 \x01(\tR\x06Apikey\"(\n" +
+	"\x06Apikey\x18\x03 \x01(\tR\x06Apikey\"7\n" +
+	"\x0fCounterResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value\"/\n" +
+	"\vBitResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x10\n" +
+	"\x03bit\x18\x02 \x01(\x05R\x03bit\"B\n" +
+	"\x0fFlushAllRequest\x12\x1b\n" +
+	"\tadmin_key\x18\x01 \x01(\tR\badminKey\x12\x12\n" +
+	"\x04drop\x18\x02 \x01(\bR\x04drop\"$\n" +
+	"\x10FlushAllResponse\x12\x10\n" +
+	"\x03dbs\x18\x01 \x01(\x05R\x03dbs\"(\n" +
 	"\x0eHealthResponse\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\tR\x06status2\xef\x04\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status2\x86\x10\n" +
 	"\tKVService\x125\n" +
 	"\bCreateDB\x12\x13.kv.CreateDBRequest\x1a\x14.kv.CreateDBResponse\x12%\n" +
 	"\x03Set\x12\x0e.kv.SetRequest\x1a\x0e.kv.OKResponse\x12'\n" +
-	"\x05SetNX\x12\x0e.kv.SetRequest\x1a\x0e.kv.OKResponse\x12'\n" +
+	"\x05SetNX\x12\x0e.kv.SetRequest\x1a\x0e.kv.OKResponse\x12.\n" +
+	"\fSetIfGreater\x12\x0e.kv.SetRequest\x1a\x0e.kv.OKResponse\x12+\n" +
+	"\tSetIfLess\x12\x0e.kv.SetRequest\x1a\x0e.kv.OKResponse\x12'\n" +
 	"\x04Incr\x12\x0f.kv.IncrRequest\x1a\x0e.kv.OKResponse\x12&\n" +
-	"\x03Get\x12\x0e.kv.GetRequest\x1a\x0f.kv.GetResponse\x12+\n" +
-	"\x06Delete\x12\x11.kv.DeleteRequest\x1a\x0e.kv.OKResponse\x12/\n" +
+	"\x03Get\x12\x0e.kv.GetRequest\x1a\x0f.kv.GetResponse\x126\n" +
+	"\vCounterIncr\x12\x12.kv.CounterRequest\x1a\x13.kv.CounterResponse\x126\n" +
+	"\vCounterDecr\x12\x12.kv.CounterRequest\x1a\x13.kv.CounterResponse\x12A\n" +
+	"\fIncrAndCheck\x12\x17.kv.IncrAndCheckRequest\x1a\x18.kv.IncrAndCheckResponse\x12,\n" +
+	"\x06SetBit\x12\x11.kv.SetBitRequest\x1a\x0f.kv.BitResponse\x12,\n" +
+	"\x06GetBit\x12\x11.kv.GetBitRequest\x1a\x0f.kv.BitResponse\x12+\n" +
+	"\x06Delete\x12\x11.kv.DeleteRequest\x1a\x0e.kv.OKResponse\x12'\n" +
+	"\x04HSet\x12\x0f.kv.HSetRequest\x1a\x0e.kv.OKResponse\x12(\n" +
+	"\x04HGet\x12\x0f.kv.HGetRequest\x1a\x0f.kv.GetResponse\x122\n" +
+	"\aHGetAll\x12\x12.kv.HGetAllRequest\x1a\x13.kv.HGetAllResponse\x12'\n" +
+	"\x04HDel\x12\x0f.kv.HDelRequest\x1a\x0e.kv.OKResponse\x12*\n" +
+	"\x04SAdd\x12\x12.kv.SMemberRequest\x1a\x0e.kv.OKResponse\x12*\n" +
+	"\x04SRem\x12\x12.kv.SMemberRequest\x1a\x0e.kv.OKResponse\x12/\n" +
+	"\tSIsMember\x12\x12.kv.SMemberRequest\x1a\x0e.kv.OKResponse\x121\n" +
+	"\bSMembers\x12\x0f.kv.SKeyRequest\x1a\x14.kv.SMembersResponse\x12+\n" +
+	"\x05SCard\x12\x0f.kv.SKeyRequest\x1a\x11.kv.SCardResponse\x12'\n" +
+	"\x04ZAdd\x12\x0f.kv.ZAddRequest\x1a\x0e.kv.OKResponse\x120\n" +
+	"\x06ZScore\x12\x12.kv.ZMemberRequest\x1a\x12.kv.ZScoreResponse\x12.\n" +
+	"\x05ZRank\x12\x12.kv.ZMemberRequest\x1a\x11.kv.ZRankResponse\x12/\n" +
+	"\x06ZRange\x12\x11.kv.ZRangeRequest\x1a\x12.kv.ZRangeResponse\x12,\n" +
+	"\x05LPush\x12\x13.kv.ListPushRequest\x1a\x0e.kv.OKResponse\x12,\n" +
+	"\x05RPush\x12\x13.kv.ListPushRequest\x1a\x0e.kv.OKResponse\x12+\n" +
+	"\x04LPop\x12\x12.kv.ListKeyRequest\x1a\x0f.kv.GetResponse\x12+\n" +
+	"\x04RPop\x12\x12.kv.ListKeyRequest\x1a\x0f.kv.GetResponse\x125\n" +
+	"\x06LRange\x12\x14.kv.ListRangeRequest\x1a\x15.kv.ListRangeResponse\x12,\n" +
+	"\x04LLen\x12\x12.kv.ListKeyRequest\x1a\x10.kv.LLenResponse\x12/\n" +
 	"\x06Exists\x12\x11.kv.ExistsRequest\x1a\x12.kv.ExistsResponse\x12;\n" +
 	"\x0eFiFoLiFoDelete\x12\x19.kv.FiFoLiFoDeleteRequest\x1a\x0e.kv.OKResponse\x127\n" +
 	"\fFiFoLiFoPush\x12\x17.kv.FiFoLiFoPushRequest\x1a\x0e.kv.OKResponse\x12?\n" +
 	"\fFiFoLiFoFPop\x12\x16.kv.FiFoLiFoPopRequest\x1a\x17.kv.FiFoLiFoPopResponse\x12?\n" +
-	"\fFiFoLiFoLPop\x12\x16.kv.FiFoLiFoPopRequest\x1a\x17.kv.FiFoLiFoPopResponse\x124\n" +
+	"\fFiFoLiFoLPop\x12\x16.kv.FiFoLiFoPopRequest\x1a\x17.kv.FiFoLiFoPopResponse\x12@\n" +
+	"\fFiFoLiFoBPop\x12\x17.kv.FiFoLiFoBPopRequest\x1a\x17.kv.FiFoLiFoPopResponse\x12@\n" +
+	"\fFiFoLiFoMove\x12\x17.kv.FiFoLiFoMoveRequest\x1a\x17.kv.FiFoLiFoPopResponse\x125\n" +
+	"\bFlushAll\x12\x13.kv.FlushAllRequest\x1a\x14.kv.FlushAllResponse\x124\n" +
 	"\x06Health\x12\x16.google.protobuf.Empty\x1a\x12.kv.HealthResponseB(Z&hydrakv/server/hydrakv/proto/kvpb;kvpbb\x06proto3"
 
 var (
@@ -962,55 +3232,148 @@ func file_hydrakv_proto_rawDescGZIP() []byte {
 	return file_hydrakv_proto_rawDescData
 }
 
-var file_hydrakv_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_hydrakv_proto_msgTypes = make([]protoimpl.MessageInfo, 48)
 var file_hydrakv_proto_goTypes = []any{
 	(*CreateDBRequest)(nil),       // 0: kv.CreateDBRequest
 	(*SetRequest)(nil),            // 1: kv.SetRequest
 	(*GetRequest)(nil),            // 2: kv.GetRequest
 	(*DeleteRequest)(nil),         // 3: kv.DeleteRequest
 	(*IncrRequest)(nil),           // 4: kv.IncrRequest
-	(*ExistsRequest)(nil),         // 5: kv.ExistsRequest
-	(*OKResponse)(nil),            // 6: kv.OKResponse
-	(*CreateDBResponse)(nil),      // 7: kv.CreateDBResponse
-	(*GetResponse)(nil),           // 8: kv.GetResponse
-	(*ExistsResponse)(nil),        // 9: kv.ExistsResponse
-	(*FiFoLiFoDeleteRequest)(nil), // 10: kv.FiFoLiFoDeleteRequest
-	(*FiFoLiFoPushRequest)(nil),   // 11: kv.FiFoLiFoPushRequest
-	(*FiFoLiFoPopRequest)(nil),    // 12: kv.FiFoLiFoPopRequest
-	(*FiFoLiFoPopResponse)(nil),   // 13: kv.FiFoLiFoPopResponse
-	(*HealthResponse)(nil),        // 14: kv.HealthResponse
-	(*emptypb.Empty)(nil),         // 15: google.protobuf.Empty
+	(*CounterRequest)(nil),        // 5: kv.CounterRequest
+	(*IncrAndCheckRequest)(nil),   // 6: kv.IncrAndCheckRequest
+	(*IncrAndCheckResponse)(nil),  // 7: kv.IncrAndCheckResponse
+	(*SetBitRequest)(nil),         // 8: kv.SetBitRequest
+	(*GetBitRequest)(nil),         // 9: kv.GetBitRequest
+	(*HSetRequest)(nil),           // 10: kv.HSetRequest
+	(*HGetRequest)(nil),           // 11: kv.HGetRequest
+	(*HGetAllRequest)(nil),        // 12: kv.HGetAllRequest
+	(*HDelRequest)(nil),           // 13: kv.HDelRequest
+	(*SMemberRequest)(nil),        // 14: kv.SMemberRequest
+	(*SKeyRequest)(nil),           // 15: kv.SKeyRequest
+	(*ZAddRequest)(nil),           // 16: kv.ZAddRequest
+	(*ZMemberRequest)(nil),        // 17: kv.ZMemberRequest
+	(*ZRangeRequest)(nil),         // 18: kv.ZRangeRequest
+	(*ListPushRequest)(nil),       // 19: kv.ListPushRequest
+	(*ListKeyRequest)(nil),        // 20: kv.ListKeyRequest
+	(*ListRangeRequest)(nil),      // 21: kv.ListRangeRequest
+	(*ExistsRequest)(nil),         // 22: kv.ExistsRequest
+	(*OKResponse)(nil),            // 23: kv.OKResponse
+	(*CreateDBResponse)(nil),      // 24: kv.CreateDBResponse
+	(*GetResponse)(nil),           // 25: kv.GetResponse
+	(*HGetAllResponse)(nil),       // 26: kv.HGetAllResponse
+	(*SMembersResponse)(nil),      // 27: kv.SMembersResponse
+	(*SCardResponse)(nil),         // 28: kv.SCardResponse
+	(*ZScoreResponse)(nil),        // 29: kv.ZScoreResponse
+	(*ZRankResponse)(nil),         // 30: kv.ZRankResponse
+	(*ZMember)(nil),               // 31: kv.ZMember
+	(*ZRangeResponse)(nil),        // 32: kv.ZRangeResponse
+	(*ListRangeResponse)(nil),     // 33: kv.ListRangeResponse
+	(*LLenResponse)(nil),          // 34: kv.LLenResponse
+	(*ExistsResponse)(nil),        // 35: kv.ExistsResponse
+	(*FiFoLiFoDeleteRequest)(nil), // 36: kv.FiFoLiFoDeleteRequest
+	(*FiFoLiFoPushRequest)(nil),   // 37: kv.FiFoLiFoPushRequest
+	(*FiFoLiFoPopRequest)(nil),    // 38: kv.FiFoLiFoPopRequest
+	(*FiFoLiFoBPopRequest)(nil),   // 39: kv.FiFoLiFoBPopRequest
+	(*FiFoLiFoMoveRequest)(nil),   // 40: kv.FiFoLiFoMoveRequest
+	(*FiFoLiFoPopResponse)(nil),   // 41: kv.FiFoLiFoPopResponse
+	(*CounterResponse)(nil),       // 42: kv.CounterResponse
+	(*BitResponse)(nil),           // 43: kv.BitResponse
+	(*FlushAllRequest)(nil),       // 44: kv.FlushAllRequest
+	(*FlushAllResponse)(nil),      // 45: kv.FlushAllResponse
+	(*HealthResponse)(nil),        // 46: kv.HealthResponse
+	nil,                           // 47: kv.HGetAllResponse.FieldsEntry
+	(*emptypb.Empty)(nil),         // 48: google.protobuf.Empty
 }
 var file_hydrakv_proto_depIdxs = []int32{
-	0,  // 0: kv.KVService.CreateDB:input_type -> kv.CreateDBRequest
-	1,  // 1: kv.KVService.Set:input_type -> kv.SetRequest
-	1,  // 2: kv.KVService.SetNX:input_type -> kv.SetRequest
-	4,  // 3: kv.KVService.Incr:input_type -> kv.IncrRequest
-	2,  // 4: kv.KVService.Get:input_type -> kv.GetRequest
-	3,  // 5: kv.KVService.Delete:input_type -> kv.DeleteRequest
-	5,  // 6: kv.KVService.Exists:input_type -> kv.ExistsRequest
-	10, // 7: kv.KVService.FiFoLiFoDelete:input_type -> kv.FiFoLiFoDeleteRequest
-	11, // 8: kv.KVService.FiFoLiFoPush:input_type -> kv.FiFoLiFoPushRequest
-	12, // 9: kv.KVService.FiFoLiFoFPop:input_type -> kv.FiFoLiFoPopRequest
-	12, // 10: kv.KVService.FiFoLiFoLPop:input_type -> kv.FiFoLiFoPopRequest
-	15, // 11: kv.KVService.Health:input_type -> google.protobuf.Empty
-	7,  // 12: kv.KVService.CreateDB:output_type -> kv.CreateDBResponse
-	6,  // 13: kv.KVService.Set:output_type -> kv.OKResponse
-	6,  // 14: kv.KVService.SetNX:output_type -> kv.OKResponse
-	6,  // 15: kv.KVService.Incr:output_type -> kv.OKResponse
-	8,  // 16: kv.KVService.Get:output_type -> kv.GetResponse
-	6,  // 17: kv.KVService.Delete:output_type -> kv.OKResponse
-	9,  // 18: kv.KVService.Exists:output_type -> kv.ExistsResponse
-	6,  // 19: kv.KVService.FiFoLiFoDelete:output_type -> kv.OKResponse
-	6,  // 20: kv.KVService.FiFoLiFoPush:output_type -> kv.OKResponse
-	13, // 21: kv.KVService.FiFoLiFoFPop:output_type -> kv.FiFoLiFoPopResponse
-	13, // 22: kv.KVService.FiFoLiFoLPop:output_type -> kv.FiFoLiFoPopResponse
-	14, // 23: kv.KVService.Health:output_type -> kv.HealthResponse
-	12, // [12:24] is the sub-list for method output_type
-	0,  // [0:12] is the sub-list for method input_type
-	0,  // [0:0] is the sub-list for extension type_name
-	0,  // [0:0] is the sub-list for extension extendee
-	0,  // [0:0] is the sub-list for field type_name
+	47, // 0: kv.HGetAllResponse.fields:type_name -> kv.HGetAllResponse.FieldsEntry
+	31, // 1: kv.ZRangeResponse.members:type_name -> kv.ZMember
+	0,  // 2: kv.KVService.CreateDB:input_type -> kv.CreateDBRequest
+	1,  // 3: kv.KVService.Set:input_type -> kv.SetRequest
+	1,  // 4: kv.KVService.SetNX:input_type -> kv.SetRequest
+	1,  // 5: kv.KVService.SetIfGreater:input_type -> kv.SetRequest
+	1,  // 6: kv.KVService.SetIfLess:input_type -> kv.SetRequest
+	4,  // 7: kv.KVService.Incr:input_type -> kv.IncrRequest
+	2,  // 8: kv.KVService.Get:input_type -> kv.GetRequest
+	5,  // 9: kv.KVService.CounterIncr:input_type -> kv.CounterRequest
+	5,  // 10: kv.KVService.CounterDecr:input_type -> kv.CounterRequest
+	6,  // 11: kv.KVService.IncrAndCheck:input_type -> kv.IncrAndCheckRequest
+	8,  // 12: kv.KVService.SetBit:input_type -> kv.SetBitRequest
+	9,  // 13: kv.KVService.GetBit:input_type -> kv.GetBitRequest
+	3,  // 14: kv.KVService.Delete:input_type -> kv.DeleteRequest
+	10, // 15: kv.KVService.HSet:input_type -> kv.HSetRequest
+	11, // 16: kv.KVService.HGet:input_type -> kv.HGetRequest
+	12, // 17: kv.KVService.HGetAll:input_type -> kv.HGetAllRequest
+	13, // 18: kv.KVService.HDel:input_type -> kv.HDelRequest
+	14, // 19: kv.KVService.SAdd:input_type -> kv.SMemberRequest
+	14, // 20: kv.KVService.SRem:input_type -> kv.SMemberRequest
+	14, // 21: kv.KVService.SIsMember:input_type -> kv.SMemberRequest
+	15, // 22: kv.KVService.SMembers:input_type -> kv.SKeyRequest
+	15, // 23: kv.KVService.SCard:input_type -> kv.SKeyRequest
+	16, // 24: kv.KVService.ZAdd:input_type -> kv.ZAddRequest
+	17, // 25: kv.KVService.ZScore:input_type -> kv.ZMemberRequest
+	17, // 26: kv.KVService.ZRank:input_type -> kv.ZMemberRequest
+	18, // 27: kv.KVService.ZRange:input_type -> kv.ZRangeRequest
+	19, // 28: kv.KVService.LPush:input_type -> kv.ListPushRequest
+	19, // 29: kv.KVService.RPush:input_type -> kv.ListPushRequest
+	20, // 30: kv.KVService.LPop:input_type -> kv.ListKeyRequest
+	20, // 31: kv.KVService.RPop:input_type -> kv.ListKeyRequest
+	21, // 32: kv.KVService.LRange:input_type -> kv.ListRangeRequest
+	20, // 33: kv.KVService.LLen:input_type -> kv.ListKeyRequest
+	22, // 34: kv.KVService.Exists:input_type -> kv.ExistsRequest
+	36, // 35: kv.KVService.FiFoLiFoDelete:input_type -> kv.FiFoLiFoDeleteRequest
+	37, // 36: kv.KVService.FiFoLiFoPush:input_type -> kv.FiFoLiFoPushRequest
+	38, // 37: kv.KVService.FiFoLiFoFPop:input_type -> kv.FiFoLiFoPopRequest
+	38, // 38: kv.KVService.FiFoLiFoLPop:input_type -> kv.FiFoLiFoPopRequest
+	39, // 39: kv.KVService.FiFoLiFoBPop:input_type -> kv.FiFoLiFoBPopRequest
+	40, // 40: kv.KVService.FiFoLiFoMove:input_type -> kv.FiFoLiFoMoveRequest
+	44, // 41: kv.KVService.FlushAll:input_type -> kv.FlushAllRequest
+	48, // 42: kv.KVService.Health:input_type -> google.protobuf.Empty
+	24, // 43: kv.KVService.CreateDB:output_type -> kv.CreateDBResponse
+	23, // 44: kv.KVService.Set:output_type -> kv.OKResponse
+	23, // 45: kv.KVService.SetNX:output_type -> kv.OKResponse
+	23, // 46: kv.KVService.SetIfGreater:output_type -> kv.OKResponse
+	23, // 47: kv.KVService.SetIfLess:output_type -> kv.OKResponse
+	23, // 48: kv.KVService.Incr:output_type -> kv.OKResponse
+	25, // 49: kv.KVService.Get:output_type -> kv.GetResponse
+	42, // 50: kv.KVService.CounterIncr:output_type -> kv.CounterResponse
+	42, // 51: kv.KVService.CounterDecr:output_type -> kv.CounterResponse
+	7,  // 52: kv.KVService.IncrAndCheck:output_type -> kv.IncrAndCheckResponse
+	43, // 53: kv.KVService.SetBit:output_type -> kv.BitResponse
+	43, // 54: kv.KVService.GetBit:output_type -> kv.BitResponse
+	23, // 55: kv.KVService.Delete:output_type -> kv.OKResponse
+	23, // 56: kv.KVService.HSet:output_type -> kv.OKResponse
+	25, // 57: kv.KVService.HGet:output_type -> kv.GetResponse
+	26, // 58: kv.KVService.HGetAll:output_type -> kv.HGetAllResponse
+	23, // 59: kv.KVService.HDel:output_type -> kv.OKResponse
+	23, // 60: kv.KVService.SAdd:output_type -> kv.OKResponse
+	23, // 61: kv.KVService.SRem:output_type -> kv.OKResponse
+	23, // 62: kv.KVService.SIsMember:output_type -> kv.OKResponse
+	27, // 63: kv.KVService.SMembers:output_type -> kv.SMembersResponse
+	28, // 64: kv.KVService.SCard:output_type -> kv.SCardResponse
+	23, // 65: kv.KVService.ZAdd:output_type -> kv.OKResponse
+	29, // 66: kv.KVService.ZScore:output_type -> kv.ZScoreResponse
+	30, // 67: kv.KVService.ZRank:output_type -> kv.ZRankResponse
+	32, // 68: kv.KVService.ZRange:output_type -> kv.ZRangeResponse
+	23, // 69: kv.KVService.LPush:output_type -> kv.OKResponse
+	23, // 70: kv.KVService.RPush:output_type -> kv.OKResponse
+	25, // 71: kv.KVService.LPop:output_type -> kv.GetResponse
+	25, // 72: kv.KVService.RPop:output_type -> kv.GetResponse
+	33, // 73: kv.KVService.LRange:output_type -> kv.ListRangeResponse
+	34, // 74: kv.KVService.LLen:output_type -> kv.LLenResponse
+	35, // 75: kv.KVService.Exists:output_type -> kv.ExistsResponse
+	23, // 76: kv.KVService.FiFoLiFoDelete:output_type -> kv.OKResponse
+	23, // 77: kv.KVService.FiFoLiFoPush:output_type -> kv.OKResponse
+	41, // 78: kv.KVService.FiFoLiFoFPop:output_type -> kv.FiFoLiFoPopResponse
+	41, // 79: kv.KVService.FiFoLiFoLPop:output_type -> kv.FiFoLiFoPopResponse
+	41, // 80: kv.KVService.FiFoLiFoBPop:output_type -> kv.FiFoLiFoPopResponse
+	41, // 81: kv.KVService.FiFoLiFoMove:output_type -> kv.FiFoLiFoPopResponse
+	45, // 82: kv.KVService.FlushAll:output_type -> kv.FlushAllResponse
+	46, // 83: kv.KVService.Health:output_type -> kv.HealthResponse
+	43, // [43:84] is the sub-list for method output_type
+	2,  // [2:43] is the sub-list for method input_type
+	2,  // [2:2] is the sub-list for extension type_name
+	2,  // [2:2] is the sub-list for extension extendee
+	0,  // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_hydrakv_proto_init() }
@@ -1024,7 +3387,7 @@ func file_hydrakv_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_hydrakv_proto_rawDesc), len(file_hydrakv_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   15,
+			NumMessages:   48,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
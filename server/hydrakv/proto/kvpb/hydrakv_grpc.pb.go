@@ -23,14 +23,43 @@ const (
 	KVService_CreateDB_FullMethodName       = "/kv.KVService/CreateDB"
 	KVService_Set_FullMethodName            = "/kv.KVService/Set"
 	KVService_SetNX_FullMethodName          = "/kv.KVService/SetNX"
+	KVService_SetIfGreater_FullMethodName   = "/kv.KVService/SetIfGreater"
+	KVService_SetIfLess_FullMethodName      = "/kv.KVService/SetIfLess"
 	KVService_Incr_FullMethodName           = "/kv.KVService/Incr"
 	KVService_Get_FullMethodName            = "/kv.KVService/Get"
+	KVService_CounterIncr_FullMethodName    = "/kv.KVService/CounterIncr"
+	KVService_CounterDecr_FullMethodName    = "/kv.KVService/CounterDecr"
+	KVService_IncrAndCheck_FullMethodName   = "/kv.KVService/IncrAndCheck"
+	KVService_SetBit_FullMethodName         = "/kv.KVService/SetBit"
+	KVService_GetBit_FullMethodName         = "/kv.KVService/GetBit"
 	KVService_Delete_FullMethodName         = "/kv.KVService/Delete"
+	KVService_HSet_FullMethodName           = "/kv.KVService/HSet"
+	KVService_HGet_FullMethodName           = "/kv.KVService/HGet"
+	KVService_HGetAll_FullMethodName        = "/kv.KVService/HGetAll"
+	KVService_HDel_FullMethodName           = "/kv.KVService/HDel"
+	KVService_SAdd_FullMethodName           = "/kv.KVService/SAdd"
+	KVService_SRem_FullMethodName           = "/kv.KVService/SRem"
+	KVService_SIsMember_FullMethodName      = "/kv.KVService/SIsMember"
+	KVService_SMembers_FullMethodName       = "/kv.KVService/SMembers"
+	KVService_SCard_FullMethodName          = "/kv.KVService/SCard"
+	KVService_ZAdd_FullMethodName           = "/kv.KVService/ZAdd"
+	KVService_ZScore_FullMethodName         = "/kv.KVService/ZScore"
+	KVService_ZRank_FullMethodName          = "/kv.KVService/ZRank"
+	KVService_ZRange_FullMethodName         = "/kv.KVService/ZRange"
+	KVService_LPush_FullMethodName          = "/kv.KVService/LPush"
+	KVService_RPush_FullMethodName          = "/kv.KVService/RPush"
+	KVService_LPop_FullMethodName           = "/kv.KVService/LPop"
+	KVService_RPop_FullMethodName           = "/kv.KVService/RPop"
+	KVService_LRange_FullMethodName         = "/kv.KVService/LRange"
+	KVService_LLen_FullMethodName           = "/kv.KVService/LLen"
 	KVService_Exists_FullMethodName         = "/kv.KVService/Exists"
 	KVService_FiFoLiFoDelete_FullMethodName = "/kv.KVService/FiFoLiFoDelete"
 	KVService_FiFoLiFoPush_FullMethodName   = "/kv.KVService/FiFoLiFoPush"
 	KVService_FiFoLiFoFPop_FullMethodName   = "/kv.KVService/FiFoLiFoFPop"
 	KVService_FiFoLiFoLPop_FullMethodName   = "/kv.KVService/FiFoLiFoLPop"
+	KVService_FiFoLiFoBPop_FullMethodName   = "/kv.KVService/FiFoLiFoBPop"
+	KVService_FiFoLiFoMove_FullMethodName   = "/kv.KVService/FiFoLiFoMove"
+	KVService_FlushAll_FullMethodName       = "/kv.KVService/FlushAll"
 	KVService_Health_FullMethodName         = "/kv.KVService/Health"
 )
 
@@ -41,14 +70,43 @@ type KVServiceClient interface {
 	CreateDB(ctx context.Context, in *CreateDBRequest, opts ...grpc.CallOption) (*CreateDBResponse, error)
 	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*OKResponse, error)
 	SetNX(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*OKResponse, error)
+	SetIfGreater(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*OKResponse, error)
+	SetIfLess(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*OKResponse, error)
 	Incr(ctx context.Context, in *IncrRequest, opts ...grpc.CallOption) (*OKResponse, error)
 	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	CounterIncr(ctx context.Context, in *CounterRequest, opts ...grpc.CallOption) (*CounterResponse, error)
+	CounterDecr(ctx context.Context, in *CounterRequest, opts ...grpc.CallOption) (*CounterResponse, error)
+	IncrAndCheck(ctx context.Context, in *IncrAndCheckRequest, opts ...grpc.CallOption) (*IncrAndCheckResponse, error)
+	SetBit(ctx context.Context, in *SetBitRequest, opts ...grpc.CallOption) (*BitResponse, error)
+	GetBit(ctx context.Context, in *GetBitRequest, opts ...grpc.CallOption) (*BitResponse, error)
 	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*OKResponse, error)
+	HSet(ctx context.Context, in *HSetRequest, opts ...grpc.CallOption) (*OKResponse, error)
+	HGet(ctx context.Context, in *HGetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	HGetAll(ctx context.Context, in *HGetAllRequest, opts ...grpc.CallOption) (*HGetAllResponse, error)
+	HDel(ctx context.Context, in *HDelRequest, opts ...grpc.CallOption) (*OKResponse, error)
+	SAdd(ctx context.Context, in *SMemberRequest, opts ...grpc.CallOption) (*OKResponse, error)
+	SRem(ctx context.Context, in *SMemberRequest, opts ...grpc.CallOption) (*OKResponse, error)
+	SIsMember(ctx context.Context, in *SMemberRequest, opts ...grpc.CallOption) (*OKResponse, error)
+	SMembers(ctx context.Context, in *SKeyRequest, opts ...grpc.CallOption) (*SMembersResponse, error)
+	SCard(ctx context.Context, in *SKeyRequest, opts ...grpc.CallOption) (*SCardResponse, error)
+	ZAdd(ctx context.Context, in *ZAddRequest, opts ...grpc.CallOption) (*OKResponse, error)
+	ZScore(ctx context.Context, in *ZMemberRequest, opts ...grpc.CallOption) (*ZScoreResponse, error)
+	ZRank(ctx context.Context, in *ZMemberRequest, opts ...grpc.CallOption) (*ZRankResponse, error)
+	ZRange(ctx context.Context, in *ZRangeRequest, opts ...grpc.CallOption) (*ZRangeResponse, error)
+	LPush(ctx context.Context, in *ListPushRequest, opts ...grpc.CallOption) (*OKResponse, error)
+	RPush(ctx context.Context, in *ListPushRequest, opts ...grpc.CallOption) (*OKResponse, error)
+	LPop(ctx context.Context, in *ListKeyRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	RPop(ctx context.Context, in *ListKeyRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	LRange(ctx context.Context, in *ListRangeRequest, opts ...grpc.CallOption) (*ListRangeResponse, error)
+	LLen(ctx context.Context, in *ListKeyRequest, opts ...grpc.CallOption) (*LLenResponse, error)
 	Exists(ctx context.Context, in *ExistsRequest, opts ...grpc.CallOption) (*ExistsResponse, error)
 	FiFoLiFoDelete(ctx context.Context, in *FiFoLiFoDeleteRequest, opts ...grpc.CallOption) (*OKResponse, error)
 	FiFoLiFoPush(ctx context.Context, in *FiFoLiFoPushRequest, opts ...grpc.CallOption) (*OKResponse, error)
 	FiFoLiFoFPop(ctx context.Context, in *FiFoLiFoPopRequest, opts ...grpc.CallOption) (*FiFoLiFoPopResponse, error)
 	FiFoLiFoLPop(ctx context.Context, in *FiFoLiFoPopRequest, opts ...grpc.CallOption) (*FiFoLiFoPopResponse, error)
+	FiFoLiFoBPop(ctx context.Context, in *FiFoLiFoBPopRequest, opts ...grpc.CallOption) (*FiFoLiFoPopResponse, error)
+	FiFoLiFoMove(ctx context.Context, in *FiFoLiFoMoveRequest, opts ...grpc.CallOption) (*FiFoLiFoPopResponse, error)
+	FlushAll(ctx context.Context, in *FlushAllRequest, opts ...grpc.CallOption) (*FlushAllResponse, error)
 	Health(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*HealthResponse, error)
 }
 
@@ -90,6 +148,26 @@ func (c *kVServiceClient) SetNX(ctx context.Context, in *SetRequest, opts ...grp
 	return out, nil
 }
 
+func (c *kVServiceClient) SetIfGreater(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*OKResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OKResponse)
+	err := c.cc.Invoke(ctx, KVService_SetIfGreater_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) SetIfLess(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*OKResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OKResponse)
+	err := c.cc.Invoke(ctx, KVService_SetIfLess_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *kVServiceClient) Incr(ctx context.Context, in *IncrRequest, opts ...grpc.CallOption) (*OKResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(OKResponse)
@@ -110,6 +188,56 @@ func (c *kVServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.
 	return out, nil
 }
 
+func (c *kVServiceClient) CounterIncr(ctx context.Context, in *CounterRequest, opts ...grpc.CallOption) (*CounterResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CounterResponse)
+	err := c.cc.Invoke(ctx, KVService_CounterIncr_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) CounterDecr(ctx context.Context, in *CounterRequest, opts ...grpc.CallOption) (*CounterResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CounterResponse)
+	err := c.cc.Invoke(ctx, KVService_CounterDecr_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) IncrAndCheck(ctx context.Context, in *IncrAndCheckRequest, opts ...grpc.CallOption) (*IncrAndCheckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IncrAndCheckResponse)
+	err := c.cc.Invoke(ctx, KVService_IncrAndCheck_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) SetBit(ctx context.Context, in *SetBitRequest, opts ...grpc.CallOption) (*BitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BitResponse)
+	err := c.cc.Invoke(ctx, KVService_SetBit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) GetBit(ctx context.Context, in *GetBitRequest, opts ...grpc.CallOption) (*BitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BitResponse)
+	err := c.cc.Invoke(ctx, KVService_GetBit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *kVServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*OKResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(OKResponse)
@@ -120,6 +248,196 @@ func (c *kVServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts ..
 	return out, nil
 }
 
+func (c *kVServiceClient) HSet(ctx context.Context, in *HSetRequest, opts ...grpc.CallOption) (*OKResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OKResponse)
+	err := c.cc.Invoke(ctx, KVService_HSet_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) HGet(ctx context.Context, in *HGetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, KVService_HGet_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) HGetAll(ctx context.Context, in *HGetAllRequest, opts ...grpc.CallOption) (*HGetAllResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HGetAllResponse)
+	err := c.cc.Invoke(ctx, KVService_HGetAll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) HDel(ctx context.Context, in *HDelRequest, opts ...grpc.CallOption) (*OKResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OKResponse)
+	err := c.cc.Invoke(ctx, KVService_HDel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) SAdd(ctx context.Context, in *SMemberRequest, opts ...grpc.CallOption) (*OKResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OKResponse)
+	err := c.cc.Invoke(ctx, KVService_SAdd_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) SRem(ctx context.Context, in *SMemberRequest, opts ...grpc.CallOption) (*OKResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OKResponse)
+	err := c.cc.Invoke(ctx, KVService_SRem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) SIsMember(ctx context.Context, in *SMemberRequest, opts ...grpc.CallOption) (*OKResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OKResponse)
+	err := c.cc.Invoke(ctx, KVService_SIsMember_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) SMembers(ctx context.Context, in *SKeyRequest, opts ...grpc.CallOption) (*SMembersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SMembersResponse)
+	err := c.cc.Invoke(ctx, KVService_SMembers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) SCard(ctx context.Context, in *SKeyRequest, opts ...grpc.CallOption) (*SCardResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SCardResponse)
+	err := c.cc.Invoke(ctx, KVService_SCard_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) ZAdd(ctx context.Context, in *ZAddRequest, opts ...grpc.CallOption) (*OKResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OKResponse)
+	err := c.cc.Invoke(ctx, KVService_ZAdd_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) ZScore(ctx context.Context, in *ZMemberRequest, opts ...grpc.CallOption) (*ZScoreResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ZScoreResponse)
+	err := c.cc.Invoke(ctx, KVService_ZScore_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) ZRank(ctx context.Context, in *ZMemberRequest, opts ...grpc.CallOption) (*ZRankResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ZRankResponse)
+	err := c.cc.Invoke(ctx, KVService_ZRank_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) ZRange(ctx context.Context, in *ZRangeRequest, opts ...grpc.CallOption) (*ZRangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ZRangeResponse)
+	err := c.cc.Invoke(ctx, KVService_ZRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) LPush(ctx context.Context, in *ListPushRequest, opts ...grpc.CallOption) (*OKResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OKResponse)
+	err := c.cc.Invoke(ctx, KVService_LPush_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) RPush(ctx context.Context, in *ListPushRequest, opts ...grpc.CallOption) (*OKResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OKResponse)
+	err := c.cc.Invoke(ctx, KVService_RPush_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) LPop(ctx context.Context, in *ListKeyRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, KVService_LPop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) RPop(ctx context.Context, in *ListKeyRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, KVService_RPop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) LRange(ctx context.Context, in *ListRangeRequest, opts ...grpc.CallOption) (*ListRangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRangeResponse)
+	err := c.cc.Invoke(ctx, KVService_LRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) LLen(ctx context.Context, in *ListKeyRequest, opts ...grpc.CallOption) (*LLenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LLenResponse)
+	err := c.cc.Invoke(ctx, KVService_LLen_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *kVServiceClient) Exists(ctx context.Context, in *ExistsRequest, opts ...grpc.CallOption) (*ExistsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ExistsResponse)
@@ -147,226 +465,840 @@ func (c *kVServiceClient) FiFoLiFoPush(ctx context.Context, in *FiFoLiFoPushRequ
 	if err != nil {
 		return nil, err
 	}
-	return out, nil
+	return out, nil
+}
+
+func (c *kVServiceClient) FiFoLiFoFPop(ctx context.Context, in *FiFoLiFoPopRequest, opts ...grpc.CallOption) (*FiFoLiFoPopResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FiFoLiFoPopResponse)
+	err := c.cc.Invoke(ctx, KVService_FiFoLiFoFPop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) FiFoLiFoLPop(ctx context.Context, in *FiFoLiFoPopRequest, opts ...grpc.CallOption) (*FiFoLiFoPopResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FiFoLiFoPopResponse)
+	err := c.cc.Invoke(ctx, KVService_FiFoLiFoLPop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) FiFoLiFoBPop(ctx context.Context, in *FiFoLiFoBPopRequest, opts ...grpc.CallOption) (*FiFoLiFoPopResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FiFoLiFoPopResponse)
+	err := c.cc.Invoke(ctx, KVService_FiFoLiFoBPop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) FiFoLiFoMove(ctx context.Context, in *FiFoLiFoMoveRequest, opts ...grpc.CallOption) (*FiFoLiFoPopResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FiFoLiFoPopResponse)
+	err := c.cc.Invoke(ctx, KVService_FiFoLiFoMove_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) FlushAll(ctx context.Context, in *FlushAllRequest, opts ...grpc.CallOption) (*FlushAllResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FlushAllResponse)
+	err := c.cc.Invoke(ctx, KVService_FlushAll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) Health(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*HealthResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HealthResponse)
+	err := c.cc.Invoke(ctx, KVService_Health_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// KVServiceServer is the server API for KVService service.
+// All implementations must embed UnimplementedKVServiceServer
+// for forward compatibility.
+type KVServiceServer interface {
+	CreateDB(context.Context, *CreateDBRequest) (*CreateDBResponse, error)
+	Set(context.Context, *SetRequest) (*OKResponse, error)
+	SetNX(context.Context, *SetRequest) (*OKResponse, error)
+	SetIfGreater(context.Context, *SetRequest) (*OKResponse, error)
+	SetIfLess(context.Context, *SetRequest) (*OKResponse, error)
+	Incr(context.Context, *IncrRequest) (*OKResponse, error)
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	CounterIncr(context.Context, *CounterRequest) (*CounterResponse, error)
+	CounterDecr(context.Context, *CounterRequest) (*CounterResponse, error)
+	IncrAndCheck(context.Context, *IncrAndCheckRequest) (*IncrAndCheckResponse, error)
+	SetBit(context.Context, *SetBitRequest) (*BitResponse, error)
+	GetBit(context.Context, *GetBitRequest) (*BitResponse, error)
+	Delete(context.Context, *DeleteRequest) (*OKResponse, error)
+	HSet(context.Context, *HSetRequest) (*OKResponse, error)
+	HGet(context.Context, *HGetRequest) (*GetResponse, error)
+	HGetAll(context.Context, *HGetAllRequest) (*HGetAllResponse, error)
+	HDel(context.Context, *HDelRequest) (*OKResponse, error)
+	SAdd(context.Context, *SMemberRequest) (*OKResponse, error)
+	SRem(context.Context, *SMemberRequest) (*OKResponse, error)
+	SIsMember(context.Context, *SMemberRequest) (*OKResponse, error)
+	SMembers(context.Context, *SKeyRequest) (*SMembersResponse, error)
+	SCard(context.Context, *SKeyRequest) (*SCardResponse, error)
+	ZAdd(context.Context, *ZAddRequest) (*OKResponse, error)
+	ZScore(context.Context, *ZMemberRequest) (*ZScoreResponse, error)
+	ZRank(context.Context, *ZMemberRequest) (*ZRankResponse, error)
+	ZRange(context.Context, *ZRangeRequest) (*ZRangeResponse, error)
+	LPush(context.Context, *ListPushRequest) (*OKResponse, error)
+	RPush(context.Context, *ListPushRequest) (*OKResponse, error)
+	LPop(context.Context, *ListKeyRequest) (*GetResponse, error)
+	RPop(context.Context, *ListKeyRequest) (*GetResponse, error)
+	LRange(context.Context, *ListRangeRequest) (*ListRangeResponse, error)
+	LLen(context.Context, *ListKeyRequest) (*LLenResponse, error)
+	Exists(context.Context, *ExistsRequest) (*ExistsResponse, error)
+	FiFoLiFoDelete(context.Context, *FiFoLiFoDeleteRequest) (*OKResponse, error)
+	FiFoLiFoPush(context.Context, *FiFoLiFoPushRequest) (*OKResponse, error)
+	FiFoLiFoFPop(context.Context, *FiFoLiFoPopRequest) (*FiFoLiFoPopResponse, error)
+	FiFoLiFoLPop(context.Context, *FiFoLiFoPopRequest) (*FiFoLiFoPopResponse, error)
+	FiFoLiFoBPop(context.Context, *FiFoLiFoBPopRequest) (*FiFoLiFoPopResponse, error)
+	FiFoLiFoMove(context.Context, *FiFoLiFoMoveRequest) (*FiFoLiFoPopResponse, error)
+	FlushAll(context.Context, *FlushAllRequest) (*FlushAllResponse, error)
+	Health(context.Context, *emptypb.Empty) (*HealthResponse, error)
+	mustEmbedUnimplementedKVServiceServer()
+}
+
+// UnimplementedKVServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedKVServiceServer struct{}
+
+func (UnimplementedKVServiceServer) CreateDB(context.Context, *CreateDBRequest) (*CreateDBResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateDB not implemented")
+}
+func (UnimplementedKVServiceServer) Set(context.Context, *SetRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Set not implemented")
+}
+func (UnimplementedKVServiceServer) SetNX(context.Context, *SetRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetNX not implemented")
+}
+func (UnimplementedKVServiceServer) SetIfGreater(context.Context, *SetRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetIfGreater not implemented")
+}
+func (UnimplementedKVServiceServer) SetIfLess(context.Context, *SetRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetIfLess not implemented")
+}
+func (UnimplementedKVServiceServer) Incr(context.Context, *IncrRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Incr not implemented")
+}
+func (UnimplementedKVServiceServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedKVServiceServer) CounterIncr(context.Context, *CounterRequest) (*CounterResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CounterIncr not implemented")
+}
+func (UnimplementedKVServiceServer) CounterDecr(context.Context, *CounterRequest) (*CounterResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CounterDecr not implemented")
+}
+func (UnimplementedKVServiceServer) IncrAndCheck(context.Context, *IncrAndCheckRequest) (*IncrAndCheckResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method IncrAndCheck not implemented")
+}
+func (UnimplementedKVServiceServer) SetBit(context.Context, *SetBitRequest) (*BitResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetBit not implemented")
+}
+func (UnimplementedKVServiceServer) GetBit(context.Context, *GetBitRequest) (*BitResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetBit not implemented")
+}
+func (UnimplementedKVServiceServer) Delete(context.Context, *DeleteRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedKVServiceServer) HSet(context.Context, *HSetRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method HSet not implemented")
+}
+func (UnimplementedKVServiceServer) HGet(context.Context, *HGetRequest) (*GetResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method HGet not implemented")
+}
+func (UnimplementedKVServiceServer) HGetAll(context.Context, *HGetAllRequest) (*HGetAllResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method HGetAll not implemented")
+}
+func (UnimplementedKVServiceServer) HDel(context.Context, *HDelRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method HDel not implemented")
+}
+func (UnimplementedKVServiceServer) SAdd(context.Context, *SMemberRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SAdd not implemented")
+}
+func (UnimplementedKVServiceServer) SRem(context.Context, *SMemberRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SRem not implemented")
+}
+func (UnimplementedKVServiceServer) SIsMember(context.Context, *SMemberRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SIsMember not implemented")
+}
+func (UnimplementedKVServiceServer) SMembers(context.Context, *SKeyRequest) (*SMembersResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SMembers not implemented")
+}
+func (UnimplementedKVServiceServer) SCard(context.Context, *SKeyRequest) (*SCardResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SCard not implemented")
+}
+func (UnimplementedKVServiceServer) ZAdd(context.Context, *ZAddRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ZAdd not implemented")
+}
+func (UnimplementedKVServiceServer) ZScore(context.Context, *ZMemberRequest) (*ZScoreResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ZScore not implemented")
+}
+func (UnimplementedKVServiceServer) ZRank(context.Context, *ZMemberRequest) (*ZRankResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ZRank not implemented")
+}
+func (UnimplementedKVServiceServer) ZRange(context.Context, *ZRangeRequest) (*ZRangeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ZRange not implemented")
+}
+func (UnimplementedKVServiceServer) LPush(context.Context, *ListPushRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method LPush not implemented")
+}
+func (UnimplementedKVServiceServer) RPush(context.Context, *ListPushRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RPush not implemented")
+}
+func (UnimplementedKVServiceServer) LPop(context.Context, *ListKeyRequest) (*GetResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method LPop not implemented")
+}
+func (UnimplementedKVServiceServer) RPop(context.Context, *ListKeyRequest) (*GetResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RPop not implemented")
+}
+func (UnimplementedKVServiceServer) LRange(context.Context, *ListRangeRequest) (*ListRangeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method LRange not implemented")
+}
+func (UnimplementedKVServiceServer) LLen(context.Context, *ListKeyRequest) (*LLenResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method LLen not implemented")
+}
+func (UnimplementedKVServiceServer) Exists(context.Context, *ExistsRequest) (*ExistsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Exists not implemented")
+}
+func (UnimplementedKVServiceServer) FiFoLiFoDelete(context.Context, *FiFoLiFoDeleteRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method FiFoLiFoDelete not implemented")
+}
+func (UnimplementedKVServiceServer) FiFoLiFoPush(context.Context, *FiFoLiFoPushRequest) (*OKResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method FiFoLiFoPush not implemented")
+}
+func (UnimplementedKVServiceServer) FiFoLiFoFPop(context.Context, *FiFoLiFoPopRequest) (*FiFoLiFoPopResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method FiFoLiFoFPop not implemented")
+}
+func (UnimplementedKVServiceServer) FiFoLiFoLPop(context.Context, *FiFoLiFoPopRequest) (*FiFoLiFoPopResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method FiFoLiFoLPop not implemented")
+}
+func (UnimplementedKVServiceServer) FiFoLiFoBPop(context.Context, *FiFoLiFoBPopRequest) (*FiFoLiFoPopResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method FiFoLiFoBPop not implemented")
+}
+func (UnimplementedKVServiceServer) FiFoLiFoMove(context.Context, *FiFoLiFoMoveRequest) (*FiFoLiFoPopResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method FiFoLiFoMove not implemented")
+}
+func (UnimplementedKVServiceServer) FlushAll(context.Context, *FlushAllRequest) (*FlushAllResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method FlushAll not implemented")
+}
+func (UnimplementedKVServiceServer) Health(context.Context, *emptypb.Empty) (*HealthResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Health not implemented")
+}
+func (UnimplementedKVServiceServer) mustEmbedUnimplementedKVServiceServer() {}
+func (UnimplementedKVServiceServer) testEmbeddedByValue()                   {}
+
+// UnsafeKVServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to KVServiceServer will
+// result in compilation errors.
+type UnsafeKVServiceServer interface {
+	mustEmbedUnimplementedKVServiceServer()
+}
+
+func RegisterKVServiceServer(s grpc.ServiceRegistrar, srv KVServiceServer) {
+	// If the following call panics, it indicates UnimplementedKVServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&KVService_ServiceDesc, srv)
+}
+
+func _KVService_CreateDB_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateDBRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).CreateDB(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_CreateDB_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).CreateDB(ctx, req.(*CreateDBRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_Set_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).Set(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_SetNX_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).SetNX(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_SetNX_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).SetNX(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_SetIfGreater_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).SetIfGreater(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_SetIfGreater_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).SetIfGreater(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_SetIfLess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).SetIfLess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_SetIfLess_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).SetIfLess(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_Incr_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncrRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).Incr(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_Incr_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).Incr(ctx, req.(*IncrRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_CounterIncr_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CounterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).CounterIncr(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_CounterIncr_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).CounterIncr(ctx, req.(*CounterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_CounterDecr_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CounterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).CounterDecr(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_CounterDecr_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).CounterDecr(ctx, req.(*CounterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_IncrAndCheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncrAndCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).IncrAndCheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_IncrAndCheck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).IncrAndCheck(ctx, req.(*IncrAndCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_SetBit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetBitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).SetBit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_SetBit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).SetBit(ctx, req.(*SetBitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_GetBit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).GetBit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_GetBit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).GetBit(ctx, req.(*GetBitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_HSet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HSetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).HSet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_HSet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).HSet(ctx, req.(*HSetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_HGet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HGetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).HGet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_HGet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).HGet(ctx, req.(*HGetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_HGetAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HGetAllRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).HGetAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_HGetAll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).HGetAll(ctx, req.(*HGetAllRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_HDel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HDelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).HDel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_HDel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).HDel(ctx, req.(*HDelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_SAdd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).SAdd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_SAdd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).SAdd(ctx, req.(*SMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_SRem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).SRem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_SRem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).SRem(ctx, req.(*SMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_SIsMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).SIsMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_SIsMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).SIsMember(ctx, req.(*SMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_SMembers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).SMembers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_SMembers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).SMembers(ctx, req.(*SKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_SCard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).SCard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_SCard_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).SCard(ctx, req.(*SKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_ZAdd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ZAddRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).ZAdd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_ZAdd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).ZAdd(ctx, req.(*ZAddRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *kVServiceClient) FiFoLiFoFPop(ctx context.Context, in *FiFoLiFoPopRequest, opts ...grpc.CallOption) (*FiFoLiFoPopResponse, error) {
-	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(FiFoLiFoPopResponse)
-	err := c.cc.Invoke(ctx, KVService_FiFoLiFoFPop_FullMethodName, in, out, cOpts...)
-	if err != nil {
+func _KVService_ZScore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ZMemberRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(KVServiceServer).ZScore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_ZScore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).ZScore(ctx, req.(*ZMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *kVServiceClient) FiFoLiFoLPop(ctx context.Context, in *FiFoLiFoPopRequest, opts ...grpc.CallOption) (*FiFoLiFoPopResponse, error) {
-	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(FiFoLiFoPopResponse)
-	err := c.cc.Invoke(ctx, KVService_FiFoLiFoLPop_FullMethodName, in, out, cOpts...)
-	if err != nil {
+func _KVService_ZRank_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ZMemberRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(KVServiceServer).ZRank(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_ZRank_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).ZRank(ctx, req.(*ZMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *kVServiceClient) Health(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*HealthResponse, error) {
-	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(HealthResponse)
-	err := c.cc.Invoke(ctx, KVService_Health_FullMethodName, in, out, cOpts...)
-	if err != nil {
+func _KVService_ZRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ZRangeRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
-}
-
-// KVServiceServer is the server API for KVService service.
-// All implementations must embed UnimplementedKVServiceServer
-// for forward compatibility.
-type KVServiceServer interface {
-	CreateDB(context.Context, *CreateDBRequest) (*CreateDBResponse, error)
-	Set(context.Context, *SetRequest) (*OKResponse, error)
-	SetNX(context.Context, *SetRequest) (*OKResponse, error)
-	Incr(context.Context, *IncrRequest) (*OKResponse, error)
-	Get(context.Context, *GetRequest) (*GetResponse, error)
-	Delete(context.Context, *DeleteRequest) (*OKResponse, error)
-	Exists(context.Context, *ExistsRequest) (*ExistsResponse, error)
-	FiFoLiFoDelete(context.Context, *FiFoLiFoDeleteRequest) (*OKResponse, error)
-	FiFoLiFoPush(context.Context, *FiFoLiFoPushRequest) (*OKResponse, error)
-	FiFoLiFoFPop(context.Context, *FiFoLiFoPopRequest) (*FiFoLiFoPopResponse, error)
-	FiFoLiFoLPop(context.Context, *FiFoLiFoPopRequest) (*FiFoLiFoPopResponse, error)
-	Health(context.Context, *emptypb.Empty) (*HealthResponse, error)
-	mustEmbedUnimplementedKVServiceServer()
-}
-
-// UnimplementedKVServiceServer must be embedded to have
-// forward compatible implementations.
-//
-// NOTE: this should be embedded by value instead of pointer to avoid a nil
-// pointer dereference when methods are called.
-type UnimplementedKVServiceServer struct{}
-
-func (UnimplementedKVServiceServer) CreateDB(context.Context, *CreateDBRequest) (*CreateDBResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method CreateDB not implemented")
-}
-func (UnimplementedKVServiceServer) Set(context.Context, *SetRequest) (*OKResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method Set not implemented")
-}
-func (UnimplementedKVServiceServer) SetNX(context.Context, *SetRequest) (*OKResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method SetNX not implemented")
-}
-func (UnimplementedKVServiceServer) Incr(context.Context, *IncrRequest) (*OKResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method Incr not implemented")
-}
-func (UnimplementedKVServiceServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method Get not implemented")
-}
-func (UnimplementedKVServiceServer) Delete(context.Context, *DeleteRequest) (*OKResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method Delete not implemented")
-}
-func (UnimplementedKVServiceServer) Exists(context.Context, *ExistsRequest) (*ExistsResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method Exists not implemented")
-}
-func (UnimplementedKVServiceServer) FiFoLiFoDelete(context.Context, *FiFoLiFoDeleteRequest) (*OKResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method FiFoLiFoDelete not implemented")
-}
-func (UnimplementedKVServiceServer) FiFoLiFoPush(context.Context, *FiFoLiFoPushRequest) (*OKResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method FiFoLiFoPush not implemented")
-}
-func (UnimplementedKVServiceServer) FiFoLiFoFPop(context.Context, *FiFoLiFoPopRequest) (*FiFoLiFoPopResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method FiFoLiFoFPop not implemented")
-}
-func (UnimplementedKVServiceServer) FiFoLiFoLPop(context.Context, *FiFoLiFoPopRequest) (*FiFoLiFoPopResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method FiFoLiFoLPop not implemented")
-}
-func (UnimplementedKVServiceServer) Health(context.Context, *emptypb.Empty) (*HealthResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method Health not implemented")
-}
-func (UnimplementedKVServiceServer) mustEmbedUnimplementedKVServiceServer() {}
-func (UnimplementedKVServiceServer) testEmbeddedByValue()                   {}
-
-// UnsafeKVServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to KVServiceServer will
-// result in compilation errors.
-type UnsafeKVServiceServer interface {
-	mustEmbedUnimplementedKVServiceServer()
-}
-
-func RegisterKVServiceServer(s grpc.ServiceRegistrar, srv KVServiceServer) {
-	// If the following call panics, it indicates UnimplementedKVServiceServer was
-	// embedded by pointer and is nil.  This will cause panics if an
-	// unimplemented method is ever invoked, so we test this at initialization
-	// time to prevent it from happening at runtime later due to I/O.
-	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
-		t.testEmbeddedByValue()
+	if interceptor == nil {
+		return srv.(KVServiceServer).ZRange(ctx, in)
 	}
-	s.RegisterService(&KVService_ServiceDesc, srv)
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_ZRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).ZRange(ctx, req.(*ZRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _KVService_CreateDB_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateDBRequest)
+func _KVService_LPush_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPushRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(KVServiceServer).CreateDB(ctx, in)
+		return srv.(KVServiceServer).LPush(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: KVService_CreateDB_FullMethodName,
+		FullMethod: KVService_LPush_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(KVServiceServer).CreateDB(ctx, req.(*CreateDBRequest))
+		return srv.(KVServiceServer).LPush(ctx, req.(*ListPushRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _KVService_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(SetRequest)
+func _KVService_RPush_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPushRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(KVServiceServer).Set(ctx, in)
+		return srv.(KVServiceServer).RPush(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: KVService_Set_FullMethodName,
+		FullMethod: KVService_RPush_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(KVServiceServer).Set(ctx, req.(*SetRequest))
+		return srv.(KVServiceServer).RPush(ctx, req.(*ListPushRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _KVService_SetNX_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(SetRequest)
+func _KVService_LPop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListKeyRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(KVServiceServer).SetNX(ctx, in)
+		return srv.(KVServiceServer).LPop(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: KVService_SetNX_FullMethodName,
+		FullMethod: KVService_LPop_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(KVServiceServer).SetNX(ctx, req.(*SetRequest))
+		return srv.(KVServiceServer).LPop(ctx, req.(*ListKeyRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _KVService_Incr_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(IncrRequest)
+func _KVService_RPop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListKeyRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(KVServiceServer).Incr(ctx, in)
+		return srv.(KVServiceServer).RPop(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: KVService_Incr_FullMethodName,
+		FullMethod: KVService_RPop_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(KVServiceServer).Incr(ctx, req.(*IncrRequest))
+		return srv.(KVServiceServer).RPop(ctx, req.(*ListKeyRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _KVService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetRequest)
+func _KVService_LRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRangeRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(KVServiceServer).Get(ctx, in)
+		return srv.(KVServiceServer).LRange(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: KVService_Get_FullMethodName,
+		FullMethod: KVService_LRange_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(KVServiceServer).Get(ctx, req.(*GetRequest))
+		return srv.(KVServiceServer).LRange(ctx, req.(*ListRangeRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _KVService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteRequest)
+func _KVService_LLen_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListKeyRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(KVServiceServer).Delete(ctx, in)
+		return srv.(KVServiceServer).LLen(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: KVService_Delete_FullMethodName,
+		FullMethod: KVService_LLen_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(KVServiceServer).Delete(ctx, req.(*DeleteRequest))
+		return srv.(KVServiceServer).LLen(ctx, req.(*ListKeyRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -461,6 +1393,60 @@ func _KVService_FiFoLiFoLPop_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _KVService_FiFoLiFoBPop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FiFoLiFoBPopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).FiFoLiFoBPop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_FiFoLiFoBPop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).FiFoLiFoBPop(ctx, req.(*FiFoLiFoBPopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_FiFoLiFoMove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FiFoLiFoMoveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).FiFoLiFoMove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_FiFoLiFoMove_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).FiFoLiFoMove(ctx, req.(*FiFoLiFoMoveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_FlushAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FlushAllRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).FlushAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_FlushAll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).FlushAll(ctx, req.(*FlushAllRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _KVService_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -498,6 +1484,14 @@ var KVService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SetNX",
 			Handler:    _KVService_SetNX_Handler,
 		},
+		{
+			MethodName: "SetIfGreater",
+			Handler:    _KVService_SetIfGreater_Handler,
+		},
+		{
+			MethodName: "SetIfLess",
+			Handler:    _KVService_SetIfLess_Handler,
+		},
 		{
 			MethodName: "Incr",
 			Handler:    _KVService_Incr_Handler,
@@ -506,10 +1500,106 @@ var KVService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Get",
 			Handler:    _KVService_Get_Handler,
 		},
+		{
+			MethodName: "CounterIncr",
+			Handler:    _KVService_CounterIncr_Handler,
+		},
+		{
+			MethodName: "CounterDecr",
+			Handler:    _KVService_CounterDecr_Handler,
+		},
+		{
+			MethodName: "IncrAndCheck",
+			Handler:    _KVService_IncrAndCheck_Handler,
+		},
+		{
+			MethodName: "SetBit",
+			Handler:    _KVService_SetBit_Handler,
+		},
+		{
+			MethodName: "GetBit",
+			Handler:    _KVService_GetBit_Handler,
+		},
 		{
 			MethodName: "Delete",
 			Handler:    _KVService_Delete_Handler,
 		},
+		{
+			MethodName: "HSet",
+			Handler:    _KVService_HSet_Handler,
+		},
+		{
+			MethodName: "HGet",
+			Handler:    _KVService_HGet_Handler,
+		},
+		{
+			MethodName: "HGetAll",
+			Handler:    _KVService_HGetAll_Handler,
+		},
+		{
+			MethodName: "HDel",
+			Handler:    _KVService_HDel_Handler,
+		},
+		{
+			MethodName: "SAdd",
+			Handler:    _KVService_SAdd_Handler,
+		},
+		{
+			MethodName: "SRem",
+			Handler:    _KVService_SRem_Handler,
+		},
+		{
+			MethodName: "SIsMember",
+			Handler:    _KVService_SIsMember_Handler,
+		},
+		{
+			MethodName: "SMembers",
+			Handler:    _KVService_SMembers_Handler,
+		},
+		{
+			MethodName: "SCard",
+			Handler:    _KVService_SCard_Handler,
+		},
+		{
+			MethodName: "ZAdd",
+			Handler:    _KVService_ZAdd_Handler,
+		},
+		{
+			MethodName: "ZScore",
+			Handler:    _KVService_ZScore_Handler,
+		},
+		{
+			MethodName: "ZRank",
+			Handler:    _KVService_ZRank_Handler,
+		},
+		{
+			MethodName: "ZRange",
+			Handler:    _KVService_ZRange_Handler,
+		},
+		{
+			MethodName: "LPush",
+			Handler:    _KVService_LPush_Handler,
+		},
+		{
+			MethodName: "RPush",
+			Handler:    _KVService_RPush_Handler,
+		},
+		{
+			MethodName: "LPop",
+			Handler:    _KVService_LPop_Handler,
+		},
+		{
+			MethodName: "RPop",
+			Handler:    _KVService_RPop_Handler,
+		},
+		{
+			MethodName: "LRange",
+			Handler:    _KVService_LRange_Handler,
+		},
+		{
+			MethodName: "LLen",
+			Handler:    _KVService_LLen_Handler,
+		},
 		{
 			MethodName: "Exists",
 			Handler:    _KVService_Exists_Handler,
@@ -530,6 +1620,18 @@ var KVService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "FiFoLiFoLPop",
 			Handler:    _KVService_FiFoLiFoLPop_Handler,
 		},
+		{
+			MethodName: "FiFoLiFoBPop",
+			Handler:    _KVService_FiFoLiFoBPop_Handler,
+		},
+		{
+			MethodName: "FiFoLiFoMove",
+			Handler:    _KVService_FiFoLiFoMove_Handler,
+		},
+		{
+			MethodName: "FlushAll",
+			Handler:    _KVService_FlushAll_Handler,
+		},
 		{
 			MethodName: "Health",
 			Handler:    _KVService_Health_Handler,
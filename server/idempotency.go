@@ -0,0 +1,214 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"hydrakv/envhandler"
+	"hydrakv/utils"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// idempotentResult is the remembered result of a write with an idempotency key
+type idempotentResult struct {
+	done     chan struct{}
+	kept     bool
+	expireAt time.Time
+	status   int
+	header   http.Header
+	body     []byte
+	resp     any
+}
+
+// idempotencyStore remembers the results of writes per DB for HKV_IDEMPOTENCY_TTL seconds, so a retried write
+// with the same idempotency key returns the first result instead of applying the mutation again.
+type idempotencyStore struct {
+	mu        sync.Mutex
+	dbs       map[string]map[string]*idempotentResult
+	lastSweep time.Time
+}
+
+// creates a new idempotency store
+func newIdempotencyStore() *idempotencyStore {
+	return &idempotencyStore{dbs: make(map[string]map[string]*idempotentResult), lastSweep: time.Now()}
+}
+
+// ttl returns how long results are remembered
+func (st *idempotencyStore) ttl() time.Duration {
+	return time.Duration(envhandler.ENV.IdempotencyTTL()) * time.Second
+}
+
+// acquire returns the result for the key and true if the caller has to execute the write and finish the result.
+// A running write with the same key is waited for.
+func (st *idempotencyStore) acquire(db, key string) (*idempotentResult, bool) {
+	db = utils.U.DbKey(db)
+	for {
+		now := time.Now()
+
+		st.mu.Lock()
+		st.sweep(now)
+		entries, ok := st.dbs[db]
+		if !ok {
+			entries = make(map[string]*idempotentResult)
+			st.dbs[db] = entries
+		}
+		res, ok := entries[key]
+		if !ok || (res.kept && now.After(res.expireAt)) {
+			res = &idempotentResult{done: make(chan struct{})}
+			entries[key] = res
+			st.mu.Unlock()
+			return res, true
+		}
+		st.mu.Unlock()
+
+		// wait for the running write - if it failed, try again
+		<-res.done
+		if res.kept {
+			return res, false
+		}
+	}
+}
+
+// finish stores the result - failed writes are forgotten, so they can be retried
+func (st *idempotencyStore) finish(db, key string, res *idempotentResult, keep bool) {
+	st.mu.Lock()
+	if keep {
+		res.kept = true
+		res.expireAt = time.Now().Add(st.ttl())
+	} else if entries, ok := st.dbs[utils.U.DbKey(db)]; ok && entries[key] == res {
+		delete(entries, key)
+	}
+	st.mu.Unlock()
+	close(res.done)
+}
+
+// sweep removes expired results once per TTL - must be called with the lock held
+func (st *idempotencyStore) sweep(now time.Time) {
+	if now.Sub(st.lastSweep) < st.ttl() {
+		return
+	}
+	st.lastSweep = now
+	for db, entries := range st.dbs {
+		for key, res := range entries {
+			if res.kept && now.After(res.expireAt) {
+				delete(entries, key)
+			}
+		}
+		if len(entries) == 0 {
+			delete(st.dbs, db)
+		}
+	}
+}
+
+// dropDB forgets all results of a DB
+func (st *idempotencyStore) dropDB(db string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.dbs, utils.U.DbKey(db))
+}
+
+// idempotencyRecorder captures the response of a write
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *idempotencyRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *idempotencyRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// wrap makes a write handler idempotent for requests with an Idempotency-Key header
+func (st *idempotencyStore) wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idemKey := r.Header.Get("Idempotency-Key")
+		if idemKey == "" {
+			next(w, r)
+			return
+		}
+
+		// the key is scoped to the endpoint
+		db := r.PathValue("dbname")
+		key := r.Method + " " + r.URL.Path + " " + idemKey
+		res, owner := st.acquire(db, key)
+		if !owner {
+			for k, v := range res.header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(res.status)
+			_, _ = w.Write(res.body)
+			return
+		}
+
+		// a panicking write is forgotten like a failed one - the waiting requests retry it
+		keep := false
+		defer func() { st.finish(db, key, res, keep) }()
+
+		rec := &idempotencyRecorder{ResponseWriter: w}
+		next(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		res.status = rec.status
+		res.header = w.Header().Clone()
+		// the replay is encoded again
+		res.header.Del("Content-Encoding")
+		res.header.Del("Content-Length")
+		res.body = rec.body.Bytes()
+
+		// server errors are not remembered - the write may not have happened
+		keep = rec.status < http.StatusInternalServerError
+	}
+}
+
+// Returns the remembered response for gRPC writes with an idempotency key
+func grpcIdempotencyInterceptor(st *idempotencyStore) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+
+		r, ok := req.(interface {
+			GetDb() string
+			GetIdempotencyKey() string
+		})
+		if !ok || r.GetIdempotencyKey() == "" {
+			return handler(ctx, req)
+		}
+
+		// the key is scoped to the method and the apikey, since the apikey is checked in the handler
+		key := info.FullMethod + " " + r.GetIdempotencyKey()
+		if a, ok := req.(interface{ GetApikey() string }); ok {
+			key += " " + a.GetApikey()
+		}
+
+		res, owner := st.acquire(r.GetDb(), key)
+		if !owner {
+			return res.resp, nil
+		}
+
+		// failed or panicking calls are not remembered
+		keep := false
+		defer func() { st.finish(r.GetDb(), key, res, keep) }()
+
+		resp, err := handler(ctx, req)
+		res.resp = resp
+		keep = err == nil
+		return resp, err
+	}
+}
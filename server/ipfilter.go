@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"hydrakv/envhandler"
+	"log"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ipFilter rejects clients matching HKV_DENY_CIDRS or not matching HKV_ALLOW_CIDRS
+type ipFilter struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// parseCIDRs parses a comma-separated list of CIDRs - single IPs are accepted as well
+func parseCIDRs(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// CheckIPFilter returns an error if HKV_ALLOW_CIDRS or HKV_DENY_CIDRS are invalid
+func CheckIPFilter() error {
+	if _, err := parseCIDRs(*envhandler.ENV.ALLOW_CIDRS); err != nil {
+		return fmt.Errorf("%s: %w", envhandler.ALLOW_CIDRS, err)
+	}
+	if _, err := parseCIDRs(*envhandler.ENV.DENY_CIDRS); err != nil {
+		return fmt.Errorf("%s: %w", envhandler.DENY_CIDRS, err)
+	}
+	return nil
+}
+
+// creates a new IP filter from HKV_ALLOW_CIDRS and HKV_DENY_CIDRS - invalid lists are logged and ignored
+func newIPFilter() *ipFilter {
+	f := &ipFilter{}
+	var err error
+	if f.allow, err = parseCIDRs(*envhandler.ENV.ALLOW_CIDRS); err != nil {
+		log.Printf("%s ignored: %v", envhandler.ALLOW_CIDRS, err)
+	}
+	if f.deny, err = parseCIDRs(*envhandler.ENV.DENY_CIDRS); err != nil {
+		log.Printf("%s ignored: %v", envhandler.DENY_CIDRS, err)
+	}
+	return f
+}
+
+// enabled returns true if any CIDR is configured
+func (f *ipFilter) enabled() bool {
+	return len(f.allow) > 0 || len(f.deny) > 0
+}
+
+// allowed returns true if the client IP may connect - unparsable IPs are only allowed without an allowlist
+func (f *ipFilter) allowed(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return len(f.allow) == 0
+	}
+	addr = addr.Unmap()
+	for _, prefix := range f.deny {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, prefix := range f.allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reject gRPC calls from disallowed peers
+func grpcIPFilterInterceptor(f *ipFilter) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+
+		if f.enabled() && !isUnixPeer(ctx) && !f.allowed(grpcClientIP(ctx)) {
+			return nil, status.Error(codes.PermissionDenied, "ip not allowed")
+		}
+		return handler(ctx, req)
+	}
+}
@@ -1,37 +1,128 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"hydrakv/envhandler"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
 )
 
 type requestLimiter struct {
-	sem chan struct{}
+	// sem holds one slot per running request - it is swapped by resize, a request releases the sem it took
+	sem atomic.Pointer[chan struct{}]
+	// wait is how long a request waits for a free slot - 0 fails fast
+	wait atomic.Int64
+	// avg is the moving average of the request durations in nanoseconds - the base of the Retry-After estimate
+	avg atomic.Int64
 }
 
-// creates a new request limiter
+// maxRetryAfter caps the Retry-After estimate in seconds
+const maxRetryAfter = 60
+
+// creates a new request limiter for HTTP
 func newRequestLimiter() *requestLimiter {
-	return &requestLimiter{sem: make(chan struct{}, *envhandler.ENV.REQ_LIMIT)}
+	return newLimiter(envhandler.ENV.ReqLimit(), time.Duration(max(envhandler.ENV.RequestWaitMs(), 0))*time.Millisecond)
+}
+
+// newLimiter creates a limiter with limit slots
+func newLimiter(limit int, wait time.Duration) *requestLimiter {
+	l := &requestLimiter{}
+	l.resize(limit)
+	l.wait.Store(int64(wait))
+	return l
+}
+
+// resize replaces the slots by limit new ones. Running requests finish on the old slots, so for a moment
+// up to old plus new limit requests can run.
+func (l *requestLimiter) resize(limit int) {
+	if cur := l.sem.Load(); cur != nil && cap(*cur) == limit {
+		return
+	}
+	sem := make(chan struct{}, limit)
+	l.sem.Store(&sem)
+}
+
+// acquire takes a slot, waiting up to l.wait for one to free up. Returns false if no slot got free in time,
+// otherwise the sem the slot has to be released to.
+func (l *requestLimiter) acquire(ctx context.Context) (chan struct{}, bool) {
+	sem := *l.sem.Load()
+	select {
+	case sem <- struct{}{}:
+		return sem, true
+	default:
+	}
+	wait := time.Duration(l.wait.Load())
+	if wait <= 0 {
+		return nil, false
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case sem <- struct{}{}:
+		return sem, true
+	case <-timer.C:
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// observe adds the duration of a finished request to the moving average
+func (l *requestLimiter) observe(d time.Duration) {
+	for {
+		old := l.avg.Load()
+		next := int64(d)
+		if old > 0 {
+			next = old + (int64(d)-old)/8
+		}
+		if l.avg.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// retryAfter estimates the seconds until the current load is done: the average request duration scaled by the
+// share of used slots. At least 1 second, at most maxRetryAfter.
+func (l *requestLimiter) retryAfter() int {
+	sem := *l.sem.Load()
+	if cap(sem) == 0 {
+		return 1
+	}
+	load := float64(len(sem)) / float64(cap(sem))
+	secs := int(math.Ceil(time.Duration(float64(l.avg.Load()) * load).Seconds()))
+	return min(max(secs, 1), maxRetryAfter)
+}
+
+// reload applies the HTTP limits of the env - after a SIGHUP
+func (l *requestLimiter) reload() {
+	l.resize(envhandler.ENV.ReqLimit())
+	l.wait.Store(int64(time.Duration(max(envhandler.ENV.RequestWaitMs(), 0)) * time.Millisecond))
 }
 
 // wrap creates a new request limiter middleware
 func (l *requestLimiter) wrap(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		select {
-		case l.sem <- struct{}{}:
-			defer func() { <-l.sem }()
+		if sem, ok := l.acquire(r.Context()); ok {
+			defer func() { <-sem }()
+			start := time.Now()
 			next.ServeHTTP(w, r)
-		default:
-			log.Println("request limit reached - please check requestlimit!")
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTooManyRequests)
-			_ = json.NewEncoder(w).Encode(map[string]interface{}{
-				"error":       "rate_limit_exceeded",
-				"message":     "Too many requests",
-				"currentLoad": len(l.sem),
-			})
+			l.observe(time.Since(start))
+			return
 		}
+		log.Println("request limit reached - please check requestlimit!")
+		w.Header().Set("Retry-After", strconv.Itoa(l.retryAfter()))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":       "rate_limit_exceeded",
+			"message":     "Too many requests",
+			"currentLoad": len(*l.sem.Load()),
+		})
 	})
 }
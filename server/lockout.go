@@ -0,0 +1,109 @@
+package server
+
+import (
+	"hydrakv/envhandler"
+	"hydrakv/utils"
+	"sync"
+	"time"
+)
+
+// lockoutEntry counts the failed auth attempts of an IP for a DB
+type lockoutEntry struct {
+	failures    int
+	windowStart time.Time
+	lockedUntil time.Time
+}
+
+// authLockout rejects auth attempts of an IP for a DB for HKV_AUTH_LOCKOUT_COOLDOWN seconds after
+// HKV_AUTH_LOCKOUT failed attempts within HKV_AUTH_LOCKOUT_WINDOW seconds.
+type authLockout struct {
+	mu        sync.Mutex
+	entries   map[string]*lockoutEntry
+	lastSweep time.Time
+}
+
+// lockout is the global auth lockout of the HTTP and gRPC servers
+var lockout = newAuthLockout()
+
+// creates a new auth lockout
+func newAuthLockout() *authLockout {
+	return &authLockout{entries: make(map[string]*lockoutEntry), lastSweep: time.Now()}
+}
+
+// enabled returns true if HKV_AUTH_LOCKOUT is set
+func (l *authLockout) enabled() bool {
+	return envhandler.ENV.AuthLockout() > 0
+}
+
+// window returns the time in which failed attempts are counted
+func (l *authLockout) window() time.Duration {
+	return time.Duration(envhandler.ENV.AuthLockoutWindow()) * time.Second
+}
+
+// cooldown returns the time an IP is locked out
+func (l *authLockout) cooldown() time.Duration {
+	return time.Duration(envhandler.ENV.AuthLockoutCooldown()) * time.Second
+}
+
+// key returns the map key of an IP and a DB
+func (l *authLockout) key(ip, db string) string {
+	return ip + " " + utils.U.DbKey(db)
+}
+
+// locked returns true if the IP is locked out of the DB
+func (l *authLockout) locked(ip, db string) bool {
+	if !l.enabled() {
+		return false
+	}
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	e, ok := l.entries[l.key(ip, db)]
+	return ok && now.Before(e.lockedUntil)
+}
+
+// fail counts a failed attempt and locks the IP out of the DB if there were too many
+func (l *authLockout) fail(ip, db string) {
+	if !l.enabled() {
+		return
+	}
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	k := l.key(ip, db)
+	e, ok := l.entries[k]
+	if !ok || now.Sub(e.windowStart) > l.window() {
+		e = &lockoutEntry{windowStart: now}
+		l.entries[k] = e
+	}
+	e.failures++
+	if e.failures >= envhandler.ENV.AuthLockout() {
+		e.lockedUntil = now.Add(l.cooldown())
+	}
+}
+
+// reset forgets the failed attempts after a successful one
+func (l *authLockout) reset(ip, db string) {
+	if !l.enabled() {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, l.key(ip, db))
+}
+
+// sweep removes entries whose window and lockout are over once per window - must be called with the lock held
+func (l *authLockout) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window() {
+		return
+	}
+	l.lastSweep = now
+	for k, e := range l.entries {
+		if now.Sub(e.windowStart) > l.window() && now.After(e.lockedUntil) {
+			delete(l.entries, k)
+		}
+	}
+}
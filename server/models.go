@@ -1,20 +1,117 @@
 package server
 
+import (
+	"encoding/json"
+	"hydrakv/envhandler"
+	"hydrakv/utils"
+	"time"
+)
+
 type ExistsResponse struct {
 	Exists bool `json:"exists"`
 }
 
 type NewDB struct {
-	Name string `json:"name" validate:"required,alphanum,min=1,max=100"`
+	Name string `json:"name" validate:"required,dbname"`
+	// Changelog enables the changelog of the new DB
+	Changelog bool `json:"changelog"`
 }
 
 type NewDBCreated struct {
-	Name    string `json:"name" validate:"required,alphanum,min=1,max=100"`
+	Name    string `json:"name" validate:"required,dbname"`
 	Created bool   `json:"created"`
 	ApiKey  string `json:"api_key"`
 	Exists  bool   `json:"exists"`
 }
 
+type ReadKeyCreated struct {
+	Name    string `json:"name"`
+	ReadKey string `json:"read_key"`
+}
+
+// ApiKeyList is the list of the DBs with api keys
+type ApiKeyList struct {
+	DBs []ApiKeyDB `json:"dbs"`
+}
+
+// Config lists the effective settings by /admin/config
+type Config struct {
+	Settings []envhandler.Setting `json:"settings"`
+}
+
+// RequestLimitsUpdate changes the request limits at runtime - omitted limits are kept
+type RequestLimitsUpdate struct {
+	HTTP   *int `json:"http" validate:"omitempty,min=1"`
+	GRPC   *int `json:"grpc" validate:"omitempty,min=1"`
+	WaitMs *int `json:"wait_ms" validate:"omitempty,min=0"`
+}
+
+// Swap names the DBs whose contents are swapped
+type Swap struct {
+	A string `json:"a" validate:"required"`
+	B string `json:"b" validate:"required,nefield=A"`
+}
+
+// RequestLimits are the request limits in effect
+type RequestLimits struct {
+	HTTP   int `json:"http"`
+	GRPC   int `json:"grpc"`
+	WaitMs int `json:"wait_ms"`
+}
+
+// FlushAll reports the number of DBs flushed or dropped by /admin/flushall
+type FlushAll struct {
+	DBs     int  `json:"dbs"`
+	Dropped bool `json:"dropped"`
+}
+
+// Synced is the response of POST /admin/sync
+type Synced struct {
+	DBs int `json:"dbs"`
+}
+
+// HotBaskets is the response of GET /admin/baskets/{dbname}: the fullest baskets of a DB next to the totals,
+// which give the mean load to compare them with
+type HotBaskets struct {
+	Baskets int         `json:"baskets"`
+	Entries int64       `json:"entries"`
+	Hot     []HotBasket `json:"hot"`
+}
+
+// HotBasket is the entry count of a basket with some of its keys
+type HotBasket struct {
+	Index   int      `json:"index"`
+	Entries int64    `json:"entries"`
+	Keys    []string `json:"keys"`
+}
+
+// BackupManifest is the first member of a backup archive
+type BackupManifest struct {
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	DBs       []BackupDB `json:"dbs"`
+	// ApiKeys is true if the archive holds the SHA-256 hashes of the api keys
+	ApiKeys bool `json:"api_keys"`
+}
+
+// BackupDB is a DB of a backup archive - the entries are counted when the backup starts
+type BackupDB struct {
+	Name    string `json:"name"`
+	Entries int64  `json:"entries"`
+}
+
+// RestoreResult lists the DBs created by /admin/restore and the existing ones which were skipped
+type RestoreResult struct {
+	Restored []string `json:"restored"`
+	Skipped  []string `json:"skipped"`
+}
+
+// ApiKeyDB lists the metadata of the api keys of a DB
+type ApiKeyDB struct {
+	Name string             `json:"name"`
+	Keys []utils.ApiKeyInfo `json:"keys"`
+}
+
 type NewLiFoFifo struct {
 	Name  string `json:"name" validate:"required,alphanum,min=1,max=100"`
 	Limit int    `json:"limit" validate:"required,min=1,max=2000000"`
@@ -36,11 +133,20 @@ type PopFiFoLiFo struct {
 	Name   string `json:"name" validate:"required,alphanum,min=1,max=100"`
 }
 
+// MoveFiFoLiFo moves the oldest value of the FiFoLiFo Src to Dst
+type MoveFiFoLiFo struct {
+	ApiKey string `json:"api_key"`
+	Src    string `json:"src" validate:"required,alphanum,min=1,max=100"`
+	Dst    string `json:"dst" validate:"required,alphanum,min=1,max=100"`
+}
+
 type Set struct {
 	ApiKey string `json:"api_key"`
 	Ttl    int    `json:"ttl"`
 	Key    string `json:"key" validate:"required,min=1,max=30000"`
 	Value  string `json:"value" validate:"required,min=1"`
+	// ContentType is an encoding hint stored with the value, e.g. application/json - only PUT stores it
+	ContentType string `json:"content_type" validate:"max=255"`
 }
 
 type Key struct {
@@ -48,15 +154,303 @@ type Key struct {
 	Key    string `json:"key" validate:"required,min=1,max=30000"`
 }
 
-type Value struct {
+// GetEx reads a key and sets a new ttl - or removes the expiry with persist
+type GetEx struct {
+	ApiKey  string `json:"api_key"`
+	Key     string `json:"key" validate:"required,min=1,max=30000"`
+	Ttl     int64  `json:"ttl" validate:"min=0,excluded_with=Persist"`
+	Persist bool   `json:"persist"`
+}
+
+// Touch sets a new ttl of a key without reading its value
+type GetOrSet struct {
+	ApiKey string `json:"api_key"`
+	Key    string `json:"key" validate:"required,min=1,max=30000"`
+	Value  string `json:"value" validate:"required,min=1"`
+	Ttl    int64  `json:"ttl" validate:"min=0"`
+}
+
+type Touch struct {
+	ApiKey string `json:"api_key"`
+	Key    string `json:"key" validate:"required,min=1,max=30000"`
+	Ttl    int64  `json:"ttl" validate:"min=1"`
+}
+
+type Counter struct {
+	ApiKey string `json:"api_key"`
+	Ttl    int    `json:"ttl"`
+	Key    string `json:"key" validate:"required,min=1,max=30000"`
+	Amount *int64 `json:"amount"`
+}
+
+// RateLimit counts a call against limit calls per window of ttl seconds
+type RateLimit struct {
+	ApiKey string `json:"api_key"`
+	Key    string `json:"key" validate:"required,min=1,max=30000"`
+	Limit  int64  `json:"limit" validate:"min=1"`
+	Ttl    int64  `json:"ttl" validate:"min=0"`
+}
+
+type RateLimitValue struct {
+	Allowed bool  `json:"allowed"`
+	Count   int64 `json:"count"`
+}
+
+// KeyType is the type of a value, e.g. string or counter
+type KeyType struct {
 	Found bool   `json:"found"`
-	Value string `json:"value"`
+	Type  string `json:"type,omitempty"`
+}
+
+// KeyMeta is the metadata of a key - the TTL in seconds, the last write in Unix nanos and the size in bytes
+type KeyMeta struct {
+	Exists       bool   `json:"exists"`
+	TtlRemaining int64  `json:"ttl_remaining"`
+	ModifiedAt   int64  `json:"modified_at"`
+	Size         int    `json:"size"`
+	ContentType  string `json:"content_type,omitempty"`
+}
+
+// Expiring asks for the keys expiring in the next seconds
+type Expiring struct {
+	ApiKey  string `json:"api_key"`
+	Seconds int64  `json:"seconds" validate:"required,min=1"`
+}
+
+// ExpiringKeys are the keys expiring soon, the soonest first - truncated if there were more than fit a response
+type ExpiringKeys struct {
+	Keys      []string `json:"keys"`
+	Truncated bool     `json:"truncated"`
+}
+
+// MGetTTL asks for several keys with their remaining TTL
+type MGetTTL struct {
+	ApiKey string   `json:"api_key"`
+	Keys   []string `json:"keys" validate:"required,min=1,dive,min=1,max=30000"`
+}
+
+// KeyTTL is a value of MGetTTL - Value and Ttl are omitted for missing keys, a Ttl of -1 means no expiry
+type KeyTTL struct {
+	Key   string  `json:"key"`
+	Found bool    `json:"found"`
+	Value *string `json:"value,omitempty"`
+	Ttl   *int64  `json:"ttl,omitempty"`
+}
+
+// KeyTTLs are the results of MGetTTL in the order of the keys
+type KeyTTLs struct {
+	Results []KeyTTL `json:"results"`
+}
+
+// Change is a mutation recorded by the changelog of a DB
+type Change struct {
+	Seq         int64  `json:"seq"`
+	Time        string `json:"time"`
+	Action      string `json:"action"`
+	Key         string `json:"key,omitempty"`
+	Field       string `json:"field,omitempty"`
+	Value       string `json:"value,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Ttl         int64  `json:"ttl,omitempty"`
+}
+
+// Changes is a page of the changelog - Next is the since of the following page. Oldest is the first sequence number
+// still kept, a since below Oldest-1 has missed changes.
+type Changes struct {
+	Changes []Change `json:"changes"`
+	Next    int64    `json:"next"`
+	Oldest  int64    `json:"oldest"`
+}
+
+// Batch is an ordered list of operations executed by one request
+type Batch struct {
+	ApiKey string    `json:"api_key"`
+	Ops    []BatchOp `json:"ops" validate:"required,min=1,dive"`
+}
+
+// BatchOp is one operation of a batch - value and ttl are only used by set and setnx, amount only by incr
+type BatchOp struct {
+	Op     string `json:"op" validate:"required,oneof=set setnx get del exists incr"`
+	Key    string `json:"key" validate:"required,min=1,max=30000"`
+	Value  string `json:"value" validate:"required_if=Op set,required_if=Op setnx"`
+	Ttl    int    `json:"ttl" validate:"min=0"`
+	Amount *int64 `json:"amount"`
+}
+
+// BatchResult is the result of a batch operation. Value is set for a found get, Count for incr, Error if the
+// operation was rejected - the other operations of the batch are not affected by it.
+type BatchResult struct {
+	Op    string  `json:"op"`
+	OK    bool    `json:"ok"`
+	Value *string `json:"value,omitempty"`
+	Count *int64  `json:"count,omitempty"`
+	Error string  `json:"error,omitempty"`
+}
+
+type BatchResults struct {
+	Results []BatchResult `json:"results"`
+}
+
+// HashField addresses a field of a hash - the value is only needed to set it
+type HashField struct {
+	ApiKey string `json:"api_key"`
+	Key    string `json:"key" validate:"required,min=1,max=30000"`
+	Field  string `json:"field" validate:"required,min=1,max=30000"`
+	Value  string `json:"value"`
+}
+
+// HashValue holds the fields of a hash
+type HashValue struct {
+	Found  bool              `json:"found"`
+	Fields map[string]string `json:"fields"`
+}
+
+// SetMember addresses a member of a set
+type SetMember struct {
+	ApiKey string `json:"api_key"`
+	Key    string `json:"key" validate:"required,min=1,max=30000"`
+	Member string `json:"member" validate:"required,min=1,max=30000"`
+}
+
+// SetMembers holds the members of a set
+type SetMembers struct {
+	Found   bool     `json:"found"`
+	Members []string `json:"members"`
+	Card    int      `json:"card"`
+}
+
+// ZSetMember addresses a member of a sorted set - the score is only needed to add it
+type ZSetMember struct {
+	ApiKey string  `json:"api_key"`
+	Key    string  `json:"key" validate:"required,min=1,max=30000"`
+	Member string  `json:"member" validate:"required,min=1,max=30000"`
+	Score  float64 `json:"score"`
+}
+
+// ZSetRange selects the members of a sorted set from position start to stop
+type ZSetRange struct {
+	ApiKey string `json:"api_key"`
+	Key    string `json:"key" validate:"required,min=1,max=30000"`
+	Start  int    `json:"start"`
+	Stop   int    `json:"stop"`
+}
+
+// ZSetScore is the score of a member of a sorted set
+type ZSetScore struct {
+	Found bool    `json:"found"`
+	Score float64 `json:"score"`
+}
+
+// ZSetRank is the 0-based position of a member of a sorted set
+type ZSetRank struct {
+	Found bool `json:"found"`
+	Rank  int  `json:"rank"`
+}
+
+// ZSetEntry is a member of a sorted set and its score
+type ZSetEntry struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// ZSetMembers holds a range of a sorted set
+type ZSetMembers struct {
+	Found   bool        `json:"found"`
+	Members []ZSetEntry `json:"members"`
+}
+
+// ListPush is a value pushed to a list
+type ListPush struct {
+	ApiKey string `json:"api_key"`
+	Key    string `json:"key" validate:"required,min=1,max=30000"`
+	Value  string `json:"value" validate:"required,min=1"`
+}
+
+// ListRange selects the values of a list from position start to stop
+type ListRange struct {
+	ApiKey string `json:"api_key"`
+	Key    string `json:"key" validate:"required,min=1,max=30000"`
+	Start  int    `json:"start"`
+	Stop   int    `json:"stop"`
+}
+
+// ListValues holds a range of a list and the length of the list
+type ListValues struct {
+	Found  bool     `json:"found"`
+	Values []string `json:"values"`
+	Len    int      `json:"len"`
+}
+
+type CounterValue struct {
+	OK    bool  `json:"ok"`
+	Value int64 `json:"value"`
+}
+
+type SetBit struct {
+	ApiKey string `json:"api_key"`
+	Key    string `json:"key" validate:"required,min=1,max=30000"`
+	Offset int64  `json:"offset" validate:"min=0"`
+	Bit    int    `json:"bit" validate:"oneof=0 1"`
+}
+
+type GetBit struct {
+	ApiKey string `json:"api_key"`
+	Key    string `json:"key" validate:"required,min=1,max=30000"`
+	Offset int64  `json:"offset" validate:"min=0"`
+}
+
+type Bit struct {
+	OK  bool `json:"ok"`
+	Bit int  `json:"bit"`
+}
+
+type Value struct {
+	Found       bool   `json:"found"`
+	Value       string `json:"value"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// JSONValue is returned by a get with ?as=json - the stored value is embedded as JSON instead of a string
+type JSONValue struct {
+	Found bool            `json:"found"`
+	Value json.RawMessage `json:"value"`
 }
 
 type DeleteDB struct {
-	Name string `json:"name" validate:"required,min=1,max=100,alphanum"`
+	Name string `json:"name" validate:"required,dbname"`
+}
+
+// GetOrSetResponse is the value of a getorset and whether it was stored by the call
+type GetOrSetResponse struct {
+	Value   string `json:"value"`
+	Created bool   `json:"created"`
 }
 
 type OK struct {
 	OK bool `json:"ok"`
+	// Truncated is true if the value was cut to HKV_ENTRY_SIZE under the truncate oversize policy
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type Stats struct {
+	DBs []*DBObject `json:"dbs"`
+	// Total is the number of DBs matching the prefix - the DBs are a page of them with offset or limit
+	Total int `json:"total"`
+}
+
+// ValidationError is returned with a 400 if a payload cant be decoded or validated
+type ValidationError struct {
+	Error   string       `json:"error"`
+	Message string       `json:"message,omitempty"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError names the field and the violated rule
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
 }
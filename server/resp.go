@@ -0,0 +1,506 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"hydrakv/envhandler"
+	"hydrakv/hashMap"
+	"hydrakv/utils"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RESPServer speaks a subset of the Redis protocol (RESP2) on HKV_RESP_PORT, so redis-cli and Redis client
+// libraries can be used for the basic operations. A connection picks its DB with SELECT <dbname> and, with api keys
+// enabled, authenticates with AUTH <apikey> or AUTH <dbname> <apikey>.
+type RESPServer struct {
+	kv     kvLogic
+	filter *ipFilter
+	lis    net.Listener
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	// closing is set by Shutdown - connections finish their command and close
+	closing atomic.Bool
+	wg      sync.WaitGroup
+}
+
+// maxRESPArgs bounds the arguments of a command - the largest supported is a DEL or EXISTS of many keys
+const maxRESPArgs = 1024
+
+// errRESPProtocol is a malformed request - the connection is closed after the error reply
+var errRESPProtocol = errors.New("protocol error")
+
+// NewRESPServer creates a new RESP server on top of svc
+func NewRESPServer(svc kvLogic) *RESPServer {
+	return &RESPServer{kv: svc, filter: newIPFilter(), conns: make(map[net.Conn]struct{})}
+}
+
+// Start listens on ip:port and serves RESP connections until Shutdown
+func (r *RESPServer) Start(ip string, port int) {
+	lis, err := net.Listen("tcp", ip+":"+strconv.Itoa(port))
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+
+	log.Printf("Starting RESPServer on %s:%d\n", ip, port)
+	if err := r.Serve(lis); err != nil {
+		log.Fatalf("failed to serve: %v", err)
+	}
+}
+
+// Serve accepts connections on lis - it returns nil after Shutdown
+func (r *RESPServer) Serve(lis net.Listener) error {
+	r.mu.Lock()
+	if r.closing.Load() {
+		r.mu.Unlock()
+		return lis.Close()
+	}
+	r.lis = lis
+	r.mu.Unlock()
+
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if r.closing.Load() {
+				return nil
+			}
+			return err
+		}
+
+		if ip := hostIP(conn.RemoteAddr().String()); !r.filter.allowed(ip) {
+			_ = conn.Close()
+			continue
+		}
+
+		// registered under the lock Shutdown sets closing with - so it never waits for an unregistered connection
+		r.mu.Lock()
+		if r.closing.Load() {
+			r.mu.Unlock()
+			_ = conn.Close()
+			return nil
+		}
+		r.conns[conn] = struct{}{}
+		r.wg.Add(1)
+		r.mu.Unlock()
+		go func() {
+			defer r.wg.Done()
+			r.serveConn(conn)
+			r.mu.Lock()
+			delete(r.conns, conn)
+			r.mu.Unlock()
+		}()
+	}
+}
+
+// Shutdown stops accepting connections and lets the open ones finish their command until ctx is done - the rest is
+// closed then
+func (r *RESPServer) Shutdown(ctx context.Context) error {
+	// wake up the connections waiting for their next command
+	r.mu.Lock()
+	r.closing.Store(true)
+	if r.lis != nil {
+		_ = r.lis.Close()
+	}
+	for conn := range r.conns {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		r.mu.Lock()
+		for conn := range r.conns {
+			_ = conn.Close()
+		}
+		r.mu.Unlock()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// respConn is the state of one client connection
+type respConn struct {
+	ip     string
+	db     string
+	apikey string
+	w      *bufio.Writer
+}
+
+// serveConn reads commands until the client quits, the connection fails or the server shuts down. Replies are
+// flushed once no pipelined command is waiting.
+func (r *RESPServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	rd := bufio.NewReader(conn)
+	c := &respConn{ip: hostIP(conn.RemoteAddr().String()), w: bufio.NewWriter(conn)}
+	for !r.closing.Load() {
+		args, err := readRESPCommand(rd)
+		if err != nil {
+			if errors.Is(err, errRESPProtocol) {
+				c.error("ERR " + err.Error())
+				_ = c.w.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := r.exec(c, args)
+		if rd.Buffered() == 0 || quit {
+			if err := c.w.Flush(); err != nil {
+				return
+			}
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+// readRESPCommand reads an array of bulk strings or an inline command (as typed into telnet)
+func readRESPCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := readRESPLine(rd)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n > maxRESPArgs {
+		return nil, fmt.Errorf("%w: invalid multibulk length", errRESPProtocol)
+	}
+	args := make([]string, 0, max(n, 0))
+	for range n {
+		line, err := readRESPLine(rd)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("%w: expected '$', got '%.1s'", errRESPProtocol, line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > *envhandler.ENV.MAX_BODY_BYTES {
+			return nil, fmt.Errorf("%w: invalid bulk length", errRESPProtocol)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		if buf[size] != '\r' || buf[size+1] != '\n' {
+			return nil, fmt.Errorf("%w: bulk string not terminated by CRLF", errRESPProtocol)
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+// readRESPLine reads a line terminated by CRLF (a bare LF is accepted like Redis does for inline commands)
+func readRESPLine(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", fmt.Errorf("%w: line too long", errRESPProtocol)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r"), nil
+}
+
+/***********/
+/* Replies */
+/***********/
+
+func (c *respConn) simple(s string) {
+	_, _ = c.w.WriteString("+" + s + "\r\n")
+}
+
+func (c *respConn) error(s string) {
+	_, _ = c.w.WriteString("-" + s + "\r\n")
+}
+
+func (c *respConn) integer(n int64) {
+	_, _ = c.w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func (c *respConn) bulk(s string) {
+	_, _ = c.w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+func (c *respConn) null() {
+	_, _ = c.w.WriteString("$-1\r\n")
+}
+
+/************/
+/* Commands */
+/************/
+
+// exec runs a command and writes its reply - returns true if the connection has to be closed
+func (r *RESPServer) exec(c *respConn, args []string) bool {
+	cmd := strings.ToUpper(args[0])
+	args = args[1:]
+
+	switch cmd {
+	case "PING":
+		if len(args) > 0 {
+			c.bulk(args[0])
+		} else {
+			c.simple("PONG")
+		}
+	case "QUIT":
+		c.simple("OK")
+		return true
+	case "SELECT":
+		r.cmdSelect(c, args)
+	case "AUTH":
+		r.cmdAuth(c, args)
+	case "GET":
+		r.cmdGet(c, args)
+	case "SET":
+		r.cmdSet(c, args)
+	case "DEL":
+		r.cmdDel(c, args)
+	case "INCR":
+		r.cmdIncr(c, args)
+	case "EXISTS":
+		r.cmdExists(c, args)
+	default:
+		c.error(fmt.Sprintf("ERR unknown command '%.64s'", cmd))
+	}
+	return false
+}
+
+// wrongArgs replies the Redis error for a wrong number of arguments
+func wrongArgs(c *respConn, cmd string) {
+	c.error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
+
+func (r *RESPServer) cmdSelect(c *respConn, args []string) {
+	if len(args) != 1 {
+		wrongArgs(c, "select")
+		return
+	}
+	// with api keys a missing DB is only reported like a wrong key by the commands, so SELECT does not reveal
+	// which DBs exist
+	if !utils.U.CheckDbName(args[0]) || (!*envhandler.ENV.APIKEY_ENABLED && !r.kv.DBExists(args[0])) {
+		c.error("ERR DB does not exist")
+		return
+	}
+	c.db = args[0]
+	c.simple("OK")
+}
+
+// cmdAuth keeps the api key for the following commands - AUTH <dbname> <apikey> selects the DB as well, so
+// redis-cli --user <dbname> --pass <apikey> works
+func (r *RESPServer) cmdAuth(c *respConn, args []string) {
+	switch len(args) {
+	case 1:
+		c.apikey = args[0]
+	case 2:
+		// a missing DB fails like a wrong key - with api keys in authorize, so it counts for the lockout as well
+		if !utils.U.CheckDbName(args[0]) || (!*envhandler.ENV.APIKEY_ENABLED && !r.kv.DBExists(args[0])) {
+			c.error("WRONGPASS invalid username-password pair")
+			return
+		}
+		c.db, c.apikey = args[0], args[1]
+	default:
+		wrongArgs(c, "auth")
+		return
+	}
+	if c.db != "" && *envhandler.ENV.APIKEY_ENABLED {
+		switch authorize(r.kv, "resp", c.ip, c.db, c.apikey, true) {
+		case authGranted:
+		case authLocked:
+			c.error("ERR too many failed auth attempts")
+			return
+		default:
+			c.error("WRONGPASS invalid username-password pair")
+			return
+		}
+	}
+	c.simple("OK")
+}
+
+// ready checks that a DB is selected, loaded and the client may access it - writes additionally have to be
+// accepted by the storage. Writes the error reply and returns false otherwise.
+func (r *RESPServer) ready(c *respConn, write bool) bool {
+	if c.db == "" {
+		c.error("ERR no DB selected - use SELECT <dbname>")
+		return false
+	}
+	if r.kv.DBLoading(c.db) {
+		c.error("LOADING HydraKV is loading the DB in memory")
+		return false
+	}
+	if *envhandler.ENV.APIKEY_ENABLED {
+		switch authorize(r.kv, "resp", c.ip, c.db, c.apikey, !write) {
+		case authGranted:
+		case authReadOnly:
+			c.error("NOPERM read-only apikey")
+			return false
+		case authLocked:
+			c.error("ERR too many failed auth attempts")
+			return false
+		default:
+			c.error("NOAUTH Authentication required")
+			return false
+		}
+	}
+	if write && r.kv.DBThrottled(c.db) {
+		c.error("BUSY aof_backlog")
+		return false
+	}
+	if write && !r.kv.DBWritable(c.db) {
+		c.error("ERR storage_full")
+		return false
+	}
+	return true
+}
+
+func (r *RESPServer) cmdGet(c *respConn, args []string) {
+	if len(args) != 1 {
+		wrongArgs(c, "get")
+		return
+	}
+	if !r.ready(c, false) {
+		return
+	}
+	if found, value := r.kv.Get(c.db, args[0]); found {
+		c.bulk(value)
+		return
+	}
+	c.null()
+}
+
+// cmdSet supports SET key value [EX seconds] [NX]
+func (r *RESPServer) cmdSet(c *respConn, args []string) {
+	if len(args) < 2 {
+		wrongArgs(c, "set")
+		return
+	}
+	key := args[0]
+	var ttl int64
+	nx := false
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "EX":
+			if i+1 >= len(args) {
+				c.error("ERR syntax error")
+				return
+			}
+			i++
+			secs, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil || secs <= 0 {
+				c.error("ERR invalid expire time in 'set' command")
+				return
+			}
+			ttl = secs
+		default:
+			c.error("ERR syntax error")
+			return
+		}
+	}
+	if !r.ready(c, true) {
+		return
+	}
+
+	// values above HKV_ENTRY_SIZE are rejected or truncated by HKV_OVERSIZE_POLICY
+	value, _, fits := hashMap.FitValue(args[1])
+	if !fits {
+		c.error("ERR value too large")
+		return
+	}
+
+	// ttls above HKV_MAX_TTL_SECONDS are clamped or rejected by HKV_TTL_POLICY
+	ttl, fits = hashMap.FitTTL(ttl)
+	if !fits {
+		c.error("ERR ttl out of range")
+		return
+	}
+
+	if nx {
+		if r.kv.SetNX(c.db, key, value, ttl) {
+			c.simple("OK")
+		} else {
+			c.null()
+		}
+		return
+	}
+	if !r.kv.Set(c.db, key, value, ttl) {
+		c.error("ERR set failed")
+		return
+	}
+	c.simple("OK")
+}
+
+func (r *RESPServer) cmdDel(c *respConn, args []string) {
+	if len(args) == 0 {
+		wrongArgs(c, "del")
+		return
+	}
+	if !r.ready(c, true) {
+		return
+	}
+	var deleted int64
+	for _, key := range args {
+		if r.kv.Del(c.db, key) {
+			deleted++
+		}
+	}
+	c.integer(deleted)
+}
+
+// cmdIncr increments a counter like Redis does: a missing key starts at 0 and the expiry is kept
+func (r *RESPServer) cmdIncr(c *respConn, args []string) {
+	if len(args) != 1 {
+		wrongArgs(c, "incr")
+		return
+	}
+	if !r.ready(c, true) {
+		return
+	}
+	value, ok := r.kv.CounterIncr(c.db, args[0], 1, 0)
+	if !ok {
+		c.error("ERR value is not an integer or out of range")
+		return
+	}
+	c.integer(value)
+}
+
+func (r *RESPServer) cmdExists(c *respConn, args []string) {
+	if len(args) == 0 {
+		wrongArgs(c, "exists")
+		return
+	}
+	if !r.ready(c, false) {
+		return
+	}
+	var exists int64
+	for _, key := range args {
+		if _, ok := r.kv.Type(c.db, key); ok {
+			exists++
+		}
+	}
+	c.integer(exists)
+}
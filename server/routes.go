@@ -2,24 +2,69 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"hydrakv/envhandler"
+	"hydrakv/fifolifo"
+	"hydrakv/hashMap"
 	"hydrakv/utils"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
 )
 
-// Index shows up a welcome page, listing all DBs created
+// Index shows up a welcome page. The DBs created are only listed with HKV_INDEX_PAGE - and with API keys enabled
+// only to the admin key - a public list of all DB names discloses the inventory.
 func (s *Server) Index(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	if r.URL.Path == "/" {
+		if !s.indexListed(r) {
+			if err := s.templates.ExecuteTemplate(w, "landing", nil); err != nil {
+				log.Println(err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+		prefix, offset, limit, ok := readDBPage(w, r, defaultIndexLimit)
+		if !ok {
+			return
+		}
+		dbs, total := s.ListDBs(prefix, offset, limit)
 		data := struct {
 			DBs           []*DBObject
 			ApiKeyEnabled bool
+			Prefix        string
+			From, To      int
+			Total         int
+			Prev, Next    string
 		}{
-			DBs:           s.ListDBs(),
+			DBs:           dbs,
 			ApiKeyEnabled: *envhandler.ENV.APIKEY_ENABLED,
+			Prefix:        prefix,
+			From:          min(offset+1, total),
+			To:            offset + len(dbs),
+			Total:         total,
+		}
+		// the links keep the prefix and page size
+		page := func(offset int) string {
+			q := url.Values{"offset": {strconv.Itoa(offset)}, "limit": {strconv.Itoa(limit)}}
+			if prefix != "" {
+				q.Set("prefix", prefix)
+			}
+			return "/?" + q.Encode()
+		}
+		if offset > 0 {
+			data.Prev = page(max(offset-limit, 0))
+		}
+		if offset+len(dbs) < total {
+			data.Next = page(offset + len(dbs))
 		}
 		err := s.templates.ExecuteTemplate(w, "dbobjects", data)
 		if err != nil {
@@ -29,17 +74,58 @@ func (s *Server) Index(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// limits of a page of the DB listing - the JSON of /stats lists all DBs without a limit
+const (
+	defaultIndexLimit = 100
+	maxDBsLimit       = 1000
+)
+
+// readDBPage reads ?prefix=, ?offset= and ?limit= of a DB listing - on an invalid value the validation error is
+// written and ok is false
+func readDBPage(w http.ResponseWriter, r *http.Request, defaultLimit int) (prefix string, offset, limit int, ok bool) {
+	query := r.URL.Query()
+	invalid := func(field, rule string) (string, int, int, bool) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ValidationError{Error: "validation_failed",
+			Fields: []FieldError{{Field: field, Rule: rule}}})
+		return "", 0, 0, false
+	}
+
+	var err error
+	limit = defaultLimit
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return invalid("offset", "min=0")
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxDBsLimit {
+			return invalid("limit", "min=1,max="+strconv.Itoa(maxDBsLimit))
+		}
+	}
+	return query.Get("prefix"), offset, limit, true
+}
+
+// indexListed returns true if the index lists the DBs - a request with the admin key always gets the list
+func (s *Server) indexListed(r *http.Request) bool {
+	if r.Header.Get("X-Admin-Key") != "" && isAdmin(r) {
+		return true
+	}
+	return *envhandler.ENV.INDEX_PAGE && !*envhandler.ENV.APIKEY_ENABLED
+}
+
 // CreateDB creates a new DB
 func (s *Server) CreateDB(w http.ResponseWriter, r *http.Request) {
 	// secure request
-	r.Body = http.MaxBytesReader(w, r.Body, int64(*envhandler.ENV.ENTRY_SIZE))
+	r.Body = http.MaxBytesReader(w, r.Body, int64(*envhandler.ENV.MAX_BODY_BYTES))
 	// Close the Body on return
 	defer r.Body.Close()
 
 	// get the payload
 	err, payload := readPayloadAndValidate[NewDB](r.Body, s)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeBadRequest(w, err)
 		return
 	}
 
@@ -51,6 +137,14 @@ func (s *Server) CreateDB(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	if created && payload.Changelog {
+		if err := s.EnableChangelog(payload.Name); err != nil {
+			log.Println("Error enabling changelog:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "changelog_failed"})
+			return
+		}
+	}
 
 	// return the response
 	if exists {
@@ -58,7 +152,7 @@ func (s *Server) CreateDB(w http.ResponseWriter, r *http.Request) {
 	} else {
 		w.WriteHeader(http.StatusCreated)
 	}
-	_ = json.NewEncoder(w).Encode(NewDBCreated{Name: strings.ToUpper(payload.Name), Created: created,
+	_ = json.NewEncoder(w).Encode(NewDBCreated{Name: utils.U.DbKey(payload.Name), Created: created,
 		Exists: exists, ApiKey: apikey})
 }
 
@@ -74,22 +168,71 @@ func (s *Server) SetValue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// reject writes if the AOF exceeds HKV_MAX_AOF_BYTES
+	if s.storageFull(w, dbname) {
+		return
+	}
+
 	err, payload := readPayloadAndValidate[Set](r.Body, s)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeBadRequest(w, err)
 		return
 	}
 
+	// keepttl preserves the expiry of an existing key on update
+	keepTTL := false
+	if v := r.URL.Query().Get("keepttl"); v != "" {
+		if keepTTL, err = strconv.ParseBool(v); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
 	// set the value and return
 	w.Header().Set("Content-Type", "application/json")
 
+	// values above HKV_ENTRY_SIZE are rejected or truncated by HKV_OVERSIZE_POLICY
+	var truncated bool
+	if r.Method != http.MethodPatch {
+		var fits bool
+		if payload.Value, truncated, fits = hashMap.FitValue(payload.Value); !fits {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "value_too_large"})
+			return
+		}
+	}
+
+	// the content type is stored by PUT only - the other methods would silently drop it
+	if payload.ContentType != "" && r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ValidationError{Error: "validation_failed",
+			Fields: []FieldError{{Field: "content_type", Rule: "excluded"}}})
+		return
+	}
+
+	// ttls above HKV_MAX_TTL_SECONDS are clamped or rejected by HKV_TTL_POLICY - keepttl ignores the ttl
+	ttl := int64(payload.Ttl)
+	if r.Method == http.MethodPost || (r.Method == http.MethodPut && !keepTTL) {
+		var fits bool
+		if ttl, fits = fitTTL(w, ttl); !fits {
+			return
+		}
+	}
+
 	var ok bool
 
 	switch r.Method {
 	case http.MethodPut:
-		ok = s.Set(dbname, payload.Key, payload.Value, int64(payload.Ttl))
+		switch {
+		case payload.ContentType != "":
+			ok = s.SetWithContentType(dbname, payload.Key, payload.Value, payload.ContentType, ttl, keepTTL)
+		case keepTTL:
+			ok = s.SetKeepTTL(dbname, payload.Key, payload.Value)
+		default:
+			ok = s.Set(dbname, payload.Key, payload.Value, ttl)
+		}
 	case http.MethodPost:
-		ok = s.SetNX(dbname, payload.Key, payload.Value, int64(payload.Ttl))
+		ok = s.SetNX(dbname, payload.Key, payload.Value, ttl)
 	case http.MethodPatch:
 		ok = s.Incr(dbname, payload.Key, payload.Value)
 	default:
@@ -102,7 +245,7 @@ func (s *Server) SetValue(w http.ResponseWriter, r *http.Request) {
 	} else {
 		w.WriteHeader(http.StatusOK)
 	}
-	_ = json.NewEncoder(w).Encode(OK{OK: ok})
+	_ = json.NewEncoder(w).Encode(OK{OK: ok, Truncated: ok && truncated})
 }
 
 // DeleteValue deletes a value from a DB
@@ -120,7 +263,7 @@ func (s *Server) DeleteValue(w http.ResponseWriter, r *http.Request) {
 	// Read the Payload
 	err, payload := readPayloadAndValidate[Key](r.Body, s)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeBadRequest(w, err)
 		return
 	}
 
@@ -146,45 +289,97 @@ func (s *Server) GetValue(w http.ResponseWriter, r *http.Request) {
 
 	err, payload := readPayloadAndValidate[Key](r.Body, s)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeBadRequest(w, err)
 		return
 	}
 
 	// JSON Header
 	w.Header().Set("Content-Type", "application/json")
 
-	// Get the value and return
-	ok, val := s.Get(dbname, payload.Key)
+	// with ?as=json the value is decoded and embedded - the only supported format
+	asJSON := false
+	if as := r.URL.Query().Get("as"); as != "" {
+		if as != "json" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(ValidationError{Error: "validation_failed",
+				Fields: []FieldError{{Field: "as", Rule: "oneof=json"}}})
+			return
+		}
+		asJSON = true
+	}
+
+	// Get the value and return - the time of the last write is sent as header
+	ok, val, info := s.GetInfo(dbname, payload.Key)
+	if ok {
+		w.Header().Set("X-Modified-At", strconv.FormatInt(info.ModifiedAt, 10))
+	}
+	if asJSON {
+		switch {
+		case !ok:
+			w.WriteHeader(*envhandler.ENV.KEY_MISS_STATUS)
+			_ = json.NewEncoder(w).Encode(JSONValue{Found: false, Value: json.RawMessage("null")})
+		case !json.Valid([]byte(val)):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "invalid_json_value"})
+		default:
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(JSONValue{Found: true, Value: json.RawMessage(val)})
+		}
+		return
+	}
+	// a missing key is a 404 or - with HKV_KEY_MISS_STATUS=200 - a normal answer
 	if !ok {
-		w.WriteHeader(http.StatusNotFound)
+		w.WriteHeader(*envhandler.ENV.KEY_MISS_STATUS)
 	} else {
 		w.WriteHeader(http.StatusOK)
 	}
-	_ = json.NewEncoder(w).Encode(Value{Found: ok, Value: val})
+	_ = json.NewEncoder(w).Encode(Value{Found: ok, Value: val, ContentType: info.ContentType})
 }
 
-// DB checks if the DB exists
-func (s *Server) DB(w http.ResponseWriter, r *http.Request) {
+// GetExValue gets a value from a DB and refreshes or removes its TTL in the same step
+func (s *Server) GetExValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
 	defer r.Body.Close()
-	dbname := r.PathValue("dbname")
 
-	if !utils.U.CheckDbName(dbname) {
-		w.WriteHeader(http.StatusBadRequest)
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
 		return
 	}
 
+	err, payload := readPayloadAndValidate[GetEx](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
 	w.Header().Set("Content-Type", "application/json")
 
-	ok := s.DBExists(dbname)
+	// a ttl of 0 keeps the expiry, persist removes it - unless HKV_ALLOW_NO_EXPIRY forbids it
+	ttl := payload.Ttl
+	if payload.Persist || ttl > 0 {
+		var fits bool
+		if ttl, fits = fitTTL(w, ttl); !fits {
+			return
+		}
+		if ttl == 0 {
+			ttl = hashMap.PersistTTL
+		}
+	}
+	ok, val := s.GetEX(dbname, payload.Key, ttl)
 	if !ok {
 		w.WriteHeader(http.StatusNotFound)
 	} else {
 		w.WriteHeader(http.StatusOK)
 	}
-	_ = json.NewEncoder(w).Encode(ExistsResponse{Exists: ok})
+	_ = json.NewEncoder(w).Encode(Value{Found: ok, Value: val})
 }
 
-func (s *Server) DeleteDB(w http.ResponseWriter, r *http.Request) {
+// SyncValue fsyncs the AOF of a DB and answers once the writes done before are durable
+func (s *Server) SyncValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
 	defer r.Body.Close()
 
 	// bootstrap the request
@@ -194,51 +389,70 @@ func (s *Server) DeleteDB(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delet the DB and return
-	s.DBDelete(dbname)
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.SyncDB(dbname); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "sync_failed"})
+		return
+	}
 	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(OK{OK: true})
 }
 
-// ChangeApiKey creates a new API key for a existing DB
-func (s *Server) ChangeApiKey(w http.ResponseWriter, r *http.Request) {
+// GetOrSetValue returns a value of a DB or stores the given default if the key is missing
+func (s *Server) GetOrSetValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
 	defer r.Body.Close()
 
-	// just check if the *envhandler.APIKEY_ENABLED is true, otherwise return service temporary unavailable
-	if !*envhandler.ENV.APIKEY_ENABLED {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		return
-	}
-
+	// bootstrap the request
 	dbname, err := s.bootstrap(r, w)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 
-	apikey, err := s.CreateApiKey(dbname)
+	// reject writes if the AOF exceeds HKV_MAX_AOF_BYTES
+	if s.storageFull(w, dbname) {
+		return
+	}
+
+	err, payload := readPayloadAndValidate[GetOrSet](r.Body, s)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeBadRequest(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(NewDBCreated{Name: dbname, Created: false, Exists: true, ApiKey: apikey})
-}
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
 
-// HealthHandler returns 200 OK
-func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	// values above HKV_ENTRY_SIZE are rejected or truncated by HKV_OVERSIZE_POLICY
+	if _, _, fits := hashMap.FitValue(payload.Value); !fits {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "value_too_large"})
+		return
+	}
+
+	// ttls above HKV_MAX_TTL_SECONDS are clamped or rejected by HKV_TTL_POLICY
+	ttl, fits := fitTTL(w, payload.Ttl)
+	if !fits {
+		return
+	}
+
+	// a collection at key or a full DB conflict with the default
+	val, created, ok := s.GetOrSet(dbname, payload.Key, payload.Value, ttl)
+	if !ok {
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(OK{OK: false})
+		return
+	}
 	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("ok"))
+	_ = json.NewEncoder(w).Encode(GetOrSetResponse{Value: val, Created: created})
 }
 
-/*************************/
-/* Handlers for FiFoLiFo */
-/*************************/
-
-// CreateFiFoLiFo creates a new FiFoLiFo
-func (s *Server) CreateFiFoLiFo(w http.ResponseWriter, r *http.Request) {
+// TouchValue refreshes the TTL of a key in a DB without reading or rewriting its value
+func (s *Server) TouchValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
 	defer r.Body.Close()
 
 	// bootstrap the request
@@ -248,25 +462,33 @@ func (s *Server) CreateFiFoLiFo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Here we have no need to bootstrap the request, since we dont need any DB info
-	err, payload := readPayloadAndValidate[NewLiFoFifo](r.Body, s)
+	err, payload := readPayloadAndValidate[Touch](r.Body, s)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeBadRequest(w, err)
 		return
 	}
 
-	// Create the FiFoLiFo
-	err = s.AddFifoLifo(dbname, payload.Name, payload.Limit)
-	if err != nil {
-		w.WriteHeader(http.StatusConflict)
-		log.Println(err)
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	// ttls above HKV_MAX_TTL_SECONDS are clamped or rejected by HKV_TTL_POLICY
+	ttl, fits := fitTTL(w, payload.Ttl)
+	if !fits {
 		return
 	}
-	w.WriteHeader(http.StatusCreated)
+
+	ok := s.Touch(dbname, payload.Key, ttl)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(OK{OK: ok})
 }
 
-// DeleteFiFoLiFo deletes a FiFoLiFo
-func (s *Server) DeleteFiFoLiFo(w http.ResponseWriter, r *http.Request) {
+// TypeValue returns the type of a value in a DB
+func (s *Server) TypeValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
 	defer r.Body.Close()
 
 	// bootstrap the request
@@ -276,23 +498,27 @@ func (s *Server) DeleteFiFoLiFo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Here we have no need to bootstrap the request, since we dont need any DB info
-	err, payload := readPayloadAndValidate[DeleteFiFoLiFo](r.Body, s)
+	err, payload := readPayloadAndValidate[Key](r.Body, s)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeBadRequest(w, err)
 		return
 	}
-	err = s.DelFiFoLiFo(dbname, payload.Name)
-	if err != nil {
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	typ, ok := s.Type(dbname, payload.Key)
+	if !ok {
 		w.WriteHeader(http.StatusNotFound)
-		log.Println(err)
-		return
+	} else {
+		w.WriteHeader(http.StatusOK)
 	}
-	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(KeyType{Found: ok, Type: typ})
 }
 
-// PushToFiFoLiFo pushes a value to a FiFoLiFo
-func (s *Server) PushToFiFoLiFo(w http.ResponseWriter, r *http.Request) {
+// MetaValue returns the metadata of a key without its value
+func (s *Server) MetaValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
 	defer r.Body.Close()
 
 	// bootstrap the request
@@ -302,25 +528,29 @@ func (s *Server) PushToFiFoLiFo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Here we have no need to bootstrap the request, since we dont need any DB info
-	err, payload := readPayloadAndValidate[PushFiFoLiFo](r.Body, s)
+	err, payload := readPayloadAndValidate[Key](r.Body, s)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeBadRequest(w, err)
 		return
 	}
 
-	// Push
-	pushed, err := s.PushEntryFiFoLiFo(dbname, payload.Name, payload.Value)
-	if err != nil || !pushed {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println(err)
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	meta, ok := s.Meta(dbname, payload.Key)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(KeyMeta{Exists: false})
 		return
 	}
 	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(KeyMeta{Exists: true, TtlRemaining: meta.Ttl, ModifiedAt: meta.ModifiedAt, Size: meta.Size,
+		ContentType: meta.ContentType})
 }
 
-// PopFromFiFo pops a value from a FiFoLiFo
-func (s *Server) PopFromFiFo(w http.ResponseWriter, r *http.Request) {
+// ExpiringValue lists the keys of a DB which expire within the given seconds
+func (s *Server) ExpiringValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
 	defer r.Body.Close()
 
 	// bootstrap the request
@@ -330,28 +560,21 @@ func (s *Server) PopFromFiFo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Here we have no need to bootstrap the request, since we dont need any DB info
-	err, payload := readPayloadAndValidate[PopFiFoLiFo](r.Body, s)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	// Pop
-	data, err := s.PopEntryFiFo(dbname, payload.Name)
+	err, payload := readPayloadAndValidate[Expiring](r.Body, s)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println(err)
+		writeBadRequest(w, err)
 		return
 	}
 
-	// return the data
+	keys := s.ExpiringWithin(dbname, payload.Seconds)
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(data)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(ExpiringKeys{Keys: keys, Truncated: len(keys) >= hashMap.MaxExpiring})
 }
 
-// PopFromLiFo pops a value from a FiFoLiFo
-func (s *Server) PopFromLiFo(w http.ResponseWriter, r *http.Request) {
+// MGetTTLValue gets several values of a DB with their remaining TTL in one request
+func (s *Server) MGetTTLValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
 	defer r.Body.Close()
 
 	// bootstrap the request
@@ -361,48 +584,1629 @@ func (s *Server) PopFromLiFo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Here we have no need to bootstrap the request, since we dont need any DB info
-	err, payload := readPayloadAndValidate[PopFiFoLiFo](r.Body, s)
+	err, payload := readPayloadAndValidate[MGetTTL](r.Body, s)
 	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	// bounded like a batch
+	if len(payload.Keys) > *envhandler.ENV.BATCH_MAX_OPS {
 		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ValidationError{Error: "validation_failed",
+			Fields: []FieldError{{Field: "keys", Rule: "max=" + strconv.Itoa(*envhandler.ENV.BATCH_MAX_OPS)}}})
 		return
 	}
 
-	// Pop
-	data, err := s.PopEntryLiFo(dbname, payload.Name)
+	values := s.MGetWithTTL(dbname, payload.Keys)
+	results := make([]KeyTTL, len(values))
+	for i, v := range values {
+		results[i] = KeyTTL{Key: payload.Keys[i], Found: v.Found}
+		if v.Found {
+			results[i].Value, results[i].Ttl = &v.Value, &v.TTL
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(KeyTTLs{Results: results})
+}
+
+// limits of a page of the changelog
+const (
+	defaultChangesLimit = 100
+	maxChangesLimit     = 1000
+)
+
+// ChangesValue returns the changes of a DB after ?since=<seq> - at most ?limit=<n> of them
+func (s *Server) ChangesValue(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println(err)
 		return
 	}
 
-	// return the data
+	// JSON Header
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(data)
-}
-
-// bootstrap checks if the DB exists, sets MaxHeaderBytes to the entry size and checks the dbname
-func (s *Server) bootstrap(r *http.Request, w http.ResponseWriter) (string, error) {
-	// secure request
-	r.Body = http.MaxBytesReader(w, r.Body, int64(*envhandler.ENV.ENTRY_SIZE))
 
-	// get the path
-	dbname := r.PathValue("dbname")
-	if dbname == "" {
-		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-		if len(parts) >= 2 && parts[0] == "db" {
-			dbname = parts[1]
+	since, limit := int64(0), defaultChangesLimit
+	if v := r.URL.Query().Get("since"); v != "" {
+		if since, err = strconv.ParseInt(v, 10, 64); err != nil || since < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(ValidationError{Error: "validation_failed",
+				Fields: []FieldError{{Field: "since", Rule: "min=0"}}})
+			return
+		}
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxChangesLimit {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(ValidationError{Error: "validation_failed",
+				Fields: []FieldError{{Field: "limit", Rule: "min=1,max=" + strconv.Itoa(maxChangesLimit)}}})
+			return
 		}
 	}
 
-	if !utils.U.CheckDbName(dbname) {
-		w.WriteHeader(http.StatusBadRequest)
-		return "", fmt.Errorf("invalid db name")
+	// action and prefix only return the matching changes, e.g. the expired keys of a cache
+	var match func(*hashMap.Change) bool
+	action, prefix := r.URL.Query().Get("action"), r.URL.Query().Get("prefix")
+	if action != "" || prefix != "" {
+		match = func(c *hashMap.Change) bool {
+			return (action == "" || c.Action == action) && strings.HasPrefix(c.Key, prefix)
+		}
 	}
 
-	if s.DBExists(dbname) == false {
+	changes, oldest, next, err := s.Changes(dbname, since, limit, match)
+	switch {
+	case errors.Is(err, hashMap.ErrChangelogDisabled):
 		w.WriteHeader(http.StatusNotFound)
-		return "", fmt.Errorf("DB %s does not exist", dbname)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "changelog_disabled"})
+		return
+	case err != nil:
+		log.Println("Error reading changelog:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "changelog_failed"})
+		return
+	}
+
+	page := Changes{Changes: make([]Change, len(changes)), Next: next, Oldest: oldest}
+	for i, c := range changes {
+		page.Changes[i] = Change{Seq: c.Seq, Time: c.Time, Action: c.Action, Key: c.Key, Field: c.Field, Value: c.Value,
+			ContentType: c.ContentType, Ttl: c.Ttl}
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+// EnableChangelogValue enables the changelog of a DB
+func (s *Server) EnableChangelogValue(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.EnableChangelog(dbname); err != nil {
+		log.Println("Error enabling changelog:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "changelog_failed"})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(OK{OK: true})
+}
+
+// DisableChangelogValue disables the changelog of a DB and deletes it
+func (s *Server) DisableChangelogValue(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.DisableChangelog(dbname); err != nil {
+		log.Println("Error disabling changelog:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "changelog_failed"})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(OK{OK: true})
+}
+
+// BatchValue executes an ordered list of operations in one request - the api key is checked once for the batch.
+// A rejected operation gets an error result and the batch goes on, it is not a transaction.
+func (s *Server) BatchValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[Batch](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	if len(payload.Ops) > *envhandler.ENV.BATCH_MAX_OPS {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ValidationError{Error: "validation_failed",
+			Fields: []FieldError{{Field: "ops", Rule: "max=" + strconv.Itoa(*envhandler.ENV.BATCH_MAX_OPS)}}})
+		return
+	}
+
+	// reject the batch if it writes and the AOF exceeds HKV_MAX_AOF_BYTES
+	for _, op := range payload.Ops {
+		if op.Op != "get" && op.Op != "exists" {
+			if s.storageFull(w, dbname) {
+				return
+			}
+			break
+		}
+	}
+
+	results := make([]BatchResult, len(payload.Ops))
+	for i, op := range payload.Ops {
+		results[i] = s.batchOp(dbname, op)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(BatchResults{Results: results})
+}
+
+// batchOp executes one operation of a batch
+func (s *Server) batchOp(dbname string, op BatchOp) BatchResult {
+	res := BatchResult{Op: op.Op}
+	switch op.Op {
+	case "set", "setnx":
+		// values above HKV_ENTRY_SIZE are rejected or truncated by HKV_OVERSIZE_POLICY
+		value, _, fits := hashMap.FitValue(op.Value)
+		if !fits {
+			res.Error = "value_too_large"
+			return res
+		}
+		ttl, fits := hashMap.FitTTL(int64(op.Ttl))
+		if !fits {
+			res.Error = "ttl_out_of_range"
+			return res
+		}
+		if op.Op == "set" {
+			res.OK = s.Set(dbname, op.Key, value, ttl)
+		} else {
+			res.OK = s.SetNX(dbname, op.Key, value, ttl)
+		}
+	case "get":
+		var value string
+		if res.OK, value = s.Get(dbname, op.Key); res.OK {
+			res.Value = &value
+		}
+	case "del":
+		res.OK = s.Del(dbname, op.Key)
+	case "exists":
+		_, res.OK = s.Type(dbname, op.Key)
+	case "incr":
+		// the amount defaults to 1
+		amount := int64(1)
+		if op.Amount != nil {
+			amount = *op.Amount
+		}
+		// a ttl of 0 keeps the expiry of the counter
+		ttl, fits := int64(op.Ttl), true
+		if ttl > 0 {
+			if ttl, fits = hashMap.FitTTL(ttl); !fits {
+				res.Error = "ttl_out_of_range"
+				return res
+			}
+		}
+		var count int64
+		if count, res.OK = s.CounterIncr(dbname, op.Key, amount, ttl); res.OK {
+			res.Count = &count
+		}
+	}
+	return res
+}
+
+// HashSetValue sets a field of a hash in a DB
+func (s *Server) HashSetValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// reject writes if the AOF exceeds HKV_MAX_AOF_BYTES
+	if s.storageFull(w, dbname) {
+		return
+	}
+
+	err, payload := readPayloadAndValidate[HashField](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	ok := s.HSet(dbname, payload.Key, payload.Field, payload.Value)
+	if !ok {
+		w.WriteHeader(http.StatusConflict)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(OK{OK: ok})
+}
+
+// HashGetValue returns a field of a hash in a DB
+func (s *Server) HashGetValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[HashField](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	ok, val := s.HGet(dbname, payload.Key, payload.Field)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(Value{Found: ok, Value: val})
+}
+
+// HashGetAllValue returns all fields of a hash in a DB
+func (s *Server) HashGetAllValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[Key](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	fields := s.HGetAll(dbname, payload.Key)
+	if fields == nil {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(HashValue{Found: fields != nil, Fields: fields})
+}
+
+// HashDeleteValue deletes a field of a hash in a DB
+func (s *Server) HashDeleteValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[HashField](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	ok := s.HDel(dbname, payload.Key, payload.Field)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(OK{OK: ok})
+}
+
+// SetAddValue adds a member to a set in a DB
+func (s *Server) SetAddValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// reject writes if the AOF exceeds HKV_MAX_AOF_BYTES
+	if s.storageFull(w, dbname) {
+		return
+	}
+
+	err, payload := readPayloadAndValidate[SetMember](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	ok := s.SAdd(dbname, payload.Key, payload.Member)
+	if !ok {
+		w.WriteHeader(http.StatusConflict)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(OK{OK: ok})
+}
+
+// SetRemoveValue removes a member of a set in a DB
+func (s *Server) SetRemoveValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[SetMember](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	ok := s.SRem(dbname, payload.Key, payload.Member)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(OK{OK: ok})
+}
+
+// SetIsMemberValue checks if a value is a member of a set in a DB
+func (s *Server) SetIsMemberValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[SetMember](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	ok := s.SIsMember(dbname, payload.Key, payload.Member)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(OK{OK: ok})
+}
+
+// SetMembersValue returns the members of a set in a DB
+func (s *Server) SetMembersValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[Key](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	members := s.SMembers(dbname, payload.Key)
+	if members == nil {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(SetMembers{Found: members != nil, Members: members, Card: len(members)})
+}
+
+// ZSetAddValue adds a member to a sorted set in a DB or updates its score
+func (s *Server) ZSetAddValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// reject writes if the AOF exceeds HKV_MAX_AOF_BYTES
+	if s.storageFull(w, dbname) {
+		return
+	}
+
+	err, payload := readPayloadAndValidate[ZSetMember](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	ok := s.ZAdd(dbname, payload.Key, payload.Member, payload.Score)
+	if !ok {
+		w.WriteHeader(http.StatusConflict)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(OK{OK: ok})
+}
+
+// ZSetScoreValue returns the score of a member of a sorted set in a DB
+func (s *Server) ZSetScoreValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[ZSetMember](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	score, ok := s.ZScore(dbname, payload.Key, payload.Member)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(ZSetScore{Found: ok, Score: score})
+}
+
+// ZSetRankValue returns the rank of a member of a sorted set in a DB
+func (s *Server) ZSetRankValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[ZSetMember](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	rank, ok := s.ZRank(dbname, payload.Key, payload.Member)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(ZSetRank{Found: ok, Rank: rank})
+}
+
+// ZSetRangeValue returns a range of a sorted set in a DB
+func (s *Server) ZSetRangeValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[ZSetRange](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	members := s.ZRange(dbname, payload.Key, payload.Start, payload.Stop)
+	if members == nil {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	entries := make([]ZSetEntry, len(members))
+	for i, z := range members {
+		entries[i] = ZSetEntry{Member: z.Member, Score: z.Score}
+	}
+	_ = json.NewEncoder(w).Encode(ZSetMembers{Found: members != nil, Members: entries})
+}
+
+// ListPushValue prepends (lpush) or appends (rpush) a value to a list in a DB
+func (s *Server) ListPushValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// reject writes if the AOF exceeds HKV_MAX_AOF_BYTES
+	if s.storageFull(w, dbname) {
+		return
+	}
+
+	err, payload := readPayloadAndValidate[ListPush](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	var ok bool
+	if strings.HasSuffix(r.URL.Path, "/lpush") {
+		ok = s.LPush(dbname, payload.Key, payload.Value)
+	} else {
+		ok = s.RPush(dbname, payload.Key, payload.Value)
+	}
+
+	if !ok {
+		w.WriteHeader(http.StatusConflict)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(OK{OK: ok})
+}
+
+// ListPopValue removes and returns the first (lpop) or the last (rpop) value of a list in a DB
+func (s *Server) ListPopValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[Key](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	var val string
+	var ok bool
+	if strings.HasSuffix(r.URL.Path, "/lpop") {
+		val, ok = s.LPop(dbname, payload.Key)
+	} else {
+		val, ok = s.RPop(dbname, payload.Key)
+	}
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(Value{Found: ok, Value: val})
+}
+
+// ListRangeValue returns a range and the length of a list in a DB
+func (s *Server) ListRangeValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[ListRange](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	values := s.LRange(dbname, payload.Key, payload.Start, payload.Stop)
+	if values == nil {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(ListValues{Found: values != nil, Values: values, Len: s.LLen(dbname, payload.Key)})
+}
+
+// CounterValue increments or decrements a counter in a DB
+func (s *Server) CounterValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// reject writes if the AOF exceeds HKV_MAX_AOF_BYTES
+	if s.storageFull(w, dbname) {
+		return
+	}
+
+	err, payload := readPayloadAndValidate[Counter](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// the amount defaults to 1
+	amount := int64(1)
+	if payload.Amount != nil {
+		amount = *payload.Amount
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	// a ttl of 0 keeps the expiry of the counter
+	ttl := int64(payload.Ttl)
+	if ttl > 0 {
+		var fits bool
+		if ttl, fits = fitTTL(w, ttl); !fits {
+			return
+		}
+	}
+
+	var value int64
+	var ok bool
+	if strings.HasSuffix(r.URL.Path, "/decr") {
+		value, ok = s.CounterDecr(dbname, payload.Key, amount, ttl)
+	} else {
+		value, ok = s.CounterIncr(dbname, payload.Key, amount, ttl)
+	}
+
+	if !ok {
+		w.WriteHeader(http.StatusConflict)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(CounterValue{OK: ok, Value: value})
+}
+
+// RateLimitValue counts a call against a rate limit - 429 if the limit of the window is reached
+func (s *Server) RateLimitValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// reject writes if the AOF exceeds HKV_MAX_AOF_BYTES
+	if s.storageFull(w, dbname) {
+		return
+	}
+
+	err, payload := readPayloadAndValidate[RateLimit](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	// the ttl is the window - 0 never ends it
+	ttl, fits := fitTTL(w, payload.Ttl)
+	if !fits {
+		return
+	}
+
+	count, allowed := s.IncrAndCheck(dbname, payload.Key, payload.Limit, ttl)
+	if !allowed {
+		w.WriteHeader(http.StatusTooManyRequests)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(RateLimitValue{Allowed: allowed, Count: count})
+}
+
+// SetIfValue sets a number only if it is greater (setifgreater) or less (setifless) than the stored number
+func (s *Server) SetIfValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// reject writes if the AOF exceeds HKV_MAX_AOF_BYTES
+	if s.storageFull(w, dbname) {
+		return
+	}
+
+	err, payload := readPayloadAndValidate[Set](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	ttl, fits := fitTTL(w, int64(payload.Ttl))
+	if !fits {
+		return
+	}
+
+	var ok bool
+	if strings.HasSuffix(r.URL.Path, "/setifless") {
+		ok = s.SetIfLess(dbname, payload.Key, payload.Value, ttl)
+	} else {
+		ok = s.SetIfGreater(dbname, payload.Key, payload.Value, ttl)
+	}
+
+	if !ok {
+		w.WriteHeader(http.StatusConflict)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(OK{OK: ok})
+}
+
+// SetBitValue sets or clears a bit of a value in a DB
+func (s *Server) SetBitValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// reject writes if the AOF exceeds HKV_MAX_AOF_BYTES
+	if s.storageFull(w, dbname) {
+		return
+	}
+
+	err, payload := readPayloadAndValidate[SetBit](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	// Set the bit and return the original one
+	old, ok := s.SetBit(dbname, payload.Key, payload.Offset, payload.Bit)
+	if !ok {
+		w.WriteHeader(http.StatusConflict)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(Bit{OK: ok, Bit: old})
+}
+
+// GetBitValue gets a bit of a value from a DB
+func (s *Server) GetBitValue(w http.ResponseWriter, r *http.Request) {
+	// Close the Body on return
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[GetBit](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// JSON Header
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(Bit{OK: true, Bit: s.GetBit(dbname, payload.Key, payload.Offset)})
+}
+
+// DB checks if the DB exists
+func (s *Server) DB(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	dbname := r.PathValue("dbname")
+
+	if !utils.U.CheckDbName(dbname) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	ok := s.DBExists(dbname)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(ExistsResponse{Exists: ok})
+}
+
+func (s *Server) DeleteDB(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// Delet the DB and return
+	s.DBDelete(dbname)
+	w.WriteHeader(http.StatusOK)
+}
+
+// ChangeApiKey creates a new API key for a existing DB
+func (s *Server) ChangeApiKey(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// just check if the *envhandler.APIKEY_ENABLED is true, otherwise return service temporary unavailable
+	if !*envhandler.ENV.APIKEY_ENABLED {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	apikey, err := s.CreateApiKey(dbname)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(NewDBCreated{Name: dbname, Created: false, Exists: true, ApiKey: apikey})
+}
+
+// ChangeReadKey creates a new read-only API key for a existing DB - the write key stays valid
+func (s *Server) ChangeReadKey(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// just check if the *envhandler.APIKEY_ENABLED is true, otherwise return service temporary unavailable
+	if !*envhandler.ENV.APIKEY_ENABLED {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	readkey, err := s.CreateReadKey(dbname)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(ReadKeyCreated{Name: utils.U.DbKey(dbname), ReadKey: readkey})
+}
+
+// ListApiKeys lists the DBs with api keys and the metadata of their keys - the keys themselves are never listed
+func (s *Server) ListApiKeys(w http.ResponseWriter, r *http.Request) {
+	keys := utils.U.ListApiKeys()
+	list := ApiKeyList{DBs: make([]ApiKeyDB, 0, len(keys))}
+	for name, infos := range keys {
+		list.DBs = append(list.DBs, ApiKeyDB{Name: name, Keys: infos})
+	}
+	sort.Slice(list.DBs, func(i, j int) bool { return list.DBs[i].Name < list.DBs[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// ConfigDump lists the effective settings - secrets are redacted
+func (s *Server) ConfigDump(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(Config{Settings: envhandler.ENV.Settings()})
+}
+
+// SetRequestLimits changes the HTTP and gRPC request limits and the wait for a free slot at runtime. The new
+// values are kept by a reload of HKV_ENV_FILE until the restart.
+func (s *Server) SetRequestLimits(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(*envhandler.ENV.MAX_BODY_BYTES))
+	defer r.Body.Close()
+
+	err, payload := readPayloadAndValidate[RequestLimitsUpdate](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	values := make(map[string]string)
+	if payload.HTTP != nil {
+		values[envhandler.REQ_LIMIT] = strconv.Itoa(*payload.HTTP)
+	}
+	if payload.GRPC != nil {
+		values[envhandler.GRPC_REQ_LIMIT] = strconv.Itoa(*payload.GRPC)
+	}
+	if payload.WaitMs != nil {
+		values[envhandler.REQUEST_WAIT_MS] = strconv.Itoa(*payload.WaitMs)
+	}
+	if changed := envhandler.ENV.Override(values); len(changed) > 0 {
+		s.Reload()
+		log.Printf("Admin: changed the request limits %v\n", changed)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(RequestLimits{HTTP: envhandler.ENV.ReqLimit(),
+		GRPC: envhandler.ENV.GRPCReqLimit(), WaitMs: envhandler.ENV.RequestWaitMs()})
+}
+
+// SwapDBsValue swaps the contents of two DBs - the requests of both see the other data afterward
+func (s *Server) SwapDBsValue(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(*envhandler.ENV.MAX_BODY_BYTES))
+	defer r.Body.Close()
+
+	err, payload := readPayloadAndValidate[Swap](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	if !utils.U.CheckDbName(payload.A) || !utils.U.CheckDbName(payload.B) {
+		http.Error(w, "invalid db name", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch err := s.SwapDBs(payload.A, payload.B); {
+	case errors.Is(err, errSwapNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "db_not_found"})
+	case errors.Is(err, errSwapLoading):
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "db_loading"})
+	case err != nil:
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "swap_failed"})
+	default:
+		log.Printf("Admin: swapped the DBs %s and %s\n", utils.U.DbKey(payload.A), utils.U.DbKey(payload.B))
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(OK{OK: true})
+	}
+}
+
+// FlushAllDBs flushes all DBs - with ?drop=true the DBs are deleted instead
+func (s *Server) FlushAllDBs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	drop := false
+	if v := r.URL.Query().Get("drop"); v != "" {
+		var err error
+		if drop, err = strconv.ParseBool(v); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(ValidationError{Error: "validation_failed",
+				Fields: []FieldError{{Field: "drop", Rule: "boolean"}}})
+			return
+		}
+	}
+
+	n := s.FlushAll(drop)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(FlushAll{DBs: n, Dropped: drop})
+}
+
+// maxHotBaskets is the largest top of HotBasketsValue
+const maxHotBaskets = 1000
+
+// HotBasketsValue lists the ?top=N (default 10) fullest baskets of a DB with some of their keys
+func (s *Server) HotBasketsValue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	dbname := r.PathValue("dbname")
+	if !utils.U.CheckDbName(dbname) {
+		http.Error(w, "invalid db name", http.StatusBadRequest)
+		return
+	}
+
+	top := 10
+	if v := r.URL.Query().Get("top"); v != "" {
+		var err error
+		if top, err = strconv.Atoi(v); err != nil || top < 1 || top > maxHotBaskets {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(ValidationError{Error: "validation_failed",
+				Fields: []FieldError{{Field: "top", Rule: "min=1,max=" + strconv.Itoa(maxHotBaskets)}}})
+			return
+		}
+	}
+
+	res, ok := s.HotBaskets(dbname, top)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "db_not_found"})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// SyncAllDBs fsyncs the AOFs of all DBs and answers once the writes done before are durable
+func (s *Server) SyncAllDBs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	n, err := s.SyncAll()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "sync_failed"})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(Synced{DBs: n})
+}
+
+// BackupDBs streams a tar archive of all DBs - with ?keys=false the api key hashes are left out
+func (s *Server) BackupDBs(w http.ResponseWriter, r *http.Request) {
+	withKeys := true
+	if v := r.URL.Query().Get("keys"); v != "" {
+		var err error
+		if withKeys, err = strconv.ParseBool(v); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(ValidationError{Error: "validation_failed",
+				Fields: []FieldError{{Field: "keys", Rule: "boolean"}}})
+			return
+		}
+	}
+
+	// a big backup may take longer than HKV_WRITE_TIMEOUT
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", `attachment; filename="hydrakv-backup.tar"`)
+	if err := s.Backup(w, withKeys); err != nil {
+		// the status is already sent - the client gets a truncated archive
+		log.Println("Error writing backup:", err)
+	}
+}
+
+// RestoreDBs restores the DBs of a backup archive - existing DBs are skipped
+func (s *Server) RestoreDBs(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// a big restore may take longer than HKV_READ_TIMEOUT
+	_ = http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/json")
+	result, err := s.Restore(r.Body)
+	if err != nil {
+		log.Println("Error restoring backup:", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(struct {
+			ValidationError
+			RestoreResult
+		}{ValidationError{Error: "invalid_backup", Message: err.Error()}, result})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// IssueApiKey creates a new write key for a existing DB without the current key, e.g. after a revoke
+func (s *Server) IssueApiKey(w http.ResponseWriter, r *http.Request) {
+	dbname := r.PathValue("dbname")
+	if !utils.U.CheckDbName(dbname) {
+		http.Error(w, "invalid db name", http.StatusBadRequest)
+		return
+	}
+	if !s.DBExists(dbname) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	apikey, err := s.CreateApiKey(dbname)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(NewDBCreated{Name: utils.U.DbKey(dbname), Created: false, Exists: true, ApiKey: apikey})
+}
+
+// RevokeApiKeys removes all api keys of a DB - the DB is inaccessible until a new key is issued
+func (s *Server) RevokeApiKeys(w http.ResponseWriter, r *http.Request) {
+	dbname := r.PathValue("dbname")
+	if !utils.U.CheckDbName(dbname) {
+		http.Error(w, "invalid db name", http.StatusBadRequest)
+		return
+	}
+
+	found, err := utils.U.RevokeApiKeys(dbname)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// HealthHandler is the readiness check - it returns 503 with the problems if the data directory is not writable or
+// an AOF is failing. DBs rejecting writes because of HKV_MAX_AOF_BYTES are listed, but do not fail the check.
+func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if problems := s.Readiness(); len(problems) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(strings.Join(problems, "\n")))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+
+	full := make([]string, 0)
+	dbs, _ := s.ListDBs("", 0, 0)
+	for _, db := range dbs {
+		if db.StorageFull {
+			full = append(full, db.Name)
+		}
+	}
+	if len(full) > 0 {
+		sort.Strings(full)
+		_, _ = w.Write([]byte("storage_full: " + strings.Join(full, ",")))
+		return
+	}
+	_, _ = w.Write([]byte("ok"))
+}
+
+// LivezHandler is the liveness check - it always returns 200 OK
+func (s *Server) LivezHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// writeBadRequest writes a 400 with a JSON body telling if the payload is malformed, has unknown fields or
+// violates validation rules
+func writeBadRequest(w http.ResponseWriter, err error) {
+	resp := ValidationError{Error: "invalid_json", Message: err.Error()}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	var validationErrs validator.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		resp = ValidationError{Error: "validation_failed", Fields: make([]FieldError, 0, len(validationErrs))}
+		for _, fe := range validationErrs {
+			resp.Fields = append(resp.Fields, FieldError{Field: fe.Field(), Rule: fe.Tag()})
+		}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		resp.Error = "invalid_json"
+	case errors.As(err, &typeErr):
+		resp = ValidationError{Error: "invalid_type", Message: err.Error(),
+			Fields: []FieldError{{Field: typeErr.Field, Rule: typeErr.Type.String()}}}
+	case errors.As(err, &maxBytesErr):
+		resp.Error = "payload_too_large"
+	case strings.HasPrefix(err.Error(), "json: unknown field"):
+		resp = ValidationError{Error: "unknown_field", Message: err.Error(),
+			Fields: []FieldError{{Field: strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`), Rule: "unknown"}}}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// fitTTL applies HKV_MAX_TTL_SECONDS and HKV_ALLOW_NO_EXPIRY to a requested ttl. It returns the ttl to store, or
+// writes a 400 and returns false if the ttl is rejected.
+func fitTTL(w http.ResponseWriter, ttl int64) (int64, bool) {
+	fitted, fits := hashMap.FitTTL(ttl)
+	if !fits {
+		rule := "max=" + strconv.FormatInt(fitted, 10)
+		if ttl == 0 {
+			rule = "min=1"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ValidationError{Error: "validation_failed",
+			Fields: []FieldError{{Field: "ttl", Rule: rule}}})
+	}
+	return fitted, fits
+}
+
+// storageFull writes a 507 if the DB rejects writes because its AOF exceeds HKV_MAX_AOF_BYTES, or a 503 while its
+// AOF write queue is backed up
+func (s *Server) storageFull(w http.ResponseWriter, dbname string) bool {
+	if s.DBThrottled(dbname) {
+		w.Header().Set("Retry-After", "1")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "aof_backlog"})
+		return true
+	}
+	if s.DBWritable(dbname) {
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInsufficientStorage)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "storage_full"})
+	return true
+}
+
+// StatsHandler returns the stats of all DBs as JSON - or a page of ?offset= and ?limit= of those starting with ?prefix=.
+// Like the index they list the DB names, so they are only returned where the index lists the DBs.
+func (s *Server) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.indexListed(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "forbidden"})
+		return
+	}
+	prefix, offset, limit, ok := readDBPage(w, r, 0)
+	if !ok {
+		return
+	}
+	dbs, total := s.ListDBs(prefix, offset, limit)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(Stats{DBs: dbs, Total: total})
+}
+
+/*************************/
+/* Handlers for FiFoLiFo */
+/*************************/
+
+// CreateFiFoLiFo creates a new FiFoLiFo
+func (s *Server) CreateFiFoLiFo(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// Here we have no need to bootstrap the request, since we dont need any DB info
+	err, payload := readPayloadAndValidate[NewLiFoFifo](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// Create the FiFoLiFo
+	err = s.AddFifoLifo(dbname, payload.Name, payload.Limit)
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		log.Println(err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// DeleteFiFoLiFo deletes a FiFoLiFo
+func (s *Server) DeleteFiFoLiFo(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// Here we have no need to bootstrap the request, since we dont need any DB info
+	err, payload := readPayloadAndValidate[DeleteFiFoLiFo](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	err = s.DelFiFoLiFo(dbname, payload.Name)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		log.Println(err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// PushToFiFoLiFo pushes a value to a FiFoLiFo
+func (s *Server) PushToFiFoLiFo(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// Here we have no need to bootstrap the request, since we dont need any DB info
+	err, payload := readPayloadAndValidate[PushFiFoLiFo](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// Push
+	pushed, err := s.PushEntryFiFoLiFo(dbname, payload.Name, payload.Value)
+	if err != nil || !pushed {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// PopFromFiFo pops a value from a FiFoLiFo
+func (s *Server) PopFromFiFo(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// Here we have no need to bootstrap the request, since we dont need any DB info
+	err, payload := readPayloadAndValidate[PopFiFoLiFo](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// Pop - with ?wait=seconds an empty FiFo is waited on, up to the write timeout of the server
+	var data string
+	if wait := r.URL.Query().Get("wait"); wait != "" {
+		seconds, convErr := strconv.Atoi(wait)
+		if convErr != nil || seconds < 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(ValidationError{Error: "validation_failed",
+				Fields: []FieldError{{Field: "wait", Rule: "min=0"}}})
+			return
+		}
+		seconds = min(seconds, max(*envhandler.ENV.WRITE_TIMEOUT-1, 0))
+		data, err = s.BPopEntryFiFo(r.Context(), dbname, payload.Name, time.Duration(seconds)*time.Second)
+	} else {
+		data, err = s.PopEntryFiFo(dbname, payload.Name)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	// return the data
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// PopFromLiFo pops a value from a FiFoLiFo
+func (s *Server) PopFromLiFo(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// Here we have no need to bootstrap the request, since we dont need any DB info
+	err, payload := readPayloadAndValidate[PopFiFoLiFo](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// Pop
+	data, err := s.PopEntryLiFo(dbname, payload.Name)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	// return the data
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// MoveFiFoLiFoValue pops the oldest value of a FiFoLiFo and pushes it to another one in one step
+func (s *Server) MoveFiFoLiFoValue(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// bootstrap the request
+	dbname, err := s.bootstrap(r, w)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err, payload := readPayloadAndValidate[MoveFiFoLiFo](r.Body, s)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// Move - a full destination keeps the value in the source
+	data, err := s.MoveFiFoLiFo(dbname, payload.Src, payload.Dst)
+	if errors.Is(err, fifolifo.ErrFull) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// return the data
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// bootstrap checks if the DB exists, limits the body to HKV_MAX_BODY_BYTES and checks the dbname
+func (s *Server) bootstrap(r *http.Request, w http.ResponseWriter) (string, error) {
+	// secure request
+	r.Body = http.MaxBytesReader(w, r.Body, int64(*envhandler.ENV.MAX_BODY_BYTES))
+
+	// get the path
+	dbname := r.PathValue("dbname")
+	if dbname == "" {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) >= 2 && parts[0] == "db" {
+			dbname = parts[1]
+		}
+	}
+
+	if !utils.U.CheckDbName(dbname) {
+		w.WriteHeader(http.StatusBadRequest)
+		return "", fmt.Errorf("invalid db name")
+	}
+
+	// a missing DB has a body, so clients can tell it from a missing key
+	if s.DBExists(dbname) == false {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "db_not_found"})
+		return "", fmt.Errorf("DB %s does not exist", dbname)
+	}
+
+	// the DB is still replaying its AOF
+	if s.DBLoading(dbname) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "db_loading"})
+		return "", fmt.Errorf("DB %s is still loading", dbname)
 	}
 	return dbname, nil
 }
@@ -1,7 +1,11 @@
 package server
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
 	"hydrakv/envhandler"
 	"hydrakv/hashMap"
@@ -11,6 +15,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"reflect"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -29,29 +36,101 @@ type Server struct {
 	validate  *validator.Validate
 	templates *template.Template
 	mut       *sync.RWMutex
+	loading   sync.WaitGroup
+	// idempotency remembers the results of writes with an Idempotency-Key header
+	idempotency *idempotencyStore
+	// limiter caps the running HTTP requests - kept to apply a reload
+	limiter *requestLimiter
+	// reloadHooks are called by Reload, e.g. to resize the gRPC limiter - registered before the server starts
+	reloadHooks []func()
+	// dropping is read locked while detached DBs are dropped - NewDB waits for them, so a DB with the same name
+	// never opens the AOF of a dropped one
+	dropping sync.RWMutex
 }
 
-// DBObject represents a database object with its name, number of entries, and number of baskets.
+// DBObject represents a database object with its name, number of entries, number of baskets and loading state.
 type DBObject struct {
-	Name    string
-	Entries int64
-	Baskets int
+	Name    string `json:"name"`
+	Entries int64  `json:"entries"`
+	Baskets int    `json:"baskets"`
+	Loading bool   `json:"loading"`
+	// CompactRatio is the current ratio of deleted to live entries since the last compaction
+	CompactRatio float64 `json:"compact_ratio"`
+	// StorageFull is true if writes are rejected because the AOF exceeds HKV_MAX_AOF_BYTES
+	StorageFull bool `json:"storage_full"`
+	// Throttled is true if writes are rejected because the AOF write queue is backed up
+	Throttled bool `json:"throttled"`
+	// TTLEntries and TTLBuckets are the entries with a TTL and their expiry buckets tracked by the TTLManager
+	TTLEntries int64 `json:"ttl_entries"`
+	TTLBuckets int64 `json:"ttl_buckets"`
+	// ApproxKeys is the estimated number of distinct keys if HKV_APPROX_CARDINALITY is set
+	ApproxKeys int64 `json:"approx_keys,omitempty"`
+	// Replay is the progress of the AOF replay while the DB is loading
+	Replay *ReplayStatus `json:"replay,omitempty"`
+}
+
+// ReplayStatus is the progress of the AOF replay of a loading DB
+type ReplayStatus struct {
+	Frames     int64 `json:"frames"`
+	Bytes      int64 `json:"bytes"`
+	TotalBytes int64 `json:"total_bytes"`
+	ElapsedMs  int64 `json:"elapsed_ms"`
 }
 
 // kvLogic defines an interface for key-value storage logic with methods for managing databases and key-value pairs.
 type kvLogic interface {
 	NewDB(name string) (err error, exists bool, created bool, apikey string)
 	Set(db string, key string, value string, ttl int64) bool
+	SetKeepTTL(db string, key string, value string) bool
+	SetWithContentType(db, key, value, contentType string, ttl int64, keepTTL bool) bool
 	SetNX(db string, key string, value string, ttl int64) bool
+	GetOrSet(db, key, defaultValue string, ttl int64) (value string, created bool, ok bool)
+	SetIfGreater(db, key, value string, ttl int64) bool
+	SetIfLess(db, key, value string, ttl int64) bool
 	Get(db, key string) (bool, string)
+	GetEX(db, key string, ttl int64) (bool, string)
+	Type(db, key string) (string, bool)
+	Meta(db, key string) (hashMap.KeyMeta, bool)
+	MGetWithTTL(db string, keys []string) []hashMap.KeyTTL
+	ExpiringWithin(db string, seconds int64) []string
+	HSet(db, key, field, value string) bool
+	HGet(db, key, field string) (bool, string)
+	HGetAll(db, key string) map[string]string
+	HDel(db, key, field string) bool
+	SAdd(db, key, member string) bool
+	SRem(db, key, member string) bool
+	SIsMember(db, key, member string) bool
+	SMembers(db, key string) []string
+	SCard(db, key string) int
+	ZAdd(db, key, member string, score float64) bool
+	ZScore(db, key, member string) (float64, bool)
+	ZRank(db, key, member string) (int, bool)
+	ZRange(db, key string, start, stop int) []hashMap.ZMember
+	LPush(db, key, value string) bool
+	RPush(db, key, value string) bool
+	LPop(db, key string) (string, bool)
+	RPop(db, key string) (string, bool)
+	LRange(db, key string, start, stop int) []string
+	LLen(db, key string) int
 	Incr(db, key, amount string) bool
+	CounterIncr(db, key string, amount, ttl int64) (int64, bool)
+	CounterDecr(db, key string, amount, ttl int64) (int64, bool)
+	IncrAndCheck(db, key string, limit, ttl int64) (int64, bool)
+	FlushAll(drop bool) int
+	SetBit(db, key string, offset int64, bit int) (int, bool)
+	GetBit(db, key string, offset int64) int
 	Del(db, key string) bool
 	DBExists(db string) bool
+	DBLoading(db string) bool
+	DBWritable(db string) bool
+	DBThrottled(db string) bool
 	AddFifoLifo(db string, name string, maxEntries int) error
 	DelFiFoLiFo(db string, name string) error
 	PushEntryFiFoLiFo(db string, fifolifoName string, data string) (bool, error)
 	PopEntryFiFo(db string, fifolifoName string) (string, error)
 	PopEntryLiFo(db string, fifolifoName string) (string, error)
+	MoveFiFoLiFo(db string, src string, dst string) (string, error)
+	BPopEntryFiFo(ctx context.Context, db string, fifolifoName string, timeout time.Duration) (string, error)
 }
 
 // NewServer initializes and returns a new Server instance configured with the provided port and IP address.
@@ -66,16 +145,34 @@ func NewServer(port int, ip string) *Server {
 	// Create the ServeMux and the RequestLimiter for HTTP
 	publicMux := http.NewServeMux()
 	privateMux := http.NewServeMux()
+	adminMux := http.NewServeMux()
 
-	limitWrapper := newRequestLimiter()
+	server.limiter = newRequestLimiter()
+	ipFilter := newIPFilter()
 
 	rootHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// IP allow- and denylist
+		if ipFilter.enabled() && !isUnixRequest(r) && !ipFilter.allowed(httpClientIP(r)) {
+			http.Error(w, "ip not allowed", http.StatusForbidden)
+			return
+		}
+
 		// Public routes
 		if utils.U.IsPublicPath(r.URL.Path) {
 			publicMux.ServeHTTP(w, r)
 			return
 		}
 
+		// Admin routes are protected by HKV_ADMIN_KEY
+		if strings.HasPrefix(r.URL.Path, "/admin/") {
+			if isAdmin(r) {
+				adminMux.ServeHTTP(w, r)
+				return
+			}
+			http.Error(w, "invalid admin key", http.StatusUnauthorized)
+			return
+		}
+
 		// disabled APIKEY
 		if !*envhandler.ENV.APIKEY_ENABLED {
 			privateMux.ServeHTTP(w, r)
@@ -90,39 +187,79 @@ func NewServer(port int, ip string) *Server {
 				dbName = parts[1]
 			}
 		}
-		dbName = strings.ToUpper(dbName)
+		dbName = utils.U.DbKey(dbName)
 
 		if utils.U.CheckDbName(dbName) == false {
 			http.Error(w, "invalid db name", http.StatusBadRequest)
 			return
 		}
 
-		key := r.Header.Get("X-API-Key")
-		if key == "" || !utils.U.IsApiKeyValid(dbName, key) {
+		// read-only requests accept read keys as well
+		switch authorize(server, "http", httpClientIP(r), dbName, r.Header.Get("X-API-Key"), isReadRequest(r)) {
+		case authGranted:
+			privateMux.ServeHTTP(w, r)
+		case authReadOnly:
+			http.Error(w, "read-only api key", http.StatusForbidden)
+		case authLocked:
+			w.Header().Set("Retry-After", strconv.Itoa(envhandler.ENV.AuthLockoutCooldown()))
+			http.Error(w, "too many failed auth attempts", http.StatusTooManyRequests)
+		default:
 			http.Error(w, "invalid api key", http.StatusUnauthorized)
-			return
 		}
-		privateMux.ServeHTTP(w, r)
 	})
 
 	server.dbs = make(map[string]*hashMap.HashMap)
 	server.validate = validator.New()
+	// validation errors name the JSON fields
+	server.validate.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" || name == "" {
+			return f.Name
+		}
+		return name
+	})
+	// DB names follow HKV_DBNAME_REGEX and HKV_DBNAME_MAXLEN
+	_ = server.validate.RegisterValidation("dbname", func(fl validator.FieldLevel) bool {
+		return utils.U.CheckDbName(fl.Field().String())
+	})
 	server.templates = templates
 	server.mut = &sync.RWMutex{}
+	server.idempotency = newIdempotencyStore()
 	server.Server = &http.Server{Addr: ip + ":" + strconv.Itoa(port),
-		Handler:        limitWrapper.wrap(rootHandler),
+		Handler:        server.limiter.wrap(newCorsHandler().wrap(newGzipHandler().wrap(rootHandler))),
 		WriteTimeout:   time.Duration(*envhandler.ENV.WRITE_TIMEOUT) * time.Second,
 		ReadTimeout:    time.Duration(*envhandler.ENV.READ_TIMEOUT) * time.Second,
 		IdleTimeout:    time.Duration(*envhandler.ENV.IDLE_TIMEOUT) * time.Second,
 		MaxHeaderBytes: *envhandler.ENV.MAX_HEADER_BATES,
 	}
 
+	// h2c multiplexes requests over one connection - served by net/http itself, so the connections stay tracked
+	// for Shutdown and the connection limit, and every request passes the limiter and middlewares like HTTP/1.1
+	if *envhandler.ENV.HTTP2_H2C {
+		server.Server.Protocols = new(http.Protocols)
+		server.Server.Protocols.SetHTTP1(true)
+		server.Server.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	// the open connections per client IP are limited by HKV_MAX_CONNS_PER_IP
+	if connLimit := newConnLimiter(); connLimit.enabled() {
+		server.Server.Handler = connLimit.wrap(server.Server.Handler)
+		server.Server.ConnState = connLimit.connState
+		server.Server.ConnContext = connLimit.connContext
+	}
+
 	// shows the startpage with some information
 	publicMux.HandleFunc("GET /", server.Index)
 
-	// Prometheus healthroute
+	// Prometheus healthroute - readiness of the storage
 	publicMux.HandleFunc("GET /health", server.HealthHandler)
 
+	// Liveness of the process
+	publicMux.HandleFunc("GET /livez", server.LivezHandler)
+
+	// Stats of all DBs - only where the index lists them
+	publicMux.HandleFunc("GET /stats", server.StatsHandler)
+
 	// Prometheus metrics route
 	publicMux.Handle("GET /metrics", promhttp.Handler())
 
@@ -133,20 +270,94 @@ func NewServer(port int, ip string) *Server {
 	privateMux.HandleFunc("GET /db/{dbname}", server.DB)
 
 	// Sets a value in a DB
-	privateMux.HandleFunc("PUT /db/{dbname}", server.SetValue)
+	privateMux.HandleFunc("PUT /db/{dbname}", server.idempotency.wrap(server.SetValue))
 
 	// Sets a value in a DB if its key doesnt exists
-	privateMux.HandleFunc("POST /db/{dbname}", server.SetValue)
+	privateMux.HandleFunc("POST /db/{dbname}", server.idempotency.wrap(server.SetValue))
 
 	// Increments a value in a DB
-	privateMux.HandleFunc("PATCH /db/{dbname}", server.SetValue)
+	privateMux.HandleFunc("PATCH /db/{dbname}", server.idempotency.wrap(server.SetValue))
 
 	// Deletes a value from a DB
-	privateMux.HandleFunc("DELETE /db/{dbname}/keys", server.DeleteValue)
+	privateMux.HandleFunc("DELETE /db/{dbname}/keys", server.idempotency.wrap(server.DeleteValue))
 
 	// Gets a value from a DB
 	privateMux.HandleFunc("POST /db/{dbname}/keys", server.GetValue)
 
+	// Gets the type of a value
+	privateMux.HandleFunc("POST /db/{dbname}/type", server.TypeValue)
+	privateMux.HandleFunc("POST /db/{dbname}/meta", server.MetaValue)
+
+	// list the keys expiring soon
+	privateMux.HandleFunc("POST /db/{dbname}/expiring", server.ExpiringValue)
+
+	// Gets several values with their remaining TTL
+	privateMux.HandleFunc("POST /db/{dbname}/mget-ttl", server.MGetTTLValue)
+
+	// Changelog of a DB: read the changes after ?since=<seq>, enable or disable it
+	privateMux.HandleFunc("GET /db/{dbname}/changes", server.ChangesValue)
+	privateMux.HandleFunc("PUT /db/{dbname}/changes", server.EnableChangelogValue)
+	privateMux.HandleFunc("DELETE /db/{dbname}/changes", server.DisableChangelogValue)
+
+	// executes an ordered list of operations in one request
+	privateMux.HandleFunc("POST /db/{dbname}/batch", server.idempotency.wrap(server.BatchValue))
+
+	// Gets a value and refreshes or removes its TTL
+	privateMux.HandleFunc("POST /db/{dbname}/getex", server.GetExValue)
+
+	// fsyncs the AOF of a DB
+	privateMux.HandleFunc("POST /db/{dbname}/sync", server.SyncValue)
+
+	// Gets a value or sets a default if it is missing
+	privateMux.HandleFunc("POST /db/{dbname}/getorset", server.idempotency.wrap(server.GetOrSetValue))
+
+	// Refreshes the TTL of a key without reading it
+	privateMux.HandleFunc("POST /db/{dbname}/touch", server.TouchValue)
+
+	// Sets, gets and deletes fields of a hash
+	privateMux.HandleFunc("PUT /db/{dbname}/hash", server.idempotency.wrap(server.HashSetValue))
+	privateMux.HandleFunc("POST /db/{dbname}/hash/get", server.HashGetValue)
+	privateMux.HandleFunc("POST /db/{dbname}/hash/getall", server.HashGetAllValue)
+	privateMux.HandleFunc("DELETE /db/{dbname}/hash", server.idempotency.wrap(server.HashDeleteValue))
+
+	// Adds, removes and checks members of a set
+	privateMux.HandleFunc("PUT /db/{dbname}/set", server.idempotency.wrap(server.SetAddValue))
+	privateMux.HandleFunc("DELETE /db/{dbname}/set", server.idempotency.wrap(server.SetRemoveValue))
+	privateMux.HandleFunc("POST /db/{dbname}/set/ismember", server.SetIsMemberValue)
+	privateMux.HandleFunc("POST /db/{dbname}/set/members", server.SetMembersValue)
+
+	// Adds members to a sorted set and queries their scores, ranks and ranges
+	privateMux.HandleFunc("PUT /db/{dbname}/zset", server.idempotency.wrap(server.ZSetAddValue))
+	privateMux.HandleFunc("POST /db/{dbname}/zset/score", server.ZSetScoreValue)
+	privateMux.HandleFunc("POST /db/{dbname}/zset/rank", server.ZSetRankValue)
+	privateMux.HandleFunc("POST /db/{dbname}/zset/range", server.ZSetRangeValue)
+
+	// Pushes and pops values of a list and reads its range and length
+	privateMux.HandleFunc("POST /db/{dbname}/list/lpush", server.idempotency.wrap(server.ListPushValue))
+	privateMux.HandleFunc("POST /db/{dbname}/list/rpush", server.idempotency.wrap(server.ListPushValue))
+	privateMux.HandleFunc("POST /db/{dbname}/list/lpop", server.idempotency.wrap(server.ListPopValue))
+	privateMux.HandleFunc("POST /db/{dbname}/list/rpop", server.idempotency.wrap(server.ListPopValue))
+	privateMux.HandleFunc("POST /db/{dbname}/list/range", server.ListRangeValue)
+
+	// Increments a counter
+	privateMux.HandleFunc("POST /db/{dbname}/counter/incr", server.idempotency.wrap(server.CounterValue))
+
+	// Decrements a counter
+	privateMux.HandleFunc("POST /db/{dbname}/counter/decr", server.idempotency.wrap(server.CounterValue))
+
+	// Counts a call against a rate limit
+	privateMux.HandleFunc("POST /db/{dbname}/counter/limit", server.idempotency.wrap(server.RateLimitValue))
+
+	// Sets a number only if it is greater / less than the stored one
+	privateMux.HandleFunc("POST /db/{dbname}/setifgreater", server.idempotency.wrap(server.SetIfValue))
+	privateMux.HandleFunc("POST /db/{dbname}/setifless", server.idempotency.wrap(server.SetIfValue))
+
+	// Sets or clears a bit of a value
+	privateMux.HandleFunc("POST /db/{dbname}/setbit", server.idempotency.wrap(server.SetBitValue))
+
+	// Gets a bit of a value
+	privateMux.HandleFunc("POST /db/{dbname}/getbit", server.GetBitValue)
+
 	// Creates a new FiFoLiFo
 	privateMux.HandleFunc("POST /db/{dbname}/fifolifo", server.CreateFiFoLiFo)
 
@@ -154,7 +365,7 @@ func NewServer(port int, ip string) *Server {
 	privateMux.HandleFunc("DELETE /db/{dbname}/fifolifo", server.DeleteFiFoLiFo)
 
 	// Pushes a value to a FiFoLiFo
-	privateMux.HandleFunc("PUT /db/{dbname}/fifolifo", server.PushToFiFoLiFo)
+	privateMux.HandleFunc("PUT /db/{dbname}/fifolifo", server.idempotency.wrap(server.PushToFiFoLiFo))
 
 	// Pops a value from a FiFo
 	privateMux.HandleFunc("POST /db/{dbname}/fifo", server.PopFromFiFo)
@@ -162,15 +373,106 @@ func NewServer(port int, ip string) *Server {
 	// Pops a value from a Lifo
 	privateMux.HandleFunc("POST /db/{dbname}/lifo", server.PopFromLiFo)
 
+	// Moves the oldest value of a FiFoLiFo to another one
+	privateMux.HandleFunc("POST /db/{dbname}/fifolifo/move", server.idempotency.wrap(server.MoveFiFoLiFoValue))
+
 	// Changes a apikey for a existing DB
 	privateMux.HandleFunc("UPDATE /db/{dbname}", server.ChangeApiKey)
 
+	// Creates or replaces the read-only apikey of a DB
+	privateMux.HandleFunc("POST /db/{dbname}/readkey", server.ChangeReadKey)
+
 	// DeleteDB route
 	privateMux.HandleFunc("DELETE /db/{dbname}", server.DeleteDB)
 
+	// Lists the DBs with api keys and the metadata of the keys
+	adminMux.HandleFunc("GET /admin/apikeys", server.ListApiKeys)
+
+	// Issues a new write apikey for a DB, e.g. after a revoke
+	adminMux.HandleFunc("POST /admin/apikeys/{dbname}", server.IssueApiKey)
+
+	// Revokes all apikeys of a DB
+	adminMux.HandleFunc("DELETE /admin/apikeys/{dbname}", server.RevokeApiKeys)
+
+	// Lists the effective settings and where they came from
+	adminMux.HandleFunc("GET /admin/config", server.ConfigDump)
+
+	// Changes the request limits at runtime
+	adminMux.HandleFunc("POST /admin/ratelimit", server.SetRequestLimits)
+
+	// swaps the contents of two DBs
+	adminMux.HandleFunc("POST /admin/swap", server.SwapDBsValue)
+
+	// Flushes or with ?drop=true deletes all DBs
+	adminMux.HandleFunc("POST /admin/flushall", server.FlushAllDBs)
+
+	// lists the fullest baskets of a DB, e.g. to find keys forced into one basket
+	adminMux.HandleFunc("GET /admin/baskets/{dbname}", server.HotBasketsValue)
+
+	// fsyncs the AOFs of all DBs, e.g. before a filesystem snapshot
+	adminMux.HandleFunc("POST /admin/sync", server.SyncAllDBs)
+
+	// Streams a backup of all DBs as tar archive
+	adminMux.HandleFunc("GET /admin/backup", server.BackupDBs)
+
+	// Restores the DBs of a backup archive
+	adminMux.HandleFunc("POST /admin/restore", server.RestoreDBs)
+
 	return server
 }
 
+// isAdmin checks the X-Admin-Key header against HKV_ADMIN_KEY - without an admin key the admin routes are disabled
+func isAdmin(r *http.Request) bool {
+	key := r.Header.Get("X-Admin-Key")
+	ok := validAdminKey(key)
+
+	outcome := authGranted
+	if !ok {
+		outcome = authDenied
+	}
+	audit.record("http", httpClientIP(r), "", key, outcome)
+	return ok
+}
+
+// validAdminKey compares key with HKV_ADMIN_KEY in constant time - false if no admin key is configured
+func validAdminKey(key string) bool {
+	adminKey := *envhandler.ENV.ADMIN_KEY
+	return adminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) == 1
+}
+
+// readSubPaths are the POST /db/{dbname}/{type}/{operation} routes of collections which only read
+var readSubPaths = map[string]bool{
+	"hash/get":     true,
+	"hash/getall":  true,
+	"set/ismember": true,
+	"set/members":  true,
+	"zset/score":   true,
+	"zset/rank":    true,
+	"zset/range":   true,
+	"list/range":   true,
+}
+
+// isReadRequest returns true for the routes a read-only api key may call: exists, get, type, meta, getbit, expiring,
+// mget-ttl, the changes and the readSubPaths of collections
+func isReadRequest(r *http.Request) bool {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "db" {
+		return false
+	}
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 2:
+		return true
+	case r.Method == http.MethodPost && len(parts) == 3:
+		return parts[2] == "keys" || parts[2] == "getbit" || parts[2] == "type" || parts[2] == "meta" ||
+			parts[2] == "expiring" || parts[2] == "mget-ttl"
+	case r.Method == http.MethodGet && len(parts) == 3:
+		return parts[2] == "changes"
+	case r.Method == http.MethodPost && len(parts) == 4:
+		return readSubPaths[parts[2]+"/"+parts[3]]
+	}
+	return false
+}
+
 // Handler returns the HTTP handler associated with the server.
 func (s *Server) Handler() http.Handler {
 	return s.Server.Handler
@@ -181,28 +483,74 @@ func (s *Server) DBExists(name string) bool {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 
-	if _, ok := s.dbs[strings.ToUpper(name)]; ok {
+	if _, ok := s.dbs[utils.U.DbKey(name)]; ok {
+		return true
+	}
+	return false
+}
+
+// DBLoading returns true if the database exists but is still replaying its AOF.
+func (s *Server) DBLoading(name string) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(name)]; ok {
+		return !hm.Ready()
+	}
+	return false
+}
+
+// DBWritable returns false if the database rejects writes because its AOF exceeds HKV_MAX_AOF_BYTES.
+func (s *Server) DBWritable(name string) bool {
+	s.mut.RLock()
+	hm, ok := s.dbs[utils.U.DbKey(name)]
+	s.mut.RUnlock()
+
+	// the storage check may compact the AOF - so it runs without holding the server lock
+	if !ok {
 		return true
 	}
+	return hm.CheckStorage()
+}
+
+// DBThrottled returns true if the database rejects writes because its AOF can't keep up with them.
+func (s *Server) DBThrottled(name string) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(name)]; ok {
+		return hm.Throttled()
+	}
 	return false
 }
 
 // NewDB initializes a new database with the given name if it does not already exist and may create a new API key.
 func (s *Server) NewDB(name string) (error, bool, bool, string) {
+	// wait until the DBs dropped so far are closed and their AOFs deleted
+	s.dropping.Lock()
+	s.dropping.Unlock()
+
 	// if DB already exists...
 	if s.DBExists(name) {
 		return nil, true, false, ""
 	}
 
-	// Create new DB
-	hm, err := hashMap.NewHashMap(name)
+	// Create new DB - it is visible as loading until its AOF is replayed
+	hm, err := hashMap.OpenHashMap(name)
 	if err != nil {
 		return err, false, false, ""
 	}
 	s.mut.Lock()
-	s.dbs[strings.ToUpper(name)] = hm
+	s.dbs[utils.U.DbKey(name)] = hm
 	s.mut.Unlock()
 
+	if err := hm.Load(); err != nil {
+		s.mut.Lock()
+		delete(s.dbs, utils.U.DbKey(name))
+		s.mut.Unlock()
+		return err, false, false, ""
+	}
+
 	// if there is an APIKEY enabled, create a new one
 	var apikey string
 	if *envhandler.ENV.APIKEY_ENABLED {
@@ -216,6 +564,16 @@ func (s *Server) NewDB(name string) (error, bool, bool, string) {
 
 // CreateApiKey generates a new API key, stores its hash, and returns the API key. Returns an error if creation or storage fails.
 func (s *Server) CreateApiKey(db string) (string, error) {
+	return s.createApiKey(db, utils.RoleWrite)
+}
+
+// CreateReadKey generates a new read-only API key, stores its hash, and returns the API key.
+func (s *Server) CreateReadKey(db string) (string, error) {
+	return s.createApiKey(db, utils.RoleRead)
+}
+
+// createApiKey generates a new API key with the given role - an existing key of the role is replaced
+func (s *Server) createApiKey(db string, role utils.ApiKeyRole) (string, error) {
 	s.mut.Lock()
 	defer s.mut.Unlock()
 	// Create a new APIKEY
@@ -225,7 +583,7 @@ func (s *Server) CreateApiKey(db string) (string, error) {
 	}
 
 	// Save the APIKEY
-	err = utils.U.SaveApiKey(db, hash)
+	err = utils.U.SaveApiKeyRole(db, role, hash)
 	if err != nil {
 		return "", err
 	}
@@ -237,31 +595,160 @@ func (s *Server) Set(db, key, value string, ttl int64) bool {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 
-	if s.CheckEntries(db) == false {
-		return false
-	}
-	if hm, ok := s.dbs[strings.ToUpper(db)]; ok {
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		if hasRoom(hm) == false {
+			return false
+		}
 		return hm.Set(ttl, key, value)
 	}
 	return false
 }
 
+// SetWithContentType stores a key-value pair in the specified database like Set - or like SetKeepTTL with keepTTL -
+// together with its content type.
+func (s *Server) SetWithContentType(db, key, value, contentType string, ttl int64, keepTTL bool) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		if hasRoom(hm) == false {
+			return false
+		}
+		return hm.SetWithContentType(ttl, key, value, contentType, keepTTL)
+	}
+	return false
+}
+
+// SetKeepTTL stores a key-value pair in the specified database and keeps the expiry of an existing key.
+func (s *Server) SetKeepTTL(db, key, value string) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		if hasRoom(hm) == false {
+			return false
+		}
+		return hm.SetKeepTTL(key, value)
+	}
+	return false
+}
+
+// SetIfGreater sets the value in the specified database if it is greater than the stored number. Returns true if changed.
+func (s *Server) SetIfGreater(db, key, value string, ttl int64) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		if hasRoom(hm) == false {
+			return false
+		}
+		return hm.SetIfGreater(ttl, key, value)
+	}
+	return false
+}
+
+// SetIfLess sets the value in the specified database if it is less than the stored number. Returns true if changed.
+func (s *Server) SetIfLess(db, key, value string, ttl int64) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		if hasRoom(hm) == false {
+			return false
+		}
+		return hm.SetIfLess(ttl, key, value)
+	}
+	return false
+}
+
 // Incr increments the value of a specified key in the given database by the specified amount. Returns true if successful.
 func (s *Server) Incr(db, key, amount string) bool {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
-	if hm, ok := s.dbs[strings.ToUpper(db)]; ok {
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
 		return hm.Incr(0, key, amount)
 	}
 	return false
 }
 
+// CounterIncr increments the counter stored at key in the specified database by amount. Returns the new value.
+func (s *Server) CounterIncr(db, key string, amount, ttl int64) (int64, bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		// a new key may be created - so the entries limit applies
+		if exists, _ := hm.Get(key); !exists && hasRoom(hm) == false {
+			return 0, false
+		}
+		return hm.CounterIncr(ttl, key, amount)
+	}
+	return 0, false
+}
+
+// IncrAndCheck increments the rate limit counter stored at key in the specified database if it is below limit.
+// Returns the count and true if the call is allowed.
+func (s *Server) IncrAndCheck(db, key string, limit, ttl int64) (int64, bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		// a new key may be created - so the entries limit applies
+		if exists, _ := hm.Get(key); !exists && hasRoom(hm) == false {
+			return 0, false
+		}
+		return hm.IncrAndCheck(key, limit, ttl)
+	}
+	return 0, false
+}
+
+// CounterDecr decrements the counter stored at key in the specified database by amount. Returns the new value.
+func (s *Server) CounterDecr(db, key string, amount, ttl int64) (int64, bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		// a new key may be created - so the entries limit applies
+		if exists, _ := hm.Get(key); !exists && hasRoom(hm) == false {
+			return 0, false
+		}
+		return hm.CounterDecr(ttl, key, amount)
+	}
+	return 0, false
+}
+
+// SetBit sets or clears the bit at offset of the value stored at key. Returns the original bit and true if successful.
+func (s *Server) SetBit(db, key string, offset int64, bit int) (int, bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		// a new key may be created - so the entries limit applies
+		if exists, _ := hm.Get(key); !exists && hasRoom(hm) == false {
+			return 0, false
+		}
+		return hm.SetBit(key, offset, bit)
+	}
+	return 0, false
+}
+
+// GetBit returns the bit at offset of the value stored at key in the specified database.
+func (s *Server) GetBit(db, key string, offset int64) int {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.GetBit(key, offset)
+	}
+	return 0
+}
+
 // Del removes the specified key from the given database and returns true if the operation is successful, otherwise false.
 func (s *Server) Del(db, key string) bool {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 
-	if hm, ok := s.dbs[strings.ToUpper(db)]; ok {
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
 		return hm.Del(key)
 	}
 	return false
@@ -272,30 +759,390 @@ func (s *Server) Get(db, key string) (bool, string) {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 
-	if hm, ok := s.dbs[strings.ToUpper(db)]; ok {
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
 		return hm.Get(key)
 	}
 	return false, ""
 }
 
+// GetInfo retrieves the value of key like Get together with the time of its last write and its content type.
+func (s *Server) GetInfo(db, key string) (bool, string, hashMap.ValueInfo) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.GetInfo(key)
+	}
+	return false, "", hashMap.ValueInfo{}
+}
+
+// Meta returns the metadata of key in the specified database and false if the key is missing.
+func (s *Server) Meta(db, key string) (hashMap.KeyMeta, bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.Meta(key)
+	}
+	return hashMap.KeyMeta{}, false
+}
+
+// MGetWithTTL returns the values of keys in the specified database with their remaining TTL, in the order of keys.
+func (s *Server) MGetWithTTL(db string, keys []string) []hashMap.KeyTTL {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.MGetWithTTL(keys)
+	}
+	return make([]hashMap.KeyTTL, len(keys))
+}
+
+// Changes returns up to limit changes of the changelog of db after the sequence number since which match - all for a
+// nil match - with the first change still kept and the sequence number of the last change read. The files are read
+// without holding the lock of the DBs.
+func (s *Server) Changes(db string, since int64, limit int, match func(*hashMap.Change) bool) ([]hashMap.Change, int64,
+	int64, error) {
+	s.mut.RLock()
+	hm, ok := s.dbs[utils.U.DbKey(db)]
+	s.mut.RUnlock()
+	if !ok {
+		return nil, 0, since, hashMap.ErrChangelogDisabled
+	}
+	changelog := hm.Changelog()
+	if changelog == nil {
+		return nil, 0, since, hashMap.ErrChangelogDisabled
+	}
+	return changelog.ChangesMatching(since, limit, match)
+}
+
+// EnableChangelog starts recording the changes of db - an enabled changelog is kept
+func (s *Server) EnableChangelog(db string) error {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.EnableChangelog()
+	}
+	return nil
+}
+
+// DisableChangelog stops recording the changes of db and deletes its changelog
+func (s *Server) DisableChangelog(db string) error {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.DisableChangelog()
+	}
+	return nil
+}
+
+// ExpiringWithin returns the keys of the specified database which expire in the next seconds, the soonest first.
+func (s *Server) ExpiringWithin(db string, seconds int64) []string {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.ExpiringWithin(seconds)
+	}
+	return []string{}
+}
+
+// GetEX returns the value of key in the specified database and sets (ttl > 0), keeps (0) or removes (< 0) its expiry.
+func (s *Server) GetEX(db, key string, ttl int64) (bool, string) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.GetEX(key, ttl)
+	}
+	return false, ""
+}
+
+// Touch sets (ttl > 0), keeps (0) or removes (< 0) the expiry of key in the specified database without reading it.
+func (s *Server) Touch(db, key string, ttl int64) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.Touch(key, ttl)
+	}
+	return false
+}
+
+// Type returns the type of the value stored at key in the specified database and false if the key is missing.
+func (s *Server) Type(db, key string) (string, bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.Type(key)
+	}
+	return "", false
+}
+
+// HSet sets a field of the hash stored at key in the specified database. Returns false if the key holds another type.
+func (s *Server) HSet(db, key, field, value string) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		if hasRoom(hm) == false {
+			return false
+		}
+		return hm.HSet(key, field, value)
+	}
+	return false
+}
+
+// HGet returns the value of a field of the hash stored at key in the specified database.
+func (s *Server) HGet(db, key, field string) (bool, string) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.HGet(key, field)
+	}
+	return false, ""
+}
+
+// HGetAll returns all fields of the hash stored at key in the specified database - nil if there is no hash.
+func (s *Server) HGetAll(db, key string) map[string]string {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.HGetAll(key)
+	}
+	return nil
+}
+
+// HDel removes a field of the hash stored at key in the specified database. Returns true if the field existed.
+func (s *Server) HDel(db, key, field string) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.HDel(key, field)
+	}
+	return false
+}
+
+// SAdd adds a member to the set stored at key in the specified database. Returns true if the member was added.
+func (s *Server) SAdd(db, key, member string) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		if hasRoom(hm) == false {
+			return false
+		}
+		return hm.SAdd(key, member)
+	}
+	return false
+}
+
+// SRem removes a member of the set stored at key in the specified database. Returns true if the member existed.
+func (s *Server) SRem(db, key, member string) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.SRem(key, member)
+	}
+	return false
+}
+
+// SIsMember returns true if member is a member of the set stored at key in the specified database.
+func (s *Server) SIsMember(db, key, member string) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.SIsMember(key, member)
+	}
+	return false
+}
+
+// SMembers returns the members of the set stored at key in the specified database - nil if there is no set.
+func (s *Server) SMembers(db, key string) []string {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.SMembers(key)
+	}
+	return nil
+}
+
+// SCard returns the number of members of the set stored at key in the specified database.
+func (s *Server) SCard(db, key string) int {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.SCard(key)
+	}
+	return 0
+}
+
+// ZAdd adds a member with its score to the sorted set stored at key in the specified database.
+func (s *Server) ZAdd(db, key, member string, score float64) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		if hasRoom(hm) == false {
+			return false
+		}
+		return hm.ZAdd(key, member, score)
+	}
+	return false
+}
+
+// ZScore returns the score of a member of the sorted set stored at key in the specified database.
+func (s *Server) ZScore(db, key, member string) (float64, bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.ZScore(key, member)
+	}
+	return 0, false
+}
+
+// ZRank returns the position of a member of the sorted set stored at key in the specified database.
+func (s *Server) ZRank(db, key, member string) (int, bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.ZRank(key, member)
+	}
+	return 0, false
+}
+
+// ZRange returns a range of the sorted set stored at key in the specified database - nil if there is no sorted set.
+func (s *Server) ZRange(db, key string, start, stop int) []hashMap.ZMember {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.ZRange(key, start, stop)
+	}
+	return nil
+}
+
+// LPush prepends a value to the list stored at key in the specified database.
+func (s *Server) LPush(db, key, value string) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		if hasRoom(hm) == false {
+			return false
+		}
+		return hm.LPush(key, value)
+	}
+	return false
+}
+
+// RPush appends a value to the list stored at key in the specified database.
+func (s *Server) RPush(db, key, value string) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		if hasRoom(hm) == false {
+			return false
+		}
+		return hm.RPush(key, value)
+	}
+	return false
+}
+
+// LPop removes and returns the first value of the list stored at key in the specified database.
+func (s *Server) LPop(db, key string) (string, bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.LPop(key)
+	}
+	return "", false
+}
+
+// RPop removes and returns the last value of the list stored at key in the specified database.
+func (s *Server) RPop(db, key string) (string, bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.RPop(key)
+	}
+	return "", false
+}
+
+// LRange returns a range of the list stored at key in the specified database - nil if there is no list.
+func (s *Server) LRange(db, key string, start, stop int) []string {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.LRange(key, start, stop)
+	}
+	return nil
+}
+
+// LLen returns the number of values of the list stored at key in the specified database.
+func (s *Server) LLen(db, key string) int {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		return hm.LLen(key)
+	}
+	return 0
+}
+
 // SetNX attempts to set a key with a value and TTL if the key does not already exist in the specified database.
 func (s *Server) SetNX(db, key, value string, ttl int64) bool {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 
-	if s.CheckEntries(db) == false {
-		return false
-	}
-	if hm, ok := s.dbs[strings.ToUpper(db)]; ok {
-		exists, _ := hm.Get(key)
-		if exists {
+	if hm, ok := s.dbs[utils.U.DbKey(db)]; ok {
+		if hasRoom(hm) == false {
 			return false
 		}
-		return hm.Set(ttl, key, value)
+		return hm.SetNX(ttl, key, value)
 	}
 	return false
 }
 
+// GetOrSet returns the value of key in the specified database or stores and returns defaultValue if it is missing.
+// ok is false if key holds a collection or the key is missing and the DB is full.
+func (s *Server) GetOrSet(db, key, defaultValue string, ttl int64) (value string, created bool, ok bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	hm, found := s.dbs[utils.U.DbKey(db)]
+	if !found {
+		return "", false, false
+	}
+	// a full DB still answers the keys it has
+	if hasRoom(hm) == false {
+		found, value = hm.Get(key)
+		return value, false, found
+	}
+	if value, created = hm.GetOrSet(key, defaultValue, ttl); created || value != "" {
+		return value, created, true
+	}
+	// an empty value left by an old AOF or a collection - only the value is found by Get
+	found, value = hm.Get(key)
+	return value, false, found
+}
+
 // readPayloadAndValidate reads JSON payload from the request body, validates it, and returns the error or the decoded payload.
 func readPayloadAndValidate[T any](body io.ReadCloser, s *Server) (error, T) {
 	var payload T
@@ -317,6 +1164,8 @@ func readPayloadAndValidate[T any](body io.ReadCloser, s *Server) (error, T) {
 }
 
 // ReloadDb reloads the database connections and restores API keys if enabled.
+// The DBs are registered as loading right away, their AOFs are replayed in the background, so the server
+// can accept connections sooner.
 func (s *Server) ReloadDb() error {
 	dbs, err := restartcheck.RCheck.Check()
 	if err != nil {
@@ -331,23 +1180,103 @@ func (s *Server) ReloadDb() error {
 		}
 	}
 
+	// register all DBs - they stay in loading state until their AOF is replayed
+	hms := make([]*hashMap.HashMap, 0, len(dbs))
 	for _, db := range dbs {
-		err, _, _, _ := s.NewDB(db)
+		if s.DBExists(db) {
+			continue
+		}
+		hm, err := hashMap.OpenHashMap(db)
 		if err != nil {
 			log.Printf("Error recreating DB %s: %v", db, err)
+			continue
 		}
+		s.mut.Lock()
+		s.dbs[utils.U.DbKey(db)] = hm
+		s.mut.Unlock()
+		hms = append(hms, hm)
 	}
+
+	s.loading.Add(1)
+	go func() {
+		defer s.loading.Done()
+		s.loadDBs(hms)
+	}()
 	return nil
 }
 
+// loadDBs replays the AOFs of the given DBs with a worker pool bounded by the number of CPUs.
+// Errors are collected per DB - a failing DB is removed, the others are loaded anyway.
+func (s *Server) loadDBs(hms []*hashMap.HashMap) {
+	start := time.Now()
+	jobs := make(chan *hashMap.HashMap)
+	errs := make([]error, 0)
+	errMut := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < min(runtime.NumCPU(), len(hms)); i++ {
+		wg.Go(func() {
+			for hm := range jobs {
+				if err := hm.Load(); err != nil {
+					errMut.Lock()
+					errs = append(errs, fmt.Errorf("error recreating DB %s: %w", hm.Name, err))
+					errMut.Unlock()
+
+					// remove the broken DB
+					s.mut.Lock()
+					delete(s.dbs, hm.Name)
+					s.mut.Unlock()
+					_ = hm.Close()
+				}
+			}
+		})
+	}
+
+	for _, hm := range hms {
+		jobs <- hm
+	}
+	close(jobs)
+	wg.Wait()
+
+	// print possible errors
+	for _, err := range errs {
+		log.Println(err)
+	}
+	log.Printf("Loaded %d of %d DBs in %s", len(hms)-len(errs), len(hms), time.Since(start))
+}
+
+// WaitForDBs blocks until all DBs restored by ReloadDb are loaded.
+func (s *Server) WaitForDBs() {
+	s.loading.Wait()
+}
+
 // Start initializes the server, attempts to reload the database, and begins listening for incoming HTTP connections.
 func (s *Server) Start() {
+	// without a usable data folder no DB can be created - better to stop than to fail on every request
+	if err := utils.U.EnsureDir(*envhandler.ENV.DB_FOLDER, envhandler.DB_FOLDER); err != nil {
+		log.Fatalf("Invalid data folder: %v", err)
+	}
+
 	// lets check for existing bin files in the aof dir
 	err := s.ReloadDb()
 	if err != nil {
 		log.Println(err)
 	}
 
+	// the Unix socket is served next to TCP - Shutdown closes and removes it
+	if path := *envhandler.ENV.UNIX_SOCKET; path != "" {
+		lis, err := listenUnix(path)
+		if err != nil {
+			log.Fatalf("failed to listen: %v", err)
+		}
+		log.Printf("Starting HTTPServer on unix socket %s\n", path)
+		go func() {
+			if err := s.Server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Serve unix socket: %v", err)
+			}
+		}()
+	}
+
 	log.Printf("Starting HTTPServer on %s:%d\n", s.ip, s.port)
 	err = s.Server.ListenAndServe()
 	if err != nil {
@@ -363,6 +1292,11 @@ func (s *Server) CloseDbs() {
 	for _, db := range s.dbs {
 		errors = append(errors, db.Close())
 	}
+	// requests which outlived the drain find no DB instead of a closed AOF
+	s.dbs = make(map[string]*hashMap.HashMap)
+
+	// keep the last-used times of the api keys
+	errors = append(errors, utils.U.FlushApiKeyMeta())
 
 	// print possible errors
 	for _, err := range errors {
@@ -372,31 +1306,127 @@ func (s *Server) CloseDbs() {
 	}
 }
 
+// CloseDbsContext closes all DBs like CloseDbs, but gives up waiting once ctx is done
+func (s *Server) CloseDbsContext(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.CloseDbs()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reload applies the hot-reloadable settings of the env to the running server - call it after envhandler.ENV.ReloadENVs.
+// The other reloadable settings are read on every use and need no action here.
+func (s *Server) Reload() {
+	s.limiter.reload()
+	for _, hook := range s.reloadHooks {
+		hook()
+	}
+}
+
+// OnReload registers fn to be called by Reload - not safe to call once the server runs
+func (s *Server) OnReload(fn func()) {
+	s.reloadHooks = append(s.reloadHooks, fn)
+}
+
 // CheckEntries checks if the number of entries in the database identified by name is below the maximum allowed limit.
 func (s *Server) CheckEntries(name string) bool {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 
-	if _, ok := s.dbs[strings.ToUpper(name)]; ok {
-		return s.dbs[strings.ToUpper(name)].GetEntries() < int64(*envhandler.ENV.MAX_ENTRIES)
+	if hm, ok := s.dbs[utils.U.DbKey(name)]; ok {
+		return hasRoom(hm)
 	}
 	return false
 }
 
-// ListDBs returns a slice of pointers to DBObject, representing a detailed list of databases managed by the server.
-func (s *Server) ListDBs() []*DBObject {
+// hasRoom is CheckEntries for a DB already looked up - the wrappers holding the server lock use it, since taking the
+// read lock again blocks behind a waiting writer
+func hasRoom(hm *hashMap.HashMap) bool {
+	return hm.GetEntries() < int64(envhandler.ENV.MaxEntries())
+}
+
+// Readiness returns the problems which make the server unable to serve - an empty slice means ready.
+func (s *Server) Readiness() []string {
+	problems := make([]string, 0)
+
+	// the data directory must be writable - it is created on demand like by the first DB
+	var f *os.File
+	err := utils.U.EnsureDir(*envhandler.ENV.DB_FOLDER, envhandler.DB_FOLDER)
+	if err == nil {
+		f, err = os.CreateTemp(*envhandler.ENV.DB_FOLDER, ".health-*")
+	}
+	if err == nil {
+		_, err = f.Write([]byte("ok"))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		_ = os.Remove(f.Name())
+	}
+	if err != nil {
+		problems = append(problems, "data directory not writable: "+err.Error())
+	}
+
+	// the AOF loops must be alive
+	s.mut.RLock()
+	for name, db := range s.dbs {
+		if err := db.Healthy(); err != nil {
+			problems = append(problems, "db "+name+": "+err.Error())
+		}
+	}
+	s.mut.RUnlock()
+
+	sort.Strings(problems)
+	return problems
+}
+
+// ListDBs returns the DBs whose names start with prefix sorted by name - the page of limit DBs after offset, all for a
+// limit of 0 - and the number of DBs matching. Only the DBs of the page are described.
+func (s *Server) ListDBs(prefix string, offset, limit int) ([]*DBObject, int) {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 
-	dbs := make([]*DBObject, 0)
+	prefix = utils.U.DbKey(prefix)
+	names := make([]string, 0, len(s.dbs))
+	for name := range s.dbs {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	total := len(names)
+	names = names[min(offset, total):]
+	if limit > 0 && limit < len(names) {
+		names = names[:limit]
+	}
 
-	for _, db := range s.dbs {
+	dbs := make([]*DBObject, 0, len(names))
+	for _, key := range names {
+		db := s.dbs[key]
 		entries := db.GetEntries()
 		name := db.Name
 		baskets := db.GetBasketNum()
-		dbs = append(dbs, &DBObject{Name: name, Entries: entries, Baskets: baskets})
+		obj := &DBObject{Name: name, Entries: entries, Baskets: baskets, Loading: !db.Ready(),
+			CompactRatio: db.CompactRatio(), StorageFull: db.StorageFull(), Throttled: db.Throttled()}
+		obj.TTLEntries, obj.TTLBuckets = db.TTLEntries()
+		if *envhandler.ENV.APPROX_CARDINALITY {
+			obj.ApproxKeys = db.GetApproxCardinality()
+		}
+		if obj.Loading {
+			progress := db.ReplayProgress()
+			obj.Replay = &ReplayStatus{Frames: progress.Frames, Bytes: progress.Bytes, TotalBytes: progress.Size,
+				ElapsedMs: progress.Elapsed.Milliseconds()}
+		}
+		dbs = append(dbs, obj)
 	}
-	return dbs
+	return dbs, total
 }
 
 // AddFifoLifo adds a new FifoLifo instance to the server's map of FifoLifos, keyed by the specified name.'
@@ -405,7 +1435,7 @@ func (s *Server) AddFifoLifo(db, name string, maxEntries int) error {
 	defer s.mut.Unlock()
 
 	// we dont check that the db exists - this already done in the endpoint
-	err := s.dbs[strings.ToUpper(db)].AddFifoLifo(name, maxEntries)
+	err := s.dbs[utils.U.DbKey(db)].AddFifoLifo(name, maxEntries)
 	return err
 }
 
@@ -413,8 +1443,7 @@ func (s *Server) AddFifoLifo(db, name string, maxEntries int) error {
 func (s *Server) DelFiFoLiFo(db, name string) error {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
-	s.dbs[strings.ToUpper(db)].DelFiFoLiFo(name) // returns nothing - if it doesnt exist, it will not return an error
-	return nil
+	return s.dbs[utils.U.DbKey(db)].DelFiFoLiFo(name)
 }
 
 // PushEntryFiFoLiFo adds an Entry to the Fifo Lifo
@@ -422,7 +1451,7 @@ func (s *Server) PushEntryFiFoLiFo(db, fifolifoName, data string) (bool, error)
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 
-	return s.dbs[strings.ToUpper(db)].PushEntryFiFoLiFo(fifolifoName, data)
+	return s.dbs[utils.U.DbKey(db)].PushEntryFiFoLiFo(fifolifoName, data)
 }
 
 // PopEntryFiFo removes an Entry from the Fifo Lifo
@@ -430,7 +1459,7 @@ func (s *Server) PopEntryFiFo(db, fifolifoName string) (string, error) {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 
-	return s.dbs[strings.ToUpper(db)].PopEntryFiFo(fifolifoName)
+	return s.dbs[utils.U.DbKey(db)].PopEntryFiFo(fifolifoName)
 }
 
 // PopEntryLiFo removes an Entry from the Lifo Lifo
@@ -438,26 +1467,209 @@ func (s *Server) PopEntryLiFo(db, fifolifoName string) (string, error) {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 
-	return s.dbs[strings.ToUpper(db)].PopEntryLiFo(fifolifoName)
+	return s.dbs[utils.U.DbKey(db)].PopEntryLiFo(fifolifoName)
+}
+
+// BPopEntryFiFo removes an Entry from the Fifo Lifo and waits up to timeout for a push if it is empty
+func (s *Server) BPopEntryFiFo(ctx context.Context, db, fifolifoName string, timeout time.Duration) (string, error) {
+	// the server lock is not held while waiting, since it would block creating and deleting DBs
+	s.mut.RLock()
+	hm, ok := s.dbs[utils.U.DbKey(db)]
+	s.mut.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("DB %s does not exist", db)
+	}
+
+	return hm.BPopEntryFiFo(ctx, fifolifoName, timeout)
+}
+
+// MoveFiFoLiFo pops the oldest Entry of the src Fifo Lifo and pushes it to the dst Fifo Lifo atomically
+func (s *Server) MoveFiFoLiFo(db, src, dst string) (string, error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.dbs[utils.U.DbKey(db)].MoveFiFoLiFo(src, dst)
 }
 
 // DBDelete deletes a database by name, closes its instance, removes its AOF file, and updates the server's database map.
 func (s *Server) DBDelete(name string) {
+	s.mut.Lock()
+	hm := s.detachDB(name)
+	// a new DB with the same name waits until the DB is dropped
+	s.dropping.RLock()
+	s.mut.Unlock()
+	defer s.dropping.RUnlock()
+
+	// closing waits for a running load - so it runs without holding the server lock
+	s.dropDB(hm)
+}
+
+// FlushAll flushes all DBs - or deletes them with drop - and returns the number of affected DBs. DBs which are
+// still replaying their AOF are only dropped, not flushed.
+func (s *Server) FlushAll(drop bool) int {
+	if drop {
+		return s.dropAll()
+	}
+
+	// the flushes run without the server lock - a DB dropped meanwhile is not flushed anymore
+	s.mut.RLock()
+	dbs := make([]*hashMap.HashMap, 0, len(s.dbs))
+	for _, db := range s.dbs {
+		if db.Ready() {
+			dbs = append(dbs, db)
+		}
+	}
+	s.mut.RUnlock()
+
+	for _, db := range dbs {
+		db.Flush()
+	}
+	return len(dbs)
+}
+
+// dropAll deletes all DBs for FlushAll and returns their number
+func (s *Server) dropAll() int {
+	s.mut.Lock()
+	dropped := make([]*hashMap.HashMap, 0, len(s.dbs))
+	for name := range s.dbs {
+		dropped = append(dropped, s.detachDB(name))
+	}
+	// a new DB with the same name waits until the DBs are dropped
+	s.dropping.RLock()
+	s.mut.Unlock()
+	defer s.dropping.RUnlock()
+
+	// closing waits for a running load - so it runs without holding the server lock
+	for _, hm := range dropped {
+		s.dropDB(hm)
+	}
+	return len(dropped)
+}
+
+// HotBaskets returns the n fullest baskets of the database with the totals of its baskets and entries - false if
+// the database is missing or still loading.
+func (s *Server) HotBaskets(name string, n int) (HotBaskets, bool) {
+	s.mut.RLock()
+	hm, ok := s.dbs[utils.U.DbKey(name)]
+	s.mut.RUnlock()
+
+	if !ok || !hm.Ready() {
+		return HotBaskets{}, false
+	}
+	res := HotBaskets{Baskets: hm.GetBasketNum(), Entries: hm.GetEntries(), Hot: make([]HotBasket, 0, n)}
+	for _, b := range hm.HotBaskets(n) {
+		res.Hot = append(res.Hot, HotBasket{Index: b.Index, Entries: b.Entries, Keys: b.Keys})
+	}
+	return res, true
+}
+
+// SyncDB blocks until all writes to the database done so far are fsynced to its AOF. A missing database is
+// nothing to sync.
+func (s *Server) SyncDB(name string) error {
+	s.mut.RLock()
+	hm, ok := s.dbs[utils.U.DbKey(name)]
+	s.mut.RUnlock()
+
+	// the sync waits for the AOF loop - so it runs without holding the server lock
+	if !ok {
+		return nil
+	}
+	return hm.Sync()
+}
+
+// SyncAll fsyncs the AOFs of all loaded databases one after another and returns their number. DBs which are
+// still loading have no writes yet. The first error is returned after all DBs were tried.
+func (s *Server) SyncAll() (int, error) {
+	s.mut.RLock()
+	dbs := make([]*hashMap.HashMap, 0, len(s.dbs))
+	for _, hm := range s.dbs {
+		if hm.Ready() {
+			dbs = append(dbs, hm)
+		}
+	}
+	s.mut.RUnlock()
+
+	var first error
+	for _, hm := range dbs {
+		if err := hm.Sync(); err != nil {
+			log.Printf("Error syncing AOF of %s: %v", hm.Name, err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return len(dbs), first
+}
+
+// errors of SwapDBs
+var (
+	errSwapNotFound = errors.New("db not found")
+	errSwapLoading  = errors.New("db is loading")
+)
+
+// SwapDBs swaps the contents of the DBs a and b, e.g. to put a DB warmed in the background into place. The requests
+// wait for the swap and see the other data afterward. The api keys stay with the names, the remembered idempotent
+// writes of both are dropped.
+func (s *Server) SwapDBs(a, b string) error {
+	a, b = utils.U.DbKey(a), utils.U.DbKey(b)
+
 	s.mut.Lock()
 	defer s.mut.Unlock()
 
-	// Close the DB
-	err := s.dbs[strings.ToUpper(name)].Close()
+	hmA, okA := s.dbs[a]
+	hmB, okB := s.dbs[b]
+	if !okA || !okB {
+		return errSwapNotFound
+	}
+	if !hmA.Ready() || !hmB.Ready() {
+		return errSwapLoading
+	}
+
+	if err := hashMap.SwapDBs(hmA, hmB); err != nil {
+		return err
+	}
+	s.dbs[a], s.dbs[b] = hmB, hmA
+
+	// a retry of a write to a must not be answered by the result of the write to the old data
+	s.idempotency.dropDB(a)
+	s.idempotency.dropDB(b)
+	return nil
+}
+
+// detachDB removes a DB from the server and deletes its api keys - s.mut has to be locked. The DB has to be passed
+// to dropDB afterward.
+func (s *Server) detachDB(name string) *hashMap.HashMap {
+	hm := s.dbs[utils.U.DbKey(name)]
+
+	// Delete the DB from the map
+	delete(s.dbs, utils.U.DbKey(name))
+
+	// Delete the apikeys, so a new DB with the same name does not inherit them
+	if _, err := utils.U.RevokeApiKeys(name); err != nil {
+		log.Println(err)
+	}
+
+	// a new DB with the same name starts without remembered writes
+	s.idempotency.dropDB(name)
+	return hm
+}
+
+// dropDB closes a DB detached by detachDB and deletes its AOF - s.dropping has to be read locked, s.mut not
+func (s *Server) dropDB(hm *hashMap.HashMap) {
+	// Close the DB - its AOF is deleted, so it is not compacted anymore
+	err := hm.Drop()
 	if err != nil {
 		log.Println(err)
 	}
 
 	// Delete the AOF file
-	err = os.Remove(s.dbs[strings.ToUpper(name)].Aof.FileName)
+	err = os.Remove(hm.Aof.FileName)
 	if err != nil {
 		log.Println(err)
 	}
 
-	// Delete the DB from the map
-	delete(s.dbs, strings.ToUpper(name))
+	// a new DB with the same name starts without changelog
+	if err := hm.DisableChangelog(); err != nil {
+		log.Println(err)
+	}
 }
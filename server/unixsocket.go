@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"google.golang.org/grpc/peer"
+)
+
+// unixSocketMode restricts the socket to the user and group of the server - access is granted by the filesystem
+const unixSocketMode = 0o660
+
+// listenUnix listens on the Unix socket path. A socket left behind by a crashed server is removed first, any other
+// file is kept and fails the listen. The socket file is removed again when the listener is closed.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("unix socket %s: file exists and is no socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("unix socket %s: %w", path, err)
+		}
+	}
+
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, unixSocketMode); err != nil {
+		_ = lis.Close()
+		return nil, fmt.Errorf("unix socket %s: %w", path, err)
+	}
+	return lis, nil
+}
+
+// isUnixAddr returns true for the address of a Unix socket
+func isUnixAddr(addr net.Addr) bool {
+	return addr != nil && addr.Network() == "unix"
+}
+
+// isUnixRequest returns true if a HTTP request came in over the Unix socket - these clients have no IP, their
+// access is restricted by the permissions of the socket instead of the IP filter
+func isUnixRequest(r *http.Request) bool {
+	addr, _ := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return isUnixAddr(addr)
+}
+
+// isUnixPeer is isUnixRequest for gRPC calls
+func isUnixPeer(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	return ok && isUnixAddr(p.Addr)
+}
@@ -1,14 +1,24 @@
 package tests
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"hydrakv/envhandler"
+	"hydrakv/restartcheck"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -276,3 +286,1509 @@ func TestAPI_TTL(t *testing.T) {
 		t.Fatalf("Expected 404 for expired key, got %d", resp.StatusCode)
 	}
 }
+
+func TestAPI_Stats(t *testing.T) {
+	oldIndex := *envhandler.ENV.INDEX_PAGE
+	defer func() { *envhandler.ENV.INDEX_PAGE = oldIndex }()
+	_, client, base := newAPIServer(t)
+
+	// Create DB
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "statsdb"})
+	doJSON(t, client, http.MethodPut, base+"/db/statsdb", serverpkg.Set{Key: "k", Value: "v"})
+	doJSON(t, client, http.MethodPut, base+"/db/statsdb", serverpkg.Set{Key: "t", Value: "v", Ttl: 60})
+
+	// like the index the stats only list the DBs with HKV_INDEX_PAGE
+	*envhandler.ENV.INDEX_PAGE = false
+	if resp, body := doJSON(t, client, http.MethodGet, base+"/stats", nil); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("stats without index: expected 403, got %d %s", resp.StatusCode, body)
+	}
+
+	// then they list the DB as loaded
+	*envhandler.ENV.INDEX_PAGE = true
+	resp, body := doJSON(t, client, http.MethodGet, base+"/stats", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("stats: expected 200, got %d", resp.StatusCode)
+	}
+	var stats serverpkg.Stats
+	if err := json.Unmarshal(body, &stats); err != nil {
+		t.Fatalf("decode stats: %v", err)
+	}
+
+	found := false
+	for _, db := range stats.DBs {
+		if db.Name == "STATSDB" {
+			found = true
+			if db.Loading {
+				t.Fatalf("stats: DB should not be loading")
+			}
+			if db.Entries < 1 {
+				t.Fatalf("stats: expected at least 1 entry, got %d", db.Entries)
+			}
+			if db.TTLEntries != 1 || db.TTLBuckets != 1 {
+				t.Fatalf("stats: expected 1 TTL entry in 1 bucket, got %d in %d", db.TTLEntries, db.TTLBuckets)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("stats: STATSDB not listed: %s", string(body))
+	}
+}
+
+func TestAPI_StatsPagination(t *testing.T) {
+	// a folder of its own, so a rerun counts the same DBs
+	oldIndex, oldFolder := *envhandler.ENV.INDEX_PAGE, *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.INDEX_PAGE = true
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() {
+		*envhandler.ENV.INDEX_PAGE = oldIndex
+		*envhandler.ENV.DB_FOLDER = oldFolder
+	}()
+	_, client, base := newAPIServer(t)
+	for _, name := range []string{"pagedb3", "pagedb1", "pagedb2"} {
+		doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: name})
+	}
+
+	// the DBs are sorted by name, the prefix is matched like a DB name
+	page := func(query string) serverpkg.Stats {
+		t.Helper()
+		resp, body := doJSON(t, client, http.MethodGet, base+"/stats"+query, nil)
+		var stats serverpkg.Stats
+		if err := json.Unmarshal(body, &stats); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("stats%s: got %d %s", query, resp.StatusCode, body)
+		}
+		return stats
+	}
+	if stats := page("?prefix=pagedb&limit=2"); stats.Total != 3 || len(stats.DBs) != 2 ||
+		stats.DBs[0].Name != "PAGEDB1" || stats.DBs[1].Name != "PAGEDB2" {
+		t.Fatalf("first page: %+v", stats)
+	}
+	if stats := page("?prefix=PAGEDB&offset=2&limit=2"); stats.Total != 3 || len(stats.DBs) != 1 ||
+		stats.DBs[0].Name != "PAGEDB3" {
+		t.Fatalf("second page: %+v", stats)
+	}
+	if stats := page("?prefix=pagedb&offset=5"); stats.Total != 3 || len(stats.DBs) != 0 {
+		t.Fatalf("page after the end: %+v", stats)
+	}
+	if stats := page(""); stats.Total < 3 || len(stats.DBs) != stats.Total {
+		t.Fatalf("without limit all DBs are listed: %d of %d", len(stats.DBs), stats.Total)
+	}
+	for _, query := range []string{"?offset=-1", "?offset=x", "?limit=0", "?limit=1001"} {
+		if resp, body := doJSON(t, client, http.MethodGet, base+"/stats"+query, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d %s", query, resp.StatusCode, body)
+		}
+	}
+
+	// the index pages the same way
+	resp, body := doJSON(t, client, http.MethodGet, base+"/?prefix=pagedb&limit=2", nil)
+	if html := string(body); resp.StatusCode != http.StatusOK || !strings.Contains(html, "PAGEDB2") ||
+		strings.Contains(html, "PAGEDB3") || !strings.Contains(html, "1-2 of 3") || !strings.Contains(html, "offset=2") {
+		t.Fatalf("index page: got %d %s", resp.StatusCode, html)
+	}
+}
+
+func TestAPI_IdempotencyKey(t *testing.T) {
+	_, client, base := newAPIServer(t)
+
+	// Create DB
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "idemdb"})
+	doJSON(t, client, http.MethodDelete, base+"/db/idemdb/keys", serverpkg.Key{Key: "counter"})
+
+	incr := func(idemKey string) *http.Response {
+		t.Helper()
+		b, _ := json.Marshal(serverpkg.Set{Key: "counter", Value: "5"})
+		req, err := http.NewRequest(http.MethodPatch, base+"/db/idemdb", bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", idemKey)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("incr: expected 200, got %d", resp.StatusCode)
+		}
+		return resp
+	}
+
+	// 1. A retry with the same key is applied only once
+	incr("retry-1")
+	if resp := incr("retry-1"); resp.Header.Get("Idempotent-Replayed") != "true" {
+		t.Fatal("expected the retry to be replayed")
+	}
+	_, body := doJSON(t, client, http.MethodPost, base+"/db/idemdb/keys", serverpkg.Key{Key: "counter"})
+	var val serverpkg.Value
+	json.Unmarshal(body, &val)
+	if val.Value != "5" {
+		t.Fatalf("Expected 5, got %s", val.Value)
+	}
+
+	// 2. A new key applies the increment again
+	incr("retry-2")
+	_, body = doJSON(t, client, http.MethodPost, base+"/db/idemdb/keys", serverpkg.Key{Key: "counter"})
+	json.Unmarshal(body, &val)
+	if val.Value != "10" {
+		t.Fatalf("Expected 10, got %s", val.Value)
+	}
+}
+
+func TestAPI_HealthAndLivez(t *testing.T) {
+	_, client, base := newAPIServer(t)
+
+	// 1. Ready and alive
+	resp, body := doJSON(t, client, http.MethodGet, base+"/health", nil)
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Fatalf("health: expected 200 ok, got %d %s", resp.StatusCode, string(body))
+	}
+	resp, _ = doJSON(t, client, http.MethodGet, base+"/livez", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("livez: expected 200, got %d", resp.StatusCode)
+	}
+
+	// 2. An unusable data directory fails the readiness, but not the liveness
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	folder := *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.DB_FOLDER = file
+	t.Cleanup(func() { *envhandler.ENV.DB_FOLDER = folder })
+
+	resp, body = doJSON(t, client, http.MethodGet, base+"/health", nil)
+	if resp.StatusCode != http.StatusServiceUnavailable || !strings.Contains(string(body), "data directory not writable") {
+		t.Fatalf("health: expected 503 with details, got %d %s", resp.StatusCode, string(body))
+	}
+	resp, _ = doJSON(t, client, http.MethodGet, base+"/livez", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("livez: expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_DBNamePolicy(t *testing.T) {
+	_, client, base := newAPIServer(t)
+
+	// hyphens and underscores are allowed by default
+	for _, name := range []string{"my-cache", "user_sessions"} {
+		resp, body := doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: name})
+		if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
+			t.Fatalf("create %s: unexpected status %d, body=%s", name, resp.StatusCode, string(body))
+		}
+		resp, _ = doJSON(t, client, http.MethodPut, base+"/db/"+name, serverpkg.Set{Key: "k", Value: "v"})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("set %s: expected 200, got %d", name, resp.StatusCode)
+		}
+	}
+
+	// names which are not filesystem-safe are rejected
+	for _, name := range []string{"bad.name", "../evil", ""} {
+		resp, _ := doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: name})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("create %q: expected 400, got %d", name, resp.StatusCode)
+		}
+	}
+}
+
+func TestAPI_DBNameCaseSensitive(t *testing.T) {
+	sensitive, folder := *envhandler.ENV.DBNAME_CASE_SENSITIVE, *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.DBNAME_CASE_SENSITIVE = true
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	t.Cleanup(func() {
+		*envhandler.ENV.DBNAME_CASE_SENSITIVE = sensitive
+		*envhandler.ENV.DB_FOLDER = folder
+	})
+
+	_, client, base := newAPIServer(t)
+
+	// MyDb and mydb are different DBs which keep their case
+	for _, name := range []string{"MyDb", "mydb"} {
+		resp, body := doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: name})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create %s: expected 201, got %d", name, resp.StatusCode)
+		}
+		var created serverpkg.NewDBCreated
+		if err := json.Unmarshal(body, &created); err != nil || created.Name != name {
+			t.Fatalf("create %s: unexpected name %q", name, created.Name)
+		}
+		doJSON(t, client, http.MethodPut, base+"/db/"+name, serverpkg.Set{Key: "k", Value: name})
+		if _, err := os.Stat(filepath.Join(*envhandler.ENV.DB_FOLDER, name+".bin")); err != nil {
+			t.Fatalf("AOF file of %s: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"MyDb", "mydb"} {
+		_, body := doJSON(t, client, http.MethodPost, base+"/db/"+name+"/keys", serverpkg.Key{Key: "k"})
+		var v serverpkg.Value
+		json.Unmarshal(body, &v)
+		if v.Value != name {
+			t.Fatalf("get %s: expected %s, got %s", name, name, v.Value)
+		}
+	}
+
+	// a different case is a different DB
+	resp, _ := doJSON(t, client, http.MethodGet, base+"/db/MYDB", nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("MYDB should not exist, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_ValidationErrors(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "valdb"})
+
+	post := func(body string) serverpkg.ValidationError {
+		t.Helper()
+		resp, err := client.Post(base+"/db/valdb", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, resp.StatusCode)
+		}
+		var ve serverpkg.ValidationError
+		if err := json.NewDecoder(resp.Body).Decode(&ve); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return ve
+	}
+
+	// 1. malformed JSON
+	if ve := post(`{"key": `); ve.Error != "invalid_json" {
+		t.Fatalf("expected invalid_json, got %+v", ve)
+	}
+
+	// 2. unknown field
+	if ve := post(`{"key": "k", "value": "v", "color": "red"}`); ve.Error != "unknown_field" ||
+		len(ve.Fields) != 1 || ve.Fields[0].Field != "color" {
+		t.Fatalf("expected unknown_field color, got %+v", ve)
+	}
+
+	// 3. validation rule violated - the JSON field names are reported
+	ve := post(`{"key": "k"}`)
+	if ve.Error != "validation_failed" || len(ve.Fields) != 1 || ve.Fields[0].Field != "value" ||
+		ve.Fields[0].Rule != "required" {
+		t.Fatalf("expected validation_failed value/required, got %+v", ve)
+	}
+}
+
+func TestAPI_CORS(t *testing.T) {
+	origins := *envhandler.ENV.CORS_ORIGINS
+	*envhandler.ENV.CORS_ORIGINS = "https://app.example.com"
+	t.Cleanup(func() { *envhandler.ENV.CORS_ORIGINS = origins })
+
+	_, client, base := newAPIServer(t)
+
+	preflight := func(origin string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodOptions, base+"/db/corsdb", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+		req.Header.Set("Access-Control-Request-Headers", "X-API-Key")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// 1. preflight of an allowed origin
+	resp := preflight("https://app.example.com")
+	if resp.StatusCode != http.StatusNoContent ||
+		resp.Header.Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		!strings.Contains(resp.Header.Get("Access-Control-Allow-Headers"), "X-API-Key") {
+		t.Fatalf("preflight: unexpected response %d %v", resp.StatusCode, resp.Header)
+	}
+
+	// 2. preflight of another origin
+	if resp := preflight("https://evil.example.com"); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("preflight: expected 403, got %d", resp.StatusCode)
+	}
+
+	// 3. simple requests get the allow origin header
+	req, _ := http.NewRequest(http.MethodGet, base+"/health", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("do request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Fatalf("expected the allow origin header, got %v", resp.Header)
+	}
+}
+
+func TestAPI_Gzip(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "gzipdb"})
+
+	large := strings.Repeat("a", 1500)
+	doJSON(t, client, http.MethodPut, base+"/db/gzipdb", serverpkg.Set{Key: "large", Value: large})
+	doJSON(t, client, http.MethodPut, base+"/db/gzipdb", serverpkg.Set{Key: "small", Value: "v"})
+
+	get := func(key string) (*http.Response, []byte) {
+		t.Helper()
+		b, _ := json.Marshal(serverpkg.Key{Key: key})
+		req, _ := http.NewRequest(http.MethodPost, base+"/db/gzipdb/keys", bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		// setting the header disables the transparent decompression of the client
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		defer resp.Body.Close()
+		var rdr io.Reader = resp.Body
+		if resp.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(resp.Body)
+			if err != nil {
+				t.Fatalf("gzip reader: %v", err)
+			}
+			rdr = zr
+		}
+		data, err := io.ReadAll(rdr)
+		if err != nil {
+			t.Fatalf("read body: %v", err)
+		}
+		return resp, data
+	}
+
+	// 1. large responses are compressed
+	resp, body := get("large")
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzip response, got %v", resp.Header)
+	}
+	var v serverpkg.Value
+	if err := json.Unmarshal(body, &v); err != nil || v.Value != large {
+		t.Fatalf("unexpected value after decompression: %v", err)
+	}
+
+	// 2. small responses are not
+	resp, body = get("small")
+	if resp.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("expected an uncompressed response, got %v", resp.Header)
+	}
+	if err := json.Unmarshal(body, &v); err != nil || v.Value != "v" {
+		t.Fatalf("unexpected value: %s", string(body))
+	}
+}
+
+func TestAPI_LargeValue(t *testing.T) {
+	entrySize := *envhandler.ENV.ENTRY_SIZE
+	*envhandler.ENV.ENTRY_SIZE = 30000
+	t.Cleanup(func() { *envhandler.ENV.ENTRY_SIZE = entrySize })
+
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "largedb"})
+
+	// a 20KB value fits into the body limit
+	value := strings.Repeat("x", 20*1024)
+	resp, body := doJSON(t, client, http.MethodPut, base+"/db/largedb", serverpkg.Set{Key: "big", Value: value})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set: expected 200, got %d, body=%s", resp.StatusCode, string(body))
+	}
+
+	_, body = doJSON(t, client, http.MethodPost, base+"/db/largedb/keys", serverpkg.Key{Key: "big"})
+	var v serverpkg.Value
+	if err := json.Unmarshal(body, &v); err != nil || v.Value != value {
+		t.Fatalf("get: value mismatch (len %d)", len(v.Value))
+	}
+
+	// bodies above HKV_MAX_BODY_BYTES are rejected
+	value = strings.Repeat("x", *envhandler.ENV.MAX_BODY_BYTES)
+	resp, _ = doJSON(t, client, http.MethodPut, base+"/db/largedb", serverpkg.Set{Key: "big", Value: value})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("set: expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_IPFilter(t *testing.T) {
+	allow, deny, trust := *envhandler.ENV.ALLOW_CIDRS, *envhandler.ENV.DENY_CIDRS, *envhandler.ENV.TRUST_PROXY
+	t.Cleanup(func() {
+		*envhandler.ENV.ALLOW_CIDRS = allow
+		*envhandler.ENV.DENY_CIDRS = deny
+		*envhandler.ENV.TRUST_PROXY = trust
+	})
+
+	get := func(client *http.Client, base, forwardedFor string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, base+"/livez", nil)
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// 1. the test client is not in the allowlist
+	*envhandler.ENV.ALLOW_CIDRS = "10.0.0.0/8"
+	_, client, base := newAPIServer(t)
+	if code := get(client, base, "10.1.2.3"); code != http.StatusForbidden {
+		t.Fatalf("allowlist: expected 403, got %d", code)
+	}
+
+	// 2. behind a trusted proxy the forwarded address counts
+	*envhandler.ENV.TRUST_PROXY = true
+	if code := get(client, base, "192.168.1.1, 10.1.2.3"); code != http.StatusOK {
+		t.Fatalf("trusted proxy: expected 200, got %d", code)
+	}
+	if code := get(client, base, "10.1.2.3, 192.168.1.1"); code != http.StatusForbidden {
+		t.Fatalf("trusted proxy: expected 403, got %d", code)
+	}
+
+	// 3. the denylist wins
+	*envhandler.ENV.TRUST_PROXY = false
+	*envhandler.ENV.ALLOW_CIDRS = ""
+	*envhandler.ENV.DENY_CIDRS = "127.0.0.1, ::1"
+	_, client, base = newAPIServer(t)
+	if code := get(client, base, ""); code != http.StatusForbidden {
+		t.Fatalf("denylist: expected 403, got %d", code)
+	}
+
+	// 4. invalid lists are reported
+	*envhandler.ENV.DENY_CIDRS = "10.0.0.0/33"
+	if err := serverpkg.CheckIPFilter(); err == nil {
+		t.Fatal("expected an error for an invalid CIDR")
+	}
+}
+
+func TestAPI_ClientIPBehindProxy(t *testing.T) {
+	allow, trust := *envhandler.ENV.ALLOW_CIDRS, *envhandler.ENV.TRUST_PROXY
+	t.Cleanup(func() {
+		*envhandler.ENV.ALLOW_CIDRS = allow
+		*envhandler.ENV.TRUST_PROXY = trust
+	})
+
+	// only the proxied client is allowed, the test client itself is not
+	*envhandler.ENV.ALLOW_CIDRS = "10.1.2.3"
+	_, client, base := newAPIServer(t)
+
+	get := func(header map[string]string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, base+"/livez", nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	cases := []struct {
+		name   string
+		trust  bool
+		header map[string]string
+		want   int
+	}{
+		{"untrusted forwarded for", false, map[string]string{"X-Forwarded-For": "10.1.2.3"}, http.StatusForbidden},
+		{"untrusted real ip", false, map[string]string{"X-Real-IP": "10.1.2.3"}, http.StatusForbidden},
+		{"last hop", true, map[string]string{"X-Forwarded-For": "1.1.1.1, 10.1.2.3"}, http.StatusOK},
+		{"spoofed first hop", true, map[string]string{"X-Forwarded-For": "10.1.2.3, 1.1.1.1"}, http.StatusForbidden},
+		{"hop with port", true, map[string]string{"X-Forwarded-For": "10.1.2.3:4711"}, http.StatusOK},
+		{"real ip", true, map[string]string{"X-Real-IP": "10.1.2.3"}, http.StatusOK},
+		{"invalid hop", true, map[string]string{"X-Forwarded-For": "not-an-ip", "X-Real-IP": "10.1.2.3"}, http.StatusForbidden},
+		{"no header", true, nil, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		*envhandler.ENV.TRUST_PROXY = c.trust
+		if code := get(c.header); code != c.want {
+			t.Errorf("%s: expected %d, got %d", c.name, c.want, code)
+		}
+	}
+}
+
+func TestAPI_OversizePolicy(t *testing.T) {
+	entrySize, policy := *envhandler.ENV.ENTRY_SIZE, *envhandler.ENV.OVERSIZE_POLICY
+	t.Cleanup(func() {
+		*envhandler.ENV.ENTRY_SIZE = entrySize
+		*envhandler.ENV.OVERSIZE_POLICY = policy
+	})
+	*envhandler.ENV.ENTRY_SIZE = 10
+
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "oversizedb"})
+	doJSON(t, client, http.MethodDelete, base+"/db/oversizedb/keys", serverpkg.Key{Key: "k"})
+
+	// 1. rejected by default
+	*envhandler.ENV.OVERSIZE_POLICY = envhandler.OVERSIZE_REJECT
+	resp, body := doJSON(t, client, http.MethodPut, base+"/db/oversizedb", serverpkg.Set{Key: "k", Value: "0123456789abc"})
+	if resp.StatusCode != http.StatusRequestEntityTooLarge || !strings.Contains(string(body), "value_too_large") {
+		t.Fatalf("reject: expected 413, got %d %s", resp.StatusCode, body)
+	}
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/oversizedb/keys", serverpkg.Key{Key: "k"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("reject: value was stored")
+	}
+
+	// 2. truncated and flagged
+	*envhandler.ENV.OVERSIZE_POLICY = envhandler.OVERSIZE_TRUNCATE
+	resp, body = doJSON(t, client, http.MethodPut, base+"/db/oversizedb", serverpkg.Set{Key: "k", Value: "0123456789abc"})
+	var ok serverpkg.OK
+	if err := json.Unmarshal(body, &ok); err != nil || resp.StatusCode != http.StatusOK || !ok.Truncated {
+		t.Fatalf("truncate: unexpected response %d %s", resp.StatusCode, body)
+	}
+	_, body = doJSON(t, client, http.MethodPost, base+"/db/oversizedb/keys", serverpkg.Key{Key: "k"})
+	var v serverpkg.Value
+	if err := json.Unmarshal(body, &v); err != nil || v.Value != "0123456789" {
+		t.Fatalf("truncate: unexpected value %s", body)
+	}
+
+	// 3. values within the limit are not flagged
+	_, body = doJSON(t, client, http.MethodPut, base+"/db/oversizedb", serverpkg.Set{Key: "k", Value: "short"})
+	if strings.Contains(string(body), "truncated") {
+		t.Fatalf("short value flagged as truncated: %s", body)
+	}
+}
+
+func TestAPI_MaxTTL(t *testing.T) {
+	maxTTL, policy, noExpiry := *envhandler.ENV.MAX_TTL_SECONDS, *envhandler.ENV.TTL_POLICY, *envhandler.ENV.ALLOW_NO_EXPIRY
+	t.Cleanup(func() {
+		*envhandler.ENV.MAX_TTL_SECONDS = maxTTL
+		*envhandler.ENV.TTL_POLICY = policy
+		*envhandler.ENV.ALLOW_NO_EXPIRY = noExpiry
+	})
+	*envhandler.ENV.MAX_TTL_SECONDS = 100
+
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "maxttldb"})
+	ttlOf := func(key string) int64 {
+		_, body := doJSON(t, client, http.MethodPost, base+"/db/maxttldb/mget-ttl", serverpkg.MGetTTL{Keys: []string{key}})
+		var got serverpkg.KeyTTLs
+		if err := json.Unmarshal(body, &got); err != nil || len(got.Results) != 1 || got.Results[0].Ttl == nil {
+			t.Fatalf("mget-ttl %s: %s", key, body)
+		}
+		return *got.Results[0].Ttl
+	}
+
+	// 1. clamped to the cap, ttls within it and no expiry are kept
+	*envhandler.ENV.TTL_POLICY = envhandler.TTL_CLAMP
+	doJSON(t, client, http.MethodPut, base+"/db/maxttldb", serverpkg.Set{Key: "long", Value: "v", Ttl: 1000000})
+	doJSON(t, client, http.MethodPut, base+"/db/maxttldb", serverpkg.Set{Key: "short", Value: "v", Ttl: 50})
+	doJSON(t, client, http.MethodPut, base+"/db/maxttldb", serverpkg.Set{Key: "forever", Value: "v"})
+	if ttl := ttlOf("long"); ttl <= 50 || ttl > 100 {
+		t.Fatalf("clamp: expected the cap, got %d", ttl)
+	}
+	if ttl := ttlOf("short"); ttl <= 0 || ttl > 50 {
+		t.Fatalf("clamp: expected 50, got %d", ttl)
+	}
+	if ttl := ttlOf("forever"); ttl != -1 {
+		t.Fatalf("clamp: expected no expiry, got %d", ttl)
+	}
+
+	// 2. without no expiry a ttl of 0 gets the cap
+	*envhandler.ENV.ALLOW_NO_EXPIRY = false
+	doJSON(t, client, http.MethodPut, base+"/db/maxttldb", serverpkg.Set{Key: "forever", Value: "v"})
+	if ttl := ttlOf("forever"); ttl <= 50 || ttl > 100 {
+		t.Fatalf("no expiry: expected the cap, got %d", ttl)
+	}
+
+	// 3. rejected with 400
+	*envhandler.ENV.TTL_POLICY = envhandler.TTL_REJECT
+	resp, body := doJSON(t, client, http.MethodPut, base+"/db/maxttldb", serverpkg.Set{Key: "rejected", Value: "v", Ttl: 101})
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "max=100") {
+		t.Fatalf("reject: expected 400, got %d %s", resp.StatusCode, body)
+	}
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/maxttldb", serverpkg.Set{Key: "rejected", Value: "v"})
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "min=1") {
+		t.Fatalf("reject no expiry: expected 400, got %d %s", resp.StatusCode, body)
+	}
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/maxttldb/keys", serverpkg.Key{Key: "rejected"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("reject: value was stored")
+	}
+
+	// 4. counters keep their expiry with a ttl of 0, a batch rejects the operation only
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/maxttldb/counter/incr", serverpkg.Counter{Key: "hits"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("counter: expected 200, got %d %s", resp.StatusCode, body)
+	}
+	_, body = doJSON(t, client, http.MethodPost, base+"/db/maxttldb/batch", serverpkg.Batch{Ops: []serverpkg.BatchOp{
+		{Op: "set", Key: "b1", Value: "v", Ttl: 1000}, {Op: "set", Key: "b2", Value: "v", Ttl: 10}}})
+	var results serverpkg.BatchResults
+	if err := json.Unmarshal(body, &results); err != nil || results.Results[0].Error != "ttl_out_of_range" || !results.Results[1].OK {
+		t.Fatalf("batch: unexpected results %s", body)
+	}
+}
+
+func TestAPI_SetIfGreaterLess(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "setifdb"})
+	doJSON(t, client, http.MethodDelete, base+"/db/setifdb/keys", serverpkg.Key{Key: "latency"})
+
+	for _, c := range []struct {
+		path  string
+		value string
+		code  int
+	}{
+		{"/setifgreater", "120", http.StatusOK},
+		{"/setifgreater", "80", http.StatusConflict},
+		{"/setifgreater", "150", http.StatusOK},
+		{"/setifless", "200", http.StatusConflict},
+		{"/setifless", "90", http.StatusOK},
+		{"/setifgreater", "fast", http.StatusConflict},
+	} {
+		resp, body := doJSON(t, client, http.MethodPost, base+"/db/setifdb"+c.path, serverpkg.Set{Key: "latency", Value: c.value})
+		if resp.StatusCode != c.code {
+			t.Fatalf("%s %s: expected %d, got %d %s", c.path, c.value, c.code, resp.StatusCode, body)
+		}
+	}
+
+	_, body := doJSON(t, client, http.MethodPost, base+"/db/setifdb/keys", serverpkg.Key{Key: "latency"})
+	var v serverpkg.Value
+	if err := json.Unmarshal(body, &v); err != nil || v.Value != "90" {
+		t.Fatalf("expected 90, got %s", body)
+	}
+}
+
+func TestAPI_Type(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "typedb"})
+	doJSON(t, client, http.MethodDelete, base+"/db/typedb/keys", serverpkg.Key{Key: "hits"})
+	doJSON(t, client, http.MethodDelete, base+"/db/typedb/keys", serverpkg.Key{Key: "name"})
+	doJSON(t, client, http.MethodPut, base+"/db/typedb", serverpkg.Set{Key: "name", Value: "hydra"})
+	doJSON(t, client, http.MethodPost, base+"/db/typedb/counter/incr", serverpkg.Counter{Key: "hits"})
+
+	for key, want := range map[string]string{"name": "string", "hits": "counter"} {
+		resp, body := doJSON(t, client, http.MethodPost, base+"/db/typedb/type", serverpkg.Key{Key: key})
+		var kt serverpkg.KeyType
+		if err := json.Unmarshal(body, &kt); err != nil || resp.StatusCode != http.StatusOK || !kt.Found || kt.Type != want {
+			t.Fatalf("%s: expected %s, got %d %s", key, want, resp.StatusCode, body)
+		}
+	}
+
+	resp, _ := doJSON(t, client, http.MethodPost, base+"/db/typedb/type", serverpkg.Key{Key: "missing"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("missing: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_GetAsJSON(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "jsondb"})
+	doJSON(t, client, http.MethodPut, base+"/db/jsondb", serverpkg.Set{Key: "doc", Value: `{"a":[1,2]}`})
+	doJSON(t, client, http.MethodPut, base+"/db/jsondb", serverpkg.Set{Key: "text", Value: "not json"})
+	doJSON(t, client, http.MethodDelete, base+"/db/jsondb/keys", serverpkg.Key{Key: "missing"})
+
+	resp, body := doJSON(t, client, http.MethodPost, base+"/db/jsondb/keys?as=json", serverpkg.Key{Key: "doc"})
+	var v struct {
+		Found bool `json:"found"`
+		Value struct {
+			A []int `json:"a"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil || resp.StatusCode != http.StatusOK || !v.Found || len(v.Value.A) != 2 {
+		t.Fatalf("get as json: expected an embedded object, got %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/jsondb/keys?as=json", serverpkg.Key{Key: "text"})
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("get invalid json: expected 422, got %d %s", resp.StatusCode, body)
+	}
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/jsondb/keys?as=json", serverpkg.Key{Key: "missing"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("get missing as json: expected 404, got %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/jsondb/keys?as=xml", serverpkg.Key{Key: "doc"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unknown format: expected 400, got %d", resp.StatusCode)
+	}
+
+	// without the option the value stays a string
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/jsondb/keys", serverpkg.Key{Key: "doc"})
+	var sv serverpkg.Value
+	if err := json.Unmarshal(body, &sv); err != nil || resp.StatusCode != http.StatusOK || sv.Value != `{"a":[1,2]}` {
+		t.Fatalf("get: expected the raw string, got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestAPI_GetEx(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "getexdb"})
+	doJSON(t, client, http.MethodPut, base+"/db/getexdb", serverpkg.Set{Key: "session", Value: "s1", Ttl: 1})
+
+	// a sliding expiry keeps the session alive past its first ttl
+	resp, body := doJSON(t, client, http.MethodPost, base+"/db/getexdb/getex", serverpkg.GetEx{Key: "session", Ttl: 60})
+	var v serverpkg.Value
+	if err := json.Unmarshal(body, &v); err != nil || resp.StatusCode != http.StatusOK || v.Value != "s1" {
+		t.Fatalf("getex: expected s1, got %d %s", resp.StatusCode, body)
+	}
+	time.Sleep(2100 * time.Millisecond)
+	if resp, body = doJSON(t, client, http.MethodPost, base+"/db/getexdb/keys", serverpkg.Key{Key: "session"}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the refreshed session to survive, got %d %s", resp.StatusCode, body)
+	}
+
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/getexdb/getex", serverpkg.GetEx{Key: "session", Persist: true})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("getex persist: expected 200, got %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/getexdb/getex", serverpkg.GetEx{Key: "session", Ttl: 5, Persist: true})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("ttl with persist: expected 400, got %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/getexdb/getex", serverpkg.GetEx{Key: "missing", Ttl: 5})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("getex missing: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_KeyMissStatus(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "missdb"})
+
+	// a missing key is a 404 with found=false, a missing DB a 404 with an error
+	resp, body := doJSON(t, client, http.MethodPost, base+"/db/missdb/keys", serverpkg.Key{Key: "missing"})
+	var v serverpkg.Value
+	if err := json.Unmarshal(body, &v); err != nil || resp.StatusCode != http.StatusNotFound || v.Found {
+		t.Fatalf("missing key: expected 404 with found=false, got %d %s", resp.StatusCode, body)
+	}
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/nosuchdb/keys", serverpkg.Key{Key: "missing"})
+	var e serverpkg.ErrorResponse
+	if err := json.Unmarshal(body, &e); err != nil || resp.StatusCode != http.StatusNotFound || e.Error != "db_not_found" {
+		t.Fatalf("missing db: expected 404 with db_not_found, got %d %s", resp.StatusCode, body)
+	}
+
+	// a miss can be a normal answer, the missing DB stays a 404
+	old := *envhandler.ENV.KEY_MISS_STATUS
+	*envhandler.ENV.KEY_MISS_STATUS = http.StatusOK
+	t.Cleanup(func() { *envhandler.ENV.KEY_MISS_STATUS = old })
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/missdb/keys", serverpkg.Key{Key: "missing"})
+	if err := json.Unmarshal(body, &v); err != nil || resp.StatusCode != http.StatusOK || v.Found {
+		t.Fatalf("missing key: expected 200 with found=false, got %d %s", resp.StatusCode, body)
+	}
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/nosuchdb/keys", serverpkg.Key{Key: "missing"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("missing db: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_NestedDataFolder(t *testing.T) {
+	oldFolder := *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.DB_FOLDER = filepath.Join(t.TempDir(), "var", "lib", "hydrakv")
+	t.Cleanup(func() { *envhandler.ENV.DB_FOLDER = oldFolder })
+
+	_, client, base := newAPIServer(t)
+	resp, body := doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "nesteddb"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create under a nested folder: expected 201, got %d %s", resp.StatusCode, body)
+	}
+	if resp, body := doJSON(t, client, http.MethodPut, base+"/db/nesteddb", serverpkg.Set{Key: "k", Value: "v"}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("set: expected 200, got %d %s", resp.StatusCode, body)
+	}
+
+	// a restart finds the DB in the created folder
+	dbs, err := restartcheck.RCheck.Check()
+	if err != nil || !slices.Contains(dbs, "nesteddb") {
+		t.Fatalf("expected nesteddb after a restart, got %v (err=%v)", dbs, err)
+	}
+}
+
+func TestRestartCheck_SnapshotFolder(t *testing.T) {
+	oldFolder := *envhandler.ENV.SNAPSHOT_FOLDER
+	t.Cleanup(func() { *envhandler.ENV.SNAPSHOT_FOLDER = oldFolder })
+
+	// unset it is the DB folder
+	*envhandler.ENV.SNAPSHOT_FOLDER = ""
+	if got := envhandler.ENV.SnapshotFolder(); got != *envhandler.ENV.DB_FOLDER {
+		t.Fatalf("default snapshot folder: got %q", got)
+	}
+
+	// a separate folder is created, the spool files a crash left are removed - other files are kept
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	*envhandler.ENV.SNAPSHOT_FOLDER = dir
+	if _, err := restartcheck.RCheck.Check(); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	stale, kept := filepath.Join(dir, ".snapshot-crashdb-123.tmp"), filepath.Join(dir, "keep.tar")
+	for _, name := range []string{stale, kept} {
+		if err := os.WriteFile(name, []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := restartcheck.RCheck.Check(); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("expected the stale spool file to be removed, got %v", err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Fatalf("expected other files to be kept: %v", err)
+	}
+
+	// a file in its place is reported with the setting
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	*envhandler.ENV.SNAPSHOT_FOLDER = file
+	if _, err := restartcheck.RCheck.Check(); err == nil || !strings.Contains(err.Error(), envhandler.SNAPSHOT_FOLDER) {
+		t.Fatalf("expected an error naming %s, got %v", envhandler.SNAPSHOT_FOLDER, err)
+	}
+}
+
+func TestAPI_Touch(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "touchdb"})
+	doJSON(t, client, http.MethodPut, base+"/db/touchdb", serverpkg.Set{Key: "session", Value: "s1", Ttl: 5})
+
+	resp, body := doJSON(t, client, http.MethodPost, base+"/db/touchdb/touch", serverpkg.Touch{Key: "session", Ttl: 600})
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"ok":true`) {
+		t.Fatalf("touch: expected 200, got %d %s", resp.StatusCode, body)
+	}
+	_, body = doJSON(t, client, http.MethodPost, base+"/db/touchdb/mget-ttl", serverpkg.MGetTTL{Keys: []string{"session"}})
+	var got serverpkg.KeyTTLs
+	if err := json.Unmarshal(body, &got); err != nil || *got.Results[0].Ttl <= 5 || *got.Results[0].Value != "s1" {
+		t.Fatalf("touch: expected the refreshed ttl, got %s", body)
+	}
+
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/touchdb/touch", serverpkg.Touch{Key: "missing", Ttl: 600})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("touch missing: expected 404, got %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/touchdb/touch", serverpkg.Touch{Key: "session"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("touch without ttl: expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_GetOrSet(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "getorsetdb"})
+	doJSON(t, client, http.MethodDelete, base+"/db/getorsetdb/keys", serverpkg.Key{Key: "cache"})
+	doJSON(t, client, http.MethodDelete, base+"/db/getorsetdb/keys", serverpkg.Key{Key: "user"})
+
+	for i, want := range []serverpkg.GetOrSetResponse{{Value: "v1", Created: true}, {Value: "v1"}} {
+		resp, body := doJSON(t, client, http.MethodPost, base+"/db/getorsetdb/getorset",
+			serverpkg.GetOrSet{Key: "cache", Value: "v" + strconv.Itoa(i+1), Ttl: 60})
+		var got serverpkg.GetOrSetResponse
+		if err := json.Unmarshal(body, &got); err != nil || resp.StatusCode != http.StatusOK || got != want {
+			t.Fatalf("call %d: expected %+v, got %d %s", i, want, resp.StatusCode, body)
+		}
+	}
+
+	doJSON(t, client, http.MethodPut, base+"/db/getorsetdb/hash", serverpkg.HashField{Key: "user", Field: "name", Value: "ada"})
+	resp, _ := doJSON(t, client, http.MethodPost, base+"/db/getorsetdb/getorset", serverpkg.GetOrSet{Key: "user", Value: "v1"})
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("getorset on a hash: expected 409, got %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/getorsetdb/getorset", serverpkg.GetOrSet{Key: "cache"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("getorset without value: expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_RateLimit(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "ratedb"})
+	doJSON(t, client, http.MethodDelete, base+"/db/ratedb/keys", serverpkg.Key{Key: "ip:1"})
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		resp, body := doJSON(t, client, http.MethodPost, base+"/db/ratedb/counter/limit", serverpkg.RateLimit{Key: "ip:1", Limit: 3, Ttl: 60})
+		var v serverpkg.RateLimitValue
+		if err := json.Unmarshal(body, &v); err != nil || resp.StatusCode != want || v.Count != int64(min(i+1, 3)) {
+			t.Fatalf("call %d: expected %d, got %d %s", i, want, resp.StatusCode, body)
+		}
+	}
+
+	resp, _ := doJSON(t, client, http.MethodPost, base+"/db/ratedb/counter/limit", serverpkg.RateLimit{Key: "ip:1"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("missing limit: expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_Hash(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "hashdb"})
+	doJSON(t, client, http.MethodDelete, base+"/db/hashdb/keys", serverpkg.Key{Key: "user"})
+
+	for field, value := range map[string]string{"name": "hydra", "age": "3"} {
+		resp, body := doJSON(t, client, http.MethodPut, base+"/db/hashdb/hash", serverpkg.HashField{Key: "user", Field: field, Value: value})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("hset %s: expected 200, got %d %s", field, resp.StatusCode, body)
+		}
+	}
+
+	resp, body := doJSON(t, client, http.MethodPost, base+"/db/hashdb/hash/get", serverpkg.HashField{Key: "user", Field: "name"})
+	var v serverpkg.Value
+	if err := json.Unmarshal(body, &v); err != nil || resp.StatusCode != http.StatusOK || v.Value != "hydra" {
+		t.Fatalf("hget: expected hydra, got %d %s", resp.StatusCode, body)
+	}
+
+	resp, _ = doJSON(t, client, http.MethodDelete, base+"/db/hashdb/hash", serverpkg.HashField{Key: "user", Field: "age"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("hdel: expected 200, got %d", resp.StatusCode)
+	}
+
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/hashdb/hash/getall", serverpkg.Key{Key: "user"})
+	var hv serverpkg.HashValue
+	if err := json.Unmarshal(body, &hv); err != nil || resp.StatusCode != http.StatusOK || len(hv.Fields) != 1 || hv.Fields["name"] != "hydra" {
+		t.Fatalf("hgetall: expected {name: hydra}, got %d %s", resp.StatusCode, body)
+	}
+
+	// a hash is no string
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/hashdb/keys", serverpkg.Key{Key: "user"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("get on a hash: expected 404, got %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/hashdb/hash/getall", serverpkg.Key{Key: "missing"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("missing hash: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_SetType(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "setdb"})
+	doJSON(t, client, http.MethodDelete, base+"/db/setdb/keys", serverpkg.Key{Key: "seen"})
+
+	for member, want := range map[string]int{"a": http.StatusOK, "b": http.StatusOK} {
+		resp, body := doJSON(t, client, http.MethodPut, base+"/db/setdb/set", serverpkg.SetMember{Key: "seen", Member: member})
+		if resp.StatusCode != want {
+			t.Fatalf("sadd %s: expected %d, got %d %s", member, want, resp.StatusCode, body)
+		}
+	}
+	resp, _ := doJSON(t, client, http.MethodPut, base+"/db/setdb/set", serverpkg.SetMember{Key: "seen", Member: "a"})
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("duplicate sadd: expected 409, got %d", resp.StatusCode)
+	}
+
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/setdb/set/ismember", serverpkg.SetMember{Key: "seen", Member: "b"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("sismember: expected 200, got %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, client, http.MethodDelete, base+"/db/setdb/set", serverpkg.SetMember{Key: "seen", Member: "b"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("srem: expected 200, got %d", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/setdb/set/ismember", serverpkg.SetMember{Key: "seen", Member: "b"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("sismember after srem: expected 404, got %d", resp.StatusCode)
+	}
+
+	resp, body := doJSON(t, client, http.MethodPost, base+"/db/setdb/set/members", serverpkg.Key{Key: "seen"})
+	var sm serverpkg.SetMembers
+	if err := json.Unmarshal(body, &sm); err != nil || resp.StatusCode != http.StatusOK || sm.Card != 1 || sm.Members[0] != "a" {
+		t.Fatalf("smembers: expected [a], got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestAPI_ZSet(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "zsetdb"})
+	doJSON(t, client, http.MethodDelete, base+"/db/zsetdb/keys", serverpkg.Key{Key: "board"})
+
+	for member, score := range map[string]float64{"alice": 30, "bob": 10, "carol": 20} {
+		resp, body := doJSON(t, client, http.MethodPut, base+"/db/zsetdb/zset", serverpkg.ZSetMember{Key: "board", Member: member, Score: score})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("zadd %s: expected 200, got %d %s", member, resp.StatusCode, body)
+		}
+	}
+
+	resp, body := doJSON(t, client, http.MethodPost, base+"/db/zsetdb/zset/range", serverpkg.ZSetRange{Key: "board", Start: -2, Stop: -1})
+	var zm serverpkg.ZSetMembers
+	if err := json.Unmarshal(body, &zm); err != nil || resp.StatusCode != http.StatusOK || len(zm.Members) != 2 ||
+		zm.Members[0].Member != "carol" || zm.Members[1].Member != "alice" || zm.Members[1].Score != 30 {
+		t.Fatalf("zrange: expected [carol alice], got %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/zsetdb/zset/rank", serverpkg.ZSetMember{Key: "board", Member: "alice"})
+	var zr serverpkg.ZSetRank
+	if err := json.Unmarshal(body, &zr); err != nil || resp.StatusCode != http.StatusOK || zr.Rank != 2 {
+		t.Fatalf("zrank: expected 2, got %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/zsetdb/zset/score", serverpkg.ZSetMember{Key: "board", Member: "bob"})
+	var zs serverpkg.ZSetScore
+	if err := json.Unmarshal(body, &zs); err != nil || resp.StatusCode != http.StatusOK || zs.Score != 10 {
+		t.Fatalf("zscore: expected 10, got %d %s", resp.StatusCode, body)
+	}
+
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/zsetdb/zset/score", serverpkg.ZSetMember{Key: "board", Member: "dave"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("zscore of a missing member: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_List(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "listdb"})
+	doJSON(t, client, http.MethodDelete, base+"/db/listdb/keys", serverpkg.Key{Key: "jobs"})
+
+	for _, c := range []struct{ op, value string }{{"rpush", "b"}, {"rpush", "c"}, {"lpush", "a"}} {
+		resp, body := doJSON(t, client, http.MethodPost, base+"/db/listdb/list/"+c.op, serverpkg.ListPush{Key: "jobs", Value: c.value})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s %s: expected 200, got %d %s", c.op, c.value, resp.StatusCode, body)
+		}
+	}
+
+	resp, body := doJSON(t, client, http.MethodPost, base+"/db/listdb/list/rpop", serverpkg.Key{Key: "jobs"})
+	var v serverpkg.Value
+	if err := json.Unmarshal(body, &v); err != nil || resp.StatusCode != http.StatusOK || v.Value != "c" {
+		t.Fatalf("rpop: expected c, got %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/listdb/list/range", serverpkg.ListRange{Key: "jobs", Start: 0, Stop: -1})
+	var lv serverpkg.ListValues
+	if err := json.Unmarshal(body, &lv); err != nil || resp.StatusCode != http.StatusOK || lv.Len != 2 ||
+		len(lv.Values) != 2 || lv.Values[0] != "a" || lv.Values[1] != "b" {
+		t.Fatalf("range: expected [a b], got %d %s", resp.StatusCode, body)
+	}
+
+	resp, _ = doJSON(t, client, http.MethodPost, base+"/db/listdb/list/lpop", serverpkg.Key{Key: "missing"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("lpop of a missing list: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_Meta(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "metadb"})
+	doJSON(t, client, http.MethodDelete, base+"/db/metadb/keys", serverpkg.Key{Key: "name"})
+
+	before := time.Now().UnixNano()
+	doJSON(t, client, http.MethodPut, base+"/db/metadb", serverpkg.Set{Key: "name", Value: "hydra", Ttl: 60})
+
+	meta := func() serverpkg.KeyMeta {
+		t.Helper()
+		resp, body := doJSON(t, client, http.MethodPost, base+"/db/metadb/meta", serverpkg.Key{Key: "name"})
+		var m serverpkg.KeyMeta
+		if err := json.Unmarshal(body, &m); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("meta: got %d %s", resp.StatusCode, body)
+		}
+		return m
+	}
+	m := meta()
+	if !m.Exists || m.Size != 5 || m.TtlRemaining <= 0 || m.TtlRemaining > 60 || m.ModifiedAt < before {
+		t.Fatalf("unexpected meta %+v", m)
+	}
+
+	// the get sends the time of the last write
+	resp, _ := doJSON(t, client, http.MethodPost, base+"/db/metadb/keys", serverpkg.Key{Key: "name"})
+	if got := resp.Header.Get("X-Modified-At"); got != strconv.FormatInt(m.ModifiedAt, 10) {
+		t.Fatalf("X-Modified-At: got %q want %d", got, m.ModifiedAt)
+	}
+
+	// a write moves it forward
+	doJSON(t, client, http.MethodPut, base+"/db/metadb", serverpkg.Set{Key: "name", Value: "hydrakv"})
+	if m2 := meta(); m2.ModifiedAt <= m.ModifiedAt || m2.Size != 7 {
+		t.Fatalf("after write: %+v, before %+v", m2, m)
+	}
+
+	resp, body := doJSON(t, client, http.MethodPost, base+"/db/metadb/meta", serverpkg.Key{Key: "missing"})
+	if resp.StatusCode != http.StatusNotFound || !strings.Contains(string(body), `"exists":false`) {
+		t.Fatalf("missing: expected 404, got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestAPI_ContentType(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "ctypedb"})
+
+	get := func() serverpkg.Value {
+		t.Helper()
+		_, body := doJSON(t, client, http.MethodPost, base+"/db/ctypedb/keys", serverpkg.Key{Key: "doc"})
+		var v serverpkg.Value
+		if err := json.Unmarshal(body, &v); err != nil {
+			t.Fatalf("get: %s", body)
+		}
+		return v
+	}
+
+	resp, body := doJSON(t, client, http.MethodPut, base+"/db/ctypedb",
+		serverpkg.Set{Key: "doc", Value: `{"a":1}`, ContentType: "application/json"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set: got %d %s", resp.StatusCode, body)
+	}
+	if v := get(); !v.Found || v.ContentType != "application/json" {
+		t.Fatalf("get: %+v", v)
+	}
+	_, body = doJSON(t, client, http.MethodPost, base+"/db/ctypedb/meta", serverpkg.Key{Key: "doc"})
+	if !strings.Contains(string(body), `"content_type":"application/json"`) {
+		t.Fatalf("meta: %s", body)
+	}
+
+	// a set without content type removes it, the response leaves it out
+	doJSON(t, client, http.MethodPut, base+"/db/ctypedb", serverpkg.Set{Key: "doc", Value: "plain"})
+	_, body = doJSON(t, client, http.MethodPost, base+"/db/ctypedb/keys", serverpkg.Key{Key: "doc"})
+	if strings.Contains(string(body), "content_type") {
+		t.Fatalf("plain value: %s", body)
+	}
+
+	// only PUT stores a content type
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/ctypedb",
+		serverpkg.Set{Key: "new", Value: "v", ContentType: "text/plain"})
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "content_type") {
+		t.Fatalf("setnx with content type: expected 400, got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestAPI_Expiring(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "expiringdb"})
+	doJSON(t, client, http.MethodPut, base+"/db/expiringdb", serverpkg.Set{Key: "later", Value: "v", Ttl: 50})
+	doJSON(t, client, http.MethodPut, base+"/db/expiringdb", serverpkg.Set{Key: "soon", Value: "v", Ttl: 5})
+	doJSON(t, client, http.MethodPut, base+"/db/expiringdb", serverpkg.Set{Key: "forever", Value: "v"})
+
+	resp, body := doJSON(t, client, http.MethodPost, base+"/db/expiringdb/expiring", serverpkg.Expiring{Seconds: 60})
+	var got serverpkg.ExpiringKeys
+	if err := json.Unmarshal(body, &got); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expiring: got %d %s", resp.StatusCode, body)
+	}
+	if !slices.Equal(got.Keys, []string{"soon", "later"}) || got.Truncated {
+		t.Fatalf("unexpected keys %+v", got)
+	}
+
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/expiringdb/expiring", serverpkg.Expiring{Seconds: 0})
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "validation_failed") {
+		t.Fatalf("seconds 0: expected 400, got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestAPI_Batch(t *testing.T) {
+	// a folder of its own, so a rerun starts without the DB
+	oldFolder := *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	t.Cleanup(func() { *envhandler.ENV.DB_FOLDER = oldFolder })
+
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "batchdb"})
+
+	ops := []serverpkg.BatchOp{
+		{Op: "set", Key: "a", Value: "1"},
+		{Op: "setnx", Key: "a", Value: "2"},
+		{Op: "get", Key: "a"},
+		{Op: "incr", Key: "c"},
+		{Op: "incr", Key: "c", Amount: new(int64(4))},
+		{Op: "exists", Key: "c"},
+		{Op: "del", Key: "a"},
+		{Op: "get", Key: "a"},
+	}
+	resp, body := doJSON(t, client, http.MethodPost, base+"/db/batchdb/batch", serverpkg.Batch{Ops: ops})
+	var got serverpkg.BatchResults
+	if err := json.Unmarshal(body, &got); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("batch: got %d %s", resp.StatusCode, body)
+	}
+
+	// the operations run in order
+	want := []string{"set true", "setnx false", "get true 1", "incr true #1", "incr true #5", "exists true",
+		"del true", "get false"}
+	if len(got.Results) != len(want) {
+		t.Fatalf("expected %d results, got %s", len(want), body)
+	}
+	for i, res := range got.Results {
+		line := res.Op + " " + strconv.FormatBool(res.OK)
+		if res.Value != nil {
+			line += " " + *res.Value
+		}
+		if res.Count != nil {
+			line += " #" + strconv.FormatInt(*res.Count, 10)
+		}
+		if line != want[i] {
+			t.Fatalf("result %d: got %q want %q", i, line, want[i])
+		}
+	}
+
+	// unknown operations, empty and too large batches are rejected as a whole
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/batchdb/batch",
+		serverpkg.Batch{Ops: []serverpkg.BatchOp{{Op: "flushall", Key: "a"}}})
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "oneof") {
+		t.Fatalf("unknown op: expected 400, got %d %s", resp.StatusCode, body)
+	}
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/batchdb/batch", serverpkg.Batch{})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("empty batch: expected 400, got %d %s", resp.StatusCode, body)
+	}
+
+	maxOps := *envhandler.ENV.BATCH_MAX_OPS
+	*envhandler.ENV.BATCH_MAX_OPS = 2
+	t.Cleanup(func() { *envhandler.ENV.BATCH_MAX_OPS = maxOps })
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/batchdb/batch", serverpkg.Batch{Ops: ops[:3]})
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "max=2") {
+		t.Fatalf("too many ops: expected 400, got %d %s", resp.StatusCode, body)
+	}
+	if _, body = doJSON(t, client, http.MethodPost, base+"/db/batchdb/keys", serverpkg.Key{Key: "a"}); !strings.Contains(string(body), `"found":false`) {
+		t.Fatalf("rejected batch was executed: %s", body)
+	}
+}
+
+func TestAPI_MGetTTL(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "mgetttldb"})
+	doJSON(t, client, http.MethodPut, base+"/db/mgetttldb", serverpkg.Set{Key: "session", Value: "s1", Ttl: 60})
+	doJSON(t, client, http.MethodPut, base+"/db/mgetttldb", serverpkg.Set{Key: "forever", Value: "f"})
+	doJSON(t, client, http.MethodPut, base+"/db/mgetttldb/hash", serverpkg.HashField{Key: "hash", Field: "f", Value: "v"})
+
+	keys := []string{"session", "missing", "forever", "hash"}
+	resp, body := doJSON(t, client, http.MethodPost, base+"/db/mgetttldb/mget-ttl", serverpkg.MGetTTL{Keys: keys})
+	var got serverpkg.KeyTTLs
+	if err := json.Unmarshal(body, &got); err != nil || resp.StatusCode != http.StatusOK || len(got.Results) != len(keys) {
+		t.Fatalf("mget-ttl: got %d %s", resp.StatusCode, body)
+	}
+
+	// in the order of the keys, missing keys and collections without value and ttl
+	if r := got.Results[0]; r.Key != "session" || !r.Found || *r.Value != "s1" || *r.Ttl <= 0 || *r.Ttl > 60 {
+		t.Fatalf("session: %s", body)
+	}
+	if r := got.Results[2]; r.Key != "forever" || !r.Found || *r.Value != "f" || *r.Ttl != -1 {
+		t.Fatalf("forever: %s", body)
+	}
+	for _, r := range []serverpkg.KeyTTL{got.Results[1], got.Results[3]} {
+		if r.Found || r.Value != nil || r.Ttl != nil {
+			t.Fatalf("%s: %s", r.Key, body)
+		}
+	}
+
+	// bounded like a batch
+	maxOps := *envhandler.ENV.BATCH_MAX_OPS
+	*envhandler.ENV.BATCH_MAX_OPS = 2
+	t.Cleanup(func() { *envhandler.ENV.BATCH_MAX_OPS = maxOps })
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/mgetttldb/mget-ttl", serverpkg.MGetTTL{Keys: keys})
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "max=2") {
+		t.Fatalf("too many keys: expected 400, got %d %s", resp.StatusCode, body)
+	}
+	resp, body = doJSON(t, client, http.MethodPost, base+"/db/mgetttldb/mget-ttl", serverpkg.MGetTTL{})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("no keys: expected 400, got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestAPI_Changelog(t *testing.T) {
+	// a folder of its own, so a rerun starts without the DB and its changelog
+	oldFolder := *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	t.Cleanup(func() { *envhandler.ENV.DB_FOLDER = oldFolder })
+
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "changesdb", Changelog: true})
+	doJSON(t, client, http.MethodPut, base+"/db/changesdb", serverpkg.Set{Key: "a", Value: "1"})
+	doJSON(t, client, http.MethodPut, base+"/db/changesdb", serverpkg.Set{Key: "a", Value: "2"})
+
+	// the changes are recorded by the AOF loop
+	var got serverpkg.Changes
+	deadline := time.Now().Add(5 * time.Second)
+	for len(got.Changes) < 2 && time.Now().Before(deadline) {
+		resp, body := doJSON(t, client, http.MethodGet, base+"/db/changesdb/changes", nil)
+		if err := json.Unmarshal(body, &got); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("changes: got %d %s", resp.StatusCode, body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(got.Changes) != 2 || got.Next != 2 || got.Oldest != 1 || got.Changes[1].Action != "set" ||
+		got.Changes[1].Key != "a" || got.Changes[1].Value != "2" {
+		t.Fatalf("changes: %+v", got)
+	}
+	resp, body := doJSON(t, client, http.MethodGet, base+"/db/changesdb/changes?since=1&limit=10", nil)
+	if err := json.Unmarshal(body, &got); err != nil || resp.StatusCode != http.StatusOK || len(got.Changes) != 1 ||
+		got.Changes[0].Seq != 2 {
+		t.Fatalf("changes since 1: got %d %s", resp.StatusCode, body)
+	}
+	resp, body = doJSON(t, client, http.MethodGet, base+"/db/changesdb/changes?action=set&prefix=b", nil)
+	if err := json.Unmarshal(body, &got); err != nil || resp.StatusCode != http.StatusOK || len(got.Changes) != 0 ||
+		got.Next != 2 {
+		t.Fatalf("changes of b: got %d %s", resp.StatusCode, body)
+	}
+	resp, body = doJSON(t, client, http.MethodGet, base+"/db/changesdb/changes?action=set&prefix=a&limit=1", nil)
+	if err := json.Unmarshal(body, &got); err != nil || resp.StatusCode != http.StatusOK || len(got.Changes) != 1 ||
+		got.Changes[0].Seq != 1 || got.Next != 1 {
+		t.Fatalf("changes of a: got %d %s", resp.StatusCode, body)
+	}
+	for _, query := range []string{"?since=-1", "?limit=0", "?limit=1001", "?since=x"} {
+		if resp, body := doJSON(t, client, http.MethodGet, base+"/db/changesdb/changes"+query, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d %s", query, resp.StatusCode, body)
+		}
+	}
+
+	// opt-in per DB
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "nochangesdb"})
+	resp, body = doJSON(t, client, http.MethodGet, base+"/db/nochangesdb/changes", nil)
+	if resp.StatusCode != http.StatusNotFound || !strings.Contains(string(body), "changelog_disabled") {
+		t.Fatalf("disabled changelog: expected 404, got %d %s", resp.StatusCode, body)
+	}
+	if resp, body := doJSON(t, client, http.MethodPut, base+"/db/nochangesdb/changes", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("enable: got %d %s", resp.StatusCode, body)
+	}
+	resp, body = doJSON(t, client, http.MethodGet, base+"/db/nochangesdb/changes", nil)
+	if err := json.Unmarshal(body, &got); err != nil || resp.StatusCode != http.StatusOK || len(got.Changes) != 0 ||
+		got.Oldest != 1 {
+		t.Fatalf("enabled changelog: got %d %s", resp.StatusCode, body)
+	}
+	if resp, body := doJSON(t, client, http.MethodDelete, base+"/db/nochangesdb/changes", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("disable: got %d %s", resp.StatusCode, body)
+	}
+	if resp, _ := doJSON(t, client, http.MethodGet, base+"/db/nochangesdb/changes", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("disabled changelog: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_H2C(t *testing.T) {
+	h2c := *envhandler.ENV.HTTP2_H2C
+	t.Cleanup(func() { *envhandler.ENV.HTTP2_H2C = h2c })
+
+	// the protocols are set on the http.Server, so the test server has to use them
+	start := func() (*httptest.Server, *atomic.Int32) {
+		s := serverpkg.NewServer(0, "127.0.0.1")
+		ts := httptest.NewUnstartedServer(s.Handler())
+		ts.Config.Protocols = s.Server.Protocols
+		conns := &atomic.Int32{}
+		ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				conns.Add(1)
+			}
+		}
+		ts.Start()
+		t.Cleanup(ts.Close)
+		return ts, conns
+	}
+
+	// a client which only speaks HTTP/2 without TLS
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}, Timeout: 2 * time.Second}
+
+	// 1. off by default - HTTP/2 is not understood
+	*envhandler.ENV.HTTP2_H2C = false
+	ts, _ := start()
+	if resp, err := client.Get(ts.URL + "/livez"); err == nil {
+		resp.Body.Close()
+		t.Fatalf("HTTP/2 request without h2c: got %d", resp.StatusCode)
+	}
+
+	// 2. with h2c concurrent requests share one connection and pass all middlewares
+	*envhandler.ENV.HTTP2_H2C = true
+	ts, conns := start()
+	doJSON(t, client, http.MethodPost, ts.URL+"/create", serverpkg.NewDB{Name: "h2cdb"})
+	var wg sync.WaitGroup
+	errs := make(chan string, 20)
+	for i := range 20 {
+		wg.Go(func() {
+			key := "k" + strconv.Itoa(i)
+			body, _ := json.Marshal(serverpkg.Set{Key: key, Value: "v"})
+			req, _ := http.NewRequest(http.MethodPut, ts.URL+"/db/h2cdb", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := client.Do(req)
+			if err != nil {
+				errs <- fmt.Sprintf("set %s: %v", key, err)
+				return
+			}
+			resp.Body.Close()
+			if resp.ProtoMajor != 2 || resp.StatusCode != http.StatusOK {
+				errs <- fmt.Sprintf("set %s: %s %d", key, resp.Proto, resp.StatusCode)
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if n := conns.Load(); n != 1 {
+		t.Fatalf("got %d connections, want 1", n)
+	}
+
+	// HTTP/1.1 clients keep working
+	resp, _ := doJSON(t, http.DefaultClient, http.MethodPost, ts.URL+"/db/h2cdb/keys", serverpkg.Key{Key: "k7"})
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 1 {
+		t.Fatalf("HTTP/1.1 get: %s %d", resp.Proto, resp.StatusCode)
+	}
+}
+
+func TestAPI_MaxConnsPerIP(t *testing.T) {
+	limit, trust := *envhandler.ENV.MAX_CONNS_PER_IP, *envhandler.ENV.TRUST_PROXY
+	t.Cleanup(func() {
+		*envhandler.ENV.MAX_CONNS_PER_IP = limit
+		*envhandler.ENV.TRUST_PROXY = trust
+	})
+	*envhandler.ENV.MAX_CONNS_PER_IP = 2
+
+	// the limit hooks into the connections, so the test server has to use them
+	start := func() *httptest.Server {
+		s := serverpkg.NewServer(0, "127.0.0.1")
+		ts := httptest.NewUnstartedServer(s.Handler())
+		ts.Config.ConnState = s.Server.ConnState
+		ts.Config.ConnContext = s.Server.ConnContext
+		ts.Start()
+		t.Cleanup(ts.Close)
+		return ts
+	}
+	livez := func(conn net.Conn, forwardedFor string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, "http://hydrakv/livez", nil)
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		if err := req.Write(conn); err != nil {
+			return 0
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// 1. the third connection of the same IP is closed
+	ts := start()
+	addr := ts.Listener.Addr().String()
+	conns := make([]net.Conn, 0, 3)
+	for range 3 {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	if code := livez(conns[0], ""); code != http.StatusOK {
+		t.Fatalf("first connection: expected 200, got %d", code)
+	}
+	if code := livez(conns[2], ""); code != 0 {
+		t.Fatalf("third connection: expected it to be closed, got %d", code)
+	}
+
+	// a closed connection frees its slot
+	conns[1].Close()
+	time.Sleep(50 * time.Millisecond)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if code := livez(conn, ""); code != http.StatusOK {
+		t.Fatalf("after close: expected 200, got %d", code)
+	}
+
+	// 2. behind a proxy the connections count for the forwarded client
+	*envhandler.ENV.TRUST_PROXY = true
+	ts = start()
+	codes := make([]int, 0, 3)
+	for range 3 {
+		conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		codes = append(codes, livez(conn, "10.1.2.3"))
+	}
+	if !slices.Equal(codes, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}) {
+		t.Fatalf("behind a proxy: got %v", codes)
+	}
+	conn, err = net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if code := livez(conn, "10.9.9.9"); code != http.StatusOK {
+		t.Fatalf("other client behind the proxy: expected 200, got %d", code)
+	}
+}
@@ -1,11 +1,19 @@
 package tests
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"hydrakv/envhandler"
 	serverpkg "hydrakv/server"
+	"hydrakv/utils"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -72,3 +80,953 @@ func TestAPIKeyEngine(t *testing.T) {
 	}
 	resp.Body.Close()
 }
+
+func TestAPIKey_ReadKey(t *testing.T) {
+	oldVal, oldFolder := *envhandler.ENV.APIKEY_ENABLED, *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.APIKEY_ENABLED = true
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() {
+		*envhandler.ENV.APIKEY_ENABLED = oldVal
+		*envhandler.ENV.DB_FOLDER = oldFolder
+	}()
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	client := ts.Client()
+	base := ts.URL
+
+	dbName := "readkeydb"
+	_, body := doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: dbName})
+	var created serverpkg.NewDBCreated
+	if err := json.Unmarshal(body, &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	do := func(method, url, key string, payload any) *http.Response {
+		t.Helper()
+		b, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, url, bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// 1. only the write key may create a read key
+	resp, err := func() (*http.Response, error) {
+		req, _ := http.NewRequest(http.MethodPost, base+"/db/"+dbName+"/readkey", nil)
+		req.Header.Set("X-API-Key", created.ApiKey)
+		return client.Do(req)
+	}()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("create read key failed: %v", err)
+	}
+	var readKey serverpkg.ReadKeyCreated
+	if err := json.NewDecoder(resp.Body).Decode(&readKey); err != nil || readKey.ReadKey == "" {
+		t.Fatalf("decode read key: %v", err)
+	}
+	resp.Body.Close()
+
+	// 2. the write key writes, the read key reads but cant write
+	if resp := do(http.MethodPut, base+"/db/"+dbName, created.ApiKey, serverpkg.Set{Key: "k", Value: "v"}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("write with write key: expected 200, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodPost, base+"/db/"+dbName+"/keys", readKey.ReadKey, serverpkg.Key{Key: "k"}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("read with read key: expected 200, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodPut, base+"/db/"+dbName, readKey.ReadKey, serverpkg.Set{Key: "k", Value: "v2"}); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("write with read key: expected 403, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodPost, base+"/db/"+dbName+"/readkey", readKey.ReadKey, nil); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("read key creation with read key: expected 403, got %d", resp.StatusCode)
+	}
+
+	// 3. both keys survive a restore from the versioned .apikey file
+	if err := utils.U.RestoreApiKeys(); err != nil {
+		t.Fatalf("RestoreApiKeys: %v", err)
+	}
+	if !utils.U.IsApiKeyValid(dbName, created.ApiKey) || utils.U.IsApiKeyValid(dbName, readKey.ReadKey) {
+		t.Fatal("write key not restored")
+	}
+	if !utils.U.IsReadKeyValid(dbName, readKey.ReadKey) {
+		t.Fatal("read key not restored")
+	}
+}
+
+func TestAPIKey_LegacyFile(t *testing.T) {
+	oldFolder := *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() { *envhandler.ENV.DB_FOLDER = oldFolder }()
+
+	// a legacy .apikey file holds the SHA-256 of the write key only
+	hash := sha256.Sum256([]byte("legacy-key"))
+	if err := os.WriteFile(filepath.Join(*envhandler.ENV.DB_FOLDER, ".LEGACYDB.apikey"), hash[:], 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := utils.U.RestoreApiKeys(); err != nil {
+		t.Fatalf("RestoreApiKeys: %v", err)
+	}
+	if !utils.U.IsApiKeyValid("legacydb", "legacy-key") {
+		t.Fatal("legacy write key not restored")
+	}
+}
+
+func TestAPIKey_AuditLog(t *testing.T) {
+	oldVal, oldLog := *envhandler.ENV.APIKEY_ENABLED, *envhandler.ENV.AUDIT_LOG
+	auditFile := filepath.Join(t.TempDir(), "audit.log")
+	*envhandler.ENV.APIKEY_ENABLED = true
+	*envhandler.ENV.AUDIT_LOG = auditFile
+	defer func() {
+		*envhandler.ENV.APIKEY_ENABLED = oldVal
+		*envhandler.ENV.AUDIT_LOG = oldLog
+	}()
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	client := ts.Client()
+
+	dbName := "auditdb"
+	_, body := doJSON(t, client, http.MethodPost, ts.URL+"/create", serverpkg.NewDB{Name: dbName})
+	var created serverpkg.NewDBCreated
+	if err := json.Unmarshal(body, &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	for _, key := range []string{"wrong-secret-key", created.ApiKey} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/db/"+dbName, nil)
+		req.Header.Set("X-API-Key", key)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	data, err := os.ReadFile(auditFile)
+	if err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+	if bytes.Contains(data, []byte("wrong-secret-key")) || bytes.Contains(data, []byte(created.ApiKey)) {
+		t.Fatal("audit log contains an api key")
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 audit entries, got %d: %s", len(lines), data)
+	}
+	var entry struct {
+		IP      string `json:"ip"`
+		DB      string `json:"db"`
+		Key     string `json:"key"`
+		Outcome string `json:"outcome"`
+	}
+	for i, outcome := range []string{"denied", "granted"} {
+		if err := json.Unmarshal(lines[i], &entry); err != nil {
+			t.Fatalf("decode audit entry: %v", err)
+		}
+		if entry.Outcome != outcome || entry.DB != utils.U.DbKey(dbName) || entry.IP != "127.0.0.1" || len(entry.Key) != 8 {
+			t.Fatalf("unexpected audit entry %d: %s", i, lines[i])
+		}
+	}
+}
+
+func TestAPIKey_Lockout(t *testing.T) {
+	oldVal, oldLockout := *envhandler.ENV.APIKEY_ENABLED, *envhandler.ENV.AUTH_LOCKOUT
+	*envhandler.ENV.APIKEY_ENABLED = true
+	*envhandler.ENV.AUTH_LOCKOUT = 3
+	defer func() {
+		*envhandler.ENV.APIKEY_ENABLED = oldVal
+		*envhandler.ENV.AUTH_LOCKOUT = oldLockout
+	}()
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	client := ts.Client()
+
+	dbName := "lockoutdb"
+	_, body := doJSON(t, client, http.MethodPost, ts.URL+"/create", serverpkg.NewDB{Name: dbName})
+	var created serverpkg.NewDBCreated
+	if err := json.Unmarshal(body, &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	get := func(db, key string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/db/"+db, nil)
+		req.Header.Set("X-API-Key", key)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// a successful attempt resets the failures
+	get(dbName, "wrong")
+	get(dbName, "wrong")
+	if resp := get(dbName, created.ApiKey); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	for i := 0; i < 3; i++ {
+		if resp := get(dbName, "wrong"); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i, resp.StatusCode)
+		}
+	}
+
+	// locked out, even with the right key
+	resp := get(dbName, created.ApiKey)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Fatal("missing Retry-After header")
+	}
+
+	// other DBs are not affected
+	if resp := get("otherlockoutdb", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for another DB, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPIKey_AdminListAndRevoke(t *testing.T) {
+	oldVal, oldAdmin, oldFolder := *envhandler.ENV.APIKEY_ENABLED, *envhandler.ENV.ADMIN_KEY, *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.APIKEY_ENABLED = true
+	*envhandler.ENV.ADMIN_KEY = "admin-secret"
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() {
+		*envhandler.ENV.APIKEY_ENABLED = oldVal
+		*envhandler.ENV.ADMIN_KEY = oldAdmin
+		*envhandler.ENV.DB_FOLDER = oldFolder
+	}()
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	client := ts.Client()
+
+	dbName := "admindb"
+	_, body := doJSON(t, client, http.MethodPost, ts.URL+"/create", serverpkg.NewDB{Name: dbName})
+	var created serverpkg.NewDBCreated
+	if err := json.Unmarshal(body, &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	do := func(method, path, header, key string) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, nil)
+		req.Header.Set(header, key)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		defer resp.Body.Close()
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(resp.Body)
+		return resp, buf.Bytes()
+	}
+
+	// 1. the admin routes need the admin key
+	if resp, _ := do(http.MethodGet, "/admin/apikeys", "X-Admin-Key", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong admin key: expected 401, got %d", resp.StatusCode)
+	}
+
+	// 2. the list shows the metadata but never the key
+	do(http.MethodGet, "/db/"+dbName, "X-API-Key", created.ApiKey)
+	resp, body := do(http.MethodGet, "/admin/apikeys", "X-Admin-Key", "admin-secret")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("list: expected 200, got %d", resp.StatusCode)
+	}
+	if bytes.Contains(body, []byte(created.ApiKey)) {
+		t.Fatal("list contains the api key")
+	}
+	var list serverpkg.ApiKeyList
+	if err := json.Unmarshal(body, &list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	found := false
+	for _, db := range list.DBs {
+		if db.Name == utils.U.DbKey(dbName) {
+			found = true
+			if len(db.Keys) != 1 || db.Keys[0].Role != "write" || db.Keys[0].CreatedAt == nil || db.Keys[0].LastUsed == nil {
+				t.Fatalf("unexpected key metadata: %s", body)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("db missing in list: %s", body)
+	}
+
+	// 3. after a revoke the old key and its files are gone
+	if resp, _ := do(http.MethodDelete, "/admin/apikeys/"+dbName, "X-Admin-Key", "admin-secret"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke: expected 200, got %d", resp.StatusCode)
+	}
+	if resp, _ := do(http.MethodGet, "/db/"+dbName, "X-API-Key", created.ApiKey); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("revoked key: expected 401, got %d", resp.StatusCode)
+	}
+	for _, name := range []string{".ADMINDB.apikey", ".ADMINDB.meta"} {
+		if _, err := os.Stat(filepath.Join(*envhandler.ENV.DB_FOLDER, name)); !os.IsNotExist(err) {
+			t.Fatalf("%s not removed: %v", name, err)
+		}
+	}
+	if resp, _ := do(http.MethodDelete, "/admin/apikeys/"+dbName, "X-Admin-Key", "admin-secret"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("second revoke: expected 404, got %d", resp.StatusCode)
+	}
+
+	// 4. a new key can be issued
+	resp, body = do(http.MethodPost, "/admin/apikeys/"+dbName, "X-Admin-Key", "admin-secret")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("issue: expected 200, got %d", resp.StatusCode)
+	}
+	var issued serverpkg.NewDBCreated
+	if err := json.Unmarshal(body, &issued); err != nil || issued.ApiKey == "" {
+		t.Fatalf("decode issued key: %v", err)
+	}
+	if resp, _ := do(http.MethodGet, "/db/"+dbName, "X-API-Key", issued.ApiKey); resp.StatusCode != http.StatusOK {
+		t.Fatalf("issued key: expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPIKey_DeleteDBRemovesKeys(t *testing.T) {
+	oldVal, oldFolder := *envhandler.ENV.APIKEY_ENABLED, *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.APIKEY_ENABLED = true
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() {
+		*envhandler.ENV.APIKEY_ENABLED = oldVal
+		*envhandler.ENV.DB_FOLDER = oldFolder
+	}()
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	client := ts.Client()
+
+	do := func(method, path, key string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, nil)
+		req.Header.Set("X-API-Key", key)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		return resp
+	}
+
+	dbName := "deletekeydb"
+	create := func() serverpkg.NewDBCreated {
+		t.Helper()
+		resp, body := doJSON(t, client, http.MethodPost, ts.URL+"/create", serverpkg.NewDB{Name: dbName})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create: expected 201, got %d", resp.StatusCode)
+		}
+		var created serverpkg.NewDBCreated
+		if err := json.Unmarshal(body, &created); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return created
+	}
+
+	// 1. a DB with a write and a read key
+	old := create()
+	resp := do(http.MethodPost, "/db/"+dbName+"/readkey", old.ApiKey)
+	var oldRead serverpkg.ReadKeyCreated
+	if err := json.NewDecoder(resp.Body).Decode(&oldRead); err != nil {
+		t.Fatalf("decode read key: %v", err)
+	}
+	resp.Body.Close()
+
+	// 2. delete it - the key files are gone
+	resp = do(http.MethodDelete, "/db/"+dbName, old.ApiKey)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d", resp.StatusCode)
+	}
+	for _, name := range []string{".DELETEKEYDB.apikey", ".DELETEKEYDB.meta"} {
+		if _, err := os.Stat(filepath.Join(*envhandler.ENV.DB_FOLDER, name)); !os.IsNotExist(err) {
+			t.Fatalf("%s not removed: %v", name, err)
+		}
+	}
+
+	// 3. the recreated DB does not accept the old keys
+	created := create()
+	for _, key := range []string{old.ApiKey, oldRead.ReadKey} {
+		resp = do(http.MethodGet, "/db/"+dbName, key)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("old key: expected 401, got %d", resp.StatusCode)
+		}
+	}
+	resp = do(http.MethodGet, "/db/"+dbName, created.ApiKey)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("new key: expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPIKey_RestoreMalformedFiles(t *testing.T) {
+	oldFolder := *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() { *envhandler.ENV.DB_FOLDER = oldFolder }()
+
+	hash := sha256.Sum256([]byte("good-key"))
+	files := map[string][]byte{
+		".GOODDB.apikey":     hash[:],
+		".EMPTYDB.apikey":    {},
+		".LONGDB.apikey":     append(hash[:], 0x01),
+		".TRUNCDB.apikey":    hash[:31],
+		".GOODDB.meta":       []byte("{not json"),
+		".not.a.db.apikey":   hash[:],
+		"GOODDB.bin":         {},
+		".HKVKDB.apikey":     []byte("HKVK\x02"),
+		".BADVERSION.apikey": []byte("HKVK\x09"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(*envhandler.ENV.DB_FOLDER, name), data, 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	if err := utils.U.RestoreApiKeys(); err != nil {
+		t.Fatalf("RestoreApiKeys: %v", err)
+	}
+	if !utils.U.IsApiKeyValid("gooddb", "good-key") {
+		t.Fatal("valid key file not restored")
+	}
+	for _, db := range []string{"emptydb", "longdb", "truncdb", "hkvkdb", "badversion"} {
+		if utils.U.IsApiKeyValid(db, "good-key") {
+			t.Fatalf("malformed key file of %s restored", db)
+		}
+	}
+}
+
+func TestAPIKey_FileModes(t *testing.T) {
+	oldKeys, oldFolder := *envhandler.ENV.APIKEY_ENABLED, *envhandler.ENV.DB_FOLDER
+	oldFile, oldDir := *envhandler.ENV.FILE_MODE, *envhandler.ENV.DIR_MODE
+	*envhandler.ENV.APIKEY_ENABLED = true
+	*envhandler.ENV.DB_FOLDER = filepath.Join(t.TempDir(), "data")
+	*envhandler.ENV.FILE_MODE = "0640"
+	*envhandler.ENV.DIR_MODE = "0750"
+	defer func() {
+		*envhandler.ENV.APIKEY_ENABLED = oldKeys
+		*envhandler.ENV.DB_FOLDER = oldFolder
+		*envhandler.ENV.FILE_MODE = oldFile
+		*envhandler.ENV.DIR_MODE = oldDir
+	}()
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	if resp, body := doJSON(t, ts.Client(), http.MethodPost, ts.URL+"/create", serverpkg.NewDB{Name: "modedb"}); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d %s", resp.StatusCode, body)
+	}
+
+	mode := func(name string) os.FileMode {
+		t.Helper()
+		info, err := os.Stat(filepath.Join(*envhandler.ENV.DB_FOLDER, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Mode().Perm()
+	}
+	if got := mode(""); got != 0750 {
+		t.Fatalf("data folder: expected 0750, got %04o", got)
+	}
+	if got := mode("modedb.bin"); got != 0640 {
+		t.Fatalf("AOF: expected 0640, got %04o", got)
+	}
+	keyName := "." + utils.U.DbKey("modedb") + ".apikey"
+	if got := mode(keyName); got != 0600 {
+		t.Fatalf("api key file: expected 0600, got %04o", got)
+	}
+
+	metaName := "." + utils.U.DbKey("modedb") + ".meta"
+	if got := mode(metaName); got != 0600 {
+		t.Fatalf("api key meta file: expected 0600, got %04o", got)
+	}
+
+	// the files of an older version are restricted on start
+	for _, name := range []string{keyName, metaName} {
+		if err := os.Chmod(filepath.Join(*envhandler.ENV.DB_FOLDER, name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := utils.U.RestoreApiKeys(); err != nil {
+		t.Fatalf("RestoreApiKeys: %v", err)
+	}
+	for _, name := range []string{keyName, metaName} {
+		if got := mode(name); got != 0600 {
+			t.Fatalf("restored %s: expected 0600, got %04o", name, got)
+		}
+	}
+}
+
+func TestAPIKey_AdminFlushAll(t *testing.T) {
+	oldAdmin, oldFolder := *envhandler.ENV.ADMIN_KEY, *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.ADMIN_KEY = "admin-secret"
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() {
+		*envhandler.ENV.ADMIN_KEY = oldAdmin
+		*envhandler.ENV.DB_FOLDER = oldFolder
+	}()
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	client := ts.Client()
+
+	for _, name := range []string{"flushdb1", "flushdb2"} {
+		doJSON(t, client, http.MethodPost, ts.URL+"/create", serverpkg.NewDB{Name: name})
+		doJSON(t, client, http.MethodPut, ts.URL+"/db/"+name, serverpkg.Set{Key: "k", Value: "v"})
+	}
+
+	flush := func(query, key string) (*http.Response, serverpkg.FlushAll) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/admin/flushall"+query, nil)
+		req.Header.Set("X-Admin-Key", key)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		defer resp.Body.Close()
+		var res serverpkg.FlushAll
+		_ = json.NewDecoder(resp.Body).Decode(&res)
+		return resp, res
+	}
+
+	if resp, _ := flush("", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong admin key: expected 401, got %d", resp.StatusCode)
+	}
+	if resp, _ := flush("?drop=maybe", "admin-secret"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid drop: expected 400, got %d", resp.StatusCode)
+	}
+
+	// 1. a flush keeps the DBs but deletes their keys
+	if resp, res := flush("", "admin-secret"); resp.StatusCode != http.StatusOK || res.DBs != 2 || res.Dropped {
+		t.Fatalf("flushall: expected 2 flushed DBs, got %d %+v", resp.StatusCode, res)
+	}
+	if resp, _ := doJSON(t, client, http.MethodPost, ts.URL+"/db/flushdb1/keys", serverpkg.Key{Key: "k"}); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("flushed key: expected 404, got %d", resp.StatusCode)
+	}
+	if !s.DBExists("flushdb2") {
+		t.Fatal("flush deleted the DB")
+	}
+
+	// 2. drop deletes the DBs
+	doJSON(t, client, http.MethodPut, ts.URL+"/db/flushdb1", serverpkg.Set{Key: "k", Value: "v"})
+	if resp, res := flush("?drop=true", "admin-secret"); resp.StatusCode != http.StatusOK || res.DBs != 2 || !res.Dropped {
+		t.Fatalf("flushall drop: expected 2 dropped DBs, got %d %+v", resp.StatusCode, res)
+	}
+	if s.DBExists("flushdb1") || s.DBExists("flushdb2") {
+		t.Fatal("expected the DBs to be dropped")
+	}
+
+	// 3. a DB with the name of a dropped one starts empty - the AOF of the dropped one is gone
+	if err, _, created, _ := s.NewDB("flushdb1"); err != nil || !created {
+		t.Fatalf("recreate: expected a new DB, got %v %v", err, created)
+	}
+	if resp, _ := doJSON(t, client, http.MethodPost, ts.URL+"/db/flushdb1/keys", serverpkg.Key{Key: "k"}); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("recreated DB: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPIKey_AdminSync(t *testing.T) {
+	oldAdmin, oldFolder := *envhandler.ENV.ADMIN_KEY, *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.ADMIN_KEY = "admin-secret"
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() {
+		*envhandler.ENV.ADMIN_KEY = oldAdmin
+		*envhandler.ENV.DB_FOLDER = oldFolder
+	}()
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	client := ts.Client()
+
+	for _, name := range []string{"syncdb1", "syncdb2"} {
+		doJSON(t, client, http.MethodPost, ts.URL+"/create", serverpkg.NewDB{Name: name})
+		doJSON(t, client, http.MethodPut, ts.URL+"/db/"+name, serverpkg.Set{Key: "k", Value: "v"})
+	}
+
+	sync := func(key string) (*http.Response, serverpkg.Synced) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/admin/sync", nil)
+		req.Header.Set("X-Admin-Key", key)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		defer resp.Body.Close()
+		var res serverpkg.Synced
+		_ = json.NewDecoder(resp.Body).Decode(&res)
+		return resp, res
+	}
+
+	if resp, _ := sync("wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong admin key: expected 401, got %d", resp.StatusCode)
+	}
+	if resp, res := sync("admin-secret"); resp.StatusCode != http.StatusOK || res.DBs != 2 {
+		t.Fatalf("sync: expected 2 synced DBs, got %d %+v", resp.StatusCode, res)
+	}
+
+	// a single DB is synced by its own route
+	if resp, body := doJSON(t, client, http.MethodPost, ts.URL+"/db/syncdb1/sync", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("db sync: expected 200, got %d %s", resp.StatusCode, body)
+	}
+	if resp, _ := doJSON(t, client, http.MethodPost, ts.URL+"/db/nosuchdb/sync", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("db sync of a missing DB: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPIKey_AdminHotBaskets(t *testing.T) {
+	oldAdmin, oldFolder := *envhandler.ENV.ADMIN_KEY, *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.ADMIN_KEY = "admin-secret"
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() {
+		*envhandler.ENV.ADMIN_KEY = oldAdmin
+		*envhandler.ENV.DB_FOLDER = oldFolder
+	}()
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	client := ts.Client()
+
+	doJSON(t, client, http.MethodPost, ts.URL+"/create", serverpkg.NewDB{Name: "hotdb"})
+	for i := range 50 {
+		doJSON(t, client, http.MethodPut, ts.URL+"/db/hotdb", serverpkg.Set{Key: "k-" + strconv.Itoa(i), Value: "v"})
+	}
+
+	get := func(path, key string) (*http.Response, serverpkg.HotBaskets) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		req.Header.Set("X-Admin-Key", key)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		defer resp.Body.Close()
+		var res serverpkg.HotBaskets
+		_ = json.NewDecoder(resp.Body).Decode(&res)
+		return resp, res
+	}
+
+	if resp, _ := get("/admin/baskets/hotdb", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong admin key: expected 401, got %d", resp.StatusCode)
+	}
+	resp, res := get("/admin/baskets/hotdb?top=2", "admin-secret")
+	if resp.StatusCode != http.StatusOK || res.Entries != 50 || res.Baskets == 0 || len(res.Hot) != 2 ||
+		res.Hot[0].Entries < res.Hot[1].Entries || len(res.Hot[0].Keys) == 0 {
+		t.Fatalf("hot baskets: unexpected %d %+v", resp.StatusCode, res)
+	}
+	if resp, _ := get("/admin/baskets/hotdb?top=0", "admin-secret"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("top=0: expected 400, got %d", resp.StatusCode)
+	}
+	if resp, _ := get("/admin/baskets/nosuchdb", "admin-secret"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("missing DB: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPIKey_AdminSwap(t *testing.T) {
+	oldAdmin, oldFolder := *envhandler.ENV.ADMIN_KEY, *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.ADMIN_KEY = "admin-secret"
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() {
+		*envhandler.ENV.ADMIN_KEY = oldAdmin
+		*envhandler.ENV.DB_FOLDER = oldFolder
+	}()
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	client := ts.Client()
+
+	doJSON(t, client, http.MethodPost, ts.URL+"/create", serverpkg.NewDB{Name: "swapblue"})
+	doJSON(t, client, http.MethodPost, ts.URL+"/create", serverpkg.NewDB{Name: "swapgreen"})
+	doJSON(t, client, http.MethodPut, ts.URL+"/db/swapblue", serverpkg.Set{Key: "k", Value: "blue"})
+	doJSON(t, client, http.MethodPut, ts.URL+"/db/swapgreen", serverpkg.Set{Key: "k", Value: "green"})
+	doJSON(t, client, http.MethodPut, ts.URL+"/db/swapgreen", serverpkg.Set{Key: "warm", Value: "yes"})
+
+	swap := func(key string, payload serverpkg.Swap) *http.Response {
+		t.Helper()
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/admin/swap", bytes.NewReader(body))
+		req.Header.Set("X-Admin-Key", key)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	if resp := swap("wrong", serverpkg.Swap{A: "swapblue", B: "swapgreen"}); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong admin key: expected 401, got %d", resp.StatusCode)
+	}
+	if resp := swap("admin-secret", serverpkg.Swap{A: "swapblue", B: "swapblue"}); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("same DB: expected 400, got %d", resp.StatusCode)
+	}
+	if resp := swap("admin-secret", serverpkg.Swap{A: "swapblue", B: "swapmissing"}); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("missing DB: expected 404, got %d", resp.StatusCode)
+	}
+
+	// the requests see the other data at once, writes go to the swapped AOFs
+	if resp := swap("admin-secret", serverpkg.Swap{A: "swapblue", B: "swapgreen"}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("swap: expected 200, got %d", resp.StatusCode)
+	}
+	if ok, v := s.Get("swapblue", "k"); !ok || v != "green" {
+		t.Fatalf("swapblue after swap: got %q", v)
+	}
+	if ok, v := s.Get("swapgreen", "k"); !ok || v != "blue" {
+		t.Fatalf("swapgreen after swap: got %q", v)
+	}
+	doJSON(t, client, http.MethodPut, ts.URL+"/db/swapblue", serverpkg.Set{Key: "after", Value: "swap"})
+	s.CloseDbs()
+
+	if _, err := os.Stat(filepath.Join(*envhandler.ENV.DB_FOLDER, "swapblue.bin.swap")); !os.IsNotExist(err) {
+		t.Fatalf("swap file left behind: %v", err)
+	}
+
+	// a restart loads the swapped files
+	s = serverpkg.NewServer(0, "127.0.0.1")
+	if err := s.ReloadDb(); err != nil {
+		t.Fatalf("ReloadDb: %v", err)
+	}
+	s.WaitForDBs()
+	defer s.CloseDbs()
+	for _, c := range []struct{ db, key, want string }{
+		{"swapblue", "k", "green"}, {"swapblue", "warm", "yes"}, {"swapblue", "after", "swap"}, {"swapgreen", "k", "blue"},
+	} {
+		if ok, v := s.Get(c.db, c.key); !ok || v != c.want {
+			t.Fatalf("%s %s after restart: got %q want %q", c.db, c.key, v, c.want)
+		}
+	}
+	if ok, _ := s.Get("swapgreen", "warm"); ok {
+		t.Fatalf("swapgreen holds warm after restart")
+	}
+}
+
+func TestAPIKey_AdminConfig(t *testing.T) {
+	oldAdmin, oldGzip := *envhandler.ENV.ADMIN_KEY, *envhandler.ENV.GZIP_MIN_BYTES
+	t.Cleanup(func() {
+		*envhandler.ENV.ADMIN_KEY = oldAdmin
+		*envhandler.ENV.GZIP_MIN_BYTES = oldGzip
+	})
+	t.Setenv(envhandler.GZIP_MIN_BYTES, "4321")
+	envhandler.ENV.LoadENVs()
+	*envhandler.ENV.ADMIN_KEY = "admin-secret"
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	get := func(key string) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/admin/config", nil)
+		req.Header.Set("X-Admin-Key", key)
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	if resp, _ := get("wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong admin key: expected 401, got %d", resp.StatusCode)
+	}
+	resp, body := get("admin-secret")
+	var cfg serverpkg.Config
+	if err := json.Unmarshal(body, &cfg); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("config: got %d %s", resp.StatusCode, body)
+	}
+	settings := make(map[string]envhandler.Setting, len(cfg.Settings))
+	for _, setting := range cfg.Settings {
+		settings[setting.Name] = setting
+	}
+
+	// overrides are marked with their source, the admin key never leaves the server
+	if got := settings[envhandler.GZIP_MIN_BYTES]; got.Value != float64(4321) || got.Source != envhandler.SourceEnv {
+		t.Fatalf("%s: got %+v", envhandler.GZIP_MIN_BYTES, got)
+	}
+	if got := settings[envhandler.FSYNC]; got.Value != *envhandler.ENV.FSYNC || got.Source != envhandler.SourceDefault {
+		t.Fatalf("%s: got %+v", envhandler.FSYNC, got)
+	}
+	if got := settings[envhandler.ADMIN_KEY]; got.Value != "[redacted]" || strings.Contains(string(body), "admin-secret") {
+		t.Fatalf("admin key not redacted: %s", body)
+	}
+}
+
+func TestAPIKey_AdminBackupRestore(t *testing.T) {
+	oldVal, oldAdmin, oldFolder := *envhandler.ENV.APIKEY_ENABLED, *envhandler.ENV.ADMIN_KEY, *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.APIKEY_ENABLED = true
+	*envhandler.ENV.ADMIN_KEY = "admin-secret"
+	defer func() {
+		*envhandler.ENV.APIKEY_ENABLED = oldVal
+		*envhandler.ENV.ADMIN_KEY = oldAdmin
+		*envhandler.ENV.DB_FOLDER = oldFolder
+	}()
+
+	newServer := func() (*serverpkg.Server, *httptest.Server) {
+		*envhandler.ENV.DB_FOLDER = t.TempDir()
+		s := serverpkg.NewServer(0, "127.0.0.1")
+		ts := httptest.NewServer(s.Handler())
+		t.Cleanup(ts.Close)
+		return s, ts
+	}
+	do := func(ts *httptest.Server, method, path, header, key string, body []byte) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, bytes.NewReader(body))
+		req.Header.Set(header, key)
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		defer resp.Body.Close()
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(resp.Body)
+		return resp, buf.Bytes()
+	}
+	marshal := func(v any) []byte {
+		data, _ := json.Marshal(v)
+		return data
+	}
+
+	// 1. a source server with two DBs
+	_, src := newServer()
+	keys := map[string]string{}
+	for _, name := range []string{"backupdb1", "backupdb2"} {
+		_, body := doJSON(t, src.Client(), http.MethodPost, src.URL+"/create", serverpkg.NewDB{Name: name})
+		var created serverpkg.NewDBCreated
+		if err := json.Unmarshal(body, &created); err != nil || created.ApiKey == "" {
+			t.Fatalf("create %s: %s", name, body)
+		}
+		keys[name] = created.ApiKey
+		do(src, http.MethodPut, "/db/"+name, "X-API-Key", created.ApiKey, marshal(serverpkg.Set{Key: "k", Value: "v-" + name}))
+	}
+	do(src, http.MethodPut, "/db/backupdb1/hash", "X-API-Key", keys["backupdb1"], marshal(serverpkg.HashField{Key: "h", Field: "f", Value: "x"}))
+
+	if resp, _ := do(src, http.MethodGet, "/admin/backup", "X-Admin-Key", "wrong", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong admin key: expected 401, got %d", resp.StatusCode)
+	}
+	resp, archive := do(src, http.MethodGet, "/admin/backup", "X-Admin-Key", "admin-secret", nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-tar" {
+		t.Fatalf("backup: expected a tar, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// 2. the archive is restored into an empty server - the api keys stay valid
+	_, dst := newServer()
+	resp, body := do(dst, http.MethodPost, "/admin/restore", "X-Admin-Key", "admin-secret", archive)
+	var result serverpkg.RestoreResult
+	if err := json.Unmarshal(body, &result); err != nil || resp.StatusCode != http.StatusOK || len(result.Restored) != 2 {
+		t.Fatalf("restore: expected 2 restored DBs, got %d %s", resp.StatusCode, body)
+	}
+	for name, key := range keys {
+		resp, body := do(dst, http.MethodPost, "/db/"+name+"/keys", "X-API-Key", key, marshal(serverpkg.Key{Key: "k"}))
+		var v serverpkg.Value
+		if err := json.Unmarshal(body, &v); err != nil || resp.StatusCode != http.StatusOK || v.Value != "v-"+name {
+			t.Fatalf("restored %s: expected v-%s, got %d %s", name, name, resp.StatusCode, body)
+		}
+	}
+	resp, body = do(dst, http.MethodPost, "/db/backupdb1/hash/get", "X-API-Key", keys["backupdb1"], marshal(serverpkg.HashField{Key: "h", Field: "f"}))
+	if resp.StatusCode != http.StatusOK || !bytes.Contains(body, []byte(`"x"`)) {
+		t.Fatalf("restored hash: got %d %s", resp.StatusCode, body)
+	}
+
+	// 3. existing DBs are skipped, garbage is rejected
+	resp, body = do(dst, http.MethodPost, "/admin/restore", "X-Admin-Key", "admin-secret", archive)
+	if err := json.Unmarshal(body, &result); err != nil || resp.StatusCode != http.StatusOK || len(result.Skipped) != 2 || len(result.Restored) != 0 {
+		t.Fatalf("second restore: expected 2 skipped DBs, got %d %s", resp.StatusCode, body)
+	}
+	if resp, _ := do(dst, http.MethodPost, "/admin/restore", "X-Admin-Key", "admin-secret", []byte("no tar")); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid archive: expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPIKey_IndexPage(t *testing.T) {
+	oldVal, oldAdmin, oldIndex, oldFolder := *envhandler.ENV.APIKEY_ENABLED, *envhandler.ENV.ADMIN_KEY,
+		*envhandler.ENV.INDEX_PAGE, *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.APIKEY_ENABLED = true
+	*envhandler.ENV.ADMIN_KEY = "admin-secret"
+	*envhandler.ENV.INDEX_PAGE = true
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() {
+		*envhandler.ENV.APIKEY_ENABLED = oldVal
+		*envhandler.ENV.ADMIN_KEY = oldAdmin
+		*envhandler.ENV.INDEX_PAGE = oldIndex
+		*envhandler.ENV.DB_FOLDER = oldFolder
+	}()
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	client := ts.Client()
+	doJSON(t, client, http.MethodPost, ts.URL+"/create", serverpkg.NewDB{Name: "indexdb"})
+
+	index := func(adminKey string) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/", nil)
+		if adminKey != "" {
+			req.Header.Set("X-Admin-Key", adminKey)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("index: expected 200, got %d %s", resp.StatusCode, body)
+		}
+		return string(body)
+	}
+
+	// with API keys the DB names are only listed for the admin key
+	if body := index(""); strings.Contains(body, "INDEXDB") {
+		t.Fatalf("index without admin key lists the DBs: %s", body)
+	}
+	if body := index("wrong"); strings.Contains(body, "INDEXDB") {
+		t.Fatalf("index with a wrong admin key lists the DBs: %s", body)
+	}
+	if body := index("admin-secret"); !strings.Contains(body, "INDEXDB") {
+		t.Fatalf("index with admin key does not list the DBs: %s", body)
+	}
+
+	// the stats list the DBs just like the index
+	stats := func(adminKey string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/stats", nil)
+		req.Header.Set("X-Admin-Key", adminKey)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := stats("wrong"); code != http.StatusForbidden {
+		t.Fatalf("stats with a wrong admin key: expected 403, got %d", code)
+	}
+	if code := stats("admin-secret"); code != http.StatusOK {
+		t.Fatalf("stats with admin key: expected 200, got %d", code)
+	}
+
+	// without API keys HKV_INDEX_PAGE decides
+	*envhandler.ENV.APIKEY_ENABLED = false
+	if body := index(""); !strings.Contains(body, "INDEXDB") {
+		t.Fatalf("enabled index does not list the DBs: %s", body)
+	}
+	*envhandler.ENV.INDEX_PAGE = false
+	if body := index(""); strings.Contains(body, "INDEXDB") || !strings.Contains(body, "HydraKV") {
+		t.Fatalf("disabled index: expected the landing page, got %s", body)
+	}
+}
@@ -191,3 +191,46 @@ func TestAPI_BulkSetGetDeleteSetNX(t *testing.T) {
 		t.Fatalf("final check error: %s", e)
 	}
 }
+
+// TestAPI_SetNXRace lets many clients POST the same key at once - the existence check and the insert are one
+// atomic step, so exactly one of them may succeed.
+func TestAPI_SetNXRace(t *testing.T) {
+	_, client, base := newAPIServer(t)
+	doJSON(t, client, http.MethodPost, base+"/create", serverpkg.NewDB{Name: "nxracedb"})
+
+	for round := 0; round < 20; round++ {
+		key := "lock:" + strconv.Itoa(round)
+		doJSON(t, client, http.MethodDelete, base+"/db/nxracedb/keys", serverpkg.Key{Key: key})
+
+		const racers = 32
+		var wg sync.WaitGroup
+		statuses := make(chan int, racers)
+		start := make(chan struct{})
+		for i := 0; i < racers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				r, _ := doJSON(t, client, http.MethodPost, base+"/db/nxracedb", serverpkg.Set{Key: key, Value: "owner-" + strconv.Itoa(i), Ttl: 30})
+				statuses <- r.StatusCode
+			}()
+		}
+		close(start)
+		wg.Wait()
+		close(statuses)
+
+		wins := 0
+		for status := range statuses {
+			switch status {
+			case http.StatusOK:
+				wins++
+			case http.StatusConflict:
+			default:
+				t.Fatalf("round %d: unexpected status %d", round, status)
+			}
+		}
+		if wins != 1 {
+			t.Fatalf("round %d: expected exactly one SetNX to succeed, got %d", round, wins)
+		}
+	}
+}
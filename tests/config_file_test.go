@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"flag"
+	"hydrakv/envhandler"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	want := map[string]string{envhandler.PORT: "9191", envhandler.FSYNC: "always",
+		envhandler.APIKEY_ENABLED: "true", envhandler.AOF_HIGH_WATER: "0.75", envhandler.MAX_AOF_BYTES: "10000000000"}
+	files := []string{
+		write("hydrakv.json", `{"HKV_PORT": 9191, "HKV_FSYNC": "always", "HKV_APIKEY_ENABLED": true,
+			"HKV_AOF_HIGH_WATER": 0.75, "HKV_MAX_AOF_BYTES": 10000000000}`),
+		write("hydrakv.yaml", "HKV_PORT: 9191\nHKV_FSYNC: always\nHKV_APIKEY_ENABLED: true\n"+
+			"HKV_AOF_HIGH_WATER: 0.75\nHKV_MAX_AOF_BYTES: 10000000000\n"),
+	}
+	for _, file := range files {
+		values, err := envhandler.ReadConfigFile(file)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if len(values) != len(want) {
+			t.Fatalf("%s: got %v", file, values)
+		}
+		for key, val := range want {
+			if values[key] != val {
+				t.Fatalf("%s: %s is %q, want %q", file, key, values[key], val)
+			}
+		}
+	}
+
+	// typos, nested values and other formats are reported
+	for file, msg := range map[string]string{
+		write("typo.json", `{"HKV_PORT": 1, "HKV_PROT": 2, "PORT": 3}`): "unknown settings HKV_PROT, PORT",
+		write("nested.yml", "HKV_ALLOW_CIDRS:\n  - 10.0.0.0/8\n"):       "must be a string, number or bool",
+		write("self.json", `{"HKV_CONFIG_FILE": "other.json"}`):         "unknown settings HKV_CONFIG_FILE",
+		write("hydrakv.toml", "HKV_PORT = 1\n"):                         "unsupported config file format",
+		write("broken.json", `{"HKV_PORT": `):                           "broken.json: unexpected EOF",
+	} {
+		if _, err := envhandler.ReadConfigFile(file); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Fatalf("%s: expected error %q, got %v", file, msg, err)
+		}
+	}
+}
+
+func TestLoadENVs_ConfigFile(t *testing.T) {
+	// LoadENVs sets the values in place - only the changed ones are restored, the DBs of other tests still read the
+	// settings
+	maxOps, gzip, shutdown := *envhandler.ENV.BATCH_MAX_OPS, *envhandler.ENV.GZIP_MIN_BYTES,
+		*envhandler.ENV.SHUTDOWN_TIMEOUT
+	t.Cleanup(func() {
+		*envhandler.ENV.BATCH_MAX_OPS = maxOps
+		*envhandler.ENV.GZIP_MIN_BYTES = gzip
+		*envhandler.ENV.SHUTDOWN_TIMEOUT = shutdown
+		*envhandler.ENV.CONFIG_FILE = ""
+	})
+
+	file := filepath.Join(t.TempDir(), "hydrakv.json")
+	content := `{"HKV_BATCH_MAX_OPS": 7, "HKV_GZIP_MIN_BYTES": 5, "HKV_SHUTDOWN_TIMEOUT": 3}`
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// flags > env > file > defaults
+	t.Setenv(envhandler.CONFIG_FILE, file)
+	t.Setenv(envhandler.GZIP_MIN_BYTES, "9")
+	t.Setenv(envhandler.SHUTDOWN_TIMEOUT, "11")
+	if err := flag.Set(envhandler.SHUTDOWN_TIMEOUT, "13"); err != nil {
+		t.Fatal(err)
+	}
+	envhandler.ENV.LoadENVs()
+
+	if got := *envhandler.ENV.BATCH_MAX_OPS; got != 7 {
+		t.Fatalf("file value: got %d", got)
+	}
+	if got := *envhandler.ENV.GZIP_MIN_BYTES; got != 9 {
+		t.Fatalf("env over file: got %d", got)
+	}
+	if got := *envhandler.ENV.SHUTDOWN_TIMEOUT; got != 13 {
+		t.Fatalf("flag over env: got %d", got)
+	}
+}
+
+func TestLoadENVs_GRPCNames(t *testing.T) {
+	// only the changed settings are restored, the DBs of other tests still read the others
+	streams, port, multiplier := *envhandler.ENV.GRPC_MAX_CONCURRENT_STREAMS, *envhandler.ENV.GRPC_PORT,
+		*envhandler.ENV.CPU_MULTIPLIER
+	t.Cleanup(func() {
+		*envhandler.ENV.GRPC_MAX_CONCURRENT_STREAMS = streams
+		*envhandler.ENV.GRPC_PORT = port
+		*envhandler.ENV.CPU_MULTIPLIER = multiplier
+	})
+
+	// the gRPC settings and the CPU multiplier are read by their HKV_* names
+	t.Setenv(envhandler.GRPC_MAX_CONCURRENT_STREAMS, "17")
+	t.Setenv(envhandler.GRPC_PORT, "19393")
+	t.Setenv(envhandler.CPU_MULTIPLIER, "3")
+	envhandler.ENV.LoadENVs()
+	if envhandler.GRPC_MAX_CONCURRENT_STREAMS != "HKV_GRPC_MAX_CONCURRENT_STREAMS" ||
+		*envhandler.ENV.GRPC_MAX_CONCURRENT_STREAMS != 17 {
+		t.Fatalf("%s: got %d", envhandler.GRPC_MAX_CONCURRENT_STREAMS, *envhandler.ENV.GRPC_MAX_CONCURRENT_STREAMS)
+	}
+	if *envhandler.ENV.GRPC_PORT != 19393 || *envhandler.ENV.CPU_MULTIPLIER != 3 {
+		t.Fatalf("got port %d and multiplier %d", *envhandler.ENV.GRPC_PORT, *envhandler.ENV.CPU_MULTIPLIER)
+	}
+
+	// the former name without prefix is still honored, the new one wins
+	t.Setenv("GRPC_MAX_CONCURRENT_STREAMS", "23")
+	envhandler.ENV.LoadENVs()
+	if got := *envhandler.ENV.GRPC_MAX_CONCURRENT_STREAMS; got != 17 {
+		t.Fatalf("both names: got %d", got)
+	}
+	os.Unsetenv(envhandler.GRPC_MAX_CONCURRENT_STREAMS)
+	envhandler.ENV.LoadENVs()
+	if got := *envhandler.ENV.GRPC_MAX_CONCURRENT_STREAMS; got != 23 {
+		t.Fatalf("former name: got %d", got)
+	}
+}
@@ -3,10 +3,13 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
+	"hydrakv/envhandler"
 	"hydrakv/server"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestFifoLifoAPI(t *testing.T) {
@@ -192,4 +195,101 @@ func TestFifoLifoAPI(t *testing.T) {
 			t.Errorf("Expected status 500 for pop from empty queue, got %d", w.Code)
 		}
 	})
+	t.Run("Move between Queues", func(t *testing.T) {
+		for _, q := range []server.NewLiFoFifo{{Name: "movesrc", Limit: 10}, {Name: "movedst", Limit: 1}} {
+			bC, _ := json.Marshal(q)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, dbPrefix+"/fifolifo", bytes.NewReader(bC)))
+		}
+		for _, v := range []string{"job1", "job2"} {
+			bP, _ := json.Marshal(server.PushFiFoLiFo{Name: "movesrc", Value: v})
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, dbPrefix+"/fifolifo", bytes.NewReader(bP)))
+		}
+
+		move := func() *httptest.ResponseRecorder {
+			body, _ := json.Marshal(server.MoveFiFoLiFo{Src: "movesrc", Dst: "movedst"})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, dbPrefix+"/fifolifo/move", bytes.NewReader(body)))
+			return w
+		}
+
+		w := move()
+		var val string
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &val) != nil || val != "job1" {
+			t.Fatalf("Expected job1, got %d %s", w.Code, w.Body.String())
+		}
+
+		// the destination is full
+		if w = move(); w.Code != http.StatusConflict {
+			t.Errorf("Expected status 409 for a full destination, got %d", w.Code)
+		}
+	})
+	t.Run("Blocking Pop with wait", func(t *testing.T) {
+		bC, _ := json.Marshal(server.NewLiFoFifo{Name: "waitqueue", Limit: 10})
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, dbPrefix+"/fifolifo", bytes.NewReader(bC)))
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			bP, _ := json.Marshal(server.PushFiFoLiFo{Name: "waitqueue", Value: "late"})
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, dbPrefix+"/fifolifo", bytes.NewReader(bP)))
+		}()
+
+		body, _ := json.Marshal(server.PopFiFoLiFo{Name: "waitqueue"})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, dbPrefix+"/fifo?wait=5", bytes.NewReader(body)))
+		var val string
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &val) != nil || val != "late" {
+			t.Fatalf("Expected late, got %d %s", w.Code, w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, dbPrefix+"/fifo?wait=-1", bytes.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for a negative wait, got %d", w.Code)
+		}
+	})
+}
+
+func TestRequestLimiterWait(t *testing.T) {
+	limit, wait := *envhandler.ENV.REQ_LIMIT, *envhandler.ENV.REQUEST_WAIT_MS
+	t.Cleanup(func() {
+		*envhandler.ENV.REQ_LIMIT = limit
+		*envhandler.ENV.REQUEST_WAIT_MS = wait
+	})
+	*envhandler.ENV.REQ_LIMIT = 1
+
+	// a blocking pop holds the only slot for about 300ms
+	run := func(waitMs int) *httptest.ResponseRecorder {
+		*envhandler.ENV.REQUEST_WAIT_MS = waitMs
+		s := server.NewServer(0, "127.0.0.1")
+		handler := s.Handler()
+		s.NewDB("LIMITWAITDB")
+		bC, _ := json.Marshal(server.NewLiFoFifo{Name: "slowqueue", Limit: 10})
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/db/LIMITWAITDB/fifolifo", bytes.NewReader(bC)))
+
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			s.PushEntryFiFoLiFo("LIMITWAITDB", "slowqueue", "done")
+		}()
+		holding := make(chan struct{})
+		go func() {
+			defer close(holding)
+			body, _ := json.Marshal(server.PopFiFoLiFo{Name: "slowqueue"})
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/db/LIMITWAITDB/fifo?wait=5", bytes.NewReader(body)))
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/db/LIMITWAITDB", nil))
+		<-holding
+		return w
+	}
+
+	if w := run(0); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 without a wait, got %d", w.Code)
+	} else if secs, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || secs < 1 {
+		t.Errorf("Expected a Retry-After of at least 1 second, got %q", w.Header().Get("Retry-After"))
+	}
+	if w := run(2000); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after waiting for the slot, got %d", w.Code)
+	}
 }
@@ -3,14 +3,20 @@ package tests
 import (
 	"context"
 	"fmt"
+	"hydrakv/envhandler"
 	"hydrakv/server"
 	"hydrakv/server/hydrakv/proto/kvpb"
 	"net"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func setupFiFoLiFoGRPC(t *testing.T) (kvpb.KVServiceClient, *server.Server, func()) {
@@ -117,3 +123,145 @@ func TestFiFoLiFoGRPC(t *testing.T) {
 		t.Fatalf("FiFoLiFoPush should have failed for deleted queue")
 	}
 }
+
+func TestFiFoLiFoGRPC_Move(t *testing.T) {
+	client, s, cleanup := setupFiFoLiFoGRPC(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbName := "GRPCMOVEDB"
+	s.NewDB(dbName)
+	_ = s.AddFifoLifo(dbName, "jobs", 10)
+	_ = s.AddFifoLifo(dbName, "processing", 10)
+	_, _ = client.FiFoLiFoPush(ctx, &kvpb.FiFoLiFoPushRequest{Db: dbName, Name: "jobs", Value: "job1"})
+
+	resp, err := client.FiFoLiFoMove(ctx, &kvpb.FiFoLiFoMoveRequest{Db: dbName, Src: "jobs", Dst: "processing"})
+	if err != nil || resp.Value != "job1" {
+		t.Fatalf("FiFoLiFoMove: expected job1, got %v (err=%v)", resp, err)
+	}
+	popResp, err := client.FiFoLiFoFPop(ctx, &kvpb.FiFoLiFoPopRequest{Db: dbName, Name: "processing"})
+	if err != nil || popResp.Value != "job1" {
+		t.Fatalf("FiFoLiFoFPop: expected job1 in processing, got %v (err=%v)", popResp, err)
+	}
+	if _, err := client.FiFoLiFoMove(ctx, &kvpb.FiFoLiFoMoveRequest{Db: dbName, Src: "jobs", Dst: "processing"}); err == nil {
+		t.Fatal("FiFoLiFoMove from an empty queue should fail")
+	}
+}
+
+func TestFiFoLiFoGRPC_BPop(t *testing.T) {
+	client, s, cleanup := setupFiFoLiFoGRPC(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbName := "GRPCBPOPDB"
+	s.NewDB(dbName)
+	_ = s.AddFifoLifo(dbName, "jobs", 10)
+
+	// an empty queue times out
+	_, err := client.FiFoLiFoBPop(ctx, &kvpb.FiFoLiFoBPopRequest{Db: dbName, Name: "jobs", TimeoutMs: 50})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("FiFoLiFoBPop: expected FailedPrecondition, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = client.FiFoLiFoPush(ctx, &kvpb.FiFoLiFoPushRequest{Db: dbName, Name: "jobs", Value: "job1"})
+	}()
+	resp, err := client.FiFoLiFoBPop(ctx, &kvpb.FiFoLiFoBPopRequest{Db: dbName, Name: "jobs"})
+	if err != nil || resp.Value != "job1" {
+		t.Fatalf("FiFoLiFoBPop: expected job1, got %v (err=%v)", resp, err)
+	}
+}
+
+func TestFiFoLiFoGRPC_StatusCodes(t *testing.T) {
+	client, s, cleanup := setupFiFoLiFoGRPC(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbName := "GRPCFIFOCODESDB"
+	s.NewDB(dbName)
+	_ = s.AddFifoLifo(dbName, "jobs", 1)
+	_ = s.AddFifoLifo(dbName, "done", 1)
+
+	check := func(name string, err error, code codes.Code, queue string) {
+		t.Helper()
+		if status.Code(err) != code {
+			t.Fatalf("%s: expected %v, got %v", name, code, err)
+		}
+		if !strings.Contains(status.Convert(err).Message(), queue) {
+			t.Fatalf("%s: expected the queue %s in %q", name, queue, status.Convert(err).Message())
+		}
+	}
+
+	_, err := client.FiFoLiFoFPop(ctx, &kvpb.FiFoLiFoPopRequest{Db: dbName, Name: "jobs"})
+	check("FPop empty", err, codes.FailedPrecondition, "jobs")
+	_, err = client.FiFoLiFoLPop(ctx, &kvpb.FiFoLiFoPopRequest{Db: dbName, Name: "missing"})
+	check("LPop missing", err, codes.NotFound, "missing")
+	_, err = client.FiFoLiFoPush(ctx, &kvpb.FiFoLiFoPushRequest{Db: dbName, Name: "missing", Value: "job"})
+	check("Push missing", err, codes.NotFound, "missing")
+	_, err = client.FiFoLiFoPush(ctx, &kvpb.FiFoLiFoPushRequest{Db: dbName, Name: "jobs"})
+	check("Push empty entry", err, codes.InvalidArgument, "jobs")
+
+	if _, err := client.FiFoLiFoPush(ctx, &kvpb.FiFoLiFoPushRequest{Db: dbName, Name: "jobs", Value: "job1"}); err != nil {
+		t.Fatalf("FiFoLiFoPush: %v", err)
+	}
+	_, err = client.FiFoLiFoPush(ctx, &kvpb.FiFoLiFoPushRequest{Db: dbName, Name: "jobs", Value: "job2"})
+	check("Push full", err, codes.ResourceExhausted, "jobs")
+
+	_, err = client.FiFoLiFoMove(ctx, &kvpb.FiFoLiFoMoveRequest{Db: dbName, Src: "jobs", Dst: "missing"})
+	check("Move missing dst", err, codes.NotFound, "missing")
+	if _, err := client.FiFoLiFoPush(ctx, &kvpb.FiFoLiFoPushRequest{Db: dbName, Name: "done", Value: "job0"}); err != nil {
+		t.Fatalf("FiFoLiFoPush: %v", err)
+	}
+	_, err = client.FiFoLiFoMove(ctx, &kvpb.FiFoLiFoMoveRequest{Db: dbName, Src: "jobs", Dst: "done"})
+	check("Move full dst", err, codes.ResourceExhausted, "done")
+
+	_, err = client.FiFoLiFoDelete(ctx, &kvpb.FiFoLiFoDeleteRequest{Db: dbName, Name: "missing"})
+	check("Delete missing", err, codes.NotFound, "missing")
+	_, err = client.FiFoLiFoFPop(ctx, &kvpb.FiFoLiFoPopRequest{Db: "NOSUCHDB", Name: "jobs"})
+	check("FPop missing db", err, codes.NotFound, "db_not_found")
+}
+
+func TestFiFoLiFoGRPC_RetryAfter(t *testing.T) {
+	limit := *envhandler.ENV.GRPC_REQ_LIMIT
+	t.Cleanup(func() { *envhandler.ENV.GRPC_REQ_LIMIT = limit })
+	*envhandler.ENV.GRPC_REQ_LIMIT = 1
+
+	client, s, cleanup := setupFiFoLiFoGRPC(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbName := "GRPCRETRYDB"
+	s.NewDB(dbName)
+	_ = s.AddFifoLifo(dbName, "jobs", 10)
+
+	// a blocking pop holds the only slot
+	holding := make(chan struct{})
+	go func() {
+		defer close(holding)
+		_, _ = client.FiFoLiFoBPop(ctx, &kvpb.FiFoLiFoBPopRequest{Db: dbName, Name: "jobs", TimeoutMs: 300})
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	var trailer metadata.MD
+	_, err := client.FiFoLiFoFPop(ctx, &kvpb.FiFoLiFoPopRequest{Db: dbName, Name: "jobs"}, grpc.Trailer(&trailer))
+	<-holding
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+	retry := trailer.Get("retry-after")
+	if len(retry) != 1 {
+		t.Fatalf("expected a retry-after trailer, got %v", trailer)
+	}
+	if secs, err := strconv.Atoi(retry[0]); err != nil || secs < 1 {
+		t.Fatalf("invalid retry-after %q", retry[0])
+	}
+}
@@ -1,8 +1,12 @@
 package tests
 
 import (
+	"context"
+	"errors"
 	"hydrakv/fifolifo"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestFifoLifo(t *testing.T) {
@@ -102,4 +106,95 @@ func TestFifoLifo(t *testing.T) {
 			t.Errorf("Expected empty queue, got %d", q.Len())
 		}
 	})
+	t.Run("Move", func(t *testing.T) {
+		src, _ := fifolifo.NewFiFoLiFo("move-src", 10)
+		dst, _ := fifolifo.NewFiFoLiFo("move-dst", 1)
+
+		src.Push("A")
+		src.Push("B")
+
+		// the oldest entry is moved to the tail of dst
+		val, err := fifolifo.Move(src, dst)
+		if err != nil || val != "A" {
+			t.Fatalf("Expected A, got %s (err=%v)", val, err)
+		}
+
+		// a full dst keeps the entry in src
+		if _, err := fifolifo.Move(src, dst); !errors.Is(err, fifolifo.ErrFull) {
+			t.Fatalf("Expected ErrFull, got %v", err)
+		}
+		if src.Len() != 1 || dst.Len() != 1 {
+			t.Fatalf("Expected 1/1 entries, got %d/%d", src.Len(), dst.Len())
+		}
+
+		dst.FPop()
+		src.FPop()
+		if _, err := fifolifo.Move(src, dst); !errors.Is(err, fifolifo.ErrEmpty) {
+			t.Fatalf("Expected ErrEmpty, got %v", err)
+		}
+	})
+
+	t.Run("Concurrent Moves", func(t *testing.T) {
+		a, _ := fifolifo.NewFiFoLiFo("conc-a", 1000)
+		b, _ := fifolifo.NewFiFoLiFo("conc-b", 1000)
+		for i := 0; i < 100; i++ {
+			a.Push("a")
+			b.Push("b")
+		}
+
+		// moves in both directions must neither deadlock nor lose entries
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 500; j++ {
+					fifolifo.Move(a, b)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 500; j++ {
+					fifolifo.Move(b, a)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if a.Len()+b.Len() != 200 {
+			t.Fatalf("Expected 200 entries, got %d", a.Len()+b.Len())
+		}
+	})
+	t.Run("Blocking Pop", func(t *testing.T) {
+		q, _ := fifolifo.NewFiFoLiFo("test-bpop", 10)
+
+		// waiters are served in the order they started waiting
+		results := []chan string{make(chan string, 1), make(chan string, 1)}
+		for _, res := range results {
+			go func() {
+				val, _ := q.BFPop(context.Background())
+				res <- val
+			}()
+			time.Sleep(20 * time.Millisecond)
+		}
+		q.Push("first")
+		q.Push("second")
+		if got := <-results[0]; got != "first" {
+			t.Errorf("Expected first waiter to get first, got %s", got)
+		}
+		if got := <-results[1]; got != "second" {
+			t.Errorf("Expected second waiter to get second, got %s", got)
+		}
+
+		// a timed out waiter is removed, so the next push stays in the queue
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := q.BFPop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected DeadlineExceeded, got %v", err)
+		}
+		q.Push("kept")
+		if q.Len() != 1 {
+			t.Errorf("Expected the push to stay in the queue, got length %d", q.Len())
+		}
+	})
 }
@@ -71,8 +71,19 @@ func setupFullServer(t *testing.T) (*server.Server, string, kvpb.KVServiceClient
 		s.CloseDbs()
 	}
 
-	// Wait a bit for servers to start
-	time.Sleep(100 * time.Millisecond)
+	// Wait for the REST server - it listens after the DBs of the data folder are registered
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", restPort), 100*time.Millisecond)
+		if err == nil {
+			c.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("REST server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
 
 	return s, restBase, client, cleanup
 }
@@ -9,11 +9,14 @@ import (
 	"testing"
 	"time"
 
+	"hydrakv/envhandler"
 	"hydrakv/server"
 	"hydrakv/server/hydrakv/proto/kvpb"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 func newGRPCServer(t *testing.T) (kvpb.KVServiceClient, func()) {
@@ -180,6 +183,290 @@ func TestGRPC_SequentialCRUD(t *testing.T) {
 	}
 }
 
+func TestGRPC_IdempotencyKey(t *testing.T) {
+	client, cleanup := newGRPCServer(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbName := "grpcidemdb"
+	if _, err := client.CreateDB(ctx, &kvpb.CreateDBRequest{Name: dbName}); err != nil {
+		t.Fatalf("CreateDB failed: %v", err)
+	}
+	_, _ = client.Delete(ctx, &kvpb.DeleteRequest{Db: dbName, Key: "hits"})
+
+	// a retry with the same idempotency key returns the first result
+	req := &kvpb.CounterRequest{Db: dbName, Key: "hits", Amount: 3, IdempotencyKey: "retry-1"}
+	for i := 0; i < 2; i++ {
+		resp, err := client.CounterIncr(ctx, req)
+		if err != nil {
+			t.Fatalf("CounterIncr failed: %v", err)
+		}
+		if !resp.Ok || resp.Value != 3 {
+			t.Fatalf("CounterIncr expected 3, got %d (ok=%v)", resp.Value, resp.Ok)
+		}
+	}
+
+	getResp, _ := client.Get(ctx, &kvpb.GetRequest{Db: dbName, Key: "hits"})
+	if getResp.Value != "3" {
+		t.Fatalf("expected the counter to be incremented once, got %s", getResp.Value)
+	}
+}
+
+func TestGRPC_SetIfGreaterLess(t *testing.T) {
+	client, cleanup := newGRPCServer(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbName := "grpcsetifdb"
+	if _, err := client.CreateDB(ctx, &kvpb.CreateDBRequest{Name: dbName}); err != nil {
+		t.Fatalf("CreateDB failed: %v", err)
+	}
+	_, _ = client.Delete(ctx, &kvpb.DeleteRequest{Db: dbName, Key: "hwm"})
+
+	for _, c := range []struct {
+		less  bool
+		value string
+		ok    bool
+	}{{false, "10", true}, {false, "7", false}, {false, "12", true}, {true, "20", false}, {true, "3", true}} {
+		req := &kvpb.SetRequest{Db: dbName, Key: "hwm", Value: c.value}
+		var resp *kvpb.OKResponse
+		var err error
+		if c.less {
+			resp, err = client.SetIfLess(ctx, req)
+		} else {
+			resp, err = client.SetIfGreater(ctx, req)
+		}
+		if err != nil || resp.Ok != c.ok {
+			t.Fatalf("value %s (less=%v): expected ok=%v, got %v (err=%v)", c.value, c.less, c.ok, resp.GetOk(), err)
+		}
+	}
+
+	getResp, _ := client.Get(ctx, &kvpb.GetRequest{Db: dbName, Key: "hwm"})
+	if getResp.Value != "3" {
+		t.Fatalf("expected 3, got %s", getResp.Value)
+	}
+}
+
+func TestGRPC_IncrAndCheck(t *testing.T) {
+	client, cleanup := newGRPCServer(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbName := "grpcratedb"
+	if _, err := client.CreateDB(ctx, &kvpb.CreateDBRequest{Name: dbName}); err != nil {
+		t.Fatalf("CreateDB failed: %v", err)
+	}
+	_, _ = client.Delete(ctx, &kvpb.DeleteRequest{Db: dbName, Key: "calls"})
+
+	for i, allowed := range []bool{true, true, false} {
+		resp, err := client.IncrAndCheck(ctx, &kvpb.IncrAndCheckRequest{Db: dbName, Key: "calls", Limit: 2, Ttl: 60})
+		if err != nil || resp.Allowed != allowed || resp.Count != min(int64(i+1), 2) {
+			t.Fatalf("call %d: expected allowed=%v, got %v (err=%v)", i, allowed, resp, err)
+		}
+	}
+	if _, err := client.IncrAndCheck(ctx, &kvpb.IncrAndCheckRequest{Db: dbName, Key: "calls"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("limit 0: expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestGRPC_GetNotFound(t *testing.T) {
+	client, cleanup := newGRPCServer(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.CreateDB(ctx, &kvpb.CreateDBRequest{Name: "grpcmissdb"}); err != nil {
+		t.Fatalf("CreateDB failed: %v", err)
+	}
+	resp, err := client.Get(ctx, &kvpb.GetRequest{Db: "grpcmissdb", Key: "missing"})
+	if err != nil || resp.Found {
+		t.Fatalf("missing key: expected found=false, got %v (err=%v)", resp, err)
+	}
+	if _, err := client.Get(ctx, &kvpb.GetRequest{Db: "grpcnosuchdb", Key: "k"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("missing db: expected NotFound, got %v", err)
+	}
+}
+
+func TestGRPC_FlushAll(t *testing.T) {
+	oldAdmin, oldFolder := *envhandler.ENV.ADMIN_KEY, *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.ADMIN_KEY = "admin-secret"
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() {
+		*envhandler.ENV.ADMIN_KEY = oldAdmin
+		*envhandler.ENV.DB_FOLDER = oldFolder
+	}()
+
+	client, cleanup := newGRPCServer(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.CreateDB(ctx, &kvpb.CreateDBRequest{Name: "grpcflushdb"}); err != nil {
+		t.Fatalf("CreateDB failed: %v", err)
+	}
+	_, _ = client.Set(ctx, &kvpb.SetRequest{Db: "grpcflushdb", Key: "k", Value: "v"})
+
+	if _, err := client.FlushAll(ctx, &kvpb.FlushAllRequest{AdminKey: "wrong"}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("wrong admin key: expected Unauthenticated, got %v", err)
+	}
+	resp, err := client.FlushAll(ctx, &kvpb.FlushAllRequest{AdminKey: "admin-secret"})
+	if err != nil || resp.Dbs != 1 {
+		t.Fatalf("FlushAll: expected 1 DB, got %v (err=%v)", resp, err)
+	}
+	if getResp, _ := client.Get(ctx, &kvpb.GetRequest{Db: "grpcflushdb", Key: "k"}); getResp.GetFound() {
+		t.Fatal("expected the key to be flushed")
+	}
+}
+
+func TestGRPC_Hash(t *testing.T) {
+	client, cleanup := newGRPCServer(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbName := "grpchashdb"
+	if _, err := client.CreateDB(ctx, &kvpb.CreateDBRequest{Name: dbName}); err != nil {
+		t.Fatalf("CreateDB failed: %v", err)
+	}
+	_, _ = client.Delete(ctx, &kvpb.DeleteRequest{Db: dbName, Key: "user"})
+
+	for _, field := range []string{"name", "tmp"} {
+		resp, err := client.HSet(ctx, &kvpb.HSetRequest{Db: dbName, Key: "user", Field: field, Value: "hydra"})
+		if err != nil || !resp.Ok {
+			t.Fatalf("HSet %s failed: %v", field, err)
+		}
+	}
+	if resp, err := client.HDel(ctx, &kvpb.HDelRequest{Db: dbName, Key: "user", Field: "tmp"}); err != nil || !resp.Ok {
+		t.Fatalf("HDel failed: %v", err)
+	}
+
+	getResp, err := client.HGet(ctx, &kvpb.HGetRequest{Db: dbName, Key: "user", Field: "name"})
+	if err != nil || !getResp.Found || getResp.Value != "hydra" {
+		t.Fatalf("HGet: expected hydra, got %v (err=%v)", getResp, err)
+	}
+	allResp, err := client.HGetAll(ctx, &kvpb.HGetAllRequest{Db: dbName, Key: "user"})
+	if err != nil || !allResp.Found || len(allResp.Fields) != 1 || allResp.Fields["name"] != "hydra" {
+		t.Fatalf("HGetAll: expected {name: hydra}, got %v (err=%v)", allResp, err)
+	}
+}
+
+func TestGRPC_SetType(t *testing.T) {
+	client, cleanup := newGRPCServer(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbName := "grpcsetdb"
+	if _, err := client.CreateDB(ctx, &kvpb.CreateDBRequest{Name: dbName}); err != nil {
+		t.Fatalf("CreateDB failed: %v", err)
+	}
+	_, _ = client.Delete(ctx, &kvpb.DeleteRequest{Db: dbName, Key: "seen"})
+
+	for _, c := range []struct {
+		member string
+		ok     bool
+	}{{"a", true}, {"b", true}, {"a", false}} {
+		resp, err := client.SAdd(ctx, &kvpb.SMemberRequest{Db: dbName, Key: "seen", Member: c.member})
+		if err != nil || resp.Ok != c.ok {
+			t.Fatalf("SAdd %s: expected ok=%v, got %v (err=%v)", c.member, c.ok, resp.GetOk(), err)
+		}
+	}
+	if resp, err := client.SRem(ctx, &kvpb.SMemberRequest{Db: dbName, Key: "seen", Member: "b"}); err != nil || !resp.Ok {
+		t.Fatalf("SRem failed: %v", err)
+	}
+	if resp, err := client.SIsMember(ctx, &kvpb.SMemberRequest{Db: dbName, Key: "seen", Member: "a"}); err != nil || !resp.Ok {
+		t.Fatalf("SIsMember: expected a to be a member (err=%v)", err)
+	}
+
+	membersResp, err := client.SMembers(ctx, &kvpb.SKeyRequest{Db: dbName, Key: "seen"})
+	if err != nil || !membersResp.Found || len(membersResp.Members) != 1 || membersResp.Members[0] != "a" {
+		t.Fatalf("SMembers: expected [a], got %v (err=%v)", membersResp, err)
+	}
+	cardResp, err := client.SCard(ctx, &kvpb.SKeyRequest{Db: dbName, Key: "seen"})
+	if err != nil || cardResp.Card != 1 {
+		t.Fatalf("SCard: expected 1, got %v (err=%v)", cardResp, err)
+	}
+}
+
+func TestGRPC_ZSet(t *testing.T) {
+	client, cleanup := newGRPCServer(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbName := "grpczsetdb"
+	if _, err := client.CreateDB(ctx, &kvpb.CreateDBRequest{Name: dbName}); err != nil {
+		t.Fatalf("CreateDB failed: %v", err)
+	}
+	_, _ = client.Delete(ctx, &kvpb.DeleteRequest{Db: dbName, Key: "board"})
+
+	for member, score := range map[string]float64{"alice": 30, "bob": 10, "carol": 10} {
+		resp, err := client.ZAdd(ctx, &kvpb.ZAddRequest{Db: dbName, Key: "board", Member: member, Score: score})
+		if err != nil || !resp.Ok {
+			t.Fatalf("ZAdd %s failed: %v", member, err)
+		}
+	}
+
+	rangeResp, err := client.ZRange(ctx, &kvpb.ZRangeRequest{Db: dbName, Key: "board", Start: 0, Stop: 1})
+	if err != nil || !rangeResp.Found || len(rangeResp.Members) != 2 || rangeResp.Members[0].Member != "bob" || rangeResp.Members[1].Member != "carol" {
+		t.Fatalf("ZRange: expected [bob carol], got %v (err=%v)", rangeResp, err)
+	}
+	rankResp, err := client.ZRank(ctx, &kvpb.ZMemberRequest{Db: dbName, Key: "board", Member: "alice"})
+	if err != nil || !rankResp.Found || rankResp.Rank != 2 {
+		t.Fatalf("ZRank: expected 2, got %v (err=%v)", rankResp, err)
+	}
+	scoreResp, err := client.ZScore(ctx, &kvpb.ZMemberRequest{Db: dbName, Key: "board", Member: "carol"})
+	if err != nil || !scoreResp.Found || scoreResp.Score != 10 {
+		t.Fatalf("ZScore: expected 10, got %v (err=%v)", scoreResp, err)
+	}
+}
+
+func TestGRPC_List(t *testing.T) {
+	client, cleanup := newGRPCServer(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbName := "grpclistdb"
+	if _, err := client.CreateDB(ctx, &kvpb.CreateDBRequest{Name: dbName}); err != nil {
+		t.Fatalf("CreateDB failed: %v", err)
+	}
+	_, _ = client.Delete(ctx, &kvpb.DeleteRequest{Db: dbName, Key: "jobs"})
+
+	for _, v := range []string{"b", "c"} {
+		if resp, err := client.RPush(ctx, &kvpb.ListPushRequest{Db: dbName, Key: "jobs", Value: v}); err != nil || !resp.Ok {
+			t.Fatalf("RPush %s failed: %v", v, err)
+		}
+	}
+	if resp, err := client.LPush(ctx, &kvpb.ListPushRequest{Db: dbName, Key: "jobs", Value: "a"}); err != nil || !resp.Ok {
+		t.Fatalf("LPush failed: %v", err)
+	}
+	if resp, err := client.LPop(ctx, &kvpb.ListKeyRequest{Db: dbName, Key: "jobs"}); err != nil || !resp.Found || resp.Value != "a" {
+		t.Fatalf("LPop: expected a, got %v (err=%v)", resp, err)
+	}
+
+	rangeResp, err := client.LRange(ctx, &kvpb.ListRangeRequest{Db: dbName, Key: "jobs", Start: 0, Stop: -1})
+	if err != nil || !rangeResp.Found || len(rangeResp.Values) != 2 || rangeResp.Values[0] != "b" || rangeResp.Values[1] != "c" {
+		t.Fatalf("LRange: expected [b c], got %v (err=%v)", rangeResp, err)
+	}
+	lenResp, err := client.LLen(ctx, &kvpb.ListKeyRequest{Db: dbName, Key: "jobs"})
+	if err != nil || lenResp.Len != 2 {
+		t.Fatalf("LLen: expected 2, got %v (err=%v)", lenResp, err)
+	}
+}
+
 func BenchmarkGRPC_RPS(b *testing.B) {
 	// Silence logs during benchmark
 	log.SetOutput(io.Discard)
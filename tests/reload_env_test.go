@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"hydrakv/envhandler"
+	"hydrakv/server"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReloadENVs(t *testing.T) {
+	// the settings are set and restored by a reload - it swaps them under the reload lock, the DBs of other tests
+	// still read the settings
+	limit, wait := envhandler.ENV.ReqLimit(), envhandler.ENV.RequestWaitMs()
+	t.Cleanup(func() {
+		envhandler.ENV.ReloadENVs(map[string]string{envhandler.REQ_LIMIT: strconv.Itoa(limit),
+			envhandler.REQUEST_WAIT_MS: strconv.Itoa(wait)})
+	})
+	envhandler.ENV.ReloadENVs(map[string]string{envhandler.REQ_LIMIT: "1", envhandler.REQUEST_WAIT_MS: "0"})
+
+	s := server.NewServer(0, "127.0.0.1")
+	handler := s.Handler()
+	s.NewDB("RELOADENVDB")
+	bC, _ := json.Marshal(server.NewLiFoFifo{Name: "slowqueue", Limit: 10})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/db/RELOADENVDB/fifolifo", bytes.NewReader(bC)))
+
+	// a blocking pop holds the only slot until we push
+	holding := make(chan struct{})
+	go func() {
+		defer close(holding)
+		body, _ := json.Marshal(server.PopFiFoLiFo{Name: "slowqueue"})
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/db/RELOADENVDB/fifo?wait=5", bytes.NewReader(body)))
+	}()
+	defer func() {
+		s.PushEntryFiFoLiFo("RELOADENVDB", "slowqueue", "done")
+		<-holding
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	get := func() int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/db/RELOADENVDB", nil))
+		return w.Code
+	}
+	if code := get(); code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429 before the reload, got %d", code)
+	}
+
+	// raise the limit, break the fsync policy and try a setting which needs a restart
+	file := filepath.Join(t.TempDir(), "hydrakv.env")
+	content := "# hot reload\n" +
+		envhandler.REQ_LIMIT + "=2\n\n" +
+		envhandler.FSYNC + "=sometimes\n" +
+		envhandler.PORT + "=1234\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	values, err := envhandler.ReadEnvFile(file)
+	if err != nil {
+		t.Fatalf("ReadEnvFile: %v", err)
+	}
+
+	fsync, port := envhandler.ENV.Fsync(), *envhandler.ENV.PORT
+	changed := envhandler.ENV.ReloadENVs(values)
+	if !slices.Equal(changed, []string{envhandler.REQ_LIMIT}) {
+		t.Fatalf("Expected only %s to change, got %v", envhandler.REQ_LIMIT, changed)
+	}
+	if envhandler.ENV.Fsync() != fsync || *envhandler.ENV.PORT != port {
+		t.Fatalf("invalid or not reloadable settings were applied")
+	}
+
+	s.Reload()
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("Expected status 200 after the reload, got %d", code)
+	}
+
+	// broken lines are reported
+	if err := os.WriteFile(file, []byte("HKV_FSYNC always\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := envhandler.ReadEnvFile(file); err == nil {
+		t.Fatalf("Expected an error for a line without '='")
+	}
+}
+
+func TestAdmin_RateLimit(t *testing.T) {
+	limit, grpcLimit, wait, admin := envhandler.ENV.ReqLimit(), envhandler.ENV.GRPCReqLimit(),
+		envhandler.ENV.RequestWaitMs(), *envhandler.ENV.ADMIN_KEY
+	t.Cleanup(func() {
+		// the changed settings are restored under the reload lock - a restart drops the overrides
+		envhandler.ENV.Override(map[string]string{envhandler.REQ_LIMIT: strconv.Itoa(limit),
+			envhandler.GRPC_REQ_LIMIT: strconv.Itoa(grpcLimit), envhandler.REQUEST_WAIT_MS: strconv.Itoa(wait)})
+		envhandler.ENV.LoadENVs()
+		*envhandler.ENV.ADMIN_KEY = admin
+	})
+	*envhandler.ENV.ADMIN_KEY = "admin-secret"
+
+	s := server.NewServer(0, "127.0.0.1")
+	reloads := 0
+	s.OnReload(func() { reloads++ })
+	handler := s.Handler()
+
+	post := func(body string) (int, server.RequestLimits) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/admin/ratelimit", bytes.NewReader([]byte(body)))
+		req.Header.Set("X-Admin-Key", "admin-secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var limits server.RequestLimits
+		_ = json.Unmarshal(w.Body.Bytes(), &limits)
+		return w.Code, limits
+	}
+
+	// omitted limits are kept, the hooks - e.g. of the gRPC server - apply the change
+	code, limits := post(`{"http": 3, "grpc": 5}`)
+	if code != http.StatusOK || limits != (server.RequestLimits{HTTP: 3, GRPC: 5, WaitMs: wait}) {
+		t.Fatalf("ratelimit: got %d %+v", code, limits)
+	}
+	if reloads != 1 {
+		t.Fatalf("expected 1 reload, got %d", reloads)
+	}
+	if code, _ := post(`{"http": 0}`); code != http.StatusBadRequest {
+		t.Fatalf("http 0: expected 400, got %d", code)
+	}
+	if code, _ := post(`{"http": 3}`); code != http.StatusOK || reloads != 1 {
+		t.Fatalf("unchanged limit: got %d and %d reloads", code, reloads)
+	}
+
+	// a reload of the env file keeps the overrides
+	changed := envhandler.ENV.ReloadENVs(map[string]string{envhandler.REQ_LIMIT: "7", envhandler.REQUEST_WAIT_MS: "9"})
+	if !slices.Equal(changed, []string{envhandler.REQUEST_WAIT_MS}) || envhandler.ENV.ReqLimit() != 3 {
+		t.Fatalf("reload: changed %v, limit %d", changed, envhandler.ENV.ReqLimit())
+	}
+}
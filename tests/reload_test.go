@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"hydrakv/envhandler"
+	"testing"
+	"time"
+
+	serverpkg "hydrakv/server"
+)
+
+func TestReloadDb_Parallel(t *testing.T) {
+	// use a fresh data folder, so only our DBs are reloaded
+	oldFolder := *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() {
+		*envhandler.ENV.DB_FOLDER = oldFolder
+	}()
+
+	const dbs = 20
+	const keys = 50
+
+	// 1. Create DBs with data
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	for i := 0; i < dbs; i++ {
+		db := fmt.Sprintf("reloaddb%d", i)
+		if err, _, _, _ := s.NewDB(db); err != nil {
+			t.Fatalf("NewDB %s: %v", db, err)
+		}
+		for k := 0; k < keys; k++ {
+			s.Set(db, fmt.Sprintf("k-%d", k), fmt.Sprintf("v-%d-%d", i, k), 0)
+		}
+	}
+	s.CloseDbs()
+
+	// 2. Reload them in a new server
+	s = serverpkg.NewServer(0, "127.0.0.1")
+	if err := s.ReloadDb(); err != nil {
+		t.Fatalf("ReloadDb: %v", err)
+	}
+	s.WaitForDBs()
+	defer s.CloseDbs()
+
+	// 3. All DBs are loaded with their data
+	for i := 0; i < dbs; i++ {
+		db := fmt.Sprintf("reloaddb%d", i)
+		if s.DBLoading(db) {
+			t.Fatalf("%s is still loading", db)
+		}
+		for k := 0; k < keys; k++ {
+			ok, v := s.Get(db, fmt.Sprintf("k-%d", k))
+			if want := fmt.Sprintf("v-%d-%d", i, k); !ok || v != want {
+				t.Fatalf("%s: got %s (ok=%v) want %s", db, v, ok, want)
+			}
+		}
+	}
+}
+
+func TestCloseDbsContext(t *testing.T) {
+	oldFolder := *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	defer func() {
+		*envhandler.ENV.DB_FOLDER = oldFolder
+	}()
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	if err, _, _, _ := s.NewDB("closedb"); err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	s.Set("closedb", "k", "v", 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.CloseDbsContext(ctx); err != nil {
+		t.Fatalf("CloseDbsContext: %v", err)
+	}
+
+	// a request which outlived the drain must not reach the closed AOF
+	if s.Set("closedb", "late", "v", 0) {
+		t.Fatalf("write after close was accepted")
+	}
+
+	// the data before the close is persisted
+	s = serverpkg.NewServer(0, "127.0.0.1")
+	if err := s.ReloadDb(); err != nil {
+		t.Fatalf("ReloadDb: %v", err)
+	}
+	s.WaitForDBs()
+	defer s.CloseDbs()
+	if ok, v := s.Get("closedb", "k"); !ok || v != "v" {
+		t.Fatalf("got %s (ok=%v) want v", v, ok)
+	}
+	if ok, _ := s.Get("closedb", "late"); ok {
+		t.Fatalf("late write was persisted")
+	}
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"hydrakv/envhandler"
+	serverpkg "hydrakv/server"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// respClient is a minimal RESP2 client - it sends commands as arrays of bulk strings and returns the raw reply
+type respClient struct {
+	t    *testing.T
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// newRESPServer starts a RESP server with its own DB folder on a free port and connects a client to it
+func newRESPServer(t *testing.T) (*serverpkg.Server, *respClient) {
+	t.Helper()
+	oldFolder := *envhandler.ENV.DB_FOLDER
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	t.Cleanup(func() { *envhandler.ENV.DB_FOLDER = oldFolder })
+
+	s := serverpkg.NewServer(0, "127.0.0.1")
+	rs := serverpkg.NewRESPServer(s)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = rs.Serve(lis) }()
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = rs.Shutdown(ctx)
+	})
+	return s, &respClient{t: t, conn: conn, rd: bufio.NewReader(conn)}
+}
+
+// send writes a command without reading its reply - for pipelining
+func (c *respClient) send(args ...string) {
+	c.t.Helper()
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		b.WriteString("$" + strconv.Itoa(len(a)) + "\r\n" + a + "\r\n")
+	}
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+}
+
+// reply reads one reply - a bulk string is returned with its header, e.g. "$5 hydra"
+func (c *respClient) reply() string {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := c.rd.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if strings.HasPrefix(line, "$") && line != "$-1" {
+		value, err := c.rd.ReadString('\n')
+		if err != nil {
+			c.t.Fatalf("read bulk: %v", err)
+		}
+		return line + " " + strings.TrimSuffix(value, "\r\n")
+	}
+	return line
+}
+
+// do sends a command and checks its reply
+func (c *respClient) do(want string, args ...string) {
+	c.t.Helper()
+	c.send(args...)
+	if got := c.reply(); got != want {
+		c.t.Fatalf("%v: got %q want %q", args, got, want)
+	}
+}
+
+func TestRESP_Commands(t *testing.T) {
+	s, c := newRESPServer(t)
+	if err, _, _, _ := s.NewDB("respdb"); err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	c.do("+PONG", "PING")
+	c.do("-ERR no DB selected - use SELECT <dbname>", "GET", "k")
+	c.do("-ERR DB does not exist", "SELECT", "missingdb")
+	c.do("+OK", "SELECT", "respdb")
+
+	c.do("+OK", "SET", "name", "hydra")
+	c.do("$5 hydra", "GET", "name")
+	c.do("$-1", "GET", "missing")
+
+	// NX only sets missing keys, EX sets the expiry
+	c.do("$-1", "SET", "name", "other", "NX")
+	c.do("+OK", "set", "lock", "1", "ex", "60", "nx")
+	if meta, ok := s.Meta("respdb", "lock"); !ok || meta.Ttl <= 0 || meta.Ttl > 60 {
+		t.Fatalf("SET EX: meta %+v", meta)
+	}
+	c.do("-ERR invalid expire time in 'set' command", "SET", "k", "v", "EX", "0")
+	c.do("-ERR syntax error", "SET", "k", "v", "XX")
+
+	c.do(":2", "EXISTS", "name", "lock", "missing")
+	c.do(":1", "INCR", "counter")
+	c.do(":2", "INCR", "counter")
+	c.do("-ERR value is not an integer or out of range", "INCR", "name")
+	c.do(":2", "DEL", "name", "counter", "missing")
+	c.do(":0", "EXISTS", "name")
+
+	c.do("-ERR wrong number of arguments for 'get' command", "GET")
+	c.do("-ERR unknown command 'FLUSHALL'", "FLUSHALL")
+
+	// pipelined commands are answered in order
+	c.send("SET", "p", "1")
+	c.send("INCR", "p")
+	c.send("GET", "p")
+	for _, want := range []string{"+OK", ":2", "$1 2"} {
+		if got := c.reply(); got != want {
+			t.Fatalf("pipeline: got %q want %q", got, want)
+		}
+	}
+
+	// inline commands as typed into telnet
+	if _, err := c.conn.Write([]byte("GET p\r\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got := c.reply(); got != "$1 2" {
+		t.Fatalf("inline: got %q", got)
+	}
+
+	c.do("+OK", "QUIT")
+}
+
+func TestRESP_Auth(t *testing.T) {
+	oldVal := *envhandler.ENV.APIKEY_ENABLED
+	*envhandler.ENV.APIKEY_ENABLED = true
+	t.Cleanup(func() { *envhandler.ENV.APIKEY_ENABLED = oldVal })
+
+	s, c := newRESPServer(t)
+	err, _, _, apikey := s.NewDB("respauthdb")
+	if err != nil || apikey == "" {
+		t.Fatalf("NewDB: %v %q", err, apikey)
+	}
+
+	c.do("+OK", "SELECT", "respauthdb")
+	c.do("-NOAUTH Authentication required", "GET", "k")
+	c.do("-WRONGPASS invalid username-password pair", "AUTH", "wrong")
+
+	// a missing DB can't be told apart from a wrong key
+	c.do("-WRONGPASS invalid username-password pair", "AUTH", "respmissingdb", apikey)
+	c.do("+OK", "SELECT", "respmissingdb")
+	c.do("-NOAUTH Authentication required", "GET", "k")
+	c.do("+OK", "SELECT", "respauthdb")
+
+	// AUTH <dbname> <apikey> is what redis-cli --user --pass sends
+	c.do("+OK", "AUTH", "respauthdb", apikey)
+	c.do("+OK", "SET", "k", "v")
+	c.do("$1 v", "GET", "k")
+}
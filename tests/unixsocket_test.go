@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"context"
+	"hydrakv/envhandler"
+	"hydrakv/server"
+	"hydrakv/server/hydrakv/proto/kvpb"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// withUnixSockets points both Unix socket settings into a temp dir and allows no TCP client but 10.0.0.0/8, so
+// only the sockets are usable
+func withUnixSockets(t *testing.T) (httpSock, grpcSock string) {
+	t.Helper()
+	dir := t.TempDir()
+	old := []*string{envhandler.ENV.UNIX_SOCKET, envhandler.ENV.GRPC_UNIX_SOCKET, envhandler.ENV.ALLOW_CIDRS,
+		envhandler.ENV.DB_FOLDER}
+	saved := []string{*old[0], *old[1], *old[2], *old[3]}
+	t.Cleanup(func() {
+		for i, p := range old {
+			*p = saved[i]
+		}
+	})
+
+	httpSock, grpcSock = filepath.Join(dir, "http.sock"), filepath.Join(dir, "grpc.sock")
+	*envhandler.ENV.UNIX_SOCKET = httpSock
+	*envhandler.ENV.GRPC_UNIX_SOCKET = grpcSock
+	*envhandler.ENV.ALLOW_CIDRS = "10.0.0.0/8"
+	*envhandler.ENV.DB_FOLDER = t.TempDir()
+	return httpSock, grpcSock
+}
+
+// waitForFile waits until path exists
+func waitForFile(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s was not created", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestUnixSocket_HTTP(t *testing.T) {
+	sock, _ := withUnixSockets(t)
+
+	// a socket left behind by a crashed server is replaced
+	stale, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	s := server.NewServer(0, "127.0.0.1")
+	go s.Start()
+	waitForFile(t, sock)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", sock)
+		},
+	}}
+
+	// the socket is ready before the DBs are reloaded and the TCP listener runs - retry until it answers
+	var resp *http.Response
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err = client.Get("http://unix/livez")
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("request over the unix socket: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// the allowlist does not apply to the socket
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("livez: got %d %s", resp.StatusCode, body)
+	}
+	if fi, err := os.Stat(sock); err != nil || fi.Mode().Perm() != 0o660 {
+		t.Fatalf("socket mode: %v %v", fi.Mode(), err)
+	}
+
+	// the socket file is removed on shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.Server.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if _, err := os.Stat(sock); !os.IsNotExist(err) {
+		t.Fatalf("socket still exists after shutdown: %v", err)
+	}
+}
+
+func TestUnixSocket_GRPC(t *testing.T) {
+	_, sock := withUnixSockets(t)
+
+	gs := server.NewGRPCServer(server.NewServer(0, "127.0.0.1"))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := lis.Addr().(*net.TCPAddr).Port
+	_ = lis.Close()
+	go gs.Start("127.0.0.1", port)
+	waitForFile(t, sock)
+
+	conn, err := grpc.NewClient("unix://"+sock, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := kvpb.NewKVServiceClient(conn).CreateDB(ctx, &kvpb.CreateDBRequest{Name: "unixdb"})
+	if err != nil || !resp.Created {
+		t.Fatalf("CreateDB over the unix socket: %v %v", resp, err)
+	}
+
+	gs.Stop()
+	if _, err := os.Stat(sock); !os.IsNotExist(err) {
+		t.Fatalf("socket still exists after stop: %v", err)
+	}
+}
@@ -5,54 +5,169 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"hydrakv/envhandler"
+	"log"
 	"os"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Utils struct {
-	DbNameRegex *regexp.Regexp
-	apiKeys     map[string][32]byte
-	mu          sync.RWMutex
+	DbNameRegex  *regexp.Regexp
+	DbNameMaxLen int
+	apiKeys      map[string]map[ApiKeyRole][32]byte
+	apiKeyMeta   map[string]map[ApiKeyRole]*keyMeta
+	mu           sync.RWMutex
 }
 
+// keyMeta is the metadata of an api key - lastUsed is updated on every request, so it is atomic
+type keyMeta struct {
+	createdAt time.Time
+	lastUsed  atomic.Int64
+}
+
+// ApiKeyInfo is the metadata of an api key - the key itself is never part of it
+type ApiKeyInfo struct {
+	Role      string     `json:"role"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+}
+
+// metaEntry is the metadata of an api key in the .meta file
+type metaEntry struct {
+	CreatedAt time.Time `json:"created_at"`
+	LastUsed  time.Time `json:"last_used"`
+}
+
+// ApiKeyRole is the permission of an api key
+type ApiKeyRole byte
+
+const (
+	// RoleWrite allows all operations of a DB
+	RoleWrite ApiKeyRole = 1
+	// RoleRead allows read-only operations
+	RoleRead ApiKeyRole = 2
+)
+
+// String returns the name of the role
+func (r ApiKeyRole) String() string {
+	switch r {
+	case RoleWrite:
+		return "write"
+	case RoleRead:
+		return "read"
+	}
+	return fmt.Sprintf("role(%d)", byte(r))
+}
+
+// the .apikey files start with the magic and the version of the format
+const (
+	apiKeyFileMagic   = "HKVK"
+	apiKeyFileVersion = 2
+)
+
 var U = &Utils{}
 
+// dummyApiKeyHash is compared against for DBs without api keys
+var dummyApiKeyHash = sha256.Sum256([]byte("hydrakv-no-api-key"))
+
+// DefaultDbNameRegex allows letters, digits, hyphens and underscores
+const DefaultDbNameRegex = "^[a-zA-Z0-9_-]+$"
+
 // init will init the Utils struct
 func init() {
-	// Same rule as: validate:"alphanum,min=1,max=100"
-	U.DbNameRegex = regexp.MustCompile("^[a-zA-Z0-9]{1,100}$")
-	U.apiKeys = map[string][32]byte{}
+	U.DbNameRegex = regexp.MustCompile(DefaultDbNameRegex)
+	U.DbNameMaxLen = 100
+	U.apiKeys = map[string]map[ApiKeyRole][32]byte{}
+	U.apiKeyMeta = map[string]map[ApiKeyRole]*keyMeta{}
+}
+
+// SetDbNamePolicy sets the regex and the maximum length for db names - it fails if the regex does not compile
+func (u *Utils) SetDbNamePolicy(regex string, maxLen int) error {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return fmt.Errorf("invalid db name regex %q: %w", regex, err)
+	}
+	if maxLen < 1 {
+		return fmt.Errorf("invalid db name max length %d", maxLen)
+	}
+	u.DbNameRegex = re
+	u.DbNameMaxLen = maxLen
+	return nil
 }
 
-// CheckDbName checks if the given db name is valid
+// DbKey returns the name under which a DB is stored - names are uppercased unless HKV_DBNAME_CASE_SENSITIVE is set
+func (u *Utils) DbKey(name string) string {
+	if *envhandler.ENV.DBNAME_CASE_SENSITIVE {
+		return name
+	}
+	return strings.ToUpper(name)
+}
+
+// CheckDbName checks if the given db name is valid. Independent of the regex, names must be filesystem-safe,
+// since they become the names of the .bin and .apikey files.
 func (u *Utils) CheckDbName(name string) bool {
+	if len(name) == 0 || len(name) > u.DbNameMaxLen {
+		return false
+	}
+	for _, c := range name {
+		if c == '.' || c == '/' || c == '\\' || c == ':' || c < 0x20 || c == 0x7f {
+			return false
+		}
+	}
 	return u.DbNameRegex.MatchString(name)
 }
 
 // IsPublicPath checks if the given path is public
 func (u *Utils) IsPublicPath(path string) bool {
-	return path == "/health" || path == "/metrics" || path == "/create" || path == "/"
+	return path == "/health" || path == "/livez" || path == "/metrics" || path == "/create" || path == "/" || path == "/stats"
 }
 
-// IsApiKeyValid checks if the given api key is valid
+// IsApiKeyValid checks if the given api key is a write key - write keys allow all operations
 func (u *Utils) IsApiKeyValid(db, apiKey string) bool {
-	db = strings.ToUpper(db)
+	return u.HasAccess(db, apiKey, false)
+}
+
+// IsReadKeyValid checks if the given api key allows read operations - this is true for read and write keys
+func (u *Utils) IsReadKeyValid(db, apiKey string) bool {
+	return u.HasAccess(db, apiKey, true)
+}
+
+// HasAccess checks if the given api key allows the operation - read-only operations accept read keys as well
+func (u *Utils) HasAccess(db, apiKey string, readOnly bool) bool {
+	db = u.DbKey(db)
 
 	// apiKey arrives as a string (header/proto), so hash the string form.
 	hash := sha256.Sum256([]byte(apiKey))
 
 	u.mu.RLock()
-	val, ok := u.apiKeys[db]
-	u.mu.RUnlock()
+	defer u.mu.RUnlock()
+	keys, ok := u.apiKeys[db]
+	if !ok {
+		// compare anyway, so an unknown DB can't be told apart from a wrong key by timing
+		subtle.ConstantTimeCompare(dummyApiKeyHash[:], hash[:])
+		return false
+	}
 
-	if ok {
-		return subtle.ConstantTimeCompare(val[:], hash[:]) == 1
+	valid := false
+	for role, val := range keys {
+		if role != RoleWrite && !(readOnly && role == RoleRead) {
+			continue
+		}
+		if subtle.ConstantTimeCompare(val[:], hash[:]) == 1 {
+			valid = true
+			if meta, ok := u.apiKeyMeta[db][role]; ok {
+				meta.lastUsed.Store(time.Now().UnixNano())
+			}
+		}
 	}
-	return false
+	return valid
 }
 
 // CreateRandomApiKey creates a random api key
@@ -70,57 +185,303 @@ func (u *Utils) CreateRandomApiKey() (string, [32]byte, error) {
 	return apiKeyStr, hash, nil
 }
 
-// SaveApiKey saves the given api key
+// SaveApiKey saves the given api key as write key
 func (u *Utils) SaveApiKey(db string, apiKey [32]byte) error {
-	db = strings.ToUpper(db)
+	return u.SaveApiKeyRole(db, RoleWrite, apiKey)
+}
+
+// SaveApiKeyRole saves the given api key with its role - an existing key of the role is replaced
+func (u *Utils) SaveApiKeyRole(db string, role ApiKeyRole, apiKey [32]byte) error {
+	db = u.DbKey(db)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, ok := u.apiKeys[db]; !ok {
+		u.apiKeys[db] = make(map[ApiKeyRole][32]byte)
+	}
+	u.apiKeys[db][role] = apiKey
+	if _, ok := u.apiKeyMeta[db]; !ok {
+		u.apiKeyMeta[db] = make(map[ApiKeyRole]*keyMeta)
+	}
+	u.apiKeyMeta[db][role] = &keyMeta{createdAt: time.Now()}
+
+	if err := writeApiKeyFile(db, encodeApiKeys(u.apiKeys[db])); err != nil {
+		return err
+	}
+	return u.writeApiKeyMeta(db)
+}
+
+// ListApiKeys returns the metadata of the api keys per DB
+func (u *Utils) ListApiKeys() map[string][]ApiKeyInfo {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	list := make(map[string][]ApiKeyInfo, len(u.apiKeys))
+	for db, keys := range u.apiKeys {
+		infos := make([]ApiKeyInfo, 0, len(keys))
+		for _, role := range []ApiKeyRole{RoleWrite, RoleRead} {
+			if _, ok := keys[role]; !ok {
+				continue
+			}
+			info := ApiKeyInfo{Role: role.String()}
+			if meta, ok := u.apiKeyMeta[db][role]; ok {
+				if !meta.createdAt.IsZero() {
+					createdAt := meta.createdAt
+					info.CreatedAt = &createdAt
+				}
+				if nanos := meta.lastUsed.Load(); nanos != 0 {
+					lastUsed := time.Unix(0, nanos)
+					info.LastUsed = &lastUsed
+				}
+			}
+			infos = append(infos, info)
+		}
+		list[db] = infos
+	}
+	return list
+}
+
+// RevokeApiKeys removes all api keys of a DB and their files - the DB is inaccessible until a new key is saved.
+// It returns false if the DB has no keys.
+func (u *Utils) RevokeApiKeys(db string) (bool, error) {
+	db = u.DbKey(db)
 
 	u.mu.Lock()
-	u.apiKeys[db] = apiKey
-	u.mu.Unlock()
+	defer u.mu.Unlock()
+	if _, ok := u.apiKeys[db]; !ok {
+		return false, nil
+	}
+	delete(u.apiKeys, db)
+	delete(u.apiKeyMeta, db)
+
+	for _, file := range []string{apiKeyFile(db), apiKeyMetaFile(db)} {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// ExportApiKeys returns the api key hashes of a DB in the format of the .apikey file - false if the DB has no keys
+func (u *Utils) ExportApiKeys(db string) ([]byte, bool) {
+	db = u.DbKey(db)
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	keys, ok := u.apiKeys[db]
+	if !ok {
+		return nil, false
+	}
+	return encodeApiKeys(keys), true
+}
 
-	// create or open the file in *envhandler
-	file, err := os.OpenFile(*envhandler.ENV.DB_FOLDER+"/."+db+".apikey", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+// ImportApiKeys stores api key hashes exported by ExportApiKeys for a DB, e.g. from a backup. Existing keys of the
+// DB are replaced.
+func (u *Utils) ImportApiKeys(db string, data []byte) error {
+	db = u.DbKey(db)
+	keys, err := decodeApiKeys(data)
 	if err != nil {
+		return fmt.Errorf("api keys of %s: %w", db, err)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.apiKeys[db] = keys
+	u.apiKeyMeta[db] = make(map[ApiKeyRole]*keyMeta, len(keys))
+	for role := range keys {
+		u.apiKeyMeta[db][role] = &keyMeta{createdAt: time.Now()}
+	}
+	if err := writeApiKeyFile(db, encodeApiKeys(keys)); err != nil {
 		return err
 	}
-	defer file.Close()
-	_, err = file.Write(apiKey[:])
+	return u.writeApiKeyMeta(db)
+}
+
+// FlushApiKeyMeta writes the metadata of all api keys, so the last-used times survive a restart
+func (u *Utils) FlushApiKeyMeta() error {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	var errs []error
+	for db := range u.apiKeyMeta {
+		errs = append(errs, u.writeApiKeyMeta(db))
+	}
+	return errors.Join(errs...)
+}
+
+// writeApiKeyMeta writes the .meta file of a DB - must be called with the lock held
+func (u *Utils) writeApiKeyMeta(db string) error {
+	entries := make(map[string]metaEntry)
+	for role, meta := range u.apiKeyMeta[db] {
+		entry := metaEntry{CreatedAt: meta.createdAt}
+		if nanos := meta.lastUsed.Load(); nanos != 0 {
+			entry.LastUsed = time.Unix(0, nanos)
+		}
+		entries[role.String()] = entry
+	}
+	data, err := json.Marshal(entries)
 	if err != nil {
 		return err
 	}
-	return nil
+	if err := os.WriteFile(apiKeyMetaFile(db), data, apiKeyFileMode); err != nil {
+		return err
+	}
+	return os.Chmod(apiKeyMetaFile(db), apiKeyFileMode)
 }
 
-// RestoreApiKeys restores the api keys from the .apikey files
+// readApiKeyMeta reads the .meta file of a DB - keys without metadata (e.g. of older versions) get empty metadata
+func (u *Utils) readApiKeyMeta(db string, keys map[ApiKeyRole][32]byte) map[ApiKeyRole]*keyMeta {
+	entries := make(map[string]metaEntry)
+	if data, err := os.ReadFile(apiKeyMetaFile(db)); err == nil {
+		_ = json.Unmarshal(data, &entries)
+	}
+
+	metas := make(map[ApiKeyRole]*keyMeta, len(keys))
+	for role := range keys {
+		entry := entries[role.String()]
+		meta := &keyMeta{createdAt: entry.CreatedAt}
+		if !entry.LastUsed.IsZero() {
+			meta.lastUsed.Store(entry.LastUsed.UnixNano())
+		}
+		metas[role] = meta
+	}
+	return metas
+}
+
+// RestoreApiKeys restores the api keys from the .apikey files. Files which are no api key files of a DB are ignored,
+// malformed ones are logged and skipped, so their DBs stay inaccessible instead of stopping the start.
 func (u *Utils) RestoreApiKeys() error {
 	files, err := os.ReadDir(*envhandler.ENV.DB_FOLDER)
 	if err != nil {
 		return err
 	}
 	for _, file := range files {
-		if !strings.HasSuffix(file.Name(), ".apikey") {
+		if !file.Type().IsRegular() || !strings.HasPrefix(file.Name(), ".") || !strings.HasSuffix(file.Name(), ".apikey") {
 			continue
 		}
-		apiKey, err := u.ReadApiKey(strings.TrimSuffix(file.Name(), ".apikey"))
-		if err != nil {
-			return err
+		db := strings.TrimSuffix(strings.TrimPrefix(file.Name(), "."), ".apikey")
+		if !u.CheckDbName(db) {
+			continue
 		}
-		err = u.SaveApiKey(strings.TrimSuffix(file.Name(), ".apikey"), [32]byte(apiKey))
+		keys, err := u.ReadApiKeys(db)
 		if err != nil {
-			return err
+			log.Printf("Skipping api key file %s: %v", file.Name(), err)
+			continue
+		}
+		// older versions wrote the files readable by everyone
+		for _, name := range []string{apiKeyFile(db), apiKeyMetaFile(db)} {
+			if err := os.Chmod(name, apiKeyFileMode); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("Can't restrict the permissions of %s: %v", name, err)
+			}
 		}
+		u.mu.Lock()
+		u.apiKeys[u.DbKey(db)] = keys
+		u.apiKeyMeta[u.DbKey(db)] = u.readApiKeyMeta(u.DbKey(db), keys)
+		u.mu.Unlock()
 	}
 	return nil
 }
 
-// ReadApiKey reads the api key from the file
-func (u *Utils) ReadApiKey(db string) ([]byte, error) {
-	db = strings.ToUpper(db)
+// ReadApiKeys reads the api keys and their roles from the file
+func (u *Utils) ReadApiKeys(db string) (map[ApiKeyRole][32]byte, error) {
+	db = u.DbKey(db)
 
 	// read the file
-	apiKey, err := os.ReadFile(*envhandler.ENV.DB_FOLDER + "/." + db + ".apikey")
+	data, err := os.ReadFile(apiKeyFile(db))
 	if err != nil {
 		return nil, err
 	}
-	return apiKey, nil
+	keys, err := decodeApiKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("api key file of %s: %w", db, err)
+	}
+	return keys, nil
+}
+
+// EnsureDir creates dir with its parents if it is missing. It fails with an actionable error naming the setting
+// if dir is a file or can't be created, instead of a failing DB creation later on.
+func (u *Utils) EnsureDir(dir, setting string) error {
+	info, err := os.Stat(dir)
+	switch {
+	case err == nil && info.IsDir():
+		return nil
+	case err == nil:
+		return fmt.Errorf("%s %q is a file, not a directory: point %s to a directory or move the file away",
+			setting, dir, setting)
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("%s %q can't be read: %w", setting, dir, err)
+	}
+	if err := os.MkdirAll(dir, envhandler.ENV.DirMode()); err != nil {
+		return fmt.Errorf("%s %q can't be created: %w", setting, dir, err)
+	}
+	return nil
+}
+
+// apiKeyFileMode are the permissions of the .apikey and .meta files - they hold credential material and its use,
+// whatever HKV_FILE_MODE is
+const apiKeyFileMode = 0600
+
+// writeApiKeyFile writes the .apikey file of a DB - the mode of a file written by an older version is tightened
+func writeApiKeyFile(db string, data []byte) error {
+	if err := os.WriteFile(apiKeyFile(db), data, apiKeyFileMode); err != nil {
+		return err
+	}
+	return os.Chmod(apiKeyFile(db), apiKeyFileMode)
+}
+
+// apiKeyFile returns the path of the .apikey file of a DB
+func apiKeyFile(db string) string {
+	return *envhandler.ENV.DB_FOLDER + "/." + db + ".apikey"
+}
+
+// apiKeyMetaFile returns the path of the .meta file with the created-at and last-used times of the api keys of a DB
+func apiKeyMetaFile(db string) string {
+	return *envhandler.ENV.DB_FOLDER + "/." + db + ".meta"
+}
+
+// encodeApiKeys encodes the keys in the versioned format: magic, version and role + SHA-256 per key
+func encodeApiKeys(keys map[ApiKeyRole][32]byte) []byte {
+	data := append([]byte(apiKeyFileMagic), apiKeyFileVersion)
+	for _, role := range []ApiKeyRole{RoleWrite, RoleRead} {
+		if hash, ok := keys[role]; ok {
+			data = append(data, byte(role))
+			data = append(data, hash[:]...)
+		}
+	}
+	return data
+}
+
+// decodeApiKeys decodes a versioned .apikey file - files of 32 bytes are the legacy format with a single write key
+func decodeApiKeys(data []byte) (map[ApiKeyRole][32]byte, error) {
+	keys := make(map[ApiKeyRole][32]byte)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty")
+	}
+	if len(data) == sha256.Size {
+		keys[RoleWrite] = [32]byte(data)
+		return keys, nil
+	}
+
+	header := len(apiKeyFileMagic) + 1
+	if len(data) < header || string(data[:len(apiKeyFileMagic)]) != apiKeyFileMagic {
+		return nil, fmt.Errorf("unknown format")
+	}
+	if data[len(apiKeyFileMagic)] != apiKeyFileVersion {
+		return nil, fmt.Errorf("unknown version %d", data[len(apiKeyFileMagic)])
+	}
+	entries := data[header:]
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no keys")
+	}
+	if len(entries)%(1+sha256.Size) != 0 {
+		return nil, fmt.Errorf("truncated")
+	}
+	for i := 0; i < len(entries); i += 1 + sha256.Size {
+		role := ApiKeyRole(entries[i])
+		if role != RoleWrite && role != RoleRead {
+			return nil, fmt.Errorf("unknown role %d", role)
+		}
+		keys[role] = [32]byte(entries[i+1 : i+1+sha256.Size])
+	}
+	return keys, nil
 }